@@ -0,0 +1,621 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/jobs"
+	"github.com/maxkimambo/trasher/internal/latency"
+	"github.com/maxkimambo/trasher/internal/naming"
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/report"
+	"github.com/maxkimambo/trasher/internal/signal"
+	"github.com/maxkimambo/trasher/internal/sizedist"
+	"github.com/maxkimambo/trasher/internal/telemetry"
+	"github.com/maxkimambo/trasher/internal/throttle"
+	"github.com/maxkimambo/trasher/internal/worker"
+	"github.com/maxkimambo/trasher/internal/writer"
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/generator"
+	"github.com/maxkimambo/trasher/pkg/sizeparser"
+)
+
+// batchSpec describes one of the files of a batch run.
+type batchSpec struct {
+	name    string
+	size    int64
+	pattern string
+	// seed is the pattern's seed, or nil for a random one per file
+	seed *int64
+}
+
+// batchFile is one of the files of a batch run.
+type batchFile struct {
+	batchSpec
+	out         *writer.FileWriter
+	checksumGen *checksum.ChecksumGenerator
+	job         *worker.Job
+	written     int64
+	// span traces the file's job as part of the batch's span
+	span *telemetry.Span
+	// err is what failed while generating or finishing this file
+	err error
+}
+
+// endSpan ends the file's span with the file's result: its own error or, if
+// it has none, batchErr, the error that stopped the batch before the file
+// was completed.
+func (f *batchFile) endSpan(batchErr error) {
+	err := f.err
+	if err == nil {
+		err = batchErr
+	}
+	status := "completed"
+	if errors.Is(err, errCancelled) {
+		status = "cancelled"
+	} else if err != nil {
+		status = "failed"
+	}
+	f.span.SetAttributes(
+		telemetry.String("trasher.status", status),
+		telemetry.Int("trasher.bytes_written", atomic.LoadInt64(&f.written)))
+	if f.seed != nil {
+		f.span.SetAttributes(telemetry.Int("trasher.seed", *f.seed))
+	}
+	if sum := f.checksumGen.FullChecksum(); err == nil && sum != "" {
+		f.span.SetAttributes(telemetry.String("trasher.checksum", f.checksumGen.GetAlgorithm()+":"+sum))
+	}
+	// A child span is exported with the batch's span, so ending it can't fail
+	f.span.End(context.Background(), err)
+}
+
+// batchNames returns the names of count files generated for output: the
+// output name with a number from 1 to count added before its extension,
+// padded so the names sort in order (test-01.dat ... test-10.dat), or the
+// names expanded from output if it is a template (see naming.Expand).
+func batchNames(output string, count int) ([]string, error) {
+	if naming.IsTemplate(output) {
+		return naming.Expand(output, count, naming.Vars{Pattern: patternName(), Time: time.Now()})
+	}
+
+	ext := filepath.Ext(output)
+	base := strings.TrimSuffix(output, ext)
+	width := len(strconv.Itoa(count))
+
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s-%0*d%s", base, width, i+1, ext)
+	}
+	return names, nil
+}
+
+// randomSizes draws the sizes of count files from --size-dist, between
+// --size-min and --size-max.
+func randomSizes(count int) ([]int64, error) {
+	minBytes, err := sizeparser.Parse(sizeMin)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --size-min: %v", err)
+	}
+	maxBytes, err := sizeparser.Parse(sizeMax)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --size-max: %v", err)
+	}
+	r := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	return sizedist.Sample(sizeDist, minBytes, maxBytes, count, r)
+}
+
+// patternName names the pattern for the {pattern} placeholder: the pattern
+// without its options, the patterns of a composite pattern joined by +, or
+// map for a pattern map.
+func patternName() string {
+	if patternMap != "" {
+		return "map"
+	}
+	if parts, ok, err := generator.ParseComposite(pattern); ok && err == nil {
+		names := make([]string, len(parts))
+		for i, part := range parts {
+			names[i] = part.Pattern
+		}
+		return strings.Join(names, "+")
+	}
+	name, _, _ := strings.Cut(pattern, ":")
+	return strings.TrimSpace(name)
+}
+
+// batchSpecs describes a file of sizeBytes of the --pattern for each of
+// names.
+func batchSpecs(names []string, sizeBytes int64) []batchSpec {
+	specs := make([]batchSpec, len(names))
+	for i, name := range names {
+		specs[i] = batchSpec{name: name, size: sizeBytes, pattern: pattern, seed: patternSeed()}
+	}
+	return specs
+}
+
+// batchPattern describes the patterns of a batch's files: their pattern if
+// they share one, or each pattern in use.
+func batchPattern(specs []batchSpec) string {
+	var patterns []string
+	for _, spec := range specs {
+		if !slices.Contains(patterns, spec.pattern) {
+			patterns = append(patterns, spec.pattern)
+		}
+	}
+	return strings.Join(patterns, " ")
+}
+
+// runBatch generates the files of specs. Each file is a job of one worker
+// pool, so the files are generated at the same time by the same workers
+// from the same buffers instead of one after another.
+func runBatch(specs []batchSpec, chunkSizeBytes, maxMemoryBytes, hashBufBytes int64, chunkAuto bool) (err error) {
+	var totalBytes int64
+	for _, spec := range specs {
+		totalBytes += spec.size
+	}
+
+	// Create context and shutdown handler
+	ctx, shutdownHandler := signal.WithShutdownHandler(os.Stdout)
+
+	// Create a writer and checksum generator per file
+	files := make([]*batchFile, 0, len(specs))
+	defer func() {
+		for _, file := range files {
+			file.out.Close()
+		}
+	}()
+	for _, spec := range specs {
+		opts, err := withPermissions(writer.Options{
+			Force:     force,
+			Sparse:    sparse,
+			DropCache: dropCache,
+			Engine:    ioEngine,
+		})
+		if err != nil {
+			return err
+		}
+		out, err := writer.NewFileWriterWithOptions(spec.name, spec.size, opts)
+		if err != nil {
+			return writeFailed(fmt.Errorf("failed to create file writer for %s: %w", spec.name, err))
+		}
+		shutdownHandler.RegisterCleanupFunc(out.Close)
+		checksumGen := checksum.NewChecksumGenerator(spec.name, spec.size)
+		if err := checksumGen.SetMode(hashMode); err != nil {
+			return err
+		}
+		checksumGen.SetHashBuffer(hashBufBytes)
+		if err := checksumGen.SetFormat(sumFormat); err != nil {
+			return err
+		}
+		files = append(files, &batchFile{
+			batchSpec:   spec,
+			out:         out,
+			checksumGen: checksumGen,
+		})
+	}
+
+	// Remove the partial output of a failed or cancelled run; the files were
+	// all created or overwritten by this run
+	if cleanupErr {
+		defer func() {
+			if err == nil {
+				return
+			}
+			for _, file := range files {
+				file.out.Close()
+				for _, path := range []string{file.name, file.name + ".checksum.txt", file.name + checksum.ManifestSuffix} {
+					if removeErr := os.Remove(path); removeErr != nil && !os.IsNotExist(removeErr) {
+						fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", path, removeErr)
+					}
+				}
+				if removeErr := checksum.RemoveMerkle(file.name); removeErr != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", removeErr)
+				}
+			}
+			fmt.Printf("Removed partial output of %d files\n", len(files))
+		}()
+	}
+
+	// Create the pattern generators' options and key
+	genOpts, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+	key, err := encryptionKey()
+	if err != nil {
+		return err
+	}
+	if key != nil && verbose {
+		fmt.Printf("Encryption key: %x\n", key)
+	}
+
+	var closers []io.Closer
+	defer func() {
+		for _, closer := range closers {
+			if closeErr := closer.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	}()
+
+	// Create worker pool; each file runs as a job of its own
+	workerPool := worker.NewWorkerPool(ctx, workers, chunkSizeBytes)
+	if maxMemoryBytes > 0 {
+		workerPool.SetMemoryBudget(maxMemoryBytes)
+	}
+	if autoScale {
+		workerPool.SetAutoScale(worker.DefaultScaleInterval)
+	}
+	workerPool.SetChunkTuning(chunkAuto)
+	workerPool.SetHugePages(hugePages)
+	workerPool.SetDoubleBuffering(doubleBuf)
+	workerPool.SetSplitSize(worker.DefaultSplitSize)
+	defer workerPool.Close()
+
+	// Progress covers all files together
+	getWritten := func() int64 {
+		var written int64
+		for _, file := range files {
+			written += atomic.LoadInt64(&file.written)
+		}
+		return written
+	}
+	progressReporter := progress.NewProgressReporter(totalBytes, verbose, os.Stdout)
+	shutdownHandler.SetProgressReporter(progressReporter)
+	if verbose {
+		progressReporter.SetReport(worker.DefaultStatsInterval, workerReport(workerPool, nil))
+	}
+	progressReporter.SetFiles(func() []progress.FileStatus {
+		statuses := make([]progress.FileStatus, len(files))
+		for i, file := range files {
+			statuses[i] = progress.FileStatus{Name: file.name, Size: file.size, Written: atomic.LoadInt64(&file.written)}
+		}
+		return statuses
+	})
+	progressReporter.Start(getWritten)
+	peakMeter := progress.NewPeakMeter(progress.DefaultPeakInterval)
+	peakMeter.Start(getWritten)
+
+	// Record each file in the user's state directory for `trasher status`
+	for _, file := range files {
+		tracker := registerJob(file.name, file.pattern, file.size)
+		if tracker == nil {
+			continue
+		}
+		fileWritten := func() int64 {
+			return atomic.LoadInt64(&file.written)
+		}
+		tracker.Start(fileWritten)
+		defer func() {
+			state := jobs.StateCompleted
+			if ctx.Err() != nil {
+				state = jobs.StateCancelled
+			} else if err != nil {
+				state = jobs.StateFailed
+			}
+			tracker.Finish(fileWritten(), state, err)
+		}()
+	}
+
+	startTime := time.Now()
+	var generationTime time.Duration
+	writeLatency := latency.NewHistogram()
+	writeMeta := false
+	runSpan.AddEvent("generation started",
+		telemetry.Int("trasher.files", int64(len(files))),
+		telemetry.Int("trasher.workers", int64(workerPool.ActiveWorkers())),
+		telemetry.Int("trasher.chunk_size", workerPool.ChunkSize()))
+
+	// Trace each file's job as a child of the batch's span
+	for _, file := range files {
+		file.span = runSpan.StartChild("trasher.generate.file",
+			telemetry.String("trasher.output", file.name),
+			telemetry.String("trasher.pattern", file.pattern),
+			telemetry.Int("trasher.size_bytes", file.size))
+	}
+	defer func() {
+		for _, file := range files {
+			file.endSpan(err)
+		}
+	}()
+
+	// Export the batch's metrics while it runs
+	meter := otelExporter.StartMeter(telemetry.DefaultMetricsInterval, getWritten, writeLatency,
+		telemetry.String("trasher.output", output),
+		telemetry.String("trasher.pattern", batchPattern(specs)))
+	defer stopMeter(meter)
+
+	// Record the batch's performance for benchmark tracking and history, and
+	// next to each completed file as its metadata
+	defer func() {
+		peakMeter.Stop()
+
+		s := &report.Summary{
+			Version:   version,
+			Output:    output,
+			Pattern:   batchPattern(specs),
+			SizeBytes: totalBytes,
+			Workers:   workerPool.ActiveWorkers(),
+			ChunkSize: workerPool.ChunkSize(),
+			StartedAt: startTime,
+
+			WriteLatency: report.NewLatency(writeLatency),
+		}
+		if len(specs) > 0 && batchPattern(specs) == specs[0].pattern {
+			s.Seed = specs[0].seed
+		}
+		if generationTime == 0 {
+			generationTime = time.Since(startTime)
+		}
+		s.Finalize(getWritten(), generationTime, err)
+		s.PeakThroughput = max(peakMeter.Peak(), s.Throughput)
+		if err != nil && ctx.Err() != nil {
+			s.Status = "cancelled"
+		}
+		lastSummary = s
+
+		if summary != "" {
+			if writeErr := report.Write(summary, s); writeErr != nil && err == nil {
+				err = writeErr
+			}
+		}
+		// Each file's metadata describes that file, generated alongside
+		// the others in the batch's time
+		for _, file := range files {
+			if !writeMeta || err != nil {
+				break
+			}
+			meta := *s
+			meta.Output = file.name
+			meta.Pattern = file.pattern
+			meta.Seed = file.seed
+			meta.SizeBytes = file.size
+			meta.Checksum = file.checksumGen.FullChecksum()
+			meta.Algorithm = file.checksumGen.GetAlgorithm()
+			meta.Finalize(file.size, generationTime, nil)
+			meta.PeakThroughput = 0
+			err = report.Write(file.name+report.MetadataSuffix, &meta)
+		}
+		if !noHistory {
+			if histErr := appendHistory(s); histErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to record run history: %v\n", histErr)
+			}
+		}
+	}()
+
+	// Pace writes across all files when an IOPS limit is set
+	var iopsLimiter *throttle.Limiter
+	if maxIOPS > 0 {
+		iopsLimiter = throttle.NewLimiter(float64(maxIOPS))
+	}
+
+	// A zero file only needs its holes and checksums (see runTrasher)
+	sparseZeros := func(file *batchFile) bool {
+		return file.pattern == "zero" && patternMap == "" && magic == "" && encrypt == "" && !writeZeros
+	}
+	if verbose && slices.ContainsFunc(files, sparseZeros) {
+		fmt.Println("Zero pattern: leaving the files sparse (use --write-zeros to write them)")
+	}
+
+	var wg sync.WaitGroup
+	for _, file := range files {
+		if sparseZeros(file) {
+			if err := file.out.PunchHole(0, file.size); err != nil && !errors.Is(err, writer.ErrHolesUnsupported) {
+				progressReporter.Stop()
+				return err
+			}
+			if !noChecksum && !addTrailer {
+				if err := file.checksumGen.UpdateWithZeroChunks(chunkSizeBytes); err != nil {
+					progressReporter.Stop()
+					return err
+				}
+			}
+			atomic.StoreInt64(&file.written, file.size)
+			continue
+		}
+
+		file.job = workerPool.NewJob()
+		file.job.SetOrdered(ordered)
+		if !noChecksum && !addTrailer {
+			file.job.SetChunkHash(file.checksumGen.NewChunkHash)
+		}
+
+		// writeChunk records a chunk's checksum and writes it to the file
+		writeChunk := func(result worker.Result) error {
+			if !noChecksum && !addTrailer {
+				if err := file.checksumGen.AddChunkChecksum(result.Offset, result.Checksum); err != nil {
+					return fmt.Errorf("checksum error: %v", err)
+				}
+			}
+			if !noChecksum {
+				if err := file.checksumGen.UpdateOrdered(result.Buffer, result.Offset); err != nil {
+					return fmt.Errorf("checksum error: %v", err)
+				}
+			}
+			if iopsLimiter != nil {
+				if err := iopsLimiter.Wait(ctx, 1); err != nil {
+					return err
+				}
+			}
+			writeStart := time.Now()
+			if err := file.out.WriteAt(result.Buffer, result.Offset); err != nil {
+				return fmt.Errorf("file write error: %w", err)
+			}
+			writeLatency.Record(time.Since(writeStart))
+			atomic.AddInt64(&file.written, int64(len(result.Buffer)))
+			return nil
+		}
+
+		// In the channel pipeline each file has a writer of its own
+		if pipeline == "direct" {
+			file.job.SetSink(writeChunk)
+		} else {
+			wg.Add(1)
+			go func(job *worker.Job) {
+				defer wg.Done()
+				for result := range job.Results() {
+					if result.Err != nil || ctx.Err() != nil {
+						workerPool.ReturnBuffer(result.Buffer)
+						continue
+					}
+					err := writeChunk(result)
+					workerPool.ReturnBuffer(result.Buffer)
+					if err != nil {
+						job.Fail(result.Offset, err)
+					}
+				}
+			}(file.job)
+		}
+
+		// Each file gets its own seed, so the files differ, unless it was
+		// given one
+		fileOpts := genOpts.WithSharedSeed()
+		if file.seed != nil {
+			fileOpts.Seed, fileOpts.Seeded = *file.seed, true
+		}
+		if _, err := file.job.StartWithFactory(func() (generator.Generator, error) {
+			gen, base, err := newOutputGenerator(file.pattern, fileOpts, key)
+			if closer, ok := base.(io.Closer); ok {
+				closers = append(closers, closer)
+			}
+			return gen, err
+		}, file.size); err != nil {
+			progressReporter.Stop()
+			return err
+		}
+	}
+
+	// Wait for every file's job, then for the writers to finish
+	for _, file := range files {
+		if file.job != nil {
+			file.job.Wait()
+		}
+	}
+	wg.Wait()
+
+	progressReporter.Stop()
+	generationTime = time.Since(startTime)
+	runSpan.AddEvent("generation finished", telemetry.Int("trasher.bytes_written", getWritten()))
+
+	// Report every chunk that failed, unless the run was cancelled
+	var failed []string
+	var chunkErrs []worker.ChunkError
+	for _, file := range files {
+		if file.job == nil {
+			continue
+		}
+		fileErrs := file.job.Errors()
+		for _, chunkErr := range fileErrs {
+			failed = append(failed, fmt.Sprintf("%s offset %d: %v", file.name, chunkErr.Offset, chunkErr.Err))
+			chunkErrs = append(chunkErrs, chunkErr)
+		}
+		if len(fileErrs) > 0 && ctx.Err() == nil {
+			file.err = chunksFailed(fileErrs)
+		}
+	}
+	if len(failed) > 0 && ctx.Err() == nil {
+		fmt.Printf("\n%d chunks failed:\n", len(failed))
+		for _, line := range failed {
+			fmt.Printf("  %s\n", line)
+		}
+		shutdownHandler.Stop()
+		return chunksFailed(chunkErrs)
+	}
+
+	if ctx.Err() != nil {
+		return errCancelled
+	}
+
+	// Close the files and write their checksum files and manifests
+	var checksumGens []*checksum.ChecksumGenerator
+	var sizes []int64
+	finishFile := func(file *batchFile) error {
+		if err := file.out.Close(); err != nil {
+			return writeFailed(fmt.Errorf("failed to close %s: %w", file.name, err))
+		}
+		if noChecksum || addTrailer || sumsFile != "" || noMeta {
+			if err := removeChecksumFiles(file.name); err != nil {
+				return err
+			}
+		}
+		if addTrailer {
+			if err := appendTrailer(file.checksumGen, file.name, file.size, file.pattern, file.seed); err != nil {
+				return fmt.Errorf("failed to append trailer to %s: %v", file.name, err)
+			}
+		} else if sumsFile != "" {
+			checksumGens = append(checksumGens, file.checksumGen)
+			sizes = append(sizes, file.size)
+		} else if !noChecksum {
+			if err := file.checksumGen.WriteChecksumFile(); err != nil {
+				return writeFailed(fmt.Errorf("failed to write checksum file for %s: %w", file.name, err))
+			}
+		}
+		if manifest {
+			if err := file.checksumGen.WriteManifest(file.size); err != nil {
+				return fmt.Errorf("failed to write manifest for %s: %v", file.name, err)
+			}
+		}
+		if merkle {
+			if err := file.checksumGen.WriteMerkle(file.size); err != nil {
+				return fmt.Errorf("failed to write Merkle tree for %s: %v", file.name, err)
+			}
+		}
+		if dropCache && sumsFile == "" {
+			writer.DropCache(file.name)
+		}
+		return nil
+	}
+	for _, file := range files {
+		if err := finishFile(file); err != nil {
+			file.err = err
+			return err
+		}
+	}
+	writeMeta = !noChecksum && !addTrailer && !noMeta
+	if sumsFile != "" {
+		if err := checksum.WriteFileSet(sumsFile, sumFormat, checksumGens, sizes); err != nil {
+			return err
+		}
+		if dropCache {
+			for _, file := range files {
+				writer.DropCache(file.name)
+			}
+		}
+	}
+	if !noChecksum && !addTrailer {
+		runSpan.AddEvent("checksums written")
+	}
+	if ctx.Err() != nil {
+		return errCancelled
+	}
+
+	if chunkAuto && verbose {
+		fmt.Printf("\nTuned chunk size: %s\n", progress.FormatBytes(workerPool.ChunkSize()))
+	}
+	if verbose {
+		fmt.Printf("\nGenerated %d files successfully!\n", len(files))
+		for _, file := range files {
+			fmt.Printf("  %s\n", file.name)
+		}
+		if sumsFile != "" {
+			fmt.Printf("Checksum file: %s\n", sumsFile)
+		}
+		printWriteLatency(writeLatency)
+	} else if len(files) == 1 {
+		fmt.Printf("Successfully generated 1 file (%s)\n", files[0].name)
+	} else {
+		fmt.Printf("Successfully generated %d files (%s to %s)\n", len(files), files[0].name, files[len(files)-1].name)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/report"
+)
+
+var (
+	benchThreshold        float64
+	benchFailOnRegression bool
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Work with benchmark summaries",
+	Long: `Bench groups tooling for run summaries written with --summary-json,
+for tracking storage performance across runs, hosts or firmware updates.`,
+}
+
+var benchCompareCmd = &cobra.Command{
+	Use:   "compare <old.json> <new.json>",
+	Short: "Compare two run summaries and report regressions",
+	Long: `Compare loads two summaries written with --summary-json and reports how
+throughput and duration changed. Changes smaller than --threshold percent are
+reported as unchanged.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBenchCompare(args[0], args[1])
+	},
+}
+
+func runBenchCompare(oldPath, newPath string) error {
+	baseline, err := report.Load(oldPath)
+	if err != nil {
+		return err
+	}
+	current, err := report.Load(newPath)
+	if err != nil {
+		return err
+	}
+
+	comparison := report.Compare(baseline, current, benchThreshold)
+
+	for _, warning := range comparison.Warnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+	if len(comparison.Warnings) > 0 {
+		fmt.Println()
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METRIC\tOLD\tNEW\tCHANGE\tVERDICT")
+	for _, m := range comparison.Metrics {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%+.2f%%\t%s\n",
+			m.Name, formatMetric(m.Name, m.Old), formatMetric(m.Name, m.New), m.ChangePercent, m.Verdict)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	regressions := comparison.Regressions()
+	if benchFailOnRegression && len(regressions) > 0 {
+		return fmt.Errorf("%d metrics regressed by more than %.1f%%", len(regressions), benchThreshold)
+	}
+	return nil
+}
+
+// formatMetric renders a metric value in its natural unit.
+func formatMetric(name string, value float64) string {
+	switch name {
+	case "throughput":
+		return progress.FormatThroughput(value)
+	case "duration":
+		return fmt.Sprintf("%.2fs", value)
+	default:
+		return fmt.Sprintf("%.2f", value)
+	}
+}
+
+func init() {
+	benchCompareCmd.Flags().Float64Var(&benchThreshold, "threshold", 5, "Percent change below which a metric counts as unchanged")
+	benchCompareCmd.Flags().BoolVar(&benchFailOnRegression, "fail-on-regression", false, "Exit with an error if any metric regressed")
+	benchCmd.AddCommand(benchCompareCmd)
+	rootCmd.AddCommand(benchCmd)
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/predict"
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/signal"
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/sizeparser"
+)
+
+var (
+	sumPredict   bool
+	sumPattern   string
+	sumSize      string
+	sumChunkSize string
+	sumMode      string
+	sumWorkers   int
+	sumChunks    bool
+	sumQuiet     bool
+	sumVerbose   bool
+)
+
+var checksumCmd = &cobra.Command{
+	Use:   "checksum --predict",
+	Short: "Compute the checksum a generated file will have without writing it",
+	Long: `Checksum --predict generates the data of a file in memory, on a pool of
+workers, and prints the full-file checksum its checksum file will record,
+without touching disk. Expected values can be computed on a build server and
+compared with the checksum files written on the target hardware.
+
+The pattern must generate the same data on every run: a pattern without
+random data, such as sequential or zero, or a seeded one, such as
+random:seed=42. --chunk-size and --checksum-mode must match the run being
+predicted, since tree checksums, chunk checksums and the records of the
+jsonl and csv patterns follow the chunk boundaries. Runs with --magic,
+--encrypt or --pattern-map can't be predicted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !sumPredict {
+			return fmt.Errorf("--predict is required: checksum only predicts the checksums of files not yet generated")
+		}
+		return runPredict()
+	},
+}
+
+func runPredict() error {
+	sizeBytes, err := sizeparser.Parse(sumSize)
+	if err != nil {
+		return fmt.Errorf("invalid size: %v", err)
+	}
+	chunkBytes, err := sizeparser.Parse(sumChunkSize)
+	if err != nil {
+		return fmt.Errorf("invalid chunk size: %v", err)
+	}
+
+	p, err := predict.New(sumPattern, sizeBytes, predict.Options{
+		ChunkSize: chunkBytes,
+		Mode:      sumMode,
+		Workers:   sumWorkers,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, shutdownHandler := signal.WithShutdownHandler(os.Stdout)
+	defer shutdownHandler.Stop()
+
+	progressReporter := progress.NewProgressReporter(p.Bytes(), sumVerbose, os.Stdout)
+	shutdownHandler.SetProgressReporter(progressReporter)
+	progressReporter.Start(p.BytesGenerated)
+
+	result, err := p.Run(ctx)
+	progressReporter.Stop()
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("prediction interrupted after %s", progress.FormatBytes(p.BytesGenerated()))
+		}
+		return err
+	}
+
+	if sumQuiet {
+		fmt.Println(result.Checksum)
+		return nil
+	}
+	fmt.Printf("Predicted %s of %s in %s chunks in %s\n", progress.FormatBytes(sizeBytes), sumPattern,
+		progress.FormatBytes(chunkBytes), progress.FormatDuration(result.Elapsed))
+	fmt.Printf("%s (full file): %s\n", result.Algorithm, result.Checksum)
+	if sumChunks {
+		for _, chunk := range result.Chunks {
+			fmt.Printf("SHA256 (offset %d): %s\n", chunk.Offset, chunk.Checksum)
+		}
+	}
+	return nil
+}
+
+func init() {
+	checksumCmd.Flags().BoolVar(&sumPredict, "predict", false, "Compute the checksums of a file that has not been generated")
+	checksumCmd.Flags().StringVarP(&sumPattern, "pattern", "p", "", "Pattern of the file, with its seed if it has one, e.g. random:seed=42 (required)")
+	checksumCmd.Flags().StringVarP(&sumSize, "size", "s", "", "Size of the file (required)")
+	checksumCmd.Flags().StringVarP(&sumChunkSize, "chunk-size", "c", "64MB", "Chunk size of the run being predicted")
+	checksumCmd.Flags().StringVar(&sumMode, "checksum-mode", checksum.ModeStream, "Checksum mode of the run being predicted: stream, ordered or tree")
+	checksumCmd.Flags().IntVarP(&sumWorkers, "workers", "w", runtime.NumCPU(), "Number of parallel generators")
+	checksumCmd.Flags().BoolVar(&sumChunks, "chunks", false, "Also print the checksum of every chunk")
+	checksumCmd.Flags().BoolVarP(&sumQuiet, "quiet", "q", false, "Print only the full-file checksum")
+	checksumCmd.Flags().BoolVarP(&sumVerbose, "verbose", "v", false, "Show detailed progress")
+	checksumCmd.MarkFlagRequired("pattern")
+	checksumCmd.MarkFlagRequired("size")
+	rootCmd.AddCommand(checksumCmd)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/compare"
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/signal"
+	"github.com/maxkimambo/trasher/pkg/sizeparser"
+)
+
+var (
+	compareRanges    int
+	compareWorkers   int
+	compareChunkSize string
+	compareVerbose   bool
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <a> <b>",
+	Short: "Find where two files differ",
+	Long: `Compare reads two files side by side on a pool of workers and reports the
+ranges of bytes where they differ, with their offsets, such as where two
+supposedly identical disk images diverge.
+
+The first --ranges differing ranges are listed in file order and all of them
+are counted. Bytes past the end of the shorter file count as one range.
+Compare exits non-zero if the files differ.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCompare(args[0], args[1])
+	},
+}
+
+func runCompare(pathA, pathB string) error {
+	chunkBytes, err := sizeparser.Parse(compareChunkSize)
+	if err != nil {
+		return fmt.Errorf("invalid chunk size: %v", err)
+	}
+
+	c, err := compare.New(pathA, pathB, compare.Options{
+		Workers:   compareWorkers,
+		ChunkSize: chunkBytes,
+		MaxRanges: compareRanges,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, shutdownHandler := signal.WithShutdownHandler(os.Stdout)
+	defer shutdownHandler.Stop()
+
+	progressReporter := progress.NewProgressReporter(c.Bytes(), compareVerbose, os.Stdout)
+	shutdownHandler.SetProgressReporter(progressReporter)
+	progressReporter.Start(c.BytesRead)
+
+	result, err := c.Run(ctx)
+	progressReporter.Stop()
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("comparison interrupted after %s", progress.FormatBytes(c.BytesRead()))
+		}
+		return err
+	}
+
+	fmt.Printf("Compared %s in %s, %s\n", progress.FormatBytes(result.BytesRead),
+		progress.FormatDuration(result.Elapsed), progress.FormatThroughput(result.Throughput()))
+	if result.SizeA != result.SizeB {
+		fmt.Printf("Sizes differ: %s is %d bytes, %s is %d bytes\n", pathA, result.SizeA, pathB, result.SizeB)
+	}
+	if result.Identical() {
+		fmt.Println("Files are identical")
+		return nil
+	}
+
+	for _, r := range result.Ranges {
+		fmt.Printf("  offset %d (0x%x): %d bytes\n", r.Offset, r.Offset, r.Length)
+	}
+	if hidden := result.RangeCount - int64(len(result.Ranges)); hidden > 0 {
+		fmt.Printf("  ... and %d more ranges\n", hidden)
+	}
+	return fmt.Errorf("files differ in %d bytes across %d ranges", result.DifferentBytes, result.RangeCount)
+}
+
+func init() {
+	compareCmd.Flags().IntVarP(&compareRanges, "ranges", "n", 20, "Number of differing ranges to list")
+	compareCmd.Flags().IntVarP(&compareWorkers, "workers", "w", runtime.NumCPU(), "Number of parallel readers")
+	compareCmd.Flags().StringVarP(&compareChunkSize, "chunk-size", "c", "4MB", "How much of each file a worker compares at a time")
+	compareCmd.Flags().BoolVarP(&compareVerbose, "verbose", "v", false, "Show detailed progress")
+	rootCmd.AddCommand(compareCmd)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/config"
+)
+
+var (
+	profile string
+	cfgFile string
+)
+
+// applyConfig sets the flags of cmd that the command line leaves unset from
+// the config file: its defaults, overridden by --profile. Config values act
+// as defaults, so they don't count as given for checks such as "--pattern
+// and --pattern-map cannot be used together". Keys for flags that cmd
+// doesn't have are skipped if another command has them.
+func applyConfig(cmd *cobra.Command) error {
+	path := cfgFile
+	if path == "" {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			if profile != "" {
+				return err
+			}
+			return nil
+		}
+	}
+
+	c, err := config.Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Without a config file there are no defaults to apply, but a
+		// file or profile that was asked for must exist
+		if cfgFile != "" || profile != "" {
+			return fmt.Errorf("config file %s not found", path)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	settings, err := c.Settings(profile)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if name == "profile" || name == "config" {
+			return fmt.Errorf("%s: %s can't be set in the config file", path, name)
+		}
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil {
+			if !anyCommandHasFlag(rootCmd, name) {
+				return fmt.Errorf("%s: unknown flag %s", path, name)
+			}
+			continue
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := flag.Value.Set(settings[name]); err != nil {
+			return fmt.Errorf("%s: invalid value %q for %s: %v", path, settings[name], name, err)
+		}
+	}
+	return nil
+}
+
+// anyCommandHasFlag reports whether cmd or any of its subcommands has a flag
+// called name.
+func anyCommandHasFlag(cmd *cobra.Command, name string) bool {
+	if cmd.Flags().Lookup(name) != nil {
+		return true
+	}
+	for _, sub := range cmd.Commands() {
+		if anyCommandHasFlag(sub, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Use the flag values of this profile from the config file")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file with flag defaults and profiles (default: per-user config directory, e.g. ~/.config/trasher/config.yaml)")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return applyConfig(cmd)
+	}
+}
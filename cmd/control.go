@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/control"
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/signal"
+	"github.com/maxkimambo/trasher/internal/worker"
+)
+
+// controlCommands are the commands trasher control sends.
+var controlCommands = []string{control.CommandStatus, control.CommandPause, control.CommandResume, control.CommandCancel}
+
+var controlJSON bool
+
+var controlCmd = &cobra.Command{
+	Use:   "control SOCKET status|pause|resume|cancel",
+	Short: "Check on or steer a run through its control socket",
+	Long: `Control sends a command to a run started with --control-socket and prints
+the run's status after it: status only reports, pause stops workers from
+starting new chunks, resume lets them go on and cancel aborts the run as
+Ctrl-C does.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runControl(args[0], args[1])
+	},
+}
+
+// runControl sends command to the control socket at path and prints the
+// status the run replies with.
+func runControl(path, command string) error {
+	if !slices.Contains(controlCommands, command) {
+		return fmt.Errorf("unknown command %q (available: %s)", command, strings.Join(controlCommands, ", "))
+	}
+
+	client, err := control.Dial(path)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	status, err := client.Do(command)
+	if err != nil {
+		return err
+	}
+	if controlJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	}
+
+	state := "running"
+	if status.Paused {
+		state = "paused"
+	}
+	fmt.Printf("%s (%s): %s\n", status.Output, status.Pattern, state)
+	fmt.Printf("Progress: %.2f%% (%s / %s)\n", status.Percent,
+		progress.FormatBytes(status.WrittenBytes), progress.FormatBytes(status.TotalBytes))
+	fmt.Printf("Throughput: %s | Elapsed: %s | ETA: %s\n", progress.FormatThroughput(status.Throughput),
+		progress.FormatDuration(time.Duration(status.ElapsedSeconds*float64(time.Second))),
+		progress.FormatDuration(time.Duration(status.ETASeconds*float64(time.Second))))
+	return nil
+}
+
+// jobController exposes a running generation to the control socket.
+type jobController struct {
+	output     string
+	pattern    string
+	totalBytes int64
+	startTime  time.Time
+	getWritten func() int64
+	pool       *worker.WorkerPool
+	shutdown   *signal.ShutdownHandler
+}
+
+// Status returns a snapshot of the job's progress.
+func (c *jobController) Status() control.Status {
+	written := c.getWritten()
+	elapsed := time.Since(c.startTime).Seconds()
+
+	status := control.Status{
+		Output:         c.output,
+		Pattern:        c.pattern,
+		TotalBytes:     c.totalBytes,
+		WrittenBytes:   written,
+		ElapsedSeconds: elapsed,
+		Paused:         c.pool.IsPaused(),
+	}
+	if c.totalBytes > 0 {
+		status.Percent = float64(written) / float64(c.totalBytes) * 100
+	}
+	if elapsed > 0 {
+		status.Throughput = float64(written) / elapsed
+	}
+	if status.Throughput > 0 && written < c.totalBytes {
+		status.ETASeconds = float64(c.totalBytes-written) / status.Throughput
+	}
+	return status
+}
+
+// Pause stops workers from starting new chunks.
+func (c *jobController) Pause() error {
+	c.pool.Pause()
+	return nil
+}
+
+// Resume continues a paused job.
+func (c *jobController) Resume() error {
+	c.pool.Resume()
+	return nil
+}
+
+// Cancel aborts the job as if it had received SIGINT.
+func (c *jobController) Cancel() error {
+	c.shutdown.Stop()
+	return nil
+}
+
+func init() {
+	controlCmd.Flags().BoolVar(&controlJSON, "json", false, "Print the status as JSON, as the socket returns it")
+	rootCmd.AddCommand(controlCmd)
+}
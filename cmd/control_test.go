@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/maxkimambo/trasher/internal/control"
+)
+
+// testController is a control.Controller recording the commands it gets.
+type testController struct {
+	mu        sync.Mutex
+	paused    bool
+	cancelled bool
+}
+
+func (c *testController) Status() control.Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return control.Status{Output: "test.dat", Pattern: "random", TotalBytes: 100, WrittenBytes: 25, Percent: 25, Paused: c.paused}
+}
+
+func (c *testController) Pause() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = true
+	return nil
+}
+
+func (c *testController) Resume() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = false
+	return nil
+}
+
+func (c *testController) Cancel() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancelled = true
+	return nil
+}
+
+func TestRunControl(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+	job := &testController{}
+	server, err := control.Listen(path, job)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer server.Close()
+
+	if err := runControl(path, "pause"); err != nil {
+		t.Fatalf("pause failed: %v", err)
+	}
+	if !job.Status().Paused {
+		t.Error("expected the job to be paused")
+	}
+	if err := runControl(path, "resume"); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if job.Status().Paused {
+		t.Error("expected the job to be resumed")
+	}
+	if err := runControl(path, "cancel"); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	job.mu.Lock()
+	cancelled := job.cancelled
+	job.mu.Unlock()
+	if !cancelled {
+		t.Error("expected the job to be cancelled")
+	}
+
+	if err := runControl(path, "stop"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("expected an unknown command to be rejected, got %v", err)
+	}
+	if err := runControl(filepath.Join(t.TempDir(), "missing.sock"), "status"); err == nil {
+		t.Error("expected an error for a missing socket")
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/maxkimambo/trasher/internal/diskspace"
+	"github.com/maxkimambo/trasher/internal/worker"
+)
+
+// Exit codes, so scripts can tell why a run failed. Any other error, such as
+// an invalid flag, exits with 1.
+const (
+	// exitMismatch means verify found data that doesn't match its checksums.
+	exitMismatch = 2
+	// exitMissingChecksum means verify found no checksums to compare with.
+	exitMissingChecksum = 3
+	// exitReadError means verify couldn't read the data.
+	exitReadError = 4
+	// exitValidation means a pre-flight check rejected the run before
+	// anything was written.
+	exitValidation = 5
+	// exitNoSpace means the target doesn't have room for the data, found
+	// before the run or while writing.
+	exitNoSpace = 6
+	// exitWriteError means writing the output failed.
+	exitWriteError = 7
+	// exitCancelled means the run was stopped by a signal, following the
+	// shell convention of 128 plus SIGINT.
+	exitCancelled = 130
+)
+
+// exitError is an error that makes the process exit with code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// errCancelled is the error of a run stopped by a signal.
+var errCancelled = &exitError{code: exitCancelled, err: errors.New("operation cancelled")}
+
+// exitCode returns the code trasher exits with after err.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return 1
+}
+
+// validationFailed gives a pre-flight check's error its exit code: running
+// out of space, or failing validation.
+func validationFailed(err error) error {
+	code := exitValidation
+	if noSpace(err) {
+		code = exitNoSpace
+	}
+	return &exitError{code: code, err: fmt.Errorf("validation failed: %v", err)}
+}
+
+// writeFailed gives an error writing the output its exit code: running out
+// of space, or failing to write.
+func writeFailed(err error) error {
+	code := exitWriteError
+	if noSpace(err) {
+		code = exitNoSpace
+	}
+	return &exitError{code: code, err: err}
+}
+
+// chunksFailed is the error of a run some chunks of which failed to write.
+// It counts as running out of space if any chunk did.
+func chunksFailed(chunkErrs []worker.ChunkError) error {
+	err := fmt.Errorf("%d chunks failed", len(chunkErrs))
+	for _, chunkErr := range chunkErrs {
+		if noSpace(chunkErr.Err) {
+			return &exitError{code: exitNoSpace, err: err}
+		}
+	}
+	return &exitError{code: exitWriteError, err: err}
+}
+
+// noSpace reports whether err comes from running out of space.
+func noSpace(err error) bool {
+	return errors.Is(err, diskspace.ErrInsufficient) || errors.Is(err, syscall.ENOSPC)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/fill"
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/signal"
+	"github.com/maxkimambo/trasher/pkg/sizeparser"
+)
+
+var (
+	fillTarget    string
+	fillLeaveFree string
+	fillFileSize  string
+	fillPattern   string
+	fillChunkSize string
+	fillWorkers   int
+	fillVerbose   bool
+)
+
+var fillCmd = &cobra.Command{
+	Use:   "fill --target DIR",
+	Short: "Write files until a filesystem is down to a given free space",
+	Long: `Fill writes files named trasher-fill-NNNN.dat into the target directory
+until the free space on its filesystem drops to --leave-free, to test alerting
+and how services behave under disk pressure. Free space is checked before
+every chunk, so space taken by other programs while the fill runs counts
+too.
+
+Without --leave-free, or if something else takes the remaining space first,
+the fill writes until the filesystem is full and stops there without
+failing. The files are kept; remove them to free the space again.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runFill()
+	},
+}
+
+func runFill() error {
+	var err error
+	var leaveFree int64
+	if fillLeaveFree != "" {
+		if leaveFree, err = sizeparser.Parse(fillLeaveFree); err != nil {
+			return fmt.Errorf("invalid --leave-free: %v", err)
+		}
+	}
+	fileSize, err := sizeparser.Parse(fillFileSize)
+	if err != nil {
+		return fmt.Errorf("invalid file size: %v", err)
+	}
+	chunkBytes, err := sizeparser.Parse(fillChunkSize)
+	if err != nil {
+		return fmt.Errorf("invalid chunk size: %v", err)
+	}
+
+	f, err := fill.New(fillTarget, fill.Options{
+		LeaveFree: leaveFree,
+		FileSize:  fileSize,
+		ChunkSize: chunkBytes,
+		Pattern:   fillPattern,
+		Workers:   fillWorkers,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Filling %s, about %s to write\n", fillTarget, progress.FormatBytes(f.Bytes()))
+
+	ctx, shutdownHandler := signal.WithShutdownHandler(os.Stdout)
+	defer shutdownHandler.Stop()
+
+	progressReporter := progress.NewProgressReporter(f.Bytes(), fillVerbose, os.Stdout)
+	shutdownHandler.SetProgressReporter(progressReporter)
+	progressReporter.Start(f.BytesWritten)
+
+	result, err := f.Run(ctx)
+	progressReporter.Stop()
+	if err != nil {
+		if ctx.Err() != nil {
+			return &exitError{code: exitCancelled, err: err}
+		}
+		return writeFailed(err)
+	}
+
+	fmt.Printf("Wrote %s in %d files in %s (%s/s), stopped because %s\n",
+		progress.FormatBytes(result.Written), len(result.Files), progress.FormatDuration(result.Elapsed),
+		progress.FormatBytes(int64(result.Throughput())), result.Reason)
+	fmt.Printf("Free space left: %s\n", progress.FormatBytes(result.Available))
+	if fillVerbose {
+		for _, path := range result.Files {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+	return nil
+}
+
+func init() {
+	fillCmd.Flags().StringVar(&fillTarget, "target", "", "Directory to fill (required)")
+	fillCmd.Flags().StringVar(&fillLeaveFree, "leave-free", "", "Free space to leave on the filesystem, e.g. 20GB; without it the filesystem is filled completely")
+	fillCmd.Flags().StringVar(&fillFileSize, "file-size", "1GB", "Size of each file written")
+	fillCmd.Flags().StringVarP(&fillPattern, "pattern", "p", "random", "Data pattern to write")
+	fillCmd.Flags().StringVarP(&fillChunkSize, "chunk-size", "c", "4MB", "Size of each chunk generated and written")
+	fillCmd.Flags().IntVarP(&fillWorkers, "workers", "w", runtime.NumCPU(), "Number of parallel generators")
+	fillCmd.Flags().BoolVarP(&fillVerbose, "verbose", "v", false, "Show detailed progress and list the files written")
+	fillCmd.MarkFlagRequired("target")
+	rootCmd.AddCommand(fillCmd)
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/device"
+	"github.com/maxkimambo/trasher/internal/diskspace"
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/report"
+	"github.com/maxkimambo/trasher/internal/writer"
+)
+
+var (
+	historyFile   string
+	historyDevice string
+	historyRuns   int
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show throughput trends of past runs per device",
+	Long: `History prints the throughput of past runs grouped by host and device,
+oldest first, with each run compared against the device's average. A latest
+run well below the average can point at gradually degrading storage.
+
+Every generation run is recorded unless --no-history is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runHistory()
+	},
+}
+
+func runHistory() error {
+	path, err := resolveHistoryPath(historyFile)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Println("No runs recorded yet")
+		return nil
+	}
+
+	entries, err := report.LoadHistory(path)
+	if err != nil {
+		return err
+	}
+
+	trends := report.Trends(entries)
+	printed := 0
+	for _, trend := range trends {
+		if historyDevice != "" && trend.Device != historyDevice {
+			continue
+		}
+		if printed > 0 {
+			fmt.Println()
+		}
+		printed++
+
+		fmt.Printf("%s on %s: %d runs, average %s, best %s, latest %s (%+.1f%% vs average)\n",
+			trend.Device, trend.Host, len(trend.Runs),
+			progress.FormatThroughput(trend.Average), progress.FormatThroughput(trend.Best),
+			progress.FormatThroughput(trend.Latest), trend.LatestChangePercent())
+
+		runs := trend.Runs
+		if historyRuns > 0 && len(runs) > historyRuns {
+			runs = runs[len(runs)-historyRuns:]
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  STARTED\tSIZE\tPATTERN\tTHROUGHPUT\tVS AVERAGE")
+		for _, run := range runs {
+			var change float64
+			if trend.Average > 0 {
+				change = (run.Throughput - trend.Average) / trend.Average * 100
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%+.1f%%\n",
+				run.StartedAt.Local().Format("2006-01-02 15:04"),
+				progress.FormatBytes(run.SizeBytes),
+				run.Pattern,
+				progress.FormatThroughput(run.Throughput),
+				change)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if printed == 0 {
+		fmt.Println("No completed runs recorded")
+	}
+	return nil
+}
+
+// appendHistory records a finished run in the history file.
+func appendHistory(s *report.Summary) error {
+	path, err := resolveHistoryPath(historyLog)
+	if err != nil {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	return report.AppendHistory(path, &report.HistoryEntry{
+		Summary: *s,
+		Host:    host,
+		Device:  storageDevice(s.Output),
+	})
+}
+
+// resolveHistoryPath returns path, or the default history file if it's empty.
+func resolveHistoryPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	return report.DefaultHistoryPath()
+}
+
+// storageDevice names the storage an output was written to: the block device
+// itself, the mount point of the volume holding the file, or the network
+// target or object data was sent to.
+func storageDevice(output string) string {
+	if writer.IsNetworkTarget(output) || writer.IsObjectTarget(output) {
+		return output
+	}
+	if abs, err := filepath.Abs(output); err == nil {
+		output = abs
+	}
+	if device.IsBlockDevice(output) {
+		return output
+	}
+	if info, err := diskspace.Query(filepath.Dir(output)); err == nil {
+		return info.Volume
+	}
+	return filepath.Dir(output)
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&historyFile, "file", "", "History file to read (default: per-user config directory)")
+	historyCmd.Flags().StringVar(&historyDevice, "device", "", "Only show runs against this device or mount point")
+	historyCmd.Flags().IntVar(&historyRuns, "runs", 10, "Number of most recent runs to list per device (0 for all)")
+	rootCmd.AddCommand(historyCmd)
+}
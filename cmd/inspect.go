@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/signal"
+	"github.com/maxkimambo/trasher/internal/trailer"
+)
+
+var (
+	inspectNoVerify bool
+	inspectWorkers  int
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <file>",
+	Short: "Identify and verify a file generated with --trailer",
+	Long: `Inspect reads the trailer appended to a file generated with --trailer
+and prints how the file was generated: its size, pattern, seed and digest.
+It then hashes the data before the trailer and compares it against the
+recorded digest, so the file is verified without a checksum file. The data
+is read by several workers in parallel and hashed in order.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runInspect(args[0])
+	},
+}
+
+func runInspect(path string) error {
+	t, err := trailer.Read(path)
+	if errors.Is(err, trailer.ErrNoTrailer) {
+		return fmt.Errorf("%s has no trailer (was it generated with --trailer?)", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("File:      %s\n", path)
+	fmt.Printf("Generator: %s\n", t.Generator)
+	fmt.Printf("Created:   %s\n", t.CreatedAt.Local().Format(time.RFC3339))
+	fmt.Printf("Size:      %s (%d bytes)\n", progress.FormatBytes(t.Size), t.Size)
+	fmt.Printf("Pattern:   %s\n", t.Pattern)
+	if t.Seed != nil {
+		fmt.Printf("Seed:      %d\n", *t.Seed)
+	}
+	fmt.Printf("%-10s %s\n", t.Algorithm+":", t.Digest)
+
+	if inspectNoVerify {
+		return nil
+	}
+	ctx, shutdownHandler := signal.WithShutdownHandler(os.Stdout)
+	defer shutdownHandler.Stop()
+
+	digest, err := t.DataDigest(ctx, path, inspectWorkers)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("inspection interrupted")
+		}
+		return err
+	}
+	if digest != t.Digest {
+		return fmt.Errorf("data does not match the trailer digest (got %s)", digest)
+	}
+	fmt.Println("OK")
+	return nil
+}
+
+func init() {
+	inspectCmd.Flags().BoolVar(&inspectNoVerify, "no-verify", false, "Only print the trailer, without reading the data")
+	inspectCmd.Flags().IntVarP(&inspectWorkers, "workers", "w", runtime.NumCPU(), "Number of parallel readers")
+	rootCmd.AddCommand(inspectCmd)
+}
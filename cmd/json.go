@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/maxkimambo/trasher/internal/report"
+)
+
+var (
+	jsonOut bool
+	// lastSummary is the summary of the last generation run, for --json
+	// and repeated runs.
+	lastSummary *report.Summary
+)
+
+// jsonResult is the document --json prints: the run's summary and the exit
+// code trasher exits with.
+type jsonResult struct {
+	*report.Summary
+	ExitCode int `json:"exit_code"`
+}
+
+// runJSON runs run with its human-readable output discarded and prints the
+// run's summary as a single JSON document on stdout instead, for test
+// harnesses. Errors and warnings still go to stderr. A run that fails before
+// it starts writing gets a summary holding just the error.
+func runJSON(run func() error) error {
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", os.DevNull, err)
+	}
+	os.Stdout = devNull
+	lastSummary = nil
+
+	err = run()
+	os.Stdout = stdout
+	devNull.Close()
+
+	s := lastSummary
+	if s == nil {
+		s = &report.Summary{Version: version, Output: output, Pattern: pattern}
+		s.Finalize(0, 0, err)
+	} else if err != nil && s.Error == "" {
+		// The run failed after its summary was taken, e.g. writing it
+		s.Status = "failed"
+		s.Error = err.Error()
+	}
+
+	data, encErr := json.MarshalIndent(jsonResult{Summary: s, ExitCode: exitCode(err)}, "", "  ")
+	if encErr != nil {
+		return fmt.Errorf("failed to encode summary: %v", encErr)
+	}
+	fmt.Fprintln(stdout, string(data))
+	return err
+}
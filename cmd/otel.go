@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/maxkimambo/trasher/internal/report"
+	"github.com/maxkimambo/trasher/internal/telemetry"
+)
+
+var (
+	otelURL string
+	// otelExporter sends traces and metrics of generation runs to the
+	// collector at --otel-endpoint; it is nil, exporting nothing, without it
+	otelExporter *telemetry.Exporter
+	// runSpan is the span of the generation run in progress, which records
+	// the run's phases as events
+	runSpan *telemetry.Span
+)
+
+// startExporter creates the exporter for --otel-endpoint, if it is set.
+func startExporter() error {
+	if otelURL == "" {
+		return nil
+	}
+	exporter, err := telemetry.NewExporter(otelURL, version)
+	if err != nil {
+		return err
+	}
+	otelExporter = exporter
+	return nil
+}
+
+// traceRun starts the span of a generation run writing output with pattern.
+// The function it returns ends the span with the run's error and the
+// figures of the summary the run recorded, and exports it.
+func traceRun(output, pattern string) func(err error) {
+	span := otelExporter.StartSpan("trasher.generate",
+		telemetry.String("trasher.output", output),
+		telemetry.String("trasher.pattern", pattern))
+	runSpan = span
+	before := lastSummary
+
+	return func(err error) {
+		runSpan = nil
+		if s := lastSummary; s != nil && s != before {
+			span.SetAttributes(summaryAttributes(s)...)
+		}
+		if exportErr := span.End(context.Background(), err); exportErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", exportErr)
+		} else if span != nil && verbose {
+			fmt.Printf("Trace ID: %s\n", span.TraceID())
+		}
+	}
+}
+
+// stopMeter stops exporting the metrics of a run, warning if an export
+// failed.
+func stopMeter(meter *telemetry.Meter) {
+	if err := meter.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// summaryAttributes describes the outcome of a run as span attributes.
+func summaryAttributes(s *report.Summary) []telemetry.Attribute {
+	attrs := []telemetry.Attribute{
+		telemetry.String("trasher.status", s.Status),
+		telemetry.Int("trasher.size_bytes", s.SizeBytes),
+		telemetry.Int("trasher.bytes_written", s.BytesWritten),
+		telemetry.Int("trasher.workers", int64(s.Workers)),
+		telemetry.Int("trasher.chunk_size", s.ChunkSize),
+		telemetry.Float("trasher.duration_seconds", s.DurationSeconds),
+		telemetry.Float("trasher.throughput", s.Throughput),
+		telemetry.Float("trasher.peak_throughput", s.PeakThroughput),
+	}
+	if s.Seed != nil {
+		attrs = append(attrs, telemetry.Int("trasher.seed", *s.Seed))
+	}
+	if s.Checksum != "" {
+		attrs = append(attrs, telemetry.String("trasher.checksum", s.Algorithm+":"+s.Checksum))
+	}
+	if l := s.WriteLatency; l != nil {
+		attrs = append(attrs,
+			telemetry.Int("trasher.writes", int64(l.Writes)),
+			telemetry.Float("trasher.write_latency.p50_seconds", l.P50Seconds),
+			telemetry.Float("trasher.write_latency.p99_seconds", l.P99Seconds),
+			telemetry.Float("trasher.write_latency.max_seconds", l.MaxSeconds))
+	}
+	return attrs
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/repair"
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+var (
+	repairPattern  string
+	repairDryRun   bool
+	repairManifest string
+)
+
+var repairCmd = &cobra.Command{
+	Use:   "repair <file>",
+	Short: "Regenerate corrupted chunks of a generated file in place",
+	Long: `Repair verifies each chunk of a file against the chunk checksums in its
+.checksum.txt file and regenerates only the corrupted chunks in place.
+
+This works for patterns whose data depends only on the file offset, so the
+original bytes can be reproduced. The pattern must match the one the file
+was generated with; regenerated chunks that don't match their recorded
+checksum are reported and left untouched. With --manifest, the chunks and
+their checksums are read from a chunk manifest instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRepair(args[0])
+	},
+}
+
+func runRepair(path string) error {
+	gen, err := generator.NewGenerator(repairPattern)
+	if err != nil {
+		return err
+	}
+
+	offsetGen, ok := gen.(generator.OffsetGenerator)
+	if !ok {
+		return fmt.Errorf("pattern %s is not deterministic and cannot be used for repair", repairPattern)
+	}
+
+	var result *repair.Result
+	if repairManifest != "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("cannot access %s: %v", path, err)
+		}
+		chunks, err := checksum.LoadManifest(repairManifest, info.Size())
+		if err != nil {
+			return fmt.Errorf("failed to load manifest: %v", err)
+		}
+		result, err = repair.RepairChunks(path, chunks, offsetGen, repairDryRun)
+		if err != nil {
+			return err
+		}
+	} else {
+		result, err = repair.Repair(path, offsetGen, repairDryRun)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Checked %d chunks, %d corrupted\n", result.Checked, len(result.Corrupted))
+	for _, chunk := range result.Corrupted {
+		fmt.Printf("  corrupted: offset %d, %d bytes\n", chunk.Offset, chunk.Size)
+	}
+
+	if repairDryRun {
+		if !result.Clean() {
+			return fmt.Errorf("%d corrupted chunks found", len(result.Corrupted))
+		}
+		return nil
+	}
+
+	fmt.Printf("Repaired %d chunks\n", len(result.Repaired))
+	if len(result.Unrepairable) > 0 {
+		for _, chunk := range result.Unrepairable {
+			fmt.Printf("  unrepairable: offset %d, %d bytes\n", chunk.Offset, chunk.Size)
+		}
+		return fmt.Errorf("%d chunks could not be repaired (was the file generated with pattern %s?)",
+			len(result.Unrepairable), repairPattern)
+	}
+
+	return nil
+}
+
+func init() {
+	repairCmd.Flags().StringVarP(&repairPattern, "pattern", "p", "", "Pattern the file was generated with (required)")
+	repairCmd.Flags().BoolVar(&repairDryRun, "dry-run", false, "Report corrupted chunks without rewriting them")
+	repairCmd.Flags().StringVar(&repairManifest, "manifest", "", "Chunk manifest (.manifest.json) to repair against instead of the checksum file")
+	repairCmd.MarkFlagRequired("pattern")
+	rootCmd.AddCommand(repairCmd)
+}
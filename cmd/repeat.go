@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/burnin"
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/signal"
+	"github.com/maxkimambo/trasher/internal/verify"
+)
+
+var (
+	repeat     int
+	loopFor    time.Duration
+	verifyEach bool
+	deleteEach bool
+	cycle      bool
+)
+
+// generate writes the output of an iteration. Tests replace it to tamper
+// with the output before it is verified.
+var generate = runTrasher
+
+// repeating reports whether the run is repeated with --repeat, --loop or
+// --cycle.
+func repeating() bool {
+	return repeat > 0 || loopFor > 0 || cycle
+}
+
+// runRepeated generates the output over and over for --repeat and --loop,
+// reading it back after each iteration with --verify-each and deleting it
+// with --delete-each, and prints statistics over all iterations. Corrupted
+// iterations are counted and the test goes on; any other error ends it.
+// --cycle verifies and deletes every iteration, called a cycle, and stops
+// at the first that finds corrupted data.
+func runRepeated() error {
+	label := "Iteration"
+	if cycle {
+		label = "Cycle"
+	}
+
+	var stats burnin.Stats
+	start := time.Now()
+	basePattern, baseForce := pattern, force
+	defer func() {
+		pattern, force = basePattern, baseForce
+	}()
+
+	var err error
+	for n := 1; repeat == 0 || n <= repeat; n++ {
+		if loopFor > 0 && time.Since(start) >= loopFor {
+			break
+		}
+		// A run replaces --pattern with --pattern-map for its reports
+		pattern = basePattern
+		var it burnin.Iteration
+		if it, err = runIteration(label, n); err != nil {
+			break
+		}
+		stats.Add(it)
+		printIteration(label, it)
+		if cycle && it.Corrupted > 0 {
+			break
+		}
+		// Later iterations overwrite the output this test wrote
+		force = true
+	}
+
+	printStats(strings.ToLower(label)+"s", &stats, time.Since(start))
+	if err != nil {
+		return err
+	}
+	if cycle && stats.Corrupted > 0 {
+		return &exitError{code: exitMismatch, err: fmt.Errorf("cycle %d found corrupted data", stats.Iterations)}
+	}
+	if stats.Corrupted > 0 {
+		return &exitError{code: exitMismatch, err: fmt.Errorf("%d of %d iterations found corrupted data", stats.Corrupted, stats.Iterations)}
+	}
+	return nil
+}
+
+// runIteration generates the output once, then verifies and deletes it as
+// asked. Corrupted output is kept for inspection instead of being deleted.
+func runIteration(label string, n int) (burnin.Iteration, error) {
+	it := burnin.Iteration{Number: n}
+	if repeat > 0 {
+		fmt.Printf("%s %d of %d\n", label, n, repeat)
+	} else {
+		fmt.Printf("%s %d\n", label, n)
+	}
+
+	lastSummary = nil
+	if err := generate(); err != nil {
+		return it, fmt.Errorf("%s %d: %w", strings.ToLower(label), n, err)
+	}
+	if s := lastSummary; s != nil {
+		it.Written = s.BytesWritten
+		it.WriteTime = time.Duration(s.DurationSeconds * float64(time.Second))
+	}
+
+	if verifyEach {
+		result, err := verifyOutput(output)
+		if err != nil {
+			return it, fmt.Errorf("%s %d: %w", strings.ToLower(label), n, err)
+		}
+		it.Verified = true
+		it.VerifyRead = result.BytesRead
+		it.VerifyTime = result.Elapsed
+		it.Corrupted = len(result.Corrupted)
+		for _, chunk := range result.Corrupted {
+			fmt.Printf("  corrupted: offset %d, %d bytes\n", chunk.Offset, chunk.Size)
+		}
+	}
+
+	if deleteEach && it.Corrupted > 0 {
+		fmt.Printf("Kept %s for inspection\n", output)
+	} else if deleteEach {
+		if err := removeChecksumFiles(output); err != nil {
+			return it, err
+		}
+		if err := os.Remove(output); err != nil {
+			return it, fmt.Errorf("failed to delete %s: %v", output, err)
+		}
+	}
+	return it, nil
+}
+
+// verifyOutput reads path back and checks it against its checksum file.
+func verifyOutput(path string) (*verify.Result, error) {
+	v, err := verify.New(path, verify.Options{Workers: workers})
+	if err != nil {
+		return nil, verifyError(err)
+	}
+
+	ctx, shutdownHandler := signal.WithShutdownHandler(os.Stdout)
+	defer shutdownHandler.Stop()
+
+	progressReporter := progress.NewProgressReporter(v.Bytes(), verbose, os.Stdout)
+	shutdownHandler.SetProgressReporter(progressReporter)
+	progressReporter.Start(v.BytesRead)
+
+	result, err := v.Run(ctx)
+	progressReporter.Stop()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errCancelled
+		}
+		return nil, verifyError(err)
+	}
+	return result, nil
+}
+
+// printIteration prints the result of an iteration, called label.
+func printIteration(label string, it burnin.Iteration) {
+	fmt.Printf("%s %d: wrote %s in %s (%s)", label, it.Number, progress.FormatBytes(it.Written),
+		progress.FormatDuration(it.WriteTime), progress.FormatThroughput(it.WriteThroughput()))
+	if it.Verified {
+		fmt.Printf(", verified in %s (%s)", progress.FormatDuration(it.VerifyTime),
+			progress.FormatThroughput(it.VerifyThroughput()))
+		if it.Corrupted > 0 {
+			fmt.Printf(": %d corrupted chunks", it.Corrupted)
+		} else {
+			fmt.Print(": OK")
+		}
+	}
+	fmt.Println()
+}
+
+// printStats prints the statistics of all iterations, called plural, of a
+// test that ran for elapsed.
+func printStats(plural string, stats *burnin.Stats, elapsed time.Duration) {
+	fmt.Printf("\n%d %s in %s\n", stats.Iterations, plural, progress.FormatDuration(elapsed))
+	if stats.Iterations == 0 {
+		return
+	}
+	fmt.Printf("Written: %s, %s average, %s slowest, %s fastest\n", progress.FormatBytes(stats.Written),
+		progress.FormatThroughput(stats.WriteThroughput()), progress.FormatThroughput(stats.MinWrite),
+		progress.FormatThroughput(stats.MaxWrite))
+	if stats.VerifyRead > 0 {
+		fmt.Printf("Verified: %s, %s average, %d %s corrupted\n", progress.FormatBytes(stats.VerifyRead),
+			progress.FormatThroughput(stats.VerifyThroughput()), stats.Corrupted, plural)
+	}
+}
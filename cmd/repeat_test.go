@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCycleKeepsCorruptedOutput(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "cycle.dat")
+
+	savedOutput, savedSize, savedChunk, savedHistory := output, size, chunkSize, noHistory
+	savedVerify, savedDelete, savedCycle := verifyEach, deleteEach, cycle
+	t.Cleanup(func() {
+		output, size, chunkSize, noHistory = savedOutput, savedSize, savedChunk, savedHistory
+		verifyEach, deleteEach, cycle = savedVerify, savedDelete, savedCycle
+		generate = runTrasher
+	})
+	output, size, chunkSize, noHistory = path, "4MB", "1MB", true
+	verifyEach, deleteEach, cycle = true, true, true
+
+	// Flip a byte between writing the file and reading it back
+	generate = func() error {
+		if err := runTrasher(); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		b := make([]byte, 1)
+		if _, err := f.ReadAt(b, 2<<20); err != nil {
+			return err
+		}
+		b[0] ^= 0xff
+		_, err = f.WriteAt(b, 2<<20)
+		return err
+	}
+
+	it, err := runIteration("Cycle", 1)
+	if err != nil {
+		t.Fatalf("runIteration failed: %v", err)
+	}
+	if it.Corrupted != 1 {
+		t.Errorf("expected 1 corrupted chunk, got %d", it.Corrupted)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the corrupted output to be kept: %v", err)
+	}
+
+	// An intact iteration is deleted as before
+	generate = runTrasher
+	force = true
+	defer func() { force = false }()
+	if it, err = runIteration("Cycle", 2); err != nil {
+		t.Fatalf("runIteration failed: %v", err)
+	}
+	if it.Corrupted != 0 {
+		t.Errorf("expected no corrupted chunks, got %d", it.Corrupted)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the intact output to be deleted, got %v", err)
+	}
+}
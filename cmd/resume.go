@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/lock"
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/resume"
+	"github.com/maxkimambo/trasher/internal/signal"
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/generator"
+	"github.com/maxkimambo/trasher/pkg/sizeparser"
+)
+
+var (
+	resumeWorkers int
+	resumeVerbose bool
+	// generateCmd is the root command, whose flags journalFlags records. It
+	// is set in init, since the root command's run refers to journalFlags.
+	generateCmd *cobra.Command
+)
+
+// journaledFlags are the flags that shape a run's data, recorded in its
+// journal when they were set so a resumed run generates the same data.
+var journaledFlags = []string{
+	"pattern-map", "pattern-bytes", "mixed-chunk", "mixed-phase", "mixed-random-ratio",
+	"compress-ratio", "dedup-ratio", "dedup-block", "entropy", "schema", "log-format",
+	"corpus", "generator-cmd", "magic", "encrypt", "checksum-mode", "checksum-format",
+}
+
+// keyFingerprint is the journal entry recording the fingerprint of the
+// --encrypt-key a run was given. The key itself is secret and left out, so
+// resume must be given it again, and checks it against the fingerprint.
+const keyFingerprint = "encrypt-key-fingerprint"
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <file.manifest.json>",
+	Short: "Complete a file whose generation was interrupted",
+	Long: `Resume completes a file a run with --manifest didn't finish because it was
+interrupted or failed. Such a run leaves a journal in place of the manifest,
+listing the chunks it wrote and the settings it generated them with; resume
+generates only the chunks missing from the journal, writes them in place and
+then writes the checksum file and a complete manifest.
+
+A run encrypted with --encrypt-key is resumed with the same --encrypt-key;
+the journal records only a fingerprint of the key, to check it against.
+
+The checksums of the chunks already written are taken from the journal, not
+read back. Patterns whose data depends only on the offset, such as
+sequential or a seeded random pattern, resume to the same bytes an
+uninterrupted run would have written; other patterns fill the missing chunks
+with fresh data of the same kind.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runResume(args[0])
+	},
+}
+
+// journalFlags returns the data-shaping flags of the current run, with the
+// pattern it resolved to and the chunk size it ended with, for its journal.
+func journalFlags(chunkSize int64) map[string]string {
+	flags := map[string]string{"chunk-size": fmt.Sprintf("%dB", chunkSize)}
+	// A pattern map replaces the pattern in the run's reports
+	if patternMap == "" {
+		flags["pattern"] = pattern
+	}
+	for _, name := range journaledFlags {
+		if flag := generateCmd.Flags().Lookup(name); flag != nil && flag.Changed {
+			flags[name] = flag.Value.String()
+		}
+	}
+	if encryptKey != "" {
+		if key, err := generator.ParseEncryptKey(encryptKey); err == nil {
+			flags[keyFingerprint] = generator.KeyFingerprint(key)
+		}
+	}
+	return flags
+}
+
+func runResume(manifestPath string) error {
+	r, err := resume.Load(manifestPath)
+	if err != nil {
+		return err
+	}
+	journal := r.Journal()
+
+	// Generate the missing data with the interrupted run's settings
+	for name, value := range journal.Flags {
+		if name == "chunk-size" || name == keyFingerprint {
+			continue
+		}
+		flag := rootCmd.Flags().Lookup(name)
+		if flag == nil {
+			return fmt.Errorf("%s: unknown flag %s", manifestPath, name)
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("%s: invalid value %q for %s: %v", manifestPath, value, name, err)
+		}
+	}
+	if err := checkResumeKey(r.Path(), journal.Flags[keyFingerprint]); err != nil {
+		return err
+	}
+	var chunkBytes int64
+	if value, ok := journal.Flags["chunk-size"]; ok {
+		if chunkBytes, err = sizeparser.Parse(value); err != nil {
+			return fmt.Errorf("%s: invalid chunk size: %v", manifestPath, err)
+		}
+	}
+
+	genOpts, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+	genOpts = genOpts.WithSharedSeed()
+	key, err := encryptionKey()
+	if err != nil {
+		return err
+	}
+	newGen := func() (generator.Generator, error) {
+		gen, _, err := newOutputGenerator(pattern, genOpts, key)
+		return gen, err
+	}
+	gen, err := newGen()
+	if err != nil {
+		return fmt.Errorf("failed to create generator: %v", err)
+	}
+	if closer, ok := gen.(io.Closer); ok {
+		closer.Close()
+	}
+
+	if !noLock {
+		targetLock, err := lock.Acquire(r.Path())
+		if err != nil {
+			return err
+		}
+		defer targetLock.Release()
+	}
+
+	fmt.Printf("Resuming %s: %s of %s missing\n", r.Path(),
+		progress.FormatBytes(r.Bytes()), progress.FormatBytes(journal.Size))
+
+	ctx, shutdownHandler := signal.WithShutdownHandler(os.Stdout)
+	defer shutdownHandler.Stop()
+
+	progressReporter := progress.NewProgressReporter(r.Bytes(), resumeVerbose, os.Stdout)
+	shutdownHandler.SetProgressReporter(progressReporter)
+	progressReporter.Start(r.BytesWritten)
+
+	result, err := r.Run(ctx, newGen, resume.Options{
+		ChunkSize: chunkBytes,
+		Workers:   resumeWorkers,
+		Mode:      hashMode,
+		Format:    sumFormat,
+	})
+	progressReporter.Stop()
+	if err != nil {
+		if ctx.Err() != nil {
+			return &exitError{code: exitCancelled, err: fmt.Errorf("resume interrupted; run it again to carry on: %v", err)}
+		}
+		return writeFailed(err)
+	}
+
+	fmt.Printf("Completed %s: wrote %d missing chunks (%s) in %s (%s), kept %d\n", r.Path(),
+		result.Generated, progress.FormatBytes(result.Written), progress.FormatDuration(result.Elapsed),
+		progress.FormatThroughput(result.Throughput()), result.Restored)
+	if resumeVerbose {
+		fmt.Printf("Checksum file: %s.checksum.txt\n", r.Path())
+		fmt.Printf("Manifest file: %s%s\n", r.Path(), checksum.ManifestSuffix)
+	}
+	return nil
+}
+
+// checkResumeKey checks the --encrypt-key given to resume path against the
+// fingerprint its journal recorded, if any, before anything is written.
+func checkResumeKey(path, fingerprint string) error {
+	if fingerprint == "" {
+		if encryptKey != "" && encrypt == "" {
+			return fmt.Errorf("%s was not encrypted, so --encrypt-key doesn't apply", path)
+		}
+		return nil
+	}
+	if encryptKey == "" {
+		return fmt.Errorf("%s was encrypted with a given key; resume it with the same --encrypt-key", path)
+	}
+	key, err := generator.ParseEncryptKey(encryptKey)
+	if err != nil {
+		return err
+	}
+	if generator.KeyFingerprint(key) != fingerprint {
+		return fmt.Errorf("--encrypt-key is not the key %s was encrypted with", path)
+	}
+	return nil
+}
+
+func init() {
+	generateCmd = rootCmd
+	resumeCmd.Flags().IntVarP(&resumeWorkers, "workers", "w", runtime.NumCPU(), "Number of parallel generators")
+	resumeCmd.Flags().BoolVarP(&resumeVerbose, "verbose", "v", false, "Show detailed progress")
+	resumeCmd.Flags().StringVar(&encryptKey, "encrypt-key", "", "Hex AES key the interrupted run was given with --encrypt-key")
+	rootCmd.AddCommand(resumeCmd)
+}
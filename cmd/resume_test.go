@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+func TestCheckResumeKey(t *testing.T) {
+	savedKey, savedEncrypt := encryptKey, encrypt
+	t.Cleanup(func() {
+		encryptKey, encrypt = savedKey, savedEncrypt
+	})
+
+	key := bytes.Repeat([]byte{0x42}, 32)
+	fingerprint := generator.KeyFingerprint(key)
+	tests := []struct {
+		name        string
+		key         string
+		encrypt     string
+		fingerprint string
+		expectedMsg string
+	}{
+		{"matching key", hex.EncodeToString(key), "aes-ctr", fingerprint, ""},
+		{"missing key", "", "aes-ctr", fingerprint, "resume it with the same --encrypt-key"},
+		{"wrong key", strings.Repeat("43", 32), "aes-ctr", fingerprint, "is not the key"},
+		{"invalid key", "xyz", "aes-ctr", fingerprint, "invalid encryption key"},
+		{"unencrypted", "", "", "", ""},
+		{"key for unencrypted", hex.EncodeToString(key), "", "", "was not encrypted"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encryptKey, encrypt = test.key, test.encrypt
+			err := checkResumeKey("test.dat", test.fingerprint)
+			if test.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestJournalFlagsLeaveOutKey(t *testing.T) {
+	savedKey := encryptKey
+	flag := rootCmd.Flags().Lookup("encrypt-key")
+	t.Cleanup(func() {
+		encryptKey = savedKey
+		flag.Changed = false
+	})
+
+	// Set as on the command line, which marks the flag as given
+	key := bytes.Repeat([]byte{0x42}, 32)
+	if err := rootCmd.Flags().Set("encrypt-key", hex.EncodeToString(key)); err != nil {
+		t.Fatal(err)
+	}
+	flags := journalFlags(1 << 20)
+	for name, value := range flags {
+		if strings.Contains(value, encryptKey) {
+			t.Errorf("expected the key to be left out of the journal, found it in %s", name)
+		}
+	}
+	if flags[keyFingerprint] != generator.KeyFingerprint(key) {
+		t.Errorf("expected the key's fingerprint, got %q", flags[keyFingerprint])
+	}
+}
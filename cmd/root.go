@@ -1,35 +1,115 @@
 package cmd
 
 import (
+	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/maxkimambo/trasher/internal/checksum"
+	"github.com/maxkimambo/trasher/internal/calibrate"
+	"github.com/maxkimambo/trasher/internal/control"
+	"github.com/maxkimambo/trasher/internal/device"
+	"github.com/maxkimambo/trasher/internal/diskspace"
+	"github.com/maxkimambo/trasher/internal/jobs"
+	"github.com/maxkimambo/trasher/internal/latency"
+	"github.com/maxkimambo/trasher/internal/lock"
 	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/report"
 	"github.com/maxkimambo/trasher/internal/signal"
+	"github.com/maxkimambo/trasher/internal/sizedist"
+	"github.com/maxkimambo/trasher/internal/telemetry"
+	"github.com/maxkimambo/trasher/internal/throttle"
+	"github.com/maxkimambo/trasher/internal/trailer"
+	"github.com/maxkimambo/trasher/internal/tui"
 	"github.com/maxkimambo/trasher/internal/validation"
 	"github.com/maxkimambo/trasher/internal/worker"
 	"github.com/maxkimambo/trasher/internal/writer"
+	"github.com/maxkimambo/trasher/pkg/checksum"
 	"github.com/maxkimambo/trasher/pkg/generator"
 	"github.com/maxkimambo/trasher/pkg/sizeparser"
 )
 
 var (
-	size      string
-	pattern   string
-	output    string
-	workers   int
-	chunkSize string
-	force     bool
-	verbose   bool
-	version   = "0.1.0"
+	size       string
+	pattern    string
+	output     string
+	workers    int
+	count      int
+	sizeMin    string
+	sizeMax    string
+	sizeDist   string
+	leaveFree  string
+	autoScale  bool
+	warmUp     bool
+	chunkSize  string
+	maxIOPS    int
+	duration   time.Duration
+	drain      bool
+	hugePages  bool
+	doubleBuf  bool
+	freeBelow  string
+	ordered    bool
+	pipeline   string
+	ioEngine   string
+	hashMode   string
+	hashBuf    string
+	manifest   bool
+	merkle     bool
+	sumFormat  string
+	sumsFile   string
+	noChecksum bool
+	addTrailer bool
+	noMeta     bool
+	maxMemory  string
+	force      bool
+	verbose    bool
+	noLock     bool
+	ctlSocket  string
+	tuiMode    bool
+	mixedChunk string
+	mixedPhase string
+	mixedRatio float64
+	compress   float64
+	dedupRatio float64
+	dedupBlock string
+	entropy    float64
+	schema     string
+	logFormat  string
+	hexPattern string
+	magic      string
+	encrypt    string
+	encryptKey string
+	patternMap string
+	writeZeros bool
+	sparse     bool
+	dropCache  bool
+	cleanupErr bool
+	genCommand string
+	corpus     string
+	summary    string
+	noHistory  bool
+	historyLog string
+	expect     device.Expectation
+	expectSize string
+	fileMode   string
+	owner      string
+	group      string
+	version    = "0.1.0"
 )
 
+// stopInterrupted is the reason a run drained on a signal stopped early.
+const stopInterrupted = "interrupted"
+
 var rootCmd = &cobra.Command{
 	Use:   "trasher",
 	Short: "A high-performance file generation tool",
@@ -37,79 +117,539 @@ var rootCmd = &cobra.Command{
 with configurable data patterns using concurrent workers for optimal performance.`,
 	Version: version,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if patternMap != "" && cmd.Flags().Changed("pattern") {
+			return fmt.Errorf("--pattern and --pattern-map cannot be used together")
+		}
+		// A byte sequence selects the bytes pattern unless one was named
+		if hexPattern != "" && !cmd.Flags().Changed("pattern") && patternMap == "" {
+			pattern = "bytes"
+		}
+		// Likewise a generator command selects the exec pattern
+		if genCommand != "" && !cmd.Flags().Changed("pattern") && patternMap == "" {
+			pattern = "exec"
+		}
+		if size == "" && sizeMin == "" && sizeMax == "" && leaveFree == "" {
+			return fmt.Errorf(`required flag(s) "size" not set`)
+		}
+		if leaveFree != "" && (size != "" || sizeMin != "" || sizeMax != "") {
+			return fmt.Errorf("--leave-free replaces --size and cannot be used with --size, --size-min or --size-max")
+		}
+		if size != "" && (sizeMin != "" || sizeMax != "") {
+			return fmt.Errorf("--size cannot be used with --size-min and --size-max")
+		}
+		if (sizeMin != "") != (sizeMax != "") {
+			return fmt.Errorf("--size-min and --size-max must be given together")
+		}
+		if sizeMin != "" && count < 2 {
+			return fmt.Errorf("--size-min and --size-max give the files of a --count run random sizes and need --count")
+		}
+		if cmd.Flags().Changed("size-dist") && sizeMin == "" {
+			return fmt.Errorf("--size-dist requires --size-min and --size-max")
+		}
+		if warmUp && (autoScale || chunkSize == "auto") {
+			return fmt.Errorf("--calibrate cannot be used with --workers auto or --chunk-size auto")
+		}
+		if count > 1 && (warmUp || duration > 0 || freeBelow != "" || drain || ctlSocket != "") {
+			return fmt.Errorf("--count cannot be used with --calibrate, --duration, --stop-when-free-below, --graceful-drain or --control-socket")
+		}
+		if noChecksum && (manifest || merkle || cmd.Flags().Changed("checksum-mode") || cmd.Flags().Changed("checksum-format")) {
+			return fmt.Errorf("--no-checksum cannot be used with --manifest, --merkle, --checksum-mode or --checksum-format")
+		}
+		if addTrailer && (noChecksum || manifest || merkle || hashMode == checksum.ModeTree || cmd.Flags().Changed("checksum-format")) {
+			return fmt.Errorf("--trailer replaces the checksum file and cannot be used with --no-checksum, --manifest, --merkle, --checksum-format or --checksum-mode tree")
+		}
+		if hashBuf != "" && hashMode != checksum.ModeOrdered {
+			return fmt.Errorf("--hash-buffer requires --checksum-mode ordered")
+		}
+		if sumsFile != "" && (count < 2 || noChecksum || addTrailer) {
+			return fmt.Errorf("--checksum-file covers the files of a --count run and cannot be used with --no-checksum or --trailer")
+		}
+		if repeat < 0 {
+			return fmt.Errorf("--repeat must be at least 1")
+		}
+		if cycle {
+			if count > 1 || writer.IsNetworkTarget(output) || writer.IsObjectTarget(output) || device.IsBlockDevice(output) {
+				return fmt.Errorf("--cycle needs a single file output")
+			}
+			if noChecksum || addTrailer {
+				return fmt.Errorf("--cycle reads the file back against its checksum file and cannot be used with --no-checksum or --trailer")
+			}
+			verifyEach, deleteEach = true, true
+		}
+		if (verifyEach || deleteEach) && !repeating() {
+			return fmt.Errorf("--verify-each and --delete-each require --repeat or --loop")
+		}
+		if (verifyEach || deleteEach) && (count > 1 || writer.IsNetworkTarget(output) || writer.IsObjectTarget(output) || device.IsBlockDevice(output)) {
+			return fmt.Errorf("--verify-each and --delete-each need a single file output")
+		}
+		if verifyEach && (noChecksum || addTrailer) {
+			return fmt.Errorf("--verify-each reads the file back against its checksum file and cannot be used with --no-checksum or --trailer")
+		}
+		if fileMode != "" || owner != "" || group != "" {
+			if writer.IsNetworkTarget(output) || writer.IsObjectTarget(output) {
+				return fmt.Errorf("--mode, --owner and --group need a file output")
+			}
+			if _, err := withPermissions(writer.Options{}); err != nil {
+				return err
+			}
+		}
+		if tuiMode {
+			if jsonOut || count > 1 {
+				return fmt.Errorf("--tui shows a single file's run and cannot be used with --json or --count")
+			}
+			if !tui.IsTerminal(os.Stdout) {
+				return fmt.Errorf("--tui needs a terminal")
+			}
+			if err := tui.CanReadKeys(os.Stdin); err != nil {
+				return fmt.Errorf("--tui can't read keys from stdin: %v", err)
+			}
+		}
+		if err := startExporter(); err != nil {
+			return err
+		}
+		if jsonOut && repeating() {
+			return fmt.Errorf("--json reports a single run and cannot be used with --repeat, --loop or --cycle")
+		}
+		if repeating() {
+			return runRepeated()
+		}
+		if jsonOut {
+			return runJSON(runTrasher)
+		}
 		return runTrasher()
 	},
 }
 
-func runTrasher() error {
+func runTrasher() (err error) {
+	// Trace the run when an OTLP endpoint is set
+	endTrace := traceRun(output, pattern)
+	defer func() {
+		endTrace(err)
+	}()
+
+	// Network and object store targets are written remotely instead of to a
+	// local file
+	network := writer.IsNetworkTarget(output)
+	object := writer.IsObjectTarget(output)
+	remote := network || object
+	if warmUp && (remote || device.IsBlockDevice(output)) {
+		return fmt.Errorf("--calibrate needs a file output, since it writes trial data next to it")
+	}
+	if manifest && remote {
+		return fmt.Errorf("--manifest needs a file or block device output")
+	}
+	if merkle && remote {
+		return fmt.Errorf("--merkle needs a file or block device output")
+	}
+	if addTrailer && (remote || device.IsBlockDevice(output)) {
+		return fmt.Errorf("--trailer needs a file output, since it is appended after the data")
+	}
+
+	// A batch run generates several files named after the output
+	names := []string{output}
+	if count > 1 {
+		if names, err = batchNames(output, count); err != nil {
+			return err
+		}
+		// A template can repeat a name; check before locking the names
+		if err := validation.NewValidator().ValidateNames(names); err != nil {
+			return validationFailed(err)
+		}
+	}
+
+	// Random file sizes are drawn up front, so validation checks their total
+	var sizes []int64
+	var totalSize int64
+	if sizeMin != "" {
+		if sizes, err = randomSizes(count); err != nil {
+			return err
+		}
+		for _, fileSize := range sizes {
+			totalSize += fileSize
+		}
+	}
+
+	// Lock the targets so concurrent runs can't write the same output
+	if !noLock && !remote {
+		for _, name := range names {
+			targetLock, err := lock.Acquire(name)
+			if err != nil {
+				return err
+			}
+			defer targetLock.Release()
+		}
+	}
+
+	// Make sure a block device target is the disk the caller meant
+	if expectSize != "" {
+		if expect.Size, err = sizeparser.Parse(expectSize); err != nil {
+			return fmt.Errorf("invalid --expect-size: %v", err)
+		}
+	}
+	if err := device.Guard(output, expect); err != nil {
+		return err
+	}
+
+	// A size given as a share of free space, or by the space to leave free,
+	// becomes bytes of the output's filesystem before it is checked like any
+	// other size
+	validator := validation.NewValidator()
+	resolved, err := validator.ResolveSize(size, names[0])
+	if leaveFree != "" {
+		resolved, err = validator.ResolveLeaveFree(leaveFree, names[0], count)
+	}
+	if err != nil {
+		return validationFailed(err)
+	}
+
 	// Create validation configuration
 	config := validation.ValidationConfig{
-		Size:       size,
+		Size:       resolved,
 		Pattern:    pattern,
-		OutputPath: output,
+		OutputPath: names[0],
+		Count:      count,
 		Workers:    workers,
 		ChunkSize:  chunkSize,
 		Force:      force,
+		MaxIOPS:    maxIOPS,
+		Duration:   duration,
+		Pipeline:   pipeline,
+		IOEngine:   ioEngine,
+		MaxMemory:  maxMemory,
+
+		MixedChunkSize:   mixedChunk,
+		MixedPhase:       mixedPhase,
+		MixedRandomRatio: mixedRatio,
+		CompressRatio:    compress,
+		DedupRatio:       dedupRatio,
+		DedupBlockSize:   dedupBlock,
+		Entropy:          entropy,
+		Schema:           schema,
+		LogFormat:        logFormat,
+		PatternBytes:     hexPattern,
+		Magic:            magic,
+		Encrypt:          encrypt,
+		EncryptKey:       encryptKey,
+		PatternMap:       patternMap,
+		GeneratorCommand: genCommand,
+		Corpus:           corpus,
+		Sparse:           sparse,
+
+		StopWhenFreeBelow: freeBelow,
+		ChecksumMode:      hashMode,
+		ChecksumFormat:    sumFormat,
+	}
+	if sizes != nil {
+		config.Size = sizeMax
+		config.TotalSize = totalSize
 	}
 
 	// Run pre-flight validation
-	validator := validation.NewValidator()
 	if err := validator.ValidateAll(config); err != nil {
-		return fmt.Errorf("validation failed: %v", err)
+		return validationFailed(err)
 	}
+	runSpan.AddEvent("validated")
 
 	// Parse size and chunk size
-	sizeBytes, err := sizeparser.Parse(size)
+	sizeBytes, err := sizeparser.Parse(config.Size)
 	if err != nil {
 		return fmt.Errorf("failed to parse size: %v", err)
 	}
 
-	chunkSizeBytes, err := sizeparser.Parse(chunkSize)
-	if err != nil {
-		return fmt.Errorf("failed to parse chunk size: %v", err)
+	// An automatic chunk size is tuned up to the largest size worth trying
+	chunkAuto := chunkSize == "auto"
+	chunkSizeBytes := int64(worker.MaxTunedChunkSize)
+	if !chunkAuto {
+		if chunkSizeBytes, err = sizeparser.Parse(chunkSize); err != nil {
+			return fmt.Errorf("failed to parse chunk size: %v", err)
+		}
+	}
+
+	var freeBelowBytes int64
+	if freeBelow != "" {
+		if freeBelowBytes, err = sizeparser.Parse(freeBelow); err != nil {
+			return fmt.Errorf("failed to parse free space threshold: %v", err)
+		}
+	}
+
+	var maxMemoryBytes int64
+	if maxMemory != "" {
+		if maxMemoryBytes, err = sizeparser.Parse(maxMemory); err != nil {
+			return fmt.Errorf("failed to parse memory budget: %v", err)
+		}
+	}
+
+	hashBufBytes := int64(checksum.DefaultHashBuffer)
+	if hashBuf != "" {
+		if hashBufBytes, err = sizeparser.Parse(hashBuf); err != nil {
+			return fmt.Errorf("failed to parse hash buffer size: %v", err)
+		}
+	}
+
+	// Report the region layout wherever the pattern would be reported
+	if patternMap != "" {
+		pattern = patternMap
 	}
 
 	if verbose {
-		fmt.Printf("Generating file: %s\n", output)
-		fmt.Printf("Size: %s (%d bytes)\n", size, sizeBytes)
+		if count > 1 {
+			fmt.Printf("Generating %d files: %s to %s\n", count, names[0], names[count-1])
+		} else {
+			fmt.Printf("Generating file: %s\n", output)
+		}
+		if sizes != nil {
+			fmt.Printf("Sizes: %s from %s to %s (%s in total)\n", sizeDist, sizeMin, sizeMax, progress.FormatBytes(totalSize))
+		} else if leaveFree != "" {
+			fmt.Printf("Size: %s (%d bytes, leaving %s free)\n", progress.FormatBytes(sizeBytes), sizeBytes, leaveFree)
+		} else {
+			fmt.Printf("Size: %s (%d bytes)\n", size, sizeBytes)
+		}
 		fmt.Printf("Pattern: %s\n", pattern)
-		fmt.Printf("Workers: %d\n", workers)
-		fmt.Printf("Chunk size: %s (%d bytes)\n", chunkSize, chunkSizeBytes)
+		if autoScale {
+			fmt.Printf("Workers: auto (up to %d)\n", workers)
+		} else {
+			fmt.Printf("Workers: %d\n", workers)
+		}
+		if chunkAuto {
+			fmt.Printf("Chunk size: auto (%s to %s)\n",
+				progress.FormatBytes(worker.MinTunedChunkSize), progress.FormatBytes(chunkSizeBytes))
+		} else {
+			fmt.Printf("Chunk size: %s (%d bytes)\n", chunkSize, chunkSizeBytes)
+		}
+		if maxMemoryBytes > 0 {
+			fmt.Printf("Memory budget: %s (%d chunks in flight)\n", maxMemory, max(maxMemoryBytes/chunkSizeBytes, 1))
+		}
+		if !remote {
+			fmt.Printf("I/O engine: %s\n", ioEngine)
+			if noChecksum {
+				fmt.Println("Checksum mode: none")
+			} else {
+				fmt.Printf("Checksum mode: %s\n", hashMode)
+			}
+			if hashMode == checksum.ModeOrdered && !noChecksum {
+				fmt.Printf("Hash buffer: %s\n", progress.FormatBytes(hashBufBytes))
+			}
+		}
 		fmt.Println()
 	}
 
+	if count > 1 {
+		specs := batchSpecs(names, sizeBytes)
+		for i, fileSize := range sizes {
+			specs[i].size = fileSize
+		}
+		return runBatch(specs, chunkSizeBytes, maxMemoryBytes, hashBufBytes, chunkAuto)
+	}
+
 	// Create context and shutdown handler
 	ctx, shutdownHandler := signal.WithShutdownHandler(os.Stdout)
 
-	// Create file writer
-	fileWriter, err := writer.NewFileWriter(output, sizeBytes, force)
-	if err != nil {
-		return fmt.Errorf("failed to create file writer: %v", err)
+	// Create the file, socket or object writer
+	var out writer.Writer
+	var fileWriter *writer.FileWriter
+	var socketWriter *writer.SocketWriter
+	switch {
+	case network:
+		socketWriter, err = writer.NewSocketWriter(output, sizeBytes)
+		if err != nil {
+			return fmt.Errorf("failed to create socket writer: %v", err)
+		}
+		out = socketWriter
+	case object:
+		// Each chunk is uploaded as one part, by up to one upload per worker
+		out, err = writer.NewObjectWriter(output, sizeBytes, chunkSizeBytes, workers)
+		if err != nil {
+			return fmt.Errorf("failed to create object writer: %v", err)
+		}
+	default:
+		var opts writer.Options
+		opts, err = withPermissions(writer.Options{
+			Force:        force,
+			Sparse:       sparse,
+			DropCache:    dropCache,
+			NoSpaceCheck: freeBelowBytes > 0,
+			Engine:       ioEngine,
+		})
+		if err != nil {
+			return err
+		}
+		fileWriter, err = writer.NewFileWriterWithOptions(output, sizeBytes, opts)
+		if err != nil {
+			return writeFailed(fmt.Errorf("failed to create file writer: %w", err))
+		}
+		out = fileWriter
 	}
 
-	// Register cleanup for file writer
+	// Register cleanup for the writer
 	shutdownHandler.RegisterCleanupFunc(func() error {
-		return fileWriter.Close()
+		return out.Close()
 	})
 
+	// Remove the partial output of a failed or cancelled run. Only files this
+	// run created or overwrote are removed; block devices are left alone.
+	if cleanupErr && fileWriter != nil && !device.IsBlockDevice(output) {
+		defer func() {
+			if err == nil {
+				return
+			}
+			fileWriter.Close()
+			for _, path := range []string{output, output + ".checksum.txt", output + checksum.ManifestSuffix} {
+				if removeErr := os.Remove(path); removeErr != nil && !os.IsNotExist(removeErr) {
+					fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", path, removeErr)
+				}
+			}
+			if removeErr := checksum.RemoveMerkle(output); removeErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", removeErr)
+			}
+			fmt.Printf("Removed partial output %s\n", output)
+		}()
+	}
+
 	// Set writer in shutdown handler for progress reporting
-	shutdownHandler.SetWriter(fileWriter)
+	shutdownHandler.SetWriter(out)
 
-	// Create progress reporter
-	progressReporter := progress.NewProgressReporter(sizeBytes, verbose, os.Stdout)
+	// Create progress reporter; the dashboard shows progress instead
+	progressOut := io.Writer(os.Stdout)
+	if tuiMode {
+		progressOut = io.Discard
+	}
+	progressReporter := progress.NewProgressReporter(sizeBytes, verbose, progressOut)
 	shutdownHandler.SetProgressReporter(progressReporter)
 
 	// Create pattern generator
-	gen, err := generator.NewGenerator(pattern)
+	genOpts, err := generatorOptions()
+	if err != nil {
+		return err
+	}
+	// Workers get their own generators, so they must all use the same seed
+	genOpts = genOpts.WithSharedSeed()
+	key, err := encryptionKey()
+	if err != nil {
+		return err
+	}
+
+	var closers []io.Closer
+	defer func() {
+		for _, closer := range closers {
+			if closeErr := closer.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	}()
+	newOutput := func() (generator.Generator, generator.Generator, error) {
+		gen, base, err := newOutputGenerator(pattern, genOpts, key)
+		if closer, ok := base.(io.Closer); ok {
+			closers = append(closers, closer)
+		}
+		return gen, base, err
+	}
+
+	gen, base, err := newOutput()
 	if err != nil {
 		return fmt.Errorf("failed to create generator: %v", err)
 	}
+	if stamps, ok := base.(generator.BlockChecker); ok && verbose {
+		fmt.Printf("Run ID: %016x\n", stamps.RunID())
+	}
+	if key != nil && verbose {
+		fmt.Printf("Encryption key: %x\n", key)
+	}
+
+	// A zero file needs no data written: leave it sparse unless told
+	// otherwise. Block devices keep whatever they held, so they are written.
+	_, isZero := gen.(*generator.ZeroGenerator)
+	sparseZeros := isZero && !writeZeros && !remote && !device.IsBlockDevice(output)
+	if sparseZeros && verbose {
+		fmt.Println("Zero pattern: leaving the file sparse (use --write-zeros to write it)")
+	}
+	// A block device can often zero itself far faster than zeros are written
+	zeroDevice := isZero && !writeZeros && !remote && device.IsBlockDevice(output)
 
 	// Create checksum generator
 	checksumGen := checksum.NewChecksumGenerator(output, sizeBytes)
+	if err := checksumGen.SetMode(hashMode); err != nil {
+		return err
+	}
+	checksumGen.SetHashBuffer(hashBufBytes)
+	if err := checksumGen.SetFormat(sumFormat); err != nil {
+		return err
+	}
+	// Remote output is not read back for a checksum file, and a trailer
+	// only records the full-file checksum
+	hashing := !remote && !noChecksum && !addTrailer
+	// The trailer's digest can be hashed in order as well
+	orderedHash := !remote && !noChecksum && hashMode == checksum.ModeOrdered
+
+	// Pick the fastest worker count and chunk size before the main run
+	if warmUp && !sparseZeros {
+		calibrateOpts := calibrate.Options{
+			Writer: writer.Options{Sparse: sparse, DropCache: dropCache, Engine: ioEngine},
+		}
+		if hashing {
+			calibrateOpts.NewHash = checksumGen.NewChunkHash
+		}
+		fmt.Printf("Calibrating with %s of trial data...\n", progress.FormatBytes(calibrate.DefaultTotal))
+		trials, err := calibrate.Run(ctx, output+".calibrate", calibrate.DefaultTotal, calibrate.Candidates(workers),
+			func() (generator.Generator, error) {
+				gen, _, err := newOutput()
+				return gen, err
+			},
+			calibrateOpts)
+		if err != nil {
+			return err
+		}
+		if verbose {
+			for _, trial := range trials {
+				fmt.Printf("  %d workers, %s chunks: %s\n", trial.Workers,
+					progress.FormatBytes(trial.ChunkSize), progress.FormatThroughput(trial.Throughput()))
+			}
+		}
+		best := calibrate.Best(trials)
+		workers, chunkSizeBytes = best.Workers, best.ChunkSize
+		runSpan.AddEvent("calibrated",
+			telemetry.Int("trasher.workers", int64(workers)),
+			telemetry.Int("trasher.chunk_size", chunkSizeBytes))
+		fmt.Printf("Calibrated: %d workers, %s chunks (%s)\n\n", workers,
+			progress.FormatBytes(chunkSizeBytes), progress.FormatThroughput(best.Throughput()))
+	}
 
 	// Create worker pool
 	workerPool := worker.NewWorkerPool(ctx, workers, chunkSizeBytes)
+	workerPool.SetOrdered(ordered)
+	if hashing {
+		workerPool.SetChunkHash(checksumGen.NewChunkHash)
+	}
+	if maxMemoryBytes > 0 {
+		workerPool.SetMemoryBudget(maxMemoryBytes)
+	}
+	if autoScale {
+		workerPool.SetAutoScale(worker.DefaultScaleInterval)
+	}
+	workerPool.SetChunkTuning(chunkAuto)
+	workerPool.SetHugePages(hugePages)
+	workerPool.SetDoubleBuffering(doubleBuf)
+	// Let idle workers help with the last chunks
+	workerPool.SetSplitSize(worker.DefaultSplitSize)
+
+	// In verbose mode, show what each worker, and in the channel pipeline
+	// the writer, is doing now and then, so a slow worker or a stalled
+	// writer stands out
+	var writerBusyNow atomic.Bool
+	var writerSince atomic.Int64
+	writerSince.Store(time.Now().UnixNano())
+	if verbose {
+		var writerState func() string
+		if pipeline != "direct" {
+			writerState = func() string {
+				since := time.Since(time.Unix(0, writerSince.Load())).Round(time.Millisecond)
+				if writerBusyNow.Load() {
+					return fmt.Sprintf("Writer: writing for %s", since)
+				}
+				return fmt.Sprintf("Writer: waiting for chunks for %s", since)
+			}
+		}
+		progressReporter.SetReport(worker.DefaultStatsInterval, workerReport(workerPool, writerState))
+	}
 
 	// Start progress reporting
 	var writtenBytes int64
@@ -117,65 +657,352 @@ func runTrasher() error {
 		return atomic.LoadInt64(&writtenBytes)
 	}
 	progressReporter.Start(getWritten)
+	peakMeter := progress.NewPeakMeter(progress.DefaultPeakInterval)
+	peakMeter.Start(getWritten)
+
+	// A run stopped on a signal counts as cancelled even once it has drained
+	var stopReason atomic.Value
+	cancelled := func() bool {
+		return ctx.Err() != nil || stopReason.Load() == stopInterrupted
+	}
+
+	// Record the job in the user's state directory for `trasher status`
+	if tracker := registerJob(output, pattern, sizeBytes); tracker != nil {
+		tracker.Start(getWritten)
+		defer func() {
+			state := jobs.StateCompleted
+			if cancelled() {
+				state = jobs.StateCancelled
+			} else if err != nil {
+				state = jobs.StateFailed
+			}
+			tracker.Finish(getWritten(), state, err)
+		}()
+	}
+
+	startTime := time.Now()
+	var generationTime time.Duration
+	writeLatency := latency.NewHistogram()
+	var metaFile string
+	runSpan.AddEvent("generation started",
+		telemetry.Int("trasher.workers", int64(workers)),
+		telemetry.Int("trasher.chunk_size", chunkSizeBytes))
+
+	// Export the run's metrics while it runs
+	meter := otelExporter.StartMeter(telemetry.DefaultMetricsInterval, getWritten, writeLatency,
+		telemetry.String("trasher.output", output),
+		telemetry.String("trasher.pattern", pattern))
+	defer stopMeter(meter)
+
+	// Record the run's performance for benchmark tracking and history, and
+	// next to a completed file as its metadata
+	defer func() {
+		peakMeter.Stop()
+
+		s := &report.Summary{
+			Version:   version,
+			Output:    output,
+			Pattern:   pattern,
+			Seed:      patternSeed(),
+			SizeBytes: sizeBytes,
+			Workers:   workerPool.ActiveWorkers(),
+			ChunkSize: workerPool.ChunkSize(),
+			StartedAt: startTime,
+			Checksum:  checksumGen.FullChecksum(),
+
+			WriteLatency: report.NewLatency(writeLatency),
+		}
+		if socketWriter != nil {
+			s.Checksum = socketWriter.Checksum()
+		}
+		if patternMap != "" {
+			s.Pattern = patternMap
+		}
+		if s.Checksum != "" {
+			s.Algorithm = checksumGen.GetAlgorithm()
+		}
+		if generationTime == 0 {
+			generationTime = time.Since(startTime)
+		}
+		s.Finalize(getWritten(), generationTime, err)
+		s.PeakThroughput = max(peakMeter.Peak(), s.Throughput)
+		if err != nil && cancelled() {
+			s.Status = "cancelled"
+		}
+		lastSummary = s
+
+		if summary != "" {
+			if writeErr := report.Write(summary, s); writeErr != nil && err == nil {
+				err = writeErr
+			}
+		}
+		if metaFile != "" && err == nil {
+			err = report.Write(metaFile, s)
+		}
+		if !noHistory {
+			if histErr := appendHistory(s); histErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to record run history: %v\n", histErr)
+			}
+		}
+	}()
+
+	// Expose progress and pause/cancel control to external tools
+	if ctlSocket != "" {
+		ctlServer, err := control.Listen(ctlSocket, &jobController{
+			output:     output,
+			pattern:    pattern,
+			totalBytes: sizeBytes,
+			startTime:  startTime,
+			getWritten: getWritten,
+			pool:       workerPool,
+			shutdown:   shutdownHandler,
+		})
+		if err != nil {
+			progressReporter.Stop()
+			return err
+		}
+		defer ctlServer.Close()
+	}
+
+	// A sparse zero file only needs its holes and checksums
+	remaining := sizeBytes
+	if sparseZeros {
+		if err := fileWriter.PunchHole(0, sizeBytes); err != nil && !errors.Is(err, writer.ErrHolesUnsupported) {
+			progressReporter.Stop()
+			return err
+		}
+		if hashing {
+			if err := checksumGen.UpdateWithZeroChunks(chunkSizeBytes); err != nil {
+				progressReporter.Stop()
+				return err
+			}
+		}
+		atomic.StoreInt64(&writtenBytes, sizeBytes)
+		remaining = 0
+	}
+
+	// Zero a device in place a chunk at a time, so progress is reported and
+	// cancellation is noticed, and write the zeros if it can't be
+	if zeroDevice {
+		var zeroErr error
+		var offset int64
+		for ; offset < sizeBytes && ctx.Err() == nil; offset += chunkSizeBytes {
+			length := min(chunkSizeBytes, sizeBytes-offset)
+			if zeroErr = fileWriter.ZeroRange(offset, length); zeroErr != nil {
+				break
+			}
+			atomic.AddInt64(&writtenBytes, length)
+		}
 
-	// Start worker pool
-	workerPool.Start(gen, sizeBytes)
+		switch {
+		case errors.Is(zeroErr, writer.ErrZeroRangeUnsupported) && offset == 0:
+			if verbose {
+				fmt.Println("Zero pattern: the device can't zero itself, writing zeros")
+			}
+		case zeroErr != nil:
+			progressReporter.Stop()
+			return writeFailed(zeroErr)
+		default:
+			if verbose {
+				fmt.Println("Zero pattern: zeroed the device in place (use --write-zeros to write it)")
+			}
+			if hashing {
+				if err := checksumGen.UpdateWithZeroChunks(chunkSizeBytes); err != nil {
+					progressReporter.Stop()
+					return err
+				}
+			}
+			remaining = 0
+		}
+	}
 
-	// Process results
+	// Pace writes when an IOPS limit is set
+	var iopsLimiter *throttle.Limiter
+	if maxIOPS > 0 {
+		iopsLimiter = throttle.NewLimiter(float64(maxIOPS))
+	}
+
+	// Show the dashboard, whose keys pace writes to a byte rate
+	var rateLimiter *throttle.Limiter
+	stopDashboard := func() {}
+	if tuiMode {
+		rateLimiter = throttle.NewLimiter(0)
+		dashboard := tui.New(ctx, &jobController{
+			output:     output,
+			pattern:    pattern,
+			totalBytes: sizeBytes,
+			startTime:  startTime,
+			getWritten: getWritten,
+			pool:       workerPool,
+			shutdown:   shutdownHandler,
+		}, tui.Options{
+			Workers: workerPool.WorkerStats,
+			Latency: writeLatency,
+			Errors:  workerPool.Errors,
+			Limiter: rateLimiter,
+		}, os.Stdout, os.Stdin)
+		dashboard.Start()
+		stopDashboard = dashboard.Stop
+		defer dashboard.Stop()
+	}
+
+	// A --manifest run that doesn't finish leaves a journal of the chunks
+	// it wrote, so trasher resume can complete the file. A random
+	// encryption key is never recorded, so such a run can't be resumed.
+	journaling := manifest && hashing && !cleanupErr && (encrypt == "" || encryptKey != "")
+	var journalMu sync.Mutex
+	var journal []checksum.ChunkInfo
+	if journaling {
+		defer func() {
+			if err == nil {
+				return
+			}
+			journalMu.Lock()
+			defer journalMu.Unlock()
+			if journalErr := checksum.WriteJournal(output, sizeBytes, journal, journalFlags(workerPool.ChunkSize())); journalErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", journalErr)
+				return
+			}
+			fmt.Printf("Journal written to %s%s; complete the file with trasher resume\n", output, checksum.ManifestSuffix)
+		}()
+	}
+
+	// writeChunk records a chunk's checksum and writes it out
+	writeChunk := func(result worker.Result) error {
+		// Record the checksum the worker computed
+		if hashing {
+			if err := checksumGen.AddChunkChecksum(result.Offset, result.Checksum); err != nil {
+				return fmt.Errorf("checksum error: %v", err)
+			}
+		}
+		if orderedHash {
+			if err := checksumGen.UpdateOrdered(result.Buffer, result.Offset); err != nil {
+				return fmt.Errorf("checksum error: %v", err)
+			}
+		}
+
+		// Each chunk is one write operation
+		if iopsLimiter != nil {
+			if err := iopsLimiter.Wait(ctx, 1); err != nil {
+				return err
+			}
+		}
+		if rateLimiter != nil {
+			if err := rateLimiter.Wait(ctx, int64(len(result.Buffer))); err != nil {
+				return err
+			}
+		}
+
+		// Write to file or socket
+		writeStart := time.Now()
+		if err := out.WriteAt(result.Buffer, result.Offset); err != nil {
+			return fmt.Errorf("file write error: %w", err)
+		}
+		writeLatency.Record(time.Since(writeStart))
+		if journaling {
+			journalMu.Lock()
+			journal = append(journal, checksum.ChunkInfo{
+				Offset:   result.Offset,
+				Size:     int64(len(result.Buffer)),
+				Checksum: hex.EncodeToString(result.Checksum),
+			})
+			journalMu.Unlock()
+		}
+
+		// Update written bytes counter
+		atomic.AddInt64(&writtenBytes, int64(len(result.Buffer)))
+		return nil
+	}
+
+	// In the direct pipeline workers write their chunks themselves
+	if pipeline == "direct" {
+		workerPool.SetSink(writeChunk)
+	}
+
+	// Start worker pool, giving each worker its own generator; the first
+	// worker takes the one already created
+	workerGens := 0
+	if _, err := workerPool.StartWithFactory(func() (generator.Generator, error) {
+		if workerGens++; workerGens == 1 {
+			return gen, nil
+		}
+		gen, _, err := newOutput()
+		return gen, err
+	}, remaining); err != nil {
+		progressReporter.Stop()
+		return err
+	}
+
+	// Stop handing out chunks once a limit is reached; the chunks in
+	// progress still finish, so the output ends cleanly after them
+	stopEarly := func(reason string) {
+		stopReason.CompareAndSwap(nil, reason)
+		workerPool.Stop()
+	}
+	if drain {
+		shutdownHandler.SetDrainFunc(func() {
+			stopEarly(stopInterrupted)
+		})
+	}
+	if duration > 0 {
+		timer := time.AfterFunc(duration, func() {
+			stopEarly(fmt.Sprintf("time limit of %s reached", duration))
+		})
+		defer timer.Stop()
+	}
+	if freeBelowBytes > 0 {
+		watchCtx, stopWatch := context.WithCancel(ctx)
+		defer stopWatch()
+		var lastWritten int64
+		go diskspace.Watch(watchCtx, filepath.Dir(output), diskspace.DefaultWatchInterval, func(available int64) bool {
+			// Chunks handed out but not yet written will still take up room,
+			// as will about as much as was written since the last check
+			written := getWritten()
+			needed := workerPool.End() - lastWritten
+			lastWritten = written
+			if available-needed >= freeBelowBytes {
+				return true
+			}
+			stopEarly(fmt.Sprintf("free space fell below %s", progress.FormatBytes(freeBelowBytes)))
+			return false
+		})
+	}
+
+	// Process results; in the channel pipeline workers send them here. The
+	// time spent waiting for them and writing them shows which side stalls.
 	var wg sync.WaitGroup
+	var writerWait, writerBusy time.Duration
 	wg.Add(1)
 
 	go func() {
 		defer wg.Done()
 		for {
+			waitStart := time.Now()
+			writerBusyNow.Store(false)
+			writerSince.Store(waitStart.UnixNano())
 			select {
 			case <-ctx.Done():
 				return
 			case result, ok := <-workerPool.Results():
+				writeStart := time.Now()
+				writerWait += writeStart.Sub(waitStart)
+				writerBusyNow.Store(true)
+				writerSince.Store(writeStart.UnixNano())
 				if !ok {
 					// Channel closed, all work completed
 					return
 				}
 
-				// Update checksum
-				if err := checksumGen.UpdateWithChunk(result.Buffer, result.Offset); err != nil {
-					fmt.Printf("\nChecksum error: %v\n", err)
-					shutdownHandler.Stop()
-					workerPool.ReturnBuffer(result.Buffer)
-					return
-				}
-
-				// Write to file
-				if err := fileWriter.WriteAt(result.Buffer, result.Offset); err != nil {
-					fmt.Printf("\nFile write error: %v\n", err)
-					shutdownHandler.Stop()
-					workerPool.ReturnBuffer(result.Buffer)
+				// The pool has already recorded a failed chunk
+				if result.Err != nil {
 					return
 				}
 
-				// Update written bytes counter
-				atomic.AddInt64(&writtenBytes, int64(len(result.Buffer)))
-
-				// Return buffer to pool
+				err := writeChunk(result)
 				workerPool.ReturnBuffer(result.Buffer)
-			}
-		}
-	}()
-
-	// Monitor for errors in a separate goroutine
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case err, ok := <-workerPool.Errors():
-				if !ok {
-					// Error channel closed, work completed
-					return
-				}
+				writerBusy += time.Since(writeStart)
 				if err != nil {
-					fmt.Printf("\nError: %v\n", err)
-					shutdownHandler.Stop()
+					workerPool.Fail(result.Offset, err)
 					return
 				}
 			}
@@ -189,29 +1016,165 @@ func runTrasher() error {
 
 	// Stop progress reporting immediately after work completion
 	progressReporter.Stop()
+	stopDashboard()
+	generationTime = time.Since(startTime)
+	runSpan.AddEvent("generation finished", telemetry.Int("trasher.bytes_written", getWritten()))
+
+	// Report every chunk that failed before the pool stopped. Once the run
+	// is cancelled, chunks only fail because the output was closed under them.
+	if chunkErrs := workerPool.Errors(); len(chunkErrs) > 0 && ctx.Err() == nil {
+		fmt.Printf("\n%d chunks failed:\n", len(chunkErrs))
+		for _, chunkErr := range chunkErrs {
+			fmt.Printf("  offset %d: %v\n", chunkErr.Offset, chunkErr.Err)
+		}
+		shutdownHandler.Stop()
+		return chunksFailed(chunkErrs)
+	}
 
 	// Check if operation was cancelled
 	select {
 	case <-ctx.Done():
-		return fmt.Errorf("operation cancelled")
+		return errCancelled
 	default:
 		// Operation completed successfully
 	}
 
+	// A run stopped early ends after the last chunk handed out
+	dataSize := sizeBytes
+	if end := workerPool.End(); end < remaining {
+		if err := out.Truncate(end); err != nil {
+			return writeFailed(fmt.Errorf("failed to truncate output: %w", err))
+		}
+		dataSize = end
+		fmt.Printf("\nStopped after %s of %s: %s\n",
+			progress.FormatBytes(end), progress.FormatBytes(sizeBytes), stopReason.Load())
+		runSpan.AddEvent("stopped early", telemetry.String("trasher.reason", fmt.Sprint(stopReason.Load())))
+	}
+
+	if chunkAuto && verbose {
+		fmt.Printf("\nTuned chunk size: %s\n", progress.FormatBytes(workerPool.ChunkSize()))
+	}
+
+	if verbose {
+		// In the direct pipeline the workers are the writers
+		stalls := workerPool.Stalls()
+		if pipeline != "direct" {
+			stalls.Waiting, stalls.Writing = writerWait, writerBusy
+		}
+		if bottleneck := stalls.Bottleneck(); bottleneck != "" {
+			fmt.Printf("\nBottleneck: %s\n", bottleneck)
+			fmt.Printf("Workers: %s generating, %s handing chunks on\n",
+				stalls.Generating.Round(time.Millisecond), stalls.Blocked.Round(time.Millisecond))
+			if pipeline != "direct" {
+				fmt.Printf("Writer: %s waiting for chunks, %s writing\n",
+					stalls.Waiting.Round(time.Millisecond), stalls.Writing.Round(time.Millisecond))
+			}
+		}
+		printWriteLatency(writeLatency)
+	}
+
+	// A network receiver can't be read back, so report the streamed checksum
+	if network {
+		if err := socketWriter.Close(); err != nil {
+			return writeFailed(fmt.Errorf("failed to close connection: %w", err))
+		}
+		if cancelled() {
+			return errCancelled
+		}
+		if verbose {
+			fmt.Printf("\nData sent successfully!\n")
+			fmt.Printf("Target: %s\n", output)
+			fmt.Printf("SHA-256: %s\n", socketWriter.Checksum())
+		} else {
+			fmt.Printf("Successfully sent %d bytes to %s\n", sizeBytes, output)
+		}
+		return nil
+	}
+
+	// Completing the upload creates the object
+	if object {
+		if err := out.Close(); err != nil {
+			return writeFailed(err)
+		}
+		if cancelled() {
+			return errCancelled
+		}
+		if verbose {
+			fmt.Printf("\nUpload completed successfully!\n")
+			fmt.Printf("Object: %s\n", output)
+		} else {
+			fmt.Printf("Successfully uploaded %s\n", output)
+		}
+		return nil
+	}
+
 	// Close file writer
 	if err := fileWriter.Close(); err != nil {
-		return fmt.Errorf("failed to close file: %v", err)
+		return writeFailed(fmt.Errorf("failed to close file: %w", err))
 	}
 
-	// Write checksum file
-	if err := checksumGen.WriteChecksumFile(); err != nil {
-		return fmt.Errorf("failed to write checksum file: %v", err)
+	// Write the checksum file or trailer, removing the checksum file of a
+	// file this run replaced if it has none
+	if noChecksum || addTrailer || noMeta {
+		if err := removeChecksumFiles(output); err != nil {
+			return err
+		}
+	}
+	if addTrailer {
+		if err := appendTrailer(checksumGen, output, dataSize, pattern, patternSeed()); err != nil {
+			return err
+		}
+	} else if !noChecksum {
+		if err := checksumGen.WriteChecksumFile(); err != nil {
+			return writeFailed(fmt.Errorf("failed to write checksum file: %w", err))
+		}
+		runSpan.AddEvent("checksum written")
+		if !noMeta {
+			metaFile = output + report.MetadataSuffix
+		}
+	}
+	if manifest {
+		if err := checksumGen.WriteManifest(dataSize); err != nil {
+			return err
+		}
+	}
+	if merkle {
+		if err := checksumGen.WriteMerkle(dataSize); err != nil {
+			return err
+		}
+	}
+	// The full-file checksum read the data back into the page cache
+	if dropCache {
+		writer.DropCache(output)
+	}
+	if cancelled() {
+		return errCancelled
 	}
 
 	if verbose {
 		fmt.Printf("\nFile generation completed successfully!\n")
 		fmt.Printf("Output file: %s\n", output)
-		fmt.Printf("Checksum file: %s.checksum.txt\n", output)
+		if addTrailer {
+			fmt.Printf("Trailer: appended (check with trasher inspect %s)\n", output)
+		} else if !noChecksum {
+			fmt.Printf("Checksum file: %s.checksum.txt\n", output)
+		}
+		if metaFile != "" {
+			fmt.Printf("Metadata file: %s\n", metaFile)
+		}
+		if hasher := checksumGen.OrderedHasher(); orderedHash && hasher != nil {
+			if _, err := hasher.Sum(); err != nil {
+				fmt.Printf("Hash buffer: %s was not enough, the file was read back to compute its checksum\n", progress.FormatBytes(hasher.Limit()))
+			} else {
+				fmt.Printf("Hash buffer: peak %s of %s\n", progress.FormatBytes(hasher.Peak()), progress.FormatBytes(hasher.Limit()))
+			}
+		}
+		if manifest {
+			fmt.Printf("Manifest file: %s%s\n", output, checksum.ManifestSuffix)
+		}
+		if merkle {
+			fmt.Printf("Merkle tree: %s%s\n", output, checksum.MerkleSuffix)
+		}
 	} else {
 		fmt.Printf("Successfully generated %s\n", output)
 	}
@@ -219,22 +1182,317 @@ func runTrasher() error {
 	return nil
 }
 
+// newGenerator creates the generator for the pattern or pattern map.
+func newGenerator(pattern string, opts generator.Options) (generator.Generator, error) {
+	if patternMap == "" {
+		return generator.NewGeneratorWithOptions(pattern, opts)
+	}
+
+	regions, err := generator.ParsePatternMap(patternMap)
+	if err != nil {
+		return nil, err
+	}
+	return generator.NewPatternMapGenerator(regions, opts)
+}
+
+// newOutputGenerator creates the generator for the output: the pattern or
+// pattern map generator, returned as base, wrapped to add the magic header
+// and to encrypt with key when those are set.
+func newOutputGenerator(pattern string, opts generator.Options, key []byte) (gen, base generator.Generator, err error) {
+	if base, err = newGenerator(pattern, opts); err != nil {
+		return nil, nil, err
+	}
+
+	gen = base
+	if magic != "" {
+		if gen, err = generator.NewMagicGenerator(magic, gen); err != nil {
+			return nil, base, err
+		}
+	}
+	// Encrypt last so the whole output, header included, is ciphertext
+	if key != nil {
+		if gen, err = generator.NewEncryptGenerator(encrypt, key, gen); err != nil {
+			return nil, base, err
+		}
+	}
+	return gen, base, nil
+}
+
+// appendTrailer appends a trailer describing the size bytes of data at path,
+// generated with pattern and seed, so trasher inspect can identify and verify
+// the file without a checksum file.
+func appendTrailer(checksumGen *checksum.ChecksumGenerator, path string, size int64, pattern string, seed *int64) error {
+	digest, err := checksumGen.Finalize()
+	if err != nil {
+		return err
+	}
+
+	t := trailer.Trailer{
+		Generator: "trasher " + version,
+		CreatedAt: time.Now().UTC(),
+		Size:      size,
+		Pattern:   pattern,
+		Seed:      seed,
+		Algorithm: trailer.Algorithm,
+		Digest:    digest,
+	}
+	return trailer.Append(path, t)
+}
+
+// patternSeed returns the seed given with the pattern, or nil if the pattern
+// isn't seeded.
+func patternSeed() *int64 {
+	if patternMap != "" {
+		return nil
+	}
+	if _, opts, err := generator.ParsePattern(pattern, generator.Options{}); err == nil && opts.Seeded {
+		return &opts.Seed
+	}
+	return nil
+}
+
+// removeChecksumFiles removes the checksum file, manifest, Merkle tree and
+// metadata left next to path by an earlier run, which no longer describe it.
+func removeChecksumFiles(path string) error {
+	for _, sidecar := range []string{path + ".checksum.txt", path + checksum.ManifestSuffix, path + report.MetadataSuffix} {
+		if err := os.Remove(sidecar); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale %s: %v", sidecar, err)
+		}
+	}
+	return checksum.RemoveMerkle(path)
+}
+
+// printWriteLatency prints the distribution of the run's write latencies.
+func printWriteLatency(h *latency.Histogram) {
+	if h.Count() == 0 {
+		return
+	}
+	round := func(d time.Duration) time.Duration {
+		return d.Round(time.Microsecond)
+	}
+	fmt.Printf("Write latency: p50 %v, p95 %v, p99 %v, max %v (%d writes)\n",
+		round(h.Quantile(0.50)), round(h.Quantile(0.95)), round(h.Quantile(0.99)), round(h.Max()), h.Count())
+}
+
+// encryptionKey returns the key to encrypt the output with: the one given
+// with --encrypt-key, or a random one. Without --encrypt it returns nil.
+func encryptionKey() ([]byte, error) {
+	if encrypt == "" {
+		return nil, nil
+	}
+	if encryptKey != "" {
+		return generator.ParseEncryptKey(encryptKey)
+	}
+	return generator.NewEncryptKey()
+}
+
+// workerReport returns the table of per-worker statistics verbose runs print
+// now and then, followed by what the writer is doing if writerState is set.
+func workerReport(pool *worker.WorkerPool, writerState func() string) func() string {
+	table := worker.NewStatsTable(time.Now())
+	return func() string {
+		report := table.Format(pool.WorkerStats(), time.Now())
+		if writerState != nil {
+			report += writerState() + "\n"
+		}
+		return report
+	}
+}
+
+// withPermissions adds the mode, owner and group given with --mode, --owner
+// and --group to opts.
+func withPermissions(opts writer.Options) (writer.Options, error) {
+	if fileMode != "" {
+		mode, err := writer.ParseMode(fileMode)
+		if err != nil {
+			return opts, err
+		}
+		opts.Mode = &mode
+	}
+	if owner != "" {
+		uid, err := writer.LookupOwner(owner)
+		if err != nil {
+			return opts, err
+		}
+		opts.Owner = &uid
+	}
+	if group != "" {
+		gid, err := writer.LookupGroup(group)
+		if err != nil {
+			return opts, err
+		}
+		opts.Group = &gid
+	}
+	return opts, nil
+}
+
+// generatorOptions builds pattern-specific generator options from flags.
+func generatorOptions() (generator.Options, error) {
+	opts := generator.Options{
+		MixedStartZero:   mixedPhase == "zero",
+		MixedRandomRatio: mixedRatio,
+		CompressRatio:    compress,
+		DedupRatio:       dedupRatio,
+		Entropy:          entropy,
+		LogFormat:        logFormat,
+	}
+
+	if mixedChunk != "" {
+		size, err := sizeparser.Parse(mixedChunk)
+		if err != nil {
+			return opts, fmt.Errorf("failed to parse mixed chunk size: %v", err)
+		}
+		opts.MixedChunkSize = int(size)
+	}
+
+	if schema != "" {
+		fields, err := generator.ParseSchema(schema)
+		if err != nil {
+			return opts, fmt.Errorf("failed to parse schema: %v", err)
+		}
+		opts.Schema = fields
+	}
+
+	opts.ExecCommand = generator.ParseCommand(genCommand)
+
+	if corpus != "" {
+		text, err := os.ReadFile(corpus)
+		if err != nil {
+			return opts, fmt.Errorf("failed to read corpus: %v", err)
+		}
+		opts.Corpus = string(text)
+	}
+
+	if hexPattern != "" {
+		b, err := generator.ParseHexBytes(hexPattern)
+		if err != nil {
+			return opts, err
+		}
+		opts.PatternBytes = b
+	}
+
+	if dedupBlock != "" {
+		size, err := sizeparser.Parse(dedupBlock)
+		if err != nil {
+			return opts, fmt.Errorf("failed to parse dedup block size: %v", err)
+		}
+		opts.DedupBlockSize = int(size)
+	}
+
+	return opts, nil
+}
+
+// workersValue is the --workers flag: a worker count, or "auto" to scale the
+// number of active workers with measured throughput, up to two per CPU.
+type workersValue struct{}
+
+func (workersValue) String() string {
+	if autoScale {
+		return "auto"
+	}
+	return strconv.Itoa(workers)
+}
+
+func (workersValue) Set(s string) error {
+	if s == "auto" {
+		autoScale = true
+		workers = runtime.NumCPU() * 2
+		return nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("must be a number or auto")
+	}
+	autoScale = false
+	workers = n
+	return nil
+}
+
+func (workersValue) Type() string {
+	return "int"
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&size, "size", "s", "", "Size of file to generate (required)")
-	rootCmd.Flags().StringVarP(&pattern, "pattern", "p", "random", "Data pattern to generate (random, sequential, zero, mixed)")
-	rootCmd.Flags().StringVarP(&output, "output", "o", "", "Output file path (required)")
-	rootCmd.Flags().IntVarP(&workers, "workers", "w", runtime.NumCPU(), "Number of worker goroutines")
-	rootCmd.Flags().StringVarP(&chunkSize, "chunk-size", "c", "64MB", "Size of data chunks per worker")
+	rootCmd.Flags().StringVarP(&size, "size", "s", "", "Size of file to generate, or a percentage of the free space on the output's filesystem such as 50% (required unless --size-min and --size-max are given)")
+	rootCmd.Flags().StringVar(&leaveFree, "leave-free", "", "Instead of --size, make the file as large as it takes to leave this much free space on the output's filesystem, e.g. 10GB (with --count, shared evenly by the files)")
+	rootCmd.Flags().StringVarP(&pattern, "pattern", "p", "random", "Data pattern to generate (random, sequential, zero, mixed, compressible, dedup, entropy, text, jsonl, csv, logs, bytes, verify, crc, aa55, walking-ones, exec, markov), or weighted patterns like random:0.7,zero:0.3")
+	rootCmd.Flags().IntVar(&count, "count", 1, "Number of files to generate, each of --size, named after --output with a number added (e.g. test-1.dat) or after --output as a template (e.g. data-{seq:04}-{rand:6}.bin)")
+	rootCmd.Flags().StringVar(&sizeMin, "size-min", "", "With --count, the smallest random file size, e.g. 4KB (replaces --size)")
+	rootCmd.Flags().StringVar(&sizeMax, "size-max", "", "With --count, the largest random file size, e.g. 2GB (replaces --size)")
+	rootCmd.Flags().StringVar(&sizeDist, "size-dist", sizedist.Uniform, "Distribution of random file sizes: uniform, loguniform or lognormal")
+	rootCmd.Flags().StringVarP(&output, "output", "o", "", "Output file path, tcp://host:port or unix:///path to stream to a receiver, or s3://, gs:// or az:// object URL (required)")
+	workers = runtime.NumCPU()
+	rootCmd.Flags().VarP(workersValue{}, "workers", "w", "Number of worker goroutines, or auto to scale with measured throughput")
+	rootCmd.Flags().StringVarP(&chunkSize, "chunk-size", "c", "64MB", "Size of data chunks per worker, or auto to tune it during the run")
+	rootCmd.Flags().BoolVar(&warmUp, "calibrate", false, "Try a few worker counts and chunk sizes on 256MB of trial data first and run with the fastest")
+	rootCmd.Flags().BoolVar(&ordered, "ordered", false, "Write chunks strictly in offset order while workers still generate in parallel")
+	rootCmd.Flags().StringVar(&maxMemory, "max-memory", "", "Maximum memory held in chunk buffers, e.g. 2GB; workers wait when it is spent (default: no limit)")
+	rootCmd.Flags().BoolVar(&hugePages, "huge-pages", false, "Back chunk buffers with transparent huge pages where supported (Linux), reducing TLB misses at high rates")
+	rootCmd.Flags().BoolVar(&doubleBuf, "double-buffer", false, "In the direct pipeline, let each worker generate its next chunk while the previous one is written")
+	rootCmd.Flags().StringVar(&ioEngine, "io-engine", writer.DefaultEngine, "How file outputs are written: pwrite, seek, mmap, uring or direct (the last three on Linux)")
+	rootCmd.Flags().StringVar(&hashMode, "checksum-mode", checksum.ModeStream, "How the full-file checksum is computed: stream (read the file back once written), ordered (hash chunks in offset order as they are written) or tree (combine the chunk checksums workers computed)")
+	rootCmd.Flags().StringVar(&hashBuf, "hash-buffer", "", "Most out-of-order data --checksum-mode ordered holds per file while waiting for earlier chunks, e.g. 1GB (default 256MB)")
+	rootCmd.Flags().StringVar(&sumFormat, "checksum-format", checksum.FormatTrasher, "Checksum file format: trasher, or gnu or bsd for files sha256sum -c can check")
+	rootCmd.Flags().StringVar(&sumsFile, "checksum-file", "", "With --count, write one checksum file covering every file to this path instead of one per file")
+	rootCmd.Flags().BoolVar(&noChecksum, "no-checksum", false, "Skip hashing and don't write a checksum file, for scratch files")
+	rootCmd.Flags().BoolVar(&addTrailer, "trailer", false, "Append a trailer describing the file (size, pattern, seed, digest) instead of writing a checksum file")
+	rootCmd.Flags().BoolVar(&noMeta, "no-metadata", false, "Don't write the JSON metadata file (size, pattern, seed, timing, digest) next to the checksum file")
+	rootCmd.Flags().BoolVar(&manifest, "manifest", false, "Also write a JSON manifest with the offset, length and checksum of every chunk")
+	rootCmd.Flags().BoolVar(&merkle, "merkle", false, "Also write a Merkle tree over the chunk checksums (root and one file per level) to <output>.merkle/")
+	rootCmd.Flags().StringVar(&pipeline, "pipeline", "direct", "How chunks reach the output: direct (each worker writes its own chunks) or channel (one writer for all workers)")
+	rootCmd.Flags().IntVar(&maxIOPS, "max-iops", 0, "Maximum write operations per second, one per chunk (0 for no limit)")
+	rootCmd.Flags().DurationVar(&duration, "duration", 0, "Stop after this long, e.g. 10m, keeping the data written so far (0 for no limit)")
+	rootCmd.Flags().StringVar(&freeBelow, "stop-when-free-below", "", "Stop once the output's filesystem has less free space than this, e.g. 50GB, keeping the data written so far")
+	rootCmd.Flags().BoolVar(&drain, "graceful-drain", false, "On the first interrupt, finish and write the chunks already handed out to workers before stopping; a second interrupt aborts")
+	rootCmd.Flags().IntVar(&repeat, "repeat", 0, "Generate the output this many times, e.g. for a burn-in test, printing statistics over all iterations")
+	rootCmd.Flags().DurationVar(&loopFor, "loop", 0, "Generate the output over and over for this long, e.g. 24h; the last iteration finishes (with --repeat, whichever ends first)")
+	rootCmd.Flags().BoolVar(&cycle, "cycle", false, "Write, verify and delete the file over and over, until --repeat or --loop ends the test or a cycle finds corrupted data")
+	rootCmd.Flags().BoolVar(&verifyEach, "verify-each", false, "With --repeat or --loop, read the file back against its checksum file after each iteration")
+	rootCmd.Flags().BoolVar(&deleteEach, "delete-each", false, "With --repeat or --loop, delete the file and its checksum files after each iteration")
 	rootCmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing files without confirmation")
+	rootCmd.Flags().StringVar(&fileMode, "mode", "", "Permission bits of the generated files in octal, e.g. 0600, set exactly whatever the umask (default: 0644 less the umask)")
+	rootCmd.Flags().StringVar(&owner, "owner", "", "User name or ID to own the generated files (needs privileges)")
+	rootCmd.Flags().StringVar(&group, "group", "", "Group name or ID of the generated files (needs privileges unless the user is a member)")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.Flags().StringVar(&mixedChunk, "mixed-chunk", "1KB", "Length of each random/zero run for the mixed pattern")
+	rootCmd.Flags().StringVar(&mixedPhase, "mixed-phase", "random", "Run the mixed pattern starts with (random, zero)")
+	rootCmd.Flags().Float64Var(&mixedRatio, "mixed-random-ratio", 0.5, "Fraction of the mixed pattern's output that is random, e.g. 0.7 for 70% random / 30% zero")
+	rootCmd.Flags().Float64Var(&compress, "compress-ratio", generator.DefaultCompressRatio, "Compression ratio targeted by the compressible pattern")
+	rootCmd.Flags().Float64Var(&dedupRatio, "dedup-ratio", generator.DefaultDedupRatio, "Fraction of blocks the dedup pattern repeats (0 to 1)")
+	rootCmd.Flags().StringVar(&dedupBlock, "dedup-block", "4KB", "Block size the dedup pattern repeats data at")
+	rootCmd.Flags().Float64Var(&entropy, "entropy", generator.DefaultEntropy, "Shannon entropy targeted by the entropy pattern, from 0 (constant) to 1 (random)")
+	rootCmd.Flags().StringVar(&schema, "schema", generator.DefaultSchema, "Record fields for the jsonl and csv patterns as name:type,...")
+	rootCmd.Flags().StringVar(&logFormat, "log-format", generator.LogFormatSyslog, "Line format of the logs pattern (syslog, apache)")
+	rootCmd.Flags().StringVar(&hexPattern, "pattern-bytes", "", "Hex byte sequence to repeat, e.g. 0xDEADBEEF (selects the bytes pattern)")
+	rootCmd.Flags().StringVar(&corpus, "corpus", "", "Text file the markov pattern learns its prose from (default: built-in corpus)")
+	rootCmd.Flags().StringVar(&genCommand, "generator-cmd", "", "External generator command serving data on stdout (selects the exec pattern)")
+	rootCmd.Flags().StringVar(&patternMap, "pattern-map", "", "Patterns per file region, e.g. 0-10GB=zero,10GB-end=random (replaces --pattern)")
+	rootCmd.Flags().BoolVar(&sparse, "sparse", false, "Only allocate blocks holding non-zero data, leaving zero blocks as holes")
+	rootCmd.Flags().BoolVar(&dropCache, "drop-cache", false, "Drop written data from the page cache so large files don't evict other cached data (Linux)")
+	rootCmd.Flags().BoolVar(&writeZeros, "write-zeros", false, "Physically write the zero pattern instead of leaving the file sparse")
+	rootCmd.Flags().StringVar(&magic, "magic", "", "Start the file with a valid header of this format (png, zip, pdf, mp4)")
+	rootCmd.Flags().StringVar(&encrypt, "encrypt", "", "Encrypt the output with this cipher (aes-ctr)")
+	rootCmd.Flags().StringVar(&encryptKey, "encrypt-key", "", "Hex AES key for --encrypt, 16, 24 or 32 bytes (default: random 256-bit key)")
+	rootCmd.Flags().StringVar(&summary, "summary-json", "", "Write a JSON summary of the run (throughput, duration, checksum) to this path")
+	rootCmd.Flags().BoolVar(&jsonOut, "json", false, "Print no progress or messages, only a JSON document of the result (bytes written, duration, average and peak throughput, checksum, exit code) at the end")
+	rootCmd.Flags().BoolVar(&noHistory, "no-history", false, "Do not record this run in the history file")
+	rootCmd.Flags().StringVar(&historyLog, "history-file", "", "History file to record the run in (default: per-user config directory)")
+	rootCmd.Flags().StringVar(&expect.Serial, "expect-serial", "", "Serial number the target block device must have")
+	rootCmd.Flags().StringVar(&expect.WWN, "expect-wwn", "", "WWN the target block device must have")
+	rootCmd.Flags().StringVar(&expectSize, "expect-size", "", "Exact capacity the target block device must have (e.g. 960GB)")
+	rootCmd.Flags().BoolVar(&noLock, "no-lock", false, "Do not take an advisory lock on the output path")
+	rootCmd.Flags().BoolVar(&cleanupErr, "cleanup-on-error", false, "Remove the partial output file and checksum file if the run fails or is cancelled")
+	rootCmd.Flags().StringVar(&ctlSocket, "control-socket", "", "Unix socket path exposing live progress and pause/resume/cancel control")
+	rootCmd.Flags().StringVar(&otelURL, "otel-endpoint", "", "OTLP/HTTP collector to export a trace span and metrics of each run to, e.g. http://localhost:4318")
+	rootCmd.Flags().BoolVar(&tuiMode, "tui", false, "Show a live dashboard of progress, throughput, write latency, worker states and errors, with keys to pause, throttle or abort the run")
 
-	rootCmd.MarkFlagRequired("size")
 	rootCmd.MarkFlagRequired("output")
-}
\ No newline at end of file
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/device"
+	"github.com/maxkimambo/trasher/internal/jobfile"
+	"github.com/maxkimambo/trasher/internal/lock"
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/validation"
+	"github.com/maxkimambo/trasher/internal/worker"
+	"github.com/maxkimambo/trasher/internal/writer"
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/sizeparser"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run JOBFILE",
+	Short: "Generate the files listed in a job file",
+	Long: `Run generates every file listed in a YAML or JSON job file (JSON if its name
+ends in .json) in one run: the files share one worker pool and are generated
+at the same time, with progress, the summary and the history entry covering
+them all. Each file has its own path, size, pattern and seed:
+
+  defaults:
+    pattern: random
+    size: 1GB
+  files:
+    - path: data/a.bin
+      size: 10GB
+      seed: 42
+    - path: data/b.bin
+      pattern: zero
+
+Entries take the size and pattern they leave out from defaults; without a
+seed, a file's data is random for each run. Every file gets a checksum file
+and metadata as with --count.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if noChecksum && cmd.Flags().Changed("checksum-mode") {
+			return fmt.Errorf("--no-checksum cannot be used with --checksum-mode")
+		}
+		if err := startExporter(); err != nil {
+			return err
+		}
+		if jsonOut {
+			return runJSON(func() error { return runJobs(args[0]) })
+		}
+		return runJobs(args[0])
+	},
+}
+
+func runJobs(path string) error {
+	jobList, err := jobfile.Load(path)
+	if err != nil {
+		return err
+	}
+
+	specs := make([]batchSpec, len(jobList))
+	validator := validation.NewValidator()
+	for i, job := range jobList {
+		if writer.IsNetworkTarget(job.Path) || writer.IsObjectTarget(job.Path) || device.IsBlockDevice(job.Path) {
+			return fmt.Errorf("%s: job files can only list files", job.Path)
+		}
+		config := validation.ValidationConfig{
+			Size:         fmt.Sprintf("%dB", job.Size),
+			Pattern:      job.Pattern,
+			OutputPath:   job.Path,
+			Workers:      workers,
+			ChunkSize:    chunkSize,
+			Force:        force,
+			MaxIOPS:      maxIOPS,
+			MaxMemory:    maxMemory,
+			ChecksumMode: hashMode,
+		}
+		if err := validator.ValidateAll(config); err != nil {
+			return validationFailed(fmt.Errorf("%s: %w", job.Path, err))
+		}
+		specs[i] = batchSpec{name: job.Path, size: job.Size, pattern: job.Pattern, seed: job.Seed}
+	}
+
+	if !noLock {
+		for _, spec := range specs {
+			targetLock, err := lock.Acquire(spec.name)
+			if err != nil {
+				return err
+			}
+			defer targetLock.Release()
+		}
+	}
+
+	chunkAuto := chunkSize == "auto"
+	chunkSizeBytes := int64(worker.MaxTunedChunkSize)
+	if !chunkAuto {
+		if chunkSizeBytes, err = sizeparser.Parse(chunkSize); err != nil {
+			return fmt.Errorf("failed to parse chunk size: %v", err)
+		}
+	}
+	var maxMemoryBytes int64
+	if maxMemory != "" {
+		if maxMemoryBytes, err = sizeparser.Parse(maxMemory); err != nil {
+			return fmt.Errorf("failed to parse memory budget: %v", err)
+		}
+	}
+
+	if verbose {
+		var totalBytes int64
+		for _, spec := range specs {
+			totalBytes += spec.size
+		}
+		fmt.Printf("Generating %d files from %s: %s in total\n", len(specs), path, progress.FormatBytes(totalBytes))
+		for _, spec := range specs {
+			seed := "random seed"
+			if spec.seed != nil {
+				seed = fmt.Sprintf("seed %d", *spec.seed)
+			}
+			fmt.Printf("  %s: %s of %s, %s\n", spec.name, progress.FormatBytes(spec.size), spec.pattern, seed)
+		}
+		fmt.Printf("Workers: %d\n", workers)
+		fmt.Printf("Chunk size: %s\n", chunkSize)
+		fmt.Println()
+	}
+
+	// The summary and history name the job file as the run's output
+	output = path
+	endTrace := traceRun(path, batchPattern(specs))
+	err = runBatch(specs, chunkSizeBytes, maxMemoryBytes, int64(checksum.DefaultHashBuffer), chunkAuto)
+	endTrace(err)
+	return err
+}
+
+func init() {
+	runCmd.Flags().VarP(workersValue{}, "workers", "w", "Number of worker goroutines shared by all files, or auto to scale with measured throughput")
+	runCmd.Flags().StringVarP(&chunkSize, "chunk-size", "c", "64MB", "Size of data chunks per worker, or auto to tune it during the run")
+	runCmd.Flags().StringVar(&maxMemory, "max-memory", "", "Maximum memory held in chunk buffers, e.g. 2GB; workers wait when it is spent (default: no limit)")
+	runCmd.Flags().IntVar(&maxIOPS, "max-iops", 0, "Maximum write operations per second across all files, one per chunk (0 for no limit)")
+	runCmd.Flags().StringVar(&hashMode, "checksum-mode", checksum.ModeStream, "How each file's full checksum is computed: stream, ordered or tree")
+	runCmd.Flags().BoolVar(&noChecksum, "no-checksum", false, "Skip hashing and don't write checksum files, for scratch files")
+	runCmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing files without confirmation")
+	runCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	runCmd.Flags().BoolVar(&jsonOut, "json", false, "Print no progress or messages, only a JSON document of the result (bytes written, duration, average and peak throughput, exit code) at the end")
+	runCmd.Flags().StringVar(&summary, "summary-json", "", "Write a JSON summary of the run (throughput, duration) to this path")
+	runCmd.Flags().BoolVar(&noHistory, "no-history", false, "Do not record this run in the history file")
+	runCmd.Flags().BoolVar(&noLock, "no-lock", false, "Do not take advisory locks on the files")
+	runCmd.Flags().BoolVar(&cleanupErr, "cleanup-on-error", false, "Remove the partial files and checksum files if the run fails or is cancelled")
+	runCmd.Flags().StringVar(&otelURL, "otel-endpoint", "", "OTLP/HTTP collector to export a trace of the run, with a span per file, and its metrics to, e.g. http://localhost:4318")
+	rootCmd.AddCommand(runCmd)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/jobs"
+	"github.com/maxkimambo/trasher/internal/progress"
+)
+
+var statusAll bool
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List running and recently completed generation jobs",
+	Long: `Status lists the user's trasher jobs on this host by reading their job
+state directory. Running jobs show live progress, throughput and ETA; jobs
+that finished within the last 24 hours are listed with their final state.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStatus()
+	},
+}
+
+func runStatus() error {
+	dir, err := jobs.DefaultDir()
+	if err != nil {
+		return err
+	}
+	records, err := jobs.List(dir)
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No jobs found")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATE\tOUTPUT\tPROGRESS\tTHROUGHPUT\tETA")
+	for _, r := range records {
+		if !statusAll && r.State == jobs.StateStale {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.1f%% (%s / %s)\t%s\t%s\n",
+			r.ID,
+			r.State,
+			r.Output,
+			r.Percent(),
+			progress.FormatBytes(r.Written),
+			progress.FormatBytes(r.TotalBytes),
+			progress.FormatThroughput(r.Throughput),
+			progress.FormatDuration(r.ETA()))
+	}
+	return tw.Flush()
+}
+
+// registerJob records a job in the user's job state directory for trasher
+// status, returning nil if it can't be. The job runs either way, so the
+// failure is only reported in verbose mode.
+func registerJob(output, pattern string, totalBytes int64) *jobs.Tracker {
+	dir, err := jobs.DefaultDir()
+	if err == nil {
+		var tracker *jobs.Tracker
+		if tracker, err = jobs.Register(dir, output, pattern, totalBytes); err == nil {
+			return tracker
+		}
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Warning: %s won't be listed by trasher status: %v\n", output, err)
+	}
+	return nil
+}
+
+func init() {
+	statusCmd.Flags().BoolVarP(&statusAll, "all", "a", false, "Include stale jobs whose process stopped reporting")
+	rootCmd.AddCommand(statusCmd)
+}
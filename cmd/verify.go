@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/signal"
+	"github.com/maxkimambo/trasher/internal/verify"
+	"github.com/maxkimambo/trasher/pkg/checksum"
+)
+
+var (
+	verifySample   string
+	verifySeed     uint64
+	verifyWorkers  int
+	verifyVerbose  bool
+	verifyMmap     bool
+	verifyStamps   bool
+	verifyCRC      bool
+	verifyRunID    string
+	verifyManifest string
+)
+
+// maxListedBlocks is how many bad blocks a stamp verification prints.
+const maxListedBlocks = 20
+
+// verifyError gives errors from the verify package the exit code of their
+// kind.
+func verifyError(err error) error {
+	var missing *verify.MissingChecksumsError
+	var readErr *verify.ReadError
+	switch {
+	case errors.As(err, &missing):
+		return &exitError{code: exitMissingChecksum, err: err}
+	case errors.As(err, &readErr):
+		return &exitError{code: exitReadError, err: err}
+	}
+	return err
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <file | checksum file>",
+	Short: "Verify a generated file against its chunk checksums",
+	Long: `Verify reads a generated file and compares each chunk against the chunk
+checksums recorded in its .checksum.txt file.
+
+With --sample only a seeded random subset of chunks is read, which gives a
+high-confidence integrity check of multi-terabyte files in a fraction of the
+time. The seed is printed so a sampled run can be repeated exactly.
+
+Chunks are read and hashed in parallel by a pool of workers, so verification
+runs at the read speed of the device.
+
+With --stamps, a file generated with the verify pattern is checked block by
+block against the offset and run ID stamped into each block, which pinpoints
+misdirected and lost writes. No checksum file is needed.
+
+With --crc, a file generated with the crc pattern is checked block by block
+against the CRC and offset each block carries, telling corrupted blocks apart
+from intact blocks written to the wrong place.
+
+With --manifest, chunks are checked against a chunk manifest written with
+--manifest instead of the checksum file.
+
+Given a checksum file covering several files, written with --checksum-file
+or by sha256sum, every file it lists is verified in turn.
+
+Exit codes:
+  0  the file matches
+  1  invalid arguments or another error
+  2  corrupted chunks or bad blocks were found
+  3  the checksum file or manifest does not exist
+  4  the file could not be read`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runVerify(cmd, args[0])
+	},
+}
+
+func runVerify(cmd *cobra.Command, path string) error {
+	opts := verify.Options{}
+	if verifySample != "" {
+		fraction, err := verify.ParseSample(verifySample)
+		if err != nil {
+			return err
+		}
+		opts.Sample = fraction
+
+		opts.Seed = verifySeed
+		if !cmd.Flags().Changed("seed") {
+			opts.Seed = uint64(time.Now().UnixNano())
+		}
+		fmt.Printf("Sampling %s of chunks with seed %d\n", verifySample, opts.Seed)
+	}
+
+	opts.Workers = verifyWorkers
+	opts.Mmap = verifyMmap
+	opts.Stamps = verifyStamps
+	opts.CRC = verifyCRC
+	opts.Manifest = verifyManifest
+	blocks := verifyStamps || verifyCRC
+	if verifyStamps && verifyCRC {
+		return fmt.Errorf("--stamps can't be combined with --crc")
+	}
+	if verifyManifest != "" && blocks {
+		return fmt.Errorf("--manifest can't be combined with --stamps or --crc")
+	}
+	if verifyRunID != "" {
+		if !blocks {
+			return fmt.Errorf("--run-id requires --stamps or --crc")
+		}
+		runID, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(verifyRunID), "0x"), 16, 64)
+		if err != nil {
+			return fmt.Errorf("invalid run ID %q: must be hex", verifyRunID)
+		}
+		opts.RunID = runID
+	}
+
+	if !blocks && verifyManifest == "" {
+		if _, err := os.Stat(path + ".checksum.txt"); os.IsNotExist(err) {
+			if set, err := checksum.LoadFileSet(path); err == nil {
+				return verifyFileSet(path, set, opts)
+			}
+		}
+	}
+
+	v, err := verify.New(path, opts)
+	if err != nil {
+		return verifyError(err)
+	}
+	if blocks {
+		fmt.Printf("Checking block stamps for run %016x\n", v.RunID())
+	}
+
+	ctx, shutdownHandler := signal.WithShutdownHandler(os.Stdout)
+	defer shutdownHandler.Stop()
+
+	progressReporter := progress.NewProgressReporter(v.Bytes(), verifyVerbose, os.Stdout)
+	shutdownHandler.SetProgressReporter(progressReporter)
+	progressReporter.Start(v.BytesRead)
+
+	result, err := v.Run(ctx)
+	progressReporter.Stop()
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("verification interrupted after %s", progress.FormatBytes(v.BytesRead()))
+		}
+		return verifyError(err)
+	}
+
+	fmt.Printf("Checked %d of %d chunks (%s) in %s, %s\n",
+		result.Checked, result.TotalChunks, progress.FormatBytes(result.BytesRead),
+		progress.FormatDuration(result.Elapsed), progress.FormatThroughput(result.Throughput()))
+	if blocks {
+		listed := result.BadBlocks[:min(len(result.BadBlocks), maxListedBlocks)]
+		for _, block := range listed {
+			fmt.Printf("  bad block: %s\n", block)
+		}
+		if hidden := result.BadBlockCount - int64(len(listed)); hidden > 0 {
+			fmt.Printf("  ... and %d more bad blocks\n", hidden)
+		}
+		if !result.Clean() {
+			return &exitError{code: exitMismatch, err: fmt.Errorf("%d bad blocks found", result.BadBlockCount)}
+		}
+		fmt.Println("OK")
+		return nil
+	}
+
+	for _, chunk := range result.Corrupted {
+		fmt.Printf("  corrupted: offset %d, %d bytes\n", chunk.Offset, chunk.Size)
+	}
+
+	if !result.Clean() {
+		return &exitError{code: exitMismatch, err: fmt.Errorf("%d corrupted chunks found", len(result.Corrupted))}
+	}
+	fmt.Println("OK")
+	return nil
+}
+
+// verifyFileSet verifies every file listed in the checksum file at setPath,
+// printing a line per file. Corrupted files take precedence over missing
+// ones in the exit code.
+func verifyFileSet(setPath string, set *checksum.FileSet, opts verify.Options) error {
+	ctx, shutdownHandler := signal.WithShutdownHandler(os.Stdout)
+	defer shutdownHandler.Stop()
+
+	var failed, missing int
+	for _, entry := range set.Files {
+		path := entry.ResolvePath(setPath)
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Printf("%s: MISSING\n", entry.Path)
+			missing++
+			continue
+		}
+
+		fileOpts := opts
+		fileOpts.Chunks, err = entry.ChunkChecksums(info.Size(), set.Algorithm)
+		if err != nil {
+			fmt.Printf("%s: FAILED (%v)\n", entry.Path, err)
+			failed++
+			continue
+		}
+		v, err := verify.New(path, fileOpts)
+		if err != nil {
+			return verifyError(err)
+		}
+		result, err := v.Run(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("verification interrupted at %s", entry.Path)
+			}
+			return verifyError(err)
+		}
+
+		if !result.Clean() {
+			fmt.Printf("%s: FAILED (%d corrupted chunks)\n", entry.Path, len(result.Corrupted))
+			for _, chunk := range result.Corrupted {
+				fmt.Printf("  corrupted: offset %d, %d bytes\n", chunk.Offset, chunk.Size)
+			}
+			failed++
+			continue
+		}
+		fmt.Printf("%s: OK (%s in %s)\n", entry.Path, progress.FormatBytes(result.BytesRead), progress.FormatDuration(result.Elapsed))
+	}
+
+	if failed > 0 || missing > 0 {
+		code := exitMismatch
+		if failed == 0 {
+			code = exitReadError
+		}
+		return &exitError{code: code, err: fmt.Errorf("%d files failed and %d missing of %d", failed, missing, len(set.Files))}
+	}
+	fmt.Printf("Checked %d files\n", len(set.Files))
+	fmt.Println("OK")
+	return nil
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifySample, "sample", "", "Verify only this share of chunks, e.g. 1% (default: all chunks)")
+	verifyCmd.Flags().Uint64Var(&verifySeed, "seed", 0, "Seed for choosing sampled chunks (default: random, printed)")
+	verifyCmd.Flags().IntVarP(&verifyWorkers, "workers", "w", runtime.NumCPU(), "Number of parallel readers")
+	verifyCmd.Flags().BoolVar(&verifyMmap, "mmap", false, "Read the file through a memory mapping instead of copying chunks")
+	verifyCmd.Flags().BoolVar(&verifyStamps, "stamps", false, "Check the offset and run ID stamped into each block by the verify pattern")
+	verifyCmd.Flags().BoolVar(&verifyCRC, "crc", false, "Check the CRC, offset and run ID each block of the crc pattern carries")
+	verifyCmd.Flags().StringVar(&verifyRunID, "run-id", "", "Run ID (hex) stamped blocks must carry (default: read from the first block)")
+	verifyCmd.Flags().StringVar(&verifyManifest, "manifest", "", "Chunk manifest (.manifest.json) to verify against instead of the checksum file")
+	verifyCmd.Flags().BoolVarP(&verifyVerbose, "verbose", "v", false, "Show detailed progress")
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -0,0 +1,82 @@
+// Package burnin accumulates the results of a test that writes, and
+// optionally verifies, a target over and over, such as a drive endurance
+// test.
+package burnin
+
+import "time"
+
+// Iteration is the result of one pass of a repeated test.
+type Iteration struct {
+	Number    int
+	Written   int64
+	WriteTime time.Duration
+	// Verified reports whether the data was read back and checked; Corrupted
+	// is then the number of chunks that didn't match.
+	Verified   bool
+	VerifyRead int64
+	VerifyTime time.Duration
+	Corrupted  int
+}
+
+// WriteThroughput returns the iteration's write rate in bytes per second.
+func (it Iteration) WriteThroughput() float64 {
+	return rate(it.Written, it.WriteTime)
+}
+
+// VerifyThroughput returns the iteration's read-back rate in bytes per
+// second.
+func (it Iteration) VerifyThroughput() float64 {
+	return rate(it.VerifyRead, it.VerifyTime)
+}
+
+// Stats accumulates the iterations of a test.
+type Stats struct {
+	Iterations int
+	// Corrupted is the number of iterations whose verification failed.
+	Corrupted  int
+	Written    int64
+	WriteTime  time.Duration
+	VerifyRead int64
+	VerifyTime time.Duration
+	// MinWrite and MaxWrite are the slowest and fastest iteration's write
+	// throughput, which show a drive slowing down over a long test.
+	MinWrite float64
+	MaxWrite float64
+}
+
+// Add records an iteration.
+func (s *Stats) Add(it Iteration) {
+	throughput := it.WriteThroughput()
+	if s.Iterations == 0 || throughput < s.MinWrite {
+		s.MinWrite = throughput
+	}
+	s.MaxWrite = max(s.MaxWrite, throughput)
+
+	s.Iterations++
+	s.Written += it.Written
+	s.WriteTime += it.WriteTime
+	s.VerifyRead += it.VerifyRead
+	s.VerifyTime += it.VerifyTime
+	if it.Verified && it.Corrupted > 0 {
+		s.Corrupted++
+	}
+}
+
+// WriteThroughput returns the average write rate over all iterations in
+// bytes per second.
+func (s *Stats) WriteThroughput() float64 {
+	return rate(s.Written, s.WriteTime)
+}
+
+// VerifyThroughput returns the average read-back rate over all iterations
+// in bytes per second.
+func (s *Stats) VerifyThroughput() float64 {
+	return rate(s.VerifyRead, s.VerifyTime)
+}
+
+func rate(bytes int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes) / elapsed.Seconds()
+}
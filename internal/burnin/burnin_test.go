@@ -0,0 +1,42 @@
+package burnin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	var s Stats
+	if s.WriteThroughput() != 0 || s.VerifyThroughput() != 0 {
+		t.Error("expected no throughput without iterations")
+	}
+
+	s.Add(Iteration{Number: 1, Written: 200, WriteTime: time.Second})
+	s.Add(Iteration{Number: 2, Written: 200, WriteTime: 2 * time.Second,
+		Verified: true, VerifyRead: 200, VerifyTime: time.Second})
+	s.Add(Iteration{Number: 3, Written: 200, WriteTime: time.Second,
+		Verified: true, VerifyRead: 200, VerifyTime: time.Second, Corrupted: 2})
+
+	if s.Iterations != 3 || s.Written != 600 || s.Corrupted != 1 {
+		t.Errorf("unexpected totals %+v", s)
+	}
+	if s.MinWrite != 100 || s.MaxWrite != 200 {
+		t.Errorf("expected write throughput from 100 to 200, got %v to %v", s.MinWrite, s.MaxWrite)
+	}
+	if s.WriteThroughput() != 150 {
+		t.Errorf("expected an average of 150, got %v", s.WriteThroughput())
+	}
+	if s.VerifyThroughput() != 200 {
+		t.Errorf("expected a verify average of 200, got %v", s.VerifyThroughput())
+	}
+}
+
+func TestIterationThroughput(t *testing.T) {
+	it := Iteration{Written: 1000, WriteTime: 2 * time.Second}
+	if it.WriteThroughput() != 500 {
+		t.Errorf("expected 500, got %v", it.WriteThroughput())
+	}
+	if it.VerifyThroughput() != 0 {
+		t.Errorf("expected no verify throughput, got %v", it.VerifyThroughput())
+	}
+}
@@ -0,0 +1,143 @@
+package calibrate
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"os"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/worker"
+	"github.com/maxkimambo/trasher/internal/writer"
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+// DefaultTotal is the amount of data written across all trials.
+const DefaultTotal = 256 * 1024 * 1024
+
+// ChunkSizes are the chunk sizes tried with each worker count.
+var ChunkSizes = []int64{1024 * 1024, 4 * 1024 * 1024, 16 * 1024 * 1024}
+
+// Config is a worker count and chunk size combination.
+type Config struct {
+	Workers   int
+	ChunkSize int64
+}
+
+// Trial is the result of writing with one configuration.
+type Trial struct {
+	Config
+	Bytes    int64
+	Duration time.Duration
+}
+
+// Throughput returns the trial's throughput in bytes per second.
+func (t Trial) Throughput() float64 {
+	if t.Duration <= 0 {
+		return 0
+	}
+	return float64(t.Bytes) / t.Duration.Seconds()
+}
+
+// Options configure how trials are run. Trials should be run the way the
+// main run will be, so its costs are measured too.
+type Options struct {
+	// Writer configures the trial file.
+	Writer writer.Options
+	// NewHash, if set, hashes every chunk as the main run does.
+	NewHash func() hash.Hash
+}
+
+// Candidates returns the configurations worth trying for up to maxWorkers
+// workers: half and all of them, each with every size in ChunkSizes.
+func Candidates(maxWorkers int) []Config {
+	counts := []int{max(maxWorkers/2, 1)}
+	if maxWorkers > counts[0] {
+		counts = append(counts, maxWorkers)
+	}
+
+	var configs []Config
+	for _, workers := range counts {
+		for _, chunkSize := range ChunkSizes {
+			configs = append(configs, Config{Workers: workers, ChunkSize: chunkSize})
+		}
+	}
+	return configs
+}
+
+// Run writes an equal share of total bytes to a trial file at path with each
+// configuration in turn and returns the trials in the same order. Each trial
+// includes syncing its data to disk, and the trial file is removed after
+// every trial.
+func Run(ctx context.Context, path string, total int64, configs []Config, newGen generator.Factory, opts Options) ([]Trial, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no configurations to calibrate")
+	}
+
+	size := max(total/int64(len(configs)), 1)
+	trials := make([]Trial, 0, len(configs))
+	for _, config := range configs {
+		trial, err := runTrial(ctx, path, size, config, newGen, opts)
+		if err != nil {
+			return nil, fmt.Errorf("calibrating %d workers with %d byte chunks: %v", config.Workers, config.ChunkSize, err)
+		}
+		trials = append(trials, trial)
+	}
+	return trials, nil
+}
+
+// runTrial writes size bytes to path with one configuration.
+func runTrial(ctx context.Context, path string, size int64, config Config, newGen generator.Factory, opts Options) (trial Trial, err error) {
+	writerOpts := opts.Writer
+	writerOpts.Force = true
+	out, err := writer.NewFileWriterWithOptions(path, size, writerOpts)
+	if err != nil {
+		return Trial{}, err
+	}
+	defer func() {
+		if closeErr := out.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		os.Remove(path)
+	}()
+
+	pool := worker.NewWorkerPool(ctx, config.Workers, config.ChunkSize)
+	if opts.NewHash != nil {
+		pool.SetChunkHash(opts.NewHash)
+	}
+	pool.SetSplitSize(worker.DefaultSplitSize)
+	pool.SetSink(func(result worker.Result) error {
+		return out.WriteAt(result.Buffer, result.Offset)
+	})
+
+	start := time.Now()
+	if _, err := pool.StartWithFactory(newGen, size); err != nil {
+		pool.Close()
+		return Trial{}, err
+	}
+	pool.Wait()
+
+	if errs := pool.Errors(); len(errs) > 0 {
+		return Trial{}, errs[0]
+	}
+	if err := ctx.Err(); err != nil {
+		return Trial{}, err
+	}
+	if err := out.Close(); err != nil {
+		return Trial{}, err
+	}
+
+	return Trial{Config: config, Bytes: size, Duration: time.Since(start)}, nil
+}
+
+// Best returns the trial with the highest throughput. Ties go to the earlier
+// trial, so fewer workers and smaller chunks win when they are as fast.
+func Best(trials []Trial) Trial {
+	var best Trial
+	for _, trial := range trials {
+		if trial.Throughput() > best.Throughput() {
+			best = trial
+		}
+	}
+	return best
+}
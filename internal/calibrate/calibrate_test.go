@@ -0,0 +1,104 @@
+package calibrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		maxWorkers int
+		counts     []int
+	}{
+		{1, []int{1}},
+		{2, []int{1, 2}},
+		{8, []int{4, 8}},
+	}
+
+	for _, test := range tests {
+		configs := Candidates(test.maxWorkers)
+		if len(configs) != len(test.counts)*len(ChunkSizes) {
+			t.Fatalf("%d workers: expected %d configurations, got %d", test.maxWorkers, len(test.counts)*len(ChunkSizes), len(configs))
+		}
+		for i, config := range configs {
+			workers := test.counts[i/len(ChunkSizes)]
+			chunkSize := ChunkSizes[i%len(ChunkSizes)]
+			if config.Workers != workers || config.ChunkSize != chunkSize {
+				t.Errorf("%d workers: expected configuration %d to be %+v, got %+v",
+					test.maxWorkers, i, Config{workers, chunkSize}, config)
+			}
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trial.dat")
+	configs := []Config{{Workers: 1, ChunkSize: 4096}, {Workers: 2, ChunkSize: 16384}}
+	newGen := generator.NewFactory("sequential", generator.Options{})
+
+	trials, err := Run(context.Background(), path, 256*1024, configs, newGen, Options{NewHash: sha256.New})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(trials) != len(configs) {
+		t.Fatalf("expected %d trials, got %d", len(configs), len(trials))
+	}
+	for i, trial := range trials {
+		if trial.Config != configs[i] {
+			t.Errorf("expected trial %d to use %+v, got %+v", i, configs[i], trial.Config)
+		}
+		if trial.Bytes != 128*1024 {
+			t.Errorf("expected trial %d to write %d bytes, got %d", i, 128*1024, trial.Bytes)
+		}
+		if trial.Throughput() <= 0 {
+			t.Errorf("expected trial %d to measure a throughput", i)
+		}
+	}
+
+	// The trial file is not left behind
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the trial file to be removed, got %v", err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trial.dat")
+	newGen := generator.NewFactory("sequential", generator.Options{})
+
+	if _, err := Run(context.Background(), path, 1024, nil, newGen, Options{}); err == nil {
+		t.Error("expected an error without configurations")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, path, 1024*1024, Candidates(1), newGen, Options{}); err == nil {
+		t.Error("expected an error for a cancelled calibration")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the trial file to be removed, got %v", err)
+	}
+}
+
+func TestBest(t *testing.T) {
+	trials := []Trial{
+		{Config{1, 1024}, 1000, time.Second},
+		{Config{2, 1024}, 3000, time.Second},
+		{Config{4, 1024}, 3000, time.Second},
+		{Config{8, 1024}, 2000, time.Second},
+	}
+
+	// The earlier of equally fast trials wins
+	if best := Best(trials); best.Config != (Config{2, 1024}) {
+		t.Errorf("expected the trial with 2 workers to win, got %+v", best.Config)
+	}
+	if best := Best(nil); best.Workers != 0 {
+		t.Errorf("expected no best trial without trials, got %+v", best)
+	}
+}
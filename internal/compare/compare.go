@@ -0,0 +1,261 @@
+package compare
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/worker"
+)
+
+// DefaultChunkSize is how much of each file a worker compares at a time.
+const DefaultChunkSize = 4 * 1024 * 1024
+
+// Options controls how two files are compared.
+type Options struct {
+	// Workers is the number of parallel readers. Zero uses one per CPU.
+	Workers int
+	// ChunkSize is how much of each file a worker reads at a time. Zero
+	// uses DefaultChunkSize.
+	ChunkSize int64
+	// MaxRanges is how many differing ranges the result lists; all of them
+	// are counted. Zero lists none.
+	MaxRanges int
+}
+
+// Range is a run of differing bytes.
+type Range struct {
+	Offset int64
+	Length int64
+}
+
+// Result describes how two files differ.
+type Result struct {
+	SizeA int64
+	SizeB int64
+	// Ranges lists the first differing ranges in file order, up to
+	// Options.MaxRanges. Bytes past the end of the shorter file are one
+	// range.
+	Ranges []Range
+	// RangeCount and DifferentBytes count every differing range and byte.
+	RangeCount     int64
+	DifferentBytes int64
+	BytesRead      int64
+	Elapsed        time.Duration
+}
+
+// Identical returns true if the files have the same size and contents.
+func (r *Result) Identical() bool {
+	return r.RangeCount == 0
+}
+
+// Throughput returns the average read rate of both files together in bytes
+// per second.
+func (r *Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.BytesRead) / r.Elapsed.Seconds()
+}
+
+// chunkDiff is what a worker found in one chunk.
+type chunkDiff struct {
+	offset int64
+	size   int64
+	// ranges is capped at the ranges a result lists; count is complete
+	ranges []Range
+	count  int64
+	bytes  int64
+	// atStart and atEnd are set if a range touches the chunk's start or end,
+	// so it may continue in the neighbouring chunk
+	atStart bool
+	atEnd   bool
+}
+
+// Comparer compares two files chunk by chunk.
+type Comparer struct {
+	pathA, pathB string
+	sizeA, sizeB int64
+	opts         Options
+	bytesRead    atomic.Int64
+}
+
+// New prepares a comparison of the files at pathA and pathB.
+func New(pathA, pathB string, opts Options) (*Comparer, error) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.MaxRanges < 0 {
+		return nil, fmt.Errorf("max ranges must not be negative, got %d", opts.MaxRanges)
+	}
+
+	c := &Comparer{pathA: pathA, pathB: pathB, opts: opts}
+	for _, f := range []struct {
+		path string
+		size *int64
+	}{{pathA, &c.sizeA}, {pathB, &c.sizeB}} {
+		info, err := os.Stat(f.path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot access %s: %v", f.path, err)
+		}
+		*f.size = info.Size()
+	}
+	return c, nil
+}
+
+// Bytes returns the number of bytes the comparison reads from both files.
+func (c *Comparer) Bytes() int64 {
+	return 2 * min(c.sizeA, c.sizeB)
+}
+
+// BytesRead returns the number of bytes read so far. It is safe to call
+// while Run is in progress.
+func (c *Comparer) BytesRead() int64 {
+	return c.bytesRead.Load()
+}
+
+// Run reads both files in parallel on a worker pool and compares them.
+func (c *Comparer) Run(ctx context.Context) (*Result, error) {
+	fileA, err := os.Open(c.pathA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", c.pathA, err)
+	}
+	defer fileA.Close()
+	fileB, err := os.Open(c.pathB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", c.pathB, err)
+	}
+	defer fileB.Close()
+
+	common := min(c.sizeA, c.sizeB)
+	var tasks []worker.Task
+	for offset := int64(0); offset < common; offset += c.opts.ChunkSize {
+		tasks = append(tasks, worker.Task{Offset: offset, Size: min(c.opts.ChunkSize, common-offset)})
+	}
+
+	// The pool hands out the buffers for the first file, and these for the
+	// second
+	buffers := sync.Pool{New: func() any {
+		buffer := make([]byte, c.opts.ChunkSize)
+		return &buffer
+	}}
+	var mu sync.Mutex
+	var diffs []chunkDiff
+
+	start := time.Now()
+	pool := worker.NewWorkerPool(ctx, c.opts.Workers, c.opts.ChunkSize)
+	err = pool.Process(tasks, func(a []byte, task worker.Task) error {
+		bufferPtr := buffers.Get().(*[]byte)
+		defer buffers.Put(bufferPtr)
+		b := (*bufferPtr)[:task.Size]
+
+		if _, err := fileA.ReadAt(a, task.Offset); err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read %s at offset %d: %v", c.pathA, task.Offset, err)
+		}
+		if _, err := fileB.ReadAt(b, task.Offset); err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read %s at offset %d: %v", c.pathB, task.Offset, err)
+		}
+		c.bytesRead.Add(2 * task.Size)
+
+		if diff := diffChunk(a, b, task.Offset, c.opts.MaxRanges); diff.count > 0 {
+			mu.Lock()
+			diffs = append(diffs, diff)
+			mu.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := merge(diffs, c.opts.MaxRanges)
+	if tail := max(c.sizeA, c.sizeB) - common; tail > 0 {
+		result.add(Range{Offset: common, Length: tail}, c.opts.MaxRanges)
+	}
+	result.SizeA, result.SizeB = c.sizeA, c.sizeB
+	result.BytesRead = c.BytesRead()
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
+
+// diffChunk finds the differing ranges of two chunks read at offset,
+// listing at most maxRanges.
+func diffChunk(a, b []byte, offset int64, maxRanges int) chunkDiff {
+	diff := chunkDiff{offset: offset, size: int64(len(a))}
+	for i := 0; i < len(a); {
+		if a[i] == b[i] {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(a) && a[j] != b[j] {
+			j++
+		}
+
+		if len(diff.ranges) < maxRanges {
+			diff.ranges = append(diff.ranges, Range{Offset: offset + int64(i), Length: int64(j - i)})
+		}
+		diff.count++
+		diff.bytes += int64(j - i)
+		diff.atStart = diff.atStart || i == 0
+		diff.atEnd = j == len(a)
+		i = j
+	}
+	return diff
+}
+
+// merge combines the differences found in each chunk, joining ranges that
+// continue from one chunk into the next.
+func merge(diffs []chunkDiff, maxRanges int) *Result {
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].offset < diffs[j].offset
+	})
+
+	result := &Result{}
+	var end int64 = -1
+	for _, diff := range diffs {
+		listedAll := int64(len(result.Ranges)) == result.RangeCount
+		continues := diff.atStart && diff.offset == end
+
+		result.RangeCount += diff.count
+		result.DifferentBytes += diff.bytes
+		ranges := diff.ranges
+		if continues {
+			result.RangeCount--
+			if listedAll && len(result.Ranges) > 0 {
+				result.Ranges[len(result.Ranges)-1].Length += ranges[0].Length
+				ranges = ranges[1:]
+			}
+		}
+		for _, r := range ranges {
+			if len(result.Ranges) < maxRanges {
+				result.Ranges = append(result.Ranges, r)
+			}
+		}
+
+		end = -1
+		if diff.atEnd {
+			end = diff.offset + diff.size
+		}
+	}
+	return result
+}
+
+// add appends a range found outside the chunks, joining it to the last
+// range if they touch.
+func (r *Result) add(rng Range, maxRanges int) {
+	r.DifferentBytes += rng.Length
+	if n := len(r.Ranges); n > 0 && r.Ranges[n-1].Offset+r.Ranges[n-1].Length == rng.Offset && int64(n) == r.RangeCount {
+		r.Ranges[n-1].Length += rng.Length
+		return
+	}
+	r.RangeCount++
+	if len(r.Ranges) < maxRanges {
+		r.Ranges = append(r.Ranges, rng)
+	}
+}
@@ -0,0 +1,119 @@
+package compare
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles writes a and b to a temporary directory and returns their paths.
+func writeFiles(t *testing.T, a, b []byte) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	pathA, pathB := filepath.Join(dir, "a.bin"), filepath.Join(dir, "b.bin")
+	if err := os.WriteFile(pathA, a, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pathB, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return pathA, pathB
+}
+
+// run compares the files at pathA and pathB with opts.
+func run(t *testing.T, pathA, pathB string, opts Options) *Result {
+	t.Helper()
+	c, err := New(pathA, pathB, opts)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	result, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return result
+}
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 13)
+	}
+	return data
+}
+
+func TestCompareIdentical(t *testing.T) {
+	data := testData(10000)
+	pathA, pathB := writeFiles(t, data, data)
+
+	result := run(t, pathA, pathB, Options{ChunkSize: 1024, MaxRanges: 10, Workers: 4})
+	if !result.Identical() || len(result.Ranges) != 0 {
+		t.Errorf("expected identical files, got %+v", result)
+	}
+	if result.BytesRead != 20000 {
+		t.Errorf("expected 20000 bytes read, got %d", result.BytesRead)
+	}
+}
+
+func TestCompareRanges(t *testing.T) {
+	a := testData(10000)
+	b := append([]byte(nil), a...)
+	// A single byte, a range crossing the chunk boundaries at 2048 and
+	// 3072, and a range ending a chunk followed by one starting the next
+	b[10] ^= 1
+	for i := 2000; i < 3100; i++ {
+		b[i] ^= 0xff
+	}
+	b[5119] ^= 1
+	b[5121] ^= 1
+	pathA, pathB := writeFiles(t, a, b)
+
+	result := run(t, pathA, pathB, Options{ChunkSize: 1024, MaxRanges: 10, Workers: 3})
+	expected := []Range{{10, 1}, {2000, 1100}, {5119, 1}, {5121, 1}}
+	if !reflect.DeepEqual(result.Ranges, expected) {
+		t.Errorf("expected ranges %v, got %v", expected, result.Ranges)
+	}
+	if result.RangeCount != 4 || result.DifferentBytes != 1103 {
+		t.Errorf("expected 4 ranges of 1103 bytes, got %d of %d", result.RangeCount, result.DifferentBytes)
+	}
+
+	// Only the first ranges are listed, but all are counted
+	result = run(t, pathA, pathB, Options{ChunkSize: 1024, MaxRanges: 2})
+	if !reflect.DeepEqual(result.Ranges, expected[:2]) || result.RangeCount != 4 {
+		t.Errorf("expected the first 2 of 4 ranges, got %v of %d", result.Ranges, result.RangeCount)
+	}
+	result = run(t, pathA, pathB, Options{ChunkSize: 1024})
+	if len(result.Ranges) != 0 || result.RangeCount != 4 {
+		t.Errorf("expected 4 ranges counted and none listed, got %v of %d", result.Ranges, result.RangeCount)
+	}
+}
+
+func TestCompareSizes(t *testing.T) {
+	a := testData(3000)
+	b := append([]byte(nil), a[:2500]...)
+	b[2499] ^= 1
+	pathA, pathB := writeFiles(t, a, b)
+
+	// The last byte of the shorter file joins the missing tail
+	result := run(t, pathA, pathB, Options{ChunkSize: 1024, MaxRanges: 10})
+	if expected := []Range{{2499, 501}}; !reflect.DeepEqual(result.Ranges, expected) {
+		t.Errorf("expected ranges %v, got %v", expected, result.Ranges)
+	}
+	if result.SizeA != 3000 || result.SizeB != 2500 || result.RangeCount != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestCompareErrors(t *testing.T) {
+	data := testData(100)
+	pathA, _ := writeFiles(t, data, data)
+
+	if _, err := New(pathA, filepath.Join(t.TempDir(), "missing"), Options{}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	if _, err := New(pathA, pathA, Options{MaxRanges: -1}); err == nil {
+		t.Error("expected an error for negative max ranges")
+	}
+}
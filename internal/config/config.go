@@ -0,0 +1,137 @@
+// Package config loads trasher's config file, which sets flag defaults for
+// every run and keeps named profiles of flags for recurring jobs:
+//
+//	defaults:
+//	  workers: 8
+//	profiles:
+//	  nvme-burnin:
+//	    pattern: random
+//	    workers: 32
+//	    chunk-size: 4MB
+//	    checksum-mode: tree
+//	    max-iops: 20000
+//
+// Keys are flag names without the dashes; underscores may stand for the
+// dashes inside a name (chunk_size).
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/maxkimambo/trasher/internal/yaml"
+)
+
+// Config is a loaded config file.
+type Config struct {
+	// Path is the file the config was loaded from.
+	Path string
+	// Defaults maps flag names to the values every run uses.
+	Defaults map[string]string
+	// Profiles maps profile names to the flag values they set.
+	Profiles map[string]map[string]string
+}
+
+// DefaultPath returns the per-user config file location,
+// ~/.config/trasher/config.yaml on Linux.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %v", err)
+	}
+	return filepath.Join(dir, "trasher", "config.yaml"), nil
+}
+
+// Load reads the config file at path. A missing file is reported with an
+// error satisfying errors.Is(err, os.ErrNotExist).
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var file struct {
+		Defaults map[string]any            `json:"defaults"`
+		Profiles map[string]map[string]any `json:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	c := &Config{Path: path, Profiles: make(map[string]map[string]string)}
+	if c.Defaults, err = flagValues(file.Defaults); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: defaults: %v", path, err)
+	}
+	for name, values := range file.Profiles {
+		if c.Profiles[name], err = flagValues(values); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: profile %s: %v", path, name, err)
+		}
+	}
+	return c, nil
+}
+
+// flagValues converts decoded values to flag values, keyed by flag name.
+func flagValues(values map[string]any) (map[string]string, error) {
+	flags := make(map[string]string, len(values))
+	for key, value := range values {
+		name := strings.ReplaceAll(key, "_", "-")
+		if _, dup := flags[name]; dup {
+			return nil, fmt.Errorf("%s is set more than once", name)
+		}
+		switch v := value.(type) {
+		case string:
+			flags[name] = v
+		case json.Number:
+			flags[name] = v.String()
+		case bool:
+			flags[name] = fmt.Sprint(v)
+		case nil:
+			return nil, fmt.Errorf("%s has no value", name)
+		default:
+			return nil, fmt.Errorf("%s must be a single value", name)
+		}
+	}
+	return flags, nil
+}
+
+// ProfileNames returns the names of the profiles, sorted.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Settings returns the flag values for a run with profile: the defaults,
+// overridden by the profile's values. An empty profile returns the defaults.
+func (c *Config) Settings(profile string) (map[string]string, error) {
+	settings := make(map[string]string, len(c.Defaults))
+	for name, value := range c.Defaults {
+		settings[name] = value
+	}
+	if profile == "" {
+		return settings, nil
+	}
+
+	values, ok := c.Profiles[profile]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return nil, fmt.Errorf("unknown profile %s: %s defines no profiles", profile, c.Path)
+		}
+		return nil, fmt.Errorf("unknown profile %s (available in %s: %s)", profile, c.Path, strings.Join(c.ProfileNames(), ", "))
+	}
+	for name, value := range values {
+		settings[name] = value
+	}
+	return settings, nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, doc string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadAndSettings(t *testing.T) {
+	path := writeConfig(t, `
+defaults:
+  workers: 8
+  no-history: true
+profiles:
+  nvme-burnin:
+    pattern: random:seed=42
+    workers: 32
+    chunk_size: 4MB
+    max-iops: 20000
+  quick:
+    size: 1GB
+`)
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := c.ProfileNames(); !reflect.DeepEqual(names, []string{"nvme-burnin", "quick"}) {
+		t.Errorf("unexpected profiles %v", names)
+	}
+
+	got, err := c.Settings("nvme-burnin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"workers":    "32",
+		"no-history": "true",
+		"pattern":    "random:seed=42",
+		"chunk-size": "4MB",
+		"max-iops":   "20000",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	defaults, err := c.Settings("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(defaults, map[string]string{"workers": "8", "no-history": "true"}) {
+		t.Errorf("unexpected defaults %v", defaults)
+	}
+
+	_, err = c.Settings("hdd")
+	if err == nil || !strings.Contains(err.Error(), "nvme-burnin, quick") {
+		t.Errorf("expected an unknown profile error listing the profiles, got %v", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not-exist error, got %v", err)
+	}
+	for _, doc := range []string{
+		"defaults:\n  workers:\n",
+		"defaults:\n  workers:\n    - 1\n",
+		"profiles:\n  a:\n    chunk-size: 1MB\n    chunk_size: 2MB\n",
+		"profile:\n  a:\n    workers: 1\n",
+		"defaults: [1]\n",
+	} {
+		if _, err := Load(writeConfig(t, doc)); err == nil {
+			t.Errorf("expected an error for %q", doc)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Commands understood by the control server.
+const (
+	CommandStatus = "status"
+	CommandPause  = "pause"
+	CommandResume = "resume"
+	CommandCancel = "cancel"
+)
+
+// Status is a snapshot of a running generation job.
+type Status struct {
+	Output         string  `json:"output"`
+	Pattern        string  `json:"pattern"`
+	TotalBytes     int64   `json:"total_bytes"`
+	WrittenBytes   int64   `json:"written_bytes"`
+	Percent        float64 `json:"percent"`
+	Throughput     float64 `json:"throughput"`
+	ETASeconds     float64 `json:"eta_seconds"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Paused         bool    `json:"paused"`
+}
+
+// Request is a single command sent by a client.
+type Request struct {
+	Command string `json:"command"`
+}
+
+// Response is the server's reply to a Request.
+type Response struct {
+	OK     bool    `json:"ok"`
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// Controller is implemented by the job being controlled.
+type Controller interface {
+	Status() Status
+	Pause() error
+	Resume() error
+	Cancel() error
+}
+
+// Server exposes a Controller over a Unix domain socket using a
+// newline-delimited JSON protocol: one Request per line, one Response per line.
+type Server struct {
+	path       string
+	listener   net.Listener
+	controller Controller
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+	conns      map[net.Conn]struct{}
+	closed     bool
+	// requests is held for reading while a request is answered, so Close
+	// waits for the reply to a cancel that ends the job
+	requests sync.RWMutex
+}
+
+// Listen creates the socket at path and starts serving requests.
+// A stale socket left behind by a crashed process is replaced.
+func Listen(path string, controller Controller) (*Server, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket %s: %v", path, err)
+	}
+
+	s := &Server{
+		path:       path,
+		listener:   listener,
+		controller: controller,
+		conns:      make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+
+	return s, nil
+}
+
+// Path returns the socket path.
+func (s *Server) Path() string {
+	return s.path
+}
+
+// acceptLoop accepts client connections until the listener is closed.
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn handles requests from a single client.
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			encoder.Encode(Response{Error: fmt.Sprintf("invalid request: %v", err)})
+			continue
+		}
+
+		s.requests.RLock()
+		s.mu.Lock()
+		closed := s.closed
+		s.mu.Unlock()
+		if closed {
+			s.requests.RUnlock()
+			return
+		}
+		err := encoder.Encode(s.handle(req))
+		s.requests.RUnlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handle dispatches a request to the controller.
+func (s *Server) handle(req Request) Response {
+	var err error
+	switch req.Command {
+	case CommandStatus:
+	case CommandPause:
+		err = s.controller.Pause()
+	case CommandResume:
+		err = s.controller.Resume()
+	case CommandCancel:
+		err = s.controller.Cancel()
+	default:
+		return Response{Error: fmt.Sprintf("unknown command: %s", req.Command)}
+	}
+
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+
+	status := s.controller.Status()
+	return Response{OK: true, Status: &status}
+}
+
+// Close stops the server, disconnects clients once the requests in progress
+// are answered and removes the socket file.
+func (s *Server) Close() error {
+	s.requests.Lock()
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		s.requests.Unlock()
+		return nil
+	}
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.requests.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	os.Remove(s.path)
+	return err
+}
+
+// removeStaleSocket removes a socket file nobody is listening on.
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot access control socket %s: %v", path, err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("control socket path %s exists and is not a socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("control socket %s is already in use", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,242 @@
+package control
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mockController struct {
+	mu        sync.Mutex
+	paused    bool
+	cancelled bool
+}
+
+func (m *mockController) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Status{Output: "test.dat", TotalBytes: 100, WrittenBytes: 40, Percent: 40, Paused: m.paused}
+}
+
+func (m *mockController) Pause() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paused = true
+	return nil
+}
+
+func (m *mockController) Resume() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paused = false
+	return nil
+}
+
+func (m *mockController) Cancel() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cancelled = true
+	return nil
+}
+
+func socketPath(t *testing.T) string {
+	// Unix socket paths are length-limited, so avoid deep temp directories
+	dir, err := os.MkdirTemp("", "trasher-ctl")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "ctl.sock")
+}
+
+func TestServerCommands(t *testing.T) {
+	path := socketPath(t)
+	ctrl := &mockController{}
+
+	server, err := Listen(path, ctrl)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer server.Close()
+
+	client, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+
+	status, err := client.Do(CommandStatus)
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if status.WrittenBytes != 40 || status.Output != "test.dat" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	status, err = client.Do(CommandPause)
+	if err != nil {
+		t.Fatalf("pause failed: %v", err)
+	}
+	if !status.Paused {
+		t.Error("expected status to report paused")
+	}
+
+	status, err = client.Do(CommandResume)
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if status.Paused {
+		t.Error("expected status to report resumed")
+	}
+
+	if _, err := client.Do(CommandCancel); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if !ctrl.cancelled {
+		t.Error("expected controller to be cancelled")
+	}
+
+	if _, err := client.Do("explode"); err == nil {
+		t.Error("expected error for unknown command")
+	}
+}
+
+// closingController closes the server as soon as it is cancelled, as a job
+// ending does.
+type closingController struct {
+	mockController
+	server *Server
+	closed chan struct{}
+}
+
+func (c *closingController) Cancel() error {
+	go func() {
+		c.server.Close()
+		close(c.closed)
+	}()
+	// Give Close the time to run before the reply is sent
+	time.Sleep(50 * time.Millisecond)
+	return c.mockController.Cancel()
+}
+
+func TestServerAnswersCancelBeforeClosing(t *testing.T) {
+	path := socketPath(t)
+	ctrl := &closingController{closed: make(chan struct{})}
+	server, err := Listen(path, ctrl)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	ctrl.server = server
+
+	client, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Do(CommandCancel); err != nil {
+		t.Errorf("expected a reply to cancel, got %v", err)
+	}
+	<-ctrl.closed
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the socket to be removed, got %v", err)
+	}
+}
+
+func TestServerInvalidRequest(t *testing.T) {
+	path := socketPath(t)
+
+	server, err := Listen(path, &mockController{})
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer server.Close()
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("not json\n"))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	if !strings.Contains(line, "invalid request") {
+		t.Errorf("expected invalid request error, got %s", line)
+	}
+}
+
+func TestServerCloseRemovesSocket(t *testing.T) {
+	path := socketPath(t)
+
+	server, err := Listen(path, &mockController{})
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+
+	client, err := Dial(path)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+
+	if err := server.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected socket file to be removed")
+	}
+
+	// Closing twice should be a no-op
+	if err := server.Close(); err != nil {
+		t.Errorf("second Close failed: %v", err)
+	}
+}
+
+func TestListenRejectsNonSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regular")
+	os.WriteFile(path, []byte("data"), 0644)
+
+	if _, err := Listen(path, &mockController{}); err == nil {
+		t.Error("expected error when path is a regular file")
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	path := socketPath(t)
+
+	// Leave a socket file behind without anyone listening
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to create socket: %v", err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+
+	server, err := Listen(path, &mockController{})
+	if err != nil {
+		t.Fatalf("expected stale socket to be replaced, got %v", err)
+	}
+	server.Close()
+}
+
+func TestListenInUse(t *testing.T) {
+	path := socketPath(t)
+
+	server, err := Listen(path, &mockController{})
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer server.Close()
+
+	if _, err := Listen(path, &mockController{}); err == nil {
+		t.Error("expected error when socket is already in use")
+	}
+}
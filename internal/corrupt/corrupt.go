@@ -0,0 +1,103 @@
+package corrupt
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sort"
+)
+
+// Options selects the damage Inject does.
+type Options struct {
+	// Bits is the number of single bits to flip, each in a different
+	// position.
+	Bits int
+	// Bytes is the number of whole bytes to replace with a different value.
+	Bytes int
+	// Seed selects the damaged positions, so a run can be repeated exactly.
+	Seed uint64
+}
+
+// Change is one damaged byte of a file.
+type Change struct {
+	Offset int64 `json:"offset"`
+	Before byte  `json:"before"`
+	After  byte  `json:"after"`
+}
+
+// Result records the damage done to a file.
+type Result struct {
+	File    string   `json:"file"`
+	Seed    uint64   `json:"seed"`
+	Changes []Change `json:"changes"`
+}
+
+// Inject damages the file at path in place at random positions chosen by
+// opts.Seed, and returns every byte it changed in offset order. Bytes
+// replaced whole are never also bit-flipped, so every change sticks.
+func Inject(path string, opts Options) (*Result, error) {
+	if opts.Bits < 0 || opts.Bytes < 0 || opts.Bits+opts.Bytes == 0 {
+		return nil, fmt.Errorf("nothing to corrupt: give a positive number of bits or bytes")
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if int64(opts.Bytes) > size || int64(opts.Bits) > (size-int64(opts.Bytes))*8 {
+		return nil, fmt.Errorf("%s is too small (%d bytes) for %d bits and %d bytes of damage", path, size, opts.Bits, opts.Bytes)
+	}
+
+	// Each damaged byte is XORed with a non-zero mask
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15))
+	masks := make(map[int64]byte)
+	replaced := make(map[int64]bool)
+	for len(replaced) < opts.Bytes {
+		offset := rng.Int64N(size)
+		if !replaced[offset] {
+			replaced[offset] = true
+			masks[offset] = byte(rng.IntN(255) + 1)
+		}
+	}
+	flipped := make(map[int64]bool)
+	for len(flipped) < opts.Bits {
+		bit := rng.Int64N(size * 8)
+		if flipped[bit] || replaced[bit/8] {
+			continue
+		}
+		flipped[bit] = true
+		masks[bit/8] ^= 1 << (bit % 8)
+	}
+
+	result := &Result{File: path, Seed: opts.Seed}
+	for offset, mask := range masks {
+		result.Changes = append(result.Changes, Change{Offset: offset, After: mask})
+	}
+	sort.Slice(result.Changes, func(i, j int) bool {
+		return result.Changes[i].Offset < result.Changes[j].Offset
+	})
+
+	b := make([]byte, 1)
+	for i := range result.Changes {
+		change := &result.Changes[i]
+		if _, err := file.ReadAt(b, change.Offset); err != nil {
+			return nil, fmt.Errorf("failed to read offset %d: %v", change.Offset, err)
+		}
+		change.Before, change.After = b[0], b[0]^change.After
+		b[0] = change.After
+		if _, err := file.WriteAt(b, change.Offset); err != nil {
+			return nil, fmt.Errorf("failed to write offset %d: %v", change.Offset, err)
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync %s: %v", path, err)
+	}
+	return result, nil
+}
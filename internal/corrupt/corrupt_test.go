@@ -0,0 +1,117 @@
+package corrupt
+
+import (
+	"bytes"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFile creates a file of size bytes with varied content.
+func writeFile(t *testing.T, size int) (string, []byte) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.bin")
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 31)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+func TestInject(t *testing.T) {
+	path, original := writeFile(t, 4096)
+
+	result, err := Inject(path, Options{Bits: 10, Bytes: 3, Seed: 42})
+	if err != nil {
+		t.Fatalf("Inject failed: %v", err)
+	}
+	damaged, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every changed byte is recorded, in offset order
+	var changed []Change
+	for i := range original {
+		if original[i] != damaged[i] {
+			changed = append(changed, Change{Offset: int64(i), Before: original[i], After: damaged[i]})
+		}
+	}
+	if !reflect.DeepEqual(changed, result.Changes) {
+		t.Errorf("recorded changes %v don't match the file's changes %v", result.Changes, changed)
+	}
+
+	// Bytes replaced whole account for at least 3 changes, bit flips for
+	// exactly 10 bits of the rest
+	var flippedBits int
+	for _, change := range result.Changes {
+		flippedBits += bits.OnesCount8(change.Before ^ change.After)
+	}
+	if len(result.Changes) < 3 || flippedBits < 13 {
+		t.Errorf("expected 3 bytes and 10 bits changed, got %d bytes and %d bits", len(result.Changes), flippedBits)
+	}
+}
+
+func TestInjectBitsOnly(t *testing.T) {
+	path, original := writeFile(t, 1024)
+
+	result, err := Inject(path, Options{Bits: 16, Seed: 7})
+	if err != nil {
+		t.Fatalf("Inject failed: %v", err)
+	}
+	damaged, _ := os.ReadFile(path)
+
+	var flippedBits int
+	for i := range original {
+		flippedBits += bits.OnesCount8(original[i] ^ damaged[i])
+	}
+	if flippedBits != 16 {
+		t.Errorf("expected 16 bits flipped, got %d", flippedBits)
+	}
+	if result.Seed != 7 || result.File != path {
+		t.Errorf("unexpected result header: %+v", result)
+	}
+}
+
+func TestInjectRepeatable(t *testing.T) {
+	first, _ := writeFile(t, 8192)
+	second, _ := writeFile(t, 8192)
+
+	for _, path := range []string{first, second} {
+		if _, err := Inject(path, Options{Bits: 5, Bytes: 5, Seed: 99}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, _ := os.ReadFile(first)
+	b, _ := os.ReadFile(second)
+	if !bytes.Equal(a, b) {
+		t.Error("expected the same seed to damage the same positions")
+	}
+}
+
+func TestInjectErrors(t *testing.T) {
+	path, _ := writeFile(t, 2)
+
+	tests := map[string]Options{
+		"nothing":       {},
+		"negative":      {Bits: -1, Bytes: 2},
+		"too many":      {Bytes: 3},
+		"too many bits": {Bytes: 1, Bits: 9},
+	}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Inject(path, opts); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	if _, err := Inject(filepath.Join(t.TempDir(), "missing"), Options{Bits: 1}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,131 @@
+// Package device identifies block devices so destructive writes can be
+// guarded against targeting the wrong disk.
+package device
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Identity describes a block device as reported by the operating system.
+// Serial and WWN are empty when the platform or device doesn't expose them.
+type Identity struct {
+	Path   string
+	Serial string
+	WWN    string
+	Size   int64
+}
+
+// Expectation lists what the caller expects the target device to be.
+// Empty fields are not checked.
+type Expectation struct {
+	Serial string
+	WWN    string
+	Size   int64
+}
+
+// IsSet returns true if any expectation was given.
+func (e Expectation) IsSet() bool {
+	return e.Serial != "" || e.WWN != "" || e.Size > 0
+}
+
+// IsBlockDevice returns true if path refers to a block device.
+func IsBlockDevice(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	mode := info.Mode()
+	return mode&os.ModeDevice != 0 && mode&os.ModeCharDevice == 0
+}
+
+// Lookup returns the identity of the block device at path.
+func Lookup(path string) (*Identity, error) {
+	id, err := lookup(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify device %s: %v", path, err)
+	}
+	if id.Size == 0 {
+		if id.Size, err = Size(path); err != nil {
+			return nil, err
+		}
+	}
+	return id, nil
+}
+
+// Size returns the capacity of the block device at path in bytes.
+func Size(path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open device %s: %v", path, err)
+	}
+	defer file.Close()
+
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine size of %s: %v", path, err)
+	}
+	return size, nil
+}
+
+// Guard checks that writing to path is safe. Regular files always pass.
+// Block devices are only accepted with at least one expectation, and every
+// given expectation must match the device, so automation can't wipe a disk
+// that was renumbered or swapped.
+func Guard(path string, expect Expectation) error {
+	if !IsBlockDevice(path) {
+		if expect.IsSet() {
+			return fmt.Errorf("device expectations were given but %s is not a block device", path)
+		}
+		return nil
+	}
+
+	if !expect.IsSet() {
+		return fmt.Errorf("refusing to write to block device %s without --expect-serial, --expect-wwn or --expect-size", path)
+	}
+
+	id, err := Lookup(path)
+	if err != nil {
+		return err
+	}
+	return id.Match(expect)
+}
+
+// Match returns an error describing the first expectation the device fails.
+func (id *Identity) Match(expect Expectation) error {
+	if expect.Serial != "" {
+		if id.Serial == "" {
+			return fmt.Errorf("cannot read serial number of %s to compare with %q", id.Path, expect.Serial)
+		}
+		if !strings.EqualFold(strings.TrimSpace(id.Serial), strings.TrimSpace(expect.Serial)) {
+			return fmt.Errorf("serial number mismatch for %s: expected %q, device reports %q", id.Path, expect.Serial, id.Serial)
+		}
+	}
+
+	if expect.WWN != "" {
+		if id.WWN == "" {
+			return fmt.Errorf("cannot read WWN of %s to compare with %q", id.Path, expect.WWN)
+		}
+		if normalizeWWN(id.WWN) != normalizeWWN(expect.WWN) {
+			return fmt.Errorf("WWN mismatch for %s: expected %q, device reports %q", id.Path, expect.WWN, id.WWN)
+		}
+	}
+
+	if expect.Size > 0 && id.Size != expect.Size {
+		return fmt.Errorf("size mismatch for %s: expected %d bytes, device has %d bytes", id.Path, expect.Size, id.Size)
+	}
+
+	return nil
+}
+
+// normalizeWWN lowercases a WWN and strips the type prefixes ("naa.", "eui.",
+// "0x") that different tools print, so the same identifier compares equal.
+func normalizeWWN(wwn string) string {
+	wwn = strings.ToLower(strings.TrimSpace(wwn))
+	for _, prefix := range []string{"naa.", "eui.", "0x"} {
+		wwn = strings.TrimPrefix(wwn, prefix)
+	}
+	return wwn
+}
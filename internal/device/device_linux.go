@@ -0,0 +1,95 @@
+//go:build linux
+
+package device
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// lookup reads the device identity from sysfs, finding the device's sysfs
+// directory from its major:minor number so any name (/dev/sdb,
+// /dev/disk/by-id/..., /dev/mapper/...) resolves to the same device.
+func lookup(path string) (*Identity, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return nil, err
+	}
+
+	rdev := uint64(st.Rdev)
+	major := (rdev>>8)&0xfff | (rdev>>32)&^0xfff
+	minor := rdev&0xff | (rdev>>12)&^0xff
+
+	dir := filepath.Join("/sys/dev/block", fmt.Sprintf("%d:%d", major, minor))
+	return identityFromSysfs(path, dir)
+}
+
+// identityFromSysfs builds an identity from a block device's sysfs directory.
+// Partitions report the serial and WWN of the disk they belong to.
+func identityFromSysfs(path, dir string) (*Identity, error) {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	id := &Identity{Path: path}
+
+	if sectors, err := readAttr(dir, "size"); err == nil {
+		n, err := strconv.ParseInt(sectors, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size in sysfs: %q", sectors)
+		}
+		// sysfs always counts 512-byte sectors regardless of the block size
+		id.Size = n * 512
+	}
+
+	disk := dir
+	if _, err := os.Stat(filepath.Join(dir, "partition")); err == nil {
+		disk = filepath.Dir(dir)
+	}
+
+	id.Serial = firstAttr(disk, "device/serial", "serial")
+	if id.Serial == "" {
+		id.Serial = vpdSerial(disk)
+	}
+	id.WWN = firstAttr(disk, "wwid", "device/wwid", "device/wwn")
+
+	return id, nil
+}
+
+// readAttr reads a trimmed sysfs attribute.
+func readAttr(dir, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// firstAttr returns the first non-empty attribute among names.
+func firstAttr(dir string, names ...string) string {
+	for _, name := range names {
+		if value, err := readAttr(dir, name); err == nil && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// vpdSerial extracts the unit serial number from the SCSI VPD page 0x80,
+// which SATA and SAS disks expose instead of a serial attribute.
+func vpdSerial(disk string) string {
+	data, err := os.ReadFile(filepath.Join(disk, "device", "vpd_pg80"))
+	if err != nil || len(data) < 4 {
+		return ""
+	}
+	length := int(data[3])
+	if 4+length > len(data) {
+		length = len(data) - 4
+	}
+	return strings.TrimSpace(string(data[4 : 4+length]))
+}
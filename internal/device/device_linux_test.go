@@ -0,0 +1,77 @@
+//go:build linux
+
+package device
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeAttrs creates sysfs-style attribute files under dir.
+func writeAttrs(t *testing.T, dir string, attrs map[string]string) {
+	t.Helper()
+
+	for name, value := range attrs {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(value), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestIdentityFromSysfs(t *testing.T) {
+	disk := filepath.Join(t.TempDir(), "nvme0n1")
+	writeAttrs(t, disk, map[string]string{
+		"size":          "2048\n",
+		"wwid":          "eui.0025388b91b2c3d4\n",
+		"device/serial": "S4EWNX0N123456      \n",
+	})
+
+	id, err := identityFromSysfs("/dev/nvme0n1", disk)
+	if err != nil {
+		t.Fatalf("identityFromSysfs failed: %v", err)
+	}
+	if id.Size != 2048*512 {
+		t.Errorf("expected size %d, got %d", 2048*512, id.Size)
+	}
+	if id.Serial != "S4EWNX0N123456" {
+		t.Errorf("unexpected serial %q", id.Serial)
+	}
+	if id.WWN != "eui.0025388b91b2c3d4" {
+		t.Errorf("unexpected WWN %q", id.WWN)
+	}
+}
+
+func TestIdentityFromSysfsPartition(t *testing.T) {
+	disk := filepath.Join(t.TempDir(), "sdb")
+	vpd := append([]byte{0x00, 0x80, 0x00, 0x08}, []byte("ZA1B2C3D")...)
+	writeAttrs(t, disk, map[string]string{
+		"size":            "1000000",
+		"device/wwid":     "naa.5000c500a1b2c3d4",
+		"device/vpd_pg80": string(vpd),
+		"sdb1/size":       "4096",
+		"sdb1/partition":  "1",
+	})
+
+	// sysfs exposes partitions through a symlink to their directory
+	link := filepath.Join(t.TempDir(), "8:17")
+	if err := os.Symlink(filepath.Join(disk, "sdb1"), link); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	id, err := identityFromSysfs("/dev/sdb1", link)
+	if err != nil {
+		t.Fatalf("identityFromSysfs failed: %v", err)
+	}
+	if id.Size != 4096*512 {
+		t.Errorf("expected partition size %d, got %d", 4096*512, id.Size)
+	}
+	if id.Serial != "ZA1B2C3D" {
+		t.Errorf("expected serial from the parent disk's VPD page, got %q", id.Serial)
+	}
+	if id.WWN != "naa.5000c500a1b2c3d4" {
+		t.Errorf("expected WWN from the parent disk, got %q", id.WWN)
+	}
+}
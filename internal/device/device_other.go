@@ -0,0 +1,10 @@
+//go:build !linux
+
+package device
+
+// lookup returns an identity with only the path set. Serial numbers and
+// WWNs are read from sysfs, which only exists on Linux; the size is filled
+// in by Lookup.
+func lookup(path string) (*Identity, error) {
+	return &Identity{Path: path}, nil
+}
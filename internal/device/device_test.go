@@ -0,0 +1,91 @@
+package device
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGuardRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.dat")
+	os.WriteFile(path, []byte("data"), 0644)
+
+	if IsBlockDevice(path) {
+		t.Fatal("regular file reported as block device")
+	}
+	if err := Guard(path, Expectation{}); err != nil {
+		t.Errorf("expected regular file to pass, got %v", err)
+	}
+	if err := Guard(filepath.Join(t.TempDir(), "missing.dat"), Expectation{}); err != nil {
+		t.Errorf("expected new file to pass, got %v", err)
+	}
+
+	err := Guard(path, Expectation{Serial: "ABC123"})
+	if err == nil || !strings.Contains(err.Error(), "not a block device") {
+		t.Errorf("expected expectations on a regular file to be rejected, got %v", err)
+	}
+}
+
+func TestIdentityMatch(t *testing.T) {
+	id := &Identity{
+		Path:   "/dev/sdb",
+		Serial: "WD-WX12345678",
+		WWN:    "naa.5000c500a1b2c3d4",
+		Size:   1 << 40,
+	}
+
+	tests := []struct {
+		name        string
+		expect      Expectation
+		expectedErr string
+	}{
+		{"serial", Expectation{Serial: "WD-WX12345678"}, ""},
+		{"serial case insensitive", Expectation{Serial: " wd-wx12345678 "}, ""},
+		{"wwn without prefix", Expectation{WWN: "5000C500A1B2C3D4"}, ""},
+		{"wwn hex prefix", Expectation{WWN: "0x5000c500a1b2c3d4"}, ""},
+		{"size", Expectation{Size: 1 << 40}, ""},
+		{"all", Expectation{Serial: "WD-WX12345678", WWN: "naa.5000c500a1b2c3d4", Size: 1 << 40}, ""},
+		{"wrong serial", Expectation{Serial: "OTHER"}, "serial number mismatch"},
+		{"wrong wwn", Expectation{WWN: "naa.5000c500ffffffff"}, "WWN mismatch"},
+		{"wrong size", Expectation{Size: 1 << 30}, "size mismatch"},
+		{"one of many wrong", Expectation{Serial: "WD-WX12345678", Size: 1 << 30}, "size mismatch"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := id.Match(test.expect)
+			if test.expectedErr == "" {
+				if err != nil {
+					t.Errorf("expected match, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+				t.Errorf("expected error containing %q, got %v", test.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestIdentityMatchUnknownValues(t *testing.T) {
+	id := &Identity{Path: "/dev/sdb", Size: 1024}
+
+	if err := id.Match(Expectation{Serial: "ABC"}); err == nil || !strings.Contains(err.Error(), "cannot read serial") {
+		t.Errorf("expected unreadable serial to fail, got %v", err)
+	}
+	if err := id.Match(Expectation{WWN: "naa.1"}); err == nil || !strings.Contains(err.Error(), "cannot read WWN") {
+		t.Errorf("expected unreadable WWN to fail, got %v", err)
+	}
+}
+
+func TestExpectationIsSet(t *testing.T) {
+	if (Expectation{}).IsSet() {
+		t.Error("empty expectation reported as set")
+	}
+	for _, e := range []Expectation{{Serial: "a"}, {WWN: "b"}, {Size: 1}} {
+		if !e.IsSet() {
+			t.Errorf("expected %+v to be set", e)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package diskspace reports free and total space for the volume holding a path.
+package diskspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrInsufficient is the error Check returns, wrapped, when a volume has
+// less space available than required.
+var ErrInsufficient = errors.New("insufficient disk space")
+
+// DefaultWatchInterval is how often Watch checks the space available.
+const DefaultWatchInterval = 500 * time.Millisecond
+
+// Info describes the space on the volume that holds a directory.
+type Info struct {
+	// Available is the space usable by the current user, which may be less
+	// than the free space on the volume when quotas are in effect.
+	Available int64
+	Total     int64
+	// Volume is the root of the volume that was actually queried, such as a
+	// mount point, "C:\", a mounted folder or a UNC share root.
+	Volume string
+}
+
+// Query returns the space available on the volume holding dir. If dir does
+// not exist yet, its nearest existing ancestor is queried instead.
+func Query(dir string) (*Info, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %v", err)
+	}
+
+	existing, err := existingAncestor(absDir)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := query(existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check disk space for %s: %v", existing, err)
+	}
+	return info, nil
+}
+
+// Check returns an error if fewer than required bytes are available on the
+// volume holding dir.
+func Check(dir string, required int64) error {
+	info, err := Query(dir)
+	if err != nil {
+		return err
+	}
+
+	if required > info.Available {
+		return fmt.Errorf("%w on %s: need %d bytes, have %d bytes",
+			ErrInsufficient, info.Volume, required, info.Available)
+	}
+	return nil
+}
+
+// Watch queries the space available on the volume holding dir every
+// interval and passes it to check, until check returns false or ctx is done.
+// Failed queries are skipped.
+func Watch(ctx context.Context, dir string, interval time.Duration, check func(available int64) bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if info, err := Query(dir); err == nil && !check(info.Available) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// existingAncestor walks up from path until it finds a directory that exists.
+func existingAncestor(path string) (string, error) {
+	for {
+		if fi, err := os.Stat(path); err == nil {
+			if fi.IsDir() {
+				return path, nil
+			}
+		} else if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to stat %s: %v", path, err)
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", fmt.Errorf("no existing directory found for %s", path)
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,122 @@
+package diskspace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuery(t *testing.T) {
+	dir := t.TempDir()
+
+	info, err := Query(dir)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if info.Total <= 0 {
+		t.Errorf("expected positive total space, got %d", info.Total)
+	}
+	if info.Available < 0 || info.Available > info.Total {
+		t.Errorf("available space %d out of range (total %d)", info.Available, info.Total)
+	}
+	if info.Volume == "" {
+		t.Error("expected the queried volume to be reported")
+	}
+	if !strings.HasPrefix(dir, info.Volume) {
+		t.Errorf("expected volume %s to contain %s", info.Volume, dir)
+	}
+}
+
+func TestQueryMissingDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	info, err := Query(filepath.Join(dir, "not", "created", "yet"))
+	if err != nil {
+		t.Fatalf("Query should fall back to an existing ancestor: %v", err)
+	}
+	if info.Total <= 0 {
+		t.Errorf("expected positive total space, got %d", info.Total)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := Check(dir, 1024); err != nil {
+		t.Errorf("expected 1KB to fit, got %v", err)
+	}
+
+	err := Check(dir, 1<<62)
+	if err == nil {
+		t.Fatal("expected insufficient space error")
+	}
+	if !strings.Contains(err.Error(), "insufficient disk space") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !errors.Is(err, ErrInsufficient) {
+		t.Errorf("expected ErrInsufficient, got %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+
+	// Checks repeat until the check asks to stop
+	var checks int
+	Watch(context.Background(), dir, time.Millisecond, func(available int64) bool {
+		if available <= 0 {
+			t.Errorf("expected positive available space, got %d", available)
+		}
+		checks++
+		return checks < 3
+	})
+	if checks != 3 {
+		t.Errorf("expected 3 checks, got %d", checks)
+	}
+
+	// Or until the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	done := make(chan struct{})
+	go func() {
+		Watch(ctx, dir, time.Millisecond, func(int64) bool { return true })
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not return after the context was cancelled")
+	}
+}
+
+func TestExistingAncestor(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.dat")
+	os.WriteFile(file, []byte("data"), 0644)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"existing directory", dir, dir},
+		{"missing children", filepath.Join(dir, "a", "b"), dir},
+		{"file", file, dir},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := existingAncestor(test.path)
+			if err != nil {
+				t.Fatalf("existingAncestor failed: %v", err)
+			}
+			if got != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
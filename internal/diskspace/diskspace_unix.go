@@ -0,0 +1,44 @@
+//go:build unix || linux || darwin
+
+package diskspace
+
+import (
+	"path/filepath"
+	"syscall"
+)
+
+// query reads the space for dir with statfs.
+func query(dir string) (*Info, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return nil, err
+	}
+
+	return &Info{
+		Available: int64(stat.Bavail) * int64(stat.Bsize),
+		Total:     int64(stat.Blocks) * int64(stat.Bsize),
+		Volume:    mountPoint(dir),
+	}, nil
+}
+
+// mountPoint returns the topmost ancestor of dir that is still on the same
+// device, which is where the file system holding dir is mounted.
+func mountPoint(dir string) string {
+	var st syscall.Stat_t
+	if err := syscall.Stat(dir, &st); err != nil {
+		return dir
+	}
+
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+
+		var parentStat syscall.Stat_t
+		if err := syscall.Stat(parent, &parentStat); err != nil || parentStat.Dev != st.Dev {
+			return dir
+		}
+		dir = parent
+	}
+}
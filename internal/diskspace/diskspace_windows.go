@@ -0,0 +1,76 @@
+//go:build windows
+
+package diskspace
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                = syscall.NewLazyDLL("kernel32.dll")
+	procGetDiskFreeSpaceExW = kernel32.NewProc("GetDiskFreeSpaceExW")
+	procGetVolumePathNameW  = kernel32.NewProc("GetVolumePathNameW")
+)
+
+// query reads the space for dir with GetDiskFreeSpaceExW. The directory
+// itself is queried rather than its drive root so that volumes mounted into
+// folders, UNC shares and subst drives report the space of the volume that
+// actually holds dir, with per-user quotas applied.
+func query(dir string) (*Info, error) {
+	dirPtr, err := syscall.UTF16PtrFromString(withTrailingSeparator(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var freeBytesAvailable, totalBytes, totalFreeBytes uint64
+	ret, _, err := procGetDiskFreeSpaceExW.Call(
+		uintptr(unsafe.Pointer(dirPtr)),
+		uintptr(unsafe.Pointer(&freeBytesAvailable)),
+		uintptr(unsafe.Pointer(&totalBytes)),
+		uintptr(unsafe.Pointer(&totalFreeBytes)),
+	)
+	if ret == 0 {
+		return nil, err
+	}
+
+	return &Info{
+		Available: int64(freeBytesAvailable),
+		Total:     int64(totalBytes),
+		Volume:    volumePath(dir),
+	}, nil
+}
+
+// volumePath returns the mount point of the volume holding dir, such as
+// "C:\", "C:\mnt\data\" or "\\server\share\". It falls back to the path's
+// volume name if GetVolumePathNameW fails.
+func volumePath(dir string) string {
+	dirPtr, err := syscall.UTF16PtrFromString(dir)
+	if err == nil {
+		buf := make([]uint16, syscall.MAX_LONG_PATH)
+		ret, _, _ := procGetVolumePathNameW.Call(
+			uintptr(unsafe.Pointer(dirPtr)),
+			uintptr(unsafe.Pointer(&buf[0])),
+			uintptr(len(buf)),
+		)
+		if ret != 0 {
+			return syscall.UTF16ToString(buf)
+		}
+	}
+
+	if volume := filepath.VolumeName(dir); volume != "" {
+		return withTrailingSeparator(volume)
+	}
+	return dir
+}
+
+// withTrailingSeparator appends a backslash, which GetDiskFreeSpaceExW
+// requires for UNC share roots.
+func withTrailingSeparator(path string) string {
+	if strings.HasSuffix(path, `\`) {
+		return path
+	}
+	return path + `\`
+}
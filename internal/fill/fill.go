@@ -0,0 +1,265 @@
+// Package fill writes files into a directory until its filesystem is down
+// to a given amount of free space, to put services under disk pressure.
+package fill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/diskspace"
+	"github.com/maxkimambo/trasher/internal/worker"
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+const (
+	// DefaultFileSize is the size of each file written.
+	DefaultFileSize = 1024 * 1024 * 1024
+	// DefaultChunkSize is how much a worker generates and writes at a time.
+	DefaultChunkSize = 4 * 1024 * 1024
+)
+
+// Reasons a fill stops.
+const (
+	StopLeaveFree = "free space reached the amount to leave"
+	StopNoSpace   = "the filesystem is full"
+)
+
+// errLeaveFree stops writing once the next chunk would leave less free space
+// than asked for.
+var errLeaveFree = errors.New(StopLeaveFree)
+
+// Options controls how a directory is filled.
+type Options struct {
+	// LeaveFree is the free space to leave on the filesystem. Zero fills
+	// it until writes fail for lack of space.
+	LeaveFree int64
+	// FileSize is the size of each file. Zero uses DefaultFileSize.
+	FileSize int64
+	// ChunkSize is how much a worker generates and writes at a time. Zero
+	// uses DefaultChunkSize.
+	ChunkSize int64
+	// Pattern is the data pattern written. Empty uses random.
+	Pattern string
+	// Workers is the number of parallel generators. Zero uses one per CPU.
+	Workers int
+}
+
+// Result describes a finished fill.
+type Result struct {
+	// Files lists the files written, the last possibly shorter than the
+	// others.
+	Files   []string
+	Written int64
+	// Available is the free space left on the filesystem.
+	Available int64
+	// Reason says why the fill stopped: StopLeaveFree or StopNoSpace.
+	Reason  string
+	Elapsed time.Duration
+}
+
+// Throughput returns the average write rate in bytes per second.
+func (r *Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Written) / r.Elapsed.Seconds()
+}
+
+// Filler fills a directory with files.
+type Filler struct {
+	dir     string
+	opts    Options
+	bytes   int64
+	written atomic.Int64
+	// query reports the space on the directory's filesystem
+	query func(dir string) (*diskspace.Info, error)
+}
+
+// New prepares to fill dir, which must exist, until opts.LeaveFree bytes
+// are left free.
+func New(dir string, opts Options) (*Filler, error) {
+	if opts.LeaveFree < 0 {
+		return nil, fmt.Errorf("space to leave free must not be negative, got %d", opts.LeaveFree)
+	}
+	if opts.FileSize <= 0 {
+		opts.FileSize = DefaultFileSize
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.Pattern == "" {
+		opts.Pattern = "random"
+	}
+	if _, err := generator.NewGenerator(opts.Pattern); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access %s: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	f := &Filler{dir: dir, opts: opts, query: diskspace.Query}
+	space, err := f.query(dir)
+	if err != nil {
+		return nil, err
+	}
+	if space.Available <= opts.LeaveFree {
+		return nil, fmt.Errorf("%s has %d bytes free, no more than the %d bytes to leave", space.Volume, space.Available, opts.LeaveFree)
+	}
+	f.bytes = space.Available - opts.LeaveFree
+	return f, nil
+}
+
+// Bytes returns how much the fill expects to write, from the free space when
+// it was prepared.
+func (f *Filler) Bytes() int64 {
+	return f.bytes
+}
+
+// BytesWritten returns the number of bytes written so far. It is safe to
+// call while Run is in progress.
+func (f *Filler) BytesWritten() int64 {
+	return f.written.Load()
+}
+
+// Run writes files of Options.FileSize into the directory, generating each
+// file's chunks in parallel and writing them in order, until the free space
+// reaches Options.LeaveFree or writes fail for lack of space. Files are
+// named trasher-fill-NNNN.dat after the first number not in use. Running
+// out of space ends the fill, keeping what was written; only other errors,
+// or cancelling ctx, are returned as errors.
+func (f *Filler) Run(ctx context.Context) (*Result, error) {
+	factory := generator.NewFactory(f.opts.Pattern, generator.Options{})
+	var (
+		gensMu sync.Mutex
+		gens   []generator.Generator
+	)
+	generate := func(buffer []byte, task worker.Task) error {
+		gensMu.Lock()
+		var gen generator.Generator
+		if n := len(gens); n > 0 {
+			gen, gens = gens[n-1], gens[:n-1]
+		}
+		gensMu.Unlock()
+		if gen == nil {
+			var err error
+			if gen, err = factory(); err != nil {
+				return fmt.Errorf("failed to create generator: %v", err)
+			}
+		}
+		defer func() {
+			gensMu.Lock()
+			gens = append(gens, gen)
+			gensMu.Unlock()
+		}()
+		return generator.GenerateChunk(gen, buffer, task.Offset)
+	}
+
+	result := &Result{}
+	start := time.Now()
+	pool := worker.NewWorkerPool(ctx, f.opts.Workers, f.opts.ChunkSize)
+	index := 1
+	for result.Reason == "" {
+		space, err := f.query(f.dir)
+		if err != nil {
+			return nil, err
+		}
+		budget := space.Available - f.opts.LeaveFree
+		if f.opts.LeaveFree > 0 && budget <= 0 {
+			result.Reason = StopLeaveFree
+			break
+		}
+		size := f.opts.FileSize
+		if f.opts.LeaveFree > 0 {
+			size = min(size, budget)
+		}
+
+		var file *os.File
+		for file == nil {
+			path := filepath.Join(f.dir, fmt.Sprintf("trasher-fill-%04d.dat", index))
+			index++
+			file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+			if err != nil && !os.IsExist(err) {
+				break
+			}
+		}
+		if file == nil {
+			if errors.Is(err, syscall.ENOSPC) {
+				result.Reason = StopNoSpace
+				break
+			}
+			return nil, fmt.Errorf("failed to create file in %s: %v", f.dir, err)
+		}
+
+		written, err := f.writeFile(pool, file, size, generate)
+		if closeErr := file.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to close %s: %v", file.Name(), closeErr)
+		}
+		// A file the fill stopped before writing to is left out
+		if written > 0 {
+			result.Files = append(result.Files, file.Name())
+		} else {
+			os.Remove(file.Name())
+		}
+		switch {
+		case errors.Is(err, errLeaveFree):
+			result.Reason = StopLeaveFree
+		case errors.Is(err, syscall.ENOSPC):
+			result.Reason = StopNoSpace
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("fill interrupted after %d files", len(result.Files))
+			}
+			return nil, err
+		}
+	}
+
+	result.Written = f.BytesWritten()
+	result.Elapsed = time.Since(start)
+	if space, err := f.query(f.dir); err == nil {
+		result.Available = space.Available
+	}
+	return result, nil
+}
+
+// writeFile writes size bytes of generated data to file, in order, checking
+// before each chunk that it leaves enough space free. It returns the number
+// of bytes written, even when it fails.
+func (f *Filler) writeFile(pool *worker.WorkerPool, file *os.File, size int64, generate worker.ChunkFunc) (int64, error) {
+	var tasks []worker.Task
+	for offset := int64(0); offset < size; offset += f.opts.ChunkSize {
+		tasks = append(tasks, worker.Task{Offset: offset, Size: min(f.opts.ChunkSize, size-offset)})
+	}
+
+	var written int64
+	err := pool.ProcessOrdered(tasks, generate, func(buffer []byte, task worker.Task) error {
+		if f.opts.LeaveFree > 0 {
+			space, err := f.query(f.dir)
+			if err != nil {
+				return err
+			}
+			if space.Available-int64(len(buffer)) < f.opts.LeaveFree {
+				return errLeaveFree
+			}
+		}
+		n, err := file.WriteAt(buffer, task.Offset)
+		written += int64(n)
+		f.written.Add(int64(n))
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Name(), err)
+		}
+		return nil
+	})
+	return written, err
+}
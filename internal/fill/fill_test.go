@@ -0,0 +1,127 @@
+package fill
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxkimambo/trasher/internal/diskspace"
+)
+
+// fakeSpace makes f see a filesystem of capacity bytes that only its own
+// writes use.
+func fakeSpace(f *Filler, capacity int64) {
+	f.query = func(dir string) (*diskspace.Info, error) {
+		return &diskspace.Info{Available: capacity - f.BytesWritten(), Total: capacity, Volume: "/fake"}, nil
+	}
+}
+
+func TestFillLeavesFreeSpace(t *testing.T) {
+	const mb = 1024 * 1024
+	dir := t.TempDir()
+	// An existing name is skipped rather than overwritten
+	existing := filepath.Join(dir, "trasher-fill-0001.dat")
+	if err := os.WriteFile(existing, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := New(dir, Options{LeaveFree: 3 * mb, FileSize: 3 * mb, ChunkSize: mb, Pattern: "sequential", Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeSpace(f, 10*mb)
+
+	result, err := f.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Reason != StopLeaveFree {
+		t.Errorf("expected to stop at the free space to leave, got %q", result.Reason)
+	}
+	if result.Written != 7*mb || result.Available != 3*mb {
+		t.Errorf("expected 7MB written and 3MB free, got %d and %d", result.Written, result.Available)
+	}
+
+	wantSizes := []int64{3 * mb, 3 * mb, mb}
+	if len(result.Files) != len(wantSizes) {
+		t.Fatalf("expected %d files, got %v", len(wantSizes), result.Files)
+	}
+	if result.Files[0] != filepath.Join(dir, "trasher-fill-0002.dat") {
+		t.Errorf("expected the first file to skip the existing name, got %s", result.Files[0])
+	}
+	for i, path := range result.Files {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != wantSizes[i] {
+			t.Errorf("%s: expected %d bytes, got %d", path, wantSizes[i], info.Size())
+		}
+	}
+	if data, err := os.ReadFile(existing); err != nil || string(data) != "keep" {
+		t.Errorf("existing file was changed: %q, %v", data, err)
+	}
+}
+
+func TestFillStopsMidFile(t *testing.T) {
+	const mb = 1024 * 1024
+	dir := t.TempDir()
+	f, err := New(dir, Options{LeaveFree: mb, FileSize: 8 * mb, ChunkSize: mb})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeSpace(f, 5*mb)
+	// Something else takes space while the fill runs
+	query := f.query
+	f.query = func(dir string) (*diskspace.Info, error) {
+		info, err := query(dir)
+		if f.BytesWritten() >= 2*mb {
+			info.Available -= mb
+		}
+		return info, err
+	}
+
+	result, err := f.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Reason != StopLeaveFree || result.Written != 3*mb || len(result.Files) != 1 {
+		t.Errorf("expected one file of 3MB, got %d bytes in %v (%s)", result.Written, result.Files, result.Reason)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the written file to be left, got %d entries", len(entries))
+	}
+}
+
+func TestNewRejectsTooLittleSpace(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(dir, Options{LeaveFree: math.MaxInt64}); err == nil {
+		t.Error("expected an error when less space is free than is to be left")
+	}
+	if _, err := New(dir, Options{LeaveFree: -1}); err == nil {
+		t.Error("expected an error for a negative amount to leave")
+	}
+	if _, err := New(filepath.Join(dir, "missing"), Options{}); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+	if _, err := New(dir, Options{Pattern: "nonexistent"}); err == nil {
+		t.Error("expected an error for an unknown pattern")
+	}
+}
+
+func TestFillCancelled(t *testing.T) {
+	const mb = 1024 * 1024
+	f, err := New(t.TempDir(), Options{FileSize: 4 * mb, ChunkSize: mb})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeSpace(f, 64*mb)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := f.Run(ctx); err == nil {
+		t.Error("expected an error from a cancelled fill")
+	}
+}
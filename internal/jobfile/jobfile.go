@@ -0,0 +1,150 @@
+// Package jobfile loads job files: lists of files for one trasher run to
+// generate, each with its own path, size, pattern and seed.
+//
+// A job file is YAML or JSON:
+//
+//	defaults:
+//	  pattern: random
+//	  size: 1GB
+//	files:
+//	  - path: data/a.bin
+//	    size: 10GB
+//	    seed: 42
+//	  - path: data/b.bin
+//	    pattern: zero
+//
+// Entries take the size and pattern they leave out from defaults. Sizes
+// are written as for --size, or as a number of bytes.
+package jobfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/maxkimambo/trasher/internal/yaml"
+	"github.com/maxkimambo/trasher/pkg/sizeparser"
+)
+
+// Size is a size in a job file, given as a string such as 10GB or as a
+// number of bytes.
+type Size string
+
+// UnmarshalJSON accepts a string or a number of bytes.
+func (s *Size) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err == nil {
+		*s = Size(n.String() + "B")
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("size must be a string such as 10GB or a number of bytes")
+	}
+	*s = Size(text)
+	return nil
+}
+
+// Entry is one file as a job file describes it.
+type Entry struct {
+	Path    string `json:"path"`
+	Size    Size   `json:"size"`
+	Pattern string `json:"pattern"`
+	Seed    *int64 `json:"seed"`
+}
+
+// Defaults holds the values entries leave out.
+type Defaults struct {
+	Size    Size   `json:"size"`
+	Pattern string `json:"pattern"`
+}
+
+// File is the content of a job file.
+type File struct {
+	Defaults Defaults `json:"defaults"`
+	Files    []Entry  `json:"files"`
+}
+
+// Job is a file to generate.
+type Job struct {
+	Path    string
+	Size    int64
+	Pattern string
+	// Seed is the seed of the pattern, or nil for a random one.
+	Seed *int64
+}
+
+// DefaultPattern is the pattern of entries that name none, with no default
+// in the file either.
+const DefaultPattern = "random"
+
+// Load reads the job file at path, as JSON if its name ends in .json and as
+// YAML otherwise, and returns its files with the defaults applied.
+func Load(path string) ([]Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job file: %v", err)
+	}
+	jobs, err := Parse(data, strings.EqualFold(filepath.Ext(path), ".json"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid job file %s: %v", path, err)
+	}
+	return jobs, nil
+}
+
+// Parse decodes a job file, as JSON if isJSON is set and as YAML otherwise,
+// and returns its files with the defaults applied.
+func Parse(data []byte, isJSON bool) ([]Job, error) {
+	var file File
+	if isJSON {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, err
+		}
+	} else if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if len(file.Files) == 0 {
+		return nil, fmt.Errorf("no files listed")
+	}
+
+	jobs := make([]Job, 0, len(file.Files))
+	seen := make(map[string]int)
+	for i, entry := range file.Files {
+		if entry.Path == "" {
+			return nil, fmt.Errorf("file %d has no path", i+1)
+		}
+		clean := filepath.Clean(entry.Path)
+		if first, dup := seen[clean]; dup {
+			return nil, fmt.Errorf("file %d has the same path as file %d: %s", i+1, first, entry.Path)
+		}
+		seen[clean] = i + 1
+
+		size := entry.Size
+		if size == "" {
+			size = file.Defaults.Size
+		}
+		if size == "" {
+			return nil, fmt.Errorf("%s has no size and there is no default size", entry.Path)
+		}
+		sizeBytes, err := sizeparser.Parse(string(size))
+		if err != nil {
+			return nil, fmt.Errorf("invalid size for %s: %v", entry.Path, err)
+		}
+
+		pattern := entry.Pattern
+		if pattern == "" {
+			pattern = file.Defaults.Pattern
+		}
+		if pattern == "" {
+			pattern = DefaultPattern
+		}
+
+		jobs = append(jobs, Job{Path: entry.Path, Size: sizeBytes, Pattern: pattern, Seed: entry.Seed})
+	}
+	return jobs, nil
+}
@@ -0,0 +1,79 @@
+package jobfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func seed(n int64) *int64 {
+	return &n
+}
+
+func TestParseYAML(t *testing.T) {
+	doc := `
+defaults:
+  size: 1KB
+  pattern: sequential
+files:
+  - path: a.bin
+    size: 10MB
+    seed: 42
+  - path: b.bin
+    pattern: zero
+  - path: c.bin
+    size: 512
+`
+	jobs, err := Parse([]byte(doc), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Job{
+		{Path: "a.bin", Size: 10 * 1024 * 1024, Pattern: "sequential", Seed: seed(42)},
+		{Path: "b.bin", Size: 1024, Pattern: "zero"},
+		{Path: "c.bin", Size: 512, Pattern: "sequential"},
+	}
+	if !reflect.DeepEqual(jobs, want) {
+		t.Errorf("got %+v, want %+v", jobs, want)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	doc := `{"files": [{"path": "a.bin", "size": "2KB", "seed": 7}, {"path": "b.bin", "size": 100}]}`
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Job{
+		{Path: "a.bin", Size: 2048, Pattern: DefaultPattern, Seed: seed(7)},
+		{Path: "b.bin", Size: 100, Pattern: DefaultPattern},
+	}
+	if !reflect.DeepEqual(jobs, want) {
+		t.Errorf("got %+v, want %+v", jobs, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, doc := range []string{
+		"",
+		"files:\n",
+		"files:\n  - size: 1KB\n",
+		"files:\n  - path: a.bin\n",
+		"files:\n  - path: a.bin\n    size: lots\n",
+		"files:\n  - path: a.bin\n    size: 1KB\n  - path: ./a.bin\n    size: 1KB\n",
+		"files:\n  - path: a.bin\n    size: 1KB\n    sede: 1\n",
+		"files:\n  - path: a.bin\n    size: 1KB\n    seed: x\n",
+	} {
+		if _, err := Parse([]byte(doc), false); err == nil {
+			t.Errorf("expected an error for %q", doc)
+		}
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing job file")
+	}
+}
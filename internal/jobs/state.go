@@ -0,0 +1,255 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State describes the lifecycle state of a generation job.
+type State string
+
+const (
+	StateRunning   State = "running"
+	StateCompleted State = "completed"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+	// StateStale marks a running job whose owner stopped updating its record.
+	StateStale State = "stale"
+)
+
+const (
+	// updateInterval is how often a running job refreshes its record.
+	updateInterval = time.Second
+	// staleAfter is how long a running record may go without updates.
+	staleAfter = 10 * time.Second
+	// retention is how long finished records are kept for status listings.
+	retention = 24 * time.Hour
+)
+
+// Record is the persisted state of a single job.
+type Record struct {
+	ID         string    `json:"id"`
+	PID        int       `json:"pid"`
+	Output     string    `json:"output"`
+	Pattern    string    `json:"pattern"`
+	TotalBytes int64     `json:"total_bytes"`
+	Written    int64     `json:"written_bytes"`
+	Throughput float64   `json:"throughput"`
+	State      State     `json:"state"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Percent returns the completion percentage of the job.
+func (r Record) Percent() float64 {
+	if r.TotalBytes <= 0 {
+		return 0
+	}
+	percent := float64(r.Written) / float64(r.TotalBytes) * 100
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// ETA returns the estimated time remaining for a running job, or 0 if unknown.
+func (r Record) ETA() time.Duration {
+	if r.State != StateRunning || r.Throughput <= 0 || r.Written >= r.TotalBytes {
+		return 0
+	}
+	remaining := float64(r.TotalBytes - r.Written)
+	return time.Duration(remaining/r.Throughput) * time.Second
+}
+
+// DefaultDir returns the per-user directory where job records are kept,
+// ~/.cache/trasher/jobs on Linux.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %v", err)
+	}
+	return filepath.Join(dir, "trasher", "jobs"), nil
+}
+
+// Tracker keeps a job's record up to date while the job runs.
+type Tracker struct {
+	dir    string
+	record Record
+	mu     sync.Mutex
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// Register creates a record for a new running job in dir.
+func Register(dir, output, pattern string, totalBytes int64) (*Tracker, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job directory: %v", err)
+	}
+
+	if abs, err := filepath.Abs(output); err == nil {
+		output = abs
+	}
+
+	now := time.Now()
+	t := &Tracker{
+		dir: dir,
+		record: Record{
+			ID:         fmt.Sprintf("%d-%d", os.Getpid(), now.UnixNano()),
+			PID:        os.Getpid(),
+			Output:     output,
+			Pattern:    pattern,
+			TotalBytes: totalBytes,
+			State:      StateRunning,
+			StartedAt:  now,
+			UpdatedAt:  now,
+		},
+		done: make(chan struct{}),
+	}
+
+	if err := t.save(); err != nil {
+		return nil, err
+	}
+
+	// Opportunistically drop old records so the directory doesn't grow forever
+	Prune(dir, retention)
+
+	return t, nil
+}
+
+// ID returns the job identifier.
+func (t *Tracker) ID() string {
+	return t.record.ID
+}
+
+// Start periodically refreshes the record using getWritten for progress.
+func (t *Tracker) Start(getWritten func() int64) {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(updateInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-t.done:
+				return
+			case <-ticker.C:
+				t.update(getWritten(), StateRunning, nil)
+			}
+		}
+	}()
+}
+
+// Finish stops refreshing and records the final state of the job.
+func (t *Tracker) Finish(written int64, state State, jobErr error) {
+	select {
+	case <-t.done:
+		return
+	default:
+		close(t.done)
+	}
+	t.wg.Wait()
+	t.update(written, state, jobErr)
+}
+
+// update writes the latest progress to the record.
+func (t *Tracker) update(written int64, state State, jobErr error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.record.Written = written
+	t.record.State = state
+	t.record.UpdatedAt = now
+	if elapsed := now.Sub(t.record.StartedAt).Seconds(); elapsed > 0 {
+		t.record.Throughput = float64(written) / elapsed
+	}
+	if jobErr != nil {
+		t.record.Error = jobErr.Error()
+	}
+
+	// Records are best-effort; a failed update must not break generation
+	t.save()
+}
+
+// save atomically writes the record to its file.
+func (t *Tracker) save() error {
+	data, err := json.MarshalIndent(t.record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode job record: %v", err)
+	}
+
+	path := filepath.Join(t.dir, t.record.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write job record: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write job record: %v", err)
+	}
+	return nil
+}
+
+// List returns all job records in dir, newest first. Running records that
+// have not been refreshed recently are reported as stale.
+func List(dir string) ([]Record, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job directory: %v", err)
+	}
+
+	var records []Record
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+
+		if record.State == StateRunning && time.Since(record.UpdatedAt) > staleAfter {
+			record.State = StateStale
+		}
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartedAt.After(records[j].StartedAt)
+	})
+
+	return records, nil
+}
+
+// Prune removes finished or stale records last updated more than maxAge ago.
+func Prune(dir string, maxAge time.Duration) error {
+	records, err := List(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if record.State == StateRunning || time.Since(record.UpdatedAt) <= maxAge {
+			continue
+		}
+		os.Remove(filepath.Join(dir, record.ID+".json"))
+	}
+	return nil
+}
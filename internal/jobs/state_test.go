@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegisterAndFinish(t *testing.T) {
+	dir := t.TempDir()
+
+	tracker, err := Register(dir, "/tmp/out.dat", "random", 1000)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	records, err := List(dir)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	if records[0].State != StateRunning {
+		t.Errorf("expected state running, got %s", records[0].State)
+	}
+	if records[0].ID != tracker.ID() {
+		t.Errorf("expected ID %s, got %s", tracker.ID(), records[0].ID)
+	}
+
+	tracker.Finish(1000, StateCompleted, nil)
+
+	records, _ = List(dir)
+	if records[0].State != StateCompleted {
+		t.Errorf("expected state completed, got %s", records[0].State)
+	}
+	if records[0].Written != 1000 {
+		t.Errorf("expected 1000 bytes written, got %d", records[0].Written)
+	}
+	if records[0].Percent() != 100 {
+		t.Errorf("expected 100%%, got %.2f", records[0].Percent())
+	}
+
+	// Finishing twice should be a no-op
+	tracker.Finish(0, StateFailed, errors.New("ignored"))
+	records, _ = List(dir)
+	if records[0].State != StateCompleted {
+		t.Errorf("expected second Finish to be ignored, got %s", records[0].State)
+	}
+}
+
+func TestTrackerFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	tracker, err := Register(dir, "out.dat", "zero", 100)
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	tracker.Start(func() int64 { return 50 })
+	tracker.Finish(50, StateFailed, errors.New("disk full"))
+
+	records, _ := List(dir)
+	if records[0].State != StateFailed {
+		t.Errorf("expected state failed, got %s", records[0].State)
+	}
+	if records[0].Error != "disk full" {
+		t.Errorf("expected error 'disk full', got %q", records[0].Error)
+	}
+}
+
+func TestListMarksStaleRecords(t *testing.T) {
+	dir := t.TempDir()
+
+	record := Record{
+		ID:        "1-1",
+		State:     StateRunning,
+		StartedAt: time.Now().Add(-time.Hour),
+		UpdatedAt: time.Now().Add(-time.Minute),
+	}
+	data, _ := json.Marshal(record)
+	os.WriteFile(filepath.Join(dir, "1-1.json"), data, 0644)
+
+	records, err := List(dir)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(records) != 1 || records[0].State != StateStale {
+		t.Errorf("expected a single stale record, got %+v", records)
+	}
+}
+
+func TestDefaultDirIsPerUser(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", cache)
+
+	dir, err := DefaultDir()
+	if err != nil {
+		t.Fatalf("DefaultDir failed: %v", err)
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(base, "trasher", "jobs"); dir != want {
+		t.Errorf("expected %s, got %s", want, dir)
+	}
+}
+
+func TestListMissingDir(t *testing.T) {
+	records, err := List(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Errorf("expected no error for missing directory, got %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("expected no records, got %d", len(records))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+
+	old := Record{ID: "1-1", State: StateCompleted, UpdatedAt: time.Now().Add(-48 * time.Hour)}
+	recent := Record{ID: "2-2", State: StateCompleted, UpdatedAt: time.Now()}
+	for _, r := range []Record{old, recent} {
+		data, _ := json.Marshal(r)
+		os.WriteFile(filepath.Join(dir, r.ID+".json"), data, 0644)
+	}
+
+	if err := Prune(dir, 24*time.Hour); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+
+	records, _ := List(dir)
+	if len(records) != 1 || records[0].ID != "2-2" {
+		t.Errorf("expected only the recent record to remain, got %+v", records)
+	}
+}
+
+func TestRecordETA(t *testing.T) {
+	r := Record{State: StateRunning, TotalBytes: 1000, Written: 500, Throughput: 100}
+	if eta := r.ETA(); eta != 5*time.Second {
+		t.Errorf("expected ETA 5s, got %v", eta)
+	}
+
+	r.State = StateCompleted
+	if eta := r.ETA(); eta != 0 {
+		t.Errorf("expected no ETA for completed job, got %v", eta)
+	}
+}
@@ -0,0 +1,102 @@
+package latency
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// linearBits sets the histogram's precision: values below 1<<linearBits
+// have exact one-nanosecond buckets, and above them each power of two is
+// divided into subBuckets buckets, which bounds the error of a recorded
+// value to 1/subBuckets, under 2%.
+const (
+	linearBits    = 7
+	linearBuckets = 1 << linearBits
+	subBuckets    = linearBuckets / 2
+)
+
+// numBuckets covers every non-negative int64.
+const numBuckets = linearBuckets + (63-linearBits)*subBuckets
+
+// Histogram records durations into logarithmic buckets of constant relative
+// width, as HDR histograms do, so quantiles from nanoseconds to hours are
+// accurate to within 2% in a fixed amount of memory. Recording is lock-free
+// and safe from any number of goroutines.
+type Histogram struct {
+	counts [numBuckets]atomic.Uint64
+	count  atomic.Uint64
+	max    atomic.Int64
+}
+
+// NewHistogram creates an empty histogram.
+func NewHistogram() *Histogram {
+	return &Histogram{}
+}
+
+// Record adds one duration to the histogram. Negative durations count as
+// zero.
+func (h *Histogram) Record(d time.Duration) {
+	v := max(int64(d), 0)
+	h.counts[bucket(v)].Add(1)
+	h.count.Add(1)
+	for {
+		current := h.max.Load()
+		if v <= current || h.max.CompareAndSwap(current, v) {
+			return
+		}
+	}
+}
+
+// Count returns the number of durations recorded.
+func (h *Histogram) Count() uint64 {
+	return h.count.Load()
+}
+
+// Max returns the longest duration recorded.
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max.Load())
+}
+
+// Quantile returns the duration that q of the recorded durations, from 0 to
+// 1, are at most: the upper end of the bucket holding that rank, capped at
+// the maximum. It returns 0 for an empty histogram.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	total := h.count.Load()
+	if total == 0 {
+		return 0
+	}
+
+	rank := max(uint64(math.Ceil(q*float64(total))), 1)
+	var seen uint64
+	for i := range h.counts {
+		if seen += h.counts[i].Load(); seen >= rank {
+			return min(time.Duration(upperBound(i)), h.Max())
+		}
+	}
+	return h.Max()
+}
+
+// bucket returns the index of the bucket holding v.
+func bucket(v int64) int {
+	if v < linearBuckets {
+		return int(v)
+	}
+	// Above the linear range, v is in the sub-bucket of its top bits
+	shift := bits.Len64(uint64(v)) - linearBits
+	return linearBuckets + (shift-1)*subBuckets + int(v>>shift) - subBuckets
+}
+
+// upperBound returns the largest value in bucket i.
+func upperBound(i int) int64 {
+	if i < linearBuckets {
+		return int64(i)
+	}
+	shift := (i-linearBuckets)/subBuckets + 1
+	sub := int64((i-linearBuckets)%subBuckets + subBuckets)
+	if shift+bits.Len64(uint64(sub+1)) > 63 {
+		return math.MaxInt64
+	}
+	return (sub+1)<<shift - 1
+}
@@ -0,0 +1,86 @@
+package latency
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHistogramQuantiles(t *testing.T) {
+	h := NewHistogram()
+	if h.Quantile(0.5) != 0 || h.Max() != 0 || h.Count() != 0 {
+		t.Fatal("expected an empty histogram to report zeros")
+	}
+
+	// 1µs to 1000µs, once each
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+
+	if h.Count() != 1000 {
+		t.Errorf("expected 1000 durations, got %d", h.Count())
+	}
+	if h.Max() != time.Millisecond {
+		t.Errorf("expected max 1ms, got %v", h.Max())
+	}
+
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, time.Microsecond},
+		{0.5, 500 * time.Microsecond},
+		{0.95, 950 * time.Microsecond},
+		{0.99, 990 * time.Microsecond},
+		{1, time.Millisecond},
+	}
+	for _, test := range tests {
+		got := h.Quantile(test.q)
+		if got < test.want || float64(got-test.want) > float64(test.want)/subBuckets {
+			t.Errorf("p%v: expected %v within %.1f%%, got %v", test.q*100, test.want, 100.0/subBuckets, got)
+		}
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	// Every value falls into a bucket whose upper bound is at least the
+	// value and close to it
+	values := []int64{0, 1, 127, 128, 129, 255, 256, 1000, 123456789, 1 << 40, math.MaxInt64 - 1, math.MaxInt64}
+	for _, v := range values {
+		i := bucket(v)
+		if i < 0 || i >= numBuckets {
+			t.Fatalf("value %d: bucket %d out of range", v, i)
+		}
+		upper := upperBound(i)
+		if upper < v {
+			t.Errorf("value %d: bucket %d ends below it at %d", v, i, upper)
+		}
+		if i > 0 && upperBound(i-1) >= v {
+			t.Errorf("value %d: previous bucket %d also holds it", v, i-1)
+		}
+	}
+}
+
+func TestHistogramConcurrent(t *testing.T) {
+	h := NewHistogram()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				h.Record(time.Duration(i*1000+j) * time.Nanosecond)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if h.Count() != 8000 {
+		t.Errorf("expected 8000 durations, got %d", h.Count())
+	}
+	if h.Max() != 7999 {
+		t.Errorf("expected max 7999ns, got %v", h.Max())
+	}
+}
@@ -0,0 +1,170 @@
+package lock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Lock is an advisory lock held on a generation target (file or device).
+type Lock struct {
+	file   *os.File
+	target string
+}
+
+// LockedError is returned when another process already holds the lock.
+type LockedError struct {
+	Target string
+	PID    int
+}
+
+func (e *LockedError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("%s is locked by PID %d (use --no-lock to bypass)", e.Target, e.PID)
+	}
+	return fmt.Sprintf("%s is locked by another trasher process (use --no-lock to bypass)", e.Target)
+}
+
+// Acquire takes an exclusive advisory lock for the given target path.
+// The lock is keyed on the resolved absolute path, so different spellings of
+// the same file or device (relative paths, symlinks) share one lock.
+func Acquire(target string) (*Lock, error) {
+	lockPath, err := lockPathFor(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := makeLockDir(filepath.Dir(lockPath)); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %v", err)
+	}
+
+	file, err := openLockFile(lockPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %v", err)
+	}
+
+	if err := tryLock(file); err != nil {
+		pid := readPID(file)
+		file.Close()
+		if err == errWouldBlock {
+			return nil, &LockedError{Target: target, PID: pid}
+		}
+		return nil, fmt.Errorf("failed to lock %s: %v", target, err)
+	}
+
+	// Record our PID so competing processes can report who holds the lock
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	return &Lock{file: file, target: target}, nil
+}
+
+// Release drops the lock. It is safe to call Release more than once.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	l.file.Truncate(0)
+	err := unlock(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
+
+// Target returns the path the lock was taken for.
+func (l *Lock) Target() string {
+	return l.target
+}
+
+// makeLockDir creates the lock directory, which every user shares so that
+// their runs lock each other out of a target. Like /tmp it is writable by
+// all and sticky, so users can't remove each other's lock files.
+func makeLockDir(dir string) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	if err := os.Mkdir(dir, 0777); err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+		return err
+	}
+	// Chmod sets the mode regardless of the umask
+	return os.Chmod(dir, 0777|os.ModeSticky)
+}
+
+// openLockFile opens the lock file at path, creating it readable and
+// writable by every user. An existing file is opened without O_CREATE, which
+// Linux refuses for other users' files in sticky directories when
+// fs.protected_regular is set.
+func openLockFile(path string) (*os.File, error) {
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR, 0)
+		if !os.IsNotExist(err) {
+			return file, err
+		}
+		file, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666)
+		if os.IsExist(err) {
+			// Another process created it first
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := file.Chmod(0666); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return file, nil
+	}
+}
+
+// lockPathFor maps a target path to its lock file in the shared lock directory.
+func lockPathFor(target string) (string, error) {
+	resolved, err := resolvePath(target)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(resolved))
+	name := hex.EncodeToString(sum[:8]) + ".lock"
+	return filepath.Join(os.TempDir(), "trasher-locks", name), nil
+}
+
+// resolvePath returns a canonical absolute path for target. Targets that do
+// not exist yet are resolved through their parent directory.
+func resolvePath(target string) (string, error) {
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path %s: %v", target, err)
+	}
+
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved, nil
+	}
+
+	dir, base := filepath.Split(abs)
+	if resolvedDir, err := filepath.EvalSymlinks(dir); err == nil {
+		return filepath.Join(resolvedDir, base), nil
+	}
+	return abs, nil
+}
+
+// readPID reads the PID recorded in a lock file, returning 0 if unknown.
+func readPID(file *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := file.ReadAt(buf, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
@@ -0,0 +1,92 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquireAndRelease(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "target.dat")
+
+	l, err := Acquire(target)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if l.Target() != target {
+		t.Errorf("expected target %s, got %s", target, l.Target())
+	}
+
+	if err := l.Release(); err != nil {
+		t.Errorf("Release failed: %v", err)
+	}
+
+	// Releasing twice should be a no-op
+	if err := l.Release(); err != nil {
+		t.Errorf("second Release failed: %v", err)
+	}
+
+	// Lock should be available again
+	l2, err := Acquire(target)
+	if err != nil {
+		t.Fatalf("re-Acquire failed: %v", err)
+	}
+	l2.Release()
+}
+
+func TestAcquireContended(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "target.dat")
+
+	l, err := Acquire(target)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer l.Release()
+
+	_, err = Acquire(target)
+	if err == nil {
+		t.Fatal("expected error when lock is already held")
+	}
+
+	var lockedErr *LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("expected LockedError, got %T: %v", err, err)
+	}
+	if lockedErr.PID != os.Getpid() {
+		t.Errorf("expected PID %d, got %d", os.Getpid(), lockedErr.PID)
+	}
+}
+
+func TestAcquireSameTargetDifferentSpelling(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.dat")
+
+	l, err := Acquire(target)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer l.Release()
+
+	// A path with redundant elements must map to the same lock
+	_, err = Acquire(filepath.Join(dir, ".", "target.dat"))
+	if err == nil {
+		t.Error("expected equivalent path to be locked")
+	}
+}
+
+func TestAcquireDifferentTargets(t *testing.T) {
+	dir := t.TempDir()
+
+	l1, err := Acquire(filepath.Join(dir, "a.dat"))
+	if err != nil {
+		t.Fatalf("Acquire a.dat failed: %v", err)
+	}
+	defer l1.Release()
+
+	l2, err := Acquire(filepath.Join(dir, "b.dat"))
+	if err != nil {
+		t.Fatalf("Acquire b.dat failed: %v", err)
+	}
+	defer l2.Release()
+}
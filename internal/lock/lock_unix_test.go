@@ -0,0 +1,60 @@
+//go:build unix || linux || darwin
+
+package lock
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestLockSharedBetweenUsers(t *testing.T) {
+	// A fresh lock directory under a temporary directory other users can
+	// reach
+	tmp := t.TempDir()
+	for _, dir := range []string{filepath.Dir(tmp), tmp} {
+		if err := os.Chmod(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("TMPDIR", tmp)
+	target := filepath.Join(tmp, "target.dat")
+
+	l, err := Acquire(target)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	l.Release()
+
+	lockPath, err := lockPathFor(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirInfo, err := os.Stat(filepath.Dir(lockPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dirInfo.Mode().Perm() != 0777 || dirInfo.Mode()&os.ModeSticky == 0 {
+		t.Errorf("expected a sticky lock directory writable by all, got %v", dirInfo.Mode())
+	}
+	fileInfo, err := os.Stat(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileInfo.Mode().Perm() != 0666 {
+		t.Errorf("expected a lock file writable by all, got %v", fileInfo.Mode())
+	}
+
+	if os.Geteuid() != 0 {
+		t.Skip("opening the lock as another user needs root")
+	}
+	// Another user can open the existing lock file and create new ones
+	other := filepath.Join(filepath.Dir(lockPath), "other.lock")
+	cmd := exec.Command("/bin/sh", "-c", `exec 3<>"$1" && : > "$2"`, "sh", lockPath, other)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{Uid: 65534, Gid: 65534}}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("expected another user to open the lock: %v: %s", err, out)
+	}
+}
@@ -0,0 +1,67 @@
+//go:build windows
+
+package lock
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var errWouldBlock = errors.New("lock is held by another process")
+
+const (
+	lockfileFailImmediately = 0x00000001
+	lockfileExclusiveLock   = 0x00000002
+	errorLockViolation      = syscall.Errno(33)
+
+	// Lock a byte range past the PID record so other processes can still read it.
+	lockOffset = 1 << 30
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+// tryLock takes a non-blocking exclusive LockFileEx lock on the file.
+func tryLock(file *os.File) error {
+	var overlapped syscall.Overlapped
+	overlapped.Offset = lockOffset
+
+	ret, _, err := procLockFileEx.Call(
+		file.Fd(),
+		uintptr(lockfileExclusiveLock|lockfileFailImmediately),
+		0,
+		1,
+		0,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if ret == 0 {
+		if err == errorLockViolation {
+			return errWouldBlock
+		}
+		return err
+	}
+	return nil
+}
+
+// unlock releases the LockFileEx lock held on the file.
+func unlock(file *os.File) error {
+	var overlapped syscall.Overlapped
+	overlapped.Offset = lockOffset
+
+	ret, _, err := procUnlockFileEx.Call(
+		file.Fd(),
+		0,
+		1,
+		0,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if ret == 0 {
+		return err
+	}
+	return nil
+}
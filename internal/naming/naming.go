@@ -0,0 +1,158 @@
+// Package naming expands output name templates, which name each file of a
+// multi-file run with placeholders such as data-{seq:04}-{rand:6}.bin:
+//
+//	{seq}       the file's number from 1, padded to the width of the count
+//	{seq:N}     the file's number padded with zeros to N digits
+//	{rand}      8 random lowercase letters and digits, different per file
+//	{rand:N}    N random lowercase letters and digits
+//	{timestamp} the run's start time in UTC, as 20060102T150405Z
+//	{date}      the run's start date in UTC, as 20060102
+//	{pattern}   the name of the data pattern
+package naming
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRandLength is the length of {rand} without an argument.
+const DefaultRandLength = 8
+
+// Limits of the placeholders' arguments.
+const (
+	maxRandLength = 64
+	maxSeqWidth   = 20
+)
+
+// randChars are the characters {rand} draws from.
+const randChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// Vars holds the values of the placeholders that are the same for every
+// file.
+type Vars struct {
+	// Pattern is the name of the data pattern.
+	Pattern string
+	// Time is when the run started.
+	Time time.Time
+}
+
+// segment is a literal part of a template, or a placeholder with its
+// argument.
+type segment struct {
+	literal string
+	name    string
+	arg     int
+}
+
+// IsTemplate reports whether name contains placeholders.
+func IsTemplate(name string) bool {
+	return strings.Contains(name, "{")
+}
+
+// Expand returns the names of count files from template, the Nth with N as
+// its {seq}. Names can collide, for example when the template has neither
+// {seq} nor {rand}; callers check for duplicates.
+func Expand(template string, count int, vars Vars) ([]string, error) {
+	segments, err := parse(template)
+	if err != nil {
+		return nil, err
+	}
+
+	width := len(strconv.Itoa(count))
+	names := make([]string, count)
+	for i := range names {
+		var b strings.Builder
+		for _, seg := range segments {
+			switch seg.name {
+			case "":
+				b.WriteString(seg.literal)
+			case "seq":
+				w := width
+				if seg.arg > 0 {
+					w = seg.arg
+				}
+				fmt.Fprintf(&b, "%0*d", w, i+1)
+			case "rand":
+				n := DefaultRandLength
+				if seg.arg > 0 {
+					n = seg.arg
+				}
+				for range n {
+					b.WriteByte(randChars[rand.N(len(randChars))])
+				}
+			case "timestamp":
+				b.WriteString(vars.Time.UTC().Format("20060102T150405Z"))
+			case "date":
+				b.WriteString(vars.Time.UTC().Format("20060102"))
+			case "pattern":
+				b.WriteString(vars.Pattern)
+			}
+		}
+		names[i] = b.String()
+	}
+	return names, nil
+}
+
+// parse splits a template into literals and placeholders.
+func parse(template string) ([]segment, error) {
+	var segments []segment
+	rest := template
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			if strings.Contains(rest, "}") {
+				return nil, fmt.Errorf("output template %s has a } without a {", template)
+			}
+			segments = append(segments, segment{literal: rest})
+			break
+		}
+		if strings.Contains(rest[:start], "}") {
+			return nil, fmt.Errorf("output template %s has a } without a {", template)
+		}
+		if start > 0 {
+			segments = append(segments, segment{literal: rest[:start]})
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("output template %s has an unclosed {", template)
+		}
+		seg, err := parsePlaceholder(rest[start+1 : start+end])
+		if err != nil {
+			return nil, fmt.Errorf("output template %s: %v", template, err)
+		}
+		segments = append(segments, seg)
+		rest = rest[start+end+1:]
+	}
+	return segments, nil
+}
+
+// parsePlaceholder parses the text between a placeholder's braces.
+func parsePlaceholder(text string) (segment, error) {
+	name, rawArg, hasArg := strings.Cut(text, ":")
+	seg := segment{name: name}
+	switch name {
+	case "seq", "rand":
+		if !hasArg {
+			return seg, nil
+		}
+		limit := maxSeqWidth
+		if name == "rand" {
+			limit = maxRandLength
+		}
+		n, err := strconv.Atoi(rawArg)
+		if err != nil || n < 1 || n > limit {
+			return seg, fmt.Errorf("{%s} takes a length from 1 to %d, got %q", name, limit, rawArg)
+		}
+		seg.arg = n
+	case "timestamp", "date", "pattern":
+		if hasArg {
+			return seg, fmt.Errorf("{%s} takes no argument", name)
+		}
+	default:
+		return seg, fmt.Errorf("unknown placeholder {%s} (available: seq, rand, timestamp, date, pattern)", text)
+	}
+	return seg, nil
+}
@@ -0,0 +1,75 @@
+package naming
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestExpand(t *testing.T) {
+	vars := Vars{Pattern: "random", Time: time.Date(2024, 3, 9, 14, 5, 7, 0, time.UTC)}
+
+	tests := []struct {
+		template string
+		count    int
+		want     []string
+	}{
+		{"data-{seq:04}.bin", 3, []string{"data-0001.bin", "data-0002.bin", "data-0003.bin"}},
+		{"data-{seq}.bin", 10, []string{"data-01.bin", "data-02.bin", "data-03.bin", "data-04.bin", "data-05.bin",
+			"data-06.bin", "data-07.bin", "data-08.bin", "data-09.bin", "data-10.bin"}},
+		{"{pattern}/{date}/{timestamp}-{seq:1}", 2, []string{"random/20240309/20240309T140507Z-1", "random/20240309/20240309T140507Z-2"}},
+		{"same.bin", 2, []string{"same.bin", "same.bin"}},
+	}
+	for _, tt := range tests {
+		got, err := Expand(tt.template, tt.count, vars)
+		if err != nil {
+			t.Errorf("%s: %v", tt.template, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestExpandRand(t *testing.T) {
+	names, err := Expand("f-{rand:6}-{rand}.dat", 50, Vars{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := regexp.MustCompile(`^f-[a-z0-9]{6}-[a-z0-9]{8}\.dat$`)
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if !valid.MatchString(name) {
+			t.Errorf("unexpected name %s", name)
+		}
+		seen[name] = true
+	}
+	if len(seen) < 2 {
+		t.Error("expected {rand} to differ between files")
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	for _, template := range []string{
+		"data-{seq",
+		"data-seq}",
+		"data-{}",
+		"data-{nope}",
+		"data-{seq:0}",
+		"data-{seq:x}",
+		"data-{rand:65}",
+		"data-{date:iso}",
+	} {
+		if _, err := Expand(template, 2, Vars{}); err == nil {
+			t.Errorf("expected an error for %s", template)
+		}
+	}
+}
+
+func TestIsTemplate(t *testing.T) {
+	if !IsTemplate("a-{seq}.bin") || IsTemplate("a.bin") {
+		t.Error("IsTemplate misclassified a name")
+	}
+}
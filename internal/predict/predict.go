@@ -0,0 +1,181 @@
+// Package predict computes the checksums a generated file will have without
+// writing it, by generating its data in memory.
+package predict
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/worker"
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+// DefaultChunkSize matches the default chunk size of a generation run.
+const DefaultChunkSize = 64 * 1024 * 1024
+
+// Options describes the run whose checksums are predicted. ChunkSize and
+// Mode must match that run: chunk checksums and tree checksums follow the
+// chunk boundaries, and so do the records of line-oriented patterns.
+type Options struct {
+	// ChunkSize is the run's chunk size. Zero uses DefaultChunkSize.
+	ChunkSize int64
+	// Mode is the run's checksum mode. Empty uses checksum.ModeStream.
+	Mode string
+	// Workers is the number of parallel generators. Zero uses one per CPU.
+	Workers int
+}
+
+// Result holds the predicted checksums.
+type Result struct {
+	// Algorithm and Checksum are the full-file checksum as the checksum
+	// file records it.
+	Algorithm string
+	Checksum  string
+	// Chunks are the chunk checksums, sorted by offset.
+	Chunks  []checksum.ChunkInfo
+	Elapsed time.Duration
+}
+
+// Predictor generates a file's data in memory and hashes it.
+type Predictor struct {
+	pattern   string
+	size      int64
+	opts      Options
+	generated atomic.Int64
+}
+
+// New prepares a prediction of the checksums of a size byte file generated
+// with pattern. The pattern must be reproducible (see
+// generator.Reproducible), so a seeded pattern such as random:seed=42.
+func New(pattern string, size int64, opts Options) (*Predictor, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("size must not be negative, got %d", size)
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.Mode == "" {
+		opts.Mode = checksum.ModeStream
+	}
+	if !generator.Reproducible(pattern) {
+		return nil, fmt.Errorf("pattern %s does not generate the same data on every run; give it a seed, e.g. random:seed=42", pattern)
+	}
+
+	gen, err := generator.NewGenerator(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := gen.(generator.OffsetGenerator); !ok {
+		return nil, fmt.Errorf("pattern %s does not derive its data from the offset and can't be predicted", pattern)
+	}
+	return &Predictor{pattern: pattern, size: size, opts: opts}, nil
+}
+
+// Bytes returns the number of bytes the prediction generates.
+func (p *Predictor) Bytes() int64 {
+	return p.size
+}
+
+// BytesGenerated returns the number of bytes generated so far. It is safe to
+// call while Run is in progress.
+func (p *Predictor) BytesGenerated() int64 {
+	return p.generated.Load()
+}
+
+// Run generates the file's chunks in parallel on a worker pool, hashing each
+// chunk as it is generated and the whole file in offset order.
+func (p *Predictor) Run(ctx context.Context) (*Result, error) {
+	sums := checksum.NewChecksumGenerator(p.pattern, p.size)
+	if err := sums.SetMode(p.opts.Mode); err != nil {
+		return nil, err
+	}
+
+	var tasks []worker.Task
+	for offset := int64(0); offset < p.size; offset += p.opts.ChunkSize {
+		tasks = append(tasks, worker.Task{Offset: offset, Size: min(p.opts.ChunkSize, p.size-offset)})
+	}
+
+	// Generators keep state between chunks, so each worker takes its own
+	factory := generator.NewFactory(p.pattern, generator.Options{})
+	var (
+		gensMu sync.Mutex
+		gens   []generator.Generator
+	)
+	hashers := sync.Pool{New: func() any { return sha256.New() }}
+
+	generate := func(buffer []byte, task worker.Task) error {
+		gensMu.Lock()
+		var gen generator.Generator
+		if n := len(gens); n > 0 {
+			gen, gens = gens[n-1], gens[:n-1]
+		}
+		gensMu.Unlock()
+		if gen == nil {
+			var err error
+			if gen, err = factory(); err != nil {
+				return fmt.Errorf("failed to create generator: %v", err)
+			}
+		}
+		defer func() {
+			gensMu.Lock()
+			gens = append(gens, gen)
+			gensMu.Unlock()
+		}()
+		if err := generator.GenerateChunk(gen, buffer, task.Offset); err != nil {
+			return fmt.Errorf("failed to generate chunk at offset %d: %v", task.Offset, err)
+		}
+
+		hasher := hashers.Get().(hash.Hash)
+		defer hashers.Put(hasher)
+		hasher.Reset()
+		hasher.Write(buffer)
+		if err := sums.AddChunkChecksum(task.Offset, hasher.Sum(nil)); err != nil {
+			return err
+		}
+		p.generated.Add(task.Size)
+		return nil
+	}
+
+	// A tree checksum only needs the chunk checksums; otherwise the whole
+	// file is hashed as well, in offset order
+	tree := p.opts.Mode == checksum.ModeTree
+	start := time.Now()
+	fileHash := sha256.New()
+	pool := worker.NewWorkerPool(ctx, p.opts.Workers, p.opts.ChunkSize)
+	var err error
+	if tree {
+		err = pool.Process(tasks, generate)
+	} else {
+		err = pool.ProcessOrdered(tasks, generate, func(buffer []byte, task worker.Task) error {
+			fileHash.Write(buffer)
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Algorithm: sums.GetAlgorithm(),
+		Chunks:    sums.GetChunkChecksums(),
+		Elapsed:   time.Since(start),
+	}
+	for i := range result.Chunks {
+		result.Chunks[i].Size = min(p.opts.ChunkSize, p.size-result.Chunks[i].Offset)
+	}
+	if tree {
+		if result.Checksum, err = sums.Finalize(); err != nil {
+			return nil, err
+		}
+	} else {
+		result.Checksum = hex.EncodeToString(fileHash.Sum(nil))
+	}
+	return result, nil
+}
@@ -0,0 +1,121 @@
+package predict
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+// generate returns the data a size byte file of pattern holds when it is
+// generated in chunkSize chunks.
+func generate(t *testing.T, pattern string, size, chunkSize int64) []byte {
+	t.Helper()
+
+	gen, err := generator.NewGenerator(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, size)
+	for offset := int64(0); offset < size; offset += chunkSize {
+		end := min(offset+chunkSize, size)
+		if err := generator.GenerateChunk(gen, data[offset:end], offset); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return data
+}
+
+func predict(t *testing.T, pattern string, size int64, opts Options) *Result {
+	t.Helper()
+
+	p, err := New(pattern, size, opts)
+	if err != nil {
+		t.Fatalf("New(%s) failed: %v", pattern, err)
+	}
+	result, err := p.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if p.BytesGenerated() != size {
+		t.Errorf("expected %d bytes generated, got %d", size, p.BytesGenerated())
+	}
+	return result
+}
+
+func TestPredictStream(t *testing.T) {
+	const size, chunkSize = 1000*1000 + 17, 64 * 1024
+
+	for _, pattern := range []string{"random:seed=42", "sequential", "jsonl:seed=3", "crc:seed=9"} {
+		data := generate(t, pattern, size, chunkSize)
+		want := sha256.Sum256(data)
+
+		for _, workers := range []int{1, 4} {
+			result := predict(t, pattern, size, Options{ChunkSize: chunkSize, Workers: workers})
+			if result.Algorithm != "SHA256" || result.Checksum != hex.EncodeToString(want[:]) {
+				t.Errorf("%s with %d workers: got %s %s, want SHA256 %x", pattern, workers, result.Algorithm, result.Checksum, want)
+			}
+
+			if len(result.Chunks) != 16 {
+				t.Fatalf("expected 16 chunks, got %d", len(result.Chunks))
+			}
+			last := result.Chunks[15]
+			lastSum := sha256.Sum256(data[last.Offset:])
+			if last.Size != size-15*chunkSize || last.Checksum != hex.EncodeToString(lastSum[:]) {
+				t.Errorf("%s: unexpected last chunk %+v", pattern, last)
+			}
+		}
+	}
+}
+
+func TestPredictTree(t *testing.T) {
+	const size, chunkSize = 300 * 1024, 64 * 1024
+	data := generate(t, "random:seed=1", size, chunkSize)
+
+	// The tree checksum a generation run would record for the same chunks
+	gen := checksum.NewChecksumGenerator("tree.bin", size)
+	gen.SetMode(checksum.ModeTree)
+	for offset := int64(0); offset < size; offset += chunkSize {
+		gen.UpdateWithChunk(data[offset:min(offset+chunkSize, size)], offset)
+	}
+	want, err := gen.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := predict(t, "random:seed=1", size, Options{ChunkSize: chunkSize, Mode: checksum.ModeTree, Workers: 3})
+	if result.Algorithm != "SHA256-TREE" || result.Checksum != want {
+		t.Errorf("got %s %s, want SHA256-TREE %s", result.Algorithm, result.Checksum, want)
+	}
+}
+
+func TestPredictRejectsUnreproduciblePatterns(t *testing.T) {
+	for _, pattern := range []string{"random", "mixed", "random:0.5,zero:0.5", "exec", "nonexistent"} {
+		if _, err := New(pattern, 1024, Options{}); err == nil {
+			t.Errorf("expected an error for pattern %s", pattern)
+		}
+	}
+}
+
+func TestPredictEmpty(t *testing.T) {
+	result := predict(t, "zero", 0, Options{})
+	want := sha256.Sum256(nil)
+	if result.Checksum != hex.EncodeToString(want[:]) || len(result.Chunks) != 0 {
+		t.Errorf("unexpected prediction for an empty file: %+v", result)
+	}
+}
+
+func TestPredictCancelled(t *testing.T) {
+	p, err := New("random:seed=1", 64*1024*1024, Options{ChunkSize: 1024 * 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Run(ctx); err == nil {
+		t.Error("expected an error from a cancelled prediction")
+	}
+}
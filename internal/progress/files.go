@@ -0,0 +1,71 @@
+package progress
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// FileStatus is the progress of one of the files of a run that writes
+// several.
+type FileStatus struct {
+	Name    string
+	Size    int64
+	Written int64
+}
+
+// Complete reports whether the whole file has been written.
+func (f FileStatus) Complete() bool {
+	return f.Written >= f.Size
+}
+
+// SetFiles makes the progress line of a run that writes several files show,
+// besides the bytes written and the ETA of the whole run, how many files
+// are complete, how many are being written and the first one not yet
+// complete, as reported by files. In verbose mode a line is also printed as
+// each file completes. It must be called before Start.
+func (p *ProgressReporter) SetFiles(files func() []FileStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.files = files
+}
+
+// fileProgress describes the files for the progress line, and returns the
+// names of the files completed since it was last called. It must be called
+// with p.mu held.
+func (p *ProgressReporter) fileProgress() (string, []string) {
+	files := p.files()
+	if p.completed == nil {
+		p.completed = make([]bool, len(files))
+	}
+
+	var done, writing int
+	var current *FileStatus
+	var completed []string
+	for i := range files {
+		if !files[i].Complete() {
+			if current == nil {
+				current = &files[i]
+			}
+			if files[i].Written > 0 {
+				writing++
+			}
+			continue
+		}
+		done++
+		if i < len(p.completed) && !p.completed[i] {
+			p.completed[i] = true
+			completed = append(completed, files[i].Name)
+		}
+	}
+
+	detail := fmt.Sprintf("%d/%d files", done, len(files))
+	// Batch files are written side by side
+	if writing > 1 {
+		detail += fmt.Sprintf(", %d in progress", writing)
+	}
+	if current != nil {
+		percent := float64(current.Written) / float64(current.Size) * 100
+		detail += fmt.Sprintf(" | %s %.0f%%", filepath.Base(current.Name), percent)
+	}
+	return detail, completed
+}
@@ -0,0 +1,84 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileProgress(t *testing.T) {
+	var buf bytes.Buffer
+	pr := NewProgressReporter(300, true, &buf)
+	files := []FileStatus{
+		{Name: "/data/a.bin", Size: 100, Written: 100},
+		{Name: "/data/b.bin", Size: 100, Written: 40},
+		{Name: "/data/c.bin", Size: 100, Written: 100},
+		{Name: "/data/d.bin", Size: 100, Written: 10},
+		{Name: "/data/e.bin", Size: 100},
+	}
+	pr.SetFiles(func() []FileStatus {
+		return files
+	})
+
+	pr.mu.Lock()
+	detail, completed := pr.fileProgress()
+	pr.mu.Unlock()
+	if detail != "2/5 files, 2 in progress | b.bin 40%" {
+		t.Errorf("unexpected detail %q", detail)
+	}
+	if strings.Join(completed, ",") != "/data/a.bin,/data/c.bin" {
+		t.Errorf("expected a.bin and c.bin to complete, got %v", completed)
+	}
+
+	// Files are only reported complete once
+	files[1].Written = 100
+	pr.mu.Lock()
+	detail, completed = pr.fileProgress()
+	pr.mu.Unlock()
+	if detail != "3/5 files | d.bin 10%" || len(completed) != 1 || completed[0] != "/data/b.bin" {
+		t.Errorf("unexpected detail %q and completed files %v", detail, completed)
+	}
+}
+
+func TestProgressReporterFiles(t *testing.T) {
+	var mu sync.Mutex
+	var buf bytes.Buffer
+	pr := NewProgressReporter(200, true, writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return buf.Write(p)
+	}))
+	written := []int64{100, 50}
+	pr.SetFiles(func() []FileStatus {
+		mu.Lock()
+		defer mu.Unlock()
+		return []FileStatus{
+			{Name: "one.bin", Size: 100, Written: written[0]},
+			{Name: "two.bin", Size: 100, Written: written[1]},
+		}
+	})
+	pr.Start(func() int64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return written[0] + written[1]
+	})
+	time.Sleep(250 * time.Millisecond)
+	pr.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	output := buf.String()
+	for _, want := range []string{"Completed one.bin\n", "1/2 files | two.bin 50%"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output %q", want, output)
+		}
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
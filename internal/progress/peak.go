@@ -0,0 +1,84 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultPeakInterval is the window PeakMeter measures throughput over.
+const DefaultPeakInterval = time.Second
+
+// PeakMeter samples a byte counter once per interval and keeps the highest
+// rate over any one interval, so a run can report its peak throughput next
+// to its average.
+type PeakMeter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	peak     float64
+	done     chan struct{}
+	finished chan struct{}
+	running  bool
+}
+
+// NewPeakMeter creates a meter measuring throughput over windows of
+// interval.
+func NewPeakMeter(interval time.Duration) *PeakMeter {
+	return &PeakMeter{
+		interval: interval,
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+}
+
+// Start begins sampling getWritten, which returns the bytes written so far.
+func (m *PeakMeter) Start(getWritten func() int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running {
+		return
+	}
+	m.running = true
+
+	go func() {
+		defer close(m.finished)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		last, lastTime := getWritten(), time.Now()
+		for {
+			select {
+			case <-m.done:
+				return
+			case now := <-ticker.C:
+				written := getWritten()
+				rate := float64(written-last) / now.Sub(lastTime).Seconds()
+				m.mu.Lock()
+				m.peak = max(m.peak, rate)
+				m.mu.Unlock()
+				last, lastTime = written, now
+			}
+		}
+	}()
+}
+
+// Stop ends sampling. It returns once the sampler has exited.
+func (m *PeakMeter) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = false
+	close(m.done)
+	m.mu.Unlock()
+	<-m.finished
+}
+
+// Peak returns the highest throughput over one interval so far, in bytes
+// per second. A run shorter than one interval has no peak and returns 0;
+// callers report the average instead.
+func (m *PeakMeter) Peak() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.peak
+}
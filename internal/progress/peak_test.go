@@ -0,0 +1,45 @@
+package progress
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPeakMeter(t *testing.T) {
+	var written atomic.Int64
+	m := NewPeakMeter(20 * time.Millisecond)
+	m.Start(written.Load)
+
+	// A burst in one window sets the peak, idle windows don't lower it
+	time.Sleep(30 * time.Millisecond)
+	written.Add(10 * 1024 * 1024)
+	time.Sleep(100 * time.Millisecond)
+	m.Stop()
+
+	peak := m.Peak()
+	if peak <= 0 {
+		t.Fatal("expected a peak after a burst")
+	}
+	// The burst fell in one window of at least 20ms
+	if peak > float64(10*1024*1024)/0.015 {
+		t.Errorf("peak %.0f B/s too high for the burst", peak)
+	}
+
+	// Stop is idempotent and Peak stays readable
+	m.Stop()
+	if m.Peak() != peak {
+		t.Error("peak changed after Stop")
+	}
+}
+
+func TestPeakMeterShortRun(t *testing.T) {
+	var written atomic.Int64
+	m := NewPeakMeter(time.Hour)
+	m.Start(written.Load)
+	written.Add(1024)
+	m.Stop()
+	if m.Peak() != 0 {
+		t.Errorf("expected no peak for a run shorter than the interval, got %f", m.Peak())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // ProgressReporter provides real-time progress reporting for file generation operations.
@@ -16,10 +17,21 @@ type ProgressReporter struct {
 	lastWritten   int64
 	verbose       bool
 	done          chan struct{}
+	finished      chan struct{}
 	writer        io.Writer
 	mu            sync.Mutex
 	running       bool
 	showProgress  bool
+	// files reports the files of a multi-file run (see SetFiles), and
+	// completed which of them have been reported complete
+	files         func() []FileStatus
+	completed     []bool
+	// lineLen is the length of the last progress line, to clear it
+	lineLen       int
+	// report is printed every reportEvery (see SetReport)
+	report        func() string
+	reportEvery   time.Duration
+	lastReport    time.Time
 }
 
 // NewProgressReporter creates a new progress reporter.
@@ -33,11 +45,22 @@ func NewProgressReporter(totalSize int64, verbose bool, writer io.Writer) *Progr
 		totalSize:    totalSize,
 		verbose:      verbose,
 		done:         make(chan struct{}),
+		finished:     make(chan struct{}),
 		writer:       writer,
 		showProgress: totalSize >= (1<<30) || verbose, // Show for files >= 1GB or verbose mode
 	}
 }
 
+// SetReport makes the reporter print the text report returns above the
+// progress line every interval, such as a table of per-worker statistics.
+// It must be called before Start.
+func (p *ProgressReporter) SetReport(interval time.Duration, report func() string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report = report
+	p.reportEvery = interval
+}
+
 // Start begins progress reporting in a separate goroutine.
 // getWrittenFunc should return the current number of bytes written.
 func (p *ProgressReporter) Start(getWrittenFunc func() int64) {
@@ -51,6 +74,7 @@ func (p *ProgressReporter) Start(getWrittenFunc func() int64) {
 	p.running = true
 	p.startTime = time.Now()
 	p.lastUpdate = p.startTime
+	p.lastReport = p.startTime
 
 	if !p.showProgress {
 		return
@@ -61,6 +85,8 @@ func (p *ProgressReporter) Start(getWrittenFunc func() int64) {
 
 // progressLoop runs the progress reporting loop.
 func (p *ProgressReporter) progressLoop(getWrittenFunc func() int64) {
+	defer close(p.finished)
+
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 
@@ -110,21 +136,40 @@ func (p *ProgressReporter) update(written int64) {
 	}
 
 	// Format output
-	p.printProgress(percent, throughput, eta, elapsed, written)
+	var detail string
+	if p.files != nil {
+		var completed []string
+		detail, completed = p.fileProgress()
+		if p.verbose {
+			for _, name := range completed {
+				p.printLine(fmt.Sprintf("Completed %s", name))
+				fmt.Fprintln(p.writer)
+				p.lineLen = 0
+			}
+		}
+	}
+	if p.report != nil && now.Sub(p.lastReport) >= p.reportEvery {
+		p.printLine("")
+		fmt.Fprintf(p.writer, "\r%s", p.report())
+		p.lineLen = 0
+		p.lastReport = now
+	}
+	p.printProgress(percent, throughput, eta, elapsed, written, detail)
 
 	// Update last values
 	p.lastUpdate = now
 	p.lastWritten = written
 }
 
-// printProgress displays the current progress.
-func (p *ProgressReporter) printProgress(percent float64, throughput float64, eta, elapsed time.Duration, written int64) {
+// printProgress displays the current progress, followed by detail if set.
+func (p *ProgressReporter) printProgress(percent float64, throughput float64, eta, elapsed time.Duration, written int64, detail string) {
 	// Format throughput
 	throughputStr := formatThroughput(throughput)
 
+	var line string
 	if p.verbose {
 		// Verbose mode: show detailed information
-		fmt.Fprintf(p.writer, "\r%s | %.2f%% | %s | ETA: %s | Elapsed: %s | Written: %s / %s",
+		line = fmt.Sprintf("%s | %.2f%% | %s | ETA: %s | Elapsed: %s | Written: %s / %s",
 			p.formatProgressBar(percent, 30),
 			percent,
 			throughputStr,
@@ -132,14 +177,35 @@ func (p *ProgressReporter) printProgress(percent float64, throughput float64, et
 			formatDuration(elapsed),
 			formatBytes(written),
 			formatBytes(p.totalSize))
+	} else if detail != "" {
+		// A multi-file run shows the total it has to write
+		line = fmt.Sprintf("%s %.2f%% | %s / %s | %s | ETA: %s",
+			p.formatProgressBar(percent, 40),
+			percent,
+			formatBytes(written),
+			formatBytes(p.totalSize),
+			throughputStr,
+			formatDuration(eta))
 	} else {
 		// Standard mode: show compact progress
-		fmt.Fprintf(p.writer, "\r%s %.2f%% | %s | ETA: %s",
+		line = fmt.Sprintf("%s %.2f%% | %s | ETA: %s",
 			p.formatProgressBar(percent, 40),
 			percent,
 			throughputStr,
 			formatDuration(eta))
 	}
+	if detail != "" {
+		line += " | " + detail
+	}
+	p.printLine(line)
+}
+
+// printLine overwrites the progress line with line, padding it to clear
+// what is left of a longer line before it.
+func (p *ProgressReporter) printLine(line string) {
+	width := utf8.RuneCountInString(line)
+	fmt.Fprintf(p.writer, "\r%s%s", line, strings.Repeat(" ", max(p.lineLen-width, 0)))
+	p.lineLen = width
 }
 
 // formatProgressBar creates a visual progress bar.
@@ -162,11 +228,12 @@ func (p *ProgressReporter) formatProgressBar(percent float64, width int) string
 }
 
 // Stop stops the progress reporting and prints final statistics.
+// It returns once the final statistics have been printed.
 func (p *ProgressReporter) Stop() {
 	p.mu.Lock()
-	defer p.mu.Unlock()
 
 	if !p.running {
+		p.mu.Unlock()
 		return
 	}
 
@@ -177,6 +244,11 @@ func (p *ProgressReporter) Stop() {
 	default:
 		close(p.done)
 	}
+	p.mu.Unlock()
+
+	if p.showProgress {
+		<-p.finished
+	}
 }
 
 // printFinalStats prints the final completion statistics.
@@ -194,7 +266,7 @@ func (p *ProgressReporter) printFinalStats(written int64) {
 	throughputStr := formatThroughput(avgThroughput)
 
 	// Clear the progress line and print final stats
-	fmt.Fprintf(p.writer, "\r%s\n", strings.Repeat(" ", 80)) // Clear line
+	fmt.Fprintf(p.writer, "\r%s\n", strings.Repeat(" ", max(p.lineLen, 80))) // Clear line
 	fmt.Fprintf(p.writer, "Completed %s in %s (average %s)\n",
 		formatBytes(written),
 		formatDuration(elapsed),
@@ -283,4 +355,19 @@ func (p *ProgressReporter) IsRunning() bool {
 // ShouldShowProgress returns whether progress should be displayed.
 func (p *ProgressReporter) ShouldShowProgress() bool {
 	return p.showProgress
-}
\ No newline at end of file
+}
+
+// FormatBytes formats a byte count in human-readable format.
+func FormatBytes(bytes int64) string {
+	return formatBytes(bytes)
+}
+
+// FormatThroughput formats a bytes-per-second rate in appropriate units.
+func FormatThroughput(bytesPerSecond float64) string {
+	return formatThroughput(bytesPerSecond)
+}
+
+// FormatDuration formats a duration in human-readable format.
+func FormatDuration(d time.Duration) string {
+	return formatDuration(d)
+}
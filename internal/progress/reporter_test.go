@@ -354,4 +354,23 @@ func BenchmarkFormatProgressBar(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		pr.formatProgressBar(45.5, 40)
 	}
-}
\ No newline at end of file
+}
+func TestProgressReporterReport(t *testing.T) {
+	var buf bytes.Buffer
+	pr := NewProgressReporter(1000, true, &buf)
+	var reports atomic.Int32
+	pr.SetReport(100*time.Millisecond, func() string {
+		reports.Add(1)
+		return "worker table\n"
+	})
+	pr.Start(func() int64 { return 500 })
+	time.Sleep(450 * time.Millisecond)
+	pr.Stop()
+
+	if n := reports.Load(); n < 2 || n > 4 {
+		t.Errorf("expected a report about every 100ms, got %d in 450ms", n)
+	}
+	if !strings.Contains(buf.String(), "worker table\n") {
+		t.Errorf("expected the report in the output, got %q", buf.String())
+	}
+}
@@ -0,0 +1,110 @@
+package repair
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+// Result summarizes a repair run.
+type Result struct {
+	Checked      int
+	Corrupted    []checksum.ChunkInfo
+	Repaired     []checksum.ChunkInfo
+	Unrepairable []checksum.ChunkInfo
+}
+
+// Clean returns true if no corrupted chunks were found.
+func (r *Result) Clean() bool {
+	return len(r.Corrupted) == 0
+}
+
+// Repair verifies every chunk of the file at path against the chunk checksums
+// in its checksum file and regenerates corrupted chunks in place using gen.
+// A regenerated chunk is only written back if it matches the recorded
+// checksum, so using the wrong pattern never makes a file worse.
+// With dryRun set, corrupted chunks are reported but not rewritten.
+func Repair(path string, gen generator.OffsetGenerator, dryRun bool) (*Result, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access %s: %v", path, err)
+	}
+
+	chunks, err := checksum.LoadChunkChecksums(path+".checksum.txt", info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chunk checksums: %v", err)
+	}
+	return RepairChunks(path, chunks, gen, dryRun)
+}
+
+// RepairChunks repairs the chunks of the file at path as Repair does, with
+// the chunks and their checksums given, for example from a manifest.
+func RepairChunks(path string, chunks []checksum.ChunkInfo, gen generator.OffsetGenerator, dryRun bool) (*Result, error) {
+	flag := os.O_RDWR
+	if dryRun {
+		flag = os.O_RDONLY
+	}
+	file, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var maxChunk int64
+	for _, chunk := range chunks {
+		if chunk.Size > maxChunk {
+			maxChunk = chunk.Size
+		}
+	}
+	buffer := make([]byte, maxChunk)
+
+	result := &Result{}
+	for _, chunk := range chunks {
+		data := buffer[:chunk.Size]
+		result.Checked++
+
+		if _, err := file.ReadAt(data, chunk.Offset); err != nil && err != io.EOF {
+			return result, fmt.Errorf("failed to read chunk at offset %d: %v", chunk.Offset, err)
+		}
+		if digest(data) == chunk.Checksum {
+			continue
+		}
+		result.Corrupted = append(result.Corrupted, chunk)
+
+		if dryRun {
+			continue
+		}
+
+		if err := gen.GenerateAt(data, chunk.Offset); err != nil {
+			return result, fmt.Errorf("failed to regenerate chunk at offset %d: %v", chunk.Offset, err)
+		}
+		if digest(data) != chunk.Checksum {
+			result.Unrepairable = append(result.Unrepairable, chunk)
+			continue
+		}
+
+		if _, err := file.WriteAt(data, chunk.Offset); err != nil {
+			return result, fmt.Errorf("failed to write chunk at offset %d: %v", chunk.Offset, err)
+		}
+		result.Repaired = append(result.Repaired, chunk)
+	}
+
+	if len(result.Repaired) > 0 {
+		if err := file.Sync(); err != nil {
+			return result, fmt.Errorf("failed to sync repaired file: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// digest returns the hex-encoded SHA-256 of data.
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,179 @@
+package repair
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+// writeFixture creates a zero-filled file with chunk checksums and a chunk
+// manifest.
+func writeFixture(t *testing.T, size, chunkSize int) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "fixture.bin")
+	data := make([]byte, size)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	gen := checksum.NewChecksumGenerator(path, int64(size))
+	for offset := 0; offset < size; offset += chunkSize {
+		end := min(offset+chunkSize, size)
+		gen.UpdateWithChunk(data[offset:end], int64(offset))
+	}
+	if err := gen.WriteChecksumFile(); err != nil {
+		t.Fatalf("failed to write checksum file: %v", err)
+	}
+	if err := gen.WriteManifest(int64(size)); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	return path
+}
+
+// corrupt overwrites bytes of the file at offset.
+func corrupt(t *testing.T, path string, offset int64, data []byte) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("failed to open fixture: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteAt(data, offset); err != nil {
+		t.Fatalf("failed to corrupt fixture: %v", err)
+	}
+}
+
+func TestRepairCleanFile(t *testing.T) {
+	path := writeFixture(t, 4096, 1024)
+
+	result, err := Repair(path, &generator.ZeroGenerator{}, false)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if result.Checked != 4 {
+		t.Errorf("expected 4 chunks checked, got %d", result.Checked)
+	}
+	if !result.Clean() {
+		t.Errorf("expected clean file, got %d corrupted chunks", len(result.Corrupted))
+	}
+}
+
+func TestRepairCorruptedChunks(t *testing.T) {
+	path := writeFixture(t, 4000, 1024)
+	corrupt(t, path, 100, []byte{0xFF, 0xFF})
+	corrupt(t, path, 3500, []byte{0x01})
+
+	result, err := Repair(path, &generator.ZeroGenerator{}, false)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if len(result.Corrupted) != 2 {
+		t.Fatalf("expected 2 corrupted chunks, got %d", len(result.Corrupted))
+	}
+	if len(result.Repaired) != 2 {
+		t.Errorf("expected 2 repaired chunks, got %d", len(result.Repaired))
+	}
+	if result.Repaired[1].Offset != 3072 || result.Repaired[1].Size != 928 {
+		t.Errorf("unexpected last repaired chunk: %+v", result.Repaired[1])
+	}
+
+	data, _ := os.ReadFile(path)
+	if !bytes.Equal(data, make([]byte, 4000)) {
+		t.Error("file was not restored to zeros")
+	}
+
+	verification, err := checksum.VerifyFile(path)
+	if err != nil || !verification.Valid {
+		t.Errorf("expected repaired file to verify, got %v", verification)
+	}
+}
+
+func TestRepairChunksFromManifest(t *testing.T) {
+	path := writeFixture(t, 4000, 1024)
+	corrupt(t, path, 2000, []byte{0xFF})
+
+	chunks, err := checksum.LoadManifest(path+checksum.ManifestSuffix, 4000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := RepairChunks(path, chunks, &generator.ZeroGenerator{}, false)
+	if err != nil {
+		t.Fatalf("RepairChunks failed: %v", err)
+	}
+	if len(result.Repaired) != 1 || result.Repaired[0].Offset != 1024 {
+		t.Errorf("expected chunk at 1024 to be repaired, got %+v", result.Repaired)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !bytes.Equal(data, make([]byte, 4000)) {
+		t.Error("file was not restored to zeros")
+	}
+}
+
+func TestRepairDryRun(t *testing.T) {
+	path := writeFixture(t, 2048, 1024)
+	corrupt(t, path, 10, []byte{0xAA})
+
+	result, err := Repair(path, &generator.ZeroGenerator{}, true)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if len(result.Corrupted) != 1 || len(result.Repaired) != 0 {
+		t.Errorf("expected 1 corrupted and 0 repaired chunks, got %d/%d",
+			len(result.Corrupted), len(result.Repaired))
+	}
+
+	data, _ := os.ReadFile(path)
+	if data[10] != 0xAA {
+		t.Error("dry run must not modify the file")
+	}
+}
+
+// onesGenerator produces data that never matches a zero-filled fixture.
+type onesGenerator struct{}
+
+func (g *onesGenerator) Name() string { return "ones" }
+
+func (g *onesGenerator) Generate(buffer []byte) error {
+	for i := range buffer {
+		buffer[i] = 1
+	}
+	return nil
+}
+
+func (g *onesGenerator) GenerateAt(buffer []byte, offset int64) error {
+	return g.Generate(buffer)
+}
+
+func TestRepairWrongPattern(t *testing.T) {
+	path := writeFixture(t, 2048, 1024)
+	corrupt(t, path, 10, []byte{0xAA})
+
+	result, err := Repair(path, &onesGenerator{}, false)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if len(result.Unrepairable) != 1 {
+		t.Errorf("expected 1 unrepairable chunk, got %d", len(result.Unrepairable))
+	}
+
+	data, _ := os.ReadFile(path)
+	if data[10] != 0xAA || data[11] != 0 {
+		t.Error("unrepairable chunk must not be overwritten")
+	}
+}
+
+func TestRepairMissingChecksumFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plain.bin")
+	os.WriteFile(path, make([]byte, 100), 0644)
+
+	if _, err := Repair(path, &generator.ZeroGenerator{}, false); err == nil {
+		t.Error("expected error when checksum file is missing")
+	}
+}
@@ -0,0 +1,101 @@
+package report
+
+import "fmt"
+
+// Verdict classifies the change of a metric between two runs.
+type Verdict string
+
+const (
+	Improved  Verdict = "improved"
+	Regressed Verdict = "regressed"
+	Unchanged Verdict = "unchanged"
+)
+
+// MetricDelta describes how a single metric changed between two runs.
+type MetricDelta struct {
+	Name           string
+	Old            float64
+	New            float64
+	ChangePercent  float64
+	HigherIsBetter bool
+	Verdict        Verdict
+}
+
+// Comparison is the result of comparing two summaries.
+type Comparison struct {
+	Metrics []MetricDelta
+	// Warnings lists configuration differences that make the comparison
+	// less meaningful, such as different sizes or patterns.
+	Warnings []string
+}
+
+// Regressions returns the metrics that got worse beyond the threshold.
+func (c *Comparison) Regressions() []MetricDelta {
+	var regressions []MetricDelta
+	for _, m := range c.Metrics {
+		if m.Verdict == Regressed {
+			regressions = append(regressions, m)
+		}
+	}
+	return regressions
+}
+
+// Compare compares a baseline summary against a new one. Changes smaller than
+// thresholdPercent in either direction are reported as unchanged.
+func Compare(baseline, current *Summary, thresholdPercent float64) *Comparison {
+	c := &Comparison{}
+
+	if baseline.SizeBytes != current.SizeBytes {
+		c.Warnings = append(c.Warnings, fmt.Sprintf("size differs: %d vs %d bytes", baseline.SizeBytes, current.SizeBytes))
+	}
+	if baseline.Pattern != current.Pattern {
+		c.Warnings = append(c.Warnings, fmt.Sprintf("pattern differs: %s vs %s", baseline.Pattern, current.Pattern))
+	}
+	if baseline.Workers != current.Workers {
+		c.Warnings = append(c.Warnings, fmt.Sprintf("workers differ: %d vs %d", baseline.Workers, current.Workers))
+	}
+	if baseline.ChunkSize != current.ChunkSize {
+		c.Warnings = append(c.Warnings, fmt.Sprintf("chunk size differs: %d vs %d bytes", baseline.ChunkSize, current.ChunkSize))
+	}
+	if baseline.Status != "completed" || current.Status != "completed" {
+		c.Warnings = append(c.Warnings, fmt.Sprintf("run status: %s vs %s", baseline.Status, current.Status))
+	}
+
+	c.Metrics = append(c.Metrics,
+		compareMetric("throughput", baseline.Throughput, current.Throughput, true, thresholdPercent),
+		compareMetric("duration", baseline.DurationSeconds, current.DurationSeconds, false, thresholdPercent),
+	)
+
+	return c
+}
+
+// compareMetric computes the delta and verdict for a single metric.
+func compareMetric(name string, baseline, current float64, higherIsBetter bool, thresholdPercent float64) MetricDelta {
+	m := MetricDelta{
+		Name:           name,
+		Old:            baseline,
+		New:            current,
+		HigherIsBetter: higherIsBetter,
+		Verdict:        Unchanged,
+	}
+
+	if baseline == 0 {
+		return m
+	}
+
+	m.ChangePercent = (current - baseline) / baseline * 100
+	if m.ChangePercent > -thresholdPercent && m.ChangePercent < thresholdPercent {
+		return m
+	}
+
+	better := m.ChangePercent > 0
+	if !higherIsBetter {
+		better = !better
+	}
+	if better {
+		m.Verdict = Improved
+	} else {
+		m.Verdict = Regressed
+	}
+	return m
+}
@@ -0,0 +1,78 @@
+package report
+
+import "testing"
+
+func summary(throughput, duration float64) *Summary {
+	return &Summary{
+		SchemaVersion:   SchemaVersion,
+		Status:          "completed",
+		Pattern:         "random",
+		SizeBytes:       1 << 30,
+		Workers:         4,
+		ChunkSize:       64 << 20,
+		Throughput:      throughput,
+		DurationSeconds: duration,
+	}
+}
+
+func TestCompareVerdicts(t *testing.T) {
+	tests := []struct {
+		name       string
+		old, new   *Summary
+		throughput Verdict
+		duration   Verdict
+	}{
+		{"unchanged", summary(1000, 10), summary(1020, 9.9), Unchanged, Unchanged},
+		{"improved", summary(1000, 10), summary(1500, 6), Improved, Improved},
+		{"regressed", summary(1000, 10), summary(800, 12.5), Regressed, Regressed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := Compare(test.old, test.new, 5)
+
+			if c.Metrics[0].Name != "throughput" || c.Metrics[0].Verdict != test.throughput {
+				t.Errorf("throughput: expected %s, got %+v", test.throughput, c.Metrics[0])
+			}
+			if c.Metrics[1].Name != "duration" || c.Metrics[1].Verdict != test.duration {
+				t.Errorf("duration: expected %s, got %+v", test.duration, c.Metrics[1])
+			}
+			if len(c.Warnings) != 0 {
+				t.Errorf("expected no warnings, got %v", c.Warnings)
+			}
+		})
+	}
+}
+
+func TestCompareRegressions(t *testing.T) {
+	c := Compare(summary(1000, 10), summary(500, 20), 5)
+
+	regressions := c.Regressions()
+	if len(regressions) != 2 {
+		t.Fatalf("expected 2 regressions, got %d", len(regressions))
+	}
+	if regressions[0].ChangePercent != -50 {
+		t.Errorf("expected -50%% throughput change, got %f", regressions[0].ChangePercent)
+	}
+}
+
+func TestCompareWarnsOnConfigDifferences(t *testing.T) {
+	old := summary(1000, 10)
+	new := summary(1000, 10)
+	new.Pattern = "zero"
+	new.Workers = 8
+
+	c := Compare(old, new, 5)
+	if len(c.Warnings) != 2 {
+		t.Errorf("expected 2 warnings, got %v", c.Warnings)
+	}
+}
+
+func TestCompareZeroBaseline(t *testing.T) {
+	c := Compare(summary(0, 0), summary(1000, 10), 5)
+	for _, m := range c.Metrics {
+		if m.Verdict != Unchanged {
+			t.Errorf("expected unchanged verdict with zero baseline, got %+v", m)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// HistoryEntry is one line of the run history file: a run summary plus the
+// host and device it ran against.
+type HistoryEntry struct {
+	Summary
+	Host string `json:"host"`
+	// Device identifies the storage that was written, such as the mount
+	// point holding the output or the block device itself.
+	Device string `json:"device"`
+}
+
+// DefaultHistoryPath returns the per-user history file location.
+func DefaultHistoryPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %v", err)
+	}
+	return filepath.Join(dir, "trasher", "history.jsonl"), nil
+}
+
+// AppendHistory appends an entry to the JSONL history file at path, creating
+// the file and its directory if needed.
+func AppendHistory(path string, entry *HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %v", err)
+	}
+	defer file.Close()
+
+	// A single write keeps lines intact when several runs finish at once
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history file: %v", err)
+	}
+	return nil
+}
+
+// LoadHistory reads all entries from the history file at path. Lines that
+// can't be parsed or use a newer schema are skipped, so one bad line doesn't
+// hide the rest of the history.
+func LoadHistory(path string) ([]HistoryEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if entry.SchemaVersion == 0 || entry.SchemaVersion > SchemaVersion {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+	return entries, nil
+}
+
+// DeviceTrend summarizes the completed runs against one device.
+type DeviceTrend struct {
+	Host   string
+	Device string
+	// Runs are the completed runs in chronological order.
+	Runs    []HistoryEntry
+	Average float64
+	Best    float64
+	Latest  float64
+}
+
+// LatestChangePercent returns how the latest throughput compares with the
+// average over all runs, in percent.
+func (t *DeviceTrend) LatestChangePercent() float64 {
+	if t.Average == 0 {
+		return 0
+	}
+	return (t.Latest - t.Average) / t.Average * 100
+}
+
+// Trends groups completed runs by host and device and computes throughput
+// statistics for each group. Failed and cancelled runs are ignored since
+// their throughput isn't representative.
+func Trends(entries []HistoryEntry) []DeviceTrend {
+	type key struct{ host, device string }
+	groups := make(map[key][]HistoryEntry)
+	for _, entry := range entries {
+		if entry.Status != "completed" {
+			continue
+		}
+		k := key{entry.Host, entry.Device}
+		groups[k] = append(groups[k], entry)
+	}
+
+	trends := make([]DeviceTrend, 0, len(groups))
+	for k, runs := range groups {
+		sort.SliceStable(runs, func(i, j int) bool {
+			return runs[i].StartedAt.Before(runs[j].StartedAt)
+		})
+
+		t := DeviceTrend{Host: k.host, Device: k.device, Runs: runs}
+		var total float64
+		for _, run := range runs {
+			total += run.Throughput
+			t.Best = max(t.Best, run.Throughput)
+		}
+		t.Average = total / float64(len(runs))
+		t.Latest = runs[len(runs)-1].Throughput
+		trends = append(trends, t)
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Host != trends[j].Host {
+			return trends[i].Host < trends[j].Host
+		}
+		return trends[i].Device < trends[j].Device
+	})
+	return trends
+}
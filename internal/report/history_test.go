@@ -0,0 +1,107 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// entry builds a completed history entry.
+func entry(device string, throughput float64, startedAt time.Time) *HistoryEntry {
+	return &HistoryEntry{
+		Summary: Summary{
+			SchemaVersion: SchemaVersion,
+			Status:        "completed",
+			StartedAt:     startedAt,
+			Throughput:    throughput,
+		},
+		Host:   "host1",
+		Device: device,
+	}
+}
+
+func TestAppendAndLoadHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "history.jsonl")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if err := AppendHistory(path, entry("/data", 100, now)); err != nil {
+		t.Fatalf("AppendHistory failed: %v", err)
+	}
+	if err := AppendHistory(path, entry("/backup", 200, now)); err != nil {
+		t.Fatalf("AppendHistory failed: %v", err)
+	}
+
+	entries, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Device != "/data" || entries[0].Host != "host1" || entries[0].Throughput != 100 {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if !entries[1].StartedAt.Equal(now) {
+		t.Errorf("expected start time %v, got %v", now, entries[1].StartedAt)
+	}
+}
+
+func TestLoadHistorySkipsBadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	content := `{"schema_version":1,"status":"completed","device":"/data"}
+not json
+{"schema_version":999,"device":"/future"}
+{"device":"/unversioned"}
+`
+	os.WriteFile(path, []byte(content), 0644)
+
+	entries, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Device != "/data" {
+		t.Errorf("expected only the valid entry, got %+v", entries)
+	}
+
+	if _, err := LoadHistory(filepath.Join(t.TempDir(), "missing.jsonl")); err == nil {
+		t.Error("expected error for missing history file")
+	}
+}
+
+func TestTrends(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	failed := entry("/data", 1, base.Add(time.Hour))
+	failed.Status = "failed"
+
+	entries := []HistoryEntry{
+		*entry("/data", 300, base.Add(48*time.Hour)),
+		*entry("/data", 500, base),
+		*failed,
+		*entry("/data", 400, base.Add(24*time.Hour)),
+		*entry("/backup", 100, base),
+	}
+
+	trends := Trends(entries)
+	if len(trends) != 2 {
+		t.Fatalf("expected 2 trends, got %d", len(trends))
+	}
+	if trends[0].Device != "/backup" || trends[1].Device != "/data" {
+		t.Errorf("expected trends sorted by device, got %s, %s", trends[0].Device, trends[1].Device)
+	}
+
+	data := trends[1]
+	if len(data.Runs) != 3 {
+		t.Fatalf("expected failed run to be excluded, got %d runs", len(data.Runs))
+	}
+	if data.Runs[0].Throughput != 500 || data.Runs[2].Throughput != 300 {
+		t.Errorf("expected runs in chronological order, got %+v", data.Runs)
+	}
+	if data.Average != 400 || data.Best != 500 || data.Latest != 300 {
+		t.Errorf("unexpected stats: average %f, best %f, latest %f", data.Average, data.Best, data.Latest)
+	}
+	if data.LatestChangePercent() != -25 {
+		t.Errorf("expected latest -25%% vs average, got %f", data.LatestChangePercent())
+	}
+}
@@ -0,0 +1,117 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/latency"
+)
+
+// SchemaVersion is the version of the Summary JSON layout. It is bumped
+// whenever fields change meaning so old and new summaries aren't compared
+// blindly.
+const SchemaVersion = 1
+
+// MetadataSuffix is appended to a generated file's path to name the summary
+// written next to it.
+const MetadataSuffix = ".meta.json"
+
+// Summary is the machine-readable result of a generation run.
+type Summary struct {
+	SchemaVersion   int       `json:"schema_version"`
+	Version         string    `json:"trasher_version"`
+	Status          string    `json:"status"`
+	Error           string    `json:"error,omitempty"`
+	Output          string    `json:"output"`
+	Pattern         string    `json:"pattern"`
+	Seed            *int64    `json:"seed,omitempty"`
+	SizeBytes       int64     `json:"size_bytes"`
+	BytesWritten    int64     `json:"bytes_written"`
+	Workers         int       `json:"workers"`
+	ChunkSize       int64     `json:"chunk_size"`
+	StartedAt       time.Time `json:"started_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Throughput      float64   `json:"throughput_bytes_per_second"`
+	PeakThroughput  float64   `json:"peak_throughput_bytes_per_second,omitempty"`
+	Checksum        string    `json:"checksum,omitempty"`
+	Algorithm       string    `json:"checksum_algorithm,omitempty"`
+	WriteLatency    *Latency  `json:"write_latency,omitempty"`
+}
+
+// Latency is the distribution of a run's write latencies.
+type Latency struct {
+	Writes     uint64  `json:"writes"`
+	P50Seconds float64 `json:"p50_seconds"`
+	P95Seconds float64 `json:"p95_seconds"`
+	P99Seconds float64 `json:"p99_seconds"`
+	MaxSeconds float64 `json:"max_seconds"`
+}
+
+// NewLatency summarizes the latencies recorded in h, or returns nil if none
+// were.
+func NewLatency(h *latency.Histogram) *Latency {
+	if h.Count() == 0 {
+		return nil
+	}
+	return &Latency{
+		Writes:     h.Count(),
+		P50Seconds: h.Quantile(0.50).Seconds(),
+		P95Seconds: h.Quantile(0.95).Seconds(),
+		P99Seconds: h.Quantile(0.99).Seconds(),
+		MaxSeconds: h.Max().Seconds(),
+	}
+}
+
+// Finalize fills in the derived fields from the run's outcome.
+func (s *Summary) Finalize(written int64, elapsed time.Duration, runErr error) {
+	s.SchemaVersion = SchemaVersion
+	s.BytesWritten = written
+	s.DurationSeconds = elapsed.Seconds()
+	if s.DurationSeconds > 0 {
+		s.Throughput = float64(written) / s.DurationSeconds
+	}
+
+	s.Status = "completed"
+	if runErr != nil {
+		s.Status = "failed"
+		s.Error = runErr.Error()
+	}
+}
+
+// Write saves the summary as indented JSON.
+func Write(path string, s *Summary) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write summary: %v", err)
+	}
+	return nil
+}
+
+// Load reads a summary written by Write. Summaries from a newer schema
+// version are rejected since their fields may not mean what we expect.
+func Load(path string) (*Summary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read summary: %v", err)
+	}
+
+	var s Summary
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse summary %s: %v", path, err)
+	}
+
+	if s.SchemaVersion == 0 {
+		return nil, fmt.Errorf("%s has no schema_version; not a trasher summary", path)
+	}
+	if s.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("%s uses schema version %d, newer than supported version %d",
+			path, s.SchemaVersion, SchemaVersion)
+	}
+
+	return &s, nil
+}
@@ -0,0 +1,104 @@
+package report
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/latency"
+)
+
+func TestSummaryFinalize(t *testing.T) {
+	s := &Summary{Output: "out.dat", SizeBytes: 2000}
+	s.Finalize(2000, 2*time.Second, nil)
+
+	if s.SchemaVersion != SchemaVersion {
+		t.Errorf("expected schema version %d, got %d", SchemaVersion, s.SchemaVersion)
+	}
+	if s.Status != "completed" {
+		t.Errorf("expected status completed, got %s", s.Status)
+	}
+	if s.Throughput != 1000 {
+		t.Errorf("expected throughput 1000, got %f", s.Throughput)
+	}
+
+	failed := &Summary{}
+	failed.Finalize(10, time.Second, errors.New("boom"))
+	if failed.Status != "failed" || failed.Error != "boom" {
+		t.Errorf("expected failed status with error, got %s/%s", failed.Status, failed.Error)
+	}
+}
+
+func TestWriteAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+
+	seed := int64(42)
+	s := &Summary{Output: "out.dat", Pattern: "random:seed=42", Seed: &seed, SizeBytes: 1024, Workers: 4}
+	s.Finalize(1024, time.Second, nil)
+
+	if err := Write(path, s); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Output != s.Output || loaded.Throughput != s.Throughput || loaded.Workers != 4 || loaded.Seed == nil || *loaded.Seed != 42 {
+		t.Errorf("loaded summary does not match: %+v", loaded)
+	}
+}
+
+func TestNewLatency(t *testing.T) {
+	h := latency.NewHistogram()
+	if NewLatency(h) != nil {
+		t.Error("expected no latency summary without writes")
+	}
+
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	l := NewLatency(h)
+	if l == nil || l.Writes != 100 {
+		t.Fatalf("expected a summary of 100 writes, got %+v", l)
+	}
+	if l.MaxSeconds != 0.1 {
+		t.Errorf("expected max 0.1s, got %v", l.MaxSeconds)
+	}
+	if l.P50Seconds < 0.05 || l.P50Seconds > 0.051 || l.P99Seconds < 0.099 || l.P99Seconds > 0.1 {
+		t.Errorf("unexpected quantiles: %+v", l)
+	}
+}
+
+func TestLoadRejectsUnknownSchema(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		content     string
+		expectedMsg string
+	}{
+		{"no version", `{"output":"x"}`, "no schema_version"},
+		{"newer version", `{"schema_version":999}`, "newer than supported"},
+		{"invalid json", `not json`, "failed to parse"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, test.name+".json")
+			os.WriteFile(path, []byte(test.content), 0644)
+
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got %v", test.expectedMsg, err)
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
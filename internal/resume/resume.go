@@ -0,0 +1,238 @@
+// Package resume completes a file whose generation was interrupted, from the
+// journal the run left in place of its manifest, writing only the chunks the
+// run didn't get to.
+package resume
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/worker"
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+// Options controls how the missing chunks are written.
+type Options struct {
+	// ChunkSize is the size of the chunks generated. It should be the
+	// interrupted run's, so patterns whose data depends on chunk boundaries
+	// break at the same places. Zero uses the worker pool's default.
+	ChunkSize int64
+	// Workers is the number of parallel generators. Zero uses one per CPU.
+	Workers int
+	// Mode and Format select how the checksum file is written, as the
+	// interrupted run's --checksum-mode and --checksum-format did. Empty
+	// uses the defaults.
+	Mode   string
+	Format string
+}
+
+// Result describes a completed file.
+type Result struct {
+	// Restored is the number of chunks the interrupted run wrote, and
+	// Generated the number written to complete the file.
+	Restored  int
+	Generated int
+	Written   int64
+	Elapsed   time.Duration
+}
+
+// Throughput returns the average write rate in bytes per second.
+func (r *Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Written) / r.Elapsed.Seconds()
+}
+
+// Resumer completes the file described by a journal.
+type Resumer struct {
+	path    string
+	journal *checksum.Manifest
+	done    []checksum.ChunkInfo
+	written atomic.Int64
+}
+
+// Load reads the journal at manifestPath, the <file>.manifest.json an
+// interrupted run with --manifest left, and prepares to complete its file.
+func Load(manifestPath string) (*Resumer, error) {
+	path, ok := strings.CutSuffix(manifestPath, checksum.ManifestSuffix)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("%s is not a manifest, whose name ends in %s", manifestPath, checksum.ManifestSuffix)
+	}
+	journal, done, err := checksum.LoadJournal(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load journal: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access %s: %v", path, err)
+	}
+	if info.Mode().IsRegular() && info.Size() > journal.Size {
+		return nil, fmt.Errorf("%s has %d bytes, more than the %d its journal describes", path, info.Size(), journal.Size)
+	}
+	return &Resumer{path: path, journal: journal, done: done}, nil
+}
+
+// Path returns the path of the file being completed.
+func (r *Resumer) Path() string {
+	return r.path
+}
+
+// Journal returns the journal, whose Flags hold the settings the
+// interrupted run generated its data with.
+func (r *Resumer) Journal() *checksum.Manifest {
+	return r.journal
+}
+
+// Bytes returns the number of bytes missing from the file.
+func (r *Resumer) Bytes() int64 {
+	missing := r.journal.Size
+	for _, chunk := range r.done {
+		missing -= chunk.Size
+	}
+	return missing
+}
+
+// BytesWritten returns the number of bytes written so far. It is safe to
+// call while Run is in progress.
+func (r *Resumer) BytesWritten() int64 {
+	return r.written.Load()
+}
+
+// missing returns the ranges the journal doesn't list, in chunks of at most
+// chunkSize bytes starting where each gap starts.
+func (r *Resumer) missing(chunkSize int64) []worker.Task {
+	var tasks []worker.Task
+	gap := func(start, end int64) {
+		for offset := start; offset < end; offset += chunkSize {
+			tasks = append(tasks, worker.Task{Offset: offset, Size: min(chunkSize, end-offset)})
+		}
+	}
+
+	var end int64
+	for _, chunk := range r.done {
+		gap(end, chunk.Offset)
+		end = chunk.Offset + chunk.Size
+	}
+	gap(end, r.journal.Size)
+	return tasks
+}
+
+// Run generates the missing chunks with generators from newGen and writes
+// them in place, then writes the checksum file and a complete manifest over
+// the journal. The checksums of the chunks the interrupted run wrote are
+// taken from the journal rather than read back. If Run fails or ctx is
+// cancelled, the journal is rewritten to include the chunks written so far,
+// so the file can be resumed again.
+func (r *Resumer) Run(ctx context.Context, newGen generator.Factory, opts Options) (*Result, error) {
+	checksumGen := checksum.NewChecksumGenerator(r.path, r.journal.Size)
+	if opts.Mode != "" {
+		if err := checksumGen.SetMode(opts.Mode); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Format != "" {
+		if err := checksumGen.SetFormat(opts.Format); err != nil {
+			return nil, err
+		}
+	}
+	if err := checksumGen.RestoreChunkChecksums(r.done); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(r.path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", r.path, err)
+	}
+	defer file.Close()
+
+	pool := worker.NewWorkerPool(ctx, opts.Workers, opts.ChunkSize)
+	tasks := r.missing(pool.ChunkSize())
+
+	var (
+		mu        sync.Mutex
+		gens      []generator.Generator
+		generated []checksum.ChunkInfo
+	)
+	generate := func(buffer []byte, task worker.Task) error {
+		mu.Lock()
+		var gen generator.Generator
+		if n := len(gens); n > 0 {
+			gen, gens = gens[n-1], gens[:n-1]
+		}
+		mu.Unlock()
+		if gen == nil {
+			var err error
+			if gen, err = newGen(); err != nil {
+				return fmt.Errorf("failed to create generator: %v", err)
+			}
+		}
+		defer func() {
+			mu.Lock()
+			gens = append(gens, gen)
+			mu.Unlock()
+		}()
+
+		if err := generator.GenerateChunk(gen, buffer, task.Offset); err != nil {
+			return err
+		}
+		chunkHash := checksumGen.NewChunkHash()
+		chunkHash.Write(buffer)
+		sum := chunkHash.Sum(nil)
+
+		if _, err := file.WriteAt(buffer, task.Offset); err != nil {
+			return fmt.Errorf("failed to write %s: %w", r.path, err)
+		}
+		r.written.Add(task.Size)
+		if err := checksumGen.AddChunkChecksum(task.Offset, sum); err != nil {
+			return fmt.Errorf("checksum error: %v", err)
+		}
+
+		mu.Lock()
+		generated = append(generated, checksum.ChunkInfo{Offset: task.Offset, Size: task.Size, Checksum: hex.EncodeToString(sum)})
+		mu.Unlock()
+		return nil
+	}
+
+	start := time.Now()
+	err = pool.Process(tasks, generate)
+	// Closing the generators stops external generator commands
+	for _, gen := range gens {
+		if closer, ok := gen.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	if err != nil {
+		if journalErr := checksum.WriteJournal(r.path, r.journal.Size, slices.Concat(r.done, generated), r.journal.Flags); journalErr != nil {
+			return nil, fmt.Errorf("%w (%v)", err, journalErr)
+		}
+		return nil, err
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close %s: %w", r.path, err)
+	}
+	if err := checksumGen.WriteChecksumFile(); err != nil {
+		return nil, fmt.Errorf("failed to write checksum file: %w", err)
+	}
+	if err := checksumGen.WriteManifest(r.journal.Size); err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Restored:  len(r.done),
+		Generated: len(generated),
+		Written:   r.BytesWritten(),
+		Elapsed:   time.Since(start),
+	}, nil
+}
@@ -0,0 +1,136 @@
+package resume
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+const chunk = 1024
+
+// interrupted writes the chunks at indexes of a sequential file of size
+// bytes, as a run stopped part way would have, with its journal, and
+// returns the file's path and its complete data.
+func interrupted(t *testing.T, size int64, indexes ...int) (string, []byte) {
+	t.Helper()
+	gen, err := generator.NewGenerator("sequential")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]byte, size)
+	if err := generator.GenerateChunk(gen, want, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	partial := make([]byte, size)
+	var chunks []checksum.ChunkInfo
+	for _, i := range indexes {
+		offset := int64(i * chunk)
+		data := want[offset:min(offset+chunk, size)]
+		copy(partial[offset:], data)
+		sum := sha256.Sum256(data)
+		chunks = append(chunks, checksum.ChunkInfo{Offset: offset, Size: int64(len(data)), Checksum: hex.EncodeToString(sum[:])})
+	}
+	if err := os.WriteFile(path, partial, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checksum.WriteJournal(path, size, chunks, map[string]string{"pattern": "sequential"}); err != nil {
+		t.Fatal(err)
+	}
+	return path, want
+}
+
+func TestResumeWritesMissingChunks(t *testing.T) {
+	const size = 10*chunk - 100
+	path, want := interrupted(t, size, 0, 1, 4, 9)
+
+	r, err := Load(path + checksum.ManifestSuffix)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if r.Path() != path || r.Journal().Flags["pattern"] != "sequential" {
+		t.Errorf("unexpected journal for %s: %+v", r.Path(), r.Journal())
+	}
+	if r.Bytes() != 6*chunk {
+		t.Errorf("expected %d bytes missing, got %d", 6*chunk, r.Bytes())
+	}
+
+	result, err := r.Run(context.Background(), generator.NewFactory("sequential", generator.Options{}), Options{ChunkSize: chunk, Workers: 2})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Restored != 4 || result.Generated != 6 || result.Written != 6*chunk {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("resumed file differs from an uninterrupted run")
+	}
+
+	verification, err := checksum.VerifyFile(path)
+	if err != nil {
+		t.Fatalf("VerifyFile failed: %v", err)
+	}
+	if !verification.Valid {
+		t.Errorf("checksum file doesn't match the file: %v", verification)
+	}
+	chunks, err := checksum.LoadManifest(path+checksum.ManifestSuffix, size)
+	if err != nil {
+		t.Fatalf("expected a complete manifest, got %v", err)
+	}
+	if len(chunks) != 10 {
+		t.Errorf("expected 10 chunks, got %d", len(chunks))
+	}
+}
+
+func TestResumeCancelledKeepsJournal(t *testing.T) {
+	path, _ := interrupted(t, 4*chunk, 1)
+
+	r, err := Load(path + checksum.ManifestSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.Run(ctx, generator.NewFactory("sequential", generator.Options{}), Options{ChunkSize: chunk}); err == nil {
+		t.Fatal("expected a cancelled run to fail")
+	}
+
+	// The file can still be resumed
+	if _, _, err := checksum.LoadJournal(path + checksum.ManifestSuffix); err != nil {
+		t.Errorf("expected the journal to be kept, got %v", err)
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	path, _ := interrupted(t, 2*chunk, 0)
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "not a manifest") {
+		t.Errorf("expected an error for a path without the manifest suffix, got %v", err)
+	}
+
+	if err := os.WriteFile(path, make([]byte, 3*chunk), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path + checksum.ManifestSuffix); err == nil || !strings.Contains(err.Error(), "more than") {
+		t.Errorf("expected an error for a file larger than its journal, got %v", err)
+	}
+
+	os.Remove(path)
+	if _, err := Load(path + checksum.ManifestSuffix); err == nil || !strings.Contains(err.Error(), "cannot access") {
+		t.Errorf("expected an error for a missing file, got %v", err)
+	}
+}
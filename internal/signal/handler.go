@@ -22,7 +22,8 @@ type ShutdownHandler struct {
 	cancel       context.CancelFunc
 	sigChan      chan os.Signal
 	cleanupFns   []CleanupFunc
-	writer       *writer.FileWriter
+	drain        func()
+	writer       writer.Writer
 	progress     *progress.ProgressReporter
 	output       io.Writer
 	mu           sync.Mutex
@@ -45,8 +46,8 @@ func NewShutdownHandler(ctx context.Context, output io.Writer) *ShutdownHandler
 	}
 }
 
-// SetWriter sets the file writer for progress reporting during shutdown.
-func (h *ShutdownHandler) SetWriter(writer *writer.FileWriter) {
+// SetWriter sets the output writer for progress reporting during shutdown.
+func (h *ShutdownHandler) SetWriter(writer writer.Writer) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	h.writer = writer
@@ -59,6 +60,15 @@ func (h *ShutdownHandler) SetProgressReporter(progress *progress.ProgressReporte
 	h.progress = progress
 }
 
+// SetDrainFunc makes the first signal call fn instead of shutting down, so
+// the work already in progress can finish; fn should make it wind down. A
+// second signal shuts down as usual.
+func (h *ShutdownHandler) SetDrainFunc(fn func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drain = fn
+}
+
 // RegisterCleanupFunc adds a cleanup function to be called during shutdown.
 // Cleanup functions are called in reverse order (LIFO).
 func (h *ShutdownHandler) RegisterCleanupFunc(fn CleanupFunc) {
@@ -78,13 +88,28 @@ func (h *ShutdownHandler) Start() {
 
 // signalLoop runs in a goroutine and waits for signals or context cancellation.
 func (h *ShutdownHandler) signalLoop() {
-	select {
-	case sig := <-h.sigChan:
-		fmt.Fprintf(h.output, "\nReceived signal %v, shutting down gracefully...\n", sig)
-		h.initiateShutdown()
-	case <-h.ctx.Done():
-		// Context was cancelled elsewhere, perform cleanup
-		h.initiateShutdown()
+	for {
+		select {
+		case sig := <-h.sigChan:
+			// The first signal only drains, if draining was asked for
+			h.mu.Lock()
+			drain := h.drain
+			h.drain = nil
+			h.mu.Unlock()
+			if drain != nil {
+				fmt.Fprintf(h.output, "\nReceived signal %v, finishing work in progress (signal again to abort)...\n", sig)
+				drain()
+				continue
+			}
+
+			fmt.Fprintf(h.output, "\nReceived signal %v, shutting down gracefully...\n", sig)
+			h.initiateShutdown()
+			return
+		case <-h.ctx.Done():
+			// Context was cancelled elsewhere, perform cleanup
+			h.initiateShutdown()
+			return
+		}
 	}
 }
 
@@ -143,8 +168,14 @@ func (h *ShutdownHandler) reportPartialProgress() {
 		fmt.Fprintf(h.output, "Written: %s / %s\n", 
 			formatBytes(written), formatBytes(total))
 		
-		if written > 0 {
-			fmt.Fprintf(h.output, "Partial file saved to: %s\n", h.writer.Path())
+		switch path := h.writer.Path(); {
+		case written == 0:
+		case writer.IsObjectTarget(path):
+			fmt.Fprintf(h.output, "Upload aborted, no object created at: %s\n", path)
+		case writer.IsNetworkTarget(path):
+			fmt.Fprintf(h.output, "Partial data sent to: %s\n", path)
+		default:
+			fmt.Fprintf(h.output, "Partial file saved to: %s\n", path)
 		}
 	} else {
 		fmt.Fprintf(h.output, "Operation interrupted before any data was written\n")
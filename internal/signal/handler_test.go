@@ -8,6 +8,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -271,6 +272,44 @@ func TestSignalHandling(t *testing.T) {
 	// but we can verify the infrastructure is in place
 }
 
+func TestDrainFunc(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewShutdownHandler(context.Background(), &buf)
+
+	var drained atomic.Int32
+	handler.SetDrainFunc(func() {
+		drained.Add(1)
+	})
+	go handler.signalLoop()
+
+	// The first signal drains without shutting down
+	handler.sigChan <- syscall.SIGINT
+	deadline := time.Now().Add(5 * time.Second)
+	for drained.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if drained.Load() != 1 {
+		t.Fatal("expected the first signal to drain")
+	}
+	if handler.IsShutdown() {
+		t.Error("handler should not shut down on the first signal")
+	}
+
+	// The second one shuts down
+	handler.sigChan <- syscall.SIGINT
+	select {
+	case <-handler.Context().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the second signal to shut down")
+	}
+	if drained.Load() != 1 {
+		t.Errorf("expected to drain once, drained %d times", drained.Load())
+	}
+	if !strings.Contains(buf.String(), "finishing work in progress") {
+		t.Errorf("expected the drain to be reported, got %q", buf.String())
+	}
+}
+
 func TestContextCancellation(t *testing.T) {
 	var buf bytes.Buffer
 	parentCtx, parentCancel := context.WithCancel(context.Background())
@@ -0,0 +1,78 @@
+// Package sizedist draws file sizes from a distribution between a minimum
+// and a maximum, so generated datasets have realistic size mixes.
+package sizedist
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+)
+
+// Distributions sizes can be drawn from.
+const (
+	// Uniform makes every size in the range equally likely.
+	Uniform = "uniform"
+	// LogUniform makes every order of magnitude in the range equally
+	// likely, so most files are small.
+	LogUniform = "loguniform"
+	// LogNormal centres sizes on the geometric mean of the range, as file
+	// sizes on real filesystems tend to be, with the range spanning three
+	// standard deviations either side.
+	LogNormal = "lognormal"
+)
+
+// Names lists the distributions.
+var Names = []string{Uniform, LogUniform, LogNormal}
+
+// maxRedraws bounds how often a lognormal size outside the range is drawn
+// again before it is clamped to the range.
+const maxRedraws = 100
+
+// Validate checks a distribution name and range.
+func Validate(dist string, minSize, maxSize int64) error {
+	switch dist {
+	case Uniform, LogUniform, LogNormal:
+	default:
+		return fmt.Errorf("unknown size distribution %s (available: %s)", dist, strings.Join(Names, ", "))
+	}
+	if minSize < 1 {
+		return fmt.Errorf("minimum size must be at least 1 byte, got %d", minSize)
+	}
+	if maxSize < minSize {
+		return fmt.Errorf("maximum size %d is smaller than the minimum size %d", maxSize, minSize)
+	}
+	return nil
+}
+
+// Sample draws n sizes from dist between minSize and maxSize inclusive,
+// using r.
+func Sample(dist string, minSize, maxSize int64, n int, r *rand.Rand) ([]int64, error) {
+	if err := Validate(dist, minSize, maxSize); err != nil {
+		return nil, err
+	}
+
+	lo, hi := math.Log(float64(minSize)), math.Log(float64(maxSize))
+	mu, sigma := (lo+hi)/2, (hi-lo)/6
+	sizes := make([]int64, n)
+	for i := range sizes {
+		switch dist {
+		case Uniform:
+			sizes[i] = minSize + r.Int64N(maxSize-minSize+1)
+		case LogUniform:
+			sizes[i] = clamp(math.Exp(lo+r.Float64()*(hi-lo)), minSize, maxSize)
+		case LogNormal:
+			x := mu + r.NormFloat64()*sigma
+			for redraw := 0; (x < lo || x > hi) && redraw < maxRedraws; redraw++ {
+				x = mu + r.NormFloat64()*sigma
+			}
+			sizes[i] = clamp(math.Exp(x), minSize, maxSize)
+		}
+	}
+	return sizes, nil
+}
+
+// clamp rounds x to the nearest size in the range.
+func clamp(x float64, minSize, maxSize int64) int64 {
+	return min(max(int64(math.Round(x)), minSize), maxSize)
+}
@@ -0,0 +1,76 @@
+package sizedist
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestSampleRange(t *testing.T) {
+	const minSize, maxSize = 4 * 1024, 2 * 1024 * 1024 * 1024
+	for _, dist := range Names {
+		sizes, err := Sample(dist, minSize, maxSize, 5000, rand.New(rand.NewPCG(1, 2)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sizes) != 5000 {
+			t.Fatalf("%s: expected 5000 sizes, got %d", dist, len(sizes))
+		}
+		for _, size := range sizes {
+			if size < minSize || size > maxSize {
+				t.Fatalf("%s: size %d outside the range", dist, size)
+			}
+		}
+	}
+}
+
+func TestSampleShape(t *testing.T) {
+	const minSize, maxSize = 1024, 1024 * 1024 * 1024
+	median := func(dist string) float64 {
+		sizes, err := Sample(dist, minSize, maxSize, 10001, rand.New(rand.NewPCG(3, 4)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(sizes)
+		return float64(sizes[len(sizes)/2])
+	}
+
+	// Uniform sizes centre on the middle of the range, the others on its
+	// geometric mean
+	if m := median(Uniform); math.Abs(m-maxSize/2) > maxSize/20 {
+		t.Errorf("uniform median %.0f, expected about %d", m, maxSize/2)
+	}
+	geoMean := math.Sqrt(minSize * maxSize)
+	for _, dist := range []string{LogUniform, LogNormal} {
+		if m := median(dist); m < geoMean/2 || m > geoMean*2 {
+			t.Errorf("%s median %.0f, expected about %.0f", dist, m, geoMean)
+		}
+	}
+}
+
+func TestSampleEqualBounds(t *testing.T) {
+	for _, dist := range Names {
+		sizes, err := Sample(dist, 4096, 4096, 10, rand.New(rand.NewPCG(5, 6)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, size := range sizes {
+			if size != 4096 {
+				t.Errorf("%s: expected 4096, got %d", dist, size)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("pareto", 1, 2); err == nil {
+		t.Error("expected an error for an unknown distribution")
+	}
+	if err := Validate(Uniform, 0, 2); err == nil {
+		t.Error("expected an error for a zero minimum")
+	}
+	if err := Validate(LogNormal, 10, 5); err == nil {
+		t.Error("expected an error for a maximum below the minimum")
+	}
+}
@@ -0,0 +1,207 @@
+package telemetry
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/latency"
+)
+
+// DefaultMetricsInterval is how often a run's metrics are exported.
+const DefaultMetricsInterval = 10 * time.Second
+
+// aggregationCumulative marks sums that count from the start of the run.
+const aggregationCumulative = 2
+
+// latencyQuantiles are the write latency quantiles exported, by the value
+// of their quantile attribute.
+var latencyQuantiles = []struct {
+	name string
+	q    float64
+}{
+	{"0.5", 0.50},
+	{"0.95", 0.95},
+	{"0.99", 0.99},
+	{"1", 1},
+}
+
+// Meter exports the metrics of a run every interval until stopped: the bytes
+// and writes so far, the throughput since the last export and the write
+// latency quantiles. A nil *Meter exports nothing.
+type Meter struct {
+	exporter *Exporter
+	written  func() int64
+	latency  *latency.Histogram
+	attrs    []Attribute
+	start    time.Time
+
+	lastWritten int64
+	lastTime    time.Time
+	err         error
+
+	done     chan struct{}
+	finished chan struct{}
+}
+
+// StartMeter starts exporting the metrics of a run every interval, with
+// written reporting the bytes written and h recording write latencies. It
+// returns nil if e is nil.
+func (e *Exporter) StartMeter(interval time.Duration, written func() int64, h *latency.Histogram, attrs ...Attribute) *Meter {
+	if e == nil {
+		return nil
+	}
+	now := time.Now()
+	m := &Meter{
+		exporter: e,
+		written:  written,
+		latency:  h,
+		attrs:    attrs,
+		start:    now,
+		lastTime: now,
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	go m.loop(interval)
+	return m
+}
+
+// Stop exports the final metrics and stops the meter. It returns the first
+// error an export failed with.
+func (m *Meter) Stop() error {
+	if m == nil {
+		return nil
+	}
+	close(m.done)
+	<-m.finished
+	return m.err
+}
+
+// loop exports the metrics every interval, and once more when stopped.
+func (m *Meter) loop(interval time.Duration) {
+	defer close(m.finished)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			m.export(time.Now())
+			return
+		case now := <-ticker.C:
+			m.export(now)
+		}
+	}
+}
+
+// export sends the metrics at now, keeping the first error.
+func (m *Meter) export(now time.Time) {
+	err := m.exporter.post(context.Background(), "/v1/metrics", m.encode(now))
+	if err != nil && m.err == nil {
+		m.err = err
+	}
+}
+
+type metricsPayload struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type metric struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+	Sum         *sum   `json:"sum,omitempty"`
+	Gauge       *gauge `json:"gauge,omitempty"`
+}
+
+type sum struct {
+	DataPoints             []dataPoint `json:"dataPoints"`
+	AggregationTemporality int         `json:"aggregationTemporality"`
+	IsMonotonic            bool        `json:"isMonotonic"`
+}
+
+type gauge struct {
+	DataPoints []dataPoint `json:"dataPoints"`
+}
+
+type dataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsInt             *string    `json:"asInt,omitempty"`
+	AsDouble          *float64   `json:"asDouble,omitempty"`
+}
+
+// encode returns the metrics at now as an OTLP metrics request, and starts
+// the next throughput interval.
+func (m *Meter) encode(now time.Time) metricsPayload {
+	written := m.written()
+	var throughput float64
+	if elapsed := now.Sub(m.lastTime).Seconds(); elapsed > 0 {
+		throughput = float64(written-m.lastWritten) / elapsed
+	}
+	m.lastWritten, m.lastTime = written, now
+
+	attrs := encodeAttributes(m.attrs)
+	counter := func(value int64) *sum {
+		s := strconv.FormatInt(value, 10)
+		return &sum{
+			DataPoints:             []dataPoint{{Attributes: attrs, StartTimeUnixNano: unixNano(m.start), TimeUnixNano: unixNano(now), AsInt: &s}},
+			AggregationTemporality: aggregationCumulative,
+			IsMonotonic:            true,
+		}
+	}
+	metrics := []metric{
+		{
+			Name:        "trasher.bytes_written",
+			Description: "Bytes written by the run so far",
+			Unit:        "By",
+			Sum:         counter(written),
+		},
+		{
+			Name:        "trasher.throughput",
+			Description: "Write throughput since the previous export",
+			Unit:        "By/s",
+			Gauge:       &gauge{DataPoints: []dataPoint{{Attributes: attrs, TimeUnixNano: unixNano(now), AsDouble: &throughput}}},
+		},
+	}
+
+	if m.latency != nil && m.latency.Count() > 0 {
+		metrics = append(metrics, metric{
+			Name:        "trasher.writes",
+			Description: "Write operations issued by the run so far",
+			Unit:        "{write}",
+			Sum:         counter(int64(m.latency.Count())),
+		})
+		points := make([]dataPoint, len(latencyQuantiles))
+		for i, quantile := range latencyQuantiles {
+			seconds := m.latency.Quantile(quantile.q).Seconds()
+			points[i] = dataPoint{
+				Attributes:   append(attrs[:len(attrs):len(attrs)], encodeAttributes([]Attribute{String("quantile", quantile.name)})...),
+				TimeUnixNano: unixNano(now),
+				AsDouble:     &seconds,
+			}
+		}
+		metrics = append(metrics, metric{
+			Name:        "trasher.write.latency",
+			Description: "Write latency quantiles over the run so far",
+			Unit:        "s",
+			Gauge:       &gauge{DataPoints: points},
+		})
+	}
+
+	return metricsPayload{ResourceMetrics: []resourceMetrics{{
+		Resource:     m.exporter.resource,
+		ScopeMetrics: []scopeMetrics{{Scope: m.exporter.scope(), Metrics: metrics}},
+	}}}
+}
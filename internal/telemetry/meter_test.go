@@ -0,0 +1,102 @@
+package telemetry
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/latency"
+)
+
+// metricsByName returns the metrics of an OTLP metrics request by name.
+func metricsByName(body map[string]any) map[string]map[string]any {
+	metrics := make(map[string]map[string]any)
+	resourceMetrics := body["resourceMetrics"].([]any)[0].(map[string]any)
+	for _, m := range resourceMetrics["scopeMetrics"].([]any)[0].(map[string]any)["metrics"].([]any) {
+		metric := m.(map[string]any)
+		metrics[metric["name"].(string)] = metric
+	}
+	return metrics
+}
+
+func TestNilMeter(t *testing.T) {
+	var e *Exporter
+	m := e.StartMeter(time.Millisecond, func() int64 { return 0 }, nil)
+	if m != nil {
+		t.Fatal("expected a nil exporter to start no meter")
+	}
+	if err := m.Stop(); err != nil {
+		t.Errorf("expected stopping a nil meter to do nothing, got %v", err)
+	}
+}
+
+func TestMeterExport(t *testing.T) {
+	c, server := newCollector(t)
+	e, err := NewExporter(server.URL, "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written atomic.Int64
+	h := latency.NewHistogram()
+	m := e.StartMeter(20*time.Millisecond, written.Load, h, String("trasher.output", "test.dat"))
+	written.Store(1 << 20)
+	time.Sleep(70 * time.Millisecond)
+	h.Record(3 * time.Millisecond)
+	written.Store(4 << 20)
+	if err := m.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	requests := c.received("/v1/metrics")
+	if len(requests) < 2 {
+		t.Fatalf("expected periodic exports and a final one, got %d", len(requests))
+	}
+
+	// The final export has the final totals
+	metrics := metricsByName(requests[len(requests)-1].body)
+	bytes := metrics["trasher.bytes_written"]["sum"].(map[string]any)
+	if bytes["isMonotonic"] != true || bytes["aggregationTemporality"] != float64(aggregationCumulative) {
+		t.Errorf("expected a cumulative monotonic sum, got %v", bytes)
+	}
+	point := bytes["dataPoints"].([]any)[0].(map[string]any)
+	if point["asInt"] != "4194304" {
+		t.Errorf("expected 4194304 bytes written, got %v", point["asInt"])
+	}
+	if attr := point["attributes"].([]any)[0].(map[string]any); attr["key"] != "trasher.output" {
+		t.Errorf("expected the meter's attributes on its data points, got %v", attr)
+	}
+	if _, ok := metrics["trasher.throughput"]; !ok {
+		t.Error("expected a throughput gauge")
+	}
+	latencies := metrics["trasher.write.latency"]["gauge"].(map[string]any)["dataPoints"].([]any)
+	if len(latencies) != len(latencyQuantiles) {
+		t.Fatalf("expected %d latency quantiles, got %d", len(latencyQuantiles), len(latencies))
+	}
+	for _, p := range latencies {
+		attrs := p.(map[string]any)["attributes"].([]any)
+		if len(attrs) != 2 || attrs[1].(map[string]any)["key"] != "quantile" {
+			t.Errorf("expected the output and quantile attributes, got %v", attrs)
+		}
+	}
+
+	// Until a write is recorded there are no latencies to export
+	if _, ok := metricsByName(requests[0].body)["trasher.write.latency"]; ok {
+		t.Error("expected no latency metric before any write")
+	}
+}
+
+func TestMeterExportError(t *testing.T) {
+	c, server := newCollector(t)
+	c.status = http.StatusServiceUnavailable
+	e, err := NewExporter(server.URL, "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := e.StartMeter(time.Hour, func() int64 { return 0 }, nil)
+	if err := m.Stop(); err == nil {
+		t.Error("expected the failed export to be reported")
+	}
+}
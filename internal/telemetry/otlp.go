@@ -0,0 +1,199 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds each export, so an unreachable collector delays the
+// end of a run by at most this long.
+const DefaultTimeout = 5 * time.Second
+
+// HeadersEnv is the environment variable OTLP exporters read extra request
+// headers from, such as an API key, as comma-separated key=value pairs.
+const HeadersEnv = "OTEL_EXPORTER_OTLP_HEADERS"
+
+// scopeName names trasher as the instrumentation scope of what it exports.
+const scopeName = "github.com/maxkimambo/trasher"
+
+// Exporter sends spans and metrics to an OpenTelemetry collector with
+// OTLP/HTTP, encoded as JSON. A nil *Exporter exports nothing, so callers
+// needn't check whether exporting is enabled.
+type Exporter struct {
+	endpoint string
+	version  string
+	headers  map[string]string
+	resource resource
+	client   *http.Client
+}
+
+// NewExporter creates an exporter for the collector at endpoint, the base
+// URL the signal paths /v1/traces and /v1/metrics are appended to, such as
+// http://localhost:4318. Headers in HeadersEnv are sent with every request.
+func NewExporter(endpoint, version string) (*Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: must be an http:// or https:// URL", endpoint)
+	}
+	headers, err := ParseHeaders(os.Getenv(HeadersEnv))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", HeadersEnv, err)
+	}
+
+	attrs := []Attribute{String("service.name", "trasher"), String("service.version", version)}
+	if host, err := os.Hostname(); err == nil {
+		attrs = append(attrs, String("host.name", host))
+	}
+	return &Exporter{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		version:  version,
+		headers:  headers,
+		resource: resource{Attributes: encodeAttributes(attrs)},
+		client:   http.DefaultClient,
+	}, nil
+}
+
+// ParseHeaders parses headers given as comma-separated key=value pairs, with
+// values URL-encoded as in HeadersEnv.
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("header %q is not key=value", pair)
+		}
+		decoded, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %v", key, err)
+		}
+		headers[key] = decoded
+	}
+	return headers, nil
+}
+
+// post sends payload as JSON to the collector's path for a signal.
+func (e *Exporter) post(ctx context.Context, path string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %v", path, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export to %s: %v", e.endpoint+path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to export to %s: %s %s", e.endpoint+path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// scope returns the instrumentation scope of what trasher exports.
+func (e *Exporter) scope() scope {
+	return scope{Name: scopeName, Version: e.version}
+}
+
+// Attribute is a key-value pair describing a span, event or metric.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Float returns a floating-point attribute.
+func Float(key string, value float64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// The OTLP JSON encoding follows the protobuf JSON mapping: fields are
+// lowerCamelCase, 64-bit integers are strings and IDs are hex.
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// encodeAttributes encodes attributes as OTLP key-values.
+func encodeAttributes(attrs []Attribute) []keyValue {
+	kvs := make([]keyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		var value anyValue
+		switch v := attr.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		case bool:
+			value.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		kvs = append(kvs, keyValue{Key: attr.Key, Value: value})
+	}
+	return kvs
+}
+
+// unixNano encodes a time as OTLP nanoseconds since the epoch.
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
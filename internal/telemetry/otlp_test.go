@@ -0,0 +1,136 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// collector is a fake OTLP/HTTP collector recording the requests it gets.
+type collector struct {
+	mu       sync.Mutex
+	requests []request
+	status   int
+}
+
+type request struct {
+	path    string
+	headers http.Header
+	body    map[string]any
+}
+
+func newCollector(t *testing.T) (*collector, *httptest.Server) {
+	t.Helper()
+
+	c := &collector{status: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("invalid JSON sent to %s: %v", r.URL.Path, err)
+		}
+		c.mu.Lock()
+		c.requests = append(c.requests, request{path: r.URL.Path, headers: r.Header, body: body})
+		status := c.status
+		c.mu.Unlock()
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			io.WriteString(w, "quota exceeded")
+		}
+	}))
+	t.Cleanup(server.Close)
+	return c, server
+}
+
+// received returns the requests sent to path.
+func (c *collector) received(path string) []request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var requests []request
+	for _, req := range c.requests {
+		if req.path == path {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+func TestNewExporterEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"http://localhost:4318", "https://otel.example.com/"} {
+		if _, err := NewExporter(endpoint, "test"); err != nil {
+			t.Errorf("expected %s to be accepted, got %v", endpoint, err)
+		}
+	}
+	for _, endpoint := range []string{"", "localhost:4318", "grpc://localhost:4317", "http://"} {
+		if _, err := NewExporter(endpoint, "test"); err == nil {
+			t.Errorf("expected %q to be rejected", endpoint)
+		}
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders("api-key=secret, x-team = storage%20qa ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 2 || headers["api-key"] != "secret" || headers["x-team"] != "storage qa" {
+		t.Errorf("unexpected headers %v", headers)
+	}
+	if headers, err := ParseHeaders(""); err != nil || len(headers) != 0 {
+		t.Errorf("expected no headers, got %v, %v", headers, err)
+	}
+	for _, s := range []string{"api-key", "=secret", "key=%zz"} {
+		if _, err := ParseHeaders(s); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestExporterPost(t *testing.T) {
+	c, server := newCollector(t)
+	t.Setenv(HeadersEnv, "api-key=secret")
+	e, err := NewExporter(server.URL+"/", "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.post(context.Background(), "/v1/traces", map[string]string{"a": "b"}); err != nil {
+		t.Fatalf("post failed: %v", err)
+	}
+	requests := c.received("/v1/traces")
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	if got := requests[0].headers.Get("api-key"); got != "secret" {
+		t.Errorf("expected the api-key header, got %q", got)
+	}
+	if got := requests[0].headers.Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected a JSON content type, got %q", got)
+	}
+
+	// A rejected export reports the collector's answer
+	c.status = http.StatusTooManyRequests
+	err = e.post(context.Background(), "/v1/traces", nil)
+	if err == nil || !strings.Contains(err.Error(), "429") || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("expected the collector's error, got %v", err)
+	}
+}
+
+func TestEncodeAttributes(t *testing.T) {
+	data, err := json.Marshal(encodeAttributes([]Attribute{
+		String("s", "v"), Int("i", 1<<40), Float("f", 0.5), Bool("b", true),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"key":"s","value":{"stringValue":"v"}},{"key":"i","value":{"intValue":"1099511627776"}},` +
+		`{"key":"f","value":{"doubleValue":0.5}},{"key":"b","value":{"boolValue":true}}]`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
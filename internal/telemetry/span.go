@@ -0,0 +1,187 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// OTLP span kind and status codes.
+const (
+	spanKindInternal = 1
+	statusOK         = 1
+	statusError      = 2
+)
+
+// Span records one run, or one part of a run as a child span: when it
+// started and ended, attributes describing it, events marking its phases
+// and whether it failed. A nil *Span records nothing.
+type Span struct {
+	exporter *Exporter
+	traceID  [16]byte
+	spanID   [8]byte
+	// parent is the span this one is part of, nil for the span of a run
+	parent *Span
+	name   string
+	start  time.Time
+	end    time.Time
+
+	mu     sync.Mutex
+	attrs  []Attribute
+	events []spanEvent
+	err    error
+	// children holds the child spans that ended, exported with this one
+	children []spanData
+}
+
+type spanEvent struct {
+	name  string
+	time  time.Time
+	attrs []Attribute
+}
+
+// StartSpan starts a span in a new trace. It returns nil if e is nil.
+func (e *Exporter) StartSpan(name string, attrs ...Attribute) *Span {
+	if e == nil {
+		return nil
+	}
+	s := &Span{exporter: e, name: name, start: time.Now(), attrs: attrs}
+	rand.Read(s.traceID[:])
+	rand.Read(s.spanID[:])
+	return s
+}
+
+// StartChild starts a span for part of the work s covers, in the same
+// trace. The child must end before s, and is exported along with it. It
+// returns nil if s is nil.
+func (s *Span) StartChild(name string, attrs ...Attribute) *Span {
+	if s == nil {
+		return nil
+	}
+	child := &Span{exporter: s.exporter, traceID: s.traceID, parent: s, name: name, start: time.Now(), attrs: attrs}
+	rand.Read(child.spanID[:])
+	return child
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// AddEvent records that something happened now, such as a phase of the run
+// starting or ending.
+func (s *Span) AddEvent(name string, attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, spanEvent{name: name, time: time.Now(), attrs: attrs})
+}
+
+// End ends the span, marking it failed if err is set, and exports it with
+// its children. A child span is kept for its parent to export instead.
+func (s *Span) End(ctx context.Context, err error) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.end = time.Now()
+	s.err = err
+	spans := append([]spanData{s.encode()}, s.children...)
+	s.mu.Unlock()
+
+	if s.parent != nil {
+		s.parent.mu.Lock()
+		defer s.parent.mu.Unlock()
+		s.parent.children = append(s.parent.children, spans...)
+		return nil
+	}
+	return s.exporter.post(ctx, "/v1/traces", tracesPayload{ResourceSpans: []resourceSpans{{
+		Resource:   s.exporter.resource,
+		ScopeSpans: []scopeSpans{{Scope: s.exporter.scope(), Spans: spans}},
+	}}})
+}
+
+// TraceID returns the span's trace ID in hex, to find it in a tracing
+// backend.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+type tracesPayload struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanData `json:"spans"`
+}
+
+type spanData struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []keyValue  `json:"attributes"`
+	Events            []eventData `json:"events,omitempty"`
+	Status            statusData  `json:"status"`
+}
+
+type eventData struct {
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Name         string     `json:"name"`
+	Attributes   []keyValue `json:"attributes,omitempty"`
+}
+
+type statusData struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// encode returns the span as OTLP span data. It must be called with s.mu
+// held.
+func (s *Span) encode() spanData {
+	data := spanData{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: unixNano(s.start),
+		EndTimeUnixNano:   unixNano(s.end),
+		Attributes:        encodeAttributes(s.attrs),
+		Status:            statusData{Code: statusOK},
+	}
+	if s.parent != nil {
+		data.ParentSpanID = hex.EncodeToString(s.parent.spanID[:])
+	}
+	for _, event := range s.events {
+		data.Events = append(data.Events, eventData{
+			TimeUnixNano: unixNano(event.time),
+			Name:         event.name,
+			Attributes:   encodeAttributes(event.attrs),
+		})
+	}
+	if s.err != nil {
+		data.Status = statusData{Code: statusError, Message: s.err.Error()}
+	}
+	return data
+}
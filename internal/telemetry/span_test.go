@@ -0,0 +1,114 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNilSpan(t *testing.T) {
+	var e *Exporter
+	s := e.StartSpan("run")
+	if s != nil {
+		t.Fatal("expected a nil exporter to start no span")
+	}
+	s.SetAttributes(String("a", "b"))
+	s.AddEvent("phase")
+	if err := s.End(context.Background(), nil); err != nil {
+		t.Errorf("expected ending a nil span to do nothing, got %v", err)
+	}
+	if s.TraceID() != "" {
+		t.Errorf("expected no trace ID, got %q", s.TraceID())
+	}
+	if child := s.StartChild("file"); child != nil {
+		t.Error("expected a nil span to start no child")
+	}
+}
+
+func TestSpanExport(t *testing.T) {
+	c, server := newCollector(t)
+	e, err := NewExporter(server.URL, "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := e.StartSpan("trasher.generate", String("trasher.output", "test.dat"))
+	s.AddEvent("generation started", Int("trasher.workers", 4))
+	s.SetAttributes(Int("trasher.bytes_written", 1024))
+	if err := s.End(context.Background(), errors.New("disk full")); err != nil {
+		t.Fatalf("End failed: %v", err)
+	}
+
+	requests := c.received("/v1/traces")
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 traces request, got %d", len(requests))
+	}
+	resourceSpans := requests[0].body["resourceSpans"].([]any)[0].(map[string]any)
+	attrs := resourceSpans["resource"].(map[string]any)["attributes"].([]any)
+	if attr := attrs[0].(map[string]any); attr["key"] != "service.name" || attr["value"].(map[string]any)["stringValue"] != "trasher" {
+		t.Errorf("expected the service name in the resource, got %v", attr)
+	}
+
+	span := resourceSpans["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)[0].(map[string]any)
+	if span["name"] != "trasher.generate" || span["traceId"] != s.TraceID() || len(span["spanId"].(string)) != 16 {
+		t.Errorf("unexpected span %v", span)
+	}
+	if len(span["traceId"].(string)) != 32 {
+		t.Errorf("expected a 16-byte trace ID in hex, got %v", span["traceId"])
+	}
+	if len(span["attributes"].([]any)) != 2 {
+		t.Errorf("expected 2 attributes, got %v", span["attributes"])
+	}
+	events := span["events"].([]any)
+	if len(events) != 1 || events[0].(map[string]any)["name"] != "generation started" {
+		t.Errorf("unexpected events %v", events)
+	}
+	status := span["status"].(map[string]any)
+	if status["code"] != float64(statusError) || status["message"] != "disk full" {
+		t.Errorf("expected an error status, got %v", status)
+	}
+}
+
+func TestChildSpan(t *testing.T) {
+	c, server := newCollector(t)
+	e, err := NewExporter(server.URL, "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent := e.StartSpan("trasher.generate")
+	child := parent.StartChild("trasher.generate.file", String("trasher.output", "test-1.dat"))
+	if child.TraceID() != parent.TraceID() {
+		t.Errorf("expected the child in the parent's trace %s, got %s", parent.TraceID(), child.TraceID())
+	}
+	if err := child.End(context.Background(), errors.New("disk full")); err != nil {
+		t.Fatalf("End failed: %v", err)
+	}
+	if requests := c.received("/v1/traces"); len(requests) != 0 {
+		t.Fatalf("expected the child to wait for its parent, got %d requests", len(requests))
+	}
+	if err := parent.End(context.Background(), nil); err != nil {
+		t.Fatalf("End failed: %v", err)
+	}
+
+	// Both spans are exported together when the parent ends
+	requests := c.received("/v1/traces")
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 traces request, got %d", len(requests))
+	}
+	resourceSpans := requests[0].body["resourceSpans"].([]any)[0].(map[string]any)
+	spans := resourceSpans["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	parentSpan, childSpan := spans[0].(map[string]any), spans[1].(map[string]any)
+	if childSpan["name"] != "trasher.generate.file" || childSpan["parentSpanId"] != parentSpan["spanId"] {
+		t.Errorf("expected the child to name its parent %v, got %v", parentSpan["spanId"], childSpan)
+	}
+	if _, ok := parentSpan["parentSpanId"]; ok {
+		t.Errorf("expected the parent to have no parent, got %v", parentSpan)
+	}
+	if status := childSpan["status"].(map[string]any); status["message"] != "disk full" {
+		t.Errorf("expected the child's error status, got %v", status)
+	}
+}
@@ -0,0 +1,71 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter paces operations to a maximum rate. Each call to Wait takes a cost
+// in units (operations, bytes) and is delayed so that, over time, no more than
+// the configured number of units are spent per second. Bursts are not
+// allowed: an operation may only start once the previous one's cost has been
+// paid off.
+type Limiter struct {
+	perSecond float64
+	next      time.Time
+	mu        sync.Mutex
+}
+
+// NewLimiter creates a limiter allowing perSecond units per second. A rate
+// of 0 or less doesn't limit operations until one is set with SetRate.
+func NewLimiter(perSecond float64) *Limiter {
+	return &Limiter{perSecond: perSecond}
+}
+
+// SetRate changes the limit to perSecond units per second, or removes it if
+// perSecond is 0 or less. The next operation starts without paying off the
+// previous one's cost at the old rate.
+func (l *Limiter) SetRate(perSecond float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.perSecond = perSecond
+	l.next = time.Time{}
+}
+
+// Rate returns the current limit in units per second, 0 if there is none.
+func (l *Limiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return max(l.perSecond, 0)
+}
+
+// Wait blocks until an operation costing n units may start, or the context
+// is cancelled.
+func (l *Limiter) Wait(ctx context.Context, n int64) error {
+	l.mu.Lock()
+	if l.perSecond <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / l.perSecond * float64(time.Second)))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
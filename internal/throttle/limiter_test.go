@@ -0,0 +1,110 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterPacesOperations(t *testing.T) {
+	l := NewLimiter(100)
+	ctx := context.Background()
+
+	// 21 operations at 100 per second take about 200ms: the first starts
+	// immediately and each of the rest waits 10ms
+	start := time.Now()
+	for i := 0; i < 21; i++ {
+		if err := l.Wait(ctx, 1); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+	}
+	elapsed := time.Since(start)
+	if elapsed < 190*time.Millisecond || elapsed > time.Second {
+		t.Errorf("expected about 200ms for 21 operations at 100/s, took %v", elapsed)
+	}
+}
+
+func TestLimiterCost(t *testing.T) {
+	l := NewLimiter(1000)
+	ctx := context.Background()
+
+	// An operation costing 100 units delays the next by 100ms
+	l.Wait(ctx, 100)
+	start := time.Now()
+	l.Wait(ctx, 1)
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected the next operation to wait about 100ms, waited %v", elapsed)
+	}
+}
+
+func TestLimiterIdleDoesNotBurst(t *testing.T) {
+	l := NewLimiter(10)
+	ctx := context.Background()
+
+	// Idle time is not saved up for a later burst
+	l.Wait(ctx, 1)
+	time.Sleep(250 * time.Millisecond)
+	l.Wait(ctx, 1)
+	start := time.Now()
+	l.Wait(ctx, 1)
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected the operation after an idle period to be paced, waited %v", elapsed)
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l := NewLimiter(1)
+	l.Wait(context.Background(), 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	if err := l.Wait(ctx, 1); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled Wait took %v", elapsed)
+	}
+}
+
+func TestLimiterSetRate(t *testing.T) {
+	l := NewLimiter(0)
+	ctx := context.Background()
+
+	// Without a rate, operations aren't paced
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		l.Wait(ctx, 1<<30)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("expected an unlimited limiter not to wait, took %v", elapsed)
+	}
+	if l.Rate() != 0 {
+		t.Errorf("expected no rate, got %v", l.Rate())
+	}
+
+	// A slow rate set later paces the next operation
+	l.SetRate(10)
+	l.Wait(ctx, 1)
+	start = time.Now()
+	l.Wait(ctx, 1)
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected the operation to wait about 100ms at 10/s, waited %v", elapsed)
+	}
+
+	// Raising the rate doesn't keep the wait owed at the old one
+	l.Wait(ctx, 100)
+	l.SetRate(1e6)
+	start = time.Now()
+	l.Wait(ctx, 1)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("expected the new rate to apply at once, waited %v", elapsed)
+	}
+	if l.Rate() != 1e6 {
+		t.Errorf("expected rate 1e6, got %v", l.Rate())
+	}
+}
@@ -0,0 +1,129 @@
+// Package trailer reads and writes the self-describing block trasher can
+// append to a generated file, so the file can be identified and verified
+// later without a checksum file.
+package trailer
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/verify"
+)
+
+// Magic ends every file with a trailer.
+const Magic = "TRASHER\x01"
+
+// footerSize is the length of the footer ending the file: the payload
+// length as a little-endian uint64, then Magic.
+const footerSize = 8 + len(Magic)
+
+// maxPayload bounds the payload read back, so a file that only happens to
+// end in Magic can't make Read allocate arbitrary memory.
+const maxPayload = 64 * 1024
+
+// Version is the version of the trailer payload layout.
+const Version = 1
+
+// ErrNoTrailer is returned by Read for files without a trailer.
+var ErrNoTrailer = errors.New("no trasher trailer found")
+
+// Trailer describes a generated file. It is appended to the file as JSON
+// followed by a fixed footer, so the file identifies itself without a
+// checksum file.
+type Trailer struct {
+	Version   int       `json:"version"`
+	Generator string    `json:"generator"`
+	CreatedAt time.Time `json:"created_at"`
+	// Size is the length of the data before the trailer.
+	Size    int64  `json:"size"`
+	Pattern string `json:"pattern"`
+	// Seed is the seed the pattern was given, if any.
+	Seed      *int64 `json:"seed,omitempty"`
+	Algorithm string `json:"algorithm"`
+	Digest    string `json:"digest"`
+}
+
+// Append writes t to the end of the file at path, after its data.
+func Append(path string, t Trailer) error {
+	t.Version = Version
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to encode trailer: %v", err)
+	}
+	footer := binary.LittleEndian.AppendUint64(nil, uint64(len(payload)))
+	footer = append(footer, Magic...)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for trailer: %v", path, err)
+	}
+	if _, err := file.Write(append(payload, footer...)); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write trailer: %v", err)
+	}
+	return file.Close()
+}
+
+// Read reads the trailer at the end of the file at path.
+func Read(path string) (*Trailer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < int64(footerSize) {
+		return nil, ErrNoTrailer
+	}
+
+	footer := make([]byte, footerSize)
+	if _, err := file.ReadAt(footer, info.Size()-int64(footerSize)); err != nil {
+		return nil, fmt.Errorf("failed to read trailer: %v", err)
+	}
+	if string(footer[8:]) != Magic {
+		return nil, ErrNoTrailer
+	}
+	length := binary.LittleEndian.Uint64(footer[:8])
+	if length > maxPayload || int64(length) > info.Size()-int64(footerSize) {
+		return nil, fmt.Errorf("corrupt trailer: payload of %d bytes", length)
+	}
+
+	payload := make([]byte, length)
+	payloadStart := info.Size() - int64(footerSize) - int64(length)
+	if _, err := file.ReadAt(payload, payloadStart); err != nil {
+		return nil, fmt.Errorf("failed to read trailer: %v", err)
+	}
+	var t Trailer
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return nil, fmt.Errorf("corrupt trailer: %v", err)
+	}
+	if t.Version != Version {
+		return nil, fmt.Errorf("unsupported trailer version %d (expected %d)", t.Version, Version)
+	}
+	if t.Size != payloadStart {
+		return nil, fmt.Errorf("trailer describes %d bytes of data but %d precede it", t.Size, payloadStart)
+	}
+	return &t, nil
+}
+
+// Algorithm is the digest algorithm trailers record.
+const Algorithm = "SHA256"
+
+// DataDigest computes the digest of the data before the trailer in the file
+// at path, for comparing against t.Digest. The data is read by workers
+// readers in parallel; zero uses one per CPU.
+func (t *Trailer) DataDigest(ctx context.Context, path string, workers int) (string, error) {
+	if t.Algorithm != Algorithm {
+		return "", fmt.Errorf("unsupported trailer digest algorithm %q", t.Algorithm)
+	}
+	return verify.Digest(ctx, path, t.Size, workers)
+}
@@ -0,0 +1,121 @@
+package trailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeWithTrailer writes data and appends a trailer describing it.
+func writeWithTrailer(t *testing.T, data []byte) (string, Trailer) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.dat")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	seed := int64(42)
+	sum := sha256.Sum256(data)
+	want := Trailer{
+		Generator: "trasher test",
+		CreatedAt: time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC),
+		Size:      int64(len(data)),
+		Pattern:   "random:seed=42",
+		Seed:      &seed,
+		Algorithm: Algorithm,
+		Digest:    hex.EncodeToString(sum[:]),
+	}
+	if err := Append(path, want); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	return path, want
+}
+
+func TestAppendAndRead(t *testing.T) {
+	data := bytes.Repeat([]byte("trailer test data "), 1000)
+	path, want := writeWithTrailer(t, data)
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if got.Version != Version || got.Size != want.Size || got.Pattern != want.Pattern ||
+		got.Seed == nil || *got.Seed != 42 || got.Digest != want.Digest || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("unexpected trailer: %+v", got)
+	}
+
+	// The data itself is unchanged
+	content, _ := os.ReadFile(path)
+	if !bytes.Equal(content[:len(data)], data) {
+		t.Error("data before the trailer was modified")
+	}
+
+	digest, err := got.DataDigest(context.Background(), path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != want.Digest {
+		t.Errorf("expected digest %s, got %s", want.Digest, digest)
+	}
+}
+
+func TestDataDigestDetectsCorruption(t *testing.T) {
+	data := make([]byte, 4096)
+	path, want := writeWithTrailer(t, data)
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteAt([]byte{0xFF}, 100)
+	file.Close()
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := got.DataDigest(context.Background(), path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest == want.Digest {
+		t.Error("expected a different digest for corrupted data")
+	}
+}
+
+func TestReadWithoutTrailer(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string][]byte{
+		"empty.dat": nil,
+		"plain.dat": bytes.Repeat([]byte{7}, 1000),
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Read(path); !errors.Is(err, ErrNoTrailer) {
+			t.Errorf("%s: expected ErrNoTrailer, got %v", name, err)
+		}
+	}
+}
+
+func TestReadRejectsTruncatedData(t *testing.T) {
+	data := make([]byte, 4096)
+	path, _ := writeWithTrailer(t, data)
+
+	// Dropping data before the trailer changes the size it describes
+	content, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, content[100:], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil || !strings.Contains(err.Error(), "describes 4096 bytes") {
+		t.Errorf("expected size mismatch error, got %v", err)
+	}
+}
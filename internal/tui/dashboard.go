@@ -0,0 +1,422 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/maxkimambo/trasher/internal/control"
+	"github.com/maxkimambo/trasher/internal/latency"
+	"github.com/maxkimambo/trasher/internal/progress"
+	"github.com/maxkimambo/trasher/internal/throttle"
+	"github.com/maxkimambo/trasher/internal/worker"
+)
+
+// DefaultRefresh is how often the dashboard is redrawn.
+const DefaultRefresh = 500 * time.Millisecond
+
+// errNotTerminal is returned when keys are to be read from a file that is
+// not a terminal.
+var errNotTerminal = errors.New("not a terminal")
+
+const (
+	// maxSamples is how many throughput samples are kept for the graph, a
+	// minute at DefaultRefresh
+	maxSamples = 120
+	// maxWorkers is how many workers the table lists
+	maxWorkers = 16
+	// maxErrors is how many of the latest errors are listed
+	maxErrors = 5
+	// minRate is the lowest limit the throttle keys set, 1 MB/s
+	minRate = 1 << 20
+)
+
+// Terminal control sequences.
+const (
+	hideCursor = "\x1b[?25l"
+	showCursor = "\x1b[?25h"
+	clearLine  = "\x1b[K"
+	clearBelow = "\x1b[J"
+)
+
+// Options selects what the dashboard shows besides the job's progress.
+type Options struct {
+	// Workers reports what each worker is doing
+	Workers func() []worker.WorkerStat
+	// Latency holds the latencies of the writes so far
+	Latency *latency.Histogram
+	// Errors reports the chunks that failed so far
+	Errors func() []worker.ChunkError
+	// Limiter paces writes, and the throttle keys change its rate; without
+	// it writes can't be throttled
+	Limiter *throttle.Limiter
+}
+
+// Dashboard draws a live view of a running job on a terminal: its progress,
+// a graph of its throughput, write latency, what each worker is doing and
+// the latest errors. Keys pressed on the terminal pause and resume the job,
+// throttle its writes or abort it.
+type Dashboard struct {
+	ctx  context.Context
+	job  control.Controller
+	opts Options
+	out  io.Writer
+	in   *os.File
+
+	mu    sync.Mutex
+	table *worker.StatsTable
+	// samples holds the throughput measured at each refresh, oldest first
+	samples     []float64
+	lastWritten int64
+	lastSample  time.Time
+	// lines is the height of the last frame, to draw the next one over it
+	lines int
+	// message says what the last key pressed did
+	message string
+	// keys is set when keys can be read from in
+	keys bool
+
+	done     chan struct{}
+	finished chan struct{}
+	keysDone chan struct{}
+	restore  func()
+	stopOnce sync.Once
+}
+
+// New creates a dashboard for job, drawn on out with keys read from in. It
+// stops drawing once ctx is cancelled.
+func New(ctx context.Context, job control.Controller, opts Options, out io.Writer, in *os.File) *Dashboard {
+	return &Dashboard{
+		ctx:      ctx,
+		job:      job,
+		opts:     opts,
+		out:      out,
+		in:       in,
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+		keysDone: make(chan struct{}),
+	}
+}
+
+// IsTerminal reports whether f is a terminal the dashboard can be drawn on.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// CanReadKeys reports why the dashboard can't read keys from f, because f
+// is not a terminal or keys can't be read on this platform, or nil if it
+// can.
+func CanReadKeys(f *os.File) error {
+	if !IsTerminal(f) {
+		return errNotTerminal
+	}
+	restore, err := cbreak(f)
+	if err != nil {
+		return err
+	}
+	restore()
+	return nil
+}
+
+// Start draws the dashboard and keeps it up to date until Stop. If in is a
+// terminal, it is put into cbreak mode to read keys.
+func (d *Dashboard) Start() {
+	now := time.Now()
+	d.table = worker.NewStatsTable(now)
+	d.lastSample = now
+
+	fmt.Fprint(d.out, hideCursor)
+	if d.in != nil {
+		if restore, err := cbreak(d.in); err == nil {
+			d.restore = restore
+			d.keys = true
+		}
+	}
+	if d.keys {
+		go d.readKeys()
+	} else {
+		close(d.keysDone)
+	}
+	go d.loop()
+}
+
+// Stop draws the final state of the job, unless it was cancelled, and
+// restores the terminal.
+func (d *Dashboard) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.done)
+		<-d.finished
+
+		// A read may not return if the terminal ignores the read timeout
+		select {
+		case <-d.keysDone:
+		case <-time.After(time.Second):
+		}
+		if d.restore != nil {
+			d.restore()
+		}
+		fmt.Fprint(d.out, showCursor)
+	})
+}
+
+// loop redraws the dashboard every DefaultRefresh.
+func (d *Dashboard) loop() {
+	defer close(d.finished)
+
+	ticker := time.NewTicker(DefaultRefresh)
+	defer ticker.Stop()
+
+	d.draw(time.Now())
+	for {
+		select {
+		case <-d.done:
+			d.draw(time.Now())
+			return
+		case now := <-ticker.C:
+			d.draw(now)
+		}
+	}
+}
+
+// readKeys handles the keys pressed until Stop.
+func (d *Dashboard) readKeys() {
+	defer close(d.keysDone)
+
+	buf := make([]byte, 16)
+	for {
+		select {
+		case <-d.done:
+			return
+		default:
+		}
+
+		// In cbreak mode a read returns nothing after a short wait, which
+		// os.File reports as io.EOF
+		n, err := d.in.Read(buf)
+		if err != nil && err != io.EOF {
+			return
+		}
+		for _, key := range buf[:n] {
+			d.handleKey(key)
+		}
+		if n > 0 {
+			d.draw(time.Now())
+		}
+	}
+}
+
+// handleKey carries out what a key asks for.
+func (d *Dashboard) handleKey(key byte) {
+	var message string
+	var err error
+	switch key {
+	case 'p', ' ':
+		if d.job.Status().Paused {
+			err = d.job.Resume()
+			message = "Resumed"
+		} else {
+			err = d.job.Pause()
+			message = "Paused: chunks in progress finish, no new ones start"
+		}
+	case '-', '+', '=', '0':
+		message = d.throttle(key)
+	case 'q':
+		err = d.job.Cancel()
+		message = "Aborting"
+	default:
+		return
+	}
+	if err != nil {
+		message = err.Error()
+	}
+
+	d.mu.Lock()
+	d.message = message
+	d.mu.Unlock()
+}
+
+// throttle halves, doubles or removes the write limit, and describes the
+// result. Halving an unlimited job starts from its current throughput.
+func (d *Dashboard) throttle(key byte) string {
+	if d.opts.Limiter == nil {
+		return "Writes can't be throttled in this run"
+	}
+
+	rate := d.opts.Limiter.Rate()
+	switch key {
+	case '-':
+		if rate == 0 {
+			d.mu.Lock()
+			rate = d.current()
+			d.mu.Unlock()
+		}
+		rate = max(rate/2, minRate)
+	case '+', '=':
+		if rate == 0 {
+			return "Writes are not limited"
+		}
+		rate *= 2
+	case '0':
+		rate = 0
+	}
+
+	d.opts.Limiter.SetRate(rate)
+	if rate == 0 {
+		return "Limit removed"
+	}
+	return "Writes limited to " + progress.FormatThroughput(rate)
+}
+
+// current returns the latest throughput sample. It must be called with
+// d.mu held.
+func (d *Dashboard) current() float64 {
+	if len(d.samples) == 0 {
+		return 0
+	}
+	return d.samples[len(d.samples)-1]
+}
+
+// draw takes a throughput sample and draws the dashboard over its previous
+// frame. Once the job is cancelled it no longer draws, so as not to draw
+// over what is printed while the job shuts down.
+func (d *Dashboard) draw(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ctx.Err() != nil {
+		return
+	}
+
+	status := d.job.Status()
+	d.sample(now, status.WrittenBytes)
+
+	cols := 0
+	if f, ok := d.out.(*os.File); ok {
+		cols = width(f)
+	}
+
+	var b strings.Builder
+	if d.lines > 0 {
+		fmt.Fprintf(&b, "\x1b[%dF", d.lines)
+	}
+	lines := d.render(status, now)
+	for _, line := range lines {
+		b.WriteString(truncate(line, cols))
+		b.WriteString(clearLine + "\n")
+	}
+	b.WriteString(clearBelow)
+	d.lines = len(lines)
+	io.WriteString(d.out, b.String())
+}
+
+// sample records the throughput since the last sample. Samples closer
+// together than half the refresh interval are skipped as too noisy. It must
+// be called with d.mu held.
+func (d *Dashboard) sample(now time.Time, written int64) {
+	elapsed := now.Sub(d.lastSample)
+	if elapsed < DefaultRefresh/2 {
+		return
+	}
+	d.samples = append(d.samples, float64(written-d.lastWritten)/elapsed.Seconds())
+	if len(d.samples) > maxSamples {
+		d.samples = d.samples[1:]
+	}
+	d.lastWritten = written
+	d.lastSample = now
+}
+
+// render returns the lines of the dashboard. It must be called with d.mu
+// held.
+func (d *Dashboard) render(status control.Status, now time.Time) []string {
+	state := "running"
+	if status.Paused {
+		state = "PAUSED"
+	}
+	lines := []string{
+		fmt.Sprintf("trasher: %s (%s) - %s", status.Output, status.Pattern, state),
+		fmt.Sprintf("%s %.2f%% | %s / %s", bar(status.Percent, 40), status.Percent,
+			progress.FormatBytes(status.WrittenBytes), progress.FormatBytes(status.TotalBytes)),
+		fmt.Sprintf("Elapsed: %s | ETA: %s | Average: %s | Limit: %s",
+			progress.FormatDuration(time.Duration(status.ElapsedSeconds*float64(time.Second))),
+			progress.FormatDuration(time.Duration(status.ETASeconds*float64(time.Second))),
+			progress.FormatThroughput(status.Throughput), d.limit()),
+		"",
+	}
+
+	var peak float64
+	for _, sample := range d.samples {
+		peak = max(peak, sample)
+	}
+	lines = append(lines,
+		fmt.Sprintf("Throughput: %s (peak %s over the last minute)",
+			progress.FormatThroughput(d.current()), progress.FormatThroughput(peak)),
+		sparkline(d.samples, maxSamples),
+		"")
+
+	if h := d.opts.Latency; h != nil && h.Count() > 0 {
+		round := func(d time.Duration) time.Duration {
+			return d.Round(time.Microsecond)
+		}
+		lines = append(lines, fmt.Sprintf("Write latency: p50 %v, p95 %v, p99 %v, max %v",
+			round(h.Quantile(0.50)), round(h.Quantile(0.95)), round(h.Quantile(0.99)), round(h.Max())), "")
+	}
+
+	if d.opts.Workers != nil {
+		stats := d.opts.Workers()
+		more := len(stats) - maxWorkers
+		if more > 0 {
+			stats = stats[:maxWorkers]
+		}
+		table := strings.TrimSuffix(d.table.Format(stats, now), "\n")
+		lines = append(lines, strings.Split(table, "\n")...)
+		if more > 0 {
+			lines = append(lines, fmt.Sprintf("... and %d more workers", more))
+		}
+		lines = append(lines, "")
+	}
+
+	if d.opts.Errors != nil {
+		errs := d.opts.Errors()
+		if len(errs) == 0 {
+			lines = append(lines, "Errors: none")
+		} else {
+			lines = append(lines, fmt.Sprintf("Errors: %d, latest:", len(errs)))
+			for _, err := range errs[max(len(errs)-maxErrors, 0):] {
+				lines = append(lines, "  "+err.Error())
+			}
+		}
+		lines = append(lines, "")
+	}
+
+	keys := "Ctrl-C: abort"
+	if d.keys {
+		keys = "p: pause/resume | -: slower | +: faster | 0: no limit | q: abort"
+	}
+	if d.message != "" {
+		keys += " | " + d.message
+	}
+	return append(lines, keys)
+}
+
+// limit describes the write limit.
+func (d *Dashboard) limit() string {
+	if d.opts.Limiter == nil || d.opts.Limiter.Rate() == 0 {
+		return "none"
+	}
+	return progress.FormatThroughput(d.opts.Limiter.Rate())
+}
+
+// truncate cuts line to cols characters, so it doesn't wrap and throw off
+// where the next frame is drawn. A cols of 0 leaves it whole.
+func truncate(line string, cols int) string {
+	if cols <= 0 || utf8.RuneCountInString(line) <= cols {
+		return line
+	}
+	return string([]rune(line)[:cols])
+}
@@ -0,0 +1,241 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/control"
+	"github.com/maxkimambo/trasher/internal/latency"
+	"github.com/maxkimambo/trasher/internal/throttle"
+	"github.com/maxkimambo/trasher/internal/worker"
+)
+
+// fakeJob is a control.Controller recording the commands it gets.
+type fakeJob struct {
+	mu        sync.Mutex
+	status    control.Status
+	cancelled bool
+	cancel    context.CancelFunc
+}
+
+func (j *fakeJob) Status() control.Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+func (j *fakeJob) Pause() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Paused = true
+	return nil
+}
+
+func (j *fakeJob) Resume() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Paused = false
+	return nil
+}
+
+func (j *fakeJob) Cancel() error {
+	j.cancelled = true
+	if j.cancel != nil {
+		j.cancel()
+	}
+	return nil
+}
+
+func newTestDashboard(t *testing.T, opts Options) (*Dashboard, *fakeJob, *bytes.Buffer) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	job := &fakeJob{
+		status: control.Status{
+			Output:         "test.dat",
+			Pattern:        "random",
+			TotalBytes:     4 << 30,
+			WrittenBytes:   1 << 30,
+			Percent:        25,
+			Throughput:     100 << 20,
+			ElapsedSeconds: 10,
+			ETASeconds:     30,
+		},
+		cancel: cancel,
+	}
+	var out bytes.Buffer
+	d := New(ctx, job, opts, &out, nil)
+	d.table = worker.NewStatsTable(time.Now())
+	d.lastSample = time.Now()
+	return d, job, &out
+}
+
+func TestDashboardRender(t *testing.T) {
+	h := latency.NewHistogram()
+	h.Record(2 * time.Millisecond)
+	errs := []worker.ChunkError{{Offset: 8 << 20, Err: errors.New("input/output error")}}
+	d, job, _ := newTestDashboard(t, Options{
+		Workers: func() []worker.WorkerStat {
+			return []worker.WorkerStat{{ID: 0, State: worker.StateGenerating, Chunks: 3}, {ID: 1, State: worker.StateIdle}}
+		},
+		Latency: h,
+		Errors:  func() []worker.ChunkError { return errs },
+		Limiter: throttle.NewLimiter(0),
+	})
+	d.samples = []float64{50 << 20, 100 << 20}
+
+	text := strings.Join(d.render(job.Status(), time.Now()), "\n")
+	for _, want := range []string{
+		"trasher: test.dat (random) - running",
+		"25.00% | 1.00 GB / 4.00 GB",
+		"ETA: 30s",
+		"Limit: none",
+		"Throughput: 100.00 MB/s (peak 100.00 MB/s",
+		"▄█",
+		"Write latency: p50 2ms",
+		"generating",
+		"idle",
+		"Errors: 1, latest:",
+		"chunk at offset 8388608: input/output error",
+		"Ctrl-C: abort",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected the dashboard to contain %q, got:\n%s", want, text)
+		}
+	}
+
+	// Only the latest errors and the first workers are listed
+	errs = make([]worker.ChunkError, 8)
+	for i := range errs {
+		errs[i] = worker.ChunkError{Offset: int64(i), Err: errors.New("failed")}
+	}
+	d.opts.Workers = func() []worker.WorkerStat {
+		return make([]worker.WorkerStat, maxWorkers+4)
+	}
+	text = strings.Join(d.render(job.Status(), time.Now()), "\n")
+	if strings.Contains(text, "offset 2:") || !strings.Contains(text, "offset 3:") || !strings.Contains(text, "Errors: 8") {
+		t.Errorf("expected the latest %d of 8 errors, got:\n%s", maxErrors, text)
+	}
+	if !strings.Contains(text, "... and 4 more workers") {
+		t.Errorf("expected the workers past %d to be summed up, got:\n%s", maxWorkers, text)
+	}
+}
+
+func TestDashboardKeys(t *testing.T) {
+	limiter := throttle.NewLimiter(0)
+	d, job, _ := newTestDashboard(t, Options{Limiter: limiter})
+	d.samples = []float64{100 << 20}
+
+	d.handleKey('p')
+	if !job.status.Paused || !strings.HasPrefix(d.message, "Paused") {
+		t.Errorf("expected p to pause the job, paused %v, message %q", job.status.Paused, d.message)
+	}
+	d.handleKey('p')
+	if job.status.Paused || d.message != "Resumed" {
+		t.Errorf("expected p to resume the job, paused %v, message %q", job.status.Paused, d.message)
+	}
+
+	// Slowing an unlimited job halves its current throughput
+	d.handleKey('-')
+	if limiter.Rate() != 50<<20 {
+		t.Errorf("expected a limit of 50 MB/s, got %v", limiter.Rate())
+	}
+	d.handleKey('+')
+	if limiter.Rate() != 100<<20 || d.message != "Writes limited to 100.00 MB/s" {
+		t.Errorf("expected a limit of 100 MB/s, got %v, message %q", limiter.Rate(), d.message)
+	}
+	for i := 0; i < 10; i++ {
+		d.handleKey('-')
+	}
+	if limiter.Rate() != minRate {
+		t.Errorf("expected the limit to stop at %d, got %v", minRate, limiter.Rate())
+	}
+	d.handleKey('0')
+	if limiter.Rate() != 0 || d.message != "Limit removed" {
+		t.Errorf("expected the limit to be removed, got %v, message %q", limiter.Rate(), d.message)
+	}
+	d.handleKey('+')
+	if limiter.Rate() != 0 {
+		t.Errorf("expected + not to set a limit, got %v", limiter.Rate())
+	}
+
+	// Other keys are ignored
+	d.handleKey('x')
+	if d.message != "Writes are not limited" {
+		t.Errorf("expected x to be ignored, message %q", d.message)
+	}
+
+	d.handleKey('q')
+	if !job.cancelled {
+		t.Error("expected q to cancel the job")
+	}
+}
+
+func TestDashboardThrottleWithoutLimiter(t *testing.T) {
+	d, _, _ := newTestDashboard(t, Options{})
+	d.handleKey('-')
+	if d.message != "Writes can't be throttled in this run" {
+		t.Errorf("unexpected message %q", d.message)
+	}
+}
+
+func TestDashboardDraw(t *testing.T) {
+	d, job, out := newTestDashboard(t, Options{})
+
+	d.draw(time.Now())
+	first := out.String()
+	if strings.Contains(first, "\x1b[") && strings.Index(first, "\x1b[") < strings.Index(first, "trasher:") {
+		t.Errorf("expected the first frame to be drawn where the cursor is, got %q", first)
+	}
+	lines := strings.Count(first, "\n")
+
+	// The next frame is drawn over the first
+	out.Reset()
+	d.draw(time.Now())
+	if want := fmt.Sprintf("\x1b[%dF", lines); !strings.HasPrefix(out.String(), want) {
+		t.Errorf("expected the frame to start by moving up %d lines, got %q", lines, out.String())
+	}
+
+	// Nothing is drawn once the job is cancelled
+	job.Cancel()
+	out.Reset()
+	d.draw(time.Now())
+	if out.Len() != 0 {
+		t.Errorf("expected nothing to be drawn after cancelling, got %q", out.String())
+	}
+}
+
+func TestDashboardStartStop(t *testing.T) {
+	d, job, out := newTestDashboard(t, Options{})
+	d.Start()
+	job.mu.Lock()
+	job.status.WrittenBytes = job.status.TotalBytes
+	job.status.Percent = 100
+	job.mu.Unlock()
+	d.Stop()
+	d.Stop()
+
+	text := out.String()
+	if !strings.HasPrefix(text, hideCursor) || !strings.HasSuffix(text, showCursor) {
+		t.Errorf("expected the cursor to be hidden and shown again, got %q", text)
+	}
+	if !strings.Contains(text, "100.00% | 4.00 GB / 4.00 GB") {
+		t.Errorf("expected the final frame to show the finished job, got %q", text)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("▁▂▃▄", 2); got != "▁▂" {
+		t.Errorf("expected the line cut to 2 characters, got %q", got)
+	}
+	if got := truncate("abc", 0); got != "abc" {
+		t.Errorf("expected the line left whole, got %q", got)
+	}
+}
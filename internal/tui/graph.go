@@ -0,0 +1,39 @@
+package tui
+
+import "strings"
+
+// levels are the characters of a sparkline, from lowest to highest.
+var levels = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws the last width samples as a row of bars scaled to the
+// largest of them. A sample of 0 is left blank.
+func sparkline(samples []float64, width int) string {
+	if len(samples) > width {
+		samples = samples[len(samples)-width:]
+	}
+	var peak float64
+	for _, sample := range samples {
+		peak = max(peak, sample)
+	}
+
+	var b strings.Builder
+	for _, sample := range samples {
+		if sample <= 0 || peak <= 0 {
+			b.WriteRune(' ')
+			continue
+		}
+		level := int(sample / peak * float64(len(levels)-1))
+		b.WriteRune(levels[min(level, len(levels)-1)])
+	}
+	return b.String()
+}
+
+// bar draws a progress bar width characters wide for percent.
+func bar(percent float64, width int) string {
+	done := min(max(int(float64(width)*percent/100), 0), width)
+	b := strings.Repeat("=", done)
+	if done < width {
+		b += ">" + strings.Repeat(" ", width-done-1)
+	}
+	return "[" + b + "]"
+}
@@ -0,0 +1,40 @@
+package tui
+
+import "testing"
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		samples []float64
+		width   int
+		want    string
+	}{
+		{nil, 10, ""},
+		{[]float64{0, 0}, 10, "  "},
+		{[]float64{1, 2, 4, 8}, 10, "▁▂▄█"},
+		{[]float64{8, 0, 8}, 10, "█ █"},
+		// Only the newest samples fit
+		{[]float64{8, 1, 2, 4}, 2, "▄█"},
+	}
+	for _, tt := range tests {
+		if got := sparkline(tt.samples, tt.width); got != tt.want {
+			t.Errorf("sparkline(%v, %d) = %q, want %q", tt.samples, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestBar(t *testing.T) {
+	tests := []struct {
+		percent float64
+		want    string
+	}{
+		{0, "[>         ]"},
+		{45, "[====>     ]"},
+		{100, "[==========]"},
+		{150, "[==========]"},
+	}
+	for _, tt := range tests {
+		if got := bar(tt.percent, 10); got != tt.want {
+			t.Errorf("bar(%v) = %q, want %q", tt.percent, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+// cbreak is not supported here, so the dashboard can't read keys.
+func cbreak(f *os.File) (func(), error) {
+	return nil, errors.New("reading keys is not supported on this platform")
+}
+
+// width can't be told here.
+func width(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,54 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package tui
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// cbreak puts the terminal on f into cbreak mode, so keys are read as they
+// are pressed without being echoed. Reads return after a tenth of a second
+// without input, so a reader can notice it should stop. Ctrl-C still sends
+// SIGINT. It returns a function restoring the previous mode, or an error if
+// f is not a terminal.
+func cbreak(f *os.File) (func(), error) {
+	var old syscall.Termios
+	if err := ioctl(f, ioctlGetTermios, unsafe.Pointer(&old)); err == syscall.ENOTTY {
+		return nil, errNotTerminal
+	} else if err != nil {
+		return nil, err
+	}
+
+	mode := old
+	mode.Lflag &^= syscall.ICANON | syscall.ECHO
+	mode.Cc[syscall.VMIN] = 0
+	mode.Cc[syscall.VTIME] = 1
+	if err := ioctl(f, ioctlSetTermios, unsafe.Pointer(&mode)); err != nil {
+		return nil, err
+	}
+	return func() {
+		ioctl(f, ioctlSetTermios, unsafe.Pointer(&old))
+	}, nil
+}
+
+// width returns the number of columns of the terminal on f, or 0 if it
+// can't be told.
+func width(f *os.File) int {
+	var size struct {
+		rows, cols, xpixels, ypixels uint16
+	}
+	if err := ioctl(f, syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil {
+		return 0
+	}
+	return int(size.cols)
+}
+
+// ioctl issues a terminal request on f.
+func ioctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package tui
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCbreakNeedsTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if _, err := cbreak(r); err == nil {
+		t.Error("expected cbreak to fail on a pipe")
+	}
+	if cols := width(r); cols != 0 {
+		t.Errorf("expected no width for a pipe, got %d", cols)
+	}
+}
+
+func TestCanReadKeysNeedsTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if err := CanReadKeys(r); err == nil || err.Error() != "not a terminal" {
+		t.Errorf("expected keys not to be read from a pipe, got %v", err)
+	}
+}
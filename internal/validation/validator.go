@@ -3,9 +3,16 @@ package validation
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/maxkimambo/trasher/internal/device"
+	"github.com/maxkimambo/trasher/internal/diskspace"
+	"github.com/maxkimambo/trasher/internal/writer"
 	"github.com/maxkimambo/trasher/pkg/generator"
 	"github.com/maxkimambo/trasher/pkg/sizeparser"
 )
@@ -23,12 +30,74 @@ type ValidationConfig struct {
 	Workers    int
 	ChunkSize  string
 	Force      bool
+	// Count is the number of files of Size to generate; 0 means one.
+	Count int
+	// TotalSize is the space the files need when their sizes differ, with
+	// Size the largest; 0 means Size times Count.
+	TotalSize int64
+	// MaxIOPS caps write operations per second; 0 means no limit.
+	MaxIOPS int
+	// Duration limits how long generation runs; 0 means no limit.
+	Duration time.Duration
+	// StopWhenFreeBelow stops generation once the output's filesystem has
+	// less free space; empty means no threshold.
+	StopWhenFreeBelow string
+	// MaxMemory bounds the memory held in chunk buffers; empty means no
+	// limit.
+	MaxMemory string
+	// Pipeline is how chunks reach the output, direct or channel; empty
+	// means direct.
+	Pipeline string
+	// IOEngine is the I/O engine file outputs are written with; empty means
+	// the default.
+	IOEngine string
+	// ChecksumMode is how the full-file checksum is computed, stream or
+	// tree; empty means stream.
+	ChecksumMode string
+	// ChecksumFormat is the checksum file format, trasher, gnu or bsd;
+	// empty means trasher.
+	ChecksumFormat string
+	// MixedChunkSize and MixedPhase tune the mixed pattern; empty means default.
+	MixedChunkSize string
+	MixedPhase     string
+	// MixedRandomRatio is the random share of the mixed pattern; zero means default.
+	MixedRandomRatio float64
+	// CompressRatio is the compressible pattern's target ratio; zero means default.
+	CompressRatio float64
+	// DedupRatio and DedupBlockSize tune the dedup pattern; zero or empty means default.
+	DedupRatio     float64
+	DedupBlockSize string
+	// Entropy is the entropy pattern's target; zero means default.
+	Entropy float64
+	// Schema is the record schema of the jsonl and csv patterns; empty means default.
+	Schema string
+	// LogFormat is the logs pattern's line format; empty means default.
+	LogFormat string
+	// PatternBytes is the hex byte sequence of the bytes pattern.
+	PatternBytes string
+	// Magic is the file format whose header starts the output; empty means none.
+	Magic string
+	// GeneratorCommand is the command line of the exec pattern.
+	GeneratorCommand string
+	// Corpus is the path of the markov pattern's training text; empty means built-in.
+	Corpus string
+	// Sparse leaves all-zero blocks unallocated, so free space is not checked.
+	Sparse bool
+	// PatternMap assigns patterns to file regions, replacing Pattern; empty means none.
+	PatternMap string
+	// Encrypt is the cipher the output is encrypted with, and EncryptKey its
+	// hex key; empty means no encryption and a random key respectively.
+	Encrypt    string
+	EncryptKey string
 }
 
 // ValidationError represents a validation error with a user-friendly message.
 type ValidationError struct {
 	Field   string
 	Message string
+	// Err is the kind of failure, such as diskspace.ErrInsufficient, if it
+	// has one callers may check for.
+	Err error
 }
 
 func (e *ValidationError) Error() string {
@@ -38,6 +107,10 @@ func (e *ValidationError) Error() string {
 	return e.Message
 }
 
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
 // NewValidator creates a new validator instance.
 func NewValidator() *Validator {
 	return &Validator{}
@@ -56,19 +129,51 @@ func (v *Validator) ValidateAll(config ValidationConfig) error {
 		return err
 	}
 
-	// Validate output path
-	if err := v.ValidateOutputPath(config.OutputPath, config.Force); err != nil {
+	// Validate the region pattern map
+	if err := v.ValidatePatternMap(config.PatternMap, sizeBytes); err != nil {
 		return err
 	}
 
-	// Validate disk space
-	if err := v.ValidateDiskSpace(config.OutputPath, sizeBytes); err != nil {
+	// Validate the number of files
+	if err := v.ValidateCount(config.Count, sizeBytes, config.OutputPath); err != nil {
 		return err
 	}
 
-	// Validate file system capabilities
-	if err := v.ValidateFileSystemCapabilities(config.OutputPath, sizeBytes); err != nil {
-		return err
+	// Validate output path; network and object store targets have no local
+	// disk to check
+	switch {
+	case writer.IsNetworkTarget(config.OutputPath):
+		if err := v.ValidateNetworkTarget(config.OutputPath, config.Sparse); err != nil {
+			return err
+		}
+	case writer.IsObjectTarget(config.OutputPath):
+		if err := v.ValidateObjectTarget(config.OutputPath, sizeBytes, config.ChunkSize, config.Sparse); err != nil {
+			return err
+		}
+	default:
+		if err := v.ValidateOutputPath(config.OutputPath, config.Force); err != nil {
+			return err
+		}
+
+		// Validate disk space; sparse files only take up room for their data,
+		// and a free space threshold stops the run before space runs out
+		if err := v.ValidateSparse(config.OutputPath, config.Sparse); err != nil {
+			return err
+		}
+		if !config.Sparse && config.StopWhenFreeBelow == "" {
+			needed := sizeBytes * int64(max(config.Count, 1))
+			if config.TotalSize > 0 {
+				needed = config.TotalSize
+			}
+			if err := v.ValidateDiskSpace(config.OutputPath, needed); err != nil {
+				return err
+			}
+		}
+
+		// Validate file system capabilities
+		if err := v.ValidateFileSystemCapabilities(config.OutputPath, sizeBytes); err != nil {
+			return err
+		}
 	}
 
 	// Validate worker count
@@ -81,6 +186,102 @@ func (v *Validator) ValidateAll(config ValidationConfig) error {
 		return err
 	}
 
+	// Validate the IOPS limit
+	if err := v.ValidateMaxIOPS(config.MaxIOPS); err != nil {
+		return err
+	}
+
+	// Validate the time limit
+	if err := v.ValidateDuration(config.Duration); err != nil {
+		return err
+	}
+
+	// Validate the free space threshold
+	if err := v.ValidateStopWhenFreeBelow(config.StopWhenFreeBelow, config.OutputPath); err != nil {
+		return err
+	}
+
+	// Validate the memory budget
+	if err := v.ValidateMaxMemory(config.MaxMemory, config.ChunkSize); err != nil {
+		return err
+	}
+
+	// Validate the write pipeline
+	if err := v.ValidatePipeline(config.Pipeline); err != nil {
+		return err
+	}
+
+	// Validate the I/O engine
+	if err := v.ValidateIOEngine(config.IOEngine, config.OutputPath); err != nil {
+		return err
+	}
+
+	// Validate the checksum mode
+	if err := v.ValidateChecksumMode(config.ChecksumMode); err != nil {
+		return err
+	}
+	if err := v.ValidateChecksumFormat(config.ChecksumFormat, config.ChecksumMode); err != nil {
+		return err
+	}
+
+	// Validate mixed pattern options
+	if err := v.ValidateMixedOptions(config.MixedChunkSize, config.MixedPhase); err != nil {
+		return err
+	}
+	if err := v.ValidateMixedRandomRatio(config.MixedRandomRatio); err != nil {
+		return err
+	}
+
+	// Validate compressible pattern options
+	if err := v.ValidateCompressRatio(config.CompressRatio); err != nil {
+		return err
+	}
+
+	// Validate dedup pattern options
+	if err := v.ValidateDedupOptions(config.DedupRatio, config.DedupBlockSize); err != nil {
+		return err
+	}
+
+	// Validate entropy pattern options
+	if err := v.ValidateEntropy(config.Entropy); err != nil {
+		return err
+	}
+
+	// Validate the record schema
+	if err := v.ValidateSchema(config.Schema); err != nil {
+		return err
+	}
+
+	// Validate the log line format
+	if err := v.ValidateLogFormat(config.LogFormat); err != nil {
+		return err
+	}
+
+	// Validate the byte sequence of the bytes pattern
+	if err := v.ValidatePatternBytes(config.Pattern, config.PatternBytes); err != nil {
+		return err
+	}
+
+	// Validate the generator command of the exec pattern
+	if err := v.ValidateGeneratorCommand(config.Pattern, config.GeneratorCommand); err != nil {
+		return err
+	}
+
+	// Validate the training corpus of the markov pattern
+	if err := v.ValidateCorpus(config.Pattern, config.Corpus); err != nil {
+		return err
+	}
+
+	// Validate the magic header format
+	if err := v.ValidateMagic(config.Magic); err != nil {
+		return err
+	}
+
+	// Validate output encryption
+	if err := v.ValidateEncryption(config.Encrypt, config.EncryptKey); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -121,6 +322,86 @@ func (v *Validator) ValidateSize(size string) (int64, error) {
 	return sizeBytes, nil
 }
 
+// ResolveSize turns a size given as a percentage of free space, such as
+// 50%, into that share of the space available on the filesystem that will
+// hold path, written as a size ValidateSize accepts. Other sizes are
+// returned unchanged.
+func (v *Validator) ResolveSize(size, path string) (string, error) {
+	if !sizeparser.IsPercent(size) {
+		return size, nil
+	}
+
+	fraction, err := sizeparser.ParsePercent(size)
+	if err != nil {
+		return "", &ValidationError{
+			Field:   "size",
+			Message: err.Error(),
+		}
+	}
+	info, err := freeSpace(path, "size", "a percentage of free space")
+	if err != nil {
+		return "", err
+	}
+	sizeBytes := int64(fraction * float64(info.Available))
+	if sizeBytes < 1 {
+		return "", &ValidationError{
+			Field:   "size",
+			Message: fmt.Sprintf("%s of the %s free on %s is less than 1 byte",
+				size, formatSize(info.Available), info.Volume),
+			Err: diskspace.ErrInsufficient,
+		}
+	}
+	return fmt.Sprintf("%dB", sizeBytes), nil
+}
+
+// ResolveLeaveFree returns the size each of count files at path must have
+// to bring the filesystem holding them down to leaveFree bytes of free
+// space, written as a size ValidateSize accepts.
+func (v *Validator) ResolveLeaveFree(leaveFree, path string, count int) (string, error) {
+	leaveBytes, err := sizeparser.Parse(leaveFree)
+	if err != nil {
+		return "", &ValidationError{
+			Field:   "leave-free",
+			Message: fmt.Sprintf("invalid free space format: %v", err),
+		}
+	}
+	info, err := freeSpace(path, "leave-free", "space to leave free")
+	if err != nil {
+		return "", err
+	}
+
+	sizeBytes := (info.Available - leaveBytes) / int64(max(count, 1))
+	if sizeBytes < 1 {
+		return "", &ValidationError{
+			Field:   "leave-free",
+			Message: fmt.Sprintf("only %s free on %s, no more than the %s to leave",
+				formatSize(info.Available), info.Volume, formatSize(leaveBytes)),
+			Err: diskspace.ErrInsufficient,
+		}
+	}
+	return fmt.Sprintf("%dB", sizeBytes), nil
+}
+
+// freeSpace queries the space available for the file output path, for a
+// setting of field, described by what, that is relative to it.
+func freeSpace(path, field, what string) (*diskspace.Info, error) {
+	if writer.IsNetworkTarget(path) || writer.IsObjectTarget(path) || device.IsBlockDevice(path) {
+		return nil, &ValidationError{
+			Field:   field,
+			Message: what + " only applies to file outputs",
+		}
+	}
+
+	info, err := diskspace.Query(filepath.Dir(path))
+	if err != nil {
+		return nil, &ValidationError{
+			Field:   "disk_space",
+			Message: fmt.Sprintf("failed to check disk space: %v", err),
+		}
+	}
+	return info, nil
+}
+
 // ValidatePattern validates the data generation pattern.
 func (v *Validator) ValidatePattern(pattern string) error {
 	if pattern == "" {
@@ -130,18 +411,43 @@ func (v *Validator) ValidatePattern(pattern string) error {
 		}
 	}
 
+	// Weighted composites are validated as a whole by the generator package
+	if _, ok, err := generator.ParseComposite(pattern); ok {
+		if err != nil {
+			return &ValidationError{
+				Field:   "pattern",
+				Message: err.Error(),
+			}
+		}
+		return nil
+	}
+
 	// Use the generator package to validate available patterns
 	availablePatterns := generator.AvailablePatterns()
+	name, _, _ := strings.Cut(pattern, ":")
+	known := false
 	for _, valid := range availablePatterns {
-		if pattern == valid {
-			return nil
+		if name == valid {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return &ValidationError{
+			Field:   "pattern",
+			Message: fmt.Sprintf("invalid pattern '%s' (available: %v)", pattern, availablePatterns),
 		}
 	}
 
-	return &ValidationError{
-		Field:   "pattern",
-		Message: fmt.Sprintf("invalid pattern '%s' (available: %v)", pattern, availablePatterns),
+	// Validate any pattern-specific options in the specification
+	if _, _, err := generator.ParsePattern(pattern, generator.Options{}); err != nil {
+		return &ValidationError{
+			Field:   "pattern",
+			Message: err.Error(),
+		}
 	}
+
+	return nil
 }
 
 // ValidateOutputPath validates the output file path and directory permissions.
@@ -199,25 +505,46 @@ func (v *Validator) validateDirectory(dir string) error {
 }
 
 // ValidateDiskSpace checks if there's sufficient disk space for the file.
+// Block devices are checked against their capacity instead.
 func (v *Validator) ValidateDiskSpace(path string, size int64) error {
-	available, total, err := getDiskSpaceInfo(path)
+	if device.IsBlockDevice(path) {
+		capacity, err := device.Size(path)
+		if err != nil {
+			return &ValidationError{
+				Field:   "disk_space",
+				Message: err.Error(),
+			}
+		}
+		if size > capacity {
+			return &ValidationError{
+				Field:   "disk_space",
+				Message: fmt.Sprintf("device %s is too small: need %s, have %s",
+					path, formatSize(size), formatSize(capacity)),
+				Err: diskspace.ErrInsufficient,
+			}
+		}
+		return nil
+	}
+
+	info, err := diskspace.Query(filepath.Dir(path))
 	if err != nil {
 		return &ValidationError{
 			Field:   "disk_space",
 			Message: fmt.Sprintf("failed to check disk space: %v", err),
 		}
 	}
-	if size > available {
+	if size > info.Available {
 		return &ValidationError{
 			Field:   "disk_space",
-			Message: fmt.Sprintf("insufficient disk space: need %s, have %s", 
-				formatSize(size), formatSize(available)),
+			Message: fmt.Sprintf("insufficient disk space on %s: need %s, have %s", 
+				info.Volume, formatSize(size), formatSize(info.Available)),
+			Err: diskspace.ErrInsufficient,
 		}
 	}
 
 	// Warn if less than 10% free space will remain
-	remaining := available - size
-	if remaining < total/10 {
+	remaining := info.Available - size
+	if remaining < info.Total/10 {
 		// This is a warning, not an error, so we don't return it
 		// In a real implementation, we might want a separate warning system
 	}
@@ -225,9 +552,87 @@ func (v *Validator) ValidateDiskSpace(path string, size int64) error {
 	return nil
 }
 
+// ValidateSparse checks that sparse output is not requested for a block
+// device, which keeps its old contents wherever writes are skipped.
+func (v *Validator) ValidateSparse(path string, sparse bool) error {
+	if sparse && device.IsBlockDevice(path) {
+		return &ValidationError{
+			Field:   "sparse",
+			Message: fmt.Sprintf("sparse output is not supported for block device %s", path),
+		}
+	}
+	return nil
+}
+
+// ValidateNetworkTarget checks that a tcp:// or unix:// output is well formed
+// and that no file-only option such as sparse output was requested.
+func (v *Validator) ValidateNetworkTarget(target string, sparse bool) error {
+	if _, _, err := writer.ParseNetworkTarget(target); err != nil {
+		return &ValidationError{
+			Field:   "output",
+			Message: err.Error(),
+		}
+	}
+	if sparse {
+		return &ValidationError{
+			Field:   "sparse",
+			Message: "sparse output is not supported for network targets",
+		}
+	}
+	return nil
+}
+
+// ValidateObjectTarget checks that an s3://, gs:// or az:// output is well
+// formed and that its chunks, uploaded one per part, fit the store's
+// multipart upload limits.
+func (v *Validator) ValidateObjectTarget(target string, size int64, chunkSize string, sparse bool) error {
+	scheme, _, _, limits, err := writer.ParseObjectTarget(target)
+	if err != nil {
+		return &ValidationError{
+			Field:   "output",
+			Message: err.Error(),
+		}
+	}
+	if sparse {
+		return &ValidationError{
+			Field:   "sparse",
+			Message: "sparse output is not supported for object store targets",
+		}
+	}
+	if chunkSize == "auto" {
+		return &ValidationError{
+			Field:   "chunk-size",
+			Message: "automatic chunk size is not supported for object store targets, whose parts must be equal-sized",
+		}
+	}
+
+	// An invalid chunk size is reported by ValidateChunkSize
+	chunkBytes, err := sizeparser.Parse(chunkSize)
+	if err != nil || chunkBytes <= 0 {
+		return nil
+	}
+
+	parts := (size + chunkBytes - 1) / chunkBytes
+	if chunkBytes > limits.MaxPart || (parts > 1 && chunkBytes < limits.MinPart) {
+		return &ValidationError{
+			Field:   "chunk-size",
+			Message: fmt.Sprintf("chunk size must be between %s and %s for %s uploads",
+				formatSize(limits.MinPart), formatSize(limits.MaxPart), scheme),
+		}
+	}
+	if parts > limits.MaxParts {
+		return &ValidationError{
+			Field:   "chunk-size",
+			Message: fmt.Sprintf("%s uploads are limited to %d parts, but %s chunks need %d (use a larger --chunk-size)",
+				scheme, limits.MaxParts, chunkSize, parts),
+		}
+	}
+	return nil
+}
+
 // ValidateFileSystemCapabilities checks file system limitations.
 func (v *Validator) ValidateFileSystemCapabilities(path string, size int64) error {
-	_, _, err := getDiskSpaceInfo(path)
+	_, err := diskspace.Query(filepath.Dir(path))
 	if err != nil {
 		return &ValidationError{
 			Field:   "filesystem",
@@ -285,7 +690,214 @@ func (v *Validator) ValidateWorkers(workers int) error {
 	return nil
 }
 
-// ValidateChunkSize validates the chunk size specification.
+// ValidateMaxIOPS validates the write operation limit. Zero means no limit.
+func (v *Validator) ValidateMaxIOPS(iops int) error {
+	if iops < 0 {
+		return &ValidationError{
+			Field:   "max-iops",
+			Message: fmt.Sprintf("IOPS limit must not be negative, got %d", iops),
+		}
+	}
+	return nil
+}
+
+// ValidateCount validates the number of files to generate. Several files
+// are only generated to files, and together must not exceed the largest
+// size of 10PB. Zero means one file.
+func (v *Validator) ValidateCount(count int, size int64, path string) error {
+	if count < 0 {
+		return &ValidationError{
+			Field:   "count",
+			Message: fmt.Sprintf("file count must not be negative, got %d", count),
+		}
+	}
+	if count <= 1 {
+		return nil
+	}
+	if writer.IsNetworkTarget(path) || writer.IsObjectTarget(path) || device.IsBlockDevice(path) {
+		return &ValidationError{
+			Field:   "count",
+			Message: "several files can only be generated to file outputs",
+		}
+	}
+	const maxTotalSize = int64(10) * (1024 * 1024 * 1024 * 1024 * 1024) // 10PB
+	if size > maxTotalSize/int64(count) {
+		return &ValidationError{
+			Field:   "count",
+			Message: fmt.Sprintf("%d files of %s exceed 10PB in total", count, formatSize(size)),
+		}
+	}
+	return nil
+}
+
+// ValidateNames checks that the files of a multi-file run all have different
+// paths, which an output template doesn't guarantee: one without {seq} or a
+// long enough {rand} can give several files the same name.
+func (v *Validator) ValidateNames(names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		clean := filepath.Clean(name)
+		if seen[clean] {
+			return &ValidationError{
+				Field:   "output",
+				Message: fmt.Sprintf("template gives more than one file the name %s; add {seq} or a longer {rand}", clean),
+			}
+		}
+		seen[clean] = true
+	}
+	return nil
+}
+
+// ValidateDuration validates the time limit. Zero means no limit.
+func (v *Validator) ValidateDuration(duration time.Duration) error {
+	if duration < 0 {
+		return &ValidationError{
+			Field:   "duration",
+			Message: fmt.Sprintf("time limit must not be negative, got %s", duration),
+		}
+	}
+	return nil
+}
+
+// ValidateStopWhenFreeBelow validates the free space threshold to stop at.
+// It only applies to files, whose filesystem must have more free space than
+// the threshold to start with. Empty means no threshold and is always valid.
+func (v *Validator) ValidateStopWhenFreeBelow(threshold, path string) error {
+	if threshold == "" {
+		return nil
+	}
+
+	bytes, err := sizeparser.Parse(threshold)
+	if err != nil {
+		return &ValidationError{
+			Field:   "stop-when-free-below",
+			Message: fmt.Sprintf("invalid free space threshold format: %v", err),
+		}
+	}
+	if writer.IsNetworkTarget(path) || writer.IsObjectTarget(path) || device.IsBlockDevice(path) {
+		return &ValidationError{
+			Field:   "stop-when-free-below",
+			Message: "a free space threshold only applies to file outputs",
+		}
+	}
+
+	info, err := diskspace.Query(filepath.Dir(path))
+	if err != nil {
+		return &ValidationError{
+			Field:   "disk_space",
+			Message: fmt.Sprintf("failed to check disk space: %v", err),
+		}
+	}
+	if info.Available <= bytes {
+		return &ValidationError{
+			Field:   "stop-when-free-below",
+			Message: fmt.Sprintf("only %s free on %s, already below %s",
+				formatSize(info.Available), info.Volume, formatSize(bytes)),
+		}
+	}
+	return nil
+}
+
+// ValidateMaxMemory validates the memory budget for chunk buffers, which must
+// hold at least one chunk. Empty means no limit and is always valid.
+func (v *Validator) ValidateMaxMemory(maxMemory, chunkSize string) error {
+	if maxMemory == "" {
+		return nil
+	}
+
+	budget, err := sizeparser.Parse(maxMemory)
+	if err != nil {
+		return &ValidationError{
+			Field:   "max-memory",
+			Message: fmt.Sprintf("invalid memory budget format: %v", err),
+		}
+	}
+
+	// An invalid chunk size is reported by ValidateChunkSize
+	chunk, err := sizeparser.Parse(chunkSize)
+	if err == nil && budget < chunk {
+		return &ValidationError{
+			Field:   "max-memory",
+			Message: fmt.Sprintf("memory budget %s is smaller than one chunk (%s)", maxMemory, chunkSize),
+		}
+	}
+	return nil
+}
+
+// ValidatePipeline validates the write pipeline mode. Empty selects the
+// default and is always valid.
+func (v *Validator) ValidatePipeline(mode string) error {
+	switch mode {
+	case "", "direct", "channel":
+		return nil
+	default:
+		return &ValidationError{
+			Field:   "pipeline",
+			Message: fmt.Sprintf("invalid pipeline '%s' (available: direct, channel)", mode),
+		}
+	}
+}
+
+// ValidateIOEngine validates the I/O engine, which only file and block device
+// outputs are written with. Empty selects the default and is always valid.
+func (v *Validator) ValidateIOEngine(engine, path string) error {
+	if engine == "" || engine == writer.DefaultEngine {
+		return nil
+	}
+	if !slices.Contains(writer.Engines, engine) {
+		return &ValidationError{
+			Field:   "io-engine",
+			Message: fmt.Sprintf("invalid I/O engine '%s' (available: %s)", engine, strings.Join(writer.Engines, ", ")),
+		}
+	}
+	if writer.IsNetworkTarget(path) || writer.IsObjectTarget(path) {
+		return &ValidationError{
+			Field:   "io-engine",
+			Message: "an I/O engine only applies to file and block device outputs",
+		}
+	}
+	return nil
+}
+
+// ValidateChecksumMode validates how the full-file checksum is computed.
+// Empty selects the default and is always valid.
+func (v *Validator) ValidateChecksumMode(mode string) error {
+	switch mode {
+	case "", "stream", "ordered", "tree":
+		return nil
+	default:
+		return &ValidationError{
+			Field:   "checksum-mode",
+			Message: fmt.Sprintf("invalid checksum mode '%s' (available: stream, ordered, tree)", mode),
+		}
+	}
+}
+
+// ValidateChecksumFormat validates the checksum file format. The sha256sum
+// formats record a SHA-256 of the whole file, which tree mode doesn't
+// compute. Empty selects the default and is always valid.
+func (v *Validator) ValidateChecksumFormat(format, mode string) error {
+	switch format {
+	case "", "trasher":
+		return nil
+	case "gnu", "bsd":
+		if mode == "tree" {
+			return &ValidationError{
+				Field:   "checksum-format",
+				Message: fmt.Sprintf("checksum format '%s' needs the stream checksum mode", format),
+			}
+		}
+		return nil
+	default:
+		return &ValidationError{
+			Field:   "checksum-format",
+			Message: fmt.Sprintf("invalid checksum format '%s' (available: trasher, gnu, bsd)", format),
+		}
+	}
+}
+
+// ValidateChunkSize validates the chunk size specification. "auto" lets the
+// worker pool tune the chunk size while it runs.
 func (v *Validator) ValidateChunkSize(chunkSize string) error {
 	if chunkSize == "" {
 		return &ValidationError{
@@ -293,6 +905,9 @@ func (v *Validator) ValidateChunkSize(chunkSize string) error {
 			Message: "chunk size cannot be empty",
 		}
 	}
+	if chunkSize == "auto" {
+		return nil
+	}
 
 	size, err := sizeparser.Parse(chunkSize)
 	if err != nil {
@@ -322,6 +937,52 @@ func (v *Validator) ValidateChunkSize(chunkSize string) error {
 	return nil
 }
 
+// ValidateMixedOptions validates the mixed pattern's run length and starting phase.
+// Empty values select the defaults and are always valid.
+func (v *Validator) ValidateMixedOptions(mixedChunkSize, phase string) error {
+	if mixedChunkSize != "" {
+		size, err := sizeparser.Parse(mixedChunkSize)
+		if err != nil {
+			return &ValidationError{
+				Field:   "mixed_chunk",
+				Message: fmt.Sprintf("invalid mixed chunk size format: %v", err),
+			}
+		}
+
+		if size > generator.MaxMixedChunkSize {
+			return &ValidationError{
+				Field:   "mixed_chunk",
+				Message: fmt.Sprintf("mixed chunk size must be at most %s", formatSize(generator.MaxMixedChunkSize)),
+			}
+		}
+	}
+
+	switch phase {
+	case "", "random", "zero":
+		return nil
+	default:
+		return &ValidationError{
+			Field:   "mixed_phase",
+			Message: fmt.Sprintf("invalid mixed phase '%s' (available: random, zero)", phase),
+		}
+	}
+}
+
+// ValidateMixedRandomRatio validates the fraction of the mixed pattern's
+// output that is random. Zero selects the default and is always valid.
+func (v *Validator) ValidateMixedRandomRatio(ratio float64) error {
+	if ratio == 0 {
+		return nil
+	}
+	if ratio < 0 || ratio >= 1 {
+		return &ValidationError{
+			Field:   "mixed_random_ratio",
+			Message: fmt.Sprintf("mixed random ratio must be between 0 and 1 exclusive, got %g (use the random or zero pattern instead)", ratio),
+		}
+	}
+	return nil
+}
+
 // formatSize formats a byte count into a human-readable string.
 func formatSize(bytes int64) string {
 	if bytes == 0 {
@@ -358,14 +1019,15 @@ func ValidateConfiguration(size, pattern, outputPath string, workers int, chunkS
 
 // GetSystemInfo returns information about the system capabilities.
 func GetSystemInfo(path string) (*SystemInfo, error) {
-	available, total, err := getDiskSpaceInfo(path)
+	info, err := diskspace.Query(filepath.Dir(path))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get system info: %v", err)
 	}
 
 	return &SystemInfo{
-		AvailableSpace: available,
-		TotalSpace:     total,
+		AvailableSpace: info.Available,
+		TotalSpace:     info.Total,
+		Volume:         info.Volume,
 		CPUCount:       runtime.NumCPU(),
 		MaxWorkers:     runtime.NumCPU() * 4,
 	}, nil
@@ -375,6 +1037,259 @@ func GetSystemInfo(path string) (*SystemInfo, error) {
 type SystemInfo struct {
 	AvailableSpace int64
 	TotalSpace     int64
+	Volume         string
 	CPUCount       int
 	MaxWorkers     int
-}
\ No newline at end of file
+}
+
+// ValidateCompressRatio validates the compressible pattern's target ratio.
+// Zero selects the default and is always valid.
+func (v *Validator) ValidateCompressRatio(ratio float64) error {
+	if ratio == 0 {
+		return nil
+	}
+	if ratio < 1 || ratio > generator.MaxCompressRatio {
+		return &ValidationError{
+			Field:   "compress_ratio",
+			Message: fmt.Sprintf("compression ratio must be between 1 and %g, got %g", generator.MaxCompressRatio, ratio),
+		}
+	}
+	return nil
+}
+
+// ValidateEntropy validates the entropy pattern's target entropy.
+// Zero selects the default and is always valid.
+func (v *Validator) ValidateEntropy(entropy float64) error {
+	if entropy < 0 || entropy > 1 {
+		return &ValidationError{
+			Field:   "entropy",
+			Message: fmt.Sprintf("entropy must be between 0 and 1, got %g", entropy),
+		}
+	}
+	return nil
+}
+
+// ValidateSchema validates a record schema. Empty selects the default and is
+// always valid.
+func (v *Validator) ValidateSchema(schema string) error {
+	if schema == "" {
+		return nil
+	}
+	if _, err := generator.ParseSchema(schema); err != nil {
+		return &ValidationError{
+			Field:   "schema",
+			Message: fmt.Sprintf("invalid schema: %v", err),
+		}
+	}
+	return nil
+}
+
+// ValidateLogFormat validates the logs pattern's line format. Empty selects
+// the default and is always valid.
+func (v *Validator) ValidateLogFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	for _, f := range generator.LogFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return &ValidationError{
+		Field:   "log_format",
+		Message: fmt.Sprintf("invalid log format '%s' (available: %s)", format, strings.Join(generator.LogFormats, ", ")),
+	}
+}
+
+// ValidatePatternBytes validates the hex byte sequence given for the bytes
+// pattern. A sequence is only accepted with the bytes pattern, and the bytes
+// pattern needs one unless its specification carries it (bytes:hex=...).
+func (v *Validator) ValidatePatternBytes(pattern, hexBytes string) error {
+	name, _, _ := strings.Cut(pattern, ":")
+	if hexBytes == "" {
+		if name == "bytes" && !strings.Contains(pattern, "hex=") {
+			return &ValidationError{
+				Field:   "pattern_bytes",
+				Message: "the bytes pattern needs a byte sequence, e.g. --pattern-bytes 0xDEADBEEF",
+			}
+		}
+		return nil
+	}
+
+	if name != "bytes" {
+		return &ValidationError{
+			Field:   "pattern_bytes",
+			Message: fmt.Sprintf("a byte sequence can only be used with the bytes pattern, not '%s'", name),
+		}
+	}
+	if _, err := generator.ParseHexBytes(hexBytes); err != nil {
+		return &ValidationError{
+			Field:   "pattern_bytes",
+			Message: err.Error(),
+		}
+	}
+	return nil
+}
+
+// ValidateGeneratorCommand validates the command of the exec pattern. A
+// command is only accepted with the exec pattern, which needs one, and its
+// program must be found.
+func (v *Validator) ValidateGeneratorCommand(pattern, command string) error {
+	name, _, _ := strings.Cut(pattern, ":")
+	args := generator.ParseCommand(command)
+	if len(args) == 0 {
+		if name == "exec" {
+			return &ValidationError{
+				Field:   "generator_cmd",
+				Message: "the exec pattern needs a command, e.g. --generator-cmd ./mygen",
+			}
+		}
+		return nil
+	}
+
+	if name != "exec" {
+		return &ValidationError{
+			Field:   "generator_cmd",
+			Message: fmt.Sprintf("a generator command can only be used with the exec pattern, not '%s'", name),
+		}
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return &ValidationError{
+			Field:   "generator_cmd",
+			Message: fmt.Sprintf("generator command not found: %v", err),
+		}
+	}
+	return nil
+}
+
+// ValidateCorpus validates the training corpus of the markov pattern. A
+// corpus is only accepted with the markov pattern, and it must be readable
+// and contain whole sentences. Empty means the built-in corpus.
+func (v *Validator) ValidateCorpus(pattern, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	name, _, _ := strings.Cut(pattern, ":")
+	if name != "markov" {
+		return &ValidationError{
+			Field:   "corpus",
+			Message: fmt.Sprintf("a corpus can only be used with the markov pattern, not '%s'", name),
+		}
+	}
+
+	corpus, err := os.ReadFile(path)
+	if err != nil {
+		return &ValidationError{
+			Field:   "corpus",
+			Message: fmt.Sprintf("cannot read corpus: %v", err),
+		}
+	}
+	if _, err := generator.TrainMarkov(string(corpus)); err != nil {
+		return &ValidationError{
+			Field:   "corpus",
+			Message: err.Error(),
+		}
+	}
+	return nil
+}
+
+// ValidateMagic validates the file format of the magic header. Empty means
+// no header and is always valid.
+func (v *Validator) ValidateMagic(format string) error {
+	if format == "" {
+		return nil
+	}
+	if _, err := generator.MagicHeader(format); err != nil {
+		return &ValidationError{
+			Field:   "magic",
+			Message: err.Error(),
+		}
+	}
+	return nil
+}
+
+// ValidateEncryption validates the output cipher and key. An empty cipher
+// means no encryption; a key then cannot be given.
+func (v *Validator) ValidateEncryption(cipherName, key string) error {
+	if cipherName == "" {
+		if key != "" {
+			return &ValidationError{
+				Field:   "encrypt-key",
+				Message: "an encryption key can only be used with --encrypt",
+			}
+		}
+		return nil
+	}
+
+	if !slices.Contains(generator.EncryptCiphers, cipherName) {
+		return &ValidationError{
+			Field:   "encrypt",
+			Message: fmt.Sprintf("invalid cipher '%s' (available: %s)", cipherName, strings.Join(generator.EncryptCiphers, ", ")),
+		}
+	}
+	if key != "" {
+		if _, err := generator.ParseEncryptKey(key); err != nil {
+			return &ValidationError{
+				Field:   "encrypt-key",
+				Message: err.Error(),
+			}
+		}
+	}
+	return nil
+}
+
+// ValidatePatternMap validates a pattern map and checks that its regions
+// cover a file of the given size. Empty means no map and is always valid.
+func (v *Validator) ValidatePatternMap(spec string, size int64) error {
+	if spec == "" {
+		return nil
+	}
+
+	regions, err := generator.ParsePatternMap(spec)
+	if err != nil {
+		return &ValidationError{
+			Field:   "pattern_map",
+			Message: err.Error(),
+		}
+	}
+
+	if last := regions[len(regions)-1]; last.End != generator.MapEnd && last.End < size {
+		return &ValidationError{
+			Field: "pattern_map",
+			Message: fmt.Sprintf("pattern map covers only %s of the %s file (end the last region with 'end')",
+				formatSize(last.End), formatSize(size)),
+		}
+	}
+	return nil
+}
+
+// ValidateDedupOptions validates the dedup pattern's duplicate ratio and block
+// size. Zero and empty values select the defaults and are always valid.
+func (v *Validator) ValidateDedupOptions(ratio float64, blockSize string) error {
+	if ratio < 0 || ratio > 1 {
+		return &ValidationError{
+			Field:   "dedup_ratio",
+			Message: fmt.Sprintf("dedup ratio must be between 0 and 1, got %g", ratio),
+		}
+	}
+
+	if blockSize != "" {
+		size, err := sizeparser.Parse(blockSize)
+		if err != nil {
+			return &ValidationError{
+				Field:   "dedup_block",
+				Message: fmt.Sprintf("invalid dedup block size format: %v", err),
+			}
+		}
+		if size < generator.MinDedupBlockSize || size > generator.MaxDedupBlockSize {
+			return &ValidationError{
+				Field: "dedup_block",
+				Message: fmt.Sprintf("dedup block size must be between %s and %s",
+					formatSize(generator.MinDedupBlockSize), formatSize(generator.MaxDedupBlockSize)),
+			}
+		}
+	}
+
+	return nil
+}
@@ -1,11 +1,17 @@
 package validation
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/diskspace"
+	"github.com/maxkimambo/trasher/internal/writer"
 )
 
 func TestNewValidator(t *testing.T) {
@@ -52,6 +58,78 @@ func TestValidateSize(t *testing.T) {
 	}
 }
 
+func TestResolveSize(t *testing.T) {
+	validator := NewValidator()
+	path := filepath.Join(t.TempDir(), "test.bin")
+
+	if got, err := validator.ResolveSize("1GB", path); err != nil || got != "1GB" {
+		t.Errorf("expected sizes to be returned unchanged, got %q, %v", got, err)
+	}
+
+	info, err := diskspace.Query(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := validator.ResolveSize("50%", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sizeBytes, err := validator.ValidateSize(got)
+	if err != nil {
+		t.Fatalf("resolved size %q is invalid: %v", got, err)
+	}
+	// Free space changes as other processes write, so allow some slack
+	if half := info.Available / 2; sizeBytes < half-half/100 || sizeBytes > half+half/100 {
+		t.Errorf("expected about %d bytes, got %d", half, sizeBytes)
+	}
+
+	for _, size := range []string{"0%", "150%", "abc%"} {
+		if _, err := validator.ResolveSize(size, path); err == nil {
+			t.Errorf("expected an error for %s", size)
+		}
+	}
+	if _, err := validator.ResolveSize("50%", "tcp://localhost:9000"); err == nil {
+		t.Error("expected an error for a network output")
+	}
+}
+
+func TestResolveLeaveFree(t *testing.T) {
+	validator := NewValidator()
+	path := filepath.Join(t.TempDir(), "test.bin")
+
+	info, err := diskspace.Query(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	leave := info.Available / 2
+	for _, count := range []int{1, 4} {
+		got, err := validator.ResolveLeaveFree(fmt.Sprintf("%dB", leave), path, count)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		sizeBytes, err := validator.ValidateSize(got)
+		if err != nil {
+			t.Fatalf("resolved size %q is invalid: %v", got, err)
+		}
+		// Free space changes as other processes write, so allow some slack
+		want := (info.Available - leave) / int64(count)
+		if sizeBytes < want-want/100 || sizeBytes > want+want/100 {
+			t.Errorf("%d files: expected about %d bytes each, got %d", count, want, sizeBytes)
+		}
+	}
+
+	_, err = validator.ResolveLeaveFree("10PB", path, 1)
+	if !errors.Is(err, diskspace.ErrInsufficient) {
+		t.Errorf("expected an insufficient space error, got %v", err)
+	}
+	if _, err := validator.ResolveLeaveFree("lots", path, 1); err == nil {
+		t.Error("expected an error for an invalid size")
+	}
+	if _, err := validator.ResolveLeaveFree("1GB", "s3://bucket/key", 1); err == nil {
+		t.Error("expected an error for an object output")
+	}
+}
+
 func TestValidatePattern(t *testing.T) {
 	validator := NewValidator()
 
@@ -64,9 +142,20 @@ func TestValidatePattern(t *testing.T) {
 		{"valid sequential", "sequential", false},
 		{"valid zero", "zero", false},
 		{"valid mixed", "mixed", false},
+		{"valid aa55", "aa55", false},
+		{"valid walking-ones", "walking-ones", false},
 		{"invalid pattern", "invalid", true},
 		{"empty pattern", "", true},
 		{"case sensitive", "Random", true}, // patterns are case-sensitive
+		{"seeded random", "random:seed=42", false},
+		{"mixed with options", "mixed:ratio=70:30,chunk=4KB", false},
+		{"unknown option", "zero:seed=1", true},
+		{"invalid option value", "random:seed=abc", true},
+		{"unknown pattern with options", "bogus:seed=1", true},
+		{"weighted composite", "random:0.5,zero:0.3,text:0.2", false},
+		{"composite unknown pattern", "random:0.5,bogus:0.5", true},
+		{"composite missing weight", "random,zero", true},
+		{"composite zero weight", "random:0,zero:1", true},
 	}
 
 	for _, test := range tests {
@@ -181,6 +270,8 @@ func TestValidateDiskSpace(t *testing.T) {
 	err = validator.ValidateDiskSpace(testFile, 1024*1024*1024*1024*1024) // 1PB
 	if err == nil {
 		t.Log("Warning: 1PB validation passed - system has very large available space")
+	} else if !errors.Is(err, diskspace.ErrInsufficient) {
+		t.Errorf("expected the error to be diskspace.ErrInsufficient, got %v", err)
 	}
 }
 
@@ -236,6 +327,7 @@ func TestValidateChunkSize(t *testing.T) {
 		{"too small", "512B", true, "chunk size must be at least"},
 		{"too large", "2GB", true, "chunk size must be at most"},
 		{"maximum chunk size", "1GB", false, ""},
+		{"auto chunk size", "auto", false, ""},
 	}
 
 	for _, test := range tests {
@@ -257,6 +349,423 @@ func TestValidateChunkSize(t *testing.T) {
 	}
 }
 
+func TestValidateMixedOptions(t *testing.T) {
+	validator := NewValidator()
+
+	tests := []struct {
+		name        string
+		chunkSize   string
+		phase       string
+		expectError bool
+		expectedMsg string
+	}{
+		{"defaults", "", "", false, ""},
+		{"custom chunk", "4MB", "", false, ""},
+		{"zero phase", "1KB", "zero", false, ""},
+		{"random phase", "", "random", false, ""},
+		{"invalid chunk", "lots", "", true, "invalid mixed chunk size format"},
+		{"too large chunk", "2GB", "", true, "mixed chunk size must be at most"},
+		{"invalid phase", "", "sideways", true, "invalid mixed phase"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validator.ValidateMixedOptions(test.chunkSize, test.phase)
+
+			if test.expectError && err == nil {
+				t.Errorf("expected error for chunk '%s' phase '%s'", test.chunkSize, test.phase)
+			}
+			if !test.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if test.expectError && test.expectedMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+					t.Errorf("expected error message to contain '%s', got: %v", test.expectedMsg, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateMixedRandomRatio(t *testing.T) {
+	validator := NewValidator()
+
+	for _, ratio := range []float64{0, 0.01, 0.7, 0.99} {
+		if err := validator.ValidateMixedRandomRatio(ratio); err != nil {
+			t.Errorf("unexpected error for ratio %g: %v", ratio, err)
+		}
+	}
+	for _, ratio := range []float64{-0.3, 1, 70} {
+		err := validator.ValidateMixedRandomRatio(ratio)
+		if err == nil || !strings.Contains(err.Error(), "mixed random ratio must be between 0 and 1") {
+			t.Errorf("expected range error for ratio %g, got %v", ratio, err)
+		}
+	}
+}
+
+func TestValidateCompressRatio(t *testing.T) {
+	validator := NewValidator()
+
+	for _, ratio := range []float64{0, 1, 2.5, 1000} {
+		if err := validator.ValidateCompressRatio(ratio); err != nil {
+			t.Errorf("unexpected error for ratio %g: %v", ratio, err)
+		}
+	}
+	for _, ratio := range []float64{0.5, -2, 1001} {
+		err := validator.ValidateCompressRatio(ratio)
+		if err == nil || !strings.Contains(err.Error(), "compression ratio must be between") {
+			t.Errorf("expected range error for ratio %g, got %v", ratio, err)
+		}
+	}
+}
+
+func TestValidateEntropy(t *testing.T) {
+	validator := NewValidator()
+
+	for _, entropy := range []float64{0, 0.01, 0.6, 1} {
+		if err := validator.ValidateEntropy(entropy); err != nil {
+			t.Errorf("unexpected error for entropy %g: %v", entropy, err)
+		}
+	}
+	for _, entropy := range []float64{-0.5, 1.2, 8} {
+		err := validator.ValidateEntropy(entropy)
+		if err == nil || !strings.Contains(err.Error(), "entropy must be between") {
+			t.Errorf("expected range error for entropy %g, got %v", entropy, err)
+		}
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	validator := NewValidator()
+
+	for _, schema := range []string{"", "id,timestamp", "id,user:string,n:int"} {
+		if err := validator.ValidateSchema(schema); err != nil {
+			t.Errorf("unexpected error for schema %q: %v", schema, err)
+		}
+	}
+	for _, schema := range []string{",", "name:blob", "id,id"} {
+		err := validator.ValidateSchema(schema)
+		if err == nil || !strings.Contains(err.Error(), "invalid schema") {
+			t.Errorf("expected schema error for %q, got %v", schema, err)
+		}
+	}
+}
+
+func TestValidateLogFormat(t *testing.T) {
+	validator := NewValidator()
+
+	for _, format := range []string{"", "syslog", "apache"} {
+		if err := validator.ValidateLogFormat(format); err != nil {
+			t.Errorf("unexpected error for format %q: %v", format, err)
+		}
+	}
+	err := validator.ValidateLogFormat("nginx")
+	if err == nil || !strings.Contains(err.Error(), "invalid log format 'nginx'") {
+		t.Errorf("expected log format error, got %v", err)
+	}
+}
+
+func TestValidatePatternMap(t *testing.T) {
+	validator := NewValidator()
+
+	tests := []struct {
+		name        string
+		spec        string
+		size        int64
+		expectedMsg string
+	}{
+		{"no map", "", 1024, ""},
+		{"runs to end", "0-1KB=zero,1KB-end=random", 1 << 20, ""},
+		{"covers size", "0-1KB=zero,1KB-1MB=random", 1 << 20, ""},
+		{"covers more than size", "0-1KB=zero,1KB-1GB=random", 1 << 20, ""},
+		{"too short", "0-1KB=zero,1KB-512KB=random", 1 << 20, "covers only"},
+		{"gap", "0-1KB=zero,2KB-end=random", 1 << 20, "must start where the previous one ends"},
+		{"unknown pattern", "0-end=bogus", 1 << 20, "unknown pattern"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validator.ValidatePatternMap(test.spec, test.size)
+			if test.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateGeneratorCommand(t *testing.T) {
+	validator := NewValidator()
+
+	tests := []struct {
+		name        string
+		pattern     string
+		command     string
+		expectedMsg string
+	}{
+		{"no command", "random", "", ""},
+		{"exec with command", "exec", os.Args[0] + " --flag", ""},
+		{"exec without command", "exec", "  ", "needs a command"},
+		{"command without exec", "random", os.Args[0], "only be used with the exec pattern"},
+		{"missing program", "exec", "/nonexistent/trasher-generator", "not found"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validator.ValidateGeneratorCommand(test.pattern, test.command)
+			if test.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateCorpus(t *testing.T) {
+	validator := NewValidator()
+
+	dir := t.TempDir()
+	prose := filepath.Join(dir, "prose.txt")
+	os.WriteFile(prose, []byte("The cat sat on the mat. It was happy."), 0644)
+	fragments := filepath.Join(dir, "fragments.txt")
+	os.WriteFile(fragments, []byte("no sentences here"), 0644)
+
+	tests := []struct {
+		name        string
+		pattern     string
+		path        string
+		expectedMsg string
+	}{
+		{"built-in corpus", "markov", "", ""},
+		{"corpus file", "markov:seed=3", prose, ""},
+		{"other pattern", "text", prose, "only be used with the markov pattern"},
+		{"missing file", "markov", filepath.Join(dir, "missing.txt"), "cannot read corpus"},
+		{"no sentences", "markov", fragments, "at least one sentence"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validator.ValidateCorpus(test.pattern, test.path)
+			if test.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateSparse(t *testing.T) {
+	validator := NewValidator()
+	path := filepath.Join(t.TempDir(), "sparse.dat")
+
+	if err := validator.ValidateSparse(path, true); err != nil {
+		t.Errorf("unexpected error for a regular file: %v", err)
+	}
+	if err := validator.ValidateSparse(path, false); err != nil {
+		t.Errorf("unexpected error without sparse output: %v", err)
+	}
+}
+
+func TestValidateNetworkTarget(t *testing.T) {
+	validator := NewValidator()
+
+	tests := []struct {
+		name        string
+		target      string
+		sparse      bool
+		expectedMsg string
+	}{
+		{"tcp", "tcp://receiver:9000", false, ""},
+		{"unix", "unix:///run/receiver.sock", false, ""},
+		{"missing port", "tcp://receiver", false, "must be tcp://host:port"},
+		{"missing path", "unix://", false, "missing socket path"},
+		{"sparse", "tcp://receiver:9000", true, "not supported for network targets"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validator.ValidateNetworkTarget(test.target, test.sparse)
+			if test.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateObjectTarget(t *testing.T) {
+	validator := NewValidator()
+	const gb = 1024 * 1024 * 1024
+
+	tests := []struct {
+		name        string
+		target      string
+		size        int64
+		chunkSize   string
+		sparse      bool
+		expectedMsg string
+	}{
+		{"s3", "s3://bucket/test.dat", 10 * gb, "64MB", false, ""},
+		{"single small part", "s3://bucket/test.dat", 1024, "1KB", false, ""},
+		{"azure small parts", "az://container/test.dat", 10 * 1024 * 1024, "1MB", false, ""},
+		{"missing key", "gs://bucket", gb, "64MB", false, "must be gs://bucket/key"},
+		{"part too small", "s3://bucket/test.dat", gb, "1MB", false, "chunk size must be between"},
+		{"too many parts", "s3://bucket/test.dat", 1024 * gb, "64MB", false, "limited to 10000 parts"},
+		{"sparse", "s3://bucket/test.dat", gb, "64MB", true, "not supported for object store targets"},
+		{"auto chunk size", "s3://bucket/test.dat", gb, "auto", false, "automatic chunk size is not supported"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validator.ValidateObjectTarget(test.target, test.size, test.chunkSize, test.sparse)
+			if test.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidatePatternBytes(t *testing.T) {
+	validator := NewValidator()
+
+	tests := []struct {
+		name        string
+		pattern     string
+		hexBytes    string
+		expectedMsg string
+	}{
+		{"no sequence", "random", "", ""},
+		{"sequence", "bytes", "0xDEADBEEF", ""},
+		{"sequence in spec", "bytes:hex=0xAA55", "", ""},
+		{"missing sequence", "bytes", "", "needs a byte sequence"},
+		{"wrong pattern", "zero", "0xFF", "only be used with the bytes pattern"},
+		{"invalid hex", "bytes", "0xXYZ", "invalid byte sequence"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validator.ValidatePatternBytes(test.pattern, test.hexBytes)
+			if test.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error message to contain '%s', got: %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateMagic(t *testing.T) {
+	validator := NewValidator()
+
+	for _, format := range []string{"", "png", "zip", "pdf", "mp4"} {
+		if err := validator.ValidateMagic(format); err != nil {
+			t.Errorf("unexpected error for format %q: %v", format, err)
+		}
+	}
+	err := validator.ValidateMagic("tiff")
+	if err == nil || !strings.Contains(err.Error(), "unknown magic header format") {
+		t.Errorf("expected magic format error, got %v", err)
+	}
+}
+
+func TestValidateEncryption(t *testing.T) {
+	validator := NewValidator()
+
+	tests := []struct {
+		name        string
+		cipher      string
+		key         string
+		expectedMsg string
+	}{
+		{"none", "", "", ""},
+		{"random key", "aes-ctr", "", ""},
+		{"aes-128 key", "aes-ctr", "000102030405060708090a0b0c0d0e0f", ""},
+		{"unknown cipher", "age", "", "invalid cipher 'age'"},
+		{"short key", "aes-ctr", "0001", "must be 16, 24 or 32 bytes"},
+		{"key without cipher", "", "000102030405060708090a0b0c0d0e0f", "only be used with --encrypt"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validator.ValidateEncryption(test.cipher, test.key)
+			if test.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateDedupOptions(t *testing.T) {
+	validator := NewValidator()
+
+	tests := []struct {
+		name        string
+		ratio       float64
+		blockSize   string
+		expectedMsg string
+	}{
+		{"defaults", 0, "", ""},
+		{"custom", 0.3, "8KB", ""},
+		{"all duplicates", 1, "512B", ""},
+		{"negative ratio", -0.1, "", "dedup ratio must be between 0 and 1"},
+		{"ratio above one", 30, "", "dedup ratio must be between 0 and 1"},
+		{"invalid block", 0.5, "blocky", "invalid dedup block size format"},
+		{"block too small", 0.5, "100B", "dedup block size must be between"},
+		{"block too large", 0.5, "1GB", "dedup block size must be between"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validator.ValidateDedupOptions(test.ratio, test.blockSize)
+			if test.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error message to contain '%s', got: %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
 func TestValidateAll(t *testing.T) {
 	validator := NewValidator()
 	tempDir := t.TempDir()
@@ -331,6 +840,20 @@ func TestValidateAll(t *testing.T) {
 			expectError: true,
 			expectedMsg: "chunk_size",
 		},
+		{
+			name: "total size beyond free space",
+			config: ValidationConfig{
+				Size:       "1GB",
+				Pattern:    "random",
+				OutputPath: filepath.Join(tempDir, "test.bin"),
+				Count:      2,
+				TotalSize:  9 * 1024 * 1024 * 1024 * 1024 * 1024,
+				Workers:    2,
+				ChunkSize:  "64MB",
+			},
+			expectError: true,
+			expectedMsg: "space",
+		},
 	}
 
 	for _, test := range tests {
@@ -531,4 +1054,215 @@ func BenchmarkFormatSize(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		formatSize(size)
 	}
-}
\ No newline at end of file
+}
+func TestValidateMaxMemory(t *testing.T) {
+	validator := NewValidator()
+
+	tests := []struct {
+		maxMemory   string
+		chunkSize   string
+		expectedMsg string
+	}{
+		{"", "64MB", ""},
+		{"2GB", "64MB", ""},
+		{"64MB", "64MB", ""},
+		{"lots", "64MB", "invalid memory budget format"},
+		{"32MB", "64MB", "smaller than one chunk"},
+	}
+
+	for _, test := range tests {
+		err := validator.ValidateMaxMemory(test.maxMemory, test.chunkSize)
+		if test.expectedMsg == "" {
+			if err != nil {
+				t.Errorf("%q/%q: unexpected error: %v", test.maxMemory, test.chunkSize, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+			t.Errorf("%q/%q: expected error containing %q, got %v", test.maxMemory, test.chunkSize, test.expectedMsg, err)
+		}
+	}
+}
+
+func TestValidatePipeline(t *testing.T) {
+	validator := NewValidator()
+
+	for _, mode := range []string{"", "direct", "channel"} {
+		if err := validator.ValidatePipeline(mode); err != nil {
+			t.Errorf("unexpected error for %q: %v", mode, err)
+		}
+	}
+	err := validator.ValidatePipeline("parallel")
+	if err == nil || !strings.Contains(err.Error(), "invalid pipeline 'parallel'") {
+		t.Errorf("expected invalid pipeline error, got %v", err)
+	}
+}
+
+func TestValidateIOEngine(t *testing.T) {
+	validator := NewValidator()
+
+	for _, engine := range append([]string{""}, writer.Engines...) {
+		if err := validator.ValidateIOEngine(engine, "test.dat"); err != nil {
+			t.Errorf("unexpected error for %q: %v", engine, err)
+		}
+	}
+	if err := validator.ValidateIOEngine("pwrite", "tcp://localhost:9000"); err != nil {
+		t.Errorf("unexpected error for the default engine: %v", err)
+	}
+
+	err := validator.ValidateIOEngine("aio", "test.dat")
+	if err == nil || !strings.Contains(err.Error(), "invalid I/O engine 'aio'") {
+		t.Errorf("expected invalid engine error, got %v", err)
+	}
+	err = validator.ValidateIOEngine("uring", "s3://bucket/key")
+	if err == nil || !strings.Contains(err.Error(), "only applies to file and block device outputs") {
+		t.Errorf("expected output type error, got %v", err)
+	}
+}
+
+func TestValidateChecksumMode(t *testing.T) {
+	validator := NewValidator()
+
+	for _, mode := range []string{"", "stream", "ordered", "tree"} {
+		if err := validator.ValidateChecksumMode(mode); err != nil {
+			t.Errorf("unexpected error for %q: %v", mode, err)
+		}
+	}
+	err := validator.ValidateChecksumMode("blake3")
+	if err == nil || !strings.Contains(err.Error(), "invalid checksum mode 'blake3'") {
+		t.Errorf("expected invalid checksum mode error, got %v", err)
+	}
+}
+
+func TestValidateChecksumFormat(t *testing.T) {
+	validator := NewValidator()
+
+	for _, format := range []string{"", "trasher", "gnu", "bsd"} {
+		if err := validator.ValidateChecksumFormat(format, "stream"); err != nil {
+			t.Errorf("unexpected error for %q: %v", format, err)
+		}
+	}
+	if err := validator.ValidateChecksumFormat("trasher", "tree"); err != nil {
+		t.Errorf("unexpected error for a tree checksum: %v", err)
+	}
+
+	err := validator.ValidateChecksumFormat("sfv", "stream")
+	if err == nil || !strings.Contains(err.Error(), "invalid checksum format 'sfv'") {
+		t.Errorf("expected invalid format error, got %v", err)
+	}
+	err = validator.ValidateChecksumFormat("gnu", "tree")
+	if err == nil || !strings.Contains(err.Error(), "needs the stream checksum mode") {
+		t.Errorf("expected checksum mode error, got %v", err)
+	}
+}
+
+func TestValidateDuration(t *testing.T) {
+	validator := NewValidator()
+
+	for _, duration := range []time.Duration{0, time.Second, 10 * time.Minute} {
+		if err := validator.ValidateDuration(duration); err != nil {
+			t.Errorf("unexpected error for %s: %v", duration, err)
+		}
+	}
+	err := validator.ValidateDuration(-time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("expected negative limit error, got %v", err)
+	}
+}
+
+func TestValidateCount(t *testing.T) {
+	validator := NewValidator()
+	path := filepath.Join(t.TempDir(), "test.dat")
+
+	tests := []struct {
+		name        string
+		count       int
+		size        int64
+		path        string
+		expectedMsg string
+	}{
+		{"default", 0, 1024, path, ""},
+		{"one file", 1, 1024, "tcp://localhost:9000", ""},
+		{"several files", 100, 1024 * 1024 * 1024, path, ""},
+		{"negative", -1, 1024, path, "must not be negative"},
+		{"network target", 2, 1024, "tcp://localhost:9000", "only be generated to file outputs"},
+		{"object target", 2, 1024, "s3://bucket/key", "only be generated to file outputs"},
+		{"too large in total", 3, 4 * 1024 * 1024 * 1024 * 1024 * 1024, path, "exceed 10PB in total"},
+	}
+
+	for _, test := range tests {
+		err := validator.ValidateCount(test.count, test.size, test.path)
+		if test.expectedMsg == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+			t.Errorf("%s: expected error containing %q, got %v", test.name, test.expectedMsg, err)
+		}
+	}
+}
+
+func TestValidateNames(t *testing.T) {
+	validator := NewValidator()
+
+	if err := validator.ValidateNames(nil); err != nil {
+		t.Errorf("unexpected error for no names: %v", err)
+	}
+	if err := validator.ValidateNames([]string{"data-1.bin", "data-2.bin", "other/data-1.bin"}); err != nil {
+		t.Errorf("unexpected error for distinct names: %v", err)
+	}
+	err := validator.ValidateNames([]string{"data-a.bin", "data-b.bin", "./data-a.bin"})
+	if err == nil || !strings.Contains(err.Error(), "the name data-a.bin") {
+		t.Errorf("expected a collision error, got %v", err)
+	}
+}
+
+func TestValidateStopWhenFreeBelow(t *testing.T) {
+	validator := NewValidator()
+	path := filepath.Join(t.TempDir(), "test.dat")
+
+	tests := []struct {
+		name        string
+		threshold   string
+		path        string
+		expectedMsg string
+	}{
+		{"no threshold", "", path, ""},
+		{"threshold", "1KB", path, ""},
+		{"invalid format", "lots", path, "invalid free space threshold format"},
+		{"network target", "1KB", "tcp://localhost:9000", "only applies to file outputs"},
+		{"object target", "1KB", "s3://bucket/test.dat", "only applies to file outputs"},
+		{"already below", "9PB", path, "already below"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validator.ValidateStopWhenFreeBelow(test.threshold, test.path)
+			if test.expectedMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateMaxIOPS(t *testing.T) {
+	validator := NewValidator()
+
+	for _, iops := range []int{0, 1, 5000} {
+		if err := validator.ValidateMaxIOPS(iops); err != nil {
+			t.Errorf("unexpected error for %d: %v", iops, err)
+		}
+	}
+	err := validator.ValidateMaxIOPS(-1)
+	if err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("expected negative limit error, got %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build linux
+
+package verify
+
+import "syscall"
+
+// adviseSequential tells the kernel the mapping will be read front to back,
+// so it reads ahead aggressively and drops pages behind the reader.
+func adviseSequential(data []byte) {
+	syscall.Madvise(data, syscall.MADV_SEQUENTIAL)
+}
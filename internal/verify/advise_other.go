@@ -0,0 +1,6 @@
+//go:build !linux
+
+package verify
+
+// adviseSequential is a no-op where madvise is not available.
+func adviseSequential(data []byte) {}
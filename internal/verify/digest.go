@@ -0,0 +1,56 @@
+package verify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/maxkimambo/trasher/internal/worker"
+)
+
+// digestChunkSize is the unit Digest reads the file in.
+const digestChunkSize = 4 * 1024 * 1024
+
+// Digest returns the hex SHA-256 of the first size bytes of the file at path.
+// The hash has to see the data in order, but reading doesn't: workers read
+// the chunks ahead in parallel (see worker.ProcessOrdered), so reading a
+// large file keeps several requests in flight instead of one. Zero workers
+// uses one per CPU.
+func Digest(ctx context.Context, path string, size int64, workers int) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", &ReadError{Err: fmt.Errorf("failed to open %s: %v", path, err)}
+	}
+	defer file.Close()
+
+	var tasks []worker.Task
+	for offset := int64(0); offset < size; offset += digestChunkSize {
+		tasks = append(tasks, worker.Task{Offset: offset, Size: min(digestChunkSize, size-offset)})
+	}
+
+	hasher := sha256.New()
+	pool := worker.NewWorkerPool(ctx, workers, digestChunkSize)
+	err = pool.ProcessOrdered(tasks, func(buffer []byte, task worker.Task) error {
+		n, err := file.ReadAt(buffer, task.Offset)
+		if n < len(buffer) {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return &ReadError{Err: fmt.Errorf("failed to read chunk at offset %d: %v", task.Offset, err)}
+		}
+		return nil
+	}, func(buffer []byte, task worker.Task) error {
+		hasher.Write(buffer)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,65 @@
+package verify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeRandom creates a file of size pseudo-random bytes and returns its
+// path and contents.
+func writeRandom(t *testing.T, size int) (string, []byte) {
+	t.Helper()
+
+	data := make([]byte, size)
+	rng := rand.NewChaCha8([32]byte{1})
+	rng.Read(data)
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	return path, data
+}
+
+func TestDigest(t *testing.T) {
+	size := 3*digestChunkSize + 12345
+	path, data := writeRandom(t, size)
+
+	for _, workers := range []int{1, 4} {
+		for _, n := range []int{0, 100, digestChunkSize, size} {
+			want := sha256.Sum256(data[:n])
+			got, err := Digest(context.Background(), path, int64(n), workers)
+			if err != nil {
+				t.Fatalf("Digest(%d bytes, %d workers) failed: %v", n, workers, err)
+			}
+			if got != hex.EncodeToString(want[:]) {
+				t.Errorf("Digest(%d bytes, %d workers) = %s, want %x", n, workers, got, want)
+			}
+		}
+	}
+}
+
+func TestDigestShortFile(t *testing.T) {
+	path, _ := writeRandom(t, 2*digestChunkSize)
+
+	_, err := Digest(context.Background(), path, 3*digestChunkSize, 4)
+	var readErr *ReadError
+	if !errors.As(err, &readErr) {
+		t.Errorf("expected ReadError for a file shorter than size, got %v", err)
+	}
+}
+
+func TestDigestCancelled(t *testing.T) {
+	path, _ := writeRandom(t, 4*digestChunkSize)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Digest(ctx, path, 4*digestChunkSize, 2); err == nil {
+		t.Error("expected an error from a cancelled digest")
+	}
+}
@@ -0,0 +1,55 @@
+package verify
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMapFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapped.bin")
+	content := bytes.Repeat([]byte("trasher"), 10000)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	data, err := mapFile(file, int64(len(content)))
+	if err != nil {
+		t.Fatalf("mapFile failed: %v", err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Error("mapped data does not match file content")
+	}
+	if err := unmapFile(data); err != nil {
+		t.Errorf("unmapFile failed: %v", err)
+	}
+}
+
+func TestVerifyMmap(t *testing.T) {
+	path := writeFixture(t, 3*mmapStep+512, mmapStep+100)
+	corrupt(t, path, 2*(mmapStep+100)+7, []byte{0x01})
+
+	v, err := New(path, Options{Workers: 2, Mmap: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	result, err := v.Run(t.Context())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Checked != 4 || result.BytesRead != v.Bytes() {
+		t.Errorf("expected 4 chunks and %d bytes, got %d chunks and %d bytes",
+			v.Bytes(), result.Checked, result.BytesRead)
+	}
+	if len(result.Corrupted) != 1 || result.Corrupted[0].Offset != 2*(mmapStep+100) {
+		t.Errorf("expected the third chunk to be corrupted, got %+v", result.Corrupted)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build unix || linux || darwin
+
+package verify
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the whole file read-only into memory.
+func mapFile(file *os.File, size int64) ([]byte, error) {
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	adviseSequential(data)
+	return data, nil
+}
+
+// unmapFile releases a mapping created by mapFile.
+func unmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
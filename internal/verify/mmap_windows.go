@@ -0,0 +1,34 @@
+//go:build windows
+
+package verify
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mapFile maps the whole file read-only into memory.
+func mapFile(file *os.File, size int64) ([]byte, error) {
+	mapping, err := syscall.CreateFileMapping(syscall.Handle(file.Fd()), nil,
+		syscall.PAGE_READONLY, uint32(size>>32), uint32(size), nil)
+	if err != nil {
+		return nil, err
+	}
+	// The view keeps the mapping alive after its handle is closed.
+	defer syscall.CloseHandle(mapping)
+
+	addr, err := syscall.MapViewOfFile(mapping, syscall.FILE_MAP_READ, 0, 0, uintptr(size))
+	if err != nil {
+		return nil, err
+	}
+	// Convert through a pointer to the address so vet accepts the uintptr
+	// returned by the system call.
+	ptr := *(*unsafe.Pointer)(unsafe.Pointer(&addr))
+	return unsafe.Slice((*byte)(ptr), size), nil
+}
+
+// unmapFile releases a mapping created by mapFile.
+func unmapFile(data []byte) error {
+	return syscall.UnmapViewOfFile(uintptr(unsafe.Pointer(&data[0])))
+}
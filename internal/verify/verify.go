@@ -0,0 +1,429 @@
+// Package verify checks generated files against their recorded chunk
+// checksums, or against the stamps embedded by the verify pattern.
+package verify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maxkimambo/trasher/internal/worker"
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+// Options controls which chunks are verified.
+type Options struct {
+	// Sample is the fraction of chunks to verify, in (0, 1]. Zero verifies
+	// every chunk.
+	Sample float64
+	// Seed selects the sampled chunks, so a sampled run can be repeated
+	// exactly.
+	Seed uint64
+	// Workers is the number of parallel readers. Zero uses one per CPU.
+	Workers int
+	// Mmap reads the file through a read-only memory mapping instead of
+	// copying each chunk into a buffer. The file must not be truncated
+	// while it is mapped.
+	Mmap bool
+	// Stamps checks every block against the offset and run ID stamped into
+	// it by the verify pattern, instead of against the checksum file.
+	Stamps bool
+	// CRC checks every block of a file written with the crc pattern against
+	// its own CRC and the offset and run ID in its header, instead of
+	// against the checksum file.
+	CRC bool
+	// RunID is the run ID stamped blocks must carry. Zero takes it from the
+	// first block of the file.
+	RunID uint64
+	// Manifest is a chunk manifest to verify against instead of the
+	// checksum file; empty uses the checksum file.
+	Manifest string
+	// Chunks are the chunk checksums to verify against, such as those read
+	// from a checksum file covering several files; nil loads them from the
+	// manifest or checksum file.
+	Chunks []checksum.ChunkInfo
+}
+
+const (
+	// mmapStep is how much of a mapped chunk is hashed between progress updates.
+	mmapStep = 1 << 20
+
+	// stampChunkSize is the unit stamped files are read and checked in.
+	stampChunkSize = 16 * 1024 * 1024
+
+	// maxBadBlocks caps how many failed blocks a result lists individually.
+	maxBadBlocks = 10000
+)
+
+// MissingChecksumsError is returned when the checksum file or manifest to
+// verify against does not exist.
+type MissingChecksumsError struct {
+	Path string
+}
+
+func (e *MissingChecksumsError) Error() string {
+	return fmt.Sprintf("%s not found", e.Path)
+}
+
+// ReadError is returned when the file being verified can't be opened or
+// read, as opposed to being read and found not to match.
+type ReadError struct {
+	Err error
+}
+
+func (e *ReadError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// Result summarizes a verification run.
+type Result struct {
+	TotalChunks int
+	Checked     int
+	BytesRead   int64
+	Elapsed     time.Duration
+	Corrupted   []checksum.ChunkInfo
+	// BadBlocks lists failed blocks of a stamp verification, in file order
+	// and capped at 10000; BadBlockCount counts all of them.
+	BadBlocks     []generator.StampMismatch
+	BadBlockCount int64
+}
+
+// Clean returns true if no corrupted chunks were found.
+func (r *Result) Clean() bool {
+	return len(r.Corrupted) == 0
+}
+
+// Throughput returns the average read rate in bytes per second.
+func (r *Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.BytesRead) / r.Elapsed.Seconds()
+}
+
+// Verifier checks the chunks of one file against its chunk checksums.
+type Verifier struct {
+	path        string
+	fileSize    int64
+	workers     int
+	mmap        bool
+	totalChunks int
+	chunks      []checksum.ChunkInfo
+	bytes       int64
+	bytesRead   atomic.Int64
+	stamps      generator.BlockChecker
+}
+
+// New loads the chunk checksums for the file at path and selects the chunks
+// to verify.
+func New(path string, opts Options) (*Verifier, error) {
+	if opts.Sample < 0 || opts.Sample > 1 {
+		return nil, fmt.Errorf("sample fraction must be between 0 and 1, got %g", opts.Sample)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, &ReadError{Err: fmt.Errorf("cannot access %s: %v", path, err)}
+	}
+
+	var chunks []checksum.ChunkInfo
+	var stamps generator.BlockChecker
+	if opts.Stamps || opts.CRC {
+		runID := opts.RunID
+		if runID == 0 {
+			if runID, err = readRunID(path, opts.CRC); err != nil {
+				return nil, err
+			}
+		}
+		if opts.CRC {
+			stamps = generator.NewCRCGenerator(runID)
+		} else {
+			stamps = generator.NewStampGenerator(runID)
+		}
+		chunks = stampChunks(info.Size())
+	} else if opts.Chunks != nil {
+		chunks = opts.Chunks
+	} else if opts.Manifest != "" {
+		if err := checkExists(opts.Manifest); err != nil {
+			return nil, err
+		}
+		chunks, err = checksum.LoadManifest(opts.Manifest, info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load manifest: %v", err)
+		}
+	} else {
+		if err := checkExists(path + ".checksum.txt"); err != nil {
+			return nil, err
+		}
+		chunks, err = checksum.LoadChunkChecksums(path+".checksum.txt", info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load chunk checksums: %v", err)
+		}
+	}
+
+	v := &Verifier{
+		path:        path,
+		fileSize:    info.Size(),
+		workers:     opts.Workers,
+		mmap:        opts.Mmap,
+		totalChunks: len(chunks),
+		chunks:      chunks,
+		stamps:      stamps,
+	}
+	if opts.Sample > 0 {
+		v.chunks = Sample(chunks, opts.Sample, opts.Seed)
+	}
+	for _, chunk := range v.chunks {
+		v.bytes += chunk.Size
+	}
+	return v, nil
+}
+
+// checkExists returns a MissingChecksumsError if the checksum file or
+// manifest at path does not exist.
+func checkExists(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return &MissingChecksumsError{Path: path}
+	}
+	return nil
+}
+
+// RunID returns the run ID stamped blocks are checked against, or zero when
+// verifying against the checksum file.
+func (v *Verifier) RunID() uint64 {
+	if v.stamps == nil {
+		return 0
+	}
+	return v.stamps.RunID()
+}
+
+// Bytes returns the number of bytes the verifier will read.
+func (v *Verifier) Bytes() int64 {
+	return v.bytes
+}
+
+// BytesRead returns the number of bytes read so far. It is safe to call
+// while Run is in progress.
+func (v *Verifier) BytesRead() int64 {
+	return v.bytesRead.Load()
+}
+
+// Run reads and hashes the selected chunks in parallel on a worker pool.
+func (v *Verifier) Run(ctx context.Context) (*Result, error) {
+	file, err := os.Open(v.path)
+	if err != nil {
+		return nil, &ReadError{Err: fmt.Errorf("failed to open %s: %v", v.path, err)}
+	}
+	defer file.Close()
+
+	var maxChunk int64
+	tasks := make([]worker.Task, len(v.chunks))
+	checksums := make(map[int64]checksum.ChunkInfo, len(v.chunks))
+	for i, chunk := range v.chunks {
+		maxChunk = max(maxChunk, chunk.Size)
+		tasks[i] = worker.Task{Offset: chunk.Offset, Size: chunk.Size}
+		checksums[chunk.Offset] = chunk
+	}
+
+	result := &Result{TotalChunks: v.totalChunks}
+	var mu sync.Mutex
+	record := func(task worker.Task, sum []byte, bad []generator.StampMismatch) {
+		chunk := checksums[task.Offset]
+
+		mu.Lock()
+		defer mu.Unlock()
+		result.Checked++
+		if v.stamps != nil {
+			if len(bad) > 0 {
+				result.Corrupted = append(result.Corrupted, chunk)
+				result.BadBlockCount += int64(len(bad))
+				result.BadBlocks = append(result.BadBlocks, bad[:min(len(bad), maxBadBlocks-len(result.BadBlocks))]...)
+			}
+			return
+		}
+		if hex.EncodeToString(sum) != chunk.Checksum {
+			result.Corrupted = append(result.Corrupted, chunk)
+		}
+	}
+
+	start := time.Now()
+	if v.mmap && v.fileSize > 0 {
+		data, err := mapFile(file, v.fileSize)
+		if err != nil {
+			return nil, &ReadError{Err: fmt.Errorf("failed to map %s: %v", v.path, err)}
+		}
+		defer unmapFile(data)
+
+		pool := worker.NewWorkerPool(ctx, v.workers, maxChunk)
+		err = pool.ProcessTasks(tasks, func(task worker.Task) error {
+			chunk := data[task.Offset : task.Offset+task.Size]
+			if v.stamps != nil {
+				record(task, nil, v.checkMapped(chunk, task.Offset))
+			} else {
+				record(task, v.hashMapped(chunk), nil)
+			}
+			return nil
+		})
+	} else {
+		pool := worker.NewWorkerPool(ctx, v.workers, maxChunk)
+		err = pool.Process(tasks, func(buffer []byte, task worker.Task) error {
+			if _, err := file.ReadAt(buffer, task.Offset); err != nil && err != io.EOF {
+				return &ReadError{Err: fmt.Errorf("failed to read chunk at offset %d: %v", task.Offset, err)}
+			}
+			v.bytesRead.Add(task.Size)
+
+			if v.stamps != nil {
+				record(task, nil, v.stamps.Check(buffer, task.Offset))
+				return nil
+			}
+			sum := sha256.Sum256(buffer)
+			record(task, sum[:], nil)
+			return nil
+		})
+	}
+	result.Elapsed = time.Since(start)
+	result.BytesRead = v.BytesRead()
+
+	sort.Slice(result.Corrupted, func(i, j int) bool {
+		return result.Corrupted[i].Offset < result.Corrupted[j].Offset
+	})
+	sort.Slice(result.BadBlocks, func(i, j int) bool {
+		return result.BadBlocks[i].Offset < result.BadBlocks[j].Offset
+	})
+	return result, err
+}
+
+// hashMapped hashes a chunk of a mapped file. Progress is counted as pages
+// are touched rather than per chunk, since faulting in a chunk is where the
+// time goes.
+func (v *Verifier) hashMapped(data []byte) []byte {
+	h := sha256.New()
+	for len(data) > 0 {
+		n := min(len(data), mmapStep)
+		h.Write(data[:n])
+		v.bytesRead.Add(int64(n))
+		data = data[n:]
+	}
+	return h.Sum(nil)
+}
+
+// checkMapped checks the stamps of a chunk of a mapped file, counting
+// progress as pages are touched like hashMapped.
+func (v *Verifier) checkMapped(data []byte, offset int64) []generator.StampMismatch {
+	var bad []generator.StampMismatch
+	for len(data) > 0 {
+		n := min(len(data), mmapStep)
+		bad = append(bad, v.stamps.Check(data[:n], offset)...)
+		v.bytesRead.Add(int64(n))
+		data = data[n:]
+		offset += int64(n)
+	}
+	return bad
+}
+
+// stampChunks splits a file of the given size into the chunks a stamp
+// verification reads, each a whole number of stamped blocks.
+func stampChunks(size int64) []checksum.ChunkInfo {
+	var chunks []checksum.ChunkInfo
+	for offset := int64(0); offset < size; offset += stampChunkSize {
+		chunks = append(chunks, checksum.ChunkInfo{Offset: offset, Size: min(stampChunkSize, size-offset)})
+	}
+	return chunks
+}
+
+// readRunID reads the run ID from the stamp of the first block of the file,
+// or with crc from the header of its first block of the crc pattern.
+func readRunID(path string, crc bool) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, &ReadError{Err: fmt.Errorf("failed to open %s: %v", path, err)}
+	}
+	defer file.Close()
+
+	header := make([]byte, generator.StampBlockSize)
+	n, err := file.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return 0, &ReadError{Err: fmt.Errorf("failed to read %s: %v", path, err)}
+	}
+
+	if crc {
+		_, runID, ok := generator.ReadCRCStamp(header[:n])
+		if !ok {
+			return 0, fmt.Errorf("%s does not start with an intact crc block; generate it with the crc pattern or pass the run ID", path)
+		}
+		return runID, nil
+	}
+	_, runID, ok := generator.ReadStamp(header[:n])
+	if !ok {
+		return 0, fmt.Errorf("%s does not start with a stamped block; generate it with the verify pattern or pass the run ID", path)
+	}
+	return runID, nil
+}
+
+// Verify checks the file at path against its chunk checksums, or against
+// its stamps with opts.Stamps or opts.CRC.
+func Verify(path string, opts Options) (*Result, error) {
+	v, err := New(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return v.Run(context.Background())
+}
+
+// Sample picks a seeded random subset of chunks covering the given fraction,
+// rounded up so at least one chunk is always checked. The subset is returned
+// in file order to keep reads as sequential as possible.
+func Sample(chunks []checksum.ChunkInfo, fraction float64, seed uint64) []checksum.ChunkInfo {
+	if fraction >= 1 || len(chunks) == 0 {
+		return chunks
+	}
+
+	count := int(math.Ceil(float64(len(chunks)) * fraction))
+
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	indices := rng.Perm(len(chunks))[:count]
+	sort.Ints(indices)
+
+	sampled := make([]checksum.ChunkInfo, count)
+	for i, idx := range indices {
+		sampled[i] = chunks[idx]
+	}
+	return sampled
+}
+
+// ParseSample parses a sample size given as a percentage ("1%", "0.5%") or
+// a fraction ("0.01").
+func ParseSample(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	percent := strings.HasSuffix(s, "%")
+
+	value, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sample size %q", s)
+	}
+	if percent {
+		value /= 100
+	}
+
+	if value <= 0 || value > 1 {
+		return 0, fmt.Errorf("sample size %q must be greater than 0%% and at most 100%%", s)
+	}
+	return value, nil
+}
@@ -0,0 +1,394 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxkimambo/trasher/pkg/checksum"
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+// writeFixture creates a zero-filled file with chunk checksums and a chunk
+// manifest.
+func writeFixture(t *testing.T, size, chunkSize int) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "fixture.bin")
+	data := make([]byte, size)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	gen := checksum.NewChecksumGenerator(path, int64(size))
+	for offset := 0; offset < size; offset += chunkSize {
+		end := min(offset+chunkSize, size)
+		gen.UpdateWithChunk(data[offset:end], int64(offset))
+	}
+	if err := gen.WriteChecksumFile(); err != nil {
+		t.Fatalf("failed to write checksum file: %v", err)
+	}
+	if err := gen.WriteManifest(int64(size)); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	return path
+}
+
+// corrupt overwrites bytes of the file at offset.
+func corrupt(t *testing.T, path string, offset int64, data []byte) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("failed to open fixture: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteAt(data, offset); err != nil {
+		t.Fatalf("failed to corrupt fixture: %v", err)
+	}
+}
+
+func TestVerifyFull(t *testing.T) {
+	path := writeFixture(t, 10*1024, 1024)
+	corrupt(t, path, 3*1024+5, []byte{0xFF})
+
+	result, err := Verify(path, Options{})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.TotalChunks != 10 || result.Checked != 10 {
+		t.Errorf("expected 10/10 chunks checked, got %d/%d", result.Checked, result.TotalChunks)
+	}
+	if result.BytesRead != 10*1024 {
+		t.Errorf("expected 10240 bytes read, got %d", result.BytesRead)
+	}
+	if len(result.Corrupted) != 1 || result.Corrupted[0].Offset != 3*1024 {
+		t.Errorf("expected chunk at 3072 to be corrupted, got %+v", result.Corrupted)
+	}
+}
+
+func TestVerifyManifest(t *testing.T) {
+	path := writeFixture(t, 10*1024, 1024)
+	corrupt(t, path, 7*1024, []byte{0xFF})
+	if err := os.Remove(path + ".checksum.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Verify(path, Options{Manifest: path + checksum.ManifestSuffix})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.Checked != 10 {
+		t.Errorf("expected 10 chunks checked, got %d", result.Checked)
+	}
+	if len(result.Corrupted) != 1 || result.Corrupted[0].Offset != 7*1024 {
+		t.Errorf("expected chunk at 7168 to be corrupted, got %+v", result.Corrupted)
+	}
+}
+
+func TestVerifyChunks(t *testing.T) {
+	path := writeFixture(t, 4*1024, 1024)
+	if err := os.Remove(path + ".checksum.txt"); err != nil {
+		t.Fatal(err)
+	}
+	set, err := checksum.LoadManifest(path+checksum.ManifestSuffix, 4*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt(t, path, 1024, []byte{0xFF})
+
+	result, err := Verify(path, Options{Chunks: set})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.Checked != 4 {
+		t.Errorf("expected 4 chunks checked, got %d", result.Checked)
+	}
+	if len(result.Corrupted) != 1 || result.Corrupted[0].Offset != 1024 {
+		t.Errorf("expected chunk at 1024 to be corrupted, got %+v", result.Corrupted)
+	}
+}
+
+func TestVerifySampled(t *testing.T) {
+	path := writeFixture(t, 100*1024, 1024)
+
+	result, err := Verify(path, Options{Sample: 0.1, Seed: 42})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.TotalChunks != 100 || result.Checked != 10 {
+		t.Errorf("expected 10 of 100 chunks checked, got %d of %d", result.Checked, result.TotalChunks)
+	}
+	if !result.Clean() {
+		t.Errorf("expected clean file, got %+v", result.Corrupted)
+	}
+}
+
+func TestVerifyRejectsBadSample(t *testing.T) {
+	path := writeFixture(t, 1024, 1024)
+
+	if _, err := Verify(path, Options{Sample: 1.5}); err == nil {
+		t.Error("expected error for sample fraction above 1")
+	}
+}
+
+func TestVerifyErrorTypes(t *testing.T) {
+	path := writeFixture(t, 1024, 1024)
+	var missing *MissingChecksumsError
+	var readErr *ReadError
+
+	if _, err := Verify(path, Options{Manifest: path + ".missing.json"}); !errors.As(err, &missing) {
+		t.Errorf("expected MissingChecksumsError for a missing manifest, got %v", err)
+	}
+	if _, err := Verify(path+".nonexistent", Options{}); !errors.As(err, &readErr) {
+		t.Errorf("expected ReadError for a missing file, got %v", err)
+	}
+
+	if err := os.Remove(path + ".checksum.txt"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Verify(path, Options{})
+	if !errors.As(err, &missing) {
+		t.Fatalf("expected MissingChecksumsError for a missing checksum file, got %v", err)
+	}
+	if missing.Path != path+".checksum.txt" {
+		t.Errorf("expected missing path %s, got %s", path+".checksum.txt", missing.Path)
+	}
+}
+
+func TestSample(t *testing.T) {
+	chunks := make([]checksum.ChunkInfo, 1000)
+	for i := range chunks {
+		chunks[i] = checksum.ChunkInfo{Offset: int64(i) * 1024, Size: 1024}
+	}
+
+	a := Sample(chunks, 0.01, 7)
+	b := Sample(chunks, 0.01, 7)
+	if len(a) != 10 {
+		t.Fatalf("expected 10 sampled chunks, got %d", len(a))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("same seed produced different samples at %d: %+v vs %+v", i, a[i], b[i])
+		}
+		if i > 0 && a[i].Offset <= a[i-1].Offset {
+			t.Errorf("sample not in file order at %d", i)
+		}
+	}
+
+	c := Sample(chunks, 0.01, 8)
+	same := true
+	for i := range a {
+		if a[i] != c[i] {
+			same = false
+		}
+	}
+	if same {
+		t.Error("different seeds produced identical samples")
+	}
+
+	if got := Sample(chunks, 0.0001, 1); len(got) != 1 {
+		t.Errorf("expected at least one chunk, got %d", len(got))
+	}
+	if got := Sample(chunks, 1, 1); len(got) != len(chunks) {
+		t.Errorf("expected all chunks for fraction 1, got %d", len(got))
+	}
+}
+
+func TestParseSample(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  float64
+		shouldErr bool
+	}{
+		{"1%", 0.01, false},
+		{"0.5%", 0.005, false},
+		{"100%", 1, false},
+		{"0.25", 0.25, false},
+		{"0%", 0, true},
+		{"150%", 0, true},
+		{"-1%", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := ParseSample(test.input)
+			if test.shouldErr {
+				if err == nil {
+					t.Errorf("expected error for %q", test.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.expected {
+				t.Errorf("expected %g, got %g", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestVerifierParallel(t *testing.T) {
+	path := writeFixture(t, 64*1024, 1024)
+	for _, offset := range []int64{50 * 1024, 2 * 1024, 33*1024 + 100} {
+		corrupt(t, path, offset, []byte{0xAB})
+	}
+
+	v, err := New(path, Options{Workers: 8})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if v.Bytes() != 64*1024 {
+		t.Errorf("expected 65536 bytes to verify, got %d", v.Bytes())
+	}
+
+	result, err := v.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if v.BytesRead() != 64*1024 || result.BytesRead != 64*1024 {
+		t.Errorf("expected 65536 bytes read, got %d", result.BytesRead)
+	}
+
+	expected := []int64{2 * 1024, 33 * 1024, 50 * 1024}
+	if len(result.Corrupted) != len(expected) {
+		t.Fatalf("expected %d corrupted chunks, got %+v", len(expected), result.Corrupted)
+	}
+	for i, offset := range expected {
+		if result.Corrupted[i].Offset != offset {
+			t.Errorf("corrupted chunk %d: expected offset %d, got %d", i, offset, result.Corrupted[i].Offset)
+		}
+	}
+}
+
+func TestVerifierCancelled(t *testing.T) {
+	path := writeFixture(t, 64*1024, 1024)
+
+	v, err := New(path, Options{Workers: 2})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := v.Run(ctx); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// writeStampedFixture creates a file with the verify pattern for runID.
+func writeStampedFixture(t *testing.T, size int, runID uint64) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "stamped.bin")
+	data := make([]byte, size)
+	generator.NewStampGenerator(runID).GenerateAt(data, 0)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	return path
+}
+
+func TestVerifyStamps(t *testing.T) {
+	const block = generator.StampBlockSize
+	path := writeStampedFixture(t, 2*stampChunkSize+10*block+123, 0xabc)
+
+	// A misdirected write: the block for offset 5 also landed at offset 2
+	data, _ := os.ReadFile(path)
+	corrupt(t, path, 2*block, data[5*block:6*block])
+	// A flipped bit in the second chunk
+	corrupt(t, path, stampChunkSize+block+99, []byte{data[stampChunkSize+block+99] ^ 1})
+
+	for _, mmap := range []bool{false, true} {
+		v, err := New(path, Options{Stamps: true, Mmap: mmap, Workers: 2})
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		if v.RunID() != 0xabc {
+			t.Errorf("expected run ID read from the file, got %x", v.RunID())
+		}
+
+		result, err := v.Run(context.Background())
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if result.TotalChunks != 3 || result.Checked != 3 || len(result.Corrupted) != 2 {
+			t.Errorf("mmap=%v: expected 2 of 3 chunks corrupted, got %+v", mmap, result)
+		}
+		if result.BadBlockCount != 2 || len(result.BadBlocks) != 2 {
+			t.Fatalf("mmap=%v: expected 2 bad blocks, got %v", mmap, result.BadBlocks)
+		}
+
+		misdirected := result.BadBlocks[0]
+		if misdirected.Offset != 2*block || misdirected.Problem != generator.StampMisdirected || misdirected.FoundOffset != 5*block {
+			t.Errorf("expected misdirected write at offset %d, got %+v", 2*block, misdirected)
+		}
+		if flipped := result.BadBlocks[1]; flipped.Offset != stampChunkSize+block || flipped.Problem != generator.StampCorrupt {
+			t.Errorf("expected corrupt block at offset %d, got %+v", stampChunkSize+block, flipped)
+		}
+	}
+}
+
+func TestVerifyCRC(t *testing.T) {
+	const block = generator.StampBlockSize
+	path := filepath.Join(t.TempDir(), "crc.bin")
+	data := make([]byte, stampChunkSize+10*block+123)
+	generator.NewCRCGenerator(0xc4c).GenerateAt(data, 0)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A misdirected write and a flipped bit in the second chunk
+	corrupt(t, path, 2*block, data[5*block:6*block])
+	corrupt(t, path, stampChunkSize+block+99, []byte{data[stampChunkSize+block+99] ^ 1})
+
+	v, err := New(path, Options{CRC: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if v.RunID() != 0xc4c {
+		t.Errorf("expected run ID read from the file, got %x", v.RunID())
+	}
+	result, err := v.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.BadBlockCount != 2 || len(result.BadBlocks) != 2 {
+		t.Fatalf("expected 2 bad blocks, got %v", result.BadBlocks)
+	}
+	if misdirected := result.BadBlocks[0]; misdirected.Problem != generator.StampMisdirected || misdirected.FoundOffset != 5*block {
+		t.Errorf("expected misdirected write at offset %d, got %+v", 2*block, misdirected)
+	}
+	if flipped := result.BadBlocks[1]; flipped.Offset != stampChunkSize+block || flipped.Problem != generator.StampCorrupt {
+		t.Errorf("expected corrupt block at offset %d, got %+v", stampChunkSize+block, flipped)
+	}
+
+	// Stamps of the verify pattern aren't crc blocks
+	if _, err := New(writeStampedFixture(t, 4*block, 1), Options{CRC: true}); err == nil {
+		t.Error("expected an error for a file without crc blocks")
+	}
+}
+
+func TestVerifyStampsRunID(t *testing.T) {
+	path := writeStampedFixture(t, 8*generator.StampBlockSize, 1)
+
+	// Checking against another run flags every block as stale
+	result, err := Verify(path, Options{Stamps: true, RunID: 2})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.BadBlockCount != 8 || result.BadBlocks[0].Problem != generator.StampStale || result.BadBlocks[0].FoundRunID != 1 {
+		t.Errorf("expected 8 stale blocks from run 1, got %d: %v", result.BadBlockCount, result.BadBlocks)
+	}
+
+	// A file without stamps needs an explicit run ID
+	plain := writeFixture(t, 4096, 1024)
+	if _, err := New(plain, Options{Stamps: true}); err == nil {
+		t.Error("expected error for a file without stamps")
+	}
+}
@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"os"
+	"unsafe"
+)
+
+// hugePageSize is the size of a transparent huge page on x86-64 and most
+// arm64 kernels.
+const hugePageSize = 2 * 1024 * 1024
+
+// pageSize is the alignment of every chunk buffer, which O_DIRECT writes need.
+var pageSize = os.Getpagesize()
+
+// alignedBuffer returns a buffer of size bytes starting at a multiple of
+// align, which must be a power of two. Its capacity is size, so reslicing it
+// to its capacity keeps it aligned.
+func alignedBuffer(size, align int) []byte {
+	buffer := make([]byte, size+align)
+	offset := 0
+	if rem := int(uintptr(unsafe.Pointer(unsafe.SliceData(buffer))) & uintptr(align-1)); rem != 0 {
+		offset = align - rem
+	}
+	return buffer[offset : offset+size : offset+size]
+}
+
+// newBuffer allocates a chunk buffer. Buffers are page-aligned; with huge
+// pages, buffers of at least one huge page are aligned to huge pages and the
+// kernel is asked to back them with huge pages.
+func (p *WorkerPool) newBuffer(size int64) []byte {
+	if !p.hugePages || size < hugePageSize {
+		return alignedBuffer(int(size), pageSize)
+	}
+	buffer := alignedBuffer(int(size), hugePageSize)
+	adviseHugePages(buffer)
+	return buffer
+}
@@ -0,0 +1,14 @@
+//go:build linux
+
+package worker
+
+import "syscall"
+
+// adviseHugePages asks the kernel to back the whole huge pages of a
+// huge-page-aligned buffer with transparent huge pages. It is only advice,
+// so errors, such as huge pages being disabled, are ignored.
+func adviseHugePages(buffer []byte) {
+	if n := len(buffer) &^ (hugePageSize - 1); n > 0 {
+		syscall.Madvise(buffer[:n], syscall.MADV_HUGEPAGE)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package worker
+
+// adviseHugePages is a no-op where transparent huge pages can't be requested
+// for a range of memory.
+func adviseHugePages(buffer []byte) {}
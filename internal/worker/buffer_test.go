@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"unsafe"
+
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+// aligned reports whether buffer starts at a multiple of align.
+func aligned(buffer []byte, align int) bool {
+	return uintptr(unsafe.Pointer(unsafe.SliceData(buffer)))%uintptr(align) == 0
+}
+
+func TestAlignedBuffer(t *testing.T) {
+	for _, size := range []int{1, 1000, 4096, 65537} {
+		for _, align := range []int{512, 4096, hugePageSize} {
+			buffer := alignedBuffer(size, align)
+			if len(buffer) != size || cap(buffer) != size {
+				t.Errorf("size %d: expected length and capacity %d, got %d and %d", size, size, len(buffer), cap(buffer))
+			}
+			if !aligned(buffer, align) {
+				t.Errorf("size %d: buffer is not aligned to %d", size, align)
+			}
+		}
+	}
+}
+
+func TestNewBuffer(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 1, 4*hugePageSize)
+	if buffer := p.newBuffer(4 * hugePageSize); !aligned(buffer, pageSize) {
+		t.Error("expected buffers to be page-aligned")
+	}
+
+	// With huge pages, large buffers are aligned to them
+	p.SetHugePages(true)
+	if buffer := p.newBuffer(4 * hugePageSize); !aligned(buffer, hugePageSize) {
+		t.Error("expected large buffers to be aligned to huge pages")
+	}
+	if buffer := p.newBuffer(4096); len(buffer) != 4096 || !aligned(buffer, pageSize) {
+		t.Error("expected small buffers to stay page-aligned")
+	}
+}
+
+func TestWorkerPoolHugePages(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 2, hugePageSize)
+	p.SetHugePages(true)
+	p.Start(&generator.SequentialGenerator{}, 3*hugePageSize+100)
+
+	var total int64
+	for result := range drain(p) {
+		if !aligned(result.Buffer, hugePageSize) {
+			t.Errorf("chunk at offset %d is not aligned to huge pages", result.Offset)
+		}
+		want := make([]byte, len(result.Buffer))
+		(&generator.SequentialGenerator{}).GenerateAt(want, result.Offset)
+		if !bytes.Equal(result.Buffer, want) {
+			t.Errorf("chunk at offset %d has the wrong data", result.Offset)
+		}
+		total += int64(len(result.Buffer))
+		p.ReturnBuffer(result.Buffer)
+	}
+	if total != 3*hugePageSize+100 {
+		t.Errorf("expected %d bytes, got %d", 3*hugePageSize+100, total)
+	}
+}
@@ -1,36 +1,158 @@
 package worker
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
 	"runtime"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/maxkimambo/trasher/pkg/generator"
 )
 
 // WorkerPool manages a pool of worker goroutines for parallel data generation.
+// The workers run jobs (see NewJob), one after another or several at a time,
+// taking chunks of the highest-priority jobs first, until the pool is
+// closed. Start, Results and the other job methods of the pool itself run a
+// single job of the pool's own, and Wait closes the pool once that job is
+// done.
 type WorkerPool struct {
 	numWorkers int
 	chunkSize  int64
-	workChan   chan workItem
-	resultChan chan ResultItem
-	errorChan  chan error
+	job        *Job
+	jobsMu     sync.Mutex
+	jobs       map[*Job]struct{}
+	arrived    chan struct{}
+	served     uint64
+	startOnce  sync.Once
+	closeOnce  sync.Once
+	quit       chan struct{}
 	wg         sync.WaitGroup
 	ctx        context.Context
 	cancel     context.CancelFunc
 	bufferPool sync.Pool
+	pauseMu    sync.Mutex
+	resumeChan chan struct{}
+	scaleEvery time.Duration
+	scaleMu    sync.Mutex
+	active     int
+	scaleChan  chan struct{}
+	generated  atomic.Int64
+	generating atomic.Int64
+	blocked    atomic.Int64
+	budget     chan struct{}
+	tuner      *chunkTuner
+	hugePages  bool
+	doubleBuf  bool
+	handoffs   []chan func()
+	splitSize  int64
+	splitMu    sync.Mutex
+	splits     []*split
+	stats      []workerStats
 }
 
 // workItem represents a unit of work to be processed by a worker.
 type workItem struct {
+	job    *Job
 	offset int64
 	size   int64
 }
 
-// ResultItem represents the result of processed work.
-type ResultItem struct {
+// split is a chunk being generated in pieces, which idle workers can help
+// generate.
+type split struct {
+	job    *Job
+	buffer []byte
+	offset int64
+	size   int64
+	pieces int64
+	next   atomic.Int64
+	wg     sync.WaitGroup
+	errMu  sync.Mutex
+	err    error
+}
+
+// Job generates one output on a pool's workers. Jobs running at the same
+// time share the workers, the buffers and the memory budget, but each has
+// its own generators, results and errors, and stopping one leaves the
+// others running.
+type Job struct {
+	pool       *WorkerPool
+	ctx        context.Context
+	cancel     context.CancelFunc
+	gens       []generator.Generator
+	hashers    []hash.Hash
+	ordered    bool
+	newHash    func() hash.Hash
+	sink       SinkFunc
+	priority   int
+	served     uint64
+	queue      chan workItem
+	started    bool
+	results    chan Result
+	resultChan chan Result
+	unordered  chan Result
+	sequenced  chan struct{}
+	slots      chan struct{}
+	chunks     sync.WaitGroup
+	end        atomic.Int64
+	stop       chan struct{}
+	stopOnce   sync.Once
+	done       chan struct{}
+	waitOnce   sync.Once
+	errMu      sync.Mutex
+	errs       []ChunkError
+}
+
+// Task describes a chunk of an existing file or stream to process with Process.
+type Task struct {
+	Offset int64
+	Size   int64
+}
+
+// ChunkFunc processes a single task. The buffer has exactly task.Size bytes
+// and is only valid for the duration of the call.
+type ChunkFunc func(buffer []byte, task Task) error
+
+// SinkFunc consumes a generated chunk, typically by writing it out. The
+// buffer is only valid for the duration of the call.
+type SinkFunc func(result Result) error
+
+// ChunkError is an error that occurred generating, consuming or processing
+// the chunk at Offset.
+type ChunkError struct {
+	Offset int64
+	Err    error
+}
+
+// Error returns the error message with the chunk's offset.
+func (e ChunkError) Error() string {
+	return fmt.Sprintf("chunk at offset %d: %v", e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// Result is a generated chunk delivered on Results. Consumers return Buffer
+// with ReturnBuffer once they are done with it.
+type Result struct {
+	// Buffer holds the chunk's data, which belongs at Offset in the output.
 	Buffer []byte
 	Offset int64
+	// Checksum is the digest of Buffer when chunk hashing is enabled with
+	// SetChunkHash.
+	Checksum []byte
+	// Err is set if the chunk could not be generated, in which case Buffer
+	// is nil. The error is also recorded in Errors and stops the job.
+	Err error
 }
 
 // NewWorkerPool creates a new worker pool with the specified configuration.
@@ -49,121 +171,1106 @@ func NewWorkerPool(ctx context.Context, numWorkers int, chunkSize int64) *Worker
 	pool := &WorkerPool{
 		numWorkers: numWorkers,
 		chunkSize:  chunkSize,
-		workChan:   make(chan workItem, numWorkers*2),
-		resultChan: make(chan ResultItem, numWorkers*2),
-		errorChan:  make(chan error, numWorkers),
+		jobs:       make(map[*Job]struct{}),
+		arrived:    make(chan struct{}),
+		quit:       make(chan struct{}),
 		ctx:        ctx,
 		cancel:     cancel,
+		stats:      make([]workerStats, numWorkers),
 	}
 
 	// Initialize buffer pool
 	pool.bufferPool = sync.Pool{
 		New: func() interface{} {
-			buffer := make([]byte, chunkSize)
+			buffer := pool.newBuffer(pool.ChunkSize())
 			return &buffer
 		},
 	}
 
+	pool.job = pool.NewJob()
+
 	return pool
 }
 
-// Start begins the worker pool processing with the given generator and total size.
+// NewJob creates a job to run on the pool's workers. Configure it, start it
+// with Start or StartWithFactory and wait for it with Wait. Jobs must not be
+// started once the pool is closed.
+func (p *WorkerPool) NewJob() *Job {
+	ctx, cancel := context.WithCancel(p.ctx)
+	return &Job{
+		pool:       p,
+		ctx:        ctx,
+		cancel:     cancel,
+		queue:      make(chan workItem, p.numWorkers*2),
+		resultChan: make(chan Result, p.numWorkers*2),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// SetPriority sets the job's priority, 0 by default. While jobs of a higher
+// priority have chunks waiting, workers take none of a lower priority's, so
+// a small urgent job is not held up behind a large one. Jobs of the same
+// priority take turns. It must be called before Start.
+func (j *Job) SetPriority(priority int) {
+	j.priority = priority
+}
+
+// SetOrdered makes Results deliver chunks strictly in offset order. Workers
+// still generate in parallel, but only up to two chunks per worker ahead of
+// the next chunk to deliver, bounding the memory held for reordering. It must
+// be called before Start.
+func (j *Job) SetOrdered(ordered bool) {
+	j.ordered = ordered
+}
+
+// SetChunkHash makes workers hash each chunk they generate with a hash from
+// newHash and return the digest in Result.Checksum. Chunks are then
+// hashed in parallel rather than by the consumer of Results. It must be
+// called before Start.
+func (j *Job) SetChunkHash(newHash func() hash.Hash) {
+	j.newHash = newHash
+}
+
+// SetSink makes workers pass each chunk to sink themselves instead of sending
+// it to Results, saving a channel hop per chunk. sink is called concurrently
+// from all workers, except in ordered mode where chunks are passed to it one
+// at a time in offset order. An error from sink stops the job and is
+// recorded in Errors. It must be called before Start.
+func (j *Job) SetSink(sink SinkFunc) {
+	j.sink = sink
+}
+
+// SetOrdered sets ordered delivery for the pool's own job (see Job.SetOrdered).
+func (p *WorkerPool) SetOrdered(ordered bool) {
+	p.job.SetOrdered(ordered)
+}
+
+// SetChunkHash sets chunk hashing for the pool's own job (see Job.SetChunkHash).
+func (p *WorkerPool) SetChunkHash(newHash func() hash.Hash) {
+	p.job.SetChunkHash(newHash)
+}
+
+// SetSink sets the sink of the pool's own job (see Job.SetSink).
+func (p *WorkerPool) SetSink(sink SinkFunc) {
+	p.job.SetSink(sink)
+}
+
+// SetMemoryBudget bounds the memory held in chunk buffers to about bytes.
+// Chunks are only handed to workers while the budget allows another buffer,
+// and a buffer's share is returned when the buffer is (see ReturnBuffer), so
+// a slow consumer blocks generation instead of growing memory. The budget
+// always allows at least one chunk and is shared by all jobs. With chunk-size
+// tuning, every chunk counts as one of the pool's full chunk size. It must be
+// called before any job starts.
+func (p *WorkerPool) SetMemoryBudget(bytes int64) {
+	p.budget = make(chan struct{}, max(bytes/p.chunkSize, 1))
+}
+
+// SetHugePages makes the pool back chunk buffers of at least 2MB with
+// transparent huge pages where the kernel supports it, which reduces TLB
+// misses at high generation rates. Buffers are page-aligned either way. It
+// must be called before any job starts.
+func (p *WorkerPool) SetHugePages(hugePages bool) {
+	p.hugePages = hugePages
+}
+
+// SetDoubleBuffering gives each worker a second buffer: a chunk the worker
+// passes to a job's sink is consumed by a goroutine of the worker's own while
+// the worker generates its next chunk, overlapping generation with writing.
+// A worker waits for its previous chunk to be consumed before handing on the
+// next, so it never holds more than two chunks. Only jobs with an unordered
+// sink are affected. It must be called before any job starts.
+func (p *WorkerPool) SetDoubleBuffering(double bool) {
+	p.doubleBuf = double
+}
+
+// SetChunkTuning makes the pool tune its chunk size while it runs instead of
+// always queueing chunks of the size it was created with. Chunks start at
+// MinTunedChunkSize and double, up to the pool's chunk size, for as long as
+// the throughput of delivering them keeps improving. It must be called
+// before any job starts.
+func (p *WorkerPool) SetChunkTuning(tune bool) {
+	p.tuner = nil
+	if tune {
+		p.tuner = newChunkTuner(min(MinTunedChunkSize, p.chunkSize), p.chunkSize, 2*p.numWorkers+1)
+	}
+}
+
+// DefaultSplitSize is the size of the pieces chunks are split into so idle
+// workers can help generate them.
+const DefaultSplitSize = 1024 * 1024
+
+// SetSplitSize makes workers generate chunks in pieces of size bytes, and
+// lets workers with no chunk of their own take over pieces of chunks others
+// are generating. This keeps workers busy at the end of a job, when there
+// are fewer chunks left than workers and an expensive generator would leave
+// most of them idle. Only generators whose output can be divided anywhere
+// are split (see generator.Splittable). It must be called before any job
+// starts.
+func (p *WorkerPool) SetSplitSize(size int64) {
+	p.splitSize = size
+}
+
+// DefaultScaleInterval is how often an auto-scaling pool measures throughput.
+const DefaultScaleInterval = 500 * time.Millisecond
+
+// SetAutoScale makes the pool adjust how many of its workers are active while
+// it runs, measuring throughput every interval. The pool starts with two
+// active workers and adds one at a time for as long as throughput keeps
+// improving, up to the pool's worker count. Workers are removed when results
+// back up, as the consumer cannot keep up, or when an added worker did not
+// help. It must be called before any job starts.
+func (p *WorkerPool) SetAutoScale(interval time.Duration) {
+	p.scaleEvery = interval
+}
+
+// ActiveWorkers returns the number of workers currently generating chunks.
+func (p *WorkerPool) ActiveWorkers() int {
+	p.scaleMu.Lock()
+	defer p.scaleMu.Unlock()
+	if p.scaleEvery == 0 {
+		return p.numWorkers
+	}
+	return p.active
+}
+
+// Start begins generating the job's totalSize bytes with the given generator.
+// All workers share the generator.
+func (j *Job) Start(gen generator.Generator, totalSize int64) {
+	gens := make([]generator.Generator, j.pool.numWorkers)
+	for i := range gens {
+		gens[i] = gen
+	}
+	j.start(gens, totalSize)
+}
+
+// StartWithFactory is like Start but gives each worker its own generator,
+// created by newGen before any work starts, so workers never contend for a
+// generator's lock. The generators must produce the same data at the same
+// offset (see generator.NewFactory). The created generators are returned so
+// the caller can close them once the job is done.
+func (j *Job) StartWithFactory(newGen generator.Factory, totalSize int64) ([]generator.Generator, error) {
+	gens := make([]generator.Generator, j.pool.numWorkers)
+	for i := range gens {
+		gen, err := newGen()
+		if err != nil {
+			closeGenerators(gens[:i])
+			return nil, fmt.Errorf("failed to create generator for worker %d: %v", i, err)
+		}
+		gens[i] = gen
+	}
+	j.start(gens, totalSize)
+	return gens, nil
+}
+
+// Start starts the pool's own job (see Job.Start).
 func (p *WorkerPool) Start(gen generator.Generator, totalSize int64) {
-	// Start worker goroutines
-	for i := 0; i < p.numWorkers; i++ {
-		p.wg.Add(1)
-		go p.worker(gen)
+	p.job.Start(gen, totalSize)
+}
+
+// StartWithFactory starts the pool's own job (see Job.StartWithFactory).
+func (p *WorkerPool) StartWithFactory(newGen generator.Factory, totalSize int64) ([]generator.Generator, error) {
+	return p.job.StartWithFactory(newGen, totalSize)
+}
+
+// closeGenerators closes the generators that hold resources.
+func closeGenerators(gens []generator.Generator) {
+	for _, gen := range gens {
+		if closer, ok := gen.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+// start queues the job's chunks for the workers, one generator per worker.
+func (j *Job) start(gens []generator.Generator, totalSize int64) {
+	p := j.pool
+	j.gens = gens
+	j.hashers = make([]hash.Hash, len(gens))
+	j.started = true
+
+	// In ordered mode workers hand results to the sequencer instead
+	j.results = j.resultChan
+	if j.ordered {
+		j.unordered = make(chan Result, p.numWorkers*2)
+		j.sequenced = make(chan struct{})
+		j.slots = make(chan struct{}, p.numWorkers*2)
+		j.results = j.unordered
+		go j.sequence()
 	}
 
+	p.startWorkers()
+
+	p.jobsMu.Lock()
+	p.jobs[j] = struct{}{}
+	p.jobsMu.Unlock()
+
 	// Start work distributor goroutine
-	go p.distributeWork(totalSize)
+	go j.distributeWork(totalSize)
+}
+
+// startWorkers starts the worker goroutines the first time a job starts.
+func (p *WorkerPool) startWorkers() {
+	p.startOnce.Do(func() {
+		if p.scaleEvery > 0 {
+			p.active = min(2, p.numWorkers)
+			p.scaleChan = make(chan struct{})
+			go p.autoScale()
+		}
+
+		if p.doubleBuf {
+			p.handoffs = make([]chan func(), p.numWorkers)
+			for i := range p.handoffs {
+				p.handoffs[i] = make(chan func())
+				p.wg.Add(1)
+				go p.consumer(p.handoffs[i])
+			}
+		}
+
+		for i := 0; i < p.numWorkers; i++ {
+			p.wg.Add(1)
+			go p.worker(i)
+		}
+	})
 }
 
-// worker is the main worker goroutine that processes work items.
-func (p *WorkerPool) worker(gen generator.Generator) {
+// consumer runs the chunks a double-buffered worker hands off, one at a
+// time, until the pool is closed. Jobs wait for their handed-off chunks, so
+// none are left when it is.
+func (p *WorkerPool) consumer(handoff <-chan func()) {
 	defer p.wg.Done()
 
 	for {
 		select {
-		case <-p.ctx.Done():
+		case <-p.quit:
 			return
-		case work, ok := <-p.workChan:
-			if !ok {
-				return
-			}
+		case consume := <-handoff:
+			consume()
+		}
+	}
+}
 
-			// Get buffer from pool
-			bufferPtr := p.bufferPool.Get().(*[]byte)
-			buffer := *bufferPtr
+// worker is the main worker goroutine that processes work items of any job
+// until the pool is closed.
+func (p *WorkerPool) worker(id int) {
+	defer p.wg.Done()
 
-			// Resize buffer if needed for last chunk
-			if work.size < int64(len(buffer)) {
-				buffer = buffer[:work.size]
-			}
+	for {
+		// Idle while scaled down
+		if !p.waitUntilActive(id) {
+			return
+		}
 
-			// Generate data
-			if err := gen.Generate(buffer); err != nil {
-				select {
-				case p.errorChan <- err:
-				case <-p.ctx.Done():
-				}
-				p.cancel()
-				p.bufferPool.Put(bufferPtr)
+		work, ok, arrived := p.next()
+		if !ok {
+			// With no chunk of its own, help with another worker's
+			if p.help(id) {
+				continue
+			}
+			select {
+			case <-p.quit:
 				return
+			case <-arrived:
 			}
+			continue
+		}
+		work.job.generate(id, work)
+		p.setState(id, stateIdle)
+	}
+}
 
-			// Send result
+// next takes the next chunk to generate from the highest-priority job with
+// chunks queued, rotating between jobs of the same priority. If no chunk is
+// queued, it returns a channel that is closed when one is.
+func (p *WorkerPool) next() (workItem, bool, <-chan struct{}) {
+	p.jobsMu.Lock()
+	defer p.jobsMu.Unlock()
+
+	var next *Job
+	for job := range p.jobs {
+		if len(job.queue) == 0 {
+			continue
+		}
+		if next == nil || job.priority > next.priority ||
+			job.priority == next.priority && job.served < next.served {
+			next = job
+		}
+	}
+	if next == nil {
+		return workItem{}, false, p.arrived
+	}
+
+	// Chunks are only taken with the lock held, so the queue is not empty
+	p.served++
+	next.served = p.served
+	return <-next.queue, true, nil
+}
+
+// notify wakes the workers waiting for a chunk to be queued.
+func (p *WorkerPool) notify() {
+	p.jobsMu.Lock()
+	defer p.jobsMu.Unlock()
+
+	close(p.arrived)
+	p.arrived = make(chan struct{})
+}
+
+// generate generates one chunk of the job on the worker with the given id and
+// passes it on.
+func (j *Job) generate(id int, work workItem) {
+	p := j.pool
+	defer j.chunks.Done()
+
+	// Hold the chunk while the pool is paused, and drop it if the job was
+	// stopped
+	if p.IsPaused() {
+		p.setState(id, statePaused)
+	}
+	if !p.waitWhilePaused(j.ctx) || j.ctx.Err() != nil {
+		p.releaseBudget()
+		return
+	}
+	p.setState(id, stateGenerating)
+
+	// Get buffer from pool; buffers from before the chunk size was tuned
+	// up are too small and left to the garbage collector
+	bufferPtr := p.bufferPool.Get().(*[]byte)
+	if int64(cap(*bufferPtr)) < work.size {
+		buffer := p.newBuffer(max(work.size, p.ChunkSize()))
+		bufferPtr = &buffer
+	}
+
+	// Resize buffer if needed for last chunk
+	buffer := (*bufferPtr)[:work.size]
+
+	// Generate data
+	start := time.Now()
+	if err := j.generateChunk(id, buffer, work.offset); err != nil {
+		p.putBuffer(bufferPtr)
+		// A failed chunk stops the job, so it skips the sequencer
+		if j.sink == nil {
 			select {
-			case <-p.ctx.Done():
-				p.bufferPool.Put(bufferPtr)
-				return
-			case p.resultChan <- ResultItem{Buffer: buffer, Offset: work.offset}:
-				// Buffer will be returned to pool after processing
+			case j.resultChan <- Result{Offset: work.offset, Err: err}:
+			case <-j.ctx.Done():
 			}
 		}
+		j.fail(work.offset, err)
+		return
+	}
+	p.generated.Add(int64(len(buffer)))
+	p.stats[id].chunks.Add(1)
+	p.stats[id].bytes.Add(int64(len(buffer)))
+
+	result := Result{Buffer: buffer, Offset: work.offset}
+	if j.newHash != nil {
+		// Only this worker uses its hasher, so it needs no lock
+		if j.hashers[id] == nil {
+			j.hashers[id] = j.newHash()
+		}
+		hasher := j.hashers[id]
+		hasher.Reset()
+		hasher.Write(buffer)
+		result.Checksum = hasher.Sum(nil)
+	}
+	handOff := time.Now()
+	p.generating.Add(int64(handOff.Sub(start)))
+	p.setState(id, stateHandingOn)
+	defer func() {
+		p.blocked.Add(int64(time.Since(handOff)))
+	}()
+
+	// Consume the chunk here unless it has to be put in order first; a
+	// double-buffered worker hands it to its consumer and moves on
+	if j.sink != nil && !j.ordered {
+		if p.handoffs == nil {
+			j.consume(result, bufferPtr)
+			return
+		}
+		j.chunks.Add(1)
+		p.handoffs[id] <- func() {
+			defer j.chunks.Done()
+			j.consume(result, bufferPtr)
+		}
+		return
+	}
+
+	// Send result
+	select {
+	case <-j.ctx.Done():
+		p.putBuffer(bufferPtr)
+	case j.results <- result:
+		// Buffer will be returned to pool after processing
+		if !j.ordered {
+			p.delivered(work.size)
+		}
 	}
 }
 
-// distributeWork creates and distributes work items to workers.
-func (p *WorkerPool) distributeWork(totalSize int64) {
-	defer close(p.workChan)
+// consume passes an unordered chunk to the job's sink and returns its buffer.
+func (j *Job) consume(result Result, bufferPtr *[]byte) {
+	err := j.sink(result)
+	j.pool.putBuffer(bufferPtr)
+	if err != nil {
+		j.fail(result.Offset, err)
+		return
+	}
+	j.pool.delivered(int64(len(result.Buffer)))
+}
+
+// delivered records that a chunk of size bytes was passed on, for chunk-size
+// tuning.
+func (p *WorkerPool) delivered(size int64) {
+	if p.tuner != nil {
+		p.tuner.observe(size, time.Now())
+	}
+}
+
+// generateChunk fills buffer with the chunk at offset on the worker with the
+// given id. Chunks larger than the split size are generated piece by piece,
+// so idle workers can take over pieces (see help).
+func (j *Job) generateChunk(id int, buffer []byte, offset int64) error {
+	p := j.pool
+	gen := j.gens[id]
+	if p.splitSize <= 0 || int64(len(buffer)) <= p.splitSize || !generator.Splittable(gen) {
+		return generator.GenerateChunk(gen, buffer, offset)
+	}
+
+	s := &split{
+		job:    j,
+		buffer: buffer,
+		offset: offset,
+		size:   p.splitSize,
+		pieces: (int64(len(buffer)) + p.splitSize - 1) / p.splitSize,
+	}
+	p.splitMu.Lock()
+	p.splits = append(p.splits, s)
+	p.splitMu.Unlock()
+	p.notify()
+
+	for piece, ok := s.claim(); ok; piece, ok = s.claim() {
+		s.generate(gen, piece)
+	}
+
+	// No worker can start helping once the chunk is withdrawn, so the wait
+	// only covers pieces already taken over
+	p.splitMu.Lock()
+	p.splits = slices.DeleteFunc(p.splits, func(other *split) bool {
+		return other == s
+	})
+	p.splitMu.Unlock()
+	s.wg.Wait()
+
+	return s.err
+}
+
+// help generates a piece of a chunk another worker is generating. It returns
+// false if no chunk had pieces left.
+func (p *WorkerPool) help(id int) bool {
+	p.splitMu.Lock()
+	var s *split
+	for _, candidate := range p.splits {
+		if candidate.next.Load() < candidate.pieces {
+			s = candidate
+			break
+		}
+	}
+	if s != nil {
+		s.wg.Add(1)
+	}
+	p.splitMu.Unlock()
+
+	if s == nil {
+		return false
+	}
+	defer s.wg.Done()
+
+	if piece, ok := s.claim(); ok {
+		p.setState(id, stateGenerating)
+		s.generate(s.job.gens[id], piece)
+		p.setState(id, stateIdle)
+	}
+	return true
+}
+
+// claim takes the next piece of the chunk to generate.
+func (s *split) claim() (int64, bool) {
+	piece := s.next.Add(1) - 1
+	return piece, piece < s.pieces
+}
+
+// generate generates a piece of the chunk with gen, recording the first
+// error.
+func (s *split) generate(gen generator.Generator, piece int64) {
+	start := piece * s.size
+	end := min(start+s.size, int64(len(s.buffer)))
+	if err := generator.GenerateChunk(gen, s.buffer[start:end], s.offset+start); err != nil {
+		s.errMu.Lock()
+		if s.err == nil {
+			s.err = err
+		}
+		s.errMu.Unlock()
+	}
+}
+
+// distributeWork creates work items for the job and queues them for the
+// workers to take (see next).
+func (j *Job) distributeWork(totalSize int64) {
+	p := j.pool
+	defer j.finish()
 
 	var offset int64
-	for offset < totalSize {
-		size := p.chunkSize
+	for offset < totalSize && j.ctx.Err() == nil && !j.stopped() {
+		size := p.ChunkSize()
 		if remaining := totalSize - offset; remaining < size {
 			size = remaining
 		}
 
+		// In ordered mode, wait until the chunk is within the window
+		if j.slots != nil {
+			select {
+			case <-j.ctx.Done():
+				return
+			case <-j.stop:
+				return
+			case j.slots <- struct{}{}:
+			}
+		}
+
+		// Wait until the memory budget allows another buffer. Chunks take
+		// their share in offset order, so the sequencer never waits for a
+		// chunk that cannot get a buffer.
+		if p.budget != nil {
+			select {
+			case <-j.ctx.Done():
+				return
+			case <-j.stop:
+				return
+			case p.budget <- struct{}{}:
+			}
+		}
+
+		j.chunks.Add(1)
 		select {
-		case <-p.ctx.Done():
+		case <-j.ctx.Done():
+			j.chunks.Done()
+			p.releaseBudget()
 			return
-		case p.workChan <- workItem{offset: offset, size: size}:
+		case <-j.stop:
+			j.chunks.Done()
+			p.releaseBudget()
+			return
+		case j.queue <- workItem{job: j, offset: offset, size: size}:
 			offset += size
+			j.end.Store(offset)
+		}
+		p.notify()
+	}
+}
+
+// finish waits until the workers are done with the job's queued chunks and
+// marks the job as done.
+func (j *Job) finish() {
+	j.chunks.Wait()
+
+	j.pool.jobsMu.Lock()
+	delete(j.pool.jobs, j)
+	j.pool.jobsMu.Unlock()
+
+	close(j.done)
+}
+
+// sequence delivers the workers' results in offset order, holding chunks
+// that finish early until the chunks before them have been delivered.
+func (j *Job) sequence() {
+	defer close(j.sequenced)
+
+	pending := make(map[int64]Result)
+	var next int64
+	for result := range j.unordered {
+		if j.ctx.Err() != nil {
+			j.pool.ReturnBuffer(result.Buffer)
+			continue
+		}
+
+		pending[result.Offset] = result
+		for item, ok := pending[next]; ok && j.ctx.Err() == nil; item, ok = pending[next] {
+			delete(pending, next)
+			size := int64(len(item.Buffer))
+			if j.deliver(item) {
+				next += size
+				<-j.slots
+			}
+		}
+	}
+}
+
+// deliver passes an ordered result to the sink, or sends it to Results. It
+// returns false if the result could not be delivered.
+func (j *Job) deliver(item Result) bool {
+	if j.sink != nil {
+		size := int64(len(item.Buffer))
+		err := j.sink(item)
+		j.pool.ReturnBuffer(item.Buffer)
+		if err != nil {
+			j.fail(item.Offset, err)
+			return false
+		}
+		j.pool.delivered(size)
+		return true
+	}
+
+	select {
+	case <-j.ctx.Done():
+		j.pool.ReturnBuffer(item.Buffer)
+		return false
+	case j.resultChan <- item:
+		j.pool.delivered(int64(len(item.Buffer)))
+		return true
+	}
+}
+
+// Fail records an error for the chunk at offset and stops the job. Consumers
+// of Results call it when they cannot handle a chunk, so their errors are
+// reported along with the workers'. Cancellation errors, which stopping the
+// job causes, are not recorded.
+func (j *Job) Fail(offset int64, err error) {
+	j.fail(offset, err)
+}
+
+// Fail fails a chunk of the pool's own job (see Job.Fail).
+func (p *WorkerPool) Fail(offset int64, err error) {
+	p.job.Fail(offset, err)
+}
+
+// fail records an error for the chunk at offset and stops the job.
+func (j *Job) fail(offset int64, err error) {
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		j.errMu.Lock()
+		j.errs = append(j.errs, ChunkError{Offset: offset, Err: err})
+		j.errMu.Unlock()
+	}
+	j.cancel()
+}
+
+// Cancel stops the job. Chunks being generated are finished but not
+// delivered, and the job's remaining chunks are dropped.
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// Stop ends the job early without cancelling it: no more chunks are handed
+// out, but those already handed out are still generated and delivered. As
+// chunks are handed out in offset order, the job then has delivered exactly
+// the data before End.
+func (j *Job) Stop() {
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+}
+
+// Stop ends the pool's own job early (see Job.Stop).
+func (p *WorkerPool) Stop() {
+	p.job.Stop()
+}
+
+// stopped reports whether Stop was called.
+func (j *Job) stopped() bool {
+	select {
+	case <-j.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// End returns the offset the job's chunks have been handed out up to. Once
+// Wait has returned, it is the job's total size unless the job was stopped
+// or cancelled early.
+func (j *Job) End() int64 {
+	return j.end.Load()
+}
+
+// End returns the end of the pool's own job (see Job.End).
+func (p *WorkerPool) End() int64 {
+	return p.job.End()
+}
+
+// waitUntilActive blocks while the worker with the given id is scaled down.
+// It returns false if the pool was closed while waiting.
+func (p *WorkerPool) waitUntilActive(id int) bool {
+	if p.scaleEvery == 0 {
+		return true
+	}
+
+	for {
+		p.scaleMu.Lock()
+		active, changed := p.active, p.scaleChan
+		p.scaleMu.Unlock()
+
+		if id < active {
+			p.setState(id, stateIdle)
+			return true
+		}
+		p.setState(id, stateScaledDown)
+		select {
+		case <-changed:
+		case <-p.quit:
+			return false
+		}
+	}
+}
+
+// setActive changes the number of active workers and wakes idle workers.
+func (p *WorkerPool) setActive(active int) {
+	p.scaleMu.Lock()
+	defer p.scaleMu.Unlock()
+
+	p.active = active
+	close(p.scaleChan)
+	p.scaleChan = make(chan struct{})
+}
+
+// scaleHold is the number of intervals the pool waits before trying another
+// worker after adding one did not help.
+const scaleHold = 5
+
+// autoScale adjusts the number of active workers to measured throughput
+// until the pool is closed.
+func (p *WorkerPool) autoScale() {
+	ticker := time.NewTicker(p.scaleEvery)
+	defer ticker.Stop()
+
+	var lastRate int64
+	grew := false
+	hold := 0
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-ticker.C:
+		}
+
+		rate := p.generated.Swap(0)
+		active := p.ActiveWorkers()
+
+		switch {
+		case p.backlog() >= active && active > 1:
+			// Results are backing up: the consumer is the bottleneck
+			p.setActive(active - 1)
+			grew = false
+			hold = scaleHold
+		case grew && rate*20 <= lastRate*21:
+			// The last worker added gained less than 5%
+			p.setActive(active - 1)
+			grew = false
+			hold = scaleHold
+		case hold > 0:
+			hold--
+			grew = false
+		case active < p.numWorkers && rate > 0:
+			p.setActive(active + 1)
+			grew = true
+		default:
+			grew = false
+		}
+		lastRate = rate
+	}
+}
+
+// backlog returns the number of results waiting for consumers across all
+// running jobs.
+func (p *WorkerPool) backlog() int {
+	p.jobsMu.Lock()
+	defer p.jobsMu.Unlock()
+
+	backlog := 0
+	for job := range p.jobs {
+		backlog += len(job.resultChan)
+	}
+	return backlog
+}
+
+// Process runs fn for every task on the pool's workers and blocks until all
+// tasks are done, the context is cancelled or fn returns an error. Each call
+// gets a pooled buffer, so fn can read into it without allocating. Process
+// is an alternative to jobs for work such as verification that consumes
+// chunks instead of generating them. It runs workers of its own, so it can
+// be called repeatedly and while jobs run.
+func (p *WorkerPool) Process(tasks []Task, fn ChunkFunc) error {
+	return p.ProcessTasks(tasks, func(task Task) error {
+		bufferPtr := p.bufferPool.Get().(*[]byte)
+		defer p.bufferPool.Put(bufferPtr)
+
+		buffer := *bufferPtr
+		if task.Size > int64(cap(buffer)) {
+			buffer = p.newBuffer(task.Size)
 		}
+		return fn(buffer[:task.Size], task)
+	})
+}
+
+// ProcessTasks is like Process but does not hand out buffers, for callers
+// that already have the data in memory, such as a memory-mapped file.
+func (p *WorkerPool) ProcessTasks(tasks []Task, fn func(task Task) error) error {
+	// The job only tracks cancellation and errors for this call
+	job := p.NewJob()
+	defer job.cancel()
+
+	taskChan := make(chan Task)
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.processWorker(taskChan, fn)
+		}()
+	}
+
+	go func() {
+		defer close(taskChan)
+		for _, task := range tasks {
+			select {
+			case <-job.ctx.Done():
+				return
+			case taskChan <- task:
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	if errs := job.Errors(); len(errs) > 0 {
+		return errs[0].Err
+	}
+	return job.ctx.Err()
+}
+
+// ProcessOrdered is like Process, but after fn has filled a task's buffer,
+// consume is called with it for one task at a time, in the order the tasks
+// were given. Work that must see the data in order, such as hashing a
+// stream, still gets the buffers filled in parallel. Tasks are handed to
+// workers in order, so a worker only waits for tasks handed out before its
+// own. Tasks must have distinct offsets.
+func (p *WorkerPool) ProcessOrdered(tasks []Task, fn, consume ChunkFunc) error {
+	turns := make(map[int64]int, len(tasks))
+	for i, task := range tasks {
+		turns[task.Offset] = i
+	}
+
+	var (
+		mu      sync.Mutex
+		turn    = sync.NewCond(&mu)
+		next    int
+		stopped bool
+	)
+	// Wake workers waiting for their turn when the run stops, since the
+	// task they wait for may never be consumed
+	stop := func() {
+		mu.Lock()
+		stopped = true
+		turn.Broadcast()
+		mu.Unlock()
+	}
+	defer context.AfterFunc(p.ctx, stop)()
+
+	return p.Process(tasks, func(buffer []byte, task Task) error {
+		if err := fn(buffer, task); err != nil {
+			stop()
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for next != turns[task.Offset] && !stopped {
+			turn.Wait()
+		}
+		if stopped {
+			return context.Canceled
+		}
+		if err := consume(buffer, task); err != nil {
+			stopped = true
+			turn.Broadcast()
+			return err
+		}
+		next++
+		turn.Broadcast()
+		return nil
+	})
+}
+
+// processWorker runs fn for tasks until the task channel is drained.
+func (j *Job) processWorker(tasks <-chan Task, fn func(task Task) error) {
+	for {
+		select {
+		case <-j.ctx.Done():
+			return
+		case task, ok := <-tasks:
+			if !ok {
+				return
+			}
+
+			if !j.pool.waitWhilePaused(j.ctx) {
+				return
+			}
+
+			if err := fn(task); err != nil {
+				j.fail(task.Offset, err)
+				return
+			}
+		}
+	}
+}
+
+// Pause stops workers from starting new chunks until Resume is called.
+// Chunks already being generated are allowed to complete.
+func (p *WorkerPool) Pause() {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+
+	if p.resumeChan == nil {
+		p.resumeChan = make(chan struct{})
 	}
 }
 
-// Results returns the result channel for reading processed chunks.
-func (p *WorkerPool) Results() <-chan ResultItem {
-	return p.resultChan
+// Resume lets workers continue after Pause.
+func (p *WorkerPool) Resume() {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+
+	if p.resumeChan != nil {
+		close(p.resumeChan)
+		p.resumeChan = nil
+	}
 }
 
-// Errors returns the error channel for reading worker errors.
-func (p *WorkerPool) Errors() <-chan error {
-	return p.errorChan
+// IsPaused returns whether the pool is currently paused.
+func (p *WorkerPool) IsPaused() bool {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+	return p.resumeChan != nil
+}
+
+// waitWhilePaused blocks while the pool is paused.
+// It returns false if ctx was cancelled while waiting.
+func (p *WorkerPool) waitWhilePaused(ctx context.Context) bool {
+	p.pauseMu.Lock()
+	resume := p.resumeChan
+	p.pauseMu.Unlock()
+
+	if resume == nil {
+		return true
+	}
+
+	select {
+	case <-resume:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
-// ReturnBuffer returns a buffer to the pool for reuse.
+// Results returns the result channel for reading the job's processed chunks.
+func (j *Job) Results() <-chan Result {
+	return j.resultChan
+}
+
+// Results returns the results of the pool's own job (see Job.Results).
+func (p *WorkerPool) Results() <-chan Result {
+	return p.job.Results()
+}
+
+// Errors returns the errors recorded so far, sorted by offset. The first
+// error stops the job, but chunks already in progress may fail as well, so
+// there can be several.
+func (j *Job) Errors() []ChunkError {
+	j.errMu.Lock()
+	defer j.errMu.Unlock()
+
+	errs := slices.Clone(j.errs)
+	slices.SortFunc(errs, func(a, b ChunkError) int {
+		return cmp.Compare(a.Offset, b.Offset)
+	})
+	return errs
+}
+
+// Errors returns the errors of the pool's own job (see Job.Errors).
+func (p *WorkerPool) Errors() []ChunkError {
+	return p.job.Errors()
+}
+
+// ReturnBuffer returns a buffer to the pool for reuse. Results without a
+// buffer are ignored.
 func (p *WorkerPool) ReturnBuffer(buffer []byte) {
-	p.bufferPool.Put(&buffer)
+	if cap(buffer) == 0 {
+		return
+	}
+
+	// The last chunk's buffer is shorter; restore its full length
+	buffer = buffer[:cap(buffer)]
+	p.putBuffer(&buffer)
+}
+
+// putBuffer returns a chunk buffer to the pool and its share of the memory
+// budget.
+func (p *WorkerPool) putBuffer(bufferPtr *[]byte) {
+	p.bufferPool.Put(bufferPtr)
+	p.releaseBudget()
+}
+
+// releaseBudget returns a chunk's share of the memory budget.
+func (p *WorkerPool) releaseBudget() {
+	if p.budget != nil {
+		select {
+		case <-p.budget:
+		default:
+		}
+	}
+}
+
+// Wait waits until the job is done and closes its result channel.
+func (j *Job) Wait() {
+	j.waitOnce.Do(func() {
+		if j.started {
+			<-j.done
+		}
+		if j.sequenced != nil {
+			close(j.unordered)
+			<-j.sequenced
+		}
+		close(j.resultChan)
+		j.cancel()
+	})
 }
 
-// Wait waits for all workers to complete and closes result channels.
+// Wait waits for the pool's own job to complete, closes its result channel
+// and closes the pool.
 func (p *WorkerPool) Wait() {
-	p.wg.Wait()
-	close(p.resultChan)
-	close(p.errorChan)
+	p.job.Wait()
+	p.Close()
+}
+
+// Close stops the workers, cancelling any jobs still running, and waits
+// until they have exited.
+func (p *WorkerPool) Close() {
+	p.closeOnce.Do(func() {
+		p.cancel()
+
+		// Workers drop the remaining chunks of the cancelled jobs
+		p.jobsMu.Lock()
+		running := make([]*Job, 0, len(p.jobs))
+		for job := range p.jobs {
+			running = append(running, job)
+		}
+		p.jobsMu.Unlock()
+		for _, job := range running {
+			<-job.done
+		}
+
+		close(p.quit)
+		p.wg.Wait()
+	})
 }
 
 // Shutdown gracefully shuts down the worker pool.
@@ -177,7 +1284,11 @@ func (p *WorkerPool) NumWorkers() int {
 	return p.numWorkers
 }
 
-// ChunkSize returns the chunk size used by the worker pool.
+// ChunkSize returns the chunk size used by the worker pool. With chunk-size
+// tuning, it is the size currently chosen.
 func (p *WorkerPool) ChunkSize() int64 {
+	if p.tuner != nil {
+		return p.tuner.current()
+	}
 	return p.chunkSize
-}
\ No newline at end of file
+}
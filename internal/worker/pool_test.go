@@ -1,10 +1,15 @@
 package worker
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -134,6 +139,85 @@ func TestWorkerPoolWithDifferentGenerators(t *testing.T) {
 	}
 }
 
+func TestWorkerPoolStartWithFactory(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 3, 512)
+
+	var created []*generator.SequentialGenerator
+	gens, err := p.StartWithFactory(func() (generator.Generator, error) {
+		gen := &generator.SequentialGenerator{}
+		created = append(created, gen)
+		return gen, nil
+	}, 4096)
+	if err != nil {
+		t.Fatalf("StartWithFactory failed: %v", err)
+	}
+	if len(gens) != 3 || len(created) != 3 {
+		t.Fatalf("expected a generator per worker, got %d (%d created)", len(gens), len(created))
+	}
+
+	data := make([]byte, 4096)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range p.Results() {
+			copy(data[result.Offset:], result.Buffer)
+			p.ReturnBuffer(result.Buffer)
+		}
+	}()
+	p.Wait()
+	<-done
+
+	expected := make([]byte, 4096)
+	generator.GenerateChunk(&generator.SequentialGenerator{}, expected, 0)
+	if !bytes.Equal(data, expected) {
+		t.Error("expected chunks from separate generators to line up")
+	}
+}
+
+func TestWorkerPoolChunkHash(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 2, 1000)
+	p.SetChunkHash(sha256.New)
+	p.Start(&generator.SequentialGenerator{}, 3500)
+
+	checked := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range p.Results() {
+			expected := sha256.Sum256(result.Buffer)
+			if !bytes.Equal(result.Checksum, expected[:]) {
+				t.Errorf("chunk at offset %d: checksum does not match its data", result.Offset)
+			}
+			checked++
+			p.ReturnBuffer(result.Buffer)
+		}
+	}()
+	p.Wait()
+	<-done
+
+	if checked != 4 {
+		t.Errorf("expected 4 chunks, got %d", checked)
+	}
+}
+
+func TestWorkerPoolStartWithFactoryError(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 3, 512)
+
+	calls := 0
+	_, err := p.StartWithFactory(func() (generator.Generator, error) {
+		if calls++; calls == 2 {
+			return nil, fmt.Errorf("out of generators")
+		}
+		return &generator.ZeroGenerator{}, nil
+	}, 4096)
+	if err == nil || !strings.Contains(err.Error(), "out of generators") {
+		t.Errorf("expected factory error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected creation to stop at the failure, got %d calls", calls)
+	}
+}
+
 func TestWorkerPoolErrorHandling(t *testing.T) {
 	// Create a generator that always fails
 	failingGen := &FailingGenerator{}
@@ -144,17 +228,146 @@ func TestWorkerPoolErrorHandling(t *testing.T) {
 	totalSize := int64(2048)
 	p.Start(failingGen, totalSize)
 
-	// Should receive an error
-	select {
-	case err := <-p.Errors():
-		if err == nil {
-			t.Error("expected error but got nil")
+	go func() {
+		for range p.Results() {
 		}
-	case <-time.After(time.Second):
-		t.Error("timeout waiting for error")
+	}()
+	p.Wait()
+
+	// Should have recorded an error
+	if errs := p.Errors(); len(errs) == 0 || errs[0].Err == nil {
+		t.Errorf("expected an error, got %v", errs)
+	}
+}
+
+// barrierGenerator fails every chunk, but only once n chunks are being
+// generated at the same time.
+type barrierGenerator struct {
+	generator.ZeroGenerator
+	barrier sync.WaitGroup
+}
+
+func newBarrierGenerator(n int) *barrierGenerator {
+	g := &barrierGenerator{}
+	g.barrier.Add(n)
+	return g
+}
+
+func (g *barrierGenerator) GenerateAt(buffer []byte, offset int64) error {
+	g.barrier.Done()
+	g.barrier.Wait()
+	return fmt.Errorf("no data at %d", offset)
+}
+
+func TestWorkerPoolErrors(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		p := NewWorkerPool(context.Background(), 3, 1024)
+		p.SetOrdered(ordered)
+		p.Start(newBarrierGenerator(3), 8*1024)
+
+		go func() {
+			for result := range p.Results() {
+				p.ReturnBuffer(result.Buffer)
+			}
+		}()
+		p.Wait()
+
+		// Every chunk in progress when the pool stopped is reported, in
+		// offset order
+		errs := p.Errors()
+		if len(errs) != 3 {
+			t.Fatalf("ordered=%v: expected 3 errors, got %v", ordered, errs)
+		}
+		for i, chunkErr := range errs {
+			offset := int64(i) * 1024
+			if chunkErr.Offset != offset {
+				t.Errorf("ordered=%v: expected error %d at offset %d, got %d", ordered, i, offset, chunkErr.Offset)
+			}
+			want := fmt.Sprintf("chunk at offset %d: no data at %d", offset, offset)
+			if chunkErr.Error() != want {
+				t.Errorf("ordered=%v: expected %q, got %q", ordered, want, chunkErr.Error())
+			}
+		}
+	}
+}
+
+func TestWorkerPoolFail(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 2, 1024)
+	p.Start(&generator.ZeroGenerator{}, 1024*1024)
+
+	// A consumer that can't handle a chunk reports it and stops the pool
+	result := <-p.Results()
+	writeErr := fmt.Errorf("disk full")
+	p.Fail(result.Offset, writeErr)
+	p.ReturnBuffer(result.Buffer)
+	go func() {
+		for result := range p.Results() {
+			p.ReturnBuffer(result.Buffer)
+		}
+	}()
+	p.Wait()
+
+	errs := p.Errors()
+	if len(errs) != 1 || errs[0].Offset != result.Offset || !errors.Is(errs[0], writeErr) {
+		t.Errorf("expected the consumer's error at offset %d, got %v", result.Offset, errs)
 	}
+}
 
-	p.Shutdown()
+func TestWorkerPoolShutdownErrors(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 2, 1024)
+	p.SetSink(func(result Result) error {
+		return context.Canceled
+	})
+	p.Start(&generator.ZeroGenerator{}, 1024*1024)
+	p.Wait()
+
+	// Errors caused by stopping the pool are not recorded
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
+
+// failAtGenerator generates sequential data but fails for the chunk at one
+// offset.
+type failAtGenerator struct {
+	generator.SequentialGenerator
+	offset int64
+}
+
+func (g *failAtGenerator) GenerateAt(buffer []byte, offset int64) error {
+	if offset == g.offset {
+		return fmt.Errorf("no data at %d", offset)
+	}
+	return g.SequentialGenerator.GenerateAt(buffer, offset)
+}
+
+func TestWorkerPoolResultErr(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		p := NewWorkerPool(context.Background(), 2, 1024)
+		p.SetOrdered(ordered)
+		p.Start(&failAtGenerator{offset: 3072}, 8*1024)
+
+		var failed []Result
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for result := range p.Results() {
+				if result.Err != nil {
+					failed = append(failed, result)
+				}
+				p.ReturnBuffer(result.Buffer)
+			}
+		}()
+		p.Wait()
+		<-done
+
+		if len(failed) != 1 || failed[0].Offset != 3072 || failed[0].Buffer != nil {
+			t.Fatalf("ordered=%v: expected one failed result at offset 3072 without a buffer, got %+v", ordered, failed)
+		}
+		if !strings.Contains(failed[0].Err.Error(), "no data at 3072") {
+			t.Errorf("ordered=%v: unexpected error %v", ordered, failed[0].Err)
+		}
+	}
 }
 
 func TestWorkerPoolGracefulShutdown(t *testing.T) {
@@ -330,6 +543,34 @@ func TestWorkerPoolConcurrency(t *testing.T) {
 	}
 }
 
+func TestWorkerPoolSequentialIsDeterministic(t *testing.T) {
+	ctx := context.Background()
+	p := NewWorkerPool(ctx, 4, 1000)
+
+	totalSize := int64(10000)
+	p.Start(&generator.SequentialGenerator{}, totalSize)
+
+	file := make([]byte, totalSize)
+	done := make(chan struct{})
+	go func() {
+		for result := range p.Results() {
+			copy(file[result.Offset:], result.Buffer)
+			p.ReturnBuffer(result.Buffer)
+		}
+		close(done)
+	}()
+
+	p.Wait()
+	<-done
+
+	// Every byte must be derived from its file offset regardless of chunk order
+	for i, b := range file {
+		if b != byte(i%256) {
+			t.Fatalf("byte at offset %d: expected %d, got %d", i, byte(i%256), b)
+		}
+	}
+}
+
 // FailingGenerator is a test generator that always returns an error
 type FailingGenerator struct{}
 
@@ -375,4 +616,819 @@ func BenchmarkWorkerPool(b *testing.B) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestWorkerPoolPauseResume(t *testing.T) {
+	ctx := context.Background()
+	p := NewWorkerPool(ctx, 2, 1024)
+
+	p.Pause()
+	if !p.IsPaused() {
+		t.Fatal("expected pool to be paused")
+	}
+
+	p.Start(&generator.ZeroGenerator{}, 4096)
+
+	// No chunks should be produced while paused
+	select {
+	case <-p.Results():
+		t.Fatal("received result while paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	p.Resume()
+	if p.IsPaused() {
+		t.Fatal("expected pool to be resumed")
+	}
+
+	count := 0
+	done := make(chan struct{})
+	go func() {
+		for result := range p.Results() {
+			count++
+			p.ReturnBuffer(result.Buffer)
+		}
+		close(done)
+	}()
+
+	p.Wait()
+	<-done
+
+	if count != 4 {
+		t.Errorf("expected 4 chunks after resume, got %d", count)
+	}
+}
+
+func TestWorkerPoolCancelWhilePaused(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewWorkerPool(ctx, 2, 1024)
+
+	p.Pause()
+	p.Start(&generator.ZeroGenerator{}, 4096)
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("paused workers did not exit on cancellation")
+	}
+}
+
+func TestWorkerPoolProcess(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 4, 1024)
+
+	var tasks []Task
+	for offset := int64(0); offset < 10*1024; offset += 1024 {
+		tasks = append(tasks, Task{Offset: offset, Size: 1024})
+	}
+	tasks = append(tasks, Task{Offset: 10 * 1024, Size: 100}, Task{Offset: 20 * 1024, Size: 4096})
+
+	var mu sync.Mutex
+	seen := make(map[int64]int)
+	err := p.Process(tasks, func(buffer []byte, task Task) error {
+		if int64(len(buffer)) != task.Size {
+			return fmt.Errorf("buffer of %d bytes for task of %d bytes", len(buffer), task.Size)
+		}
+		mu.Lock()
+		seen[task.Offset]++
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if len(seen) != len(tasks) {
+		t.Errorf("expected %d tasks processed, got %d", len(tasks), len(seen))
+	}
+	for offset, count := range seen {
+		if count != 1 {
+			t.Errorf("task at offset %d processed %d times", offset, count)
+		}
+	}
+}
+
+func TestWorkerPoolProcessError(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 2, 1024)
+
+	tasks := make([]Task, 100)
+	for i := range tasks {
+		tasks[i] = Task{Offset: int64(i) * 1024, Size: 1024}
+	}
+
+	expected := fmt.Errorf("read failed")
+	err := p.Process(tasks, func(buffer []byte, task Task) error {
+		if task.Offset == 5*1024 {
+			return expected
+		}
+		return nil
+	})
+	if err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
+
+func TestWorkerPoolProcessCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewWorkerPool(ctx, 2, 1024)
+
+	tasks := make([]Task, 1000)
+	for i := range tasks {
+		tasks[i] = Task{Offset: int64(i) * 1024, Size: 1024}
+	}
+
+	var processed int
+	var mu sync.Mutex
+	err := p.Process(tasks, func(buffer []byte, task Task) error {
+		mu.Lock()
+		processed++
+		if processed == 10 {
+			cancel()
+		}
+		mu.Unlock()
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if processed == len(tasks) {
+		t.Error("expected cancellation to stop processing early")
+	}
+}
+
+func TestWorkerPoolProcessTasks(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 3, 1024)
+
+	data := make([]byte, 8*1024)
+	tasks := make([]Task, 8)
+	for i := range tasks {
+		tasks[i] = Task{Offset: int64(i) * 1024, Size: 1024}
+	}
+
+	err := p.ProcessTasks(tasks, func(task Task) error {
+		chunk := data[task.Offset : task.Offset+task.Size]
+		for i := range chunk {
+			chunk[i] = byte(task.Offset / 1024)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ProcessTasks failed: %v", err)
+	}
+
+	for i, b := range data {
+		if b != byte(i/1024) {
+			t.Fatalf("byte %d: expected %d, got %d", i, i/1024, b)
+		}
+	}
+}
+
+func TestWorkerPoolProcessOrdered(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 4, 1024)
+
+	tasks := make([]Task, 50)
+	for i := range tasks {
+		tasks[i] = Task{Offset: int64(i) * 1024, Size: 1024}
+	}
+
+	var order []int64
+	err := p.ProcessOrdered(tasks, func(buffer []byte, task Task) error {
+		// Earlier tasks take longer, so they finish out of order
+		time.Sleep(time.Duration(50-task.Offset/1024) * time.Millisecond / 10)
+		buffer[0] = byte(task.Offset / 1024)
+		return nil
+	}, func(buffer []byte, task Task) error {
+		if buffer[0] != byte(task.Offset/1024) {
+			return fmt.Errorf("task at offset %d got another task's buffer", task.Offset)
+		}
+		order = append(order, task.Offset)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ProcessOrdered failed: %v", err)
+	}
+
+	if len(order) != len(tasks) {
+		t.Fatalf("expected %d tasks consumed, got %d", len(tasks), len(order))
+	}
+	for i, offset := range order {
+		if offset != tasks[i].Offset {
+			t.Fatalf("task %d: expected offset %d, got %d", i, tasks[i].Offset, offset)
+		}
+	}
+}
+
+func TestWorkerPoolProcessOrderedError(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 4, 1024)
+
+	tasks := make([]Task, 100)
+	for i := range tasks {
+		tasks[i] = Task{Offset: int64(i) * 1024, Size: 1024}
+	}
+
+	expected := fmt.Errorf("read failed")
+	var consumed int
+	err := p.ProcessOrdered(tasks, func(buffer []byte, task Task) error {
+		if task.Offset == 5*1024 {
+			return expected
+		}
+		return nil
+	}, func(buffer []byte, task Task) error {
+		consumed++
+		return nil
+	})
+	if err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	if consumed > 5 {
+		t.Errorf("expected only tasks before the failed one to be consumed, got %d", consumed)
+	}
+}
+
+// slowStartGenerator generates sequential data, taking longer for earlier
+// chunks so they finish after the chunks behind them.
+type slowStartGenerator struct {
+	generator.SequentialGenerator
+}
+
+func (g *slowStartGenerator) GenerateAt(buffer []byte, offset int64) error {
+	if offset < 4096 {
+		time.Sleep(time.Duration(4096-offset) * time.Millisecond / 100)
+	}
+	return g.SequentialGenerator.GenerateAt(buffer, offset)
+}
+
+func TestWorkerPoolOrdered(t *testing.T) {
+	ctx := context.Background()
+	p := NewWorkerPool(ctx, 4, 1024)
+	p.SetOrdered(true)
+	p.Start(&slowStartGenerator{}, 20*1024+100)
+
+	var offsets []int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range p.Results() {
+			expected := make([]byte, len(result.Buffer))
+			(&generator.SequentialGenerator{}).GenerateAt(expected, result.Offset)
+			if !bytes.Equal(result.Buffer, expected) {
+				t.Errorf("chunk at %d has the wrong data", result.Offset)
+			}
+			offsets = append(offsets, result.Offset)
+			p.ReturnBuffer(result.Buffer)
+		}
+	}()
+
+	p.Wait()
+	<-done
+
+	if len(offsets) != 21 {
+		t.Fatalf("expected 21 chunks, got %d", len(offsets))
+	}
+	for i, offset := range offsets {
+		if offset != int64(i)*1024 {
+			t.Fatalf("expected chunk %d at offset %d, got %d", i, i*1024, offset)
+		}
+	}
+}
+
+func TestWorkerPoolOrderedCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewWorkerPool(ctx, 4, 1024)
+	p.SetOrdered(true)
+	p.Start(&slowStartGenerator{}, 1024*1024)
+
+	// Stop reading after a few chunks, then cancel
+	for i := 0; i < 3; i++ {
+		result := <-p.Results()
+		p.ReturnBuffer(result.Buffer)
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait did not return after cancellation")
+	}
+}
+
+// sleepyGenerator takes a fixed time per chunk without using the CPU, so its
+// throughput grows with every worker added.
+type sleepyGenerator struct {
+	generator.ZeroGenerator
+}
+
+func (g *sleepyGenerator) GenerateAt(buffer []byte, offset int64) error {
+	time.Sleep(5 * time.Millisecond)
+	return nil
+}
+
+func TestWorkerPoolAutoScaleUp(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 8, 1024)
+	p.SetAutoScale(20 * time.Millisecond)
+	p.Start(&sleepyGenerator{}, 400*1024)
+
+	count, maxActive := 0, 0
+	for result := range drain(p) {
+		count++
+		maxActive = max(maxActive, p.ActiveWorkers())
+		p.ReturnBuffer(result.Buffer)
+	}
+
+	if count != 400 {
+		t.Errorf("expected 400 chunks, got %d", count)
+	}
+	if maxActive <= 2 {
+		t.Errorf("expected the pool to scale beyond 2 workers, peaked at %d", maxActive)
+	}
+}
+
+func TestWorkerPoolAutoScaleDown(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 8, 1024)
+	p.SetAutoScale(20 * time.Millisecond)
+	p.Start(&generator.ZeroGenerator{}, 40*1024)
+
+	// A slow consumer backs results up, so workers are removed
+	count := 0
+	for result := range drain(p) {
+		count++
+		time.Sleep(10 * time.Millisecond)
+		p.ReturnBuffer(result.Buffer)
+	}
+
+	if count != 40 {
+		t.Errorf("expected 40 chunks, got %d", count)
+	}
+	// The pool may be probing with a second worker
+	if active := p.ActiveWorkers(); active > 2 {
+		t.Errorf("expected a slow consumer to keep the pool at 1 or 2 workers, got %d", active)
+	}
+}
+
+// drain returns the pool's results and waits for the pool in the background.
+func drain(p *WorkerPool) <-chan Result {
+	go p.Wait()
+	return p.Results()
+}
+
+func TestWorkerPoolSink(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		p := NewWorkerPool(context.Background(), 4, 1024)
+		p.SetOrdered(ordered)
+
+		var mu sync.Mutex
+		var offsets []int64
+		data := make([]byte, 20*1024+100)
+		p.SetSink(func(result Result) error {
+			mu.Lock()
+			defer mu.Unlock()
+			copy(data[result.Offset:], result.Buffer)
+			offsets = append(offsets, result.Offset)
+			return nil
+		})
+		p.Start(&slowStartGenerator{}, int64(len(data)))
+
+		p.Wait()
+
+		// Nothing is sent to Results
+		for range p.Results() {
+			t.Error("expected no results when a sink is set")
+		}
+
+		expected := make([]byte, len(data))
+		(&generator.SequentialGenerator{}).GenerateAt(expected, 0)
+		if !bytes.Equal(data, expected) {
+			t.Errorf("ordered=%v: expected the sink to receive every chunk", ordered)
+		}
+		if len(offsets) != 21 {
+			t.Fatalf("ordered=%v: expected 21 chunks, got %d", ordered, len(offsets))
+		}
+		if ordered {
+			for i, offset := range offsets {
+				if offset != int64(i)*1024 {
+					t.Fatalf("expected chunk %d at offset %d, got %d", i, i*1024, offset)
+				}
+			}
+		}
+	}
+}
+
+func TestWorkerPoolSinkError(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		p := NewWorkerPool(context.Background(), 2, 1024)
+		p.SetOrdered(ordered)
+		p.SetSink(func(result Result) error {
+			if result.Offset == 2048 {
+				return fmt.Errorf("disk full")
+			}
+			return nil
+		})
+		p.Start(&generator.ZeroGenerator{}, 1024*1024)
+
+		go func() {
+			for range p.Results() {
+			}
+		}()
+		p.Wait()
+
+		errs := p.Errors()
+		if len(errs) != 1 || errs[0].Offset != 2048 || errs[0].Err.Error() != "disk full" {
+			t.Errorf("ordered=%v: expected the sink error to be reported, got %v", ordered, errs)
+		}
+	}
+}
+
+func TestWorkerPoolMemoryBudget(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 4, 1024)
+	p.SetMemoryBudget(3*1024 + 500)
+	p.Start(&generator.ZeroGenerator{}, 10*1024)
+
+	// Without returning buffers, only three chunks can be in flight
+	var held []Result
+	for i := 0; i < 3; i++ {
+		held = append(held, <-p.Results())
+	}
+	select {
+	case result := <-p.Results():
+		t.Fatalf("expected the budget to hold back chunk at offset %d", result.Offset)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Returning a buffer lets the next chunk through
+	p.ReturnBuffer(held[0].Buffer)
+	select {
+	case result := <-p.Results():
+		p.ReturnBuffer(result.Buffer)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a returned buffer to free the budget")
+	}
+	for _, result := range held[1:] {
+		p.ReturnBuffer(result.Buffer)
+	}
+
+	go p.Wait()
+	count := 4
+	for result := range p.Results() {
+		p.ReturnBuffer(result.Buffer)
+		count++
+	}
+	if count != 10 {
+		t.Errorf("expected 10 chunks, got %d", count)
+	}
+}
+
+func TestWorkerPoolMemoryBudgetOrdered(t *testing.T) {
+	// A budget of one chunk must not deadlock the sequencer
+	p := NewWorkerPool(context.Background(), 4, 1024)
+	p.SetOrdered(true)
+	p.SetMemoryBudget(1)
+	p.Start(&slowStartGenerator{}, 8*1024)
+
+	done := make(chan struct{})
+	var offsets []int64
+	go func() {
+		defer close(done)
+		for result := range p.Results() {
+			offsets = append(offsets, result.Offset)
+			p.ReturnBuffer(result.Buffer)
+		}
+	}()
+	p.Wait()
+	<-done
+
+	if len(offsets) != 8 {
+		t.Errorf("expected 8 chunks, got %d", len(offsets))
+	}
+}
+
+// collect returns the data of a job's chunks by offset once the job is done.
+func collect(p *WorkerPool, job *Job) map[int64][]byte {
+	chunks := make(map[int64][]byte)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range job.Results() {
+			chunks[result.Offset] = bytes.Clone(result.Buffer)
+			p.ReturnBuffer(result.Buffer)
+		}
+	}()
+	job.Wait()
+	<-done
+	return chunks
+}
+
+func TestWorkerPoolSuccessiveJobs(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 2, 1024)
+	defer p.Close()
+
+	for i, size := range []int64{4096, 2500, 1024} {
+		job := p.NewJob()
+		job.Start(&generator.SequentialGenerator{}, size)
+		chunks := collect(p, job)
+
+		if want := int((size + 1023) / 1024); len(chunks) != want {
+			t.Errorf("job %d: expected %d chunks, got %d", i, want, len(chunks))
+		}
+		if last := chunks[(size-1)/1024*1024]; int64(len(last)) != (size-1)%1024+1 {
+			t.Errorf("job %d: unexpected last chunk size %d", i, len(last))
+		}
+	}
+}
+
+func TestWorkerPoolConcurrentJobs(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 3, 1024)
+	defer p.Close()
+
+	zero := p.NewJob()
+	zero.Start(&generator.ZeroGenerator{}, 64*1024)
+	seq := p.NewJob()
+	seq.SetOrdered(true)
+	seq.Start(&generator.SequentialGenerator{}, 64*1024)
+
+	// A failing job does not stop the others
+	failing := p.NewJob()
+	failing.Start(&failAtGenerator{offset: 2048}, 64*1024)
+
+	var zeroChunks, seqChunks map[int64][]byte
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		zeroChunks = collect(p, zero)
+	}()
+	go func() {
+		defer wg.Done()
+		seqChunks = collect(p, seq)
+	}()
+	collect(p, failing)
+	wg.Wait()
+
+	if len(zeroChunks) != 64 || len(seqChunks) != 64 {
+		t.Fatalf("expected 64 chunks per job, got %d and %d", len(zeroChunks), len(seqChunks))
+	}
+	for offset, chunk := range zeroChunks {
+		if !bytes.Equal(chunk, make([]byte, 1024)) {
+			t.Errorf("zero job: chunk at offset %d is not zero", offset)
+		}
+	}
+	for offset, chunk := range seqChunks {
+		want := make([]byte, 1024)
+		(&generator.SequentialGenerator{}).GenerateAt(want, offset)
+		if !bytes.Equal(chunk, want) {
+			t.Errorf("sequential job: chunk at offset %d has the wrong data", offset)
+		}
+	}
+
+	if errs := failing.Errors(); len(errs) != 1 || errs[0].Offset != 2048 {
+		t.Errorf("expected the failing job's error at offset 2048, got %v", errs)
+	}
+	if errs := zero.Errors(); len(errs) != 0 {
+		t.Errorf("expected no errors in the zero job, got %v", errs)
+	}
+}
+
+func TestWorkerPoolCloseCancelsJobs(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 2, 1024)
+	job := p.NewJob()
+	job.Start(&generator.ZeroGenerator{}, 1024*1024*1024)
+
+	// Nobody consumes the results, so the job only ends when cancelled
+	<-job.Results()
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out closing the pool")
+	}
+	job.Wait()
+
+	// Jobs started after Close end without generating anything
+	late := p.NewJob()
+	late.Start(&generator.ZeroGenerator{}, 4096)
+	if chunks := collect(p, late); len(chunks) != 0 {
+		t.Errorf("expected no chunks from a job started after Close, got %d", len(chunks))
+	}
+}
+
+func TestWorkerPoolJobPriority(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 1, 1024)
+	defer p.Close()
+
+	var lowChunks atomic.Int64
+	low := p.NewJob()
+	low.SetSink(func(result Result) error {
+		lowChunks.Add(1)
+		return nil
+	})
+	low.Start(&sleepyGenerator{}, 200*1024)
+
+	// Once started, the urgent job's chunks go first
+	for lowChunks.Load() < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	high := p.NewJob()
+	high.SetPriority(1)
+	high.SetSink(func(result Result) error {
+		return nil
+	})
+	before := lowChunks.Load()
+	high.Start(&sleepyGenerator{}, 20*1024)
+	high.Wait()
+	after := lowChunks.Load()
+
+	// The chunk the worker was generating, and one it took before the
+	// urgent job's first chunk was queued, may still finish
+	if after-before > 2 {
+		t.Errorf("expected the low-priority job to wait, but it generated %d chunks", after-before)
+	}
+	if errs := high.Errors(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	low.Wait()
+	if n := lowChunks.Load(); n != 200 {
+		t.Errorf("expected the low-priority job to finish with 200 chunks, got %d", n)
+	}
+}
+
+// trackingGenerator generates sequential data slowly and records how many
+// calls overlap.
+type trackingGenerator struct {
+	generator.SequentialGenerator
+	active atomic.Int32
+	peak   atomic.Int32
+}
+
+func (g *trackingGenerator) GenerateAt(buffer []byte, offset int64) error {
+	active := g.active.Add(1)
+	defer g.active.Add(-1)
+	for peak := g.peak.Load(); active > peak && !g.peak.CompareAndSwap(peak, active); peak = g.peak.Load() {
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	return g.SequentialGenerator.GenerateAt(buffer, offset)
+}
+
+func TestWorkerPoolSplit(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 4, 64*1024)
+	p.SetSplitSize(4096)
+	gen := &trackingGenerator{}
+	p.Start(gen, 64*1024+1000)
+
+	chunks := make(map[int64][]byte)
+	for result := range drain(p) {
+		chunks[result.Offset] = bytes.Clone(result.Buffer)
+		p.ReturnBuffer(result.Buffer)
+	}
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	// Pieces line up into the same chunks as generating them whole
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(chunks))
+	}
+	for offset, chunk := range chunks {
+		want := make([]byte, len(chunk))
+		(&generator.SequentialGenerator{}).GenerateAt(want, offset)
+		if !bytes.Equal(chunk, want) {
+			t.Errorf("chunk at offset %d has the wrong data", offset)
+		}
+	}
+
+	// Workers without a chunk of their own helped with the first one
+	if gen.peak.Load() <= 2 {
+		t.Errorf("expected idle workers to help generate, at most %d pieces overlapped", gen.peak.Load())
+	}
+}
+
+func TestWorkerPoolSplitError(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 2, 4096)
+	p.SetSplitSize(512)
+	p.Start(&failAtGenerator{offset: 4096 + 1536}, 3*4096)
+
+	for result := range drain(p) {
+		p.ReturnBuffer(result.Buffer)
+	}
+
+	// The piece's error is reported for its chunk
+	errs := p.Errors()
+	if len(errs) != 1 || errs[0].Offset != 4096 || !strings.Contains(errs[0].Err.Error(), "no data at 5632") {
+		t.Errorf("expected the piece's error for the chunk at offset 4096, got %v", errs)
+	}
+}
+
+func TestWorkerPoolStop(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		p := NewWorkerPool(context.Background(), 4, 4096)
+		p.SetOrdered(ordered)
+		p.Start(&sleepyGenerator{}, 1000*4096)
+
+		// Chunks handed out before the stop are still delivered
+		var delivered int64
+		offsets := make(map[int64]bool)
+		for result := range drain(p) {
+			if delivered += int64(len(result.Buffer)); delivered == 3*4096 {
+				p.Stop()
+			}
+			offsets[result.Offset] = true
+			p.ReturnBuffer(result.Buffer)
+		}
+		if errs := p.Errors(); len(errs) != 0 {
+			t.Fatalf("ordered=%v: unexpected errors: %v", ordered, errs)
+		}
+
+		end := p.End()
+		if end >= 1000*4096 || end%4096 != 0 {
+			t.Fatalf("ordered=%v: expected the job to end early on a chunk boundary, got %d", ordered, end)
+		}
+		if delivered != end || int64(len(offsets))*4096 != end {
+			t.Errorf("ordered=%v: expected the %d bytes before the end to be delivered, got %d", ordered, end, delivered)
+		}
+		for offset := int64(0); offset < end; offset += 4096 {
+			if !offsets[offset] {
+				t.Errorf("ordered=%v: chunk at offset %d was not delivered", ordered, offset)
+			}
+		}
+	}
+}
+
+func TestWorkerPoolDoubleBuffering(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 1, 1024)
+	p.SetDoubleBuffering(true)
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	sunk := make(map[int64][]byte)
+	p.SetSink(func(result Result) error {
+		if result.Offset == 0 {
+			<-release
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sunk[result.Offset] = bytes.Clone(result.Buffer)
+		return nil
+	})
+	p.Start(&generator.SequentialGenerator{}, 8*1024)
+
+	// The worker generates its next chunk while the first is still being
+	// written, but holds it until the first is done
+	deadline := time.Now().Add(time.Second)
+	for p.generated.Load() < 2*1024 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if generated := p.generated.Load(); generated != 2*1024 {
+		t.Fatalf("expected two chunks generated while the first is written, got %d bytes", generated)
+	}
+	close(release)
+	p.Wait()
+
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(sunk) != 8 {
+		t.Fatalf("expected 8 chunks, got %d", len(sunk))
+	}
+	for offset, data := range sunk {
+		want := make([]byte, 1024)
+		(&generator.SequentialGenerator{}).GenerateAt(want, offset)
+		if !bytes.Equal(data, want) {
+			t.Errorf("chunk at offset %d has the wrong data", offset)
+		}
+	}
+}
+
+func TestWorkerPoolDoubleBufferingSinkError(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 2, 1024)
+	p.SetDoubleBuffering(true)
+	p.SetSink(func(result Result) error {
+		if result.Offset == 2048 {
+			return fmt.Errorf("disk full")
+		}
+		return nil
+	})
+	p.Start(&generator.ZeroGenerator{}, 1024*1024)
+	p.Wait()
+
+	errs := p.Errors()
+	if len(errs) != 1 || errs[0].Offset != 2048 {
+		t.Errorf("expected the sink error to be reported, got %v", errs)
+	}
+}
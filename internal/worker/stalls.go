@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"fmt"
+	"time"
+)
+
+// Stalls is how long a pool's workers, and the writer consuming their
+// results, spent working and waiting on each other.
+type Stalls struct {
+	// Generating is the total time workers spent generating and hashing
+	// chunks.
+	Generating time.Duration
+	// Blocked is the total time workers spent handing chunks on: waiting for
+	// Results to be read, passing them to the sink or, when double-buffered,
+	// waiting for their previous chunk to be consumed.
+	Blocked time.Duration
+	// Waiting and Writing are the time a consumer of Results spent waiting
+	// for results and handling them. The pool doesn't measure them; both are
+	// zero when workers pass chunks to a sink.
+	Waiting time.Duration
+	Writing time.Duration
+}
+
+// Stalls returns the time the pool's workers spent generating chunks and
+// handing them on.
+func (p *WorkerPool) Stalls() Stalls {
+	return Stalls{
+		Generating: time.Duration(p.generating.Load()),
+		Blocked:    time.Duration(p.blocked.Load()),
+	}
+}
+
+// Bottleneck names the side of the pipeline the run was waiting on, with the
+// share of time the other side spent waiting for it: "writer-bound 83%"
+// means workers spent 83% of their time waiting to hand chunks on. It
+// returns "" if the workers didn't run.
+func (s Stalls) Bottleneck() string {
+	workerTime := s.Generating + s.Blocked
+	if workerTime <= 0 {
+		return ""
+	}
+	writerBound := float64(s.Blocked) / float64(workerTime)
+
+	// Workers that write their own chunks are generating whenever they
+	// aren't writing
+	generatorBound := 1 - writerBound
+	if consumerTime := s.Waiting + s.Writing; consumerTime > 0 {
+		generatorBound = float64(s.Waiting) / float64(consumerTime)
+	}
+
+	if writerBound >= generatorBound {
+		return fmt.Sprintf("writer-bound %.0f%%", writerBound*100)
+	}
+	return fmt.Sprintf("generator-bound %.0f%%", generatorBound*100)
+}
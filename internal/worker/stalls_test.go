@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+func TestStallsBottleneck(t *testing.T) {
+	tests := []struct {
+		name   string
+		stalls Stalls
+		want   string
+	}{
+		{"no workers", Stalls{}, ""},
+		{"workers waiting on the writer", Stalls{Generating: time.Second, Blocked: 5 * time.Second, Waiting: time.Second, Writing: 9 * time.Second}, "writer-bound 83%"},
+		{"writer waiting on the workers", Stalls{Generating: 9 * time.Second, Blocked: time.Second, Waiting: 3 * time.Second, Writing: time.Second}, "generator-bound 75%"},
+		{"workers writing themselves", Stalls{Generating: 3 * time.Second, Blocked: time.Second}, "generator-bound 75%"},
+		{"workers mostly writing", Stalls{Generating: time.Second, Blocked: 3 * time.Second}, "writer-bound 75%"},
+	}
+
+	for _, test := range tests {
+		if got := test.stalls.Bottleneck(); got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, got)
+		}
+	}
+}
+
+func TestWorkerPoolStalls(t *testing.T) {
+	// A slow sink keeps the workers waiting to hand chunks on
+	p := NewWorkerPool(context.Background(), 2, 1024)
+	p.SetSink(func(result Result) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+	p.Start(&generator.SequentialGenerator{}, 8*1024)
+	p.Wait()
+
+	stalls := p.Stalls()
+	if stalls.Blocked < 8*5*time.Millisecond {
+		t.Errorf("expected workers to be blocked for at least 40ms, got %v", stalls.Blocked)
+	}
+	if stalls.Generating <= 0 {
+		t.Error("expected generation time to be measured")
+	}
+	if stalls.Generating >= stalls.Blocked {
+		t.Errorf("expected generating (%v) to take less time than handing on (%v)", stalls.Generating, stalls.Blocked)
+	}
+}
@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"sync"
+	"time"
+)
+
+// MinTunedChunkSize is the chunk size chunk-size tuning starts at.
+const MinTunedChunkSize = 1024 * 1024
+
+// MaxTunedChunkSize is the largest chunk size worth tuning up to. Pools that
+// tune their chunk size are usually created with it as their chunk size.
+const MaxTunedChunkSize = 128 * 1024 * 1024
+
+// chunkTuner adapts the chunk size to the throughput measured while chunks
+// are delivered. Starting small, it doubles the chunk size for as long as
+// each doubling raises throughput by at least 5%, then settles on the best
+// size measured. Each measurement covers window chunks of the current size,
+// timed from the first delivered to the last, so both generation and write
+// latency count.
+type chunkTuner struct {
+	mu       sync.Mutex
+	size     int64
+	max      int64
+	window   int
+	settled  bool
+	count    int
+	bytes    int64
+	start    time.Time
+	bestRate float64
+	bestSize int64
+}
+
+// newChunkTuner creates a tuner for chunk sizes from minSize to maxSize,
+// measuring over window chunks.
+func newChunkTuner(minSize, maxSize int64, window int) *chunkTuner {
+	return &chunkTuner{
+		size:   minSize,
+		max:    maxSize,
+		window: max(window, 2),
+	}
+}
+
+// current returns the chunk size to use for the next chunk.
+func (t *chunkTuner) current() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.size
+}
+
+// observe records that a chunk of size bytes was delivered at the given
+// time, and moves on to the next size once a measurement is complete.
+func (t *chunkTuner) observe(size int64, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Chunks queued before the last change, and the short last chunk of a
+	// job, are not measured
+	if t.settled || size != t.size {
+		return
+	}
+
+	// The first chunk starts the clock; its own time was spent before it
+	if t.count == 0 {
+		t.start = at
+	} else {
+		t.bytes += size
+	}
+	t.count++
+	if t.count < t.window {
+		return
+	}
+
+	rate := float64(t.bytes) / max(at.Sub(t.start).Seconds(), 1e-9)
+	t.count, t.bytes = 0, 0
+
+	// Doubling gained less than 5%: go back to the best size
+	if t.bestSize > 0 && rate*20 < t.bestRate*21 {
+		t.size, t.settled = t.bestSize, true
+		return
+	}
+
+	t.bestRate, t.bestSize = rate, t.size
+	if t.size*2 > t.max {
+		t.settled = true
+		return
+	}
+	t.size *= 2
+}
@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+// feed delivers window chunks of the tuner's current size, spaced so that
+// the measured throughput is rate bytes per second.
+func feed(tuner *chunkTuner, start time.Time, rate float64) time.Time {
+	size := tuner.current()
+	at := start
+	for i := 0; i < tuner.window; i++ {
+		tuner.observe(size, at)
+		at = at.Add(time.Duration(float64(size) / rate * float64(time.Second)))
+	}
+	return at
+}
+
+func TestChunkTunerDoublesWhileFaster(t *testing.T) {
+	tuner := newChunkTuner(1024, 16*1024, 3)
+	at := time.Unix(0, 0)
+
+	for _, want := range []int64{1024, 2048, 4096} {
+		if size := tuner.current(); size != want {
+			t.Fatalf("expected chunk size %d, got %d", want, size)
+		}
+		at = feed(tuner, at, float64(want)*1000)
+	}
+	if size := tuner.current(); size != 8192 {
+		t.Fatalf("expected chunk size 8192, got %d", size)
+	}
+
+	// Doubling again gains too little, so the tuner goes back and stays
+	at = feed(tuner, at, 4096*1000*1.02)
+	if size := tuner.current(); size != 4096 {
+		t.Fatalf("expected the tuner to settle on 4096, got %d", size)
+	}
+	feed(tuner, at, 1e12)
+	if size := tuner.current(); size != 4096 {
+		t.Errorf("expected a settled tuner to keep 4096, got %d", size)
+	}
+}
+
+func TestChunkTunerSettlesAtMax(t *testing.T) {
+	tuner := newChunkTuner(1024, 4096, 2)
+	at := time.Unix(0, 0)
+
+	for rate := 1e6; tuner.current() < 4096; rate *= 2 {
+		at = feed(tuner, at, rate)
+	}
+	at = feed(tuner, at, 1e9)
+	if size := tuner.current(); size != 4096 {
+		t.Errorf("expected the tuner to stop at 4096, got %d", size)
+	}
+	if !tuner.settled {
+		t.Error("expected the tuner to settle at the maximum size")
+	}
+}
+
+func TestChunkTunerIgnoresOtherSizes(t *testing.T) {
+	tuner := newChunkTuner(1024, 4096, 2)
+	at := time.Unix(0, 0)
+
+	// Short last chunks and chunks queued before a change don't count
+	for i := 0; i < 10; i++ {
+		tuner.observe(100, at)
+		tuner.observe(2048, at)
+		at = at.Add(time.Millisecond)
+	}
+	if size := tuner.current(); size != 1024 {
+		t.Errorf("expected chunk size 1024, got %d", size)
+	}
+}
+
+func TestWorkerPoolSetChunkTuning(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 2, 4*MinTunedChunkSize)
+	p.SetChunkTuning(true)
+	if size := p.ChunkSize(); size != MinTunedChunkSize {
+		t.Errorf("expected tuning to start at %d, got %d", MinTunedChunkSize, size)
+	}
+
+	// Pools with small chunks tune from their own chunk size
+	small := NewWorkerPool(context.Background(), 2, 64*1024)
+	small.SetChunkTuning(true)
+	if size := small.ChunkSize(); size != 64*1024 {
+		t.Errorf("expected tuning to start at %d, got %d", 64*1024, size)
+	}
+
+	p.SetChunkTuning(false)
+	if size := p.ChunkSize(); size != 4*MinTunedChunkSize {
+		t.Errorf("expected chunk size %d without tuning, got %d", 4*MinTunedChunkSize, size)
+	}
+}
+
+func TestWorkerPoolChunkTuning(t *testing.T) {
+	const size = 1024*1024 + 100
+	p := NewWorkerPool(context.Background(), 2, 64*1024)
+	p.tuner = newChunkTuner(4096, 64*1024, 2)
+	p.Start(&generator.SequentialGenerator{}, size)
+
+	chunks := make(map[int64][]byte)
+	for result := range drain(p) {
+		chunks[result.Offset] = bytes.Clone(result.Buffer)
+		p.ReturnBuffer(result.Buffer)
+	}
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	offsets := make([]int64, 0, len(chunks))
+	for offset := range chunks {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	// Chunks of changing sizes still cover the file exactly once
+	var next int64
+	for _, offset := range offsets {
+		if offset != next {
+			t.Fatalf("expected a chunk at offset %d, got %d", next, offset)
+		}
+		want := make([]byte, len(chunks[offset]))
+		(&generator.SequentialGenerator{}).GenerateAt(want, offset)
+		if !bytes.Equal(chunks[offset], want) {
+			t.Errorf("chunk at offset %d has the wrong data", offset)
+		}
+		next += int64(len(chunks[offset]))
+	}
+	if next != size {
+		t.Errorf("expected chunks to cover %d bytes, got %d", size, next)
+	}
+	if first := len(chunks[0]); first != 4096 {
+		t.Errorf("expected the first chunk to be 4096 bytes, got %d", first)
+	}
+}
@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultStatsInterval is how often verbose runs print per-worker
+// statistics.
+const DefaultStatsInterval = 5 * time.Second
+
+// States a worker can be in, as reported by WorkerStats.
+const (
+	StateIdle       = "idle"
+	StateGenerating = "generating"
+	StateHandingOn  = "handing on"
+	StatePaused     = "paused"
+	StateScaledDown = "scaled down"
+)
+
+// workerState is a worker's state, an index into workerStates.
+type workerState int32
+
+const (
+	stateIdle workerState = iota
+	stateGenerating
+	stateHandingOn
+	statePaused
+	stateScaledDown
+)
+
+var workerStates = [...]string{StateIdle, StateGenerating, StateHandingOn, StatePaused, StateScaledDown}
+
+// workerStats tracks one worker of a pool.
+type workerStats struct {
+	state  atomic.Int32
+	chunks atomic.Int64
+	bytes  atomic.Int64
+}
+
+// WorkerStat is a snapshot of one worker's activity.
+type WorkerStat struct {
+	ID int
+	// State is what the worker is doing: StateIdle when waiting for a
+	// chunk, StateGenerating, StateHandingOn when waiting for its chunk to
+	// be taken or written, StatePaused or StateScaledDown.
+	State string
+	// Chunks and Bytes count the chunks the worker has generated. Pieces it
+	// generated of other workers' chunks count for those workers.
+	Chunks int64
+	Bytes  int64
+}
+
+// setState records what the worker with the given id is doing.
+func (p *WorkerPool) setState(id int, state workerState) {
+	p.stats[id].state.Store(int32(state))
+}
+
+// WorkerStats returns a snapshot of each worker's activity. It only covers
+// chunks generated by jobs, not tasks run with Process.
+func (p *WorkerPool) WorkerStats() []WorkerStat {
+	stats := make([]WorkerStat, len(p.stats))
+	for i := range p.stats {
+		stats[i] = WorkerStat{
+			ID:     i,
+			State:  workerStates[p.stats[i].state.Load()],
+			Chunks: p.stats[i].chunks.Load(),
+			Bytes:  p.stats[i].bytes.Load(),
+		}
+	}
+	return stats
+}
+
+// StatsTable formats successive snapshots of per-worker statistics as
+// tables, with each worker's throughput since the previous snapshot.
+type StatsTable struct {
+	last     map[int]int64
+	lastTime time.Time
+}
+
+// NewStatsTable creates a table measuring throughput from start.
+func NewStatsTable(start time.Time) *StatsTable {
+	return &StatsTable{last: make(map[int]int64), lastTime: start}
+}
+
+// Format returns a table of stats taken at now, one line per worker.
+func (t *StatsTable) Format(stats []WorkerStat, now time.Time) string {
+	elapsed := now.Sub(t.lastTime).Seconds()
+	t.lastTime = now
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-6s  %8s  %10s  %s\n", "Worker", "Chunks", "MB/s", "State")
+	for _, stat := range stats {
+		var rate float64
+		if elapsed > 0 {
+			rate = float64(stat.Bytes-t.last[stat.ID]) / elapsed / (1 << 20)
+		}
+		t.last[stat.ID] = stat.Bytes
+		fmt.Fprintf(&b, "%-6d  %8d  %10.1f  %s\n", stat.ID, stat.Chunks, rate, stat.State)
+	}
+	return b.String()
+}
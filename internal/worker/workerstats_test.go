@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxkimambo/trasher/pkg/generator"
+)
+
+func TestWorkerStats(t *testing.T) {
+	p := NewWorkerPool(context.Background(), 3, 1024)
+	defer p.Close()
+
+	p.Start(&generator.ZeroGenerator{}, 10*1024)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range p.Results() {
+			p.ReturnBuffer(result.Buffer)
+		}
+	}()
+	p.Wait()
+	<-done
+
+	stats := p.WorkerStats()
+	if len(stats) != 3 {
+		t.Fatalf("expected 3 workers, got %d", len(stats))
+	}
+	var chunks, bytes int64
+	for i, stat := range stats {
+		if stat.ID != i {
+			t.Errorf("expected worker %d, got %d", i, stat.ID)
+		}
+		if stat.State != StateIdle {
+			t.Errorf("worker %d: expected to be idle once done, got %s", i, stat.State)
+		}
+		chunks += stat.Chunks
+		bytes += stat.Bytes
+	}
+	if chunks != 10 || bytes != 10*1024 {
+		t.Errorf("expected 10 chunks of 10KB in total, got %d chunks of %d bytes", chunks, bytes)
+	}
+}
+
+func TestStatsTable(t *testing.T) {
+	start := time.Now()
+	table := NewStatsTable(start)
+
+	out := table.Format([]WorkerStat{
+		{ID: 0, State: StateGenerating, Chunks: 4, Bytes: 4 << 20},
+		{ID: 1, State: StateHandingOn, Chunks: 2, Bytes: 2 << 20},
+	}, start.Add(2*time.Second))
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 workers, got %q", out)
+	}
+	if !strings.Contains(lines[1], "2.0") || !strings.Contains(lines[1], StateGenerating) {
+		t.Errorf("expected worker 0 at 2.0 MB/s, got %q", lines[1])
+	}
+
+	// Throughput covers the time since the previous table
+	out = table.Format([]WorkerStat{
+		{ID: 0, State: StateIdle, Chunks: 4, Bytes: 4 << 20},
+		{ID: 1, State: StateGenerating, Chunks: 6, Bytes: 6 << 20},
+	}, start.Add(4*time.Second))
+	lines = strings.Split(strings.TrimSpace(out), "\n")
+	if !strings.Contains(lines[1], " 0.0") || !strings.Contains(lines[2], "2.0") {
+		t.Errorf("expected worker 0 at 0.0 and worker 1 at 2.0 MB/s, got %q", out)
+	}
+}
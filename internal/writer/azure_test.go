@@ -0,0 +1,81 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestAzureUpload(t *testing.T) {
+	var mu sync.Mutex
+	blocks := make(map[string][]byte)
+	var blob []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.Method != http.MethodPut || r.URL.Path != "/container/dir/test.dat" || query.Get("sig") != "secret" {
+			w.Header().Set("x-ms-error-code", "AuthenticationFailed")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		switch query.Get("comp") {
+		case "block":
+			blocks[query.Get("blockid")] = body
+		case "blocklist":
+			var list struct {
+				Latest []string `xml:"Latest"`
+			}
+			xml.Unmarshal(body, &list)
+			for _, id := range list.Latest {
+				blob = append(blob, blocks[id]...)
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	backend, err := newAzureBackend(server.URL, "container", "dir/test.dat", "?sv=2021-08-06&sig=secret")
+	if err != nil {
+		t.Fatalf("newAzureBackend failed: %v", err)
+	}
+	w, err := newObjectWriter("az://container/dir/test.dat", backend, 25, 10, 2)
+	if err != nil {
+		t.Fatalf("newObjectWriter failed: %v", err)
+	}
+
+	data := []byte("0123456789abcdefghijKLMNO")
+	for _, offset := range []int{10, 20, 0} {
+		if err := w.WriteAt(data[offset:min(offset+10, 25)], int64(offset)); err != nil {
+			t.Fatalf("WriteAt(%d) failed: %v", offset, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !bytes.Equal(blob, data) {
+		t.Errorf("expected blob %q, got %q", data, blob)
+	}
+
+	// Error codes are reported
+	backend, _ = newAzureBackend(server.URL, "container", "dir/test.dat", "sig=wrong")
+	if err := backend.uploadPart(1, data); err == nil || !bytes.Contains([]byte(err.Error()), []byte("AuthenticationFailed")) {
+		t.Errorf("expected AuthenticationFailed error, got %v", err)
+	}
+}
+
+func TestAzureBlockID(t *testing.T) {
+	if len(azureBlockID(1)) != len(azureBlockID(50000)) {
+		t.Error("block IDs must all have the same length")
+	}
+	if azureBlockID(1) == azureBlockID(2) {
+		t.Error("block IDs must be unique")
+	}
+}
@@ -0,0 +1,28 @@
+//go:build linux && (amd64 || arm64)
+
+package writer
+
+import (
+	"os"
+	"syscall"
+)
+
+// sync_file_range flags from linux/fs.h and the fadvise advice from
+// linux/fadvise.h
+const (
+	syncFileRangeWaitBefore = 0x1
+	syncFileRangeWrite      = 0x2
+	syncFileRangeWaitAfter  = 0x4
+	fadvDontNeed            = 4
+)
+
+// dropCache writes back a range of file and drops it from the page cache.
+// Dirty pages cannot be dropped, so the range is flushed first. A length of
+// 0 means up to the end of the file. Errors are ignored: the cache is only
+// an optimization.
+func dropCache(file *os.File, offset, length int64) {
+	fd := file.Fd()
+	syscall.Syscall6(syscall.SYS_SYNC_FILE_RANGE, fd, uintptr(offset), uintptr(length),
+		syncFileRangeWaitBefore|syncFileRangeWrite|syncFileRangeWaitAfter, 0, 0)
+	syscall.Syscall6(syscall.SYS_FADVISE64, fd, uintptr(offset), uintptr(length), fadvDontNeed, 0, 0)
+}
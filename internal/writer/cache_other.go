@@ -0,0 +1,10 @@
+//go:build !linux || !(amd64 || arm64)
+
+package writer
+
+import "os"
+
+// dropCache is a no-op where the page cache cannot be dropped per range.
+// Windows keeps written pages on its standby list and offers no per-file
+// equivalent of posix_fadvise.
+func dropCache(file *os.File, offset, length int64) {}
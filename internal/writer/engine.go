@@ -0,0 +1,91 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ErrEngineUnsupported is returned when an I/O engine is not available on
+// the platform.
+var ErrEngineUnsupported = errors.New("I/O engine is not supported on this platform")
+
+// DefaultEngine is the I/O engine used when none is chosen.
+const DefaultEngine = "pwrite"
+
+// Engines lists the I/O engines a FileWriter can write with:
+//
+//   - pwrite: positional writes, which run in parallel
+//   - seek: a seek and a write, one at a time, as sequential tools do
+//   - mmap: copies into a shared mapping of the file (Linux)
+//   - uring: writes submitted through an io_uring (Linux)
+//   - direct: O_DIRECT writes that bypass the page cache (Linux)
+var Engines = []string{"pwrite", "seek", "mmap", "uring", "direct"}
+
+// Engine performs the writes of a FileWriter to its file. Engines may open
+// the file again or map it, but the FileWriter keeps its own handle for
+// everything else, such as sizing and syncing the file.
+type Engine interface {
+	// WriteAt writes data at offset and returns the number of bytes
+	// written. It is called concurrently, for ranges that don't overlap.
+	WriteAt(data []byte, offset int64) (int, error)
+	// Close releases the engine's resources and makes its writes visible
+	// to the file's handle. It is called once, before the file is synced.
+	Close() error
+}
+
+// newEngine creates the named engine for file, which is size bytes long.
+// An empty name selects DefaultEngine.
+func newEngine(name string, file *os.File, size int64) (Engine, error) {
+	switch name {
+	case "", "pwrite":
+		return pwriteEngine{file}, nil
+	case "seek":
+		return &seekEngine{file: file}, nil
+	case "mmap", "uring", "direct":
+		engine, err := newPlatformEngine(name, file, size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start %s I/O engine: %v", name, err)
+		}
+		return engine, nil
+	default:
+		return nil, fmt.Errorf("unknown I/O engine %q", name)
+	}
+}
+
+// pwriteEngine writes with positional writes, so concurrent writes don't
+// serialize on the file position.
+type pwriteEngine struct {
+	file *os.File
+}
+
+func (e pwriteEngine) WriteAt(data []byte, offset int64) (int, error) {
+	return e.file.WriteAt(data, offset)
+}
+
+func (e pwriteEngine) Close() error {
+	return nil
+}
+
+// seekEngine moves the file position and writes there. Writes share the
+// position, so they run one at a time.
+type seekEngine struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func (e *seekEngine) WriteAt(data []byte, offset int64) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, err := e.file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return e.file.Write(data)
+}
+
+func (e *seekEngine) Close() error {
+	return nil
+}
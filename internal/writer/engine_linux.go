@@ -0,0 +1,106 @@
+//go:build linux
+
+package writer
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"syscall"
+	"unsafe"
+)
+
+// directAlignment is the alignment of offsets, lengths and buffers that
+// O_DIRECT writes need on any device.
+const directAlignment = 4096
+
+// newPlatformEngine creates the Linux-only engines.
+func newPlatformEngine(name string, file *os.File, size int64) (Engine, error) {
+	switch name {
+	case "mmap":
+		return newMmapEngine(file, size)
+	case "uring":
+		return newUringEngine(file)
+	case "direct":
+		return newDirectEngine(file)
+	}
+	return nil, ErrEngineUnsupported
+}
+
+// mmapEngine copies data into a shared mapping of the whole file, leaving
+// the kernel to write the dirty pages back.
+type mmapEngine struct {
+	file *os.File
+	data []byte
+}
+
+// newMmapEngine maps file, which must already be size bytes long.
+func newMmapEngine(file *os.File, size int64) (*mmapEngine, error) {
+	if int64(int(size)) != size {
+		return nil, fmt.Errorf("file of %d bytes is too large to map", size)
+	}
+
+	// A shared mapping needs a handle that can read as well as write
+	rw, err := os.OpenFile(file.Name(), os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	data, err := syscall.Mmap(int(rw.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		rw.Close()
+		return nil, err
+	}
+	return &mmapEngine{file: rw, data: data}, nil
+}
+
+// WriteAt copies data into the mapping. A page the kernel can't back, for
+// example on a full disk, faults; the fault is returned as an error instead
+// of crashing the process.
+func (e *mmapEngine) WriteAt(data []byte, offset int64) (n int, err error) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("mapped write faulted: %v", r)
+		}
+	}()
+	return copy(e.data[offset:offset+int64(len(data))], data), nil
+}
+
+// Close unmaps the file; syncing the file writes back the dirty pages.
+func (e *mmapEngine) Close() error {
+	err := syscall.Munmap(e.data)
+	if closeErr := e.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// directEngine writes with O_DIRECT, bypassing the page cache. Writes that
+// are not aligned, such as a short last chunk, go through the page cache
+// instead.
+type directEngine struct {
+	direct   *os.File
+	buffered *os.File
+}
+
+// newDirectEngine opens file again for direct I/O. File systems without
+// O_DIRECT support fail here.
+func newDirectEngine(file *os.File) (*directEngine, error) {
+	direct, err := os.OpenFile(file.Name(), os.O_WRONLY|syscall.O_DIRECT, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &directEngine{direct: direct, buffered: file}, nil
+}
+
+func (e *directEngine) WriteAt(data []byte, offset int64) (int, error) {
+	address := uintptr(unsafe.Pointer(unsafe.SliceData(data)))
+	if offset%directAlignment != 0 || len(data)%directAlignment != 0 || address%directAlignment != 0 {
+		return e.buffered.WriteAt(data, offset)
+	}
+	return e.direct.WriteAt(data, offset)
+}
+
+func (e *directEngine) Close() error {
+	return e.direct.Close()
+}
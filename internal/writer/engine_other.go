@@ -0,0 +1,10 @@
+//go:build !linux
+
+package writer
+
+import "os"
+
+// newPlatformEngine is not implemented outside Linux.
+func newPlatformEngine(name string, file *os.File, size int64) (Engine, error) {
+	return nil, ErrEngineUnsupported
+}
@@ -0,0 +1,127 @@
+package writer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+// newEngineWriter creates a writer using engine, skipping the test where the
+// platform or file system doesn't support it.
+func newEngineWriter(t *testing.T, path string, size int64, engine string) *FileWriter {
+	t.Helper()
+	w, err := NewFileWriterWithOptions(path, size, Options{Engine: engine})
+	if err != nil {
+		if runtime.GOOS != "linux" || engine == "direct" {
+			t.Skipf("%s engine unavailable: %v", engine, err)
+		}
+		t.Fatalf("failed to create writer with %s engine: %v", engine, err)
+	}
+	return w
+}
+
+func TestEngines(t *testing.T) {
+	const chunkSize = 64 * 1024
+	const size = 8*chunkSize + 100
+
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	for _, engine := range Engines {
+		t.Run(engine, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.dat")
+			w := newEngineWriter(t, path, size, engine)
+
+			// Chunks arrive concurrently and out of order; the short last
+			// chunk is unaligned
+			var wg sync.WaitGroup
+			errs := make(chan error, size/chunkSize+1)
+			for offset := int64(0); offset < size; offset += chunkSize {
+				wg.Add(1)
+				go func(offset int64) {
+					defer wg.Done()
+					end := min(offset+chunkSize, size)
+					chunk := alignedCopy(data[offset:end])
+					errs <- w.WriteAt(chunk, offset)
+				}(offset)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatalf("write failed: %v", err)
+				}
+			}
+
+			if written := w.Written(); written != size {
+				t.Errorf("expected %d bytes written, got %d", size, written)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("close failed: %v", err)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Error("file contents do not match the data written")
+			}
+		})
+	}
+}
+
+func TestEngineTruncate(t *testing.T) {
+	for _, engine := range Engines {
+		t.Run(engine, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.dat")
+			w := newEngineWriter(t, path, 64*1024, engine)
+
+			if err := w.WriteAt(alignedCopy(bytes.Repeat([]byte{1}, 8192)), 0); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Truncate(8192); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("close failed: %v", err)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() != 8192 {
+				t.Errorf("expected size 8192, got %d", info.Size())
+			}
+		})
+	}
+}
+
+func TestUnknownEngine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.dat")
+	_, err := NewFileWriterWithOptions(path, 1024, Options{Engine: "carrier-pigeon"})
+	if err == nil || !strings.Contains(err.Error(), "unknown I/O engine") {
+		t.Errorf("expected unknown engine error, got %v", err)
+	}
+}
+
+// alignedCopy copies data into a page-aligned buffer, as chunk buffers are,
+// so direct writes can bypass the page cache.
+func alignedCopy(data []byte) []byte {
+	buffer := make([]byte, len(data)+os.Getpagesize())
+	offset := 0
+	for uintptr(unsafe.Pointer(&buffer[offset]))%uintptr(os.Getpagesize()) != 0 {
+		offset++
+	}
+	aligned := buffer[offset : offset+len(data)]
+	copy(aligned, data)
+	return aligned
+}
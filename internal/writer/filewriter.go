@@ -1,32 +1,106 @@
 package writer
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+
+	"github.com/maxkimambo/trasher/internal/device"
+	"github.com/maxkimambo/trasher/internal/diskspace"
 )
 
+// ErrHolesUnsupported is returned by PunchHole when the platform or file
+// system cannot deallocate file ranges.
+var ErrHolesUnsupported = errors.New("hole punching is not supported")
+
+// ErrZeroRangeUnsupported is returned by ZeroRange when the platform, file
+// system or device cannot zero ranges without writing them.
+var ErrZeroRangeUnsupported = errors.New("zeroing ranges is not supported")
+
+// SparseBlockSize is the granularity at which sparse writers skip zeros.
+const SparseBlockSize = 4096
+
+// zeroBlock is compared against to find all-zero blocks.
+var zeroBlock [SparseBlockSize]byte
+
+// Writer is a destination for generated chunks: a file or device, or a
+// network receiver.
+type Writer interface {
+	// WriteAt writes a chunk destined for the given offset. Chunks may
+	// arrive in any order.
+	WriteAt(data []byte, offset int64) error
+	// Truncate ends the output at size bytes, for runs that stop before
+	// writing everything. It is called after the last write.
+	Truncate(size int64) error
+	Close() error
+	Written() int64
+	TotalSize() int64
+	Path() string
+}
+
+// Options holds optional FileWriter settings.
+type Options struct {
+	// Force overwrites an existing file.
+	Force bool
+	// Sparse skips writing blocks that are all zeros, so only regions with
+	// data are allocated. Not supported for block devices.
+	Sparse bool
+	// DropCache flushes every written range and drops it from the page
+	// cache, so large files don't evict everything else cached. Linux only.
+	DropCache bool
+	// NoSpaceCheck skips the free space check, for runs that stop on their
+	// own before the disk fills up.
+	NoSpaceCheck bool
+	// Engine is the I/O engine that performs the writes (see Engines);
+	// empty means DefaultEngine.
+	Engine string
+	// Mode sets the file's permission bits exactly, whatever the umask;
+	// nil leaves a new file at 0644 less the umask. Owner and Group change
+	// its owner and group, which usually needs privileges; nil leaves them.
+	// Block devices are left alone.
+	Mode  *os.FileMode
+	Owner *int
+	Group *int
+}
+
 // FileWriter provides thread-safe writing to a file at specific offsets.
+// Writes go through an I/O engine, by default positional writes (pwrite),
+// so concurrent writers do not serialize on a shared file position; mu only
+// keeps Close from racing with them.
 type FileWriter struct {
 	file      *os.File
-	mu        sync.Mutex
-	written   int64
+	engine    Engine
+	mu        sync.RWMutex
+	written   atomic.Int64
 	totalSize int64
 	path      string
+	sparse    bool
+	dropCache bool
 }
 
 // NewFileWriter creates a new FileWriter that writes to the specified path.
 // If force is false and the file exists, an error is returned.
 // The file is pre-allocated to the specified size if possible.
 func NewFileWriter(path string, size int64, force bool) (*FileWriter, error) {
+	return NewFileWriterWithOptions(path, size, Options{Force: force})
+}
+
+// NewFileWriterWithOptions creates a new FileWriter with the given options.
+// Sparse files are sized without allocating space, and the free space check
+// is skipped since only their data takes up room.
+func NewFileWriterWithOptions(path string, size int64, opts Options) (*FileWriter, error) {
 	if size <= 0 {
 		return nil, fmt.Errorf("file size must be positive, got %d", size)
 	}
 
 	// Check if file exists and handle --force flag
-	if _, err := os.Stat(path); err == nil && !force {
+	_, statErr := os.Stat(path)
+	if statErr == nil && !opts.Force {
 		return nil, fmt.Errorf("file %s already exists, use --force to overwrite", path)
 	}
 
@@ -45,9 +119,16 @@ func NewFileWriter(path string, size int64, force bool) (*FileWriter, error) {
 		os.Remove(tempFile)
 	}
 
-	// Check available disk space
-	if err := checkDiskSpace(dir, size); err != nil {
-		return nil, err
+	// Block devices keep their old contents where writes are skipped
+	if opts.Sparse && device.IsBlockDevice(path) {
+		return nil, fmt.Errorf("sparse output is not supported for block device %s", path)
+	}
+
+	// Check available disk space; block devices are written in place
+	if !device.IsBlockDevice(path) && !opts.Sparse && !opts.NoSpaceCheck {
+		if err := diskspace.Check(dir, size); err != nil {
+			return nil, err
+		}
 	}
 
 	// Create or truncate the file
@@ -56,28 +137,59 @@ func NewFileWriter(path string, size int64, force bool) (*FileWriter, error) {
 		return nil, fmt.Errorf("failed to create output file: %v", err)
 	}
 
-	// Pre-allocate file space if possible
-	if err := preAllocateFile(file, size); err != nil {
+	if !device.IsBlockDevice(path) {
+		if err := applyPermissions(file, opts); err != nil {
+			file.Close()
+			// Don't leave behind a file this run created
+			if os.IsNotExist(statErr) {
+				os.Remove(path)
+			}
+			return nil, err
+		}
+	}
+
+	if opts.Sparse {
+		// Set the size without allocating anything
+		if err := markSparse(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to mark file as sparse: %v", err)
+		}
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to set file size: %v", err)
+		}
+	} else if err := preAllocateFile(file, size); err != nil {
+		// Pre-allocate file space if possible
+		file.Close()
+		return nil, err
+	}
+
+	// Engines that map the file need its final size
+	engine, err := newEngine(opts.Engine, file, size)
+	if err != nil {
 		file.Close()
 		return nil, err
 	}
 
 	return &FileWriter{
 		file:      file,
+		engine:    engine,
 		totalSize: size,
 		path:      path,
+		sparse:    opts.Sparse,
+		dropCache: opts.DropCache,
 	}, nil
 }
 
 // WriteAt writes data at the specified offset in the file.
-// This method is thread-safe and can be called concurrently.
+// This method is thread-safe and concurrent calls write in parallel.
 func (w *FileWriter) WriteAt(data []byte, offset int64) error {
 	if len(data) == 0 {
 		return nil
 	}
 
-	w.mu.Lock()
-	defer w.mu.Unlock()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 
 	if w.file == nil {
 		return fmt.Errorf("file writer is closed")
@@ -92,21 +204,111 @@ func (w *FileWriter) WriteAt(data []byte, offset int64) error {
 			offset, len(data), w.totalSize)
 	}
 
-	// Seek to the correct position
-	if _, err := w.file.Seek(offset, io.SeekStart); err != nil {
-		return fmt.Errorf("failed to seek to position %d: %v", offset, err)
+	write := w.writeAt
+	if w.sparse {
+		write = w.writeSparse
+	}
+	if err := write(data, offset); err != nil {
+		return err
+	}
+
+	if w.dropCache {
+		dropCache(w.file, offset, int64(len(data)))
 	}
+	return nil
+}
 
-	// Write the data
-	n, err := w.file.Write(data)
+// writeAt writes data with the writer's engine.
+func (w *FileWriter) writeAt(data []byte, offset int64) error {
+	n, err := w.engine.WriteAt(data, offset)
+	w.written.Add(int64(n))
 	if err != nil {
-		return fmt.Errorf("failed to write data at offset %d: %v", offset, err)
+		return fmt.Errorf("failed to write data at offset %d: %w", offset, err)
 	}
 	if n != len(data) {
 		return fmt.Errorf("short write: wrote %d bytes out of %d", n, len(data))
 	}
+	return nil
+}
 
-	w.written += int64(n)
+// writeSparse writes the runs of data that are not all-zero blocks, leaving
+// holes for the rest. Blocks are aligned to the file offset, so holes line
+// up with file system blocks; the skipped bytes count as written.
+func (w *FileWriter) writeSparse(data []byte, offset int64) error {
+	start := -1 // start of the pending run of data blocks
+	for i := 0; i < len(data); {
+		n := min(len(data)-i, SparseBlockSize-int((offset+int64(i))%SparseBlockSize))
+		if bytes.Equal(data[i:i+n], zeroBlock[:n]) {
+			if start >= 0 {
+				if err := w.writeAt(data[start:i], offset+int64(start)); err != nil {
+					return err
+				}
+				start = -1
+			}
+			w.written.Add(int64(n))
+		} else if start < 0 {
+			start = i
+		}
+		i += n
+	}
+
+	if start >= 0 {
+		return w.writeAt(data[start:], offset+int64(start))
+	}
+	return nil
+}
+
+// PunchHole deallocates length bytes at offset, which then read back as
+// zeros without taking up disk space. The file size is unchanged. It returns
+// ErrHolesUnsupported where ranges cannot be deallocated.
+func (w *FileWriter) PunchHole(offset, length int64) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.file == nil {
+		return fmt.Errorf("file writer is closed")
+	}
+	if offset < 0 || length < 0 || offset+length > w.totalSize {
+		return fmt.Errorf("hole outside file: offset=%d, len=%d, total=%d", offset, length, w.totalSize)
+	}
+	if length == 0 {
+		return nil
+	}
+
+	if err := punchHole(w.file, offset, length); err != nil {
+		if errors.Is(err, ErrHolesUnsupported) {
+			return err
+		}
+		return fmt.Errorf("failed to punch hole at offset %d: %v", offset, err)
+	}
+	return nil
+}
+
+// ZeroRange makes length bytes at offset read back as zeros without writing
+// them: the file system or device zeroes the range itself, or deallocates it
+// where it can't. Block devices are supported, which turns zero-filling a
+// disk into a metadata operation on devices that offload it. The size is
+// unchanged. It returns ErrZeroRangeUnsupported where neither is possible.
+func (w *FileWriter) ZeroRange(offset, length int64) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.file == nil {
+		return fmt.Errorf("file writer is closed")
+	}
+	if offset < 0 || length < 0 || offset+length > w.totalSize {
+		return fmt.Errorf("zero range outside file: offset=%d, len=%d, total=%d", offset, length, w.totalSize)
+	}
+	if length == 0 {
+		return nil
+	}
+
+	if err := zeroRange(w.file, offset, length); err != nil {
+		if errors.Is(err, ErrZeroRangeUnsupported) {
+			return err
+		}
+		return fmt.Errorf("failed to zero range at offset %d: %v", offset, err)
+	}
 	return nil
 }
 
@@ -119,6 +321,13 @@ func (w *FileWriter) Close() error {
 		return nil
 	}
 
+	// The engine's writes must reach the file before it is synced
+	if err := w.engine.Close(); err != nil {
+		w.file.Close()
+		w.file = nil
+		return fmt.Errorf("failed to stop I/O engine: %v", err)
+	}
+
 	// Sync to ensure all data is written to disk
 	if err := w.file.Sync(); err != nil {
 		w.file.Close()
@@ -126,16 +335,41 @@ func (w *FileWriter) Close() error {
 		return fmt.Errorf("failed to sync file: %v", err)
 	}
 
+	if w.dropCache {
+		dropCache(w.file, 0, 0)
+	}
+
 	err := w.file.Close()
 	w.file = nil
 	return err
 }
 
-// Written returns the total number of bytes written so far.
-func (w *FileWriter) Written() int64 {
+// Truncate shrinks the file to size bytes. Block devices can't be resized,
+// so they keep whatever follows.
+func (w *FileWriter) Truncate(size int64) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	return w.written
+
+	if w.file == nil {
+		return fmt.Errorf("file writer is closed")
+	}
+	if size < 0 || size > w.totalSize {
+		return fmt.Errorf("invalid size %d for file size %d", size, w.totalSize)
+	}
+
+	w.totalSize = size
+	if device.IsBlockDevice(w.path) {
+		return nil
+	}
+	if err := w.file.Truncate(size); err != nil {
+		return fmt.Errorf("failed to truncate file: %v", err)
+	}
+	return nil
+}
+
+// Written returns the total number of bytes written so far.
+func (w *FileWriter) Written() int64 {
+	return w.written.Load()
 }
 
 // TotalSize returns the total expected size of the file.
@@ -149,6 +383,19 @@ func (w *FileWriter) Path() string {
 }
 
 
+// DropCache drops a file's pages from the page cache, for example after it
+// was read back. It is a no-op where dropping is not supported.
+func DropCache(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dropCache(file, 0, 0)
+	return nil
+}
+
 // preAllocateFile attempts to pre-allocate file space for better performance.
 func preAllocateFile(file *os.File, size int64) error {
 	// Try platform-specific allocation first
@@ -1,6 +1,8 @@
 package writer
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"sync"
@@ -361,6 +363,202 @@ func TestWriterMethods(t *testing.T) {
 	}
 }
 
+func TestSparseWriter(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "sparse.dat")
+
+	const size = 8 * SparseBlockSize
+	w, err := NewFileWriterWithOptions(testFile, size, Options{Sparse: true})
+	if err != nil {
+		t.Fatalf("failed to create sparse FileWriter: %v", err)
+	}
+
+	// Data in blocks 1 and 5, with a chunk starting mid-block
+	data := make([]byte, size)
+	data[SparseBlockSize+10] = 0xAB
+	data[5*SparseBlockSize] = 0xCD
+	if err := w.WriteAt(data[:3000], 0); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	if err := w.WriteAt(data[3000:], 3000); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	if w.Written() != size {
+		t.Errorf("expected skipped zeros to count as written, got %d of %d", w.Written(), size)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if !bytes.Equal(content, data) {
+		t.Error("sparse file content does not match the data written")
+	}
+}
+
+func TestDropCacheWriter(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "nocache.dat")
+
+	w, err := NewFileWriterWithOptions(testFile, 8192, Options{DropCache: true})
+	if err != nil {
+		t.Fatalf("failed to create FileWriter: %v", err)
+	}
+
+	data := bytes.Repeat([]byte{0x5A}, 8192)
+	if err := w.WriteAt(data[:5000], 0); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	if err := w.WriteAt(data[5000:], 5000); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Dropping the cache must not lose data
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if !bytes.Equal(content, data) {
+		t.Error("file content does not match the data written")
+	}
+
+	if err := DropCache(testFile); err != nil {
+		t.Errorf("DropCache failed: %v", err)
+	}
+	if err := DropCache(testFile + ".missing"); err == nil {
+		t.Error("expected error dropping the cache of a missing file")
+	}
+}
+
+func TestPunchHole(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "holes.dat")
+
+	w, err := NewFileWriter(testFile, 3*4096, false)
+	if err != nil {
+		t.Fatalf("failed to create FileWriter: %v", err)
+	}
+
+	data := make([]byte, 3*4096)
+	for i := range data {
+		data[i] = 0xFF
+	}
+	if err := w.WriteAt(data, 0); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+
+	err = w.PunchHole(4096, 4096)
+	if errors.Is(err, ErrHolesUnsupported) {
+		w.Close()
+		t.Skip("hole punching is not supported here")
+	}
+	if err != nil {
+		t.Fatalf("PunchHole failed: %v", err)
+	}
+	if err := w.PunchHole(0, 4*4096); err == nil {
+		t.Error("expected error for a hole past the end of the file")
+	}
+	w.Close()
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if len(content) != len(data) {
+		t.Fatalf("expected size %d to be kept, got %d", len(data), len(content))
+	}
+	for i, b := range content {
+		expected := byte(0xFF)
+		if i >= 4096 && i < 2*4096 {
+			expected = 0
+		}
+		if b != expected {
+			t.Fatalf("byte %d: expected %#x, got %#x", i, expected, b)
+		}
+	}
+
+	if err := w.PunchHole(0, 4096); err == nil {
+		t.Error("expected error punching a hole after close")
+	}
+}
+
+func TestZeroRange(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "zeroed.dat")
+
+	w, err := NewFileWriter(testFile, 3*4096, false)
+	if err != nil {
+		t.Fatalf("failed to create FileWriter: %v", err)
+	}
+
+	data := bytes.Repeat([]byte{0xFF}, 3*4096)
+	if err := w.WriteAt(data, 0); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+
+	err = w.ZeroRange(4096, 4096)
+	if errors.Is(err, ErrZeroRangeUnsupported) {
+		w.Close()
+		t.Skip("zeroing ranges is not supported here")
+	}
+	if err != nil {
+		t.Fatalf("ZeroRange failed: %v", err)
+	}
+	if err := w.ZeroRange(4096, 3*4096); err == nil {
+		t.Error("expected error for a range past the end of the file")
+	}
+	w.Close()
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	copy(data[4096:], make([]byte, 4096))
+	if !bytes.Equal(content, data) {
+		t.Error("expected only the zeroed range to read back as zeros, with the size kept")
+	}
+
+	if err := w.ZeroRange(0, 4096); err == nil {
+		t.Error("expected error zeroing a range after close")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "truncated.dat")
+
+	w, err := NewFileWriter(testFile, 3*4096, false)
+	if err != nil {
+		t.Fatalf("failed to create FileWriter: %v", err)
+	}
+	if err := w.WriteAt(bytes.Repeat([]byte{0xAB}, 4096), 0); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+
+	if err := w.Truncate(4*4096); err == nil {
+		t.Error("expected error growing the file")
+	}
+	if err := w.Truncate(4096); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if w.TotalSize() != 4096 {
+		t.Errorf("expected total size 4096, got %d", w.TotalSize())
+	}
+	w.Close()
+
+	info, err := os.Stat(testFile)
+	if err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+	if info.Size() != 4096 {
+		t.Errorf("expected file size 4096, got %d", info.Size())
+	}
+	if err := w.Truncate(0); err == nil {
+		t.Error("expected error truncating after close")
+	}
+}
+
 func TestErrorConditions(t *testing.T) {
 	tempDir := t.TempDir()
 
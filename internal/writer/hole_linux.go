@@ -0,0 +1,39 @@
+//go:build linux
+
+package writer
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// fallocate mode flags from linux/falloc.h
+const (
+	fallocKeepSize  = 0x01
+	fallocPunchHole = 0x02
+	fallocZeroRange = 0x10
+)
+
+// punchHole deallocates a byte range of file, keeping its size.
+func punchHole(file *os.File, offset, length int64) error {
+	err := syscall.Fallocate(int(file.Fd()), fallocKeepSize|fallocPunchHole, offset, length)
+	if errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.ENOSYS) {
+		return ErrHolesUnsupported
+	}
+	return err
+}
+
+// zeroRange zeroes a byte range of file in place, keeping its size, and
+// punches it out where zeroing in place is unsupported. Block devices handle
+// both with write-zeroes or discard commands.
+func zeroRange(file *os.File, offset, length int64) error {
+	err := syscall.Fallocate(int(file.Fd()), fallocKeepSize|fallocZeroRange, offset, length)
+	if errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.ENOSYS) {
+		err = syscall.Fallocate(int(file.Fd()), fallocKeepSize|fallocPunchHole, offset, length)
+	}
+	if errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.ENOSYS) {
+		return ErrZeroRangeUnsupported
+	}
+	return err
+}
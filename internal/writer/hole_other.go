@@ -0,0 +1,15 @@
+//go:build !linux
+
+package writer
+
+import "os"
+
+// punchHole is not implemented outside Linux.
+func punchHole(file *os.File, offset, length int64) error {
+	return ErrHolesUnsupported
+}
+
+// zeroRange is not implemented outside Linux.
+func zeroRange(file *os.File, offset, length int64) error {
+	return ErrZeroRangeUnsupported
+}
@@ -0,0 +1,263 @@
+package writer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// objectBackend uploads the parts of a single object to an object store.
+// uploadPart may be called concurrently.
+type objectBackend interface {
+	begin() error
+	uploadPart(number int, data []byte) error
+	complete(parts int) error
+	abort() error
+}
+
+// ObjectLimits are the part constraints of an object store's multipart
+// upload: every part but the last must be at least MinPart bytes, no part may
+// exceed MaxPart bytes, and an upload has at most MaxParts parts.
+type ObjectLimits struct {
+	MinPart  int64
+	MaxPart  int64
+	MaxParts int64
+}
+
+// objectLimits holds the multipart limits of each supported object store.
+var objectLimits = map[string]ObjectLimits{
+	"s3": {MinPart: 5 << 20, MaxPart: 5 << 30, MaxParts: 10000},
+	"gs": {MinPart: 5 << 20, MaxPart: 5 << 30, MaxParts: 10000},
+	"az": {MinPart: 1, MaxPart: 4000 << 20, MaxParts: 50000},
+}
+
+// IsObjectTarget reports whether an output is an object store URL
+// (s3://, gs:// or az://) rather than a file path.
+func IsObjectTarget(output string) bool {
+	scheme, _, ok := strings.Cut(output, "://")
+	_, known := objectLimits[scheme]
+	return ok && known
+}
+
+// ParseObjectTarget splits an object store URL such as s3://bucket/key into
+// its scheme, bucket (or Azure container) and object key, and returns the
+// store's multipart limits.
+func ParseObjectTarget(target string) (string, string, string, ObjectLimits, error) {
+	scheme, rest, _ := strings.Cut(target, "://")
+	limits, ok := objectLimits[scheme]
+	if !ok {
+		return "", "", "", ObjectLimits{}, fmt.Errorf("invalid object target %q: must be s3://, gs:// or az://", target)
+	}
+
+	bucket, key, _ := strings.Cut(rest, "/")
+	if bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+		return "", "", "", ObjectLimits{}, fmt.Errorf("invalid object target %q: must be %s://bucket/key", target, scheme)
+	}
+	return scheme, bucket, key, limits, nil
+}
+
+// newObjectBackend configures the backend for a store from the environment.
+func newObjectBackend(scheme, bucket, key string) (objectBackend, error) {
+	switch scheme {
+	case "s3":
+		creds := s3Credentials{
+			accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		region := os.Getenv("AWS_REGION")
+		if region == "" {
+			region = os.Getenv("AWS_DEFAULT_REGION")
+		}
+		if region == "" {
+			region = "us-east-1"
+		}
+		endpoint := os.Getenv("AWS_ENDPOINT_URL")
+		pathStyle := endpoint != ""
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region)
+		}
+		return newS3Backend(endpoint, pathStyle, bucket, key, region, creds)
+	case "gs":
+		// Cloud Storage's XML API accepts S3 multipart uploads signed with
+		// HMAC keys
+		creds := s3Credentials{
+			accessKey: os.Getenv("GCS_HMAC_ACCESS_KEY_ID"),
+			secretKey: os.Getenv("GCS_HMAC_SECRET"),
+		}
+		return newS3Backend("https://storage.googleapis.com", true, bucket, key, "auto", creds)
+	case "az":
+		account := os.Getenv("AZURE_STORAGE_ACCOUNT")
+		endpoint := os.Getenv("AZURE_STORAGE_ENDPOINT")
+		if endpoint == "" && account != "" {
+			endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", account)
+		}
+		return newAzureBackend(endpoint, bucket, key, os.Getenv("AZURE_STORAGE_SAS_TOKEN"))
+	default:
+		return nil, fmt.Errorf("unsupported object store: %s", scheme)
+	}
+}
+
+// ObjectWriter uploads generated data to an object store as a multipart
+// upload, sending each chunk as one part. Parts are uploaded concurrently as
+// chunks arrive; the object is only created once every part is uploaded.
+type ObjectWriter struct {
+	target    string
+	totalSize int64
+	partSize  int64
+	backend   objectBackend
+	uploads   chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	err       error
+	written   atomic.Int64
+	closed    bool
+}
+
+// NewObjectWriter starts a multipart upload of size bytes to an object store
+// URL (see ParseObjectTarget). Chunks must be partSize bytes, except the last,
+// and up to uploads parts are sent at a time. Credentials are read from the
+// environment: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY for S3,
+// GCS_HMAC_ACCESS_KEY_ID and GCS_HMAC_SECRET for Cloud Storage, and
+// AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_SAS_TOKEN for Azure.
+func NewObjectWriter(target string, size, partSize int64, uploads int) (*ObjectWriter, error) {
+	scheme, bucket, key, _, err := ParseObjectTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := newObjectBackend(scheme, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return newObjectWriter(target, backend, size, partSize, uploads)
+}
+
+// newObjectWriter starts an upload through a configured backend.
+func newObjectWriter(target string, backend objectBackend, size, partSize int64, uploads int) (*ObjectWriter, error) {
+	if size <= 0 || partSize <= 0 {
+		return nil, fmt.Errorf("output and part sizes must be positive, got %d and %d", size, partSize)
+	}
+	if uploads < 1 {
+		uploads = 1
+	}
+
+	if err := backend.begin(); err != nil {
+		return nil, fmt.Errorf("failed to start upload to %s: %v", target, err)
+	}
+
+	return &ObjectWriter{
+		target:    target,
+		totalSize: size,
+		partSize:  partSize,
+		backend:   backend,
+		uploads:   make(chan struct{}, uploads),
+	}, nil
+}
+
+// WriteAt uploads a chunk as the part at the given offset. The data is
+// copied, so the caller may reuse it once WriteAt returns. An error from an
+// earlier part's upload is returned by the next WriteAt.
+func (w *ObjectWriter) WriteAt(data []byte, offset int64) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	closed, err := w.closed, w.err
+	w.mu.Unlock()
+	if closed {
+		return fmt.Errorf("object writer is closed")
+	}
+	if err != nil {
+		return err
+	}
+
+	end := offset + int64(len(data))
+	if offset%w.partSize != 0 || end > w.totalSize || (int64(len(data)) != w.partSize && end != w.totalSize) {
+		return fmt.Errorf("invalid part: offset=%d, len=%d, part size=%d, total=%d",
+			offset, len(data), w.partSize, w.totalSize)
+	}
+
+	part := append([]byte(nil), data...)
+	number := int(offset/w.partSize) + 1
+
+	w.uploads <- struct{}{}
+	w.wg.Add(1)
+	go func() {
+		defer func() {
+			<-w.uploads
+			w.wg.Done()
+		}()
+		if err := w.backend.uploadPart(number, part); err != nil {
+			w.mu.Lock()
+			if w.err == nil {
+				w.err = fmt.Errorf("failed to upload part %d to %s: %v", number, w.target, err)
+			}
+			w.mu.Unlock()
+			return
+		}
+		w.written.Add(int64(len(part)))
+	}()
+	return nil
+}
+
+// Close waits for pending parts and completes the upload, creating the
+// object. If a part failed or not all data was written, the upload is
+// aborted instead and no object is created.
+func (w *ObjectWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	w.wg.Wait()
+
+	if w.err != nil || w.written.Load() != w.totalSize {
+		if err := w.backend.abort(); err != nil && w.err == nil {
+			return fmt.Errorf("failed to abort upload to %s: %v", w.target, err)
+		}
+		return w.err
+	}
+
+	parts := int((w.totalSize + w.partSize - 1) / w.partSize)
+	if err := w.backend.complete(parts); err != nil {
+		return fmt.Errorf("failed to complete upload to %s: %v", w.target, err)
+	}
+	return nil
+}
+
+// Truncate ends the object at size bytes, which must fall on a part
+// boundary. Close then completes the upload with the parts before it.
+func (w *ObjectWriter) Truncate(size int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fmt.Errorf("object writer is closed")
+	}
+	if size <= 0 || size > w.totalSize || (size%w.partSize != 0 && size != w.totalSize) {
+		return fmt.Errorf("invalid object size %d: part size=%d, total=%d", size, w.partSize, w.totalSize)
+	}
+	w.totalSize = size
+	return nil
+}
+
+// Written returns the number of bytes uploaded so far.
+func (w *ObjectWriter) Written() int64 {
+	return w.written.Load()
+}
+
+// TotalSize returns the size of the object.
+func (w *ObjectWriter) TotalSize() int64 {
+	return w.totalSize
+}
+
+// Path returns the object store URL.
+func (w *ObjectWriter) Path() string {
+	return w.target
+}
@@ -0,0 +1,207 @@
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// memoryBackend records the parts of an upload in memory.
+type memoryBackend struct {
+	mu        sync.Mutex
+	parts     map[int][]byte
+	failPart  int
+	completed []byte
+	aborted   bool
+}
+
+func (b *memoryBackend) begin() error {
+	b.parts = make(map[int][]byte)
+	return nil
+}
+
+func (b *memoryBackend) uploadPart(number int, data []byte) error {
+	if number == b.failPart {
+		return errors.New("upload failed")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.parts[number] = data
+	return nil
+}
+
+func (b *memoryBackend) complete(parts int) error {
+	for number := 1; number <= parts; number++ {
+		b.completed = append(b.completed, b.parts[number]...)
+	}
+	return nil
+}
+
+func (b *memoryBackend) abort() error {
+	b.aborted = true
+	return nil
+}
+
+func TestObjectWriterUploadsParts(t *testing.T) {
+	backend := &memoryBackend{}
+	w, err := newObjectWriter("s3://bucket/key", backend, 2500, 1000, 2)
+	if err != nil {
+		t.Fatalf("newObjectWriter failed: %v", err)
+	}
+
+	data := make([]byte, 2500)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// Parts arrive out of order and the buffer is reused afterwards
+	buffer := make([]byte, 1000)
+	for _, offset := range []int64{2000, 0, 1000} {
+		chunk := buffer[:min(1000, 2500-int(offset))]
+		copy(chunk, data[offset:])
+		if err := w.WriteAt(chunk, offset); err != nil {
+			t.Fatalf("WriteAt(%d) failed: %v", offset, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if !bytes.Equal(backend.completed, data) {
+		t.Error("completed object does not match the data written")
+	}
+	if w.Written() != 2500 || backend.aborted {
+		t.Errorf("expected a complete upload of 2500 bytes, got %d (aborted=%v)", w.Written(), backend.aborted)
+	}
+}
+
+func TestObjectWriterInvalidParts(t *testing.T) {
+	w, err := newObjectWriter("s3://bucket/key", &memoryBackend{}, 2500, 1000, 1)
+	if err != nil {
+		t.Fatalf("newObjectWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	tests := []struct {
+		name   string
+		offset int64
+		size   int
+	}{
+		{"unaligned", 500, 1000},
+		{"short part", 0, 500},
+		{"past the end", 2000, 1000},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := w.WriteAt(make([]byte, test.size), test.offset); err == nil {
+				t.Error("expected error for an invalid part")
+			}
+		})
+	}
+}
+
+func TestObjectWriterAborts(t *testing.T) {
+	// A failed part aborts the upload
+	backend := &memoryBackend{failPart: 2}
+	w, _ := newObjectWriter("s3://bucket/key", backend, 2000, 1000, 1)
+	w.WriteAt(make([]byte, 1000), 0)
+	w.WriteAt(make([]byte, 1000), 1000)
+	if err := w.Close(); err == nil {
+		t.Error("expected error from the failed part")
+	}
+	if !backend.aborted || backend.completed != nil {
+		t.Error("expected the upload to be aborted")
+	}
+
+	// So does closing before all data was written, as on cancellation
+	backend = &memoryBackend{}
+	w, _ = newObjectWriter("s3://bucket/key", backend, 2000, 1000, 1)
+	w.WriteAt(make([]byte, 1000), 0)
+	if err := w.Close(); err != nil {
+		t.Errorf("unexpected error closing an incomplete upload: %v", err)
+	}
+	if !backend.aborted {
+		t.Error("expected the incomplete upload to be aborted")
+	}
+	if err := w.WriteAt(make([]byte, 1000), 1000); err == nil {
+		t.Error("expected error writing after close")
+	}
+}
+
+func TestObjectWriterTruncate(t *testing.T) {
+	backend := &memoryBackend{}
+	w, _ := newObjectWriter("s3://bucket/key", backend, 2500, 1000, 1)
+	w.WriteAt(bytes.Repeat([]byte{1}, 1000), 0)
+	w.WriteAt(bytes.Repeat([]byte{2}, 1000), 1000)
+
+	// A truncated object ends on a part boundary
+	for _, size := range []int64{0, 1500, 3000} {
+		if err := w.Truncate(size); err == nil {
+			t.Errorf("expected error truncating to %d bytes", size)
+		}
+	}
+	if err := w.Truncate(2000); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if backend.aborted || len(backend.completed) != 2000 {
+		t.Errorf("expected a complete upload of 2000 bytes, got %d (aborted=%v)", len(backend.completed), backend.aborted)
+	}
+}
+
+func TestParseObjectTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		scheme string
+		bucket string
+		key    string
+		valid  bool
+	}{
+		{"s3://bucket/data/test.dat", "s3", "bucket", "data/test.dat", true},
+		{"gs://bucket/test.dat", "gs", "bucket", "test.dat", true},
+		{"az://container/test.dat", "az", "container", "test.dat", true},
+		{"s3://bucket", "", "", "", false},
+		{"s3://bucket/", "", "", "", false},
+		{"s3://bucket/dir/", "", "", "", false},
+		{"s3:///key", "", "", "", false},
+		{"ftp://host/file", "", "", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.target, func(t *testing.T) {
+			scheme, bucket, key, _, err := ParseObjectTarget(test.target)
+			if !test.valid {
+				if err == nil {
+					t.Errorf("expected error for %q", test.target)
+				}
+				return
+			}
+			if err != nil || scheme != test.scheme || bucket != test.bucket || key != test.key {
+				t.Errorf("expected %s %s %s, got %s %s %s (%v)", test.scheme, test.bucket, test.key, scheme, bucket, key, err)
+			}
+		})
+	}
+
+	if !IsObjectTarget("s3://b/k") || !IsObjectTarget("az://c/b") || IsObjectTarget("tcp://host:1") || IsObjectTarget("out.dat") {
+		t.Error("IsObjectTarget misclassified a target")
+	}
+}
+
+func TestNewObjectWriterCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	if _, err := NewObjectWriter("s3://bucket/key", 100, 100, 1); err == nil {
+		t.Error("expected error without S3 credentials")
+	}
+
+	t.Setenv("AZURE_STORAGE_ACCOUNT", "account")
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "")
+	if _, err := NewObjectWriter("az://container/blob", 100, 100, 1); err == nil {
+		t.Error("expected error without an Azure SAS token")
+	}
+}
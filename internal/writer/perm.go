@@ -0,0 +1,67 @@
+package writer
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// ParseMode parses permission bits given in octal, such as "0600" or "640".
+func ParseMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid mode %q: must be octal permission bits from 0000 to 0777", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// LookupOwner returns the user ID of a user name or numeric ID.
+func LookupOwner(name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown owner %s: %v", name, err)
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+// LookupGroup returns the group ID of a group name or numeric ID.
+func LookupGroup(name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group %s: %v", name, err)
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// applyPermissions gives file the mode, owner and group opts asks for. The
+// mode is set after the file is created, so the umask doesn't mask it.
+func applyPermissions(file *os.File, opts Options) error {
+	if opts.Owner != nil || opts.Group != nil {
+		uid, gid := -1, -1
+		if opts.Owner != nil {
+			uid = *opts.Owner
+		}
+		if opts.Group != nil {
+			gid = *opts.Group
+		}
+		if err := file.Chown(uid, gid); err != nil {
+			if os.IsPermission(err) {
+				return fmt.Errorf("changing the owner or group of %s needs privileges: %v", file.Name(), err)
+			}
+			return fmt.Errorf("failed to change the owner of %s: %v", file.Name(), err)
+		}
+	}
+	if opts.Mode != nil {
+		if err := file.Chmod(*opts.Mode); err != nil {
+			return fmt.Errorf("failed to set the mode of %s: %v", file.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package writer
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  os.FileMode
+		ok    bool
+	}{
+		{"0600", 0600, true},
+		{"640", 0640, true},
+		{"0", 0, true},
+		{"0777", 0777, true},
+		{"1777", 0, false},
+		{"0800", 0, false},
+		{"rw-r--r--", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseMode(tt.input)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseMode(%q) = %o, %v; want %o, ok %v", tt.input, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLookupOwnerAndGroup(t *testing.T) {
+	if uid, err := LookupOwner("1234"); err != nil || uid != 1234 {
+		t.Errorf("expected numeric owner 1234, got %d, %v", uid, err)
+	}
+	if gid, err := LookupGroup("4321"); err != nil || gid != 4321 {
+		t.Errorf("expected numeric group 4321, got %d, %v", gid, err)
+	}
+	if _, err := LookupOwner("no-such-user-trasher"); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+	if _, err := LookupGroup("no-such-group-trasher"); err == nil {
+		t.Error("expected an error for an unknown group")
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("no current user: %v", err)
+	}
+	if uid, err := LookupOwner(current.Username); err != nil || strconv.Itoa(uid) != current.Uid {
+		t.Errorf("expected %s to be uid %s, got %d, %v", current.Username, current.Uid, uid, err)
+	}
+}
+
+func TestFileWriterPermissions(t *testing.T) {
+	dir := t.TempDir()
+	uid, gid := os.Getuid(), os.Getgid()
+
+	// Modes the umask would usually mask are set exactly
+	for _, mode := range []os.FileMode{0600, 0666} {
+		path := filepath.Join(dir, "test-"+strconv.FormatUint(uint64(mode), 8))
+		w, err := NewFileWriterWithOptions(path, 1024, Options{Mode: &mode, Owner: &uid, Group: &gid})
+		if err != nil {
+			t.Fatalf("failed to create FileWriter: %v", err)
+		}
+		w.Close()
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("expected mode %o, got %o", mode, info.Mode().Perm())
+		}
+	}
+
+	// An overwritten file takes the new mode too
+	path := filepath.Join(dir, "existing")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mode := os.FileMode(0640)
+	w, err := NewFileWriterWithOptions(path, 1024, Options{Force: true, Mode: &mode})
+	if err != nil {
+		t.Fatalf("failed to create FileWriter: %v", err)
+	}
+	w.Close()
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("expected the overwritten file to have mode 640, got %v, %v", info.Mode(), err)
+	}
+}
@@ -0,0 +1,241 @@
+package writer
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unsignedPayload skips hashing part data when signing requests.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// s3Credentials are the access keys S3 requests are signed with.
+type s3Credentials struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// s3Backend uploads an object with the S3 multipart upload API, which
+// Cloud Storage's XML API also implements.
+type s3Backend struct {
+	client   *http.Client
+	objURL   string
+	region   string
+	creds    s3Credentials
+	uploadID string
+	mu       sync.Mutex
+	etags    map[int]string
+}
+
+// newS3Backend configures uploads to bucket/key at an endpoint. Path-style
+// endpoints address the bucket in the path rather than the host name.
+func newS3Backend(endpoint string, pathStyle bool, bucket, key, region string, creds s3Credentials) (*s3Backend, error) {
+	if creds.accessKey == "" || creds.secretKey == "" {
+		return nil, fmt.Errorf("missing object store credentials")
+	}
+
+	objURL := strings.TrimSuffix(endpoint, "/")
+	if pathStyle {
+		objURL += "/" + escapePath(bucket)
+	}
+	objURL += "/" + escapePath(key)
+
+	return &s3Backend{
+		client: http.DefaultClient,
+		objURL: objURL,
+		region: region,
+		creds:  creds,
+		etags:  make(map[int]string),
+	}, nil
+}
+
+// begin creates the multipart upload.
+func (b *s3Backend) begin() error {
+	body, _, err := b.do(http.MethodPost, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil || result.UploadID == "" {
+		return fmt.Errorf("unexpected response creating upload: %s", body)
+	}
+	b.uploadID = result.UploadID
+	return nil
+}
+
+// uploadPart uploads one part and records its ETag.
+func (b *s3Backend) uploadPart(number int, data []byte) error {
+	query := url.Values{
+		"partNumber": {strconv.Itoa(number)},
+		"uploadId":   {b.uploadID},
+	}
+	_, header, err := b.do(http.MethodPut, query, data)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.etags[number] = header.Get("ETag")
+	b.mu.Unlock()
+	return nil
+}
+
+// complete assembles the uploaded parts into the object.
+func (b *s3Backend) complete(parts int) error {
+	var buf bytes.Buffer
+	buf.WriteString("<CompleteMultipartUpload>")
+	for number := 1; number <= parts; number++ {
+		fmt.Fprintf(&buf, "<Part><PartNumber>%d</PartNumber><ETag>", number)
+		xml.EscapeText(&buf, []byte(b.etags[number]))
+		buf.WriteString("</ETag></Part>")
+	}
+	buf.WriteString("</CompleteMultipartUpload>")
+
+	// Completion can fail after a 200 response, with the error in the body
+	body, _, err := b.do(http.MethodPost, url.Values{"uploadId": {b.uploadID}}, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return s3Error(body)
+}
+
+// abort discards the upload and any parts already stored.
+func (b *s3Backend) abort() error {
+	_, _, err := b.do(http.MethodDelete, url.Values{"uploadId": {b.uploadID}}, nil)
+	return err
+}
+
+// do sends a signed request for the object and returns the response body.
+func (b *s3Backend) do(method string, query url.Values, body []byte) ([]byte, http.Header, error) {
+	req, err := http.NewRequest(method, b.objURL+"?"+encodeQuery(query), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("x-amz-content-sha256", unsignedPayload)
+	if b.creds.sessionToken != "" {
+		req.Header.Set("x-amz-security-token", b.creds.sessionToken)
+	}
+	signV4(req, b.creds, b.region, "s3", time.Now())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		if err := s3Error(respBody); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("%s %s: %s", method, b.objURL, resp.Status)
+	}
+	return respBody, resp.Header, nil
+}
+
+// s3Error returns the error described by an S3 error response body, if any.
+func s3Error(body []byte) error {
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &result) != nil || result.XMLName.Local != "Error" {
+		return nil
+	}
+	return fmt.Errorf("%s: %s", result.Code, result.Message)
+}
+
+// signV4 signs a request with AWS Signature Version 4, covering the host and
+// every header already set on the request. The payload hash is taken from the
+// x-amz-content-sha256 header.
+func signV4(req *http.Request, creds s3Credentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		encodeQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("x-amz-content-sha256"),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// encodeQuery encodes query parameters sorted by name, escaping spaces as
+// %20 as signing requires.
+func encodeQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// escapePath escapes an object key for a URL path as signing requires,
+// keeping its slashes and RFC 3986 unreserved characters.
+func escapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,187 @@
+package writer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// socketDialTimeout bounds how long connecting to a network target may take.
+const socketDialTimeout = 10 * time.Second
+
+// IsNetworkTarget reports whether an output is a network address
+// (tcp://host:port or unix:///path) rather than a file path.
+func IsNetworkTarget(output string) bool {
+	return strings.HasPrefix(output, "tcp://") || strings.HasPrefix(output, "unix://")
+}
+
+// ParseNetworkTarget splits a network output such as tcp://host:9000 or
+// unix:///run/receiver.sock into a network and an address for net.Dial.
+func ParseNetworkTarget(target string) (string, string, error) {
+	network, address, ok := strings.Cut(target, "://")
+	if !ok || (network != "tcp" && network != "unix") {
+		return "", "", fmt.Errorf("invalid network target %q: must be tcp://host:port or unix:///path", target)
+	}
+
+	if network == "tcp" {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || port == "" {
+			return "", "", fmt.Errorf("invalid network target %q: must be tcp://host:port", target)
+		}
+		return network, net.JoinHostPort(host, port), nil
+	}
+
+	if address == "" {
+		return "", "", fmt.Errorf("invalid network target %q: missing socket path", target)
+	}
+	return network, address, nil
+}
+
+// SocketWriter streams generated data to a TCP or Unix socket receiver.
+// Chunks may be written in any order; they are sent in file order, holding
+// early chunks in memory until the chunks before them arrive. The stream is
+// hashed as it is sent, since it cannot be read back.
+type SocketWriter struct {
+	conn      net.Conn
+	target    string
+	totalSize int64
+	mu        sync.Mutex
+	next      int64
+	pending   map[int64][]byte
+	hasher    hash.Hash
+	written   atomic.Int64
+	closed    bool
+}
+
+// NewSocketWriter connects to a network target (see ParseNetworkTarget)
+// that will receive size bytes.
+func NewSocketWriter(target string, size int64) (*SocketWriter, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("output size must be positive, got %d", size)
+	}
+
+	network, address, err := ParseNetworkTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout(network, address, socketDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", target, err)
+	}
+
+	return &SocketWriter{
+		conn:      conn,
+		target:    target,
+		totalSize: size,
+		pending:   make(map[int64][]byte),
+		hasher:    sha256.New(),
+	}, nil
+}
+
+// WriteAt sends data destined for the given offset once everything before
+// it has been sent. Out-of-order data is copied, so the caller may reuse it.
+func (w *SocketWriter) WriteAt(data []byte, offset int64) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fmt.Errorf("socket writer is closed")
+	}
+	if offset < w.next || offset+int64(len(data)) > w.totalSize {
+		return fmt.Errorf("invalid write: offset=%d, len=%d, sent=%d, total=%d",
+			offset, len(data), w.next, w.totalSize)
+	}
+
+	if offset != w.next {
+		if _, exists := w.pending[offset]; exists {
+			return fmt.Errorf("duplicate write at offset %d", offset)
+		}
+		w.pending[offset] = append([]byte(nil), data...)
+		return nil
+	}
+
+	if err := w.send(data); err != nil {
+		return err
+	}
+	for {
+		chunk, ok := w.pending[w.next]
+		if !ok {
+			return nil
+		}
+		delete(w.pending, w.next)
+		if err := w.send(chunk); err != nil {
+			return err
+		}
+	}
+}
+
+// send writes the next data of the stream to the connection.
+func (w *SocketWriter) send(data []byte) error {
+	n, err := w.conn.Write(data)
+	w.hasher.Write(data[:n])
+	w.next += int64(n)
+	w.written.Add(int64(n))
+	if err != nil {
+		return fmt.Errorf("failed to send data at offset %d to %s: %v", w.next, w.target, err)
+	}
+	return nil
+}
+
+// Close closes the connection. Data still waiting for earlier chunks is
+// discarded.
+func (w *SocketWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.pending = nil
+	return w.conn.Close()
+}
+
+// Truncate ends the stream at size bytes. Data already sent can't be taken
+// back, so size must not be before it.
+func (w *SocketWriter) Truncate(size int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if size < w.next || size > w.totalSize {
+		return fmt.Errorf("invalid stream size %d: sent=%d, total=%d", size, w.next, w.totalSize)
+	}
+	w.totalSize = size
+	return nil
+}
+
+// Checksum returns the SHA-256 of the data sent so far, in hex.
+func (w *SocketWriter) Checksum() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return hex.EncodeToString(w.hasher.Sum(nil))
+}
+
+// Written returns the number of bytes sent so far.
+func (w *SocketWriter) Written() int64 {
+	return w.written.Load()
+}
+
+// TotalSize returns the total number of bytes to send.
+func (w *SocketWriter) TotalSize() int64 {
+	return w.totalSize
+}
+
+// Path returns the network target.
+func (w *SocketWriter) Path() string {
+	return w.target
+}
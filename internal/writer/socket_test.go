@@ -0,0 +1,207 @@
+package writer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// receive accepts one connection on l and returns everything it sends.
+func receive(t *testing.T, l net.Listener) <-chan []byte {
+	t.Helper()
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- data
+	}()
+	return received
+}
+
+func TestSocketWriterOrdersChunks(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	received := receive(t, l)
+
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	w, err := NewSocketWriter("tcp://"+l.Addr().String(), int64(len(data)))
+	if err != nil {
+		t.Fatalf("NewSocketWriter failed: %v", err)
+	}
+
+	// Chunks arrive out of order, as they do from several workers
+	for _, chunk := range [][2]int{{6000, 10000}, {2000, 6000}, {0, 2000}} {
+		if err := w.WriteAt(data[chunk[0]:chunk[1]], int64(chunk[0])); err != nil {
+			t.Fatalf("WriteAt(%d) failed: %v", chunk[0], err)
+		}
+		if chunk[0] != 0 && w.Written() != 0 {
+			t.Errorf("expected nothing sent before the first chunk, got %d bytes", w.Written())
+		}
+	}
+	if w.Written() != int64(len(data)) {
+		t.Errorf("expected %d bytes sent, got %d", len(data), w.Written())
+	}
+
+	sum := sha256.Sum256(data)
+	if w.Checksum() != hex.EncodeToString(sum[:]) {
+		t.Error("checksum does not match the data sent")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if got := <-received; !bytes.Equal(got, data) {
+		t.Errorf("receiver got %d bytes that do not match the data written", len(got))
+	}
+	if err := w.WriteAt(data[:1], 0); err == nil {
+		t.Error("expected error writing after close")
+	}
+}
+
+func TestSocketWriterUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not tested on windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "receiver.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	received := receive(t, l)
+
+	w, err := NewSocketWriter("unix://"+path, 5)
+	if err != nil {
+		t.Fatalf("NewSocketWriter failed: %v", err)
+	}
+	w.WriteAt([]byte("hello"), 0)
+	w.Close()
+
+	if got := <-received; string(got) != "hello" {
+		t.Errorf("expected hello, got %q", got)
+	}
+}
+
+func TestSocketWriterInvalidWrites(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	receive(t, l)
+
+	w, err := NewSocketWriter("tcp://"+l.Addr().String(), 100)
+	if err != nil {
+		t.Fatalf("NewSocketWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	w.WriteAt(make([]byte, 10), 0)
+	if err := w.WriteAt(make([]byte, 10), 0); err == nil {
+		t.Error("expected error rewriting data already sent")
+	}
+	if err := w.WriteAt(make([]byte, 10), 95); err == nil {
+		t.Error("expected error writing past the end")
+	}
+	w.WriteAt(make([]byte, 10), 50)
+	if err := w.WriteAt(make([]byte, 10), 50); err == nil {
+		t.Error("expected error for a duplicate pending chunk")
+	}
+}
+
+func TestSocketWriterTruncate(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	receive(t, l)
+
+	w, err := NewSocketWriter("tcp://"+l.Addr().String(), 100)
+	if err != nil {
+		t.Fatalf("NewSocketWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	w.WriteAt(make([]byte, 50), 0)
+	if err := w.Truncate(40); err == nil {
+		t.Error("expected error truncating data already sent")
+	}
+	if err := w.Truncate(60); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if w.TotalSize() != 60 {
+		t.Errorf("expected total size 60, got %d", w.TotalSize())
+	}
+	if err := w.WriteAt(make([]byte, 20), 50); err == nil {
+		t.Error("expected error writing past the new end")
+	}
+}
+
+func TestParseNetworkTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		network string
+		address string
+		valid   bool
+	}{
+		{"tcp://example.com:9000", "tcp", "example.com:9000", true},
+		{"tcp://[::1]:9000", "tcp", "[::1]:9000", true},
+		{"unix:///run/receiver.sock", "unix", "/run/receiver.sock", true},
+		{"tcp://example.com", "", "", false},
+		{"tcp://example.com:", "", "", false},
+		{"unix://", "", "", false},
+		{"udp://example.com:9000", "", "", false},
+		{"/tmp/file.dat", "", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.target, func(t *testing.T) {
+			network, address, err := ParseNetworkTarget(test.target)
+			if !test.valid {
+				if err == nil {
+					t.Errorf("expected error for %q", test.target)
+				}
+				return
+			}
+			if err != nil || network != test.network || address != test.address {
+				t.Errorf("expected %s %s, got %s %s (%v)", test.network, test.address, network, address, err)
+			}
+		})
+	}
+
+	if !IsNetworkTarget("tcp://host:1") || !IsNetworkTarget("unix:///s") || IsNetworkTarget("out.dat") {
+		t.Error("IsNetworkTarget misclassified a target")
+	}
+}
+
+func TestSocketWriterConnectError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	if _, err := NewSocketWriter("tcp://"+addr, 100); err == nil {
+		t.Error("expected error connecting to a closed port")
+	}
+}
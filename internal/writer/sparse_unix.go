@@ -0,0 +1,11 @@
+//go:build unix || linux || darwin
+
+package writer
+
+import "os"
+
+// markSparse is a no-op on Unix, where ranges that are never written are
+// left unallocated without asking.
+func markSparse(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package writer
+
+import (
+	"os"
+	"syscall"
+)
+
+// fsctlSetSparse is FSCTL_SET_SPARSE from winioctl.h.
+const fsctlSetSparse = 0x000900c4
+
+// markSparse flags the file as sparse. NTFS otherwise allocates and zeroes
+// every range up to the furthest write.
+func markSparse(file *os.File) error {
+	var returned uint32
+	return syscall.DeviceIoControl(syscall.Handle(file.Fd()), fsctlSetSparse, nil, 0, nil, 0, &returned, nil)
+}
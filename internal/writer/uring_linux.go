@@ -0,0 +1,328 @@
+//go:build linux
+
+package writer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// io_uring system calls, ring offsets and opcodes from linux/io_uring.h.
+// The system call numbers are the same on every architecture.
+const (
+	sysIOUringSetup     = 425
+	sysIOUringEnter     = 426
+	uringOffSQRing      = 0
+	uringOffCQRing      = 0x8000000
+	uringOffSQEs        = 0x10000000
+	uringOpNop          = 0
+	uringOpWrite        = 23
+	uringEnterGetEvents = 1
+)
+
+// uringEntries is the size of the submission queue, and so the most writes
+// in flight at once.
+const uringEntries = 256
+
+// uringMaxWrite is the most one write submits, since its length is 32 bits.
+const uringMaxWrite = 1 << 30
+
+// uringParams is struct io_uring_params.
+type uringParams struct {
+	sqEntries    uint32
+	cqEntries    uint32
+	flags        uint32
+	sqThreadCPU  uint32
+	sqThreadIdle uint32
+	features     uint32
+	wqFD         uint32
+	resv         [3]uint32
+	sqOff        uringSQOffsets
+	cqOff        uringCQOffsets
+}
+
+// uringSQOffsets is struct io_sqring_offsets.
+type uringSQOffsets struct {
+	head        uint32
+	tail        uint32
+	ringMask    uint32
+	ringEntries uint32
+	flags       uint32
+	dropped     uint32
+	array       uint32
+	resv1       uint32
+	userAddr    uint64
+}
+
+// uringCQOffsets is struct io_cqring_offsets.
+type uringCQOffsets struct {
+	head        uint32
+	tail        uint32
+	ringMask    uint32
+	ringEntries uint32
+	overflow    uint32
+	cqes        uint32
+	flags       uint32
+	resv1       uint32
+	userAddr    uint64
+}
+
+// uringSQE is struct io_uring_sqe.
+type uringSQE struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	rwFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	spliceFDIn  int32
+	addr3       uint64
+	pad         uint64
+}
+
+// uringCQE is struct io_uring_cqe.
+type uringCQE struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+// uringEngine submits writes through an io_uring. Writers queue their write
+// and wait, while a reaper goroutine collects completions and hands each
+// result to its writer, so writes from many workers are in flight at once
+// without a thread each.
+type uringEngine struct {
+	ringFD int
+	fileFD int32
+
+	sqRing  []byte
+	cqRing  []byte
+	sqeMem  []byte
+	sqes    []uringSQE
+	sqArray []uint32
+	sqTail  *uint32
+	sqMask  uint32
+	cqes    []uringCQE
+	cqHead  *uint32
+	cqTail  *uint32
+	cqMask  uint32
+
+	submitMu sync.Mutex
+	slots    chan struct{}
+	waitMu   sync.Mutex
+	waiting  map[uint64]chan int32
+	nextID   uint64
+	reaped   chan struct{}
+	reapErr  error
+}
+
+// newUringEngine sets up a ring writing to file.
+func newUringEngine(file *os.File) (*uringEngine, error) {
+	var params uringParams
+	ringFD, _, errno := syscall.Syscall(sysIOUringSetup, uringEntries, uintptr(unsafe.Pointer(&params)), 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("io_uring_setup: %v", errno)
+	}
+
+	e := &uringEngine{
+		ringFD:  int(ringFD),
+		fileFD:  int32(file.Fd()),
+		slots:   make(chan struct{}, params.sqEntries),
+		waiting: make(map[uint64]chan int32),
+		reaped:  make(chan struct{}),
+	}
+	if err := e.mapRings(&params); err != nil {
+		e.release()
+		return nil, err
+	}
+
+	go e.reap()
+	return e, nil
+}
+
+// mapRings maps the submission and completion queues into memory.
+func (e *uringEngine) mapRings(params *uringParams) error {
+	mmap := func(offset int64, size int) ([]byte, error) {
+		return syscall.Mmap(e.ringFD, offset, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	}
+
+	var err error
+	sqSize := int(params.sqOff.array + params.sqEntries*4)
+	if e.sqRing, err = mmap(uringOffSQRing, sqSize); err != nil {
+		return fmt.Errorf("failed to map submission queue: %v", err)
+	}
+	cqSize := int(params.cqOff.cqes + params.cqEntries*uint32(unsafe.Sizeof(uringCQE{})))
+	if e.cqRing, err = mmap(uringOffCQRing, cqSize); err != nil {
+		return fmt.Errorf("failed to map completion queue: %v", err)
+	}
+	sqeSize := int(params.sqEntries) * int(unsafe.Sizeof(uringSQE{}))
+	if e.sqeMem, err = mmap(uringOffSQEs, sqeSize); err != nil {
+		return fmt.Errorf("failed to map submission entries: %v", err)
+	}
+
+	e.sqes = unsafe.Slice((*uringSQE)(unsafe.Pointer(&e.sqeMem[0])), params.sqEntries)
+	e.sqArray = unsafe.Slice((*uint32)(unsafe.Pointer(&e.sqRing[params.sqOff.array])), params.sqEntries)
+	e.sqTail = (*uint32)(unsafe.Pointer(&e.sqRing[params.sqOff.tail]))
+	e.sqMask = *(*uint32)(unsafe.Pointer(&e.sqRing[params.sqOff.ringMask]))
+	e.cqes = unsafe.Slice((*uringCQE)(unsafe.Pointer(&e.cqRing[params.cqOff.cqes])), params.cqEntries)
+	e.cqHead = (*uint32)(unsafe.Pointer(&e.cqRing[params.cqOff.head]))
+	e.cqTail = (*uint32)(unsafe.Pointer(&e.cqRing[params.cqOff.tail]))
+	e.cqMask = *(*uint32)(unsafe.Pointer(&e.cqRing[params.cqOff.ringMask]))
+	return nil
+}
+
+// release unmaps the rings and closes the ring.
+func (e *uringEngine) release() {
+	for _, ring := range [][]byte{e.sqRing, e.cqRing, e.sqeMem} {
+		if ring != nil {
+			syscall.Munmap(ring)
+		}
+	}
+	syscall.Close(e.ringFD)
+}
+
+// WriteAt writes data at offset, resubmitting the rest of short writes.
+func (e *uringEngine) WriteAt(data []byte, offset int64) (int, error) {
+	written := 0
+	for written < len(data) {
+		n, err := e.write(data[written:min(len(data), written+uringMaxWrite)], offset+int64(written))
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if n == 0 {
+			return written, io.ErrShortWrite
+		}
+	}
+	return written, nil
+}
+
+// write submits one write and waits for its result.
+func (e *uringEngine) write(data []byte, offset int64) (int, error) {
+	e.slots <- struct{}{}
+	defer func() { <-e.slots }()
+
+	done := make(chan int32, 1)
+	e.waitMu.Lock()
+	e.nextID++
+	id := e.nextID
+	e.waiting[id] = done
+	e.waitMu.Unlock()
+
+	err := e.submit(uringSQE{
+		opcode:   uringOpWrite,
+		fd:       e.fileFD,
+		off:      uint64(offset),
+		addr:     uint64(uintptr(unsafe.Pointer(unsafe.SliceData(data)))),
+		len:      uint32(len(data)),
+		userData: id,
+	})
+	if err != nil {
+		e.waitMu.Lock()
+		delete(e.waiting, id)
+		e.waitMu.Unlock()
+		return 0, err
+	}
+
+	// The kernel writes from data until the write completes
+	var res int32
+	select {
+	case res = <-done:
+	case <-e.reaped:
+		select {
+		case res = <-done:
+		default:
+			return 0, e.reapErr
+		}
+	}
+	runtime.KeepAlive(data)
+
+	if res < 0 {
+		return 0, syscall.Errno(-res)
+	}
+	return int(res), nil
+}
+
+// submit queues sqe and passes it to the kernel. Writes in flight are
+// limited to the queue size and the kernel takes every queued entry when
+// entered, so the queue always has room.
+func (e *uringEngine) submit(sqe uringSQE) error {
+	e.submitMu.Lock()
+	defer e.submitMu.Unlock()
+
+	tail := atomic.LoadUint32(e.sqTail)
+	index := tail & e.sqMask
+	e.sqes[index] = sqe
+	e.sqArray[index] = index
+	atomic.StoreUint32(e.sqTail, tail+1)
+
+	for {
+		_, _, errno := syscall.Syscall6(sysIOUringEnter, uintptr(e.ringFD), 1, 0, 0, 0, 0)
+		switch errno {
+		case 0:
+			return nil
+		case syscall.EINTR, syscall.EAGAIN, syscall.EBUSY:
+			continue
+		default:
+			return fmt.Errorf("io_uring_enter: %v", errno)
+		}
+	}
+}
+
+// reap hands completed writes to their writers until a completion without
+// an ID, which Close submits, arrives or the ring fails.
+func (e *uringEngine) reap() {
+	defer close(e.reaped)
+
+	for {
+		head := atomic.LoadUint32(e.cqHead)
+		tail := atomic.LoadUint32(e.cqTail)
+		if head == tail {
+			_, _, errno := syscall.Syscall6(sysIOUringEnter, uintptr(e.ringFD), 0, 1, uringEnterGetEvents, 0, 0)
+			if errno != 0 && errno != syscall.EINTR {
+				e.reapErr = fmt.Errorf("io_uring_enter: %v", errno)
+				return
+			}
+			continue
+		}
+
+		for ; head != tail; head++ {
+			cqe := e.cqes[head&e.cqMask]
+			if cqe.userData == 0 {
+				atomic.StoreUint32(e.cqHead, head+1)
+				return
+			}
+
+			e.waitMu.Lock()
+			done := e.waiting[cqe.userData]
+			delete(e.waiting, cqe.userData)
+			e.waitMu.Unlock()
+			if done != nil {
+				done <- cqe.res
+			}
+		}
+		atomic.StoreUint32(e.cqHead, head)
+	}
+}
+
+// Close stops the reaper and tears down the ring. No writes are in flight by
+// then, so the no-op it submits to stop the reaper completes last.
+func (e *uringEngine) Close() error {
+	err := e.submit(uringSQE{opcode: uringOpNop})
+	if err == nil {
+		<-e.reaped
+	}
+	e.release()
+	return err
+}
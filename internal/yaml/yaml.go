@@ -0,0 +1,313 @@
+// Package yaml decodes the subset of YAML that trasher's job and config files
+// use: block mappings and sequences, plain and quoted scalars, and comments.
+// Anchors, tags, flow collections and multi-line scalars are rejected.
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// line is a non-blank line of a document with its comment removed.
+type line struct {
+	num     int
+	indent  int
+	content string
+}
+
+// parser builds a value from a document's lines.
+type parser struct {
+	lines []line
+	pos   int
+}
+
+// SyntaxError reports a document outside the supported subset.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("yaml: line %d: %s", e.Line, e.Msg)
+}
+
+// Unmarshal decodes a document into v. The document is converted to JSON
+// and decoded with encoding/json, so v uses json struct tags, and keys
+// that match no field of a struct are an error.
+func Unmarshal(data []byte, v any) error {
+	value, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("yaml: %s", strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+// Parse decodes a document into map[string]any, []any, string, bool,
+// json.Number and nil values. An empty document is nil.
+func Parse(data []byte) (any, error) {
+	lines, err := split(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	p := &parser{lines: lines}
+	value, err := p.node(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected %q after the document's top-level value", p.lines[p.pos].content)
+	}
+	return value, nil
+}
+
+// split returns the document's non-blank lines without their comments.
+func split(doc string) ([]line, error) {
+	var lines []line
+	for i, text := range strings.Split(doc, "\n") {
+		text = strings.TrimRight(stripComment(text), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, &SyntaxError{Line: i + 1, Msg: "tabs can't be used for indentation"}
+		}
+		lines = append(lines, line{num: i + 1, indent: len(text) - len(trimmed), content: trimmed})
+	}
+	return lines, nil
+}
+
+// stripComment removes a comment: a # at the start of the line or after a
+// space, outside quoted scalars.
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || text[i-1] == ' '):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	num := 0
+	if p.pos < len(p.lines) {
+		num = p.lines[p.pos].num
+	} else if len(p.lines) > 0 {
+		num = p.lines[len(p.lines)-1].num
+	}
+	return &SyntaxError{Line: num, Msg: fmt.Sprintf(format, args...)}
+}
+
+// node parses the value starting at the current line, which is indented by
+// indent.
+func (p *parser) node(indent int) (any, error) {
+	l := p.lines[p.pos]
+	if l.indent != indent {
+		return nil, p.errorf("unexpected indentation")
+	}
+	if isItem(l.content) {
+		return p.sequence(indent)
+	}
+	if _, _, ok := splitKey(l.content); ok {
+		return p.mapping(indent)
+	}
+	p.pos++
+	return scalar(l.content, l.num)
+}
+
+// sequence parses the items of a block sequence indented by indent.
+func (p *parser) sequence(indent int) (any, error) {
+	items := []any{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		// A sequence that is a mapping's value may end at the mapping's
+		// next key
+		if l.indent < indent || (l.indent == indent && !isItem(l.content)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		rest := strings.TrimLeft(l.content[1:], " ")
+		if rest == "" {
+			// The item's value is on the following lines, or empty
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				item, err := p.node(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			} else {
+				items = append(items, nil)
+			}
+			continue
+		}
+
+		// The item starts on the dash's line; parse it as if it started
+		// a line of its own, so a mapping can continue below
+		p.lines[p.pos] = line{num: l.num, indent: indent + len(l.content) - len(rest), content: rest}
+		item, err := p.node(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// mapping parses the entries of a block mapping indented by indent.
+func (p *parser) mapping(indent int) (any, error) {
+	entries := map[string]any{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		key, value, ok := splitKey(l.content)
+		if !ok {
+			return nil, p.errorf("expected a key: value pair")
+		}
+		key, err := unquote(key, l.num)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := entries[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		p.pos++
+
+		if value != "" {
+			if entries[key], err = scalar(value, l.num); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// A nested value is indented further, except that a sequence may
+		// sit at the key's own indentation
+		switch {
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			entries[key], err = p.node(p.lines[p.pos].indent)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isItem(p.lines[p.pos].content):
+			entries[key], err = p.sequence(indent)
+		default:
+			entries[key] = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// isItem reports whether content starts a sequence item.
+func isItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// splitKey splits "key: value" at the first colon followed by a space or
+// the end of the line, outside quotes.
+func splitKey(content string) (key, value string, ok bool) {
+	var quote byte
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && i == 0:
+			quote = c
+		case c == ':' && (i+1 == len(content) || content[i+1] == ' '):
+			return strings.TrimSpace(content[:i]), strings.TrimSpace(content[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+var (
+	intPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	floatPattern = regexp.MustCompile(`^[-+]?([0-9]+\.[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`)
+)
+
+// scalar decodes a scalar: quoted strings, null, booleans and numbers, and
+// anything else as a plain string.
+func scalar(text string, num int) (any, error) {
+	switch text[0] {
+	case '"', '\'':
+		return unquote(text, num)
+	case '[', '{':
+		return nil, &SyntaxError{Line: num, Msg: "flow collections are not supported"}
+	case '&', '*', '!':
+		return nil, &SyntaxError{Line: num, Msg: "anchors, aliases and tags are not supported"}
+	case '|', '>':
+		return nil, &SyntaxError{Line: num, Msg: "multi-line scalars are not supported"}
+	}
+
+	switch text {
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if intPattern.MatchString(text) || floatPattern.MatchString(text) {
+		return json.Number(strings.TrimPrefix(text, "+")), nil
+	}
+	return text, nil
+}
+
+// unquote decodes a double- or single-quoted string; other text is returned
+// as it is.
+func unquote(text string, num int) (string, error) {
+	if len(text) == 0 || (text[0] != '"' && text[0] != '\'') {
+		return text, nil
+	}
+	if len(text) < 2 || text[len(text)-1] != text[0] {
+		return "", &SyntaxError{Line: num, Msg: "unterminated quoted string"}
+	}
+	if text[0] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	s, err := strconv.Unquote(text)
+	if err != nil {
+		return "", &SyntaxError{Line: num, Msg: fmt.Sprintf("invalid quoted string %s", text)}
+	}
+	return s, nil
+}
@@ -0,0 +1,110 @@
+package yaml
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc := `# jobs
+defaults:
+  pattern: random   # the default
+  sync: true
+  note: it's fine # really
+files:
+  - path: "data/a #1.bin"
+    size: 10GB
+    seed: 42
+  - path: 'it''s.bin'
+    size: 512
+    ratio: 0.5
+  -
+    path: c.bin
+    pattern:
+empty:
+list:
+- one
+- ~
+`
+	got, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"defaults": map[string]any{"pattern": "random", "sync": true, "note": "it's fine"},
+		"files": []any{
+			map[string]any{"path": "data/a #1.bin", "size": "10GB", "seed": json.Number("42")},
+			map[string]any{"path": "it's.bin", "size": json.Number("512"), "ratio": json.Number("0.5")},
+			map[string]any{"path": "c.bin", "pattern": nil},
+		},
+		"empty": nil,
+		"list":  []any{"one", nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseNestedSequences(t *testing.T) {
+	got, err := Parse([]byte("- - a\n  - b\n- c: 1\n  d:\n  - e\n  f: 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{
+		[]any{"a", "b"},
+		map[string]any{"c": json.Number("1"), "d": []any{"e"}, "f": json.Number("2")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		doc  string
+		line int
+	}{
+		{"a: 1\n  b: 2\n", 2},
+		{"a: 1\na: 2\n", 2},
+		{"a: [1, 2]\n", 1},
+		{"a: |\n  text\n", 1},
+		{"a: &x 1\n", 1},
+		{"a: \"open\n", 1},
+		{"a:\n\tb: 1\n", 2},
+		{"- a\nb: 1\n", 2},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.doc))
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: expected a syntax error, got %v", tt.doc, err)
+			continue
+		}
+		if syntaxErr.Line != tt.line {
+			t.Errorf("%q: expected an error on line %d, got %v", tt.doc, tt.line, err)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var v struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+		Tags  []string
+	}
+	if err := Unmarshal([]byte("name: x\ncount: 3\nTags:\n  - a\n"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "x" || v.Count != 3 || !reflect.DeepEqual(v.Tags, []string{"a"}) {
+		t.Errorf("unexpected value %+v", v)
+	}
+
+	if err := Unmarshal([]byte("nmae: x\n"), &v); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+	if err := Unmarshal([]byte("count: many\n"), &v); err == nil {
+		t.Error("expected an error for a mistyped value")
+	}
+}
@@ -0,0 +1,25 @@
+// Package checksum computes the checksums trasher records for the files it
+// generates, and reads them back to verify and repair those files.
+//
+// A ChecksumGenerator hashes a file in chunks that arrive in any order and
+// from any number of goroutines. Each chunk is identified by its offset:
+// UpdateWithChunk feeds data to the chunk at an offset, and may be called
+// again with the same offset to append the chunk's next part, while
+// AddChunkChecksum records a chunk hashed elsewhere with NewChunkHash. Once
+// the file is complete, Finalize returns the full-file checksum, by reading
+// the file back in ModeStream, from the chunks passed to UpdateOrdered in
+// ModeOrdered, or by combining the chunk checksums in ModeTree, and
+// WriteChecksumFile, WriteManifest and WriteFileSet record them.
+//
+// A generator's state, including chunks only partly hashed, can be saved
+// with SaveState and restored with LoadState, so a long-running program can
+// stop and carry on hashing a file later:
+//
+//	gen := checksum.NewChecksumGenerator("data.bin", size)
+//	gen.UpdateWithChunk(chunk, offset)
+//	gen.SaveState("data.bin.state")
+//	...
+//	gen, err := checksum.LoadState("data.bin.state")
+//	gen.UpdateWithChunk(next, nextOffset)
+//	sum, err := gen.Finalize()
+package checksum
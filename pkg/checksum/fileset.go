@@ -0,0 +1,180 @@
+package checksum
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFileSet is returned by LoadFileSet for files that aren't a
+// checksum file covering several files.
+var ErrNotFileSet = errors.New("not a multi-file checksum file")
+
+// maxFileSetSize bounds the files LoadFileSet reads, so a large data file
+// passed by mistake isn't read whole.
+const maxFileSetSize = 256 * 1024 * 1024
+
+// FileSet is one checksum file covering several generated files, written
+// instead of a checksum file next to each of them.
+type FileSet struct {
+	// Algorithm is the algorithm of the full-file checksums; chunk
+	// checksums are always SHA256.
+	Algorithm string         `json:"algorithm"`
+	Files     []FileSetEntry `json:"files"`
+}
+
+// FileSetEntry is one file of a FileSet.
+type FileSetEntry struct {
+	// Path is relative to the checksum file's directory unless absolute.
+	Path     string          `json:"path"`
+	Size     int64           `json:"size,omitempty"`
+	Checksum string          `json:"checksum"`
+	Chunks   []ManifestChunk `json:"chunks,omitempty"`
+}
+
+// WriteFileSet writes one checksum file at path covering the outputs of
+// generators, each sizes[i] bytes long, finalizing their checksums. In
+// FormatTrasher it is JSON holding every file's full-file and chunk
+// checksums; in FormatGNU and FormatBSD it lists the full-file checksums as
+// sha256sum does, so `sha256sum -c` checks all files at once.
+func WriteFileSet(path, format string, generators []*ChecksumGenerator, sizes []int64) error {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	set := FileSet{Algorithm: chunkAlgorithm}
+	for i, c := range generators {
+		sum, err := c.Finalize()
+		if err != nil {
+			return err
+		}
+		set.Algorithm = c.algorithm
+
+		name, err := relativePath(dir, c.outputPath)
+		if err != nil {
+			return err
+		}
+		set.Files = append(set.Files, FileSetEntry{
+			Path:     filepath.ToSlash(name),
+			Size:     sizes[i],
+			Checksum: sum,
+			Chunks:   c.manifestChunks(sizes[i]),
+		})
+	}
+
+	var data []byte
+	switch format {
+	case FormatGNU, FormatBSD:
+		if set.Algorithm != chunkAlgorithm {
+			return fmt.Errorf("%s checksums can't be written in %s format", set.Algorithm, format)
+		}
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "# Checksums of %d files\n# Generated by trasher\n\n", len(set.Files))
+		for _, entry := range set.Files {
+			if format == FormatGNU {
+				fmt.Fprintf(&buf, "%s  %s\n", entry.Checksum, entry.Path)
+			} else {
+				fmt.Fprintf(&buf, "%s (%s) = %s\n", chunkAlgorithm, entry.Path, entry.Checksum)
+			}
+		}
+		data = buf.Bytes()
+	default:
+		encoded, err := json.MarshalIndent(set, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode checksum file: %v", err)
+		}
+		data = append(encoded, '\n')
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checksum file: %v", err)
+	}
+	return nil
+}
+
+// relativePath returns path relative to dir, or absolute if it isn't below
+// dir's volume.
+func relativePath(dir, path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(dir, abs); err == nil {
+		return rel, nil
+	}
+	return abs, nil
+}
+
+// LoadFileSet reads a checksum file covering several files, in the JSON
+// format WriteFileSet writes or in sha256sum format. Files that are neither
+// return ErrNotFileSet.
+func LoadFileSet(path string) (*FileSet, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() || info.Size() > maxFileSetSize {
+		return nil, ErrNotFileSet
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set FileSet
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &set); err != nil || len(set.Files) == 0 {
+			return nil, ErrNotFileSet
+		}
+		return &set, nil
+	}
+
+	set.Algorithm = chunkAlgorithm
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		_, sum, name, ok := parseStandardLine(line)
+		if !ok {
+			return nil, ErrNotFileSet
+		}
+		set.Files = append(set.Files, FileSetEntry{Path: name, Checksum: sum})
+	}
+	if scanner.Err() != nil || len(set.Files) == 0 {
+		return nil, ErrNotFileSet
+	}
+	return &set, nil
+}
+
+// ResolvePath returns the path of the entry's file given the path of the
+// checksum file listing it.
+func (e FileSetEntry) ResolvePath(setPath string) string {
+	path := filepath.FromSlash(e.Path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(setPath), path)
+}
+
+// ChunkChecksums returns the chunks of the entry's file of fileSize bytes
+// to verify: its recorded chunks, or the whole file as one chunk if only its
+// SHA-256 is known.
+func (e FileSetEntry) ChunkChecksums(fileSize int64, algorithm string) ([]ChunkInfo, error) {
+	if len(e.Chunks) == 0 {
+		if algorithm != chunkAlgorithm {
+			return nil, fmt.Errorf("no chunk checksums recorded for %s", e.Path)
+		}
+		if fileSize == 0 {
+			return nil, fmt.Errorf("%s is empty", e.Path)
+		}
+		return []ChunkInfo{{Offset: 0, Size: fileSize, Checksum: e.Checksum}}, nil
+	}
+	return manifestChunkInfo(Manifest{Size: e.Size, Algorithm: chunkAlgorithm, Chunks: e.Chunks}, e.Path, fileSize)
+}
@@ -0,0 +1,125 @@
+package checksum
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFileSet writes three files under dir, one in a subdirectory, and a
+// checksum file covering them in format.
+func writeFileSet(t *testing.T, dir, format string) (string, []string) {
+	t.Helper()
+	var generators []*ChecksumGenerator
+	var sizes []int64
+	var paths []string
+	for i, name := range []string{"a.bin", "b.bin", filepath.Join("sub", "c.bin")} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		data := []byte(strings.Repeat(name, 300*(i+1)))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		generator := NewChecksumGenerator(path, int64(len(data)))
+		generator.UpdateWithChunk(data[:1024], 0)
+		generator.UpdateWithChunk(data[1024:], 1024)
+		generators = append(generators, generator)
+		sizes = append(sizes, int64(len(data)))
+		paths = append(paths, path)
+	}
+
+	setPath := filepath.Join(dir, "SHA256SUMS")
+	if err := WriteFileSet(setPath, format, generators, sizes); err != nil {
+		t.Fatalf("WriteFileSet failed: %v", err)
+	}
+	return setPath, paths
+}
+
+func TestWriteAndLoadFileSet(t *testing.T) {
+	for _, format := range []string{FormatTrasher, FormatGNU, FormatBSD} {
+		t.Run(format, func(t *testing.T) {
+			setPath, paths := writeFileSet(t, t.TempDir(), format)
+
+			set, err := LoadFileSet(setPath)
+			if err != nil {
+				t.Fatalf("LoadFileSet failed: %v", err)
+			}
+			if len(set.Files) != len(paths) {
+				t.Fatalf("expected %d files, got %d", len(paths), len(set.Files))
+			}
+			for i, entry := range set.Files {
+				if got := entry.ResolvePath(setPath); got != paths[i] {
+					t.Errorf("expected path %s, got %s", paths[i], got)
+				}
+
+				info, err := os.Stat(paths[i])
+				if err != nil {
+					t.Fatal(err)
+				}
+				chunks, err := entry.ChunkChecksums(info.Size(), set.Algorithm)
+				if err != nil {
+					t.Fatalf("ChunkChecksums failed: %v", err)
+				}
+				wantChunks := 2
+				if format != FormatTrasher {
+					wantChunks = 1
+				}
+				if len(chunks) != wantChunks {
+					t.Errorf("expected %d chunks, got %d", wantChunks, len(chunks))
+				}
+
+				actual := append([]ChunkInfo(nil), chunks...)
+				if err := hashChunks(paths[i], actual); err != nil {
+					t.Fatal(err)
+				}
+				for j, chunk := range chunks {
+					if actual[j].Checksum != chunk.Checksum {
+						t.Errorf("%s chunk at %d: expected %s, got %s", entry.Path, chunk.Offset, chunk.Checksum, actual[j].Checksum)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFileSetWithSha256sum(t *testing.T) {
+	sha256sum, err := exec.LookPath("sha256sum")
+	if err != nil {
+		t.Skip("sha256sum not available")
+	}
+
+	for _, format := range []string{FormatGNU, FormatBSD} {
+		t.Run(format, func(t *testing.T) {
+			setPath, _ := writeFileSet(t, t.TempDir(), format)
+
+			cmd := exec.Command(sha256sum, "--check", "--strict", filepath.Base(setPath))
+			cmd.Dir = filepath.Dir(setPath)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("sha256sum --check failed: %v\n%s", err, output)
+			}
+		})
+	}
+}
+
+func TestLoadFileSetRejectsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"data.bin":     "\x00\x01\x02 not a checksum file",
+		"single.json":  `{"file": "x", "chunks": []}`,
+		"checksum.txt": "SHA256 (full file): " + strings.Repeat("a", 64) + "\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFileSet(path); err != ErrNotFileSet {
+			t.Errorf("%s: expected ErrNotFileSet, got %v", name, err)
+		}
+	}
+}
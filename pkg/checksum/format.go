@@ -0,0 +1,87 @@
+package checksum
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Checksum file formats. All of them keep the chunk checksums verify and
+// repair read; the GNU and BSD formats write the full-file checksum as
+// sha256sum does and the rest as comments, so `sha256sum -c` checks the file
+// directly.
+const (
+	FormatTrasher = "trasher"
+	FormatGNU     = "gnu"
+	FormatBSD     = "bsd"
+)
+
+// SetFormat selects the checksum file format, FormatTrasher, FormatGNU or
+// FormatBSD. It must be called before WriteChecksumFile.
+func (c *ChecksumGenerator) SetFormat(format string) error {
+	switch format {
+	case FormatTrasher, FormatGNU, FormatBSD:
+	default:
+		return fmt.Errorf("unknown checksum format %q", format)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.format = format
+	return nil
+}
+
+// fullChecksumLine formats the full-file checksum line of the checksum file.
+// sha256sum looks the file up by the name given, relative to the checksum
+// file's directory when run there, so only the base name is written.
+func (c *ChecksumGenerator) fullChecksumLine(checksum string) (string, error) {
+	standard := c.format == FormatGNU || c.format == FormatBSD
+	if standard && c.tree {
+		return "", fmt.Errorf("%s checksums can't be written in %s format", c.algorithm, c.format)
+	}
+
+	name := filepath.Base(c.outputPath)
+	switch c.format {
+	case FormatGNU:
+		return fmt.Sprintf("%s  %s", checksum, name), nil
+	case FormatBSD:
+		return fmt.Sprintf("%s (%s) = %s", c.algorithm, name, checksum), nil
+	default:
+		return fmt.Sprintf("%s (full file): %s", c.algorithm, checksum), nil
+	}
+}
+
+// chunkLinePrefix returns what chunk checksum lines start with: nothing, or
+// a comment marker in the standard formats.
+func (c *ChecksumGenerator) chunkLinePrefix() string {
+	if c.format == FormatGNU || c.format == FormatBSD {
+		return "# "
+	}
+	return ""
+}
+
+// parseStandardLine parses a checksum line in GNU or BSD format and returns
+// its algorithm, checksum and file name.
+func parseStandardLine(line string) (algorithm, checksum, name string, ok bool) {
+	// BSD: "SHA256 (name) = <hex>"
+	if rest, ok := strings.CutPrefix(line, chunkAlgorithm+" ("); ok {
+		if i := strings.LastIndex(rest, ") = "); i >= 0 && isDigest(rest[i+4:]) {
+			return chunkAlgorithm, rest[i+4:], rest[:i], true
+		}
+		return "", "", "", false
+	}
+
+	// GNU: "<hex>  name", or "<hex> *name" for binary mode
+	digest, rest, found := strings.Cut(line, " ")
+	if !found || !isDigest(digest) || !(strings.HasPrefix(rest, " ") || strings.HasPrefix(rest, "*")) || len(rest) < 2 {
+		return "", "", "", false
+	}
+	return chunkAlgorithm, digest, rest[1:], true
+}
+
+// isDigest reports whether s is a hex SHA-256 digest.
+func isDigest(s string) bool {
+	sum, err := hex.DecodeString(s)
+	return err == nil && len(sum) == 32
+}
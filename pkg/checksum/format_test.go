@@ -0,0 +1,135 @@
+package checksum
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFormatted writes a file and its checksum file in format.
+func writeFormatted(t *testing.T, format string) string {
+	t.Helper()
+	testFile := filepath.Join(t.TempDir(), "test.bin")
+	data := []byte(strings.Repeat("checksum format test data ", 100))
+	if err := os.WriteFile(testFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	generator := NewChecksumGenerator(testFile, int64(len(data)))
+	if err := generator.SetFormat(format); err != nil {
+		t.Fatal(err)
+	}
+	generator.UpdateWithChunk(data[:1024], 0)
+	generator.UpdateWithChunk(data[1024:], 1024)
+	if err := generator.WriteChecksumFile(); err != nil {
+		t.Fatalf("failed to write checksum file: %v", err)
+	}
+	return testFile
+}
+
+func TestChecksumFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		line   func(digest string) string
+	}{
+		{FormatTrasher, func(digest string) string { return "SHA256 (full file): " + digest }},
+		{FormatGNU, func(digest string) string { return digest + "  test.bin" }},
+		{FormatBSD, func(digest string) string { return "SHA256 (test.bin) = " + digest }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			testFile := writeFormatted(t, tt.format)
+
+			result, err := VerifyFile(testFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Valid {
+				t.Fatalf("verification should be valid, got: %s", result.Error)
+			}
+
+			content, err := os.ReadFile(testFile + ".checksum.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(content), tt.line(result.ExpectedChecksum)+"\n") {
+				t.Errorf("expected full-file line %q in:\n%s", tt.line(result.ExpectedChecksum), content)
+			}
+
+			// Chunk checksums stay readable in every format
+			info, err := os.Stat(testFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunks, err := LoadChunkChecksums(testFile+".checksum.txt", info.Size())
+			if err != nil || len(chunks) != 2 {
+				t.Errorf("expected 2 chunk checksums, got %d (%v)", len(chunks), err)
+			}
+		})
+	}
+}
+
+func TestChecksumFormatsWithSha256sum(t *testing.T) {
+	sha256sum, err := exec.LookPath("sha256sum")
+	if err != nil {
+		t.Skip("sha256sum not available")
+	}
+
+	for _, format := range []string{FormatGNU, FormatBSD} {
+		t.Run(format, func(t *testing.T) {
+			testFile := writeFormatted(t, format)
+
+			cmd := exec.Command(sha256sum, "--check", "--strict", filepath.Base(testFile)+".checksum.txt")
+			cmd.Dir = filepath.Dir(testFile)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("sha256sum --check failed: %v\n%s", err, output)
+			}
+		})
+	}
+}
+
+func TestChecksumFormatErrors(t *testing.T) {
+	generator := NewChecksumGenerator(filepath.Join(t.TempDir(), "test.bin"), 1024)
+	if err := generator.SetFormat("sfv"); err == nil || !strings.Contains(err.Error(), "unknown checksum format") {
+		t.Errorf("expected unknown format error, got %v", err)
+	}
+
+	// A tree checksum isn't what sha256sum computes
+	if err := generator.SetFormat(FormatGNU); err != nil {
+		t.Fatal(err)
+	}
+	if err := generator.SetMode(ModeTree); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := generator.fullChecksumLine("00"); err == nil || !strings.Contains(err.Error(), "can't be written in gnu format") {
+		t.Errorf("expected tree format error, got %v", err)
+	}
+}
+
+func TestParseStandardLine(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+
+	for line, name := range map[string]string{
+		digest + "  test.bin":               "test.bin",
+		digest + " *test.bin":               "test.bin",
+		"SHA256 (test.bin) = " + digest:     "test.bin",
+		"SHA256 (name (1).bin) = " + digest: "name (1).bin",
+		digest + "  dir/with spaces.bin":    "dir/with spaces.bin",
+	} {
+		if _, got, gotName, ok := parseStandardLine(line); !ok || got != digest || gotName != name {
+			t.Errorf("failed to parse %q: got %s %q", line, got, gotName)
+		}
+	}
+	for _, line := range []string{
+		"SHA256 (offset 0): " + digest,
+		digest,
+		"abc  test.bin",
+	} {
+		if _, _, _, ok := parseStandardLine(line); ok {
+			t.Errorf("unexpectedly parsed %q", line)
+		}
+	}
+}
@@ -0,0 +1,579 @@
+package checksum
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ChecksumGenerator calculates and manages checksums for generated data.
+type ChecksumGenerator struct {
+	hasher       hash.Hash
+	chunkHashers map[int64]hash.Hash
+	chunkSums    map[int64][]byte
+	outputPath   string
+	mu           sync.Mutex
+	totalSize    int64
+	algorithm    string
+	tree         bool
+	format       string
+	fullChecksum string
+	ordered      *OrderedHasher
+	hashBuffer   int64
+}
+
+// ChunkInfo holds information about a chunk's checksum.
+type ChunkInfo struct {
+	Offset   int64
+	Size     int64
+	Checksum string
+}
+
+// NewChecksumGenerator creates a new checksum generator for the given output path.
+func NewChecksumGenerator(outputPath string, totalSize int64) *ChecksumGenerator {
+	return &ChecksumGenerator{
+		hasher:       sha256.New(),
+		chunkHashers: make(map[int64]hash.Hash),
+		chunkSums:    make(map[int64][]byte),
+		outputPath:   outputPath,
+		totalSize:    totalSize,
+		algorithm:    "SHA256",
+		format:       FormatTrasher,
+	}
+}
+
+// SetMode selects how the full-file checksum is computed, ModeStream,
+// ModeOrdered or ModeTree. It must be called before WriteChecksumFile.
+func (c *ChecksumGenerator) SetMode(mode string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ordered = nil
+	switch mode {
+	case ModeStream:
+		c.tree, c.algorithm = false, chunkAlgorithm
+	case ModeOrdered:
+		c.tree, c.algorithm = false, chunkAlgorithm
+		c.ordered = NewOrderedHasher(c.hashBuffer)
+	case ModeTree:
+		c.tree, c.algorithm = true, treeAlgorithm
+	default:
+		return fmt.Errorf("unknown checksum mode %q", mode)
+	}
+	return nil
+}
+
+// SetHashBuffer sets how much out-of-order data ModeOrdered holds while
+// waiting for earlier chunks; 0 uses DefaultHashBuffer. It must be called
+// before the first chunk is passed to UpdateOrdered.
+func (c *ChecksumGenerator) SetHashBuffer(limit int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hashBuffer = limit
+	if c.ordered != nil {
+		c.ordered = NewOrderedHasher(limit)
+	}
+}
+
+// UpdateOrdered feeds the chunk data at offset to the full-file checksum in
+// ModeOrdered, and does nothing in other modes. Chunks may arrive in any
+// order; if too many arrive ahead of the ones before them, the ordered
+// checksum is abandoned and Finalize reads the file back instead.
+func (c *ChecksumGenerator) UpdateOrdered(data []byte, offset int64) error {
+	if c.ordered == nil {
+		return nil
+	}
+	if err := c.ordered.Write(data, offset); err != nil && !errors.Is(err, ErrHashBufferFull) {
+		return err
+	}
+	return nil
+}
+
+// OrderedHasher returns the hasher computing the full-file checksum in
+// ModeOrdered, for reporting on its buffer, or nil in other modes.
+func (c *ChecksumGenerator) OrderedHasher() *OrderedHasher {
+	return c.ordered
+}
+
+// orderedChecksum returns the full-file checksum computed in ModeOrdered, if
+// it covers exactly the file as written.
+func (c *ChecksumGenerator) orderedChecksum() (string, bool) {
+	if c.ordered == nil {
+		return "", false
+	}
+	sum, err := c.ordered.Sum()
+	if err != nil {
+		return "", false
+	}
+	info, err := os.Stat(c.outputPath)
+	if err != nil || !info.Mode().IsRegular() || info.Size() != c.ordered.Hashed() {
+		return "", false
+	}
+	return hex.EncodeToString(sum), true
+}
+
+// UpdateWithChunk updates the checksum with a data chunk at the specified offset.
+// This method is thread-safe and can be called concurrently from multiple goroutines.
+// Calling it again with the same offset appends to that chunk, so chunks can
+// be hashed in parts as long as each chunk's parts arrive in order.
+func (c *ChecksumGenerator) UpdateWithChunk(data []byte, offset int64) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Validate offset
+	if offset < 0 || offset >= c.totalSize {
+		return fmt.Errorf("invalid offset %d for file size %d", offset, c.totalSize)
+	}
+
+	// Update the main hasher (we need to handle ordering for the main hash)
+	// For now, we'll track chunks and compute the final hash when writing
+	
+	// Create or get chunk hasher for this offset
+	if _, exists := c.chunkHashers[offset]; !exists {
+		c.chunkHashers[offset] = sha256.New()
+	}
+	
+	// Write data to the chunk hasher
+	if _, err := c.chunkHashers[offset].Write(data); err != nil {
+		return fmt.Errorf("failed to update chunk checksum: %v", err)
+	}
+
+	return nil
+}
+
+// NewChunkHash returns a hash of the algorithm chunk checksums use, for
+// callers that hash chunks themselves and record them with AddChunkChecksum.
+func (c *ChecksumGenerator) NewChunkHash() hash.Hash {
+	return sha256.New()
+}
+
+// AddChunkChecksum records the checksum of the chunk at the specified offset,
+// computed elsewhere with a NewChunkHash hash. It lets chunks be hashed in
+// parallel instead of under the generator's lock.
+func (c *ChecksumGenerator) AddChunkChecksum(offset int64, sum []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if offset < 0 || offset >= c.totalSize {
+		return fmt.Errorf("invalid offset %d for file size %d", offset, c.totalSize)
+	}
+	if len(sum) != sha256.Size {
+		return fmt.Errorf("invalid %s checksum length %d for offset %d", chunkAlgorithm, len(sum), offset)
+	}
+
+	c.chunkSums[offset] = sum
+	return nil
+}
+
+// UpdateWithZeroChunks records the chunk checksums of a file that is all
+// zeros, as if every chunkSize chunk had been passed to UpdateWithChunk. Only
+// one chunk's worth of zeros is hashed, so files left sparse get checksums
+// without generating their data.
+func (c *ChecksumGenerator) UpdateWithZeroChunks(chunkSize int64) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	states := make(map[int64][]byte)
+	for offset := int64(0); offset < c.totalSize; offset += chunkSize {
+		size := min(chunkSize, c.totalSize-offset)
+		if _, ok := states[size]; !ok {
+			state, err := zeroHashState(size)
+			if err != nil {
+				return err
+			}
+			states[size] = state
+		}
+
+		hasher := sha256.New()
+		if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(states[size]); err != nil {
+			return fmt.Errorf("failed to restore chunk checksum: %v", err)
+		}
+		c.chunkHashers[offset] = hasher
+	}
+
+	return nil
+}
+
+// zeroHashState hashes size zero bytes and returns the hasher's state.
+func zeroHashState(size int64) ([]byte, error) {
+	hasher := sha256.New()
+	zeros := make([]byte, min(size, 1024*1024))
+	for remaining := size; remaining > 0; remaining -= int64(len(zeros)) {
+		if remaining < int64(len(zeros)) {
+			zeros = zeros[:remaining]
+		}
+		hasher.Write(zeros)
+	}
+
+	state, err := hasher.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save chunk checksum: %v", err)
+	}
+	return state, nil
+}
+
+// GetChunkChecksums returns all chunk checksums sorted by offset.
+func (c *ChecksumGenerator) GetChunkChecksums() []ChunkInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var chunks []ChunkInfo
+	for offset, hasher := range c.chunkHashers {
+		checksum := hex.EncodeToString(hasher.Sum(nil))
+		chunks = append(chunks, ChunkInfo{
+			Offset:   offset,
+			Checksum: checksum,
+		})
+	}
+	for offset, sum := range c.chunkSums {
+		chunks = append(chunks, ChunkInfo{
+			Offset:   offset,
+			Checksum: hex.EncodeToString(sum),
+		})
+	}
+
+	// Sort by offset
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Offset < chunks[j].Offset
+	})
+
+	return chunks
+}
+
+// ComputeFileChecksum computes the checksum of the entire file by reading it.
+func (c *ChecksumGenerator) ComputeFileChecksum() (string, error) {
+	file, err := os.Open(c.outputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for checksum: %v", err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("failed to read file for checksum: %v", err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Finalize computes the full-file checksum once every chunk is written and
+// recorded, in tree mode from the chunk checksums, in ordered mode from the
+// chunks hashed as they were written, and returns it.
+// WriteChecksumFile calls it; callers that don't write a checksum file call
+// it themselves.
+func (c *ChecksumGenerator) Finalize() (string, error) {
+	var fullChecksum string
+	var err error
+	if c.tree {
+		fullChecksum, err = treeChecksum(c.GetChunkChecksums())
+	} else if sum, ok := c.orderedChecksum(); ok {
+		fullChecksum = sum
+	} else {
+		fullChecksum, err = c.ComputeFileChecksum()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.fullChecksum = fullChecksum
+	c.mu.Unlock()
+	return fullChecksum, nil
+}
+
+// WriteChecksumFile writes the checksum information to a .checksum.txt file.
+func (c *ChecksumGenerator) WriteChecksumFile() error {
+	fullChecksum, err := c.Finalize()
+	if err != nil {
+		return err
+	}
+	fullLine, err := c.fullChecksumLine(fullChecksum)
+	if err != nil {
+		return err
+	}
+
+	checksumPath := c.outputPath + ".checksum.txt"
+	file, err := os.Create(checksumPath)
+	if err != nil {
+		return fmt.Errorf("failed to create checksum file: %v", err)
+	}
+	defer file.Close()
+
+	// Write header
+	if _, err := fmt.Fprintf(file, "# Checksum file for %s\n", c.outputPath); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(file, "# Generated by trasher\n\n"); err != nil {
+		return err
+	}
+
+	// Write the main file checksum
+	if _, err := fmt.Fprintln(file, fullLine); err != nil {
+		return err
+	}
+
+	// Write chunk checksums in order of offset
+	chunks := c.GetChunkChecksums()
+	if len(chunks) > 0 {
+		if _, err := fmt.Fprintf(file, "\n# Chunk checksums:\n"); err != nil {
+			return err
+		}
+		
+		for _, chunk := range chunks {
+			if _, err := fmt.Fprintf(file, "%s%s (offset %d): %s\n", 
+				c.chunkLinePrefix(), chunkAlgorithm, chunk.Offset, chunk.Checksum); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Verify validates a file against its checksum file.
+func (c *ChecksumGenerator) Verify(filePath string) (*VerificationResult, error) {
+	result := &VerificationResult{
+		FilePath:     filePath,
+		ChecksumPath: filePath + ".checksum.txt",
+	}
+
+	// Check if file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		result.Valid = false
+		result.Error = fmt.Sprintf("file does not exist: %s", filePath)
+		return result, nil
+	}
+
+	// Check if checksum file exists
+	if _, err := os.Stat(result.ChecksumPath); os.IsNotExist(err) {
+		result.Valid = false
+		result.Error = fmt.Sprintf("checksum file does not exist: %s", result.ChecksumPath)
+		return result, nil
+	}
+
+	// Parse the checksum file
+	algorithm, expectedChecksum, err := c.parseChecksumFile(result.ChecksumPath)
+	if err != nil {
+		result.Valid = false
+		result.Error = fmt.Sprintf("failed to parse checksum file: %v", err)
+		return result, nil
+	}
+
+	// A tree checksum is recomputed from the file's chunks in parallel
+	if algorithm == treeAlgorithm {
+		actualChecksum, err := computeTreeChecksum(filePath, result.ChecksumPath)
+		if err != nil {
+			result.Valid = false
+			result.Error = fmt.Sprintf("failed to read file for verification: %v", err)
+			return result, nil
+		}
+		return compareChecksums(result, expectedChecksum, actualChecksum), nil
+	}
+
+	// Calculate the actual file checksum
+	file, err := os.Open(filePath)
+	if err != nil {
+		result.Valid = false
+		result.Error = fmt.Sprintf("failed to open file for verification: %v", err)
+		return result, nil
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		result.Valid = false
+		result.Error = fmt.Sprintf("failed to read file for verification: %v", err)
+		return result, nil
+	}
+
+	actualChecksum := hex.EncodeToString(hasher.Sum(nil))
+
+	return compareChecksums(result, expectedChecksum, actualChecksum), nil
+}
+
+// compareChecksums records the expected and actual checksums in result and
+// whether they match.
+func compareChecksums(result *VerificationResult, expectedChecksum, actualChecksum string) *VerificationResult {
+	result.Valid = (actualChecksum == expectedChecksum)
+	result.ExpectedChecksum = expectedChecksum
+	result.ActualChecksum = actualChecksum
+
+	if !result.Valid {
+		result.Error = "checksum mismatch"
+	}
+
+	return result
+}
+
+// parseChecksumFile parses a checksum file and returns the algorithm and
+// value of the full file checksum.
+func (c *ChecksumGenerator) parseChecksumFile(checksumPath string) (string, string, error) {
+	file, err := os.Open(checksumPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		
+		// Skip comments and empty lines
+		if strings.HasPrefix(line, "#") || line == "" {
+			continue
+		}
+
+		// Look for the full file checksum line
+		if strings.Contains(line, "(full file)") {
+			parts := strings.SplitN(line, ": ", 2)
+			if len(parts) == 2 {
+				algorithm := strings.TrimSpace(strings.SplitN(parts[0], "(full file)", 2)[0])
+				return algorithm, strings.TrimSpace(parts[1]), nil
+			}
+		}
+
+		// Or for a sha256sum line in GNU or BSD format
+		if algorithm, checksum, _, ok := parseStandardLine(line); ok {
+			return algorithm, checksum, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+
+	return "", "", fmt.Errorf("no full file checksum found in checksum file")
+}
+
+// LoadChunkChecksums reads the per-chunk checksums recorded in a checksum file.
+// Chunk sizes are derived from the distance to the next chunk, with the last
+// chunk running to fileSize.
+func LoadChunkChecksums(checksumPath string, fileSize int64) ([]ChunkInfo, error) {
+	file, err := os.Open(checksumPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var chunks []ChunkInfo
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.Contains(line, "(offset ") {
+			continue
+		}
+
+		// Format: "SHA256 (offset 1024): <hex>"
+		parts := strings.SplitN(line, ": ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed chunk checksum line: %s", line)
+		}
+		start := strings.Index(parts[0], "(offset ") + len("(offset ")
+		end := strings.Index(parts[0], ")")
+		if end < start {
+			return nil, fmt.Errorf("malformed chunk checksum line: %s", line)
+		}
+		offset, err := strconv.ParseInt(parts[0][start:end], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk offset in line: %s", line)
+		}
+
+		chunks = append(chunks, ChunkInfo{
+			Offset:   offset,
+			Checksum: strings.TrimSpace(parts[1]),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no chunk checksums found in %s", checksumPath)
+	}
+
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Offset < chunks[j].Offset
+	})
+
+	for i := range chunks {
+		next := fileSize
+		if i+1 < len(chunks) {
+			next = chunks[i+1].Offset
+		}
+		chunks[i].Size = next - chunks[i].Offset
+		if chunks[i].Size <= 0 {
+			return nil, fmt.Errorf("chunk at offset %d lies outside file of size %d", chunks[i].Offset, fileSize)
+		}
+	}
+
+	return chunks, nil
+}
+
+// VerificationResult holds the result of a file verification operation.
+type VerificationResult struct {
+	FilePath         string
+	ChecksumPath     string
+	Valid            bool
+	ExpectedChecksum string
+	ActualChecksum   string
+	Error            string
+}
+
+// String returns a human-readable string representation of the verification result.
+func (r *VerificationResult) String() string {
+	if r.Valid {
+		return fmt.Sprintf("✓ %s: checksum verified", r.FilePath)
+	} else {
+		return fmt.Sprintf("✗ %s: %s", r.FilePath, r.Error)
+	}
+}
+
+// VerifyFile is a convenience function to verify a file using a new ChecksumGenerator.
+func VerifyFile(filePath string) (*VerificationResult, error) {
+	generator := &ChecksumGenerator{
+		algorithm: "SHA256",
+	}
+	return generator.Verify(filePath)
+}
+
+// FullChecksum returns the whole-file checksum computed by Finalize or
+// WriteChecksumFile, or an empty string if it has not been computed yet.
+func (c *ChecksumGenerator) FullChecksum() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fullChecksum
+}
+
+// GetAlgorithm returns the hash algorithm being used.
+func (c *ChecksumGenerator) GetAlgorithm() string {
+	return c.algorithm
+}
+
+// Reset clears all stored checksums and resets the generator.
+func (c *ChecksumGenerator) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hasher.Reset()
+	c.chunkHashers = make(map[int64]hash.Hash)
+	c.chunkSums = make(map[int64][]byte)
+}
\ No newline at end of file
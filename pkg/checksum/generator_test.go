@@ -1,6 +1,8 @@
 package checksum
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -71,6 +73,37 @@ func TestUpdateWithChunk(t *testing.T) {
 	}
 }
 
+func TestUpdateWithZeroChunks(t *testing.T) {
+	const totalSize, chunkSize = 10000, 4096
+
+	expected := NewChecksumGenerator("test.dat", totalSize)
+	for offset := int64(0); offset < totalSize; offset += chunkSize {
+		size := min(chunkSize, totalSize-offset)
+		if err := expected.UpdateWithChunk(make([]byte, size), offset); err != nil {
+			t.Fatalf("UpdateWithChunk failed: %v", err)
+		}
+	}
+
+	zeros := NewChecksumGenerator("test.dat", totalSize)
+	if err := zeros.UpdateWithZeroChunks(chunkSize); err != nil {
+		t.Fatalf("UpdateWithZeroChunks failed: %v", err)
+	}
+
+	want, got := expected.GetChunkChecksums(), zeros.GetChunkChecksums()
+	if len(got) != 3 || len(got) != len(want) {
+		t.Fatalf("expected %d chunks, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("chunk %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	if err := zeros.UpdateWithZeroChunks(0); err == nil {
+		t.Error("expected error for a zero chunk size")
+	}
+}
+
 func TestGetChunkChecksums(t *testing.T) {
 	tempDir := t.TempDir()
 	testFile := filepath.Join(tempDir, "test.bin")
@@ -406,7 +439,101 @@ func TestGetAlgorithm(t *testing.T) {
 	}
 }
 
+func TestAddChunkChecksum(t *testing.T) {
+	data := make([]byte, 300)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	expected := NewChecksumGenerator("test.bin", 300)
+	expected.UpdateWithChunk(data[:200], 0)
+	expected.UpdateWithChunk(data[200:], 200)
+
+	// Chunks hashed outside the generator, in any order, give the same result
+	generator := NewChecksumGenerator("test.bin", 300)
+	for _, offset := range []int64{200, 0} {
+		end := min(offset+200, 300)
+		hasher := generator.NewChunkHash()
+		hasher.Write(data[offset:end])
+		if err := generator.AddChunkChecksum(offset, hasher.Sum(nil)); err != nil {
+			t.Fatalf("AddChunkChecksum failed: %v", err)
+		}
+	}
+
+	got, want := generator.GetChunkChecksums(), expected.GetChunkChecksums()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d chunks, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("chunk %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	if err := generator.AddChunkChecksum(300, make([]byte, 32)); err == nil {
+		t.Error("expected error for offset past the end")
+	}
+	if err := generator.AddChunkChecksum(0, []byte{1, 2, 3}); err == nil {
+		t.Error("expected error for checksum of the wrong length")
+	}
+}
+
 // Helper function to check if a string contains a substring
+func TestLoadChunkChecksums(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.bin")
+
+	data := make([]byte, 2500)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if err := os.WriteFile(testFile, data, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	generator := NewChecksumGenerator(testFile, int64(len(data)))
+	generator.UpdateWithChunk(data[2048:], 2048)
+	generator.UpdateWithChunk(data[:1024], 0)
+	generator.UpdateWithChunk(data[1024:2048], 1024)
+	if err := generator.WriteChecksumFile(); err != nil {
+		t.Fatalf("failed to write checksum file: %v", err)
+	}
+
+	if len(generator.FullChecksum()) != 64 {
+		t.Errorf("expected full checksum to be recorded, got %q", generator.FullChecksum())
+	}
+
+	chunks, err := LoadChunkChecksums(testFile+".checksum.txt", int64(len(data)))
+	if err != nil {
+		t.Fatalf("LoadChunkChecksums failed: %v", err)
+	}
+
+	expected := generator.GetChunkChecksums()
+	if len(chunks) != len(expected) {
+		t.Fatalf("expected %d chunks, got %d", len(expected), len(chunks))
+	}
+
+	expectedSizes := []int64{1024, 1024, 452}
+	for i, chunk := range chunks {
+		if chunk.Offset != expected[i].Offset || chunk.Checksum != expected[i].Checksum {
+			t.Errorf("chunk %d: expected %+v, got %+v", i, expected[i], chunk)
+		}
+		if chunk.Size != expectedSizes[i] {
+			t.Errorf("chunk %d: expected size %d, got %d", i, expectedSizes[i], chunk.Size)
+		}
+	}
+
+	// A file size smaller than the last offset is inconsistent
+	if _, err := LoadChunkChecksums(testFile+".checksum.txt", 2000); err == nil {
+		t.Error("expected error when file size is smaller than last chunk offset")
+	}
+
+	// Missing checksum file
+	if _, err := LoadChunkChecksums(filepath.Join(tempDir, "missing.txt"), 100); err == nil {
+		t.Error("expected error for missing checksum file")
+	}
+}
+
 func containsString(s, substr string) bool {
 	return len(s) >= len(substr) && 
 		   (s == substr || len(s) > len(substr) && 
@@ -457,4 +584,27 @@ func BenchmarkGetChunkChecksums(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		generator.GetChunkChecksums()
 	}
-}
\ No newline at end of file
+}
+func TestFinalize(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.bin")
+	testData := []byte("data finalized without a checksum file")
+	if err := os.WriteFile(testFile, testData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	generator := NewChecksumGenerator(testFile, int64(len(testData)))
+	generator.UpdateWithChunk(testData, 0)
+	sum, err := generator.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	if want := fmt.Sprintf("%x", sha256.Sum256(testData)); sum != want {
+		t.Errorf("expected checksum %s, got %s", want, sum)
+	}
+	if generator.FullChecksum() != sum {
+		t.Errorf("expected FullChecksum %s, got %s", sum, generator.FullChecksum())
+	}
+	if _, err := os.Stat(testFile + ".checksum.txt"); !os.IsNotExist(err) {
+		t.Error("Finalize should not write a checksum file")
+	}
+}
@@ -0,0 +1,186 @@
+package checksum
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ManifestSuffix is appended to a file's path to name its manifest.
+const ManifestSuffix = ".manifest.json"
+
+// Manifest lists the offset, length and checksum of every chunk of a file,
+// so damaged regions of even very large files can be found and regenerated
+// without reading the rest.
+type Manifest struct {
+	File      string          `json:"file"`
+	Size      int64           `json:"size"`
+	Algorithm string          `json:"algorithm"`
+	Chunks    []ManifestChunk `json:"chunks"`
+	// Incomplete marks the journal of a run that stopped before finishing
+	// the file: Size is the size the file was to have, Chunks lists only the
+	// chunks written, which may leave gaps, and Flags holds the settings
+	// the run generated its data with, so it can be resumed.
+	Incomplete bool              `json:"incomplete,omitempty"`
+	Flags      map[string]string `json:"flags,omitempty"`
+}
+
+// ManifestChunk is one chunk of a manifest.
+type ManifestChunk struct {
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+	Digest string `json:"digest"`
+}
+
+// WriteManifest writes the chunk checksums to the file's manifest. size is
+// the length of the data the chunks cover, which is less than the generator's
+// size if the run stopped early.
+func (c *ChecksumGenerator) WriteManifest(size int64) error {
+	manifest := Manifest{
+		File:      filepath.Base(c.outputPath),
+		Size:      size,
+		Algorithm: chunkAlgorithm,
+		Chunks:    c.manifestChunks(size),
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %v", err)
+	}
+	if err := os.WriteFile(c.outputPath+ManifestSuffix, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	return nil
+}
+
+// WriteJournal writes the manifest of a run that stopped before finishing
+// outputPath, marked incomplete. size is the size the file was to have,
+// chunks are the chunks written so far, in any order, and flags are the
+// settings the run generated its data with.
+func WriteJournal(outputPath string, size int64, chunks []ChunkInfo, flags map[string]string) error {
+	sorted := make([]ChunkInfo, len(chunks))
+	copy(sorted, chunks)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Offset < sorted[j].Offset
+	})
+
+	journal := Manifest{
+		File:       filepath.Base(outputPath),
+		Size:       size,
+		Algorithm:  chunkAlgorithm,
+		Chunks:     make([]ManifestChunk, len(sorted)),
+		Incomplete: true,
+		Flags:      flags,
+	}
+	for i, chunk := range sorted {
+		journal.Chunks[i] = ManifestChunk{Offset: chunk.Offset, Length: chunk.Size, Digest: chunk.Checksum}
+	}
+
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode journal: %v", err)
+	}
+	if err := os.WriteFile(outputPath+ManifestSuffix, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write journal: %v", err)
+	}
+	return nil
+}
+
+// LoadJournal reads the journal of an interrupted run written by
+// WriteJournal and returns it with the chunks it lists, which must not
+// overlap or extend past the file's size.
+func LoadJournal(manifestPath string) (*Manifest, []ChunkInfo, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var journal Manifest
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest %s: %v", manifestPath, err)
+	}
+	if !journal.Incomplete {
+		return nil, nil, fmt.Errorf("%s describes a complete file, there is nothing to resume", manifestPath)
+	}
+	if journal.Algorithm != chunkAlgorithm {
+		return nil, nil, fmt.Errorf("unsupported manifest algorithm %q", journal.Algorithm)
+	}
+	if journal.Size <= 0 {
+		return nil, nil, fmt.Errorf("invalid size %d in %s", journal.Size, manifestPath)
+	}
+
+	chunks := make([]ChunkInfo, len(journal.Chunks))
+	var end int64
+	for i, chunk := range journal.Chunks {
+		if chunk.Offset < end || chunk.Length <= 0 || chunk.Offset+chunk.Length > journal.Size {
+			return nil, nil, fmt.Errorf("journal chunk at offset %d overlaps another or lies outside the file", chunk.Offset)
+		}
+		end = chunk.Offset + chunk.Length
+		chunks[i] = ChunkInfo{Offset: chunk.Offset, Size: chunk.Length, Checksum: chunk.Digest}
+	}
+	return &journal, chunks, nil
+}
+
+// manifestChunks returns the recorded chunks, the last ending at size.
+func (c *ChecksumGenerator) manifestChunks(size int64) []ManifestChunk {
+	chunks := c.GetChunkChecksums()
+	manifestChunks := make([]ManifestChunk, len(chunks))
+	for i, chunk := range chunks {
+		end := size
+		if i+1 < len(chunks) {
+			end = chunks[i+1].Offset
+		}
+		manifestChunks[i] = ManifestChunk{
+			Offset: chunk.Offset,
+			Length: end - chunk.Offset,
+			Digest: chunk.Checksum,
+		}
+	}
+	return manifestChunks
+}
+
+// LoadManifest reads the chunks listed in a manifest. The chunks must cover
+// a file of fileSize bytes exactly, one after another.
+func LoadManifest(manifestPath string, fileSize int64) ([]ChunkInfo, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %v", manifestPath, err)
+	}
+	return manifestChunkInfo(manifest, manifestPath, fileSize)
+}
+
+// manifestChunkInfo checks that the chunks of manifest, read from source,
+// cover a file of fileSize bytes and returns them.
+func manifestChunkInfo(manifest Manifest, source string, fileSize int64) ([]ChunkInfo, error) {
+	if manifest.Incomplete {
+		return nil, fmt.Errorf("%s is the journal of an interrupted run; complete the file with trasher resume", source)
+	}
+	if manifest.Algorithm != chunkAlgorithm {
+		return nil, fmt.Errorf("unsupported manifest algorithm %q", manifest.Algorithm)
+	}
+	if manifest.Size != fileSize {
+		return nil, fmt.Errorf("manifest describes %d bytes but the file has %d", manifest.Size, fileSize)
+	}
+
+	chunks := make([]ChunkInfo, len(manifest.Chunks))
+	var end int64
+	for i, chunk := range manifest.Chunks {
+		if chunk.Offset != end || chunk.Length <= 0 {
+			return nil, fmt.Errorf("manifest chunk at offset %d does not follow the previous chunk", chunk.Offset)
+		}
+		end += chunk.Length
+		chunks[i] = ChunkInfo{Offset: chunk.Offset, Size: chunk.Length, Checksum: chunk.Digest}
+	}
+	if end != fileSize {
+		return nil, fmt.Errorf("manifest chunks cover %d bytes but the file has %d", end, fileSize)
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no chunk checksums found in %s", source)
+	}
+	return chunks, nil
+}
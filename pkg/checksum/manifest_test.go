@@ -0,0 +1,132 @@
+package checksum
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteAndLoadManifest(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.bin")
+	data := make([]byte, 2500)
+
+	generator := NewChecksumGenerator(testFile, 4096)
+	for offset := 0; offset < len(data); offset += 1024 {
+		generator.UpdateWithChunk(data[offset:min(offset+1024, len(data))], int64(offset))
+	}
+	// The run stopped after 2500 of 4096 bytes
+	if err := generator.WriteManifest(2500); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	content, err := os.ReadFile(testFile + ManifestSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		t.Fatalf("manifest is not valid JSON: %v", err)
+	}
+	if manifest.File != "test.bin" || manifest.Size != 2500 || manifest.Algorithm != "SHA256" {
+		t.Errorf("unexpected manifest header: %+v", manifest)
+	}
+
+	chunks, err := LoadManifest(testFile+ManifestSuffix, 2500)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+	want := generator.GetChunkChecksums()
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d", len(chunks))
+	}
+	for i, size := range []int64{1024, 1024, 452} {
+		if chunks[i].Offset != int64(i*1024) || chunks[i].Size != size || chunks[i].Checksum != want[i].Checksum {
+			t.Errorf("chunk %d: got %+v, want offset %d size %d checksum %s", i, chunks[i], i*1024, size, want[i].Checksum)
+		}
+	}
+
+	if _, err := LoadManifest(testFile+ManifestSuffix, 4096); err == nil || !strings.Contains(err.Error(), "describes 2500 bytes") {
+		t.Errorf("expected size mismatch error, got %v", err)
+	}
+}
+
+func TestLoadManifestRejectsGaps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.bin"+ManifestSuffix)
+	manifest := Manifest{
+		File:      "test.bin",
+		Size:      2048,
+		Algorithm: "SHA256",
+		Chunks: []ManifestChunk{
+			{Offset: 0, Length: 512, Digest: "00"},
+			{Offset: 1024, Length: 1024, Digest: "00"},
+		},
+	}
+	data, _ := json.Marshal(manifest)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadManifest(path, 2048); err == nil || !strings.Contains(err.Error(), "does not follow the previous chunk") {
+		t.Errorf("expected gap error, got %v", err)
+	}
+}
+
+func TestWriteAndLoadJournal(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.bin")
+	chunks := []ChunkInfo{
+		{Offset: 2048, Size: 1024, Checksum: "cc"},
+		{Offset: 0, Size: 1024, Checksum: "aa"},
+	}
+	flags := map[string]string{"pattern": "sequential"}
+	if err := WriteJournal(testFile, 4000, chunks, flags); err != nil {
+		t.Fatalf("failed to write journal: %v", err)
+	}
+
+	journal, loaded, err := LoadJournal(testFile + ManifestSuffix)
+	if err != nil {
+		t.Fatalf("failed to load journal: %v", err)
+	}
+	if !journal.Incomplete || journal.Size != 4000 || journal.Flags["pattern"] != "sequential" {
+		t.Errorf("unexpected journal: %+v", journal)
+	}
+	if len(loaded) != 2 || loaded[0].Offset != 0 || loaded[0].Checksum != "aa" || loaded[1].Offset != 2048 || loaded[1].Size != 1024 {
+		t.Errorf("expected the chunks sorted by offset, got %+v", loaded)
+	}
+
+	// A journal is not a complete manifest to verify or repair against
+	if _, err := LoadManifest(testFile+ManifestSuffix, 4000); err == nil || !strings.Contains(err.Error(), "trasher resume") {
+		t.Errorf("expected incomplete manifest error, got %v", err)
+	}
+}
+
+func TestLoadJournalRejects(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		manifest Manifest
+		want     string
+	}{
+		{"complete", Manifest{Size: 1024, Algorithm: "SHA256"}, "nothing to resume"},
+		{"algorithm", Manifest{Size: 1024, Algorithm: "MD5", Incomplete: true}, "unsupported"},
+		{"overlap", Manifest{Size: 4096, Algorithm: "SHA256", Incomplete: true, Chunks: []ManifestChunk{
+			{Offset: 0, Length: 2048}, {Offset: 1024, Length: 1024},
+		}}, "overlaps"},
+		{"past end", Manifest{Size: 1024, Algorithm: "SHA256", Incomplete: true, Chunks: []ManifestChunk{
+			{Offset: 512, Length: 1024},
+		}}, "outside the file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+ManifestSuffix)
+			data, _ := json.Marshal(tt.manifest)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := LoadJournal(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package checksum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MerkleSuffix is appended to a file's path to name the directory holding
+// its Merkle tree.
+const MerkleSuffix = ".merkle"
+
+// merkleIndex is the file in a Merkle tree directory describing the tree.
+const merkleIndex = "tree.json"
+
+// MerkleTree is a binary hash tree over the chunks of a file. Its leaves are
+// the chunk checksums in offset order; each parent is the SHA-256 of its two
+// children concatenated, and a node without a sibling is carried up
+// unchanged. A chunk can be checked against the root with the sibling
+// digests on its path, without the rest of the file.
+type MerkleTree struct {
+	// Levels holds the digests of each level, from the leaves at index 0
+	// to the root.
+	Levels [][][]byte
+}
+
+// MerkleInfo describes a Merkle tree written with WriteMerkle.
+type MerkleInfo struct {
+	File      string `json:"file"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunk_size"`
+	Algorithm string `json:"algorithm"`
+	Leaves    int    `json:"leaves"`
+	Levels    int    `json:"levels"`
+	Root      string `json:"root"`
+}
+
+// NewMerkleTree builds the tree over chunks, which must be in offset order.
+func NewMerkleTree(chunks []ChunkInfo) (*MerkleTree, error) {
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no chunk checksums to build a Merkle tree from")
+	}
+
+	leaves := make([][]byte, len(chunks))
+	for i, chunk := range chunks {
+		sum, err := hex.DecodeString(chunk.Checksum)
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("invalid chunk checksum at offset %d", chunk.Offset)
+		}
+		leaves[i] = sum
+	}
+
+	tree := &MerkleTree{Levels: [][][]byte{leaves}}
+	for level := leaves; len(level) > 1; {
+		parents := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				parents = append(parents, level[i])
+				continue
+			}
+			parents = append(parents, merkleParent(level[i], level[i+1]))
+		}
+		tree.Levels = append(tree.Levels, parents)
+		level = parents
+	}
+	return tree, nil
+}
+
+// merkleParent returns the digest of the parent of left and right.
+func merkleParent(left, right []byte) []byte {
+	sum := sha256.Sum256(append(append(make([]byte, 0, 2*sha256.Size), left...), right...))
+	return sum[:]
+}
+
+// Root returns the digest at the top of the tree.
+func (t *MerkleTree) Root() []byte {
+	return t.Levels[len(t.Levels)-1][0]
+}
+
+// Proof returns the sibling digests on the path from leaf index to the
+// root, lowest first. Levels where the node has no sibling contribute none.
+func (t *MerkleTree) Proof(index int) ([][]byte, error) {
+	if index < 0 || index >= len(t.Levels[0]) {
+		return nil, fmt.Errorf("leaf %d out of range (%d leaves)", index, len(t.Levels[0]))
+	}
+
+	var proof [][]byte
+	for _, level := range t.Levels[:len(t.Levels)-1] {
+		if sibling := index ^ 1; sibling < len(level) {
+			proof = append(proof, level[sibling])
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof checks that leaf is leaf index of leaves in the tree
+// with the given root, using the proof Proof returned for it.
+func VerifyMerkleProof(leaf []byte, index, leaves int, proof [][]byte, root []byte) bool {
+	if index < 0 || index >= leaves {
+		return false
+	}
+
+	node := leaf
+	for width := leaves; width > 1; width = (width + 1) / 2 {
+		sibling := index ^ 1
+		if sibling < width {
+			if len(proof) == 0 {
+				return false
+			}
+			if index%2 == 0 {
+				node = merkleParent(node, proof[0])
+			} else {
+				node = merkleParent(proof[0], node)
+			}
+			proof = proof[1:]
+		}
+		index /= 2
+	}
+	return len(proof) == 0 && bytes.Equal(node, root)
+}
+
+// WriteMerkle writes the Merkle tree of the chunk checksums to the file's
+// Merkle directory: tree.json describing it and level-N.txt holding the hex
+// digests of level N, one per line, from the leaves at level 0 to the root.
+// size is the length of the data the chunks cover.
+func (c *ChecksumGenerator) WriteMerkle(size int64) error {
+	chunks := c.GetChunkChecksums()
+	tree, err := NewMerkleTree(chunks)
+	if err != nil {
+		return err
+	}
+
+	dir := c.outputPath + MerkleSuffix
+	if err := RemoveMerkle(c.outputPath); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create Merkle tree directory: %v", err)
+	}
+
+	for i, level := range tree.Levels {
+		var buf bytes.Buffer
+		for _, digest := range level {
+			fmt.Fprintf(&buf, "%x\n", digest)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("level-%d.txt", i)), buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write Merkle tree level %d: %v", i, err)
+		}
+	}
+
+	info := MerkleInfo{
+		File:      filepath.Base(c.outputPath),
+		Size:      size,
+		Algorithm: chunkAlgorithm,
+		Leaves:    len(chunks),
+		Levels:    len(tree.Levels),
+		Root:      hex.EncodeToString(tree.Root()),
+	}
+	if len(chunks) > 1 {
+		info.ChunkSize = chunks[1].Offset
+	} else {
+		info.ChunkSize = size
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode Merkle tree: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, merkleIndex), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write Merkle tree: %v", err)
+	}
+	return nil
+}
+
+// RemoveMerkle removes the Merkle tree directory WriteMerkle wrote for the
+// file at path. Files it didn't write are left in place, along with the
+// directory.
+func RemoveMerkle(path string) error {
+	dir := path + MerkleSuffix
+	levels, err := filepath.Glob(filepath.Join(dir, "level-*.txt"))
+	if err != nil {
+		return err
+	}
+	for _, name := range append(levels, filepath.Join(dir, merkleIndex)) {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale %s: %v", name, err)
+		}
+	}
+	// A directory holding files of its own is kept
+	os.Remove(dir)
+	return nil
+}
@@ -0,0 +1,145 @@
+package checksum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// merkleChunks returns n chunks of 1KB with distinct checksums.
+func merkleChunks(n int) []ChunkInfo {
+	chunks := make([]ChunkInfo, n)
+	for i := range chunks {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("chunk %d", i)))
+		chunks[i] = ChunkInfo{Offset: int64(i) * 1024, Checksum: hex.EncodeToString(sum[:])}
+	}
+	return chunks
+}
+
+func TestMerkleTree(t *testing.T) {
+	chunks := merkleChunks(3)
+	tree, err := NewMerkleTree(chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two leaves are combined and the third is carried up
+	leaf := func(i int) []byte {
+		sum, _ := hex.DecodeString(chunks[i].Checksum)
+		return sum
+	}
+	left := sha256.Sum256(append(leaf(0), leaf(1)...))
+	root := sha256.Sum256(append(left[:], leaf(2)...))
+	if len(tree.Levels) != 3 || !bytes.Equal(tree.Root(), root[:]) {
+		t.Errorf("expected root %x over 3 levels, got %x over %d", root, tree.Root(), len(tree.Levels))
+	}
+
+	single, err := NewMerkleTree(chunks[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(single.Root(), leaf(0)) {
+		t.Error("expected the root of a single chunk to be its checksum")
+	}
+
+	if _, err := NewMerkleTree(nil); err == nil {
+		t.Error("expected an error without chunks")
+	}
+	if _, err := NewMerkleTree([]ChunkInfo{{Checksum: "zz"}}); err == nil {
+		t.Error("expected an error for an invalid checksum")
+	}
+}
+
+func TestMerkleProof(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 8, 13} {
+		chunks := merkleChunks(n)
+		tree, err := NewMerkleTree(chunks)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := range chunks {
+			proof, err := tree.Proof(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			leaf := tree.Levels[0][i]
+			if !VerifyMerkleProof(leaf, i, n, proof, tree.Root()) {
+				t.Errorf("%d leaves: proof of leaf %d does not verify", n, i)
+			}
+
+			other := sha256.Sum256([]byte("other"))
+			if VerifyMerkleProof(other[:], i, n, proof, tree.Root()) {
+				t.Errorf("%d leaves: proof of leaf %d verifies the wrong data", n, i)
+			}
+			if n > 1 && VerifyMerkleProof(leaf, (i+1)%n, n, proof, tree.Root()) {
+				t.Errorf("%d leaves: proof of leaf %d verifies another index", n, i)
+			}
+		}
+	}
+
+	tree, _ := NewMerkleTree(merkleChunks(2))
+	if _, err := tree.Proof(2); err == nil {
+		t.Error("expected an error for a leaf out of range")
+	}
+}
+
+func TestWriteMerkle(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.bin")
+	data := bytes.Repeat([]byte("merkle"), 1000)
+	gen := NewChecksumGenerator(testFile, int64(len(data)))
+	for offset := 0; offset < len(data); offset += 1024 {
+		gen.UpdateWithChunk(data[offset:min(offset+1024, len(data))], int64(offset))
+	}
+
+	// Levels of an earlier, larger tree are removed
+	dir := testFile + MerkleSuffix
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "level-9.txt"), []byte("stale\n"), 0644)
+
+	if err := gen.WriteMerkle(int64(len(data))); err != nil {
+		t.Fatalf("WriteMerkle failed: %v", err)
+	}
+
+	var info MerkleInfo
+	raw, err := os.ReadFile(filepath.Join(dir, "tree.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Leaves != 6 || info.Levels != 4 || info.ChunkSize != 1024 || info.Size != 6000 {
+		t.Errorf("unexpected tree description %+v", info)
+	}
+
+	leaves, err := os.ReadFile(filepath.Join(dir, "level-0.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Fields(string(leaves))
+	chunks := gen.GetChunkChecksums()
+	if len(lines) != len(chunks) || lines[0] != chunks[0].Checksum {
+		t.Errorf("expected level 0 to list the chunk checksums, got %v", lines)
+	}
+	root, err := os.ReadFile(filepath.Join(dir, "level-3.txt"))
+	if err != nil || strings.TrimSpace(string(root)) != info.Root {
+		t.Errorf("expected the top level to hold the root %s, got %q (%v)", info.Root, root, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "level-9.txt")); !os.IsNotExist(err) {
+		t.Error("expected the stale level to be removed")
+	}
+
+	if err := RemoveMerkle(testFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("expected the Merkle tree directory to be removed")
+	}
+}
@@ -0,0 +1,130 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"sync"
+)
+
+// DefaultHashBuffer is how much out-of-order data an OrderedHasher holds by
+// default.
+const DefaultHashBuffer = 256 * 1024 * 1024
+
+// ErrHashBufferFull is returned by OrderedHasher.Write when holding a chunk
+// until the chunks before it arrive would exceed the buffer limit.
+var ErrHashBufferFull = errors.New("ordered hash buffer full")
+
+// OrderedHasher computes the SHA-256 of a file from chunks that arrive out of
+// order. Chunks are hashed as soon as every byte before them has been, and a
+// chunk that arrives early is copied into a reorder buffer until then. The
+// buffer is bounded: once a chunk would take it past the limit, the hasher
+// gives up rather than hold up the writers, and the digest has to be
+// computed another way. Writers that deliver chunks roughly in offset order,
+// as workers taking chunks in turn do, keep only a few chunks buffered.
+type OrderedHasher struct {
+	mu       sync.Mutex
+	hasher   hash.Hash
+	next     int64
+	pending  map[int64][]byte
+	buffered int64
+	peak     int64
+	limit    int64
+	failed   bool
+}
+
+// NewOrderedHasher returns an OrderedHasher that buffers at most limit bytes
+// of out-of-order chunks. A limit of 0 or less uses DefaultHashBuffer.
+func NewOrderedHasher(limit int64) *OrderedHasher {
+	if limit <= 0 {
+		limit = DefaultHashBuffer
+	}
+	return &OrderedHasher{
+		hasher:  sha256.New(),
+		pending: make(map[int64][]byte),
+		limit:   limit,
+	}
+}
+
+// Write adds the chunk data at offset. It is safe for concurrent use, and
+// data may be reused once it returns. Chunks must not overlap. Once it has
+// returned ErrHashBufferFull the hasher is spent and later writes are
+// ignored.
+func (h *OrderedHasher) Write(data []byte, offset int64) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failed {
+		return nil
+	}
+	if offset < h.next {
+		return fmt.Errorf("chunk at offset %d was already hashed", offset)
+	}
+	if offset > h.next {
+		if _, ok := h.pending[offset]; ok {
+			return fmt.Errorf("chunk at offset %d was already added", offset)
+		}
+		if h.buffered+int64(len(data)) > h.limit {
+			h.failed = true
+			h.pending, h.buffered = nil, 0
+			return ErrHashBufferFull
+		}
+		h.pending[offset] = append([]byte(nil), data...)
+		h.buffered += int64(len(data))
+		h.peak = max(h.peak, h.buffered)
+		return nil
+	}
+
+	h.hasher.Write(data)
+	h.next += int64(len(data))
+	for {
+		chunk, ok := h.pending[h.next]
+		if !ok {
+			return nil
+		}
+		delete(h.pending, h.next)
+		h.hasher.Write(chunk)
+		h.buffered -= int64(len(chunk))
+		h.next += int64(len(chunk))
+	}
+}
+
+// Hashed returns how many bytes from the start of the file have been hashed.
+func (h *OrderedHasher) Hashed() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.next
+}
+
+// Peak returns the most data the reorder buffer has held at once.
+func (h *OrderedHasher) Peak() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.peak
+}
+
+// Limit returns the most data the reorder buffer may hold.
+func (h *OrderedHasher) Limit() int64 {
+	return h.limit
+}
+
+// Sum returns the SHA-256 of the data hashed so far. It fails if the buffer
+// overflowed or chunks are still waiting for earlier ones, since the digest
+// would then not cover the data written.
+func (h *OrderedHasher) Sum() ([]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failed {
+		return nil, ErrHashBufferFull
+	}
+	if len(h.pending) > 0 {
+		return nil, fmt.Errorf("%d chunks after offset %d are missing the data before them", len(h.pending), h.next)
+	}
+	return h.hasher.Sum(nil), nil
+}
@@ -0,0 +1,138 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// shuffledChunks splits data into chunkSize chunks and returns their offsets
+// in a seeded random order.
+func shuffledChunks(data []byte, chunkSize int, seed uint64) []int {
+	var offsets []int
+	for offset := 0; offset < len(data); offset += chunkSize {
+		offsets = append(offsets, offset)
+	}
+	rng := rand.New(rand.NewPCG(seed, seed))
+	rng.Shuffle(len(offsets), func(i, j int) {
+		offsets[i], offsets[j] = offsets[j], offsets[i]
+	})
+	return offsets
+}
+
+func TestOrderedHasher(t *testing.T) {
+	data := make([]byte, 100*1000+7)
+	rand.NewChaCha8([32]byte{7}).Read(data)
+	want := sha256.Sum256(data)
+
+	for seed := uint64(0); seed < 5; seed++ {
+		h := NewOrderedHasher(int64(len(data)))
+		for _, offset := range shuffledChunks(data, 1000, seed) {
+			end := min(offset+1000, len(data))
+			if err := h.Write(data[offset:end], int64(offset)); err != nil {
+				t.Fatalf("Write at %d failed: %v", offset, err)
+			}
+		}
+		got, err := h.Sum()
+		if err != nil {
+			t.Fatalf("Sum failed: %v", err)
+		}
+		if hex.EncodeToString(got) != hex.EncodeToString(want[:]) {
+			t.Errorf("seed %d: got %x, want %x", seed, got, want)
+		}
+		if h.Hashed() != int64(len(data)) {
+			t.Errorf("expected %d bytes hashed, got %d", len(data), h.Hashed())
+		}
+		if h.Peak() == 0 || h.Peak() > h.Limit() {
+			t.Errorf("expected a peak within the %d byte limit, got %d", h.Limit(), h.Peak())
+		}
+	}
+}
+
+func TestOrderedHasherOverflow(t *testing.T) {
+	h := NewOrderedHasher(1500)
+	chunk := make([]byte, 1000)
+
+	if err := h.Write(chunk, 1000); err != nil {
+		t.Fatalf("first early chunk should fit: %v", err)
+	}
+	if err := h.Write(chunk, 2000); !errors.Is(err, ErrHashBufferFull) {
+		t.Fatalf("expected ErrHashBufferFull, got %v", err)
+	}
+	if err := h.Write(chunk, 0); err != nil {
+		t.Errorf("writes after an overflow should be ignored, got %v", err)
+	}
+	if _, err := h.Sum(); !errors.Is(err, ErrHashBufferFull) {
+		t.Errorf("expected Sum to fail after an overflow, got %v", err)
+	}
+}
+
+func TestOrderedHasherErrors(t *testing.T) {
+	h := NewOrderedHasher(0)
+	if h.Limit() != DefaultHashBuffer {
+		t.Errorf("expected default limit %d, got %d", DefaultHashBuffer, h.Limit())
+	}
+
+	chunk := make([]byte, 10)
+	h.Write(chunk, 0)
+	if err := h.Write(chunk, 0); err == nil {
+		t.Error("expected an error for a chunk already hashed")
+	}
+	h.Write(chunk, 20)
+	if err := h.Write(chunk, 20); err == nil {
+		t.Error("expected an error for a chunk already buffered")
+	}
+	if _, err := h.Sum(); err == nil {
+		t.Error("expected Sum to fail while a chunk waits for the data before it")
+	}
+}
+
+func TestFinalizeOrdered(t *testing.T) {
+	data := make([]byte, 64*1024)
+	rand.NewChaCha8([32]byte{3}).Read(data)
+	want := sha256.Sum256(data)
+
+	for _, limit := range []int64{int64(len(data)), 1} {
+		path := filepath.Join(t.TempDir(), "ordered.bin")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		gen := NewChecksumGenerator(path, int64(len(data)))
+		if err := gen.SetMode(ModeOrdered); err != nil {
+			t.Fatal(err)
+		}
+		gen.SetHashBuffer(limit)
+		for _, offset := range shuffledChunks(data, 4096, 1) {
+			if err := gen.UpdateOrdered(data[offset:offset+4096], int64(offset)); err != nil {
+				t.Fatalf("UpdateOrdered failed: %v", err)
+			}
+		}
+
+		// Change the file behind the generator's back: the ordered checksum
+		// still describes the data it was given, while a read back sees the
+		// change
+		changed := append([]byte(nil), data...)
+		changed[0] ^= 0xFF
+		if err := os.WriteFile(path, changed, 0644); err != nil {
+			t.Fatal(err)
+		}
+		readBack := sha256.Sum256(changed)
+
+		got, err := gen.Finalize()
+		if err != nil {
+			t.Fatalf("Finalize failed: %v", err)
+		}
+		expected := want
+		if limit == 1 {
+			expected = readBack
+		}
+		if got != hex.EncodeToString(expected[:]) {
+			t.Errorf("limit %d: got %s, want %x", limit, got, expected)
+		}
+	}
+}
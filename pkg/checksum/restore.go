@@ -0,0 +1,37 @@
+package checksum
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// RestoreChunkChecksums records the checksums of chunks an earlier run
+// already wrote, as read from its checksum file or manifest, so a resumed
+// run only hashes the chunks it generates and its checksum file still
+// covers the whole file. The checksums are trusted; use RehashChunks for
+// data that may have changed since.
+func (c *ChecksumGenerator) RestoreChunkChecksums(chunks []ChunkInfo) error {
+	for _, chunk := range chunks {
+		sum, err := hex.DecodeString(chunk.Checksum)
+		if err != nil {
+			return fmt.Errorf("invalid checksum for chunk at offset %d: %v", chunk.Offset, err)
+		}
+		if err := c.AddChunkChecksum(chunk.Offset, sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RehashChunks reads chunks already written to the output back and records
+// their checksums, hashing one chunk per CPU at a time. It restores the
+// checksum state of a partial file without a record of its checksums, or
+// when that record can't be trusted.
+func (c *ChecksumGenerator) RehashChunks(chunks []ChunkInfo) error {
+	hashed := make([]ChunkInfo, len(chunks))
+	copy(hashed, chunks)
+	if err := hashChunks(c.outputPath, hashed); err != nil {
+		return fmt.Errorf("failed to read chunks for checksum: %v", err)
+	}
+	return c.RestoreChunkChecksums(hashed)
+}
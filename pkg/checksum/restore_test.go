@@ -0,0 +1,112 @@
+package checksum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// partialFile writes the first two of three 1KB chunks of data, as an
+// interrupted run leaves them, and returns its path and chunks.
+func partialFile(t *testing.T, data []byte) (string, []ChunkInfo) {
+	t.Helper()
+	testFile := filepath.Join(t.TempDir(), "test.bin")
+	if err := os.WriteFile(testFile, data[:2048], 0644); err != nil {
+		t.Fatal(err)
+	}
+	return testFile, []ChunkInfo{{Offset: 0, Size: 1024}, {Offset: 1024, Size: 1024}}
+}
+
+// finishFile writes the last chunk as a resumed run would and returns the
+// checksum file's chunk checksums, verifying the full-file checksum.
+func finishFile(t *testing.T, generator *ChecksumGenerator, testFile string, data []byte) []ChunkInfo {
+	t.Helper()
+	file, err := os.OpenFile(testFile, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteAt(data[2048:], 2048); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	generator.UpdateWithChunk(data[2048:], 2048)
+
+	if err := generator.WriteChecksumFile(); err != nil {
+		t.Fatalf("failed to write checksum file: %v", err)
+	}
+	result, err := VerifyFile(testFile)
+	if err != nil || !result.Valid {
+		t.Fatalf("expected the resumed file to verify, got %v (%v)", result, err)
+	}
+	chunks, err := LoadChunkChecksums(testFile+".checksum.txt", int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chunks
+}
+
+func TestRehashChunks(t *testing.T) {
+	data := make([]byte, 3000)
+	for i := range data {
+		data[i] = byte(i * 13)
+	}
+
+	for _, mode := range []string{ModeStream, ModeTree} {
+		t.Run(mode, func(t *testing.T) {
+			testFile, written := partialFile(t, data)
+
+			generator := NewChecksumGenerator(testFile, int64(len(data)))
+			if err := generator.SetMode(mode); err != nil {
+				t.Fatal(err)
+			}
+			if err := generator.RehashChunks(written); err != nil {
+				t.Fatalf("RehashChunks failed: %v", err)
+			}
+			if written[0].Checksum != "" {
+				t.Error("RehashChunks modified the chunks passed in")
+			}
+
+			chunks := finishFile(t, generator, testFile, data)
+			if len(chunks) != 3 {
+				t.Errorf("expected 3 chunk checksums, got %d", len(chunks))
+			}
+		})
+	}
+}
+
+func TestRestoreChunkChecksums(t *testing.T) {
+	data := make([]byte, 3000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	// A complete run records the checksums the resumed run restores
+	testFile, _ := partialFile(t, data)
+	original := NewChecksumGenerator(testFile, int64(len(data)))
+	original.UpdateWithChunk(data[:1024], 0)
+	original.UpdateWithChunk(data[1024:2048], 1024)
+	recorded := original.GetChunkChecksums()
+
+	generator := NewChecksumGenerator(testFile, int64(len(data)))
+	if err := generator.SetMode(ModeTree); err != nil {
+		t.Fatal(err)
+	}
+	if err := generator.RestoreChunkChecksums(recorded); err != nil {
+		t.Fatalf("RestoreChunkChecksums failed: %v", err)
+	}
+	chunks := finishFile(t, generator, testFile, data)
+	if chunks[1].Checksum != recorded[1].Checksum {
+		t.Errorf("expected restored checksum %s, got %s", recorded[1].Checksum, chunks[1].Checksum)
+	}
+
+	if err := generator.RestoreChunkChecksums([]ChunkInfo{{Offset: 0, Checksum: "xyz"}}); err == nil {
+		t.Error("expected error for an invalid checksum")
+	}
+}
+
+func TestRehashChunksMissingFile(t *testing.T) {
+	generator := NewChecksumGenerator(filepath.Join(t.TempDir(), "missing.bin"), 1024)
+	if err := generator.RehashChunks([]ChunkInfo{{Offset: 0, Size: 1024}}); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
@@ -0,0 +1,132 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// stateVersion is the version of the state files SaveState writes.
+const stateVersion = 1
+
+// generatorState is the saved state of a ChecksumGenerator.
+type generatorState struct {
+	Version    int          `json:"version"`
+	Output     string       `json:"output"`
+	Size       int64        `json:"size"`
+	Mode       string       `json:"mode"`
+	HashBuffer int64        `json:"hash_buffer,omitempty"`
+	Format     string       `json:"format"`
+	Chunks     []chunkState `json:"chunks"`
+}
+
+// chunkState is one chunk of a saved state: the internal state of a hash
+// still being fed through UpdateWithChunk, or the digest of a chunk recorded
+// with AddChunkChecksum.
+type chunkState struct {
+	Offset int64  `json:"offset"`
+	Hash   []byte `json:"hash,omitempty"`
+	Digest string `json:"digest,omitempty"`
+}
+
+// SaveState atomically writes the generator's chunk checksums, including
+// chunks only partly passed to UpdateWithChunk, to path. A process that
+// stops part way through a file can load the state with LoadState and carry
+// on hashing where it left off.
+func (c *ChecksumGenerator) SaveState(path string) error {
+	c.mu.Lock()
+	state := generatorState{
+		Version:    stateVersion,
+		Output:     c.outputPath,
+		Size:       c.totalSize,
+		Mode:       ModeStream,
+		HashBuffer: c.hashBuffer,
+		Format:     c.format,
+	}
+	if c.tree {
+		state.Mode = ModeTree
+	} else if c.ordered != nil {
+		state.Mode = ModeOrdered
+	}
+	for offset, hasher := range c.chunkHashers {
+		saved, err := hasher.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			c.mu.Unlock()
+			return fmt.Errorf("failed to save chunk checksum at offset %d: %v", offset, err)
+		}
+		state.Chunks = append(state.Chunks, chunkState{Offset: offset, Hash: saved})
+	}
+	for offset, sum := range c.chunkSums {
+		state.Chunks = append(state.Chunks, chunkState{Offset: offset, Digest: hex.EncodeToString(sum)})
+	}
+	c.mu.Unlock()
+
+	sort.Slice(state.Chunks, func(i, j int) bool {
+		return state.Chunks[i].Offset < state.Chunks[j].Offset
+	})
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checksum state: %v", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write checksum state: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write checksum state: %v", err)
+	}
+	return nil
+}
+
+// LoadState creates a generator from a state written by SaveState, with the
+// output path, size, mode, hash buffer, format and chunk checksums it was
+// saved with. In ModeOrdered the hasher starts empty, so unless it is passed
+// the whole file again Finalize reads the file back.
+func LoadState(path string) (*ChecksumGenerator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var state generatorState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid checksum state %s: %v", path, err)
+	}
+	if state.Version != stateVersion {
+		return nil, fmt.Errorf("unsupported checksum state version %d", state.Version)
+	}
+
+	c := NewChecksumGenerator(state.Output, state.Size)
+	c.SetHashBuffer(state.HashBuffer)
+	if err := c.SetMode(state.Mode); err != nil {
+		return nil, err
+	}
+	if err := c.SetFormat(state.Format); err != nil {
+		return nil, err
+	}
+	for _, chunk := range state.Chunks {
+		if chunk.Offset < 0 || chunk.Offset >= state.Size {
+			return nil, fmt.Errorf("invalid offset %d for file size %d", chunk.Offset, state.Size)
+		}
+		if chunk.Digest != "" {
+			sum, err := hex.DecodeString(chunk.Digest)
+			if err != nil || len(sum) != sha256.Size {
+				return nil, fmt.Errorf("invalid checksum for chunk at offset %d", chunk.Offset)
+			}
+			c.chunkSums[chunk.Offset] = sum
+			continue
+		}
+
+		hasher := sha256.New()
+		if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(chunk.Hash); err != nil {
+			return nil, fmt.Errorf("failed to restore chunk checksum at offset %d: %v", chunk.Offset, err)
+		}
+		c.chunkHashers[chunk.Offset] = hasher
+	}
+	return c, nil
+}
@@ -0,0 +1,133 @@
+package checksum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveAndLoadState(t *testing.T) {
+	dir := t.TempDir()
+	testFile := filepath.Join(dir, "test.bin")
+	data := bytes.Repeat([]byte("state test data "), 256)
+	size := int64(len(data))
+
+	// One generator sees every chunk whole; the other is saved with its
+	// second chunk half hashed and its third recorded from a digest
+	whole := NewChecksumGenerator(testFile, size)
+	whole.SetMode(ModeTree)
+	whole.UpdateWithChunk(data[:1024], 0)
+	whole.UpdateWithChunk(data[1024:2048], 1024)
+	whole.UpdateWithChunk(data[2048:], 2048)
+
+	saved := NewChecksumGenerator(testFile, size)
+	saved.SetMode(ModeTree)
+	saved.UpdateWithChunk(data[:1024], 0)
+	saved.UpdateWithChunk(data[1024:1500], 1024)
+	sum := sha256.Sum256(data[2048:])
+	if err := saved.AddChunkChecksum(2048, sum[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	statePath := filepath.Join(dir, "test.state")
+	if err := saved.SaveState(statePath); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	loaded, err := LoadState(statePath)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if err := loaded.UpdateWithChunk(data[1500:2048], 1024); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded.GetChunkChecksums(), whole.GetChunkChecksums()) {
+		t.Errorf("restored chunk checksums differ:\n got %v\nwant %v", loaded.GetChunkChecksums(), whole.GetChunkChecksums())
+	}
+	if loaded.GetAlgorithm() != treeAlgorithm {
+		t.Errorf("expected mode to be restored, got algorithm %s", loaded.GetAlgorithm())
+	}
+
+	got, err := loaded.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := whole.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("expected checksum %s, got %s", want, got)
+	}
+}
+
+func TestSaveAndLoadOrderedState(t *testing.T) {
+	dir := t.TempDir()
+	testFile := filepath.Join(dir, "test.bin")
+	data := bytes.Repeat([]byte("ordered state data "), 256)
+	if err := os.WriteFile(testFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := NewChecksumGenerator(testFile, int64(len(data)))
+	saved.SetHashBuffer(4096)
+	saved.SetMode(ModeOrdered)
+	saved.UpdateWithChunk(data[:1024], 0)
+	saved.UpdateOrdered(data[:1024], 0)
+
+	statePath := filepath.Join(dir, "test.state")
+	if err := saved.SaveState(statePath); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	loaded, err := LoadState(statePath)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if loaded.OrderedHasher() == nil {
+		t.Fatal("expected ordered mode to be restored")
+	}
+	if limit := loaded.OrderedHasher().Limit(); limit != 4096 {
+		t.Errorf("expected hash buffer 4096 to be restored, got %d", limit)
+	}
+
+	// The restored hasher never saw the first chunk, so the checksum comes
+	// from reading the file back
+	loaded.UpdateWithChunk(data[1024:], 1024)
+	loaded.UpdateOrdered(data[1024:], 1024)
+	got, err := loaded.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256(data)
+	if got != hex.EncodeToString(want[:]) {
+		t.Errorf("expected checksum %x, got %s", want, got)
+	}
+}
+
+func TestLoadStateErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"not json":        "not json",
+		"unknown version": `{"version": 99}`,
+		"bad offset":      `{"version": 1, "size": 10, "mode": "stream", "format": "trasher", "chunks": [{"offset": 10, "digest": "00"}]}`,
+		"bad digest":      `{"version": 1, "size": 10, "mode": "stream", "format": "trasher", "chunks": [{"offset": 0, "digest": "zz"}]}`,
+		"bad hash state":  `{"version": 1, "size": 10, "mode": "stream", "format": "trasher", "chunks": [{"offset": 0, "hash": "AAAA"}]}`,
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(name, " ", "-"))
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadState(path); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Checksum modes. Stream mode reads the finished file back in one pass to
+// compute the full-file checksum. Ordered mode computes the same checksum
+// while the file is written, hashing chunks in offset order through a
+// bounded reorder buffer, and falls back to reading the file back if the
+// buffer overflows. Tree mode derives it from the chunk checksums workers
+// already computed in parallel, so the file is not read again and hashing
+// never limits throughput.
+const (
+	ModeStream  = "stream"
+	ModeOrdered = "ordered"
+	ModeTree    = "tree"
+)
+
+// chunkAlgorithm names the algorithm of chunk checksums in every mode.
+const chunkAlgorithm = "SHA256"
+
+// treeAlgorithm names the full-file checksum of tree mode: the SHA-256 of
+// the chunks' SHA-256 digests concatenated in offset order.
+const treeAlgorithm = "SHA256-TREE"
+
+// treeChecksum combines chunk checksums, sorted by offset, into a tree
+// checksum.
+func treeChecksum(chunks []ChunkInfo) (string, error) {
+	hasher := sha256.New()
+	for _, chunk := range chunks {
+		sum, err := hex.DecodeString(chunk.Checksum)
+		if err != nil || len(sum) != sha256.Size {
+			return "", fmt.Errorf("invalid chunk checksum at offset %d", chunk.Offset)
+		}
+		hasher.Write(sum)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// hashChunks reads the chunks of the file at path and sets their checksums,
+// hashing one chunk per CPU at a time.
+func hashChunks(path string, chunks []ChunkInfo) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var (
+		next     atomic.Int64
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		failed   atomic.Bool
+	)
+	for range min(runtime.NumCPU(), len(chunks)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hasher := sha256.New()
+			buffer := make([]byte, 1024*1024)
+			for !failed.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= len(chunks) {
+					return
+				}
+				hasher.Reset()
+				section := io.NewSectionReader(file, chunks[i].Offset, chunks[i].Size)
+				if _, err := io.CopyBuffer(hasher, section, buffer); err != nil {
+					errOnce.Do(func() { firstErr = err })
+					failed.Store(true)
+					return
+				}
+				chunks[i].Checksum = hex.EncodeToString(hasher.Sum(nil))
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// computeTreeChecksum computes the tree checksum of the file at path over
+// the chunk boundaries recorded in its checksum file.
+func computeTreeChecksum(path, checksumPath string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	chunks, err := LoadChunkChecksums(checksumPath, info.Size())
+	if err != nil {
+		return "", err
+	}
+	if err := hashChunks(path, chunks); err != nil {
+		return "", err
+	}
+	return treeChecksum(chunks)
+}
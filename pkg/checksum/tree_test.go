@@ -0,0 +1,121 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTreeFile writes data in chunkSize chunks and records their checksums
+// in a tree mode checksum file, which must not need the file itself.
+func writeTreeFile(t *testing.T, data []byte, chunkSize int) string {
+	t.Helper()
+	testFile := filepath.Join(t.TempDir(), "test.bin")
+
+	generator := NewChecksumGenerator(testFile, int64(len(data)))
+	if err := generator.SetMode(ModeTree); err != nil {
+		t.Fatal(err)
+	}
+	for offset := 0; offset < len(data); offset += chunkSize {
+		hasher := generator.NewChunkHash()
+		hasher.Write(data[offset:min(offset+chunkSize, len(data))])
+		if err := generator.AddChunkChecksum(int64(offset), hasher.Sum(nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := generator.WriteChecksumFile(); err != nil {
+		t.Fatalf("failed to write checksum file: %v", err)
+	}
+	if err := os.WriteFile(testFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return testFile
+}
+
+func TestTreeChecksum(t *testing.T) {
+	data := make([]byte, 10*1024+100)
+	for i := range data {
+		data[i] = byte(i * 31)
+	}
+	testFile := writeTreeFile(t, data, 1024)
+
+	// The root is the hash of the chunk digests in order
+	root := sha256.New()
+	for offset := 0; offset < len(data); offset += 1024 {
+		sum := sha256.Sum256(data[offset:min(offset+1024, len(data))])
+		root.Write(sum[:])
+	}
+	want := "SHA256-TREE (full file): " + hex.EncodeToString(root.Sum(nil))
+
+	content, err := os.ReadFile(testFile + ".checksum.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), want) {
+		t.Errorf("expected %q in checksum file:\n%s", want, content)
+	}
+	if !strings.Contains(string(content), "SHA256 (offset 1024): ") {
+		t.Errorf("expected SHA256 chunk checksums in checksum file:\n%s", content)
+	}
+
+	result, err := VerifyFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid {
+		t.Errorf("verification should be valid, got: %s", result.Error)
+	}
+
+	// Damage one byte of a middle chunk
+	data[5000] ^= 0xFF
+	if err := os.WriteFile(testFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err = VerifyFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.Error != "checksum mismatch" {
+		t.Errorf("expected checksum mismatch, got valid=%v error=%q", result.Valid, result.Error)
+	}
+}
+
+func TestTreeChecksumTruncatedFile(t *testing.T) {
+	data := make([]byte, 4096)
+	testFile := writeTreeFile(t, data, 1024)
+
+	if err := os.Truncate(testFile, 3000); err != nil {
+		t.Fatal(err)
+	}
+	result, err := VerifyFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid {
+		t.Error("verification of a truncated file should fail")
+	}
+}
+
+func TestSetMode(t *testing.T) {
+	generator := NewChecksumGenerator("test.bin", 1024)
+
+	if err := generator.SetMode(ModeTree); err != nil {
+		t.Fatal(err)
+	}
+	if generator.GetAlgorithm() != "SHA256-TREE" {
+		t.Errorf("expected algorithm SHA256-TREE, got %s", generator.GetAlgorithm())
+	}
+	if err := generator.SetMode(ModeStream); err != nil {
+		t.Fatal(err)
+	}
+	if generator.GetAlgorithm() != "SHA256" {
+		t.Errorf("expected algorithm SHA256, got %s", generator.GetAlgorithm())
+	}
+
+	if err := generator.SetMode("merkle"); err == nil || !strings.Contains(err.Error(), "unknown checksum mode") {
+		t.Errorf("expected unknown mode error, got %v", err)
+	}
+}
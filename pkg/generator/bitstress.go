@@ -0,0 +1,40 @@
+package generator
+
+import "fmt"
+
+// bitPatterns holds the sequences of the bit-stress patterns used by
+// memory and disk burn-in tools.
+var bitPatterns = map[string][]byte{
+	// Alternating bits, inverted on every byte so each bit toggles
+	"aa55": {0xAA, 0x55},
+	// A single set bit walking from the lowest to the highest position
+	"walking-ones": {0x01, 0x02, 0x04, 0x08, 0x10, 0x20, 0x40, 0x80},
+}
+
+// BitPatternGenerator repeats one of the classic bit-toggle sequences. These
+// keep data lines switching in known ways, surfacing signal-integrity and
+// stuck-bit faults that zero and random data miss.
+type BitPatternGenerator struct {
+	*RepeatGenerator
+	name string
+}
+
+// NewBitPatternGenerator creates a generator for a bit-stress pattern, aa55
+// or walking-ones.
+func NewBitPatternGenerator(name string) (*BitPatternGenerator, error) {
+	sequence, ok := bitPatterns[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown bit pattern: %s", name)
+	}
+
+	repeat, err := NewRepeatGenerator(sequence)
+	if err != nil {
+		return nil, err
+	}
+	return &BitPatternGenerator{RepeatGenerator: repeat, name: name}, nil
+}
+
+// Name returns the name of the generator.
+func (g *BitPatternGenerator) Name() string {
+	return g.name
+}
@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBitPatternGenerator(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected []byte
+	}{
+		{"aa55", []byte{0xAA, 0x55, 0xAA, 0x55, 0xAA, 0x55, 0xAA, 0x55, 0xAA, 0x55}},
+		{"walking-ones", []byte{0x01, 0x02, 0x04, 0x08, 0x10, 0x20, 0x40, 0x80, 0x01, 0x02}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := NewBitPatternGenerator(test.name)
+			if err != nil {
+				t.Fatalf("NewBitPatternGenerator failed: %v", err)
+			}
+			if g.Name() != test.name {
+				t.Errorf("expected name %s, got %s", test.name, g.Name())
+			}
+
+			buffer := make([]byte, len(test.expected))
+			if err := g.Generate(buffer); err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if !bytes.Equal(buffer, test.expected) {
+				t.Errorf("expected %x, got %x", test.expected, buffer)
+			}
+
+			// The sequence follows the file offset
+			g.GenerateAt(buffer[:2], 3)
+			if !bytes.Equal(buffer[:2], test.expected[3:5]) {
+				t.Errorf("expected %x at offset 3, got %x", test.expected[3:5], buffer[:2])
+			}
+		})
+	}
+}
+
+func TestBitPatternGeneratorToggles(t *testing.T) {
+	// Every bit flips between consecutive aa55 bytes
+	g, _ := NewBitPatternGenerator("aa55")
+	buffer := make([]byte, 64)
+	g.Generate(buffer)
+	for i := 1; i < len(buffer); i++ {
+		if buffer[i]^buffer[i-1] != 0xFF {
+			t.Fatalf("bytes %d and %d do not toggle every bit: %#x %#x", i-1, i, buffer[i-1], buffer[i])
+		}
+	}
+
+	// Exactly one bit is set in every walking-ones byte
+	g, _ = NewBitPatternGenerator("walking-ones")
+	g.Generate(buffer)
+	for i, b := range buffer {
+		if b == 0 || b&(b-1) != 0 {
+			t.Fatalf("byte %d has %#x, expected a single set bit", i, b)
+		}
+	}
+}
+
+func TestBitPatternGeneratorUnknown(t *testing.T) {
+	if _, err := NewBitPatternGenerator("walking-zeros"); err == nil {
+		t.Error("expected error for an unknown bit pattern")
+	}
+}
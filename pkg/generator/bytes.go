@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MaxPatternBytes is the longest byte sequence the bytes pattern repeats.
+const MaxPatternBytes = 64 * 1024
+
+// RepeatGenerator fills the output with a byte sequence repeated end to end,
+// such as a vendor-specified test pattern. The sequence is aligned to the
+// file offset, so the file starts with it and chunks line up in any order.
+type RepeatGenerator struct {
+	pattern []byte
+	offset  int64
+	mu      sync.Mutex
+}
+
+// NewRepeatGenerator creates a generator repeating pattern.
+func NewRepeatGenerator(pattern []byte) (*RepeatGenerator, error) {
+	if len(pattern) == 0 {
+		return nil, fmt.Errorf("the bytes pattern needs a byte sequence, e.g. --pattern-bytes 0xDEADBEEF")
+	}
+	if len(pattern) > MaxPatternBytes {
+		return nil, fmt.Errorf("byte sequence must be at most %d bytes, got %d", MaxPatternBytes, len(pattern))
+	}
+
+	return &RepeatGenerator{pattern: append([]byte(nil), pattern...)}, nil
+}
+
+// Name returns the name of the generator.
+func (g *RepeatGenerator) Name() string {
+	return "bytes"
+}
+
+// Pattern returns the repeated byte sequence.
+func (g *RepeatGenerator) Pattern() []byte {
+	return g.pattern
+}
+
+// Generate fills the buffer with the next bytes of the repeated sequence.
+func (g *RepeatGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+// GenerateAt fills the buffer with the repeated sequence as it appears at
+// the given file offset.
+func (g *RepeatGenerator) GenerateAt(buffer []byte, offset int64) error {
+	if len(buffer) == 0 {
+		return nil
+	}
+
+	// Lay down one period, then double the filled prefix until the buffer is full
+	start := int(offset % int64(len(g.pattern)))
+	n := copy(buffer, g.pattern[start:])
+	n += copy(buffer[n:], g.pattern[:start])
+	for n < len(buffer) {
+		n += copy(buffer[n:], buffer[:n])
+	}
+	return nil
+}
+
+// ParseHexBytes parses a byte sequence written in hex, with or without a 0x
+// prefix, such as "0xDEADBEEF". Underscores and spaces between digits are
+// ignored, so long sequences can be grouped for readability.
+func ParseHexBytes(value string) ([]byte, error) {
+	digits := strings.TrimSpace(value)
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		digits = digits[2:]
+	}
+	digits = strings.NewReplacer("_", "", " ", "").Replace(digits)
+
+	if digits == "" {
+		return nil, fmt.Errorf("invalid byte sequence %q: no hex digits", value)
+	}
+	if len(digits)%2 != 0 {
+		return nil, fmt.Errorf("invalid byte sequence %q: odd number of hex digits", value)
+	}
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid byte sequence %q: must be hex digits, e.g. 0xDEADBEEF", value)
+	}
+	if len(b) > MaxPatternBytes {
+		return nil, fmt.Errorf("invalid byte sequence %q: must be at most %d bytes", value, MaxPatternBytes)
+	}
+	return b, nil
+}
@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRepeatGenerator(t *testing.T) {
+	g, err := NewRepeatGenerator([]byte{0xDE, 0xAD, 0xBE, 0xEF, 0x01})
+	if err != nil {
+		t.Fatalf("NewRepeatGenerator failed: %v", err)
+	}
+
+	whole := make([]byte, 1003)
+	if err := g.Generate(whole); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for i, b := range whole {
+		if b != g.Pattern()[i%5] {
+			t.Fatalf("byte %d: expected %#x, got %#x", i, g.Pattern()[i%5], b)
+		}
+	}
+
+	// Chunks at any offset line up with the whole
+	for _, split := range []int{1, 3, 5, 512, 1002} {
+		part := make([]byte, len(whole)-split)
+		g.GenerateAt(part, int64(split))
+		if !bytes.Equal(part, whole[split:]) {
+			t.Errorf("chunk at offset %d does not match the whole stream", split)
+		}
+	}
+
+	// Buffers shorter than the sequence get a slice of it
+	short := make([]byte, 2)
+	g.GenerateAt(short, 4)
+	if !bytes.Equal(short, []byte{0x01, 0xDE}) {
+		t.Errorf("expected wrapped sequence, got %x", short)
+	}
+}
+
+func TestRepeatGeneratorInvalid(t *testing.T) {
+	if _, err := NewRepeatGenerator(nil); err == nil {
+		t.Error("expected error for an empty sequence")
+	}
+	if _, err := NewRepeatGenerator(make([]byte, MaxPatternBytes+1)); err == nil {
+		t.Error("expected error for an oversized sequence")
+	}
+}
+
+func TestParseHexBytes(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []byte
+	}{
+		{"0xDEADBEEF", []byte{0xDE, 0xAD, 0xBE, 0xEF}},
+		{"deadbeef", []byte{0xDE, 0xAD, 0xBE, 0xEF}},
+		{"0X00ff", []byte{0x00, 0xFF}},
+		{"0xAA55_AA55", []byte{0xAA, 0x55, 0xAA, 0x55}},
+		{" a5 5a ", []byte{0xA5, 0x5A}},
+	}
+	for _, test := range tests {
+		got, err := ParseHexBytes(test.value)
+		if err != nil || !bytes.Equal(got, test.expected) {
+			t.Errorf("ParseHexBytes(%q) = %x, %v; expected %x", test.value, got, err, test.expected)
+		}
+	}
+
+	errors := []struct {
+		value       string
+		expectedMsg string
+	}{
+		{"", "no hex digits"},
+		{"0x", "no hex digits"},
+		{"0xABC", "odd number"},
+		{"0xGG", "must be hex digits"},
+		{strings.Repeat("00", MaxPatternBytes+1), "at most"},
+	}
+	for _, test := range errors {
+		_, err := ParseHexBytes(test.value)
+		if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+			t.Errorf("ParseHexBytes(%.20q): expected error containing %q, got %v", test.value, test.expectedMsg, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultCompositeRegionSize is the size of the regions a composite pattern
+// interleaves.
+const DefaultCompositeRegionSize = 1024 * 1024
+
+// WeightedPattern is one pattern of a composite specification and its share
+// of the output.
+type WeightedPattern struct {
+	Pattern string
+	Weight  float64
+}
+
+// ParseComposite parses a weighted composite specification such as
+// "random:0.5,zero:0.3,text:0.2". ok is false if spec is not a composite
+// specification (it has no comma, or uses key=value options), in which case
+// it should be parsed with ParsePattern. Weights are relative and need not add
+// up to 1.
+func ParseComposite(spec string) (parts []WeightedPattern, ok bool, err error) {
+	spec = strings.TrimSpace(spec)
+	if !strings.Contains(spec, ",") || strings.Contains(spec, "=") {
+		return nil, false, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(spec, ",") {
+		name, rawWeight, found := strings.Cut(strings.TrimSpace(item), ":")
+		name = strings.TrimSpace(name)
+		if !found {
+			return nil, true, fmt.Errorf("composite pattern entry %q must be pattern:weight, e.g. random:0.5", item)
+		}
+		if _, known := patternOptions[name]; !known {
+			return nil, true, fmt.Errorf("unknown pattern in composite: %s", name)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(rawWeight), 64)
+		if err != nil || weight <= 0 {
+			return nil, true, fmt.Errorf("invalid weight %q for %s: must be a positive number", rawWeight, name)
+		}
+		if seen[name] {
+			return nil, true, fmt.Errorf("pattern %s given more than once in composite", name)
+		}
+		seen[name] = true
+		parts = append(parts, WeightedPattern{Pattern: name, Weight: weight})
+	}
+
+	return parts, true, nil
+}
+
+// CompositeGenerator interleaves several patterns in fixed-size regions.
+// Each region is assigned a pattern by weight, chosen from the seed and the
+// region index so the layout is the same whichever worker generates it.
+type CompositeGenerator struct {
+	generators []Generator
+	names      []string
+	cumulative []float64
+	regionSize int64
+	seed       uint64
+	offset     int64
+	mu         sync.Mutex
+}
+
+// NewCompositeGenerator creates a generator interleaving the given patterns.
+// opts configures every pattern; its seed also selects the region layout.
+func NewCompositeGenerator(parts []WeightedPattern, opts Options) (*CompositeGenerator, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("composite pattern needs at least one pattern")
+	}
+
+	regionSize := opts.CompositeRegionSize
+	if regionSize == 0 {
+		regionSize = DefaultCompositeRegionSize
+	}
+	if regionSize < 0 {
+		return nil, fmt.Errorf("composite region size must be positive, got %d", regionSize)
+	}
+
+	g := &CompositeGenerator{regionSize: int64(regionSize), seed: uint64(opts.seed())}
+
+	var total float64
+	for _, part := range parts {
+		total += part.Weight
+	}
+	var sum float64
+	for _, part := range parts {
+		gen, err := NewGeneratorWithOptions(part.Pattern, opts)
+		if err != nil {
+			return nil, fmt.Errorf("composite pattern %s: %v", part.Pattern, err)
+		}
+		sum += part.Weight
+		g.generators = append(g.generators, gen)
+		g.names = append(g.names, part.Pattern)
+		g.cumulative = append(g.cumulative, sum/total)
+	}
+
+	return g, nil
+}
+
+// Name returns the name of the generator.
+func (g *CompositeGenerator) Name() string {
+	return "composite"
+}
+
+// PatternAt returns the name of the pattern that fills the region holding
+// offset.
+func (g *CompositeGenerator) PatternAt(offset int64) string {
+	return g.names[g.regionPattern(offset/g.regionSize)]
+}
+
+// Generate fills the buffer with the next bytes of the output.
+func (g *CompositeGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+// GenerateAt fills the buffer with the output as it appears at offset, each
+// region coming from its pattern. The output is reproducible only if every
+// pattern's is.
+func (g *CompositeGenerator) GenerateAt(buffer []byte, offset int64) error {
+	for len(buffer) > 0 {
+		region := offset / g.regionSize
+		n := len(buffer)
+		if end := (region + 1) * g.regionSize; offset+int64(n) > end {
+			n = int(end - offset)
+		}
+
+		gen := g.generators[g.regionPattern(region)]
+		if err := GenerateChunk(gen, buffer[:n], offset); err != nil {
+			return err
+		}
+
+		buffer = buffer[n:]
+		offset += int64(n)
+	}
+	return nil
+}
+
+// regionPattern picks the pattern index for a region by weight.
+func (g *CompositeGenerator) regionPattern(region int64) int {
+	h := mix64(g.seed ^ mix64(uint64(region)))
+	u := float64(h>>11) / (1 << 53)
+	for i, c := range g.cumulative {
+		if u < c {
+			return i
+		}
+	}
+	return len(g.cumulative) - 1
+}
@@ -0,0 +1,115 @@
+package generator
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseComposite(t *testing.T) {
+	parts, ok, err := ParseComposite("random:0.5, zero:0.3,text:0.2")
+	if err != nil || !ok {
+		t.Fatalf("ParseComposite failed: ok=%v err=%v", ok, err)
+	}
+	expected := []WeightedPattern{{"random", 0.5}, {"zero", 0.3}, {"text", 0.2}}
+	if len(parts) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, parts)
+	}
+	for i := range expected {
+		if parts[i] != expected[i] {
+			t.Errorf("part %d: expected %v, got %v", i, expected[i], parts[i])
+		}
+	}
+
+	// Plain specifications are left to ParsePattern
+	for _, spec := range []string{"random", "random:seed=1", "mixed:ratio=70:30,chunk=4KB"} {
+		if _, ok, _ := ParseComposite(spec); ok {
+			t.Errorf("expected %q not to be a composite", spec)
+		}
+	}
+}
+
+func TestParseCompositeErrors(t *testing.T) {
+	tests := []struct {
+		spec        string
+		expectedMsg string
+	}{
+		{"random,zero", "must be pattern:weight"},
+		{"random:1,bogus:1", "unknown pattern"},
+		{"random:0,zero:1", "must be a positive number"},
+		{"random:x,zero:1", "must be a positive number"},
+		{"random:1,random:2", "more than once"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			_, ok, err := ParseComposite(test.spec)
+			if !ok || err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got ok=%v err=%v", test.expectedMsg, ok, err)
+			}
+		})
+	}
+}
+
+func TestCompositeGeneratorWeights(t *testing.T) {
+	parts := []WeightedPattern{{"sequential", 0.7}, {"zero", 0.3}}
+	g, err := NewCompositeGenerator(parts, Options{CompositeRegionSize: 4096})
+	if err != nil {
+		t.Fatalf("NewCompositeGenerator failed: %v", err)
+	}
+
+	const regions = 2000
+	data := make([]byte, regions*4096)
+	if err := g.Generate(data); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	zeros := 0
+	for r := 0; r < regions; r++ {
+		region := data[r*4096 : (r+1)*4096]
+		isZero := bytes.Equal(region, make([]byte, 4096))
+		if isZero != (g.PatternAt(int64(r*4096)) == "zero") {
+			t.Fatalf("region %d does not hold its pattern %s", r, g.PatternAt(int64(r*4096)))
+		}
+		if isZero {
+			zeros++
+		}
+	}
+
+	if share := float64(zeros) / regions; math.Abs(share-0.3) > 0.04 {
+		t.Errorf("expected about 30%% zero regions, got %.1f%%", share*100)
+	}
+}
+
+func TestCompositeGeneratorOffsets(t *testing.T) {
+	parts := []WeightedPattern{{"sequential", 1}, {"random", 1}, {"zero", 1}}
+	g, err := NewCompositeGenerator(parts, Options{Seed: 5, Seeded: true, CompositeRegionSize: 1000})
+	if err != nil {
+		t.Fatalf("NewCompositeGenerator failed: %v", err)
+	}
+
+	whole := make([]byte, 20000)
+	g.GenerateAt(whole, 0)
+	for _, split := range []int{1, 999, 1000, 7777} {
+		part := make([]byte, len(whole)-split)
+		g.GenerateAt(part, int64(split))
+		if !bytes.Equal(part, whole[split:]) {
+			t.Errorf("chunk at offset %d does not match the whole stream", split)
+		}
+	}
+}
+
+func TestNewGeneratorComposite(t *testing.T) {
+	gen, err := NewGenerator("random:1,zero:1")
+	if err != nil {
+		t.Fatalf("NewGenerator failed: %v", err)
+	}
+	if gen.Name() != "composite" {
+		t.Errorf("expected composite generator, got %s", gen.Name())
+	}
+
+	if _, err := NewGenerator("bytes:1,zero:1"); err == nil {
+		t.Error("expected error for a pattern that cannot be built from options")
+	}
+}
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	// DefaultCompressRatio is the compression ratio targeted by the
+	// compressible pattern when none is given.
+	DefaultCompressRatio = 2.0
+	// MaxCompressRatio is the highest supported ratio, at which each block
+	// still carries a few bytes of random data.
+	MaxCompressRatio = 1000.0
+
+	// compressBlockSize is the unit the target ratio is applied to. It matches
+	// the block size of typical inline compression in storage arrays.
+	compressBlockSize = 4096
+)
+
+// CompressibleGenerator generates data that compresses to a target ratio.
+// Each 4KB block starts with incompressible random bytes and is zero-filled
+// after them, so a block compresses to roughly its random share. The random
+// bytes come from a seeded stream keyed to the offset, making the output
+// reproducible from the seed.
+type CompressibleGenerator struct {
+	ratio  float64
+	random *SeededRandomGenerator
+	offset int64
+	mu     sync.Mutex
+}
+
+// NewCompressibleGenerator creates a generator targeting the given
+// compression ratio (uncompressed size / compressed size), using seed for
+// the random portion of each block.
+func NewCompressibleGenerator(ratio float64, seed int64) (*CompressibleGenerator, error) {
+	if ratio == 0 {
+		ratio = DefaultCompressRatio
+	}
+	if ratio < 1 || ratio > MaxCompressRatio || math.IsNaN(ratio) {
+		return nil, fmt.Errorf("compression ratio must be between 1 and %g, got %g", MaxCompressRatio, ratio)
+	}
+
+	return &CompressibleGenerator{
+		ratio:  ratio,
+		random: NewSeededRandomGenerator(seed),
+	}, nil
+}
+
+// Name returns the name of the generator.
+func (g *CompressibleGenerator) Name() string {
+	return "compressible"
+}
+
+// Ratio returns the targeted compression ratio.
+func (g *CompressibleGenerator) Ratio() float64 {
+	return g.ratio
+}
+
+// Generate fills the buffer with the next bytes of the stream.
+func (g *CompressibleGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+// GenerateAt fills the buffer with the stream as it appears at offset.
+func (g *CompressibleGenerator) GenerateAt(buffer []byte, offset int64) error {
+	if err := g.random.GenerateAt(buffer, offset); err != nil {
+		return err
+	}
+
+	randomLen := int64(math.Round(compressBlockSize / g.ratio))
+	for i := 0; i < len(buffer); {
+		// Position within the current 4KB block of the stream
+		within := (offset + int64(i)) % compressBlockSize
+		n := min(len(buffer)-i, int(compressBlockSize-within))
+
+		// Zero the part of this block past its random prefix
+		zeroFrom := max(randomLen-within, 0)
+		if zeroFrom < int64(n) {
+			clear(buffer[i+int(zeroFrom) : i+n])
+		}
+		i += n
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"bytes"
+	"compress/flate"
+	"testing"
+)
+
+// compressionRatio returns len(data) divided by its deflate-compressed size.
+func compressionRatio(t *testing.T, data []byte) float64 {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		t.Fatalf("failed to create compressor: %v", err)
+	}
+	w.Write(data)
+	w.Close()
+	return float64(len(data)) / float64(buf.Len())
+}
+
+func TestCompressibleGeneratorRatio(t *testing.T) {
+	for _, target := range []float64{1, 1.5, 2.5, 4, 10} {
+		g, err := NewCompressibleGenerator(target, 1)
+		if err != nil {
+			t.Fatalf("NewCompressibleGenerator(%g) failed: %v", target, err)
+		}
+
+		data := make([]byte, 1<<20)
+		if err := g.Generate(data); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+
+		got := compressionRatio(t, data)
+		if got < target*0.85 || got > target*1.15 {
+			t.Errorf("target ratio %g: compressed at %.2f", target, got)
+		}
+	}
+}
+
+func TestCompressibleGeneratorOffsets(t *testing.T) {
+	g, err := NewCompressibleGenerator(2.5, 99)
+	if err != nil {
+		t.Fatalf("NewCompressibleGenerator failed: %v", err)
+	}
+
+	whole := make([]byte, 20000)
+	g.GenerateAt(whole, 0)
+
+	// Chunks that split blocks at odd offsets must line up with the whole
+	for _, split := range []int{1, 1000, 4096, 5000, 12289} {
+		part := make([]byte, len(whole)-split)
+		g.GenerateAt(part, int64(split))
+		if !bytes.Equal(part, whole[split:]) {
+			t.Errorf("chunk at offset %d does not match the whole stream", split)
+		}
+	}
+
+	// Each block has a 1638-byte random prefix followed by zeros
+	if !bytes.Equal(whole[1638:4096], make([]byte, 4096-1638)) {
+		t.Error("expected the end of the first block to be zero")
+	}
+	if bytes.Equal(whole[:1638], make([]byte, 1638)) {
+		t.Error("expected the start of the first block to be random")
+	}
+}
+
+func TestCompressibleGeneratorInvalidRatio(t *testing.T) {
+	for _, ratio := range []float64{0.5, -1, MaxCompressRatio + 1} {
+		if _, err := NewCompressibleGenerator(ratio, 0); err == nil {
+			t.Errorf("expected error for ratio %g", ratio)
+		}
+	}
+
+	g, err := NewCompressibleGenerator(0, 0)
+	if err != nil || g.Ratio() != DefaultCompressRatio {
+		t.Errorf("expected default ratio %g, got %v (err %v)", DefaultCompressRatio, g, err)
+	}
+}
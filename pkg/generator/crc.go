@@ -0,0 +1,149 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"math/rand/v2"
+	"sync"
+)
+
+// crcSize is the size of the CRC ending each block of the crc pattern.
+const crcSize = 4
+
+// crcMagic marks the start of every block of the crc pattern.
+var crcMagic = []byte("TRSHCRC1")
+
+// crcTable is the Castagnoli polynomial, which CPUs compute in hardware.
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// CRCGenerator generates the crc pattern: every 4KB block starts with a
+// header holding its own file offset and a per-run ID, like the verify
+// pattern, and ends with a CRC-32C of the rest of the block. Each block can
+// be checked on its own without regenerating its payload, so damaged data is
+// told apart from intact data written to the wrong place.
+type CRCGenerator struct {
+	runID  uint64
+	offset int64
+	mu     sync.Mutex
+}
+
+// NewCRCGenerator creates a CRCGenerator stamping blocks with runID.
+func NewCRCGenerator(runID uint64) *CRCGenerator {
+	return &CRCGenerator{runID: runID}
+}
+
+// Name returns the name of the generator.
+func (g *CRCGenerator) Name() string {
+	return "crc"
+}
+
+// RunID returns the run ID stamped into every block.
+func (g *CRCGenerator) RunID() uint64 {
+	return g.runID
+}
+
+// Generate fills the buffer with the next blocks.
+func (g *CRCGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+// GenerateAt fills the buffer with the blocks as they appear at offset.
+func (g *CRCGenerator) GenerateAt(buffer []byte, offset int64) error {
+	generateBlocks(buffer, offset, g.fillBlock)
+	return nil
+}
+
+// fillBlock writes the block for the block-aligned offset into dst, which
+// must be StampBlockSize bytes long.
+func (g *CRCGenerator) fillBlock(dst []byte, offset int64) {
+	copy(dst, crcMagic)
+	binary.LittleEndian.PutUint64(dst[8:16], uint64(offset))
+	binary.LittleEndian.PutUint64(dst[16:24], g.runID)
+
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[0:8], g.runID)
+	binary.LittleEndian.PutUint64(key[8:16], uint64(offset))
+	copy(key[16:], "trasher-crc")
+	end := len(dst) - crcSize
+	rand.NewChaCha8(key).Read(dst[stampHeaderSize:end])
+	binary.LittleEndian.PutUint32(dst[end:], crc32.Checksum(dst[:end], crcTable))
+}
+
+// Check verifies data read from the file at offset, which must be a multiple
+// of StampBlockSize, and returns the blocks that don't match. Whole blocks
+// are checked against their own CRC and header; a short final block, as at
+// the end of a file, has no CRC and is compared with the block expected
+// there as far as it goes.
+func (g *CRCGenerator) Check(data []byte, offset int64) []StampMismatch {
+	var mismatches []StampMismatch
+	var expected []byte
+
+	for len(data) > 0 {
+		block := data[:min(len(data), StampBlockSize)]
+
+		if len(block) == StampBlockSize {
+			if m, ok := g.checkBlock(block, offset); !ok {
+				mismatches = append(mismatches, m)
+			}
+		} else {
+			if expected == nil {
+				expected = make([]byte, StampBlockSize)
+			}
+			g.fillBlock(expected, offset)
+			if !bytes.Equal(block, expected[:len(block)]) {
+				m := StampMismatch{Offset: offset, Problem: StampCorrupt}
+				if len(block) >= stampHeaderSize && !bytes.Equal(block[:8], crcMagic) {
+					m.Problem = StampMissing
+				}
+				mismatches = append(mismatches, m)
+			}
+		}
+
+		data = data[len(block):]
+		offset += int64(len(block))
+	}
+
+	return mismatches
+}
+
+// checkBlock checks one whole block read from offset.
+func (g *CRCGenerator) checkBlock(block []byte, offset int64) (StampMismatch, bool) {
+	m := StampMismatch{Offset: offset}
+	foundOffset, foundRunID, ok := ReadCRCStamp(block)
+	switch {
+	case !bytes.Equal(block[:8], crcMagic):
+		m.Problem = StampMissing
+	case !ok:
+		m.Problem = StampCorrupt
+	case foundOffset != offset:
+		m.Problem = StampMisdirected
+	case foundRunID != g.runID:
+		m.Problem = StampStale
+	default:
+		return m, true
+	}
+	m.FoundOffset, m.FoundRunID = foundOffset, foundRunID
+	return m, false
+}
+
+// ReadCRCStamp reads the header of a whole block of the crc pattern,
+// returning the offset and run ID it records. ok is false if the block
+// isn't one or its CRC doesn't match, so the header can't be trusted.
+func ReadCRCStamp(block []byte) (offset int64, runID uint64, ok bool) {
+	if len(block) != StampBlockSize || !bytes.Equal(block[:8], crcMagic) {
+		return 0, 0, false
+	}
+	end := len(block) - crcSize
+	if crc32.Checksum(block[:end], crcTable) != binary.LittleEndian.Uint32(block[end:]) {
+		return 0, 0, false
+	}
+	offset = int64(binary.LittleEndian.Uint64(block[8:16]))
+	runID = binary.LittleEndian.Uint64(block[16:24])
+	return offset, runID, true
+}
@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+func TestCRCGeneratorBlocks(t *testing.T) {
+	g := NewCRCGenerator(0xbeef)
+
+	data := make([]byte, 3*StampBlockSize+100)
+	if err := g.Generate(data); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		block := data[i*StampBlockSize : (i+1)*StampBlockSize]
+		offset, runID, ok := ReadCRCStamp(block)
+		if !ok || offset != int64(i*StampBlockSize) || runID != 0xbeef {
+			t.Errorf("block %d: expected header for offset %d, got %d/%x (ok=%v)", i, i*StampBlockSize, offset, runID, ok)
+		}
+		end := StampBlockSize - crcSize
+		if crc := crc32.Checksum(block[:end], crc32.MakeTable(crc32.Castagnoli)); crc != binary.LittleEndian.Uint32(block[end:]) {
+			t.Errorf("block %d: CRC-32C does not match", i)
+		}
+	}
+
+	// Chunks starting mid-block line up with the whole
+	for _, split := range []int{1, 24, 4092, StampBlockSize, 2*StampBlockSize + 7} {
+		part := make([]byte, len(data)-split)
+		g.GenerateAt(part, int64(split))
+		if !bytes.Equal(part, data[split:]) {
+			t.Errorf("chunk at offset %d does not match the whole stream", split)
+		}
+	}
+}
+
+func TestCRCGeneratorCheck(t *testing.T) {
+	g := NewCRCGenerator(7)
+	data := make([]byte, 7*StampBlockSize+1000)
+	g.GenerateAt(data, 0)
+
+	if mismatches := g.Check(data, 0); len(mismatches) != 0 {
+		t.Fatalf("expected clean data to verify, got %v", mismatches)
+	}
+
+	// Block 1: overwritten with zeros
+	clear(data[StampBlockSize : 2*StampBlockSize])
+	// Block 2: holds the intact block written for offset 4
+	copy(data[2*StampBlockSize:3*StampBlockSize], data[4*StampBlockSize:5*StampBlockSize])
+	// Block 3: left over from another run
+	NewCRCGenerator(8).GenerateAt(data[3*StampBlockSize:4*StampBlockSize], 3*StampBlockSize)
+	// Block 5: payload bit flip
+	data[5*StampBlockSize+100] ^= 1
+	// Block 6: the offset in its header is damaged, so it can't be trusted
+	data[6*StampBlockSize+9] ^= 1
+	// Short final block: corrupted past the header
+	data[len(data)-1] ^= 1
+
+	mismatches := g.Check(data, 0)
+	expected := []StampMismatch{
+		{Offset: 1 * StampBlockSize, Problem: StampMissing},
+		{Offset: 2 * StampBlockSize, Problem: StampMisdirected, FoundOffset: 4 * StampBlockSize, FoundRunID: 7},
+		{Offset: 3 * StampBlockSize, Problem: StampStale, FoundOffset: 3 * StampBlockSize, FoundRunID: 8},
+		{Offset: 5 * StampBlockSize, Problem: StampCorrupt},
+		{Offset: 6 * StampBlockSize, Problem: StampCorrupt},
+		{Offset: 7 * StampBlockSize, Problem: StampCorrupt},
+	}
+	if len(mismatches) != len(expected) {
+		t.Fatalf("expected %d mismatches, got %v", len(expected), mismatches)
+	}
+	for i := range expected {
+		if mismatches[i] != expected[i] {
+			t.Errorf("mismatch %d: expected %+v, got %+v", i, expected[i], mismatches[i])
+		}
+	}
+}
+
+func TestReadCRCStamp(t *testing.T) {
+	if _, _, ok := ReadCRCStamp(make([]byte, StampBlockSize)); ok {
+		t.Error("expected zeros to have no header")
+	}
+
+	block := make([]byte, StampBlockSize)
+	NewCRCGenerator(1).GenerateAt(block, 0)
+	if _, _, ok := ReadCRCStamp(block[:100]); ok {
+		t.Error("expected a short block to have no trusted header")
+	}
+	block[StampBlockSize-1] ^= 1
+	if _, _, ok := ReadCRCStamp(block); ok {
+		t.Error("expected a block with a bad CRC to have no trusted header")
+	}
+}
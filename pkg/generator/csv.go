@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CSVGenerator generates comma-separated values: a header row naming the
+// fields of a schema, then one row per record. Rows never cross chunk
+// boundaries; leftover space at the end of a chunk is filled with blank lines,
+// which CSV readers skip. Each chunk is derived from the seed and the chunk
+// offset so it can be regenerated.
+type CSVGenerator struct {
+	fields []Field
+	seed   int64
+	offset int64
+	mu     sync.Mutex
+}
+
+// NewCSVGenerator creates a generator emitting rows with the given fields as
+// columns. A nil schema selects DefaultSchema.
+func NewCSVGenerator(fields []Field, seed int64) (*CSVGenerator, error) {
+	if fields == nil {
+		var err error
+		if fields, err = ParseSchema(DefaultSchema); err != nil {
+			return nil, err
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("schema must have at least one field")
+	}
+
+	return &CSVGenerator{fields: fields, seed: seed}, nil
+}
+
+// Name returns the name of the generator.
+func (g *CSVGenerator) Name() string {
+	return "csv"
+}
+
+// Fields returns the columns of each row.
+func (g *CSVGenerator) Fields() []Field {
+	return g.fields
+}
+
+// Generate fills the buffer with the next chunk of rows.
+func (g *CSVGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+func (g *CSVGenerator) recordChunks() {}
+
+// GenerateAt fills the buffer with the chunk of rows starting at offset. The
+// chunk at offset 0 starts with the header row.
+func (g *CSVGenerator) GenerateAt(buffer []byte, offset int64) error {
+	rng := recordRand(g.seed, offset, "trasher-csv")
+	fillRecords(buffer, offset, padBlankLines, func(dst []byte, offset int64) []byte {
+		for i, field := range g.fields {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			// Generated values never contain commas, quotes or newlines
+			if offset == 0 {
+				dst = append(dst, field.Name...)
+			} else {
+				dst = appendValue(dst, field.Type, offset, rng)
+			}
+		}
+		return append(dst, '\n')
+	})
+	return nil
+}
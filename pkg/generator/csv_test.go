@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVGeneratorRows(t *testing.T) {
+	fields, err := ParseSchema("id,ts:timestamp,name:string,count:int,score:float,ok:bool")
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+	g, err := NewCSVGenerator(fields, 2)
+	if err != nil {
+		t.Fatalf("NewCSVGenerator failed: %v", err)
+	}
+
+	// Chunks of odd sizes concatenate into a well-formed file
+	var file []byte
+	for _, size := range []int{3000, 501, 9999, 20} {
+		chunk := make([]byte, size)
+		if err := g.Generate(chunk); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		file = append(file, chunk...)
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(file)).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	if len(rows) < 100 {
+		t.Fatalf("expected many rows, got %d", len(rows))
+	}
+
+	header := "id,ts,name,count,score,ok"
+	if got := strings.Join(rows[0], ","); got != header {
+		t.Errorf("expected header %q, got %q", header, got)
+	}
+
+	lastID := int64(0)
+	for i, row := range rows[1:] {
+		id, err := strconv.ParseInt(row[0], 10, 64)
+		if err != nil || id <= lastID {
+			t.Fatalf("row %d: expected increasing id, got %q", i, row[0])
+		}
+		if _, err := time.Parse(time.RFC3339Nano, row[1]); err != nil {
+			t.Errorf("row %d: invalid timestamp %q", i, row[1])
+		}
+		if _, err := strconv.ParseInt(row[3], 10, 64); err != nil {
+			t.Errorf("row %d: invalid int %q", i, row[3])
+		}
+		if _, err := strconv.ParseFloat(row[4], 64); err != nil {
+			t.Errorf("row %d: invalid float %q", i, row[4])
+		}
+		if _, err := strconv.ParseBool(row[5]); err != nil {
+			t.Errorf("row %d: invalid bool %q", i, row[5])
+		}
+		lastID = id
+	}
+}
+
+func TestCSVGeneratorHeaderOnlyAtStart(t *testing.T) {
+	g, err := NewCSVGenerator(nil, 0)
+	if err != nil {
+		t.Fatalf("NewCSVGenerator failed: %v", err)
+	}
+
+	chunk := make([]byte, 1000)
+	g.GenerateAt(chunk, 1000)
+	if bytes.Contains(chunk, []byte("message")) {
+		t.Error("expected no header row in a chunk past the start of the file")
+	}
+
+	g.GenerateAt(chunk, 0)
+	if !bytes.HasPrefix(chunk, []byte("id,timestamp,message\n")) {
+		t.Errorf("expected the first chunk to start with the header, got %q", chunk[:40])
+	}
+}
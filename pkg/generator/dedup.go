@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+)
+
+const (
+	// DefaultDedupRatio is the fraction of duplicate blocks the dedup
+	// pattern produces when none is given.
+	DefaultDedupRatio = 0.5
+	// DefaultDedupBlockSize is the default dedup block size, matching the
+	// block size most deduplicating backends fingerprint.
+	DefaultDedupBlockSize = 4096
+	// MinDedupBlockSize and MaxDedupBlockSize bound the dedup block size.
+	MinDedupBlockSize = 512
+	MaxDedupBlockSize = 64 * 1024 * 1024
+
+	// dedupTemplates is how many distinct blocks duplicates are drawn from.
+	dedupTemplates = 64
+)
+
+// DedupGenerator generates data with a controlled share of duplicate blocks.
+// The output is split into aligned blocks; each block is either unique random
+// data or a copy of one of a small set of template blocks, chosen from the
+// seed and the block index so the output is reproducible.
+type DedupGenerator struct {
+	ratio     float64
+	blockSize int
+	seed      int64
+	unique    *SeededRandomGenerator
+	offset    int64
+	mu        sync.Mutex
+}
+
+// NewDedupGenerator creates a generator where roughly ratio of all blocks of
+// blockSize bytes are duplicates. Zero values select the defaults.
+func NewDedupGenerator(ratio float64, blockSize int, seed int64) (*DedupGenerator, error) {
+	if ratio == 0 {
+		ratio = DefaultDedupRatio
+	}
+	if blockSize == 0 {
+		blockSize = DefaultDedupBlockSize
+	}
+	if ratio < 0 || ratio > 1 || math.IsNaN(ratio) {
+		return nil, fmt.Errorf("dedup ratio must be between 0 and 1, got %g", ratio)
+	}
+	if blockSize < MinDedupBlockSize || blockSize > MaxDedupBlockSize {
+		return nil, fmt.Errorf("dedup block size must be between %d and %d bytes, got %d",
+			MinDedupBlockSize, MaxDedupBlockSize, blockSize)
+	}
+
+	return &DedupGenerator{
+		ratio:     ratio,
+		blockSize: blockSize,
+		seed:      seed,
+		unique:    NewSeededRandomGenerator(seed),
+	}, nil
+}
+
+// Name returns the name of the generator.
+func (g *DedupGenerator) Name() string {
+	return "dedup"
+}
+
+// Ratio returns the targeted fraction of duplicate blocks.
+func (g *DedupGenerator) Ratio() float64 {
+	return g.ratio
+}
+
+// BlockSize returns the dedup block size in bytes.
+func (g *DedupGenerator) BlockSize() int {
+	return g.blockSize
+}
+
+// Generate fills the buffer with the next bytes of the stream.
+func (g *DedupGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+// GenerateAt fills the buffer with the stream as it appears at offset.
+func (g *DedupGenerator) GenerateAt(buffer []byte, offset int64) error {
+	// Start from unique data and overwrite the blocks that are duplicates
+	if err := g.unique.GenerateAt(buffer, offset); err != nil {
+		return err
+	}
+
+	blockSize := int64(g.blockSize)
+	var template []byte
+	for i := 0; i < len(buffer); {
+		pos := offset + int64(i)
+		block := pos / blockSize
+		within := int(pos % blockSize)
+		n := min(len(buffer)-i, g.blockSize-within)
+
+		if id, dup := g.duplicateOf(block); dup {
+			if template == nil {
+				template = make([]byte, g.blockSize)
+			}
+			g.fillTemplate(template, id)
+			copy(buffer[i:i+n], template[within:within+n])
+		}
+		i += n
+	}
+
+	return nil
+}
+
+// duplicateOf decides whether a block is a duplicate and, if so, which
+// template it copies.
+func (g *DedupGenerator) duplicateOf(block int64) (int, bool) {
+	h := mix64(uint64(g.seed) ^ mix64(uint64(block)))
+	if float64(h>>11)/(1<<53) >= g.ratio {
+		return 0, false
+	}
+	return int(mix64(h) % dedupTemplates), true
+}
+
+// fillTemplate fills buffer with the content of a template block.
+func (g *DedupGenerator) fillTemplate(buffer []byte, id int) {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[0:8], uint64(g.seed))
+	binary.LittleEndian.PutUint64(key[8:16], uint64(id))
+	copy(key[16:], "trasher-dedup")
+	rand.NewChaCha8(key).Read(buffer)
+}
+
+// mix64 is the SplitMix64 finalizer, used to turn block indexes into
+// well-distributed pseudo-random decisions.
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+// duplicateShare returns the fraction of blockSize blocks in data that repeat
+// an earlier block.
+func duplicateShare(data []byte, blockSize int) float64 {
+	seen := make(map[[32]byte]bool)
+	duplicates, blocks := 0, 0
+	for i := 0; i+blockSize <= len(data); i += blockSize {
+		sum := sha256.Sum256(data[i : i+blockSize])
+		if seen[sum] {
+			duplicates++
+		}
+		seen[sum] = true
+		blocks++
+	}
+	return float64(duplicates) / float64(blocks)
+}
+
+func TestDedupGeneratorRatio(t *testing.T) {
+	for _, test := range []struct {
+		ratio     float64
+		blockSize int
+	}{
+		{0.1, 4096},
+		{0.3, 4096},
+		{0.5, 8192},
+		{0.9, 512},
+		{1, 4096},
+	} {
+		g, err := NewDedupGenerator(test.ratio, test.blockSize, 5)
+		if err != nil {
+			t.Fatalf("NewDedupGenerator(%g, %d) failed: %v", test.ratio, test.blockSize, err)
+		}
+
+		data := make([]byte, 4096*test.blockSize)
+		if err := g.Generate(data); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+
+		// Only the first copy of each template counts as unique
+		got := duplicateShare(data, test.blockSize)
+		if got < test.ratio-0.05 || got > test.ratio+0.05 {
+			t.Errorf("ratio %g, block %d: %.3f of blocks are duplicates", test.ratio, test.blockSize, got)
+		}
+	}
+}
+
+func TestDedupGeneratorOffsets(t *testing.T) {
+	g, err := NewDedupGenerator(0.5, 1024, 11)
+	if err != nil {
+		t.Fatalf("NewDedupGenerator failed: %v", err)
+	}
+
+	whole := make([]byte, 50000)
+	g.GenerateAt(whole, 0)
+
+	// Chunks that split blocks at odd offsets must line up with the whole
+	for _, split := range []int{1, 777, 1024, 3000, 40961} {
+		part := make([]byte, len(whole)-split)
+		g.GenerateAt(part, int64(split))
+		if !bytes.Equal(part, whole[split:]) {
+			t.Errorf("chunk at offset %d does not match the whole stream", split)
+		}
+	}
+
+	// The same seed reproduces the stream; another seed does not
+	again, _ := NewDedupGenerator(0.5, 1024, 11)
+	other, _ := NewDedupGenerator(0.5, 1024, 12)
+	same := make([]byte, len(whole))
+	diff := make([]byte, len(whole))
+	again.Generate(same)
+	other.Generate(diff)
+	if !bytes.Equal(same, whole) {
+		t.Error("expected the same seed to reproduce the stream")
+	}
+	if bytes.Equal(diff, whole) {
+		t.Error("expected a different seed to produce a different stream")
+	}
+}
+
+func TestDedupGeneratorInvalidArgs(t *testing.T) {
+	for _, ratio := range []float64{-0.5, 1.5} {
+		if _, err := NewDedupGenerator(ratio, 0, 0); err == nil {
+			t.Errorf("expected error for ratio %g", ratio)
+		}
+	}
+	for _, blockSize := range []int{-1, MinDedupBlockSize - 1, MaxDedupBlockSize + 1} {
+		if _, err := NewDedupGenerator(0.5, blockSize, 0); err == nil {
+			t.Errorf("expected error for block size %d", blockSize)
+		}
+	}
+
+	g, err := NewDedupGenerator(0, 0, 0)
+	if err != nil || g.Ratio() != DefaultDedupRatio || g.BlockSize() != DefaultDedupBlockSize {
+		t.Errorf("expected defaults %g/%d, got %v (err %v)", DefaultDedupRatio, DefaultDedupBlockSize, g, err)
+	}
+}
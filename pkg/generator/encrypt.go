@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// EncryptCiphers lists the ciphers output can be encrypted with.
+var EncryptCiphers = []string{"aes-ctr"}
+
+// EncryptGenerator encrypts another generator's output with AES in counter
+// mode, so generated files are ciphertext. The IV is zero and the counter
+// advances with the file offset, so any chunk can be encrypted on its own and
+// the whole file decrypts as a single AES-CTR stream.
+type EncryptGenerator struct {
+	inner  Generator
+	block  cipher.Block
+	key    []byte
+	offset int64
+	mu     sync.Mutex
+}
+
+// NewEncryptGenerator wraps inner so its output is encrypted with the named
+// cipher under key, which must be 16, 24 or 32 bytes for AES-128, AES-192 or
+// AES-256.
+func NewEncryptGenerator(cipherName string, key []byte, inner Generator) (*EncryptGenerator, error) {
+	if cipherName != "aes-ctr" {
+		return nil, fmt.Errorf("unknown cipher '%s' (available: %s)", cipherName, strings.Join(EncryptCiphers, ", "))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %v", err)
+	}
+	return &EncryptGenerator{inner: inner, block: block, key: key}, nil
+}
+
+// ParseEncryptKey decodes a hex AES key, with or without a 0x prefix.
+func ParseEncryptKey(s string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %v", err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("encryption key must be 16, 24 or 32 bytes, got %d", len(key))
+	}
+}
+
+// KeyFingerprint identifies key by the first 8 bytes of its SHA-256 in hex,
+// so a key can be checked against a recorded one without recording the key.
+func KeyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// NewEncryptKey returns a random AES-256 key.
+func NewEncryptKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Name returns the name of the wrapped generator.
+func (g *EncryptGenerator) Name() string {
+	return g.inner.Name()
+}
+
+// Key returns the encryption key.
+func (g *EncryptGenerator) Key() []byte {
+	return g.key
+}
+
+// Generate fills the buffer with the next bytes of the output.
+func (g *EncryptGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+// GenerateAt fills the buffer with the wrapped generator's data for offset,
+// encrypted with the keystream at that offset.
+func (g *EncryptGenerator) GenerateAt(buffer []byte, offset int64) error {
+	if err := GenerateChunk(g.inner, buffer, offset); err != nil {
+		return err
+	}
+
+	var iv [aes.BlockSize]byte
+	binary.BigEndian.PutUint64(iv[8:], uint64(offset/aes.BlockSize))
+	stream := cipher.NewCTR(g.block, iv[:])
+
+	// Discard the keystream before offset within its block
+	var skip [aes.BlockSize]byte
+	stream.XORKeyStream(skip[:offset%aes.BlockSize], skip[:offset%aes.BlockSize])
+	stream.XORKeyStream(buffer, buffer)
+	return nil
+}
@@ -0,0 +1,109 @@
+package generator
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"strings"
+	"testing"
+)
+
+func TestEncryptGenerator(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	g, err := NewEncryptGenerator("aes-ctr", key, &SequentialGenerator{})
+	if err != nil {
+		t.Fatalf("NewEncryptGenerator failed: %v", err)
+	}
+	if g.Name() != "sequential" {
+		t.Errorf("expected the wrapped generator's name, got %s", g.Name())
+	}
+
+	// Chunks at unaligned offsets, generated out of order, must form one
+	// AES-CTR stream with a zero IV
+	whole := make([]byte, 1000)
+	for _, chunk := range [][2]int{{333, 1000}, {7, 333}, {0, 7}} {
+		if err := g.GenerateAt(whole[chunk[0]:chunk[1]], int64(chunk[0])); err != nil {
+			t.Fatalf("GenerateAt(%d) failed: %v", chunk[0], err)
+		}
+	}
+
+	plain := make([]byte, 1000)
+	(&SequentialGenerator{}).GenerateAt(plain, 0)
+	if bytes.Equal(whole, plain) {
+		t.Fatal("output was not encrypted")
+	}
+
+	block, _ := aes.NewCipher(key)
+	decrypted := make([]byte, 1000)
+	cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(decrypted, whole)
+	if !bytes.Equal(decrypted, plain) {
+		t.Error("output does not decrypt to the wrapped generator's data")
+	}
+
+	// Generate continues from the previous call's offset
+	first, second := make([]byte, 500), make([]byte, 500)
+	g.Generate(first)
+	g.Generate(second)
+	if !bytes.Equal(append(first, second...), whole) {
+		t.Error("sequential generation does not match offset generation")
+	}
+}
+
+func TestEncryptGeneratorErrors(t *testing.T) {
+	if _, err := NewEncryptGenerator("age", make([]byte, 32), &ZeroGenerator{}); err == nil {
+		t.Error("expected error for an unknown cipher")
+	}
+	if _, err := NewEncryptGenerator("aes-ctr", make([]byte, 10), &ZeroGenerator{}); err == nil {
+		t.Error("expected error for an invalid key length")
+	}
+}
+
+func TestParseEncryptKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		length int
+	}{
+		{"000102030405060708090a0b0c0d0e0f", 16},
+		{"0x000102030405060708090A0B0C0D0E0F1011121314151617", 24},
+		{"000102030405060708090a0b0c0d0e0f000102030405060708090a0b0c0d0e0f", 32},
+		{"0001", 0},
+		{"not hex", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			key, err := ParseEncryptKey(test.key)
+			if test.length == 0 {
+				if err == nil {
+					t.Errorf("expected error for %q", test.key)
+				}
+				return
+			}
+			if err != nil || len(key) != test.length {
+				t.Errorf("expected a %d-byte key, got %d (%v)", test.length, len(key), err)
+			}
+		})
+	}
+
+	key, err := NewEncryptKey()
+	if err != nil || len(key) != 32 {
+		t.Errorf("expected a random 32-byte key, got %d (%v)", len(key), err)
+	}
+}
+
+func TestKeyFingerprint(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	fingerprint := KeyFingerprint(key)
+	if len(fingerprint) != 16 {
+		t.Errorf("expected 8 bytes in hex, got %q", fingerprint)
+	}
+	if strings.Contains(fingerprint, "4242") {
+		t.Errorf("expected the fingerprint not to reveal the key, got %q", fingerprint)
+	}
+	if KeyFingerprint(key) != fingerprint {
+		t.Error("expected the same key to give the same fingerprint")
+	}
+	if KeyFingerprint(bytes.Repeat([]byte{0x43}, 32)) == fingerprint {
+		t.Error("expected another key to give another fingerprint")
+	}
+}
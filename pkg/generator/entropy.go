@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	// DefaultEntropy is the normalized Shannon entropy targeted by the entropy
+	// pattern when none is given.
+	DefaultEntropy = 0.5
+
+	// entropyStep is how many bytes GenerateAt draws decisions for at once.
+	entropyStep = 64 * 1024
+)
+
+// EntropyGenerator generates data with a target Shannon entropy, normalized
+// so that 0 is a constant byte and 1 is uniformly random (8 bits per byte).
+// Each byte is forced to zero with a fixed probability and is random
+// otherwise; the probability is chosen so the byte distribution has the
+// target entropy. Both the random bytes and the decisions come from seeded
+// streams keyed to the offset, making the output reproducible from the seed.
+type EntropyGenerator struct {
+	entropy   float64
+	threshold uint32
+	values    *SeededRandomGenerator
+	decisions *SeededRandomGenerator
+	offset    int64
+	mu        sync.Mutex
+}
+
+// NewEntropyGenerator creates a generator targeting the given normalized
+// entropy in (0, 1]. Zero selects DefaultEntropy.
+func NewEntropyGenerator(entropy float64, seed int64) (*EntropyGenerator, error) {
+	if entropy == 0 {
+		entropy = DefaultEntropy
+	}
+	if entropy <= 0 || entropy > 1 || math.IsNaN(entropy) {
+		return nil, fmt.Errorf("entropy must be greater than 0 and at most 1, got %g", entropy)
+	}
+
+	// Decisions are 16-bit draws; a byte is zeroed when its draw is below threshold
+	p := zeroProbability(entropy)
+	return &EntropyGenerator{
+		entropy:   entropy,
+		threshold: uint32(math.Round(p * 65536)),
+		values:    NewSeededRandomGenerator(seed),
+		decisions: NewSeededRandomGenerator(int64(mix64(uint64(seed)))),
+	}, nil
+}
+
+// Name returns the name of the generator.
+func (g *EntropyGenerator) Name() string {
+	return "entropy"
+}
+
+// Entropy returns the targeted normalized entropy.
+func (g *EntropyGenerator) Entropy() float64 {
+	return g.entropy
+}
+
+// Generate fills the buffer with the next bytes of the stream.
+func (g *EntropyGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+// GenerateAt fills the buffer with the stream as it appears at offset.
+func (g *EntropyGenerator) GenerateAt(buffer []byte, offset int64) error {
+	if err := g.values.GenerateAt(buffer, offset); err != nil {
+		return err
+	}
+
+	// Each byte has a 2-byte decision at twice its offset in the decision stream
+	decisions := make([]byte, 2*min(len(buffer), entropyStep))
+	for i := 0; i < len(buffer); i += entropyStep {
+		n := min(len(buffer)-i, entropyStep)
+		if err := g.decisions.GenerateAt(decisions[:2*n], 2*(offset+int64(i))); err != nil {
+			return err
+		}
+		for j := 0; j < n; j++ {
+			if uint32(binary.LittleEndian.Uint16(decisions[2*j:])) < g.threshold {
+				buffer[i+j] = 0
+			}
+		}
+	}
+
+	return nil
+}
+
+// zeroProbability returns the probability p with which a byte must be forced
+// to zero, the rest being uniformly random, for the byte distribution to have
+// the given normalized entropy.
+func zeroProbability(entropy float64) float64 {
+	// byteEntropy decreases monotonically in p, so bisect for the target
+	lo, hi := 0.0, 1.0
+	for range 64 {
+		mid := (lo + hi) / 2
+		if byteEntropy(mid) > entropy {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// byteEntropy returns the normalized entropy of bytes that are zero with
+// probability p and uniformly random otherwise.
+func byteEntropy(p float64) float64 {
+	other := (1 - p) / 256
+	zero := p + other
+
+	h := -zero * math.Log2(zero)
+	if other > 0 {
+		h -= 255 * other * math.Log2(other)
+	}
+	return h / 8
+}
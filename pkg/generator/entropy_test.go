@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// measuredEntropy returns the normalized Shannon entropy of the byte
+// histogram of data.
+func measuredEntropy(data []byte) float64 {
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	var h float64
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / float64(len(data))
+			h -= p * math.Log2(p)
+		}
+	}
+	return h / 8
+}
+
+func TestEntropyGeneratorTarget(t *testing.T) {
+	for _, target := range []float64{0.05, 0.25, 0.5, 0.6, 0.9, 1} {
+		g, err := NewEntropyGenerator(target, 3)
+		if err != nil {
+			t.Fatalf("NewEntropyGenerator(%g) failed: %v", target, err)
+		}
+
+		data := make([]byte, 1<<20)
+		if err := g.Generate(data); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+
+		if got := measuredEntropy(data); math.Abs(got-target) > 0.01 {
+			t.Errorf("target entropy %g: measured %.4f", target, got)
+		}
+	}
+}
+
+func TestEntropyGeneratorOffsets(t *testing.T) {
+	g, err := NewEntropyGenerator(0.6, 21)
+	if err != nil {
+		t.Fatalf("NewEntropyGenerator failed: %v", err)
+	}
+
+	whole := make([]byte, 3*entropyStep+100)
+	g.GenerateAt(whole, 0)
+
+	// Chunks starting at odd offsets and spanning steps must line up
+	for _, split := range []int{1, 999, entropyStep, entropyStep + 7, 2*entropyStep + 50} {
+		part := make([]byte, len(whole)-split)
+		g.GenerateAt(part, int64(split))
+		if !bytes.Equal(part, whole[split:]) {
+			t.Errorf("chunk at offset %d does not match the whole stream", split)
+		}
+	}
+}
+
+func TestEntropyGeneratorInvalid(t *testing.T) {
+	for _, entropy := range []float64{-0.1, 1.01, math.NaN()} {
+		if _, err := NewEntropyGenerator(entropy, 0); err == nil {
+			t.Errorf("expected error for entropy %g", entropy)
+		}
+	}
+
+	g, err := NewEntropyGenerator(0, 0)
+	if err != nil || g.Entropy() != DefaultEntropy {
+		t.Errorf("expected default entropy %g, got %v (err %v)", DefaultEntropy, g, err)
+	}
+}
+
+func TestByteEntropyBounds(t *testing.T) {
+	if h := byteEntropy(0); math.Abs(h-1) > 1e-9 {
+		t.Errorf("expected uniform bytes to have entropy 1, got %f", h)
+	}
+	if h := byteEntropy(1); h != 0 {
+		t.Errorf("expected all-zero bytes to have entropy 0, got %f", h)
+	}
+}
@@ -0,0 +1,130 @@
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// execStopTimeout is how long a generator command gets to exit after its
+// input is closed before it is killed.
+const execStopTimeout = 5 * time.Second
+
+// ExecGenerator pulls data from an external generator command, so teams can
+// plug in proprietary generators without changing trasher. For every chunk
+// the command reads a request line "<offset> <length>\n" on stdin and must
+// answer with exactly length bytes on stdout. Requests are sent one at a
+// time; stderr is passed through.
+type ExecGenerator struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	offset int64
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewExecGenerator starts the generator command, given as its program and
+// arguments.
+func NewExecGenerator(command []string) (*ExecGenerator, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("the exec pattern needs a command, e.g. --generator-cmd ./mygen")
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to generator command: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to generator command: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start generator command: %v", err)
+	}
+
+	return &ExecGenerator{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReaderSize(stdout, 1024*1024),
+	}, nil
+}
+
+// ParseCommand splits a generator command line into its program and
+// arguments at whitespace. Quoting is not supported; wrap complex commands
+// in a script.
+func ParseCommand(command string) []string {
+	return strings.Fields(command)
+}
+
+// Name returns the name of the generator.
+func (g *ExecGenerator) Name() string {
+	return "exec"
+}
+
+// Generate fills the buffer with the next bytes of the file.
+func (g *ExecGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+// GenerateAt requests the data at offset from the command and reads it into
+// the buffer.
+func (g *ExecGenerator) GenerateAt(buffer []byte, offset int64) error {
+	if len(buffer) == 0 {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return fmt.Errorf("generator command is closed")
+	}
+	if _, err := fmt.Fprintf(g.stdin, "%d %d\n", offset, len(buffer)); err != nil {
+		return fmt.Errorf("failed to send request to generator command: %v", err)
+	}
+	if n, err := io.ReadFull(g.stdout, buffer); err != nil {
+		return fmt.Errorf("generator command returned %d of %d bytes at offset %d: %v", n, len(buffer), offset, err)
+	}
+	return nil
+}
+
+// Close closes the command's input and waits for it to exit, killing it if
+// it does not exit in time.
+func (g *ExecGenerator) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+	g.stdin.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- g.cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("generator command failed: %v", err)
+		}
+		return nil
+	case <-time.After(execStopTimeout):
+		g.cmd.Process.Kill()
+		<-done
+		return fmt.Errorf("generator command did not exit within %v and was killed", execStopTimeout)
+	}
+}
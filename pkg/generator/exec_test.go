@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestExecHelperProcess is not a real test: it is the generator command run
+// by the exec tests. It answers each request with the low byte of every
+// offset, or with a short reply if asked to misbehave.
+func TestExecHelperProcess(t *testing.T) {
+	mode := os.Getenv("TRASHER_EXEC_HELPER")
+	if mode == "" {
+		return
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	out := bufio.NewWriter(os.Stdout)
+	for in.Scan() {
+		var offset, length int64
+		if _, err := fmt.Sscanf(in.Text(), "%d %d", &offset, &length); err != nil {
+			os.Exit(2)
+		}
+		if mode == "short" {
+			length /= 2
+		}
+		for i := int64(0); i < length; i++ {
+			out.WriteByte(byte(offset + i))
+		}
+		out.Flush()
+		if mode == "short" {
+			break
+		}
+	}
+	os.Exit(0)
+}
+
+// helperCommand returns a command running TestExecHelperProcess in mode.
+func helperCommand(t *testing.T, mode string) []string {
+	t.Setenv("TRASHER_EXEC_HELPER", mode)
+	return []string{os.Args[0], "-test.run=^TestExecHelperProcess$"}
+}
+
+func TestExecGenerator(t *testing.T) {
+	g, err := NewExecGenerator(helperCommand(t, "offsets"))
+	if err != nil {
+		t.Fatalf("NewExecGenerator failed: %v", err)
+	}
+
+	buffer := make([]byte, 1000)
+	if err := g.GenerateAt(buffer, 300); err != nil {
+		t.Fatalf("GenerateAt failed: %v", err)
+	}
+	for i, b := range buffer {
+		if b != byte(300+i) {
+			t.Fatalf("byte %d: expected %#x, got %#x", i, byte(300+i), b)
+		}
+	}
+
+	// Generate continues from its own offset
+	g.Generate(buffer[:10])
+	g.Generate(buffer[10:20])
+	if !bytes.Equal(buffer[:20], []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}) {
+		t.Errorf("expected the stream from offset 0, got %v", buffer[:20])
+	}
+
+	if err := g.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := g.GenerateAt(buffer, 0); err == nil {
+		t.Error("expected error generating after close")
+	}
+}
+
+func TestExecGeneratorShortReply(t *testing.T) {
+	g, err := NewExecGenerator(helperCommand(t, "short"))
+	if err != nil {
+		t.Fatalf("NewExecGenerator failed: %v", err)
+	}
+	defer g.Close()
+
+	err = g.GenerateAt(make([]byte, 100), 0)
+	if err == nil || !strings.Contains(err.Error(), "returned 50 of 100 bytes") {
+		t.Errorf("expected a short reply error, got %v", err)
+	}
+}
+
+func TestExecGeneratorInvalid(t *testing.T) {
+	if _, err := NewExecGenerator(nil); err == nil {
+		t.Error("expected error for an empty command")
+	}
+	if _, err := NewExecGenerator([]string{"/nonexistent/trasher-generator"}); err == nil {
+		t.Error("expected error for a missing command")
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	args := ParseCommand("  ./gen --seed 4\t--fast ")
+	expected := []string{"./gen", "--seed", "4", "--fast"}
+	if strings.Join(args, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+}
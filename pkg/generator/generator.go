@@ -12,6 +12,14 @@ type Generator interface {
 	Name() string
 }
 
+// OffsetGenerator is implemented by generators whose output depends only on
+// the file offset being generated. Any chunk of their output can be
+// regenerated on demand, which makes files built from them repairable.
+type OffsetGenerator interface {
+	Generator
+	GenerateAt(buffer []byte, offset int64) error
+}
+
 // RandomGenerator generates cryptographically secure random data.
 type RandomGenerator struct{}
 
@@ -50,6 +58,16 @@ func (g *SequentialGenerator) Generate(buffer []byte) error {
 	return nil
 }
 
+// GenerateAt fills the buffer with the sequential pattern as it appears at
+// the given file offset, so chunks generated in any order line up.
+func (g *SequentialGenerator) GenerateAt(buffer []byte, offset int64) error {
+	start := int(offset % 256)
+	for i := range buffer {
+		buffer[i] = uint8((start + i) % 256)
+	}
+	return nil
+}
+
 // ZeroGenerator fills the buffer with zeros.
 type ZeroGenerator struct{}
 
@@ -66,13 +84,24 @@ func (g *ZeroGenerator) Generate(buffer []byte) error {
 	return nil
 }
 
+// GenerateAt fills the buffer with zeros; the offset does not matter.
+func (g *ZeroGenerator) GenerateAt(buffer []byte, offset int64) error {
+	return g.Generate(buffer)
+}
+
+// MaxMixedChunkSize is the longest run of random or zero data the mixed
+// pattern supports.
+const MaxMixedChunkSize = 1024 * 1024 * 1024 // 1GB
+
 // MixedGenerator alternates between random data chunks and zero-filled chunks.
+// The layout repeats every random/zero pair of runs and the random runs come
+// from a seeded stream, so output depends only on the seed and the offset.
 type MixedGenerator struct {
-	random      *RandomGenerator
-	zero        *ZeroGenerator
+	random      *SeededRandomGenerator
 	chunkSize   int
+	randomRatio float64
 	isRandom    bool
-	currentPos  int
+	offset      int64
 	mu          sync.Mutex
 }
 
@@ -83,10 +112,10 @@ func NewMixedGenerator(chunkSize int) *MixedGenerator {
 		chunkSize = 1024
 	}
 	return &MixedGenerator{
-		random:    &RandomGenerator{},
-		zero:      &ZeroGenerator{},
-		chunkSize: chunkSize,
-		isRandom:  true,
+		random:      NewSeededRandomGenerator(randomSeed()),
+		chunkSize:   chunkSize,
+		randomRatio: 0.5,
+		isRandom:    true,
 	}
 }
 
@@ -95,37 +124,48 @@ func (g *MixedGenerator) Name() string {
 	return "mixed"
 }
 
-// Generate fills the buffer alternating between random and zero chunks.
+// Generate fills the buffer with the next bytes of the output.
 func (g *MixedGenerator) Generate(buffer []byte) error {
 	g.mu.Lock()
-	defer g.mu.Unlock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
 
-	offset := 0
-	for offset < len(buffer) {
-		remainingInChunk := g.chunkSize - g.currentPos
-		remainingInBuffer := len(buffer) - offset
-		copySize := min(remainingInChunk, remainingInBuffer)
+	return g.GenerateAt(buffer, offset)
+}
 
-		chunk := buffer[offset : offset+copySize]
+// GenerateAt fills the buffer alternating between random and zero runs as
+// they fall at offset. Each random/zero pair of runs spans two chunk sizes,
+// split according to the random ratio.
+func (g *MixedGenerator) GenerateAt(buffer []byte, offset int64) error {
+	pair := int64(2 * g.chunkSize)
+	randomLen := int64(float64(pair)*g.randomRatio + 0.5)
+	firstLen := randomLen
+	if !g.isRandom {
+		firstLen = pair - randomLen
+	}
 
-		var err error
-		if g.isRandom {
-			err = g.random.Generate(chunk)
-		} else {
-			err = g.zero.Generate(chunk)
+	for len(buffer) > 0 {
+		within := offset % pair
+		isRandom, runEnd := g.isRandom, firstLen
+		if within >= firstLen {
+			isRandom, runEnd = !g.isRandom, pair
 		}
-		if err != nil {
-			return err
+		n := len(buffer)
+		if remaining := runEnd - within; int64(n) > remaining {
+			n = int(remaining)
 		}
 
-		g.currentPos += copySize
-		offset += copySize
-
-		// Switch to next chunk type if current chunk is complete
-		if g.currentPos >= g.chunkSize {
-			g.isRandom = !g.isRandom
-			g.currentPos = 0
+		if isRandom {
+			if err := g.random.GenerateAt(buffer[:n], offset); err != nil {
+				return err
+			}
+		} else {
+			clear(buffer[:n])
 		}
+
+		buffer = buffer[n:]
+		offset += int64(n)
 	}
 
 	return nil
@@ -139,23 +179,206 @@ func min(a, b int) int {
 	return b
 }
 
+// GenerateChunk fills a buffer destined for the given file offset. Generators
+// implementing OffsetGenerator produce offset-derived data; others fall back
+// to Generate.
+func GenerateChunk(gen Generator, buffer []byte, offset int64) error {
+	if offsetGen, ok := gen.(OffsetGenerator); ok {
+		return offsetGen.GenerateAt(buffer, offset)
+	}
+	return gen.Generate(buffer)
+}
+
+// Splittable reports whether gen produces the same data for any byte range
+// however its output is divided, so a chunk can be generated in pieces.
+// That holds for offset generators except line-oriented ones, whose records
+// depend on chunk boundaries, and wrappers around those.
+func Splittable(gen Generator) bool {
+	if _, ok := gen.(OffsetGenerator); !ok {
+		return false
+	}
+	if _, ok := gen.(recordGenerator); ok {
+		return false
+	}
+
+	var inner []Generator
+	switch g := gen.(type) {
+	case *EncryptGenerator:
+		inner = []Generator{g.inner}
+	case *MagicGenerator:
+		inner = []Generator{g.inner}
+	case *CompositeGenerator:
+		inner = g.generators
+	case *PatternMapGenerator:
+		inner = g.generators
+	}
+	for _, g := range inner {
+		if !Splittable(g) {
+			return false
+		}
+	}
+	return true
+}
+
+// Options holds pattern-specific generator settings.
+// Zero values select each pattern's defaults.
+type Options struct {
+	// MixedChunkSize is the length in bytes of each random or zero run
+	// produced by the mixed pattern. Defaults to 1024.
+	MixedChunkSize int
+	// MixedStartZero makes the mixed pattern begin with a zero run
+	// instead of a random one.
+	MixedStartZero bool
+	// MixedRandomRatio is the fraction of mixed output that is random,
+	// between 0 and 1 exclusive. Defaults to 0.5.
+	MixedRandomRatio float64
+	// Seed makes the random pattern reproducible when Seeded is set.
+	Seed   int64
+	Seeded bool
+	// CompressRatio is the compression ratio targeted by the compressible
+	// pattern. Defaults to DefaultCompressRatio.
+	CompressRatio float64
+	// DedupRatio is the fraction of duplicate blocks produced by the dedup
+	// pattern, and DedupBlockSize the size of those blocks. Default to
+	// DefaultDedupRatio and DefaultDedupBlockSize.
+	DedupRatio     float64
+	DedupBlockSize int
+	// Entropy is the normalized Shannon entropy targeted by the entropy
+	// pattern. Defaults to DefaultEntropy.
+	Entropy float64
+	// Schema lists the record fields of the jsonl and csv patterns. Defaults to
+	// DefaultSchema.
+	Schema []Field
+	// LogFormat is the line format of the logs pattern, syslog or apache.
+	// Defaults to syslog.
+	LogFormat string
+	// PatternBytes is the byte sequence repeated by the bytes pattern.
+	PatternBytes []byte
+	// CompositeRegionSize is the size of the regions a weighted composite
+	// pattern interleaves. Defaults to DefaultCompositeRegionSize.
+	CompositeRegionSize int
+	// ExecCommand is the program and arguments of the exec pattern's
+	// generator command.
+	ExecCommand []string
+	// Corpus is the prose the markov pattern is trained on. Defaults to a
+	// built-in corpus.
+	Corpus string
+
+	// sharedSeed replaces the random seed of unseeded patterns when
+	// hasSharedSeed is set (see WithSharedSeed).
+	sharedSeed    int64
+	hasSharedSeed bool
+}
+
+// seed returns the configured seed, or a random one if none was set.
+func (o Options) seed() int64 {
+	if o.Seeded {
+		return o.Seed
+	}
+	if o.hasSharedSeed {
+		return o.sharedSeed
+	}
+	return randomSeed()
+}
+
+// WithSharedSeed returns a copy of the options in which patterns without a
+// seed all use one random seed, chosen now, instead of a new one per
+// generator. Generators created from the copy produce the same data at the
+// same offset, so they can share the work of generating one file. Unseeded
+// random data still comes from crypto/rand.
+func (o Options) WithSharedSeed() Options {
+	o.sharedSeed = randomSeed()
+	o.hasSharedSeed = true
+	return o
+}
+
+// Factory creates a new generator instance each time it is called. Worker
+// pools use a factory to give each worker its own generator, so stateful
+// generators are not shared behind a lock.
+type Factory func() (Generator, error)
+
+// NewFactory returns a Factory creating generators for a pattern (see
+// NewGeneratorWithOptions). All instances share one seed (see
+// WithSharedSeed), so any of them can generate any chunk.
+func NewFactory(pattern string, opts Options) Factory {
+	opts = opts.WithSharedSeed()
+	return func() (Generator, error) {
+		return NewGeneratorWithOptions(pattern, opts)
+	}
+}
+
 // NewGenerator creates a new generator based on the pattern name.
 func NewGenerator(pattern string) (Generator, error) {
-	switch pattern {
+	return NewGeneratorWithOptions(pattern, Options{})
+}
+
+// NewGeneratorWithOptions creates a new generator from a pattern specification
+// (see ParsePattern) or a weighted composite specification (see
+// ParseComposite). Options embedded in the specification take precedence
+// over those passed in opts.
+func NewGeneratorWithOptions(pattern string, opts Options) (Generator, error) {
+	if parts, ok, err := ParseComposite(pattern); ok {
+		if err != nil {
+			return nil, err
+		}
+		return NewCompositeGenerator(parts, opts)
+	}
+
+	name, opts, err := ParsePattern(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
 	case "random":
+		if opts.Seeded {
+			return NewSeededRandomGenerator(opts.Seed), nil
+		}
 		return &RandomGenerator{}, nil
 	case "sequential":
 		return &SequentialGenerator{}, nil
 	case "zero":
 		return &ZeroGenerator{}, nil
 	case "mixed":
-		return NewMixedGenerator(1024), nil
+		g := NewMixedGenerator(opts.MixedChunkSize)
+		g.random = NewSeededRandomGenerator(opts.seed())
+		g.isRandom = !opts.MixedStartZero
+		if opts.MixedRandomRatio > 0 {
+			g.randomRatio = opts.MixedRandomRatio
+		}
+		return g, nil
+	case "compressible":
+		return NewCompressibleGenerator(opts.CompressRatio, opts.seed())
+	case "dedup":
+		return NewDedupGenerator(opts.DedupRatio, opts.DedupBlockSize, opts.seed())
+	case "entropy":
+		return NewEntropyGenerator(opts.Entropy, opts.seed())
+	case "text":
+		return NewTextGenerator(opts.seed()), nil
+	case "jsonl":
+		return NewJSONLGenerator(opts.Schema, opts.seed())
+	case "csv":
+		return NewCSVGenerator(opts.Schema, opts.seed())
+	case "logs":
+		return NewLogsGenerator(opts.LogFormat, opts.seed())
+	case "bytes":
+		return NewRepeatGenerator(opts.PatternBytes)
+	case "verify":
+		return NewStampGenerator(uint64(opts.seed())), nil
+	case "crc":
+		return NewCRCGenerator(uint64(opts.seed())), nil
+	case "aa55", "walking-ones":
+		return NewBitPatternGenerator(name)
+	case "exec":
+		return NewExecGenerator(opts.ExecCommand)
+	case "markov":
+		return NewMarkovGenerator(opts.Corpus, opts.seed())
 	default:
-		return nil, fmt.Errorf("unknown pattern: %s", pattern)
+		return nil, fmt.Errorf("unknown pattern: %s", name)
 	}
 }
 
 // AvailablePatterns returns a list of available pattern names.
 func AvailablePatterns() []string {
-	return []string{"random", "sequential", "zero", "mixed"}
+	return []string{"random", "sequential", "zero", "mixed", "compressible", "dedup", "entropy", "text", "jsonl", "csv", "logs", "bytes", "verify", "crc", "aa55", "walking-ones", "exec", "markov"}
 }
\ No newline at end of file
@@ -97,6 +97,145 @@ func TestSequentialGenerator(t *testing.T) {
 	}
 }
 
+func TestSequentialGeneratorAt(t *testing.T) {
+	g := &SequentialGenerator{}
+
+	buffer := make([]byte, 4)
+	if err := g.GenerateAt(buffer, 254); err != nil {
+		t.Fatalf("GenerateAt failed: %v", err)
+	}
+	if expected := []byte{254, 255, 0, 1}; !bytes.Equal(buffer, expected) {
+		t.Errorf("expected %v, got %v", expected, buffer)
+	}
+
+	// Chunks generated out of order must match a single contiguous generation
+	whole := make([]byte, 3000)
+	g.GenerateAt(whole, 0)
+
+	second := make([]byte, 1000)
+	first := make([]byte, 2000)
+	g.GenerateAt(second, 2000)
+	g.GenerateAt(first, 0)
+
+	if !bytes.Equal(whole, append(first, second...)) {
+		t.Error("out-of-order chunks do not match contiguous generation")
+	}
+
+	// GenerateAt must not disturb the Generate counter
+	buffer = make([]byte, 3)
+	g.Generate(buffer)
+	if expected := []byte{0, 1, 2}; !bytes.Equal(buffer, expected) {
+		t.Errorf("expected %v, got %v", expected, buffer)
+	}
+}
+
+func TestGenerateChunk(t *testing.T) {
+	buffer := make([]byte, 3)
+	if err := GenerateChunk(&SequentialGenerator{}, buffer, 1000); err != nil {
+		t.Fatalf("GenerateChunk failed: %v", err)
+	}
+	if expected := []byte{232, 233, 234}; !bytes.Equal(buffer, expected) {
+		t.Errorf("expected offset-derived data %v, got %v", expected, buffer)
+	}
+
+	// Generators without GenerateAt fall back to Generate
+	buffer = make([]byte, 64)
+	if err := GenerateChunk(&RandomGenerator{}, buffer, 1000); err != nil {
+		t.Fatalf("GenerateChunk failed: %v", err)
+	}
+}
+
+func TestSplittable(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"random", false},
+		{"random:seed=1", true},
+		{"sequential", true},
+		{"mixed", true},
+		{"verify", true},
+		{"crc", true},
+		{"text", false},
+		{"csv", false},
+		{"sequential:0.5,zero:0.5", true},
+		{"zero:0.5,jsonl:0.5", false},
+	}
+
+	for _, test := range tests {
+		gen, err := NewGenerator(test.pattern)
+		if err != nil {
+			t.Fatalf("%s: %v", test.pattern, err)
+		}
+		if got := Splittable(gen); got != test.want {
+			t.Errorf("%s: expected Splittable %v, got %v", test.pattern, test.want, got)
+		}
+		if !test.want {
+			continue
+		}
+
+		// Pieces of a chunk must match the whole chunk
+		whole := make([]byte, 64*1024)
+		if err := GenerateChunk(gen, whole, 1<<20); err != nil {
+			t.Fatalf("%s: %v", test.pattern, err)
+		}
+		pieces := make([]byte, len(whole))
+		for start := 0; start < len(pieces); start += 4093 {
+			end := min(start+4093, len(pieces))
+			if err := GenerateChunk(gen, pieces[start:end], 1<<20+int64(start)); err != nil {
+				t.Fatalf("%s: %v", test.pattern, err)
+			}
+		}
+		if !bytes.Equal(whole, pieces) {
+			t.Errorf("%s: data generated in pieces differs", test.pattern)
+		}
+	}
+
+	// Wrappers are only as splittable as the generator they wrap
+	text, _ := NewGenerator("text")
+	magic, err := NewMagicGenerator("png", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Splittable(magic) {
+		t.Error("expected a magic header over text not to be splittable")
+	}
+}
+
+func TestNewGeneratorWithOptionsMixed(t *testing.T) {
+	gen, err := NewGeneratorWithOptions("mixed", Options{MixedChunkSize: 4096, MixedStartZero: true})
+	if err != nil {
+		t.Fatalf("NewGeneratorWithOptions failed: %v", err)
+	}
+
+	mixed, ok := gen.(*MixedGenerator)
+	if !ok {
+		t.Fatalf("expected *MixedGenerator, got %T", gen)
+	}
+	if mixed.chunkSize != 4096 {
+		t.Errorf("expected chunk size 4096, got %d", mixed.chunkSize)
+	}
+
+	buffer := make([]byte, 8192)
+	if err := gen.Generate(buffer); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// First run must be zeros, second run random
+	if !bytes.Equal(buffer[:4096], make([]byte, 4096)) {
+		t.Error("expected first 4096 bytes to be zero when starting with a zero run")
+	}
+	if bytes.Equal(buffer[4096:], make([]byte, 4096)) {
+		t.Error("expected second run to contain random data")
+	}
+
+	// Defaults are preserved when no options are given
+	gen, _ = NewGenerator("mixed")
+	if mixed := gen.(*MixedGenerator); mixed.chunkSize != 1024 || !mixed.isRandom {
+		t.Errorf("expected default 1024-byte chunks starting random, got %d/%v", mixed.chunkSize, mixed.isRandom)
+	}
+}
+
 func TestZeroGenerator(t *testing.T) {
 	g := &ZeroGenerator{}
 
@@ -180,6 +319,47 @@ func TestMixedGenerator(t *testing.T) {
 	}
 }
 
+func TestMixedGeneratorGenerateAt(t *testing.T) {
+	for _, startZero := range []bool{false, true} {
+		gen, err := NewGeneratorWithOptions("mixed", Options{
+			MixedChunkSize:   100,
+			MixedStartZero:   startZero,
+			MixedRandomRatio: 0.3,
+			Seed:             5,
+			Seeded:           true,
+		})
+		if err != nil {
+			t.Fatalf("NewGeneratorWithOptions failed: %v", err)
+		}
+		mixed := gen.(*MixedGenerator)
+
+		stream := make([]byte, 1000)
+		if err := mixed.Generate(stream); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+
+		// Chunks generated out of order, across run boundaries, line up
+		for _, offset := range []int64{870, 0, 45, 260, 599} {
+			chunk := make([]byte, 130)
+			if err := mixed.GenerateAt(chunk, offset); err != nil {
+				t.Fatalf("GenerateAt failed: %v", err)
+			}
+			if !bytes.Equal(chunk, stream[offset:offset+130]) {
+				t.Errorf("startZero=%v: chunk at offset %d differs from the stream", startZero, offset)
+			}
+		}
+
+		// 60 random and 140 zero bytes in every 200
+		zeroFrom, zeroTo := 60, 200
+		if startZero {
+			zeroFrom, zeroTo = 0, 140
+		}
+		if !bytes.Equal(stream[200+zeroFrom:200+zeroTo], make([]byte, zeroTo-zeroFrom)) {
+			t.Errorf("startZero=%v: expected zero run at %d-%d", startZero, 200+zeroFrom, 200+zeroTo)
+		}
+	}
+}
+
 func TestMixedGeneratorDefaultChunkSize(t *testing.T) {
 	g := NewMixedGenerator(0) // Should default to 1024
 	if g.chunkSize != 1024 {
@@ -202,6 +382,8 @@ func TestNewGenerator(t *testing.T) {
 		{"sequential", false, "sequential"},
 		{"zero", false, "zero"},
 		{"mixed", false, "mixed"},
+		{"aa55", false, "aa55"},
+		{"walking-ones", false, "walking-ones"},
 		{"invalid", true, ""},
 	}
 
@@ -229,7 +411,7 @@ func TestNewGenerator(t *testing.T) {
 
 func TestAvailablePatterns(t *testing.T) {
 	patterns := AvailablePatterns()
-	expected := []string{"random", "sequential", "zero", "mixed"}
+	expected := []string{"random", "sequential", "zero", "mixed", "compressible", "dedup", "entropy", "text", "jsonl", "csv", "logs", "bytes", "verify", "crc", "aa55", "walking-ones", "exec", "markov"}
 
 	if len(patterns) != len(expected) {
 		t.Errorf("expected %d patterns, got %d", len(expected), len(patterns))
@@ -242,6 +424,52 @@ func TestAvailablePatterns(t *testing.T) {
 	}
 }
 
+func TestNewFactory(t *testing.T) {
+	for _, pattern := range []string{"text", "mixed", "text:0.5,jsonl:0.5"} {
+		newGen := NewFactory(pattern, Options{CompositeRegionSize: 4096})
+
+		var outputs [][]byte
+		for i := 0; i < 2; i++ {
+			gen, err := newGen()
+			if err != nil {
+				t.Fatalf("%s: factory failed: %v", pattern, err)
+			}
+			buffer := make([]byte, 16384)
+			if err := GenerateChunk(gen, buffer, 8192); err != nil {
+				t.Fatalf("%s: GenerateChunk failed: %v", pattern, err)
+			}
+			outputs = append(outputs, buffer)
+		}
+		if !bytes.Equal(outputs[0], outputs[1]) {
+			t.Errorf("%s: expected instances from one factory to generate the same data", pattern)
+		}
+	}
+}
+
+func TestWithSharedSeed(t *testing.T) {
+	opts := Options{}.WithSharedSeed()
+	if opts.seed() != opts.seed() {
+		t.Error("expected a shared seed to be reused")
+	}
+	if seeded := (Options{Seed: 3, Seeded: true}).WithSharedSeed(); seeded.seed() != 3 {
+		t.Errorf("expected an explicit seed to take precedence, got %d", seeded.seed())
+	}
+	if _, ok := mustGenerator(t, "random", opts).(*RandomGenerator); !ok {
+		t.Error("expected unseeded random data to stay crypto/rand")
+	}
+}
+
+// mustGenerator creates a generator or fails the test.
+func mustGenerator(t *testing.T, pattern string, opts Options) Generator {
+	t.Helper()
+
+	gen, err := NewGeneratorWithOptions(pattern, opts)
+	if err != nil {
+		t.Fatalf("NewGeneratorWithOptions(%q) failed: %v", pattern, err)
+	}
+	return gen
+}
+
 // Test thread safety
 func TestGeneratorThreadSafety(t *testing.T) {
 	generators := []struct {
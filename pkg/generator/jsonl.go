@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"fmt"
+	"sync"
+)
+
+// JSONLGenerator generates JSON Lines: one JSON object per line with the
+// fields of a schema. Records never cross chunk boundaries, so every line of
+// the file is a valid JSON object, and each chunk is derived from the seed
+// and the chunk offset so it can be regenerated.
+type JSONLGenerator struct {
+	fields []Field
+	seed   int64
+	offset int64
+	mu     sync.Mutex
+}
+
+// NewJSONLGenerator creates a generator emitting records with the given
+// fields. A nil schema selects DefaultSchema.
+func NewJSONLGenerator(fields []Field, seed int64) (*JSONLGenerator, error) {
+	if fields == nil {
+		var err error
+		if fields, err = ParseSchema(DefaultSchema); err != nil {
+			return nil, err
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("schema must have at least one field")
+	}
+
+	return &JSONLGenerator{fields: fields, seed: seed}, nil
+}
+
+// Name returns the name of the generator.
+func (g *JSONLGenerator) Name() string {
+	return "jsonl"
+}
+
+// Fields returns the record schema.
+func (g *JSONLGenerator) Fields() []Field {
+	return g.fields
+}
+
+// Generate fills the buffer with the next chunk of records.
+func (g *JSONLGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+func (g *JSONLGenerator) recordChunks() {}
+
+// GenerateAt fills the buffer with the chunk of records starting at offset.
+func (g *JSONLGenerator) GenerateAt(buffer []byte, offset int64) error {
+	rng := recordRand(g.seed, offset, "trasher-jsonl")
+	fillRecords(buffer, offset, padSpaces, func(dst []byte, offset int64) []byte {
+		dst = append(dst, '{')
+		for i, field := range g.fields {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = append(dst, '"')
+			dst = append(dst, field.Name...)
+			dst = append(dst, '"', ':')
+
+			// Field names and generated strings need no escaping
+			quoted := field.Type == FieldString || field.Type == FieldTimestamp
+			if quoted {
+				dst = append(dst, '"')
+			}
+			dst = appendValue(dst, field.Type, offset, rng)
+			if quoted {
+				dst = append(dst, '"')
+			}
+		}
+		return append(dst, '}', '\n')
+	})
+	return nil
+}
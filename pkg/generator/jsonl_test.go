@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestJSONLGeneratorRecords(t *testing.T) {
+	fields, err := ParseSchema("id,timestamp,name:string,count:int,score:float,ok:bool")
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+	g, err := NewJSONLGenerator(fields, 4)
+	if err != nil {
+		t.Fatalf("NewJSONLGenerator failed: %v", err)
+	}
+
+	// Chunks of odd sizes concatenate into a file of valid lines
+	var file []byte
+	for _, size := range []int{5000, 777, 12345, 40} {
+		chunk := make([]byte, size)
+		if err := g.Generate(chunk); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		file = append(file, chunk...)
+	}
+
+	lastID := int64(-1)
+	var lastTime time.Time
+	records := 0
+	for i, line := range bytes.Split(bytes.TrimSuffix(file, []byte("\n")), []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue // chunk too small for a record
+		}
+
+		var record struct {
+			ID        int64     `json:"id"`
+			Timestamp time.Time `json:"timestamp"`
+			Name      string    `json:"name"`
+			Count     int64     `json:"count"`
+			Score     float64   `json:"score"`
+			OK        bool      `json:"ok"`
+		}
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v: %q", i, err, line)
+		}
+		if record.ID <= lastID || !record.Timestamp.After(lastTime) {
+			t.Fatalf("line %d: id and timestamp must increase (id %d, time %v)", i, record.ID, record.Timestamp)
+		}
+		if record.Name == "" {
+			t.Errorf("line %d: expected a random string", i)
+		}
+		lastID, lastTime = record.ID, record.Timestamp
+		records++
+	}
+
+	if records < 100 {
+		t.Errorf("expected many records, got %d", records)
+	}
+}
+
+func TestJSONLGeneratorChunks(t *testing.T) {
+	g, err := NewJSONLGenerator(nil, 8)
+	if err != nil {
+		t.Fatalf("NewJSONLGenerator failed: %v", err)
+	}
+
+	first := make([]byte, 4096)
+	g.GenerateAt(first, 4096)
+	again := make([]byte, 4096)
+	g.GenerateAt(again, 4096)
+	if !bytes.Equal(first, again) {
+		t.Error("expected GenerateAt to reproduce the chunk")
+	}
+
+	// The first record of a chunk has the chunk offset as its id
+	if !bytes.HasPrefix(first, []byte(`{"id":4096,"timestamp":"2025-01-01T00:00:00.004096Z","message":"`)) {
+		t.Errorf("unexpected first record %q", first[:80])
+	}
+}
+
+func TestJSONLGeneratorEmptySchema(t *testing.T) {
+	if _, err := NewJSONLGenerator([]Field{}, 0); err == nil {
+		t.Error("expected error for an empty schema")
+	}
+}
@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+)
+
+// recordGenerator is implemented by line-oriented generators. Their
+// GenerateAt output depends on where chunks start and end, not only on the
+// offset, so it can be regenerated chunk by chunk but not byte range by byte
+// range.
+type recordGenerator interface {
+	recordChunks()
+}
+
+// recordFunc appends one newline-terminated record, starting at the given
+// file offset, to dst and returns the extended slice.
+type recordFunc func(dst []byte, offset int64) []byte
+
+// padding selects how fillRecords fills the space after the last record.
+type padding int
+
+const (
+	// padSpaces inserts spaces before the last record's newline; a chunk too
+	// small for any record becomes a single line of spaces.
+	padSpaces padding = iota
+	// padBlankLines appends empty lines, for formats where trailing spaces
+	// would change the last field's value.
+	padBlankLines
+)
+
+// fillRecords fills a chunk with whole records for line-oriented patterns.
+// Records never cross the end of the chunk, so chunks generated by different
+// workers concatenate into a well-formed file. The space left after the last
+// record that fits is filled as selected by pad.
+func fillRecords(buffer []byte, offset int64, pad padding, next recordFunc) {
+	if len(buffer) == 0 {
+		return
+	}
+
+	n := 0
+	scratch := make([]byte, 0, 4096)
+	for {
+		scratch = next(scratch[:0], offset+int64(n))
+		if n+len(scratch) > len(buffer) {
+			break
+		}
+		n += copy(buffer[n:], scratch)
+	}
+
+	if n == len(buffer) {
+		return
+	}
+
+	if pad == padBlankLines {
+		for i := n; i < len(buffer); i++ {
+			buffer[i] = '\n'
+		}
+		return
+	}
+
+	// Pad up to the end of the chunk, keeping the newline last
+	for i := max(n-1, 0); i < len(buffer)-1; i++ {
+		buffer[i] = ' '
+	}
+	buffer[len(buffer)-1] = '\n'
+}
+
+// recordRand returns a random source for the records of a chunk, keyed by
+// the seed, the chunk offset and a per-pattern label so every chunk can be
+// regenerated independently.
+func recordRand(seed, offset int64, label string) *rand.Rand {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[0:8], uint64(seed))
+	binary.LittleEndian.PutUint64(key[8:16], uint64(offset))
+	copy(key[16:], label)
+	return rand.New(rand.NewChaCha8(key))
+}
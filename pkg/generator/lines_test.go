@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestFillRecords(t *testing.T) {
+	record := func(dst []byte, offset int64) []byte {
+		return fmt.Appendf(dst, "record at %d\n", offset)
+	}
+
+	tests := []struct {
+		name     string
+		size     int
+		pad      padding
+		expected string
+	}{
+		{"exact fit", 25, padSpaces, "record at 0\nrecord at 12\n"},
+		{"padded", 30, padSpaces, "record at 0\nrecord at 12     \n"},
+		{"too small for a record", 5, padSpaces, "    \n"},
+		{"single byte", 1, padSpaces, "\n"},
+		{"blank lines", 28, padBlankLines, "record at 0\nrecord at 12\n\n\n\n"},
+		{"only blank lines", 3, padBlankLines, "\n\n\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := make([]byte, test.size)
+			fillRecords(buffer, 0, test.pad, record)
+			if string(buffer) != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, buffer)
+			}
+		})
+	}
+}
+
+func TestFillRecordsOffsets(t *testing.T) {
+	var offsets []int64
+	buffer := make([]byte, 100)
+	fillRecords(buffer, 1000, padSpaces, func(dst []byte, offset int64) []byte {
+		offsets = append(offsets, offset)
+		return append(dst, "0123456789012345678\n"...)
+	})
+
+	// Five 20-byte records fit; the sixth is generated and dropped
+	expected := []int64{1000, 1020, 1040, 1060, 1080, 1100}
+	if fmt.Sprint(offsets) != fmt.Sprint(expected) {
+		t.Errorf("expected record offsets %v, got %v", expected, offsets)
+	}
+	if bytes.Count(buffer, []byte("\n")) != 5 {
+		t.Errorf("expected 5 lines, got %q", buffer)
+	}
+}
@@ -0,0 +1,168 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Log line formats supported by the logs pattern.
+const (
+	LogFormatSyslog = "syslog"
+	LogFormatApache = "apache"
+)
+
+// LogFormats lists the supported log line formats.
+var LogFormats = []string{LogFormatSyslog, LogFormatApache}
+
+// logSeverity is a syslog severity and the relative weight it appears with.
+type logSeverity struct {
+	code   int
+	name   string
+	weight int
+}
+
+// logSeverities are weighted so most lines are informational and errors are
+// rare.
+var logSeverities = []logSeverity{
+	{2, "crit", 1},
+	{3, "err", 4},
+	{4, "warning", 10},
+	{5, "notice", 15},
+	{6, "info", 55},
+	{7, "debug", 15},
+}
+
+var (
+	logHosts    = []string{"web01", "web02", "db01", "cache01", "worker03"}
+	logApps     = []string{"nginx", "sshd", "cron", "kernel", "postgres", "app"}
+	logActions  = []string{"request handled", "connection opened", "connection closed", "job finished", "cache miss", "retrying operation", "timeout waiting for lock", "disk usage high", "authentication failed", "configuration reloaded"}
+	logMethods  = []string{"GET", "GET", "GET", "POST", "PUT", "DELETE", "HEAD"}
+	logPaths    = []string{"/", "/index.html", "/api/v1/users", "/api/v1/orders", "/static/app.js", "/static/style.css", "/login", "/healthz"}
+	logStatuses = []int{200, 200, 200, 200, 200, 201, 204, 301, 304, 400, 401, 403, 404, 404, 500, 502, 503}
+	logAgents   = []string{"Mozilla/5.0 (X11; Linux x86_64)", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", "curl/8.5.0", "Go-http-client/1.1", "kube-probe/1.29"}
+)
+
+// LogsGenerator generates timestamped log lines in syslog (RFC 5424) or
+// Apache combined log format. Timestamps are derived from each line's offset,
+// so they advance monotonically through the file regardless of which worker
+// generated which chunk. Lines never cross chunk boundaries.
+type LogsGenerator struct {
+	format string
+	seed   int64
+	offset int64
+	mu     sync.Mutex
+}
+
+// NewLogsGenerator creates a generator for the given log format. An empty
+// format selects syslog.
+func NewLogsGenerator(format string, seed int64) (*LogsGenerator, error) {
+	if format == "" {
+		format = LogFormatSyslog
+	}
+	if format != LogFormatSyslog && format != LogFormatApache {
+		return nil, fmt.Errorf("unknown log format %q (available: syslog, apache)", format)
+	}
+
+	return &LogsGenerator{format: format, seed: seed}, nil
+}
+
+// Name returns the name of the generator.
+func (g *LogsGenerator) Name() string {
+	return "logs"
+}
+
+// Format returns the log line format.
+func (g *LogsGenerator) Format() string {
+	return g.format
+}
+
+// Generate fills the buffer with the next chunk of log lines.
+func (g *LogsGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+func (g *LogsGenerator) recordChunks() {}
+
+// GenerateAt fills the buffer with the chunk of log lines starting at offset.
+func (g *LogsGenerator) GenerateAt(buffer []byte, offset int64) error {
+	rng := recordRand(g.seed, offset, "trasher-logs")
+	appendLine := appendSyslogLine
+	if g.format == LogFormatApache {
+		appendLine = appendApacheLine
+	}
+
+	fillRecords(buffer, offset, padSpaces, func(dst []byte, offset int64) []byte {
+		return appendLine(dst, recordTime(offset), rng)
+	})
+	return nil
+}
+
+// appendSyslogLine appends an RFC 5424 syslog line.
+func appendSyslogLine(dst []byte, t time.Time, rng *rand.Rand) []byte {
+	severity := pickSeverity(rng)
+	const facility = 1 // user-level messages
+
+	dst = append(dst, '<')
+	dst = strconv.AppendInt(dst, int64(facility*8+severity.code), 10)
+	dst = append(dst, ">1 "...)
+	dst = t.AppendFormat(dst, "2006-01-02T15:04:05.000000Z07:00")
+	dst = append(dst, ' ')
+	dst = append(dst, pick(rng, logHosts)...)
+	dst = append(dst, ' ')
+	dst = append(dst, pick(rng, logApps)...)
+	dst = append(dst, ' ')
+	dst = strconv.AppendInt(dst, int64(100+rng.IntN(32000)), 10)
+	dst = append(dst, " - - "...)
+	dst = append(dst, severity.name...)
+	dst = append(dst, ": "...)
+	dst = append(dst, pick(rng, logActions)...)
+	dst = append(dst, " id="...)
+	dst = appendRandomString(dst, 12, rng)
+	return append(dst, '\n')
+}
+
+// appendApacheLine appends an Apache combined log format line.
+func appendApacheLine(dst []byte, t time.Time, rng *rand.Rand) []byte {
+	dst = fmt.Appendf(dst, "10.%d.%d.%d - - [", rng.IntN(256), rng.IntN(256), 1+rng.IntN(254))
+	dst = t.AppendFormat(dst, "02/Jan/2006:15:04:05 -0700")
+	dst = append(dst, "] \""...)
+	dst = append(dst, pick(rng, logMethods)...)
+	dst = append(dst, ' ')
+	dst = append(dst, pick(rng, logPaths)...)
+	dst = append(dst, " HTTP/1.1\" "...)
+	dst = strconv.AppendInt(dst, int64(pick(rng, logStatuses)), 10)
+	dst = append(dst, ' ')
+	dst = strconv.AppendInt(dst, int64(rng.IntN(50000)), 10)
+	dst = append(dst, " \"-\" \""...)
+	dst = append(dst, pick(rng, logAgents)...)
+	return append(dst, '"', '\n')
+}
+
+// pickSeverity picks a syslog severity according to its weight.
+func pickSeverity(rng *rand.Rand) logSeverity {
+	total := 0
+	for _, s := range logSeverities {
+		total += s.weight
+	}
+	n := rng.IntN(total)
+	for _, s := range logSeverities {
+		if n < s.weight {
+			return s
+		}
+		n -= s.weight
+	}
+	return logSeverities[len(logSeverities)-1]
+}
+
+// pick returns a random element of list.
+func pick[T any](rng *rand.Rand, list []T) T {
+	return list[rng.IntN(len(list))]
+}
@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	syslogLine = regexp.MustCompile(`^<(\d+)>1 (\S+) \S+ \S+ \d+ - - (\w+): [a-z ]+ id=[a-z0-9]{12} *$`)
+	apacheLine = regexp.MustCompile(`^10\.\d+\.\d+\.\d+ - - \[([^\]]+)\] "[A-Z]+ \S+ HTTP/1\.1" \d{3} \d+ "-" "[^"]+" *$`)
+)
+
+// generateChunks concatenates chunks of the given sizes from g.
+func generateChunks(t *testing.T, g Generator, sizes ...int) []byte {
+	t.Helper()
+
+	var file []byte
+	for _, size := range sizes {
+		chunk := make([]byte, size)
+		if err := g.Generate(chunk); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		file = append(file, chunk...)
+	}
+	return file
+}
+
+func TestLogsGeneratorSyslog(t *testing.T) {
+	g, err := NewLogsGenerator("", 1)
+	if err != nil {
+		t.Fatalf("NewLogsGenerator failed: %v", err)
+	}
+	if g.Format() != LogFormatSyslog {
+		t.Errorf("expected syslog by default, got %s", g.Format())
+	}
+
+	file := generateChunks(t, g, 8000, 333, 20000, 4096)
+	severities := make(map[string]int)
+	var last time.Time
+	for i, line := range bytes.Split(bytes.TrimSuffix(file, []byte("\n")), []byte("\n")) {
+		m := syslogLine.FindSubmatch(line)
+		if m == nil {
+			t.Fatalf("line %d is not a syslog line: %q", i, line)
+		}
+		ts, err := time.Parse(time.RFC3339Nano, string(m[2]))
+		if err != nil {
+			t.Fatalf("line %d: invalid timestamp %q", i, m[2])
+		}
+		if !ts.After(last) {
+			t.Fatalf("line %d: timestamp %v does not advance past %v", i, ts, last)
+		}
+		last = ts
+		severities[string(m[3])]++
+	}
+
+	if severities["info"] <= severities["err"] || len(severities) < 4 {
+		t.Errorf("expected varied severities weighted towards info, got %v", severities)
+	}
+}
+
+func TestLogsGeneratorApache(t *testing.T) {
+	g, err := NewLogsGenerator(LogFormatApache, 2)
+	if err != nil {
+		t.Fatalf("NewLogsGenerator failed: %v", err)
+	}
+
+	// Use chunks large enough to span several seconds of log time
+	file := generateChunks(t, g, 3<<20, 1<<20)
+	var last time.Time
+	for i, line := range bytes.Split(bytes.TrimSuffix(file, []byte("\n")), []byte("\n")) {
+		m := apacheLine.FindSubmatch(line)
+		if m == nil {
+			t.Fatalf("line %d is not an Apache log line: %q", i, line)
+		}
+		ts, err := time.Parse("02/Jan/2006:15:04:05 -0700", string(m[1]))
+		if err != nil {
+			t.Fatalf("line %d: invalid timestamp %q", i, m[1])
+		}
+		if ts.Before(last) {
+			t.Fatalf("line %d: timestamp %v goes back from %v", i, ts, last)
+		}
+		last = ts
+	}
+	if !last.After(recordEpoch) {
+		t.Error("expected timestamps to advance over the file")
+	}
+}
+
+func TestLogsGeneratorInvalidFormat(t *testing.T) {
+	if _, err := NewLogsGenerator("json", 0); err == nil {
+		t.Error("expected error for unknown log format")
+	}
+}
@@ -0,0 +1,131 @@
+package generator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"sync"
+)
+
+// MagicFormats lists the file formats whose header can be written at the
+// start of generated files.
+var MagicFormats = []string{"png", "zip", "pdf", "mp4"}
+
+// MagicHeader returns a minimal valid header for the given file format,
+// enough for tools such as file(1) and content-type scanners to recognize it.
+func MagicHeader(format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "png":
+		return pngHeader(), nil
+	case "zip":
+		return zipHeader(), nil
+	case "pdf":
+		// The comment line of high bytes marks the file as binary
+		return []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"), nil
+	case "mp4":
+		return mp4Header(), nil
+	default:
+		return nil, fmt.Errorf("unknown magic header format %q (available: %s)", format, strings.Join(MagicFormats, ", "))
+	}
+}
+
+// pngHeader returns the PNG signature and an IHDR chunk for a 1024x1024
+// 8-bit RGB image.
+func pngHeader() []byte {
+	h := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+	ihdr := []byte("IHDR")
+	ihdr = binary.BigEndian.AppendUint32(ihdr, 1024) // width
+	ihdr = binary.BigEndian.AppendUint32(ihdr, 1024) // height
+	ihdr = append(ihdr, 8, 2, 0, 0, 0)               // depth, RGB, compression, filter, interlace
+
+	h = binary.BigEndian.AppendUint32(h, uint32(len(ihdr)-4))
+	h = append(h, ihdr...)
+	return binary.BigEndian.AppendUint32(h, crc32.ChecksumIEEE(ihdr))
+}
+
+// zipHeader returns a ZIP local file header for a stored (uncompressed)
+// entry named data.bin.
+func zipHeader() []byte {
+	const name = "data.bin"
+
+	h := []byte("PK\x03\x04")
+	h = binary.LittleEndian.AppendUint16(h, 20)     // version needed to extract
+	h = binary.LittleEndian.AppendUint16(h, 0)      // flags
+	h = binary.LittleEndian.AppendUint16(h, 0)      // method: stored
+	h = binary.LittleEndian.AppendUint16(h, 0)      // modification time
+	h = binary.LittleEndian.AppendUint16(h, 0x5a21) // modification date: 2025-01-01
+	h = binary.LittleEndian.AppendUint32(h, 0)      // CRC-32
+	h = binary.LittleEndian.AppendUint32(h, 0)      // compressed size
+	h = binary.LittleEndian.AppendUint32(h, 0)      // uncompressed size
+	h = binary.LittleEndian.AppendUint16(h, uint16(len(name)))
+	h = binary.LittleEndian.AppendUint16(h, 0) // extra field length
+	return append(h, name...)
+}
+
+// mp4Header returns an ISO base media ftyp box for an MP4 file.
+func mp4Header() []byte {
+	brands := []string{"isom", "iso2", "mp41"}
+
+	h := binary.BigEndian.AppendUint32(nil, uint32(16+4*len(brands)))
+	h = append(h, "ftypisom"...)
+	h = binary.BigEndian.AppendUint32(h, 0x200) // minor version
+	for _, brand := range brands {
+		h = append(h, brand...)
+	}
+	return h
+}
+
+// MagicGenerator writes a file-format header over the start of another
+// generator's output, so generated files are recognized as that format. The
+// file size is unchanged: the header replaces the first bytes of pattern data.
+type MagicGenerator struct {
+	inner  Generator
+	header []byte
+	offset int64
+	mu     sync.Mutex
+}
+
+// NewMagicGenerator wraps inner so its output starts with the header of the
+// given format.
+func NewMagicGenerator(format string, inner Generator) (*MagicGenerator, error) {
+	header, err := MagicHeader(format)
+	if err != nil {
+		return nil, err
+	}
+	return &MagicGenerator{inner: inner, header: header}, nil
+}
+
+// Name returns the name of the wrapped generator.
+func (g *MagicGenerator) Name() string {
+	return g.inner.Name()
+}
+
+// Header returns the header written at the start of the output.
+func (g *MagicGenerator) Header() []byte {
+	return g.header
+}
+
+// Generate fills the buffer with the next bytes of the output.
+func (g *MagicGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+// GenerateAt fills the buffer with the wrapped generator's data for offset,
+// overlaid with the part of the header that falls within it. The output is
+// only reproducible if the wrapped generator's is.
+func (g *MagicGenerator) GenerateAt(buffer []byte, offset int64) error {
+	if err := GenerateChunk(g.inner, buffer, offset); err != nil {
+		return err
+	}
+	if offset < int64(len(g.header)) {
+		copy(buffer, g.header[offset:])
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+func TestMagicHeaders(t *testing.T) {
+	tests := []struct {
+		format string
+		prefix []byte
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")},
+		{"zip", []byte("PK\x03\x04\x14\x00")},
+		{"pdf", []byte("%PDF-1.7\n")},
+		{"mp4", []byte("\x00\x00\x00\x1cftypisom")},
+		{"PNG", []byte("\x89PNG")},
+	}
+
+	for _, test := range tests {
+		header, err := MagicHeader(test.format)
+		if err != nil {
+			t.Fatalf("MagicHeader(%s) failed: %v", test.format, err)
+		}
+		if !bytes.HasPrefix(header, test.prefix) {
+			t.Errorf("%s: expected header to start with %q, got %q", test.format, test.prefix, header)
+		}
+	}
+
+	if _, err := MagicHeader("gif"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestPNGHeaderCRC(t *testing.T) {
+	header := pngHeader()
+	if len(header) != 33 {
+		t.Fatalf("expected 33-byte PNG header, got %d", len(header))
+	}
+	chunk := header[12:29]
+	if crc := binary.BigEndian.Uint32(header[29:]); crc != crc32.ChecksumIEEE(chunk) {
+		t.Errorf("IHDR CRC mismatch: %08x", crc)
+	}
+}
+
+func TestMP4HeaderBoxSize(t *testing.T) {
+	header := mp4Header()
+	if size := binary.BigEndian.Uint32(header); int(size) != len(header) {
+		t.Errorf("ftyp box size %d does not match its length %d", size, len(header))
+	}
+}
+
+func TestMagicGenerator(t *testing.T) {
+	inner := NewSeededRandomGenerator(3)
+	g, err := NewMagicGenerator("pdf", inner)
+	if err != nil {
+		t.Fatalf("NewMagicGenerator failed: %v", err)
+	}
+	if g.Name() != "random" {
+		t.Errorf("expected wrapped generator's name, got %s", g.Name())
+	}
+
+	data := make([]byte, 4096)
+	g.Generate(data)
+	plain := make([]byte, 4096)
+	inner.GenerateAt(plain, 0)
+
+	n := len(g.Header())
+	if !bytes.Equal(data[:n], g.Header()) {
+		t.Errorf("expected output to start with the header, got %q", data[:n])
+	}
+	if !bytes.Equal(data[n:], plain[n:]) {
+		t.Error("expected pattern data after the header")
+	}
+
+	// A chunk starting inside the header gets the rest of it
+	part := make([]byte, 100)
+	g.GenerateAt(part, 5)
+	if !bytes.Equal(part, data[5:105]) {
+		t.Error("expected a chunk inside the header to match the whole output")
+	}
+
+	// Chunks past the header are the wrapped generator's data
+	g.GenerateAt(part, 1000)
+	if !bytes.Equal(part, plain[1000:1100]) {
+		t.Error("expected chunks past the header to be unchanged")
+	}
+}
@@ -0,0 +1,205 @@
+package generator
+
+import (
+	_ "embed"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+)
+
+const (
+	// markovMaxSentence caps the words of a generated sentence, ending
+	// sentences that a cyclic corpus would otherwise never end.
+	markovMaxSentence = 60
+	// markovStart marks the start of a sentence in chain states.
+	markovStart = -1
+	// markovBackoff is one in how many steps follow only the previous word.
+	markovBackoff = 6
+)
+
+// defaultCorpus is the prose the markov pattern is trained on when no corpus
+// is given.
+//
+//go:embed markov_corpus.txt
+var defaultCorpus string
+
+var (
+	defaultModel     *MarkovModel
+	defaultModelOnce sync.Once
+)
+
+// markovState is the pair of words a chain step is predicted from.
+type markovState [2]int32
+
+// MarkovModel is a word-level Markov chain of order 2 trained on a corpus.
+// To keep small corpora from being replayed sentence by sentence, some steps
+// back off to order 1 and follow only the previous word.
+type MarkovModel struct {
+	words []string
+	// next lists the words seen after each state, with repeats, so picking
+	// uniformly follows the corpus frequencies. nextWord does the same for
+	// single words.
+	next     map[markovState][]int32
+	nextWord map[int32][]int32
+}
+
+// TrainMarkov builds a Markov model from a corpus of prose. Sentences end
+// at words ending in '.', '!' or '?'.
+func TrainMarkov(corpus string) (*MarkovModel, error) {
+	m := &MarkovModel{
+		next:     make(map[markovState][]int32),
+		nextWord: make(map[int32][]int32),
+	}
+	index := make(map[string]int32)
+
+	sentences := 0
+	state := markovState{markovStart, markovStart}
+	for _, word := range strings.Fields(corpus) {
+		id, ok := index[word]
+		if !ok {
+			id = int32(len(m.words))
+			index[word] = id
+			m.words = append(m.words, word)
+		}
+
+		m.next[state] = append(m.next[state], id)
+		if state[1] != markovStart {
+			m.nextWord[state[1]] = append(m.nextWord[state[1]], id)
+		}
+		state = markovState{state[1], id}
+		if endsSentence(word) {
+			state = markovState{markovStart, markovStart}
+			sentences++
+		}
+	}
+
+	if sentences == 0 {
+		return nil, fmt.Errorf("corpus must contain at least one sentence ending in '.', '!' or '?'")
+	}
+	return m, nil
+}
+
+// DefaultMarkovModel returns the model trained on the built-in corpus.
+func DefaultMarkovModel() *MarkovModel {
+	defaultModelOnce.Do(func() {
+		model, err := TrainMarkov(defaultCorpus)
+		if err != nil {
+			panic(fmt.Sprintf("built-in markov corpus: %v", err))
+		}
+		defaultModel = model
+	})
+	return defaultModel
+}
+
+// endsSentence reports whether a word ends a sentence.
+func endsSentence(word string) bool {
+	switch word[len(word)-1] {
+	case '.', '!', '?':
+		return true
+	}
+	return false
+}
+
+// sentence walks the chain from a sentence start and appends the words of
+// the sentence to words.
+func (m *MarkovModel) sentence(rng *rand.Rand, words []string) []string {
+	start := len(words)
+	state := markovState{markovStart, markovStart}
+	for len(words)-start < markovMaxSentence {
+		choices := m.next[state]
+		if state[1] != markovStart && rng.IntN(markovBackoff) == 0 {
+			choices = m.nextWord[state[1]]
+		}
+		if len(choices) == 0 {
+			break
+		}
+		id := choices[rng.IntN(len(choices))]
+		words = append(words, m.words[id])
+		if endsSentence(m.words[id]) {
+			return words
+		}
+		state = markovState{state[1], id}
+	}
+
+	// Close sentences cut short by a dead end or the length cap
+	if n := len(words); n > start {
+		words[n-1] += "."
+	}
+	return words
+}
+
+// MarkovGenerator generates plausible prose from a Markov model: paragraphs
+// wrapped at 72 columns and separated by blank lines, like the text pattern
+// but with non-repetitive sentences learned from a corpus. Each chunk's
+// text is derived from the seed and the chunk offset so it can be
+// regenerated.
+type MarkovGenerator struct {
+	model  *MarkovModel
+	seed   int64
+	offset int64
+	mu     sync.Mutex
+}
+
+// NewMarkovGenerator creates a MarkovGenerator trained on corpus, or on the
+// built-in corpus if corpus is empty.
+func NewMarkovGenerator(corpus string, seed int64) (*MarkovGenerator, error) {
+	if corpus == "" {
+		return &MarkovGenerator{model: DefaultMarkovModel(), seed: seed}, nil
+	}
+
+	model, err := TrainMarkov(corpus)
+	if err != nil {
+		return nil, err
+	}
+	return &MarkovGenerator{model: model, seed: seed}, nil
+}
+
+// Name returns the name of the generator.
+func (g *MarkovGenerator) Name() string {
+	return "markov"
+}
+
+// Generate fills the buffer with the next chunk of text.
+func (g *MarkovGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+func (g *MarkovGenerator) recordChunks() {}
+
+// GenerateAt fills the buffer with the chunk of text starting at offset.
+func (g *MarkovGenerator) GenerateAt(buffer []byte, offset int64) error {
+	rng := recordRand(g.seed, offset, "trasher-markov")
+	var words []string
+	fillRecords(buffer, offset, padSpaces, func(dst []byte, _ int64) []byte {
+		words = words[:0]
+		for s := 3 + rng.IntN(5); s > 0; s-- {
+			words = g.model.sentence(rng, words)
+		}
+		return appendWrapped(dst, words)
+	})
+	return nil
+}
+
+// appendWrapped appends words as a paragraph wrapped at textLineWidth,
+// followed by a blank line.
+func appendWrapped(dst []byte, words []string) []byte {
+	column := 0
+	for _, word := range words {
+		if column > 0 && column+1+len(word) > textLineWidth {
+			dst = append(dst, '\n')
+			column = 0
+		} else if column > 0 {
+			dst = append(dst, ' ')
+			column++
+		}
+		dst = append(dst, word...)
+		column += len(word)
+	}
+	return append(dst, '\n', '\n')
+}
@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTrainMarkov(t *testing.T) {
+	m, err := TrainMarkov("The cat sat. The dog ran!")
+	if err != nil {
+		t.Fatalf("TrainMarkov failed: %v", err)
+	}
+	if len(m.words) != 5 {
+		t.Errorf("expected 5 distinct words, got %v", m.words)
+	}
+	if starts := m.next[markovState{markovStart, markovStart}]; len(starts) != 2 {
+		t.Errorf("expected 2 sentence starts, got %d", len(starts))
+	}
+
+	for _, corpus := range []string{"", "   ", "no sentence ends here"} {
+		if _, err := TrainMarkov(corpus); err == nil {
+			t.Errorf("expected error for corpus %q", corpus)
+		}
+	}
+}
+
+func TestMarkovGeneratorCorpus(t *testing.T) {
+	g, err := NewMarkovGenerator("The cat sat. The dog ran.", 3)
+	if err != nil {
+		t.Fatalf("NewMarkovGenerator failed: %v", err)
+	}
+
+	data := make([]byte, 4096)
+	g.Generate(data)
+
+	// Only the corpus's sentences can be produced
+	for _, sentence := range strings.SplitAfter(strings.Join(strings.Fields(string(data)), " "), ".") {
+		sentence = strings.TrimSpace(sentence)
+		if sentence != "" && sentence != "The cat sat." && sentence != "The dog ran." {
+			t.Fatalf("unexpected sentence %q", sentence)
+		}
+	}
+}
+
+func TestMarkovGeneratorReadable(t *testing.T) {
+	g, err := NewMarkovGenerator("", 1)
+	if err != nil {
+		t.Fatalf("NewMarkovGenerator failed: %v", err)
+	}
+
+	data := make([]byte, 256*1024)
+	if err := g.Generate(data); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	for _, b := range data {
+		if b != '\n' && (b < ' ' || b > '~') {
+			t.Fatalf("unexpected non-printable byte %#x", b)
+		}
+	}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimRight(line, " ")) > textLineWidth {
+			t.Fatalf("line %d exceeds %d columns: %q", i, textLineWidth, line)
+		}
+	}
+	if data[len(data)-1] != '\n' {
+		t.Error("expected the chunk to end with a newline")
+	}
+
+	// Paragraphs should hardly ever repeat
+	paragraphs := bytes.Split(bytes.TrimSpace(data), []byte("\n\n"))
+	distinct := make(map[string]bool)
+	for _, p := range paragraphs {
+		distinct[string(p)] = true
+	}
+	if len(distinct) < len(paragraphs)*9/10 {
+		t.Errorf("expected mostly distinct paragraphs, got %d of %d", len(distinct), len(paragraphs))
+	}
+}
+
+func TestMarkovGeneratorChunks(t *testing.T) {
+	g, _ := NewMarkovGenerator("", 7)
+
+	first := make([]byte, 10000)
+	second := make([]byte, 10000)
+	g.Generate(first)
+	g.Generate(second)
+
+	again := make([]byte, 10000)
+	g.GenerateAt(again, 10000)
+	if !bytes.Equal(again, second) {
+		t.Error("expected GenerateAt to reproduce the second chunk")
+	}
+	if bytes.Equal(first, second) {
+		t.Error("expected chunks at different offsets to differ")
+	}
+	if second[0] < 'A' || second[0] > 'Z' {
+		t.Errorf("expected the chunk to start a paragraph, got %q", second[:20])
+	}
+}
@@ -0,0 +1,192 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/maxkimambo/trasher/pkg/sizeparser"
+)
+
+// MapEnd marks a pattern map region that extends to the end of the file.
+const MapEnd = -1
+
+// MapRegion is one region of a pattern map: the file bytes from Start up to
+// End (exclusive, or MapEnd) filled with Pattern.
+type MapRegion struct {
+	Start   int64
+	End     int64
+	Pattern string
+}
+
+// ParsePatternMap parses a pattern map such as "0-10GB=zero,10GB-end=random"
+// into its regions. Regions must start at 0 and follow each other without
+// gaps; only the last may end at "end". Each pattern is a specification as
+// accepted by NewGeneratorWithOptions, so "0-1GB=mixed:ratio=70:30,chunk=4KB"
+// and "0-1GB=random:0.7,zero:0.3" work as expected.
+func ParsePatternMap(spec string) ([]MapRegion, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("pattern map cannot be empty")
+	}
+
+	// Commas also separate pattern options and composite weights, so a part
+	// only starts a new region if it begins with a range
+	var entries []string
+	for _, part := range strings.Split(spec, ",") {
+		if rawRange, _, ok := strings.Cut(part, "="); ok && strings.Contains(rawRange, "-") {
+			if _, _, err := parseMapRange(rawRange); err != nil {
+				return nil, err
+			}
+			entries = append(entries, part)
+			continue
+		}
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("pattern map entry %q must be start-end=pattern, e.g. 0-10GB=zero", part)
+		}
+		entries[len(entries)-1] += "," + part
+	}
+
+	var regions []MapRegion
+	var next int64
+	for i, entry := range entries {
+		rawRange, pattern, _ := strings.Cut(entry, "=")
+		start, end, _ := parseMapRange(rawRange)
+		pattern = strings.TrimSpace(pattern)
+
+		if start != next {
+			if i == 0 {
+				return nil, fmt.Errorf("pattern map must start at 0, not %s", strings.TrimSpace(rawRange))
+			}
+			return nil, fmt.Errorf("pattern map region %s must start where the previous one ends (%d)", strings.TrimSpace(rawRange), next)
+		}
+		if end == MapEnd && i != len(entries)-1 {
+			return nil, fmt.Errorf("pattern map region %s runs to the end, so it must be the last", strings.TrimSpace(rawRange))
+		}
+		if err := checkMapPattern(pattern); err != nil {
+			return nil, fmt.Errorf("pattern map region %s: %v", strings.TrimSpace(rawRange), err)
+		}
+
+		regions = append(regions, MapRegion{Start: start, End: end, Pattern: pattern})
+		next = end
+	}
+
+	return regions, nil
+}
+
+// parseMapRange parses a "start-end" byte range. The start may be 0 and the
+// end may be "end".
+func parseMapRange(value string) (int64, int64, error) {
+	rawStart, rawEnd, ok := strings.Cut(strings.TrimSpace(value), "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range %q: must be start-end", value)
+	}
+
+	start, err := parseMapBound(rawStart)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range start %q: %v", rawStart, err)
+	}
+	end := int64(MapEnd)
+	if strings.TrimSpace(strings.ToLower(rawEnd)) != "end" {
+		if end, err = parseMapBound(rawEnd); err != nil {
+			return 0, 0, fmt.Errorf("invalid range end %q: %v", rawEnd, err)
+		}
+		if end <= start {
+			return 0, 0, fmt.Errorf("invalid range %q: end must be after start", value)
+		}
+	}
+
+	return start, end, nil
+}
+
+// parseMapBound parses a range bound, a size such as 10GB or 0.
+func parseMapBound(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "0" || strings.EqualFold(value, "0B") {
+		return 0, nil
+	}
+	return sizeparser.Parse(value)
+}
+
+// checkMapPattern validates the pattern specification of a map region.
+func checkMapPattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("missing pattern")
+	}
+	if _, ok, err := ParseComposite(pattern); ok {
+		return err
+	}
+	_, _, err := ParsePattern(pattern, Options{})
+	return err
+}
+
+// PatternMapGenerator fills different regions of a file with different
+// patterns. Every pattern sees file offsets, so offset-based patterns such as
+// verify stamp the true position of each block.
+type PatternMapGenerator struct {
+	regions    []MapRegion
+	generators []Generator
+	offset     int64
+	mu         sync.Mutex
+}
+
+// NewPatternMapGenerator creates a generator for the regions of a pattern map
+// (see ParsePatternMap). opts configures every pattern.
+func NewPatternMapGenerator(regions []MapRegion, opts Options) (*PatternMapGenerator, error) {
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("pattern map needs at least one region")
+	}
+
+	g := &PatternMapGenerator{regions: regions}
+	for _, region := range regions {
+		gen, err := NewGeneratorWithOptions(region.Pattern, opts)
+		if err != nil {
+			return nil, fmt.Errorf("pattern map region %d-%d: %v", region.Start, region.End, err)
+		}
+		g.generators = append(g.generators, gen)
+	}
+
+	return g, nil
+}
+
+// Name returns the name of the generator.
+func (g *PatternMapGenerator) Name() string {
+	return "map"
+}
+
+// Generate fills the buffer with the next bytes of the file.
+func (g *PatternMapGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+// GenerateAt fills the buffer with the file as it appears at offset, each
+// byte coming from the pattern of its region.
+func (g *PatternMapGenerator) GenerateAt(buffer []byte, offset int64) error {
+	for len(buffer) > 0 {
+		i := sort.Search(len(g.regions), func(i int) bool {
+			end := g.regions[i].End
+			return end == MapEnd || end > offset
+		})
+		if i == len(g.regions) {
+			return fmt.Errorf("offset %d is not covered by the pattern map", offset)
+		}
+
+		n := len(buffer)
+		if end := g.regions[i].End; end != MapEnd && offset+int64(n) > end {
+			n = int(end - offset)
+		}
+		if err := GenerateChunk(g.generators[i], buffer[:n], offset); err != nil {
+			return err
+		}
+
+		buffer = buffer[n:]
+		offset += int64(n)
+	}
+	return nil
+}
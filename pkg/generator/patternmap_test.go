@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParsePatternMap(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []MapRegion
+	}{
+		{
+			"0-10GB=zero,10GB-end=random",
+			[]MapRegion{{0, 10 << 30, "zero"}, {10 << 30, MapEnd, "random"}},
+		},
+		{
+			"0-1MB=mixed:ratio=70:30,chunk=4KB, 1MB-2MB=random:0.7,zero:0.3",
+			[]MapRegion{{0, 1 << 20, "mixed:ratio=70:30,chunk=4KB"}, {1 << 20, 2 << 20, "random:0.7,zero:0.3"}},
+		},
+		{
+			"0B-4KB=sequential",
+			[]MapRegion{{0, 4096, "sequential"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			regions, err := ParsePatternMap(test.spec)
+			if err != nil {
+				t.Fatalf("ParsePatternMap failed: %v", err)
+			}
+			if len(regions) != len(test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, regions)
+			}
+			for i := range regions {
+				if regions[i] != test.expected[i] {
+					t.Errorf("region %d: expected %v, got %v", i, test.expected[i], regions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParsePatternMapErrors(t *testing.T) {
+	tests := []struct {
+		spec        string
+		expectedMsg string
+	}{
+		{"", "cannot be empty"},
+		{"zero", "must be start-end=pattern"},
+		{"1GB-2GB=zero", "must start at 0"},
+		{"0-1GB=zero,2GB-end=random", "must start where the previous one ends"},
+		{"0-end=zero,1GB-2GB=random", "must be the last"},
+		{"0-1GB=bogus", "unknown pattern"},
+		{"0-1GB=", "missing pattern"},
+		{"0-1GB=random:seed=abc", "invalid seed"},
+		{"0-1GB=random:1,bogus:1", "unknown pattern in composite"},
+		{"0-1GB=zero,1GB-1GB=random", "end must be after start"},
+		{"0-1GB=zero,1XB-end=random", "invalid range start"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			_, err := ParsePatternMap(test.spec)
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestPatternMapGenerator(t *testing.T) {
+	regions, err := ParsePatternMap("0-1KB=zero,1KB-3KB=sequential,3KB-end=bytes:hex=AA55")
+	if err != nil {
+		t.Fatalf("ParsePatternMap failed: %v", err)
+	}
+	g, err := NewPatternMapGenerator(regions, Options{})
+	if err != nil {
+		t.Fatalf("NewPatternMapGenerator failed: %v", err)
+	}
+
+	// Generate in uneven pieces that straddle region boundaries
+	data := make([]byte, 4096)
+	if err := g.Generate(data[:700]); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := g.Generate(data[700:3500]); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := g.Generate(data[3500:]); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if !bytes.Equal(data[:1024], make([]byte, 1024)) {
+		t.Error("expected zeros in the first region")
+	}
+	for i := 1024; i < 3072; i++ {
+		if data[i] != byte(i%256) {
+			t.Fatalf("expected the sequential pattern at file offset %d, got %#x", i, data[i])
+		}
+	}
+	for i := 3072; i < len(data); i += 2 {
+		if data[i] != 0xAA || data[i+1] != 0x55 {
+			t.Fatalf("expected the byte sequence at offset %d, got %#x %#x", i, data[i], data[i+1])
+		}
+	}
+}
+
+func TestPatternMapGeneratorUncovered(t *testing.T) {
+	g, err := NewPatternMapGenerator([]MapRegion{{0, 1024, "zero"}}, Options{})
+	if err != nil {
+		t.Fatalf("NewPatternMapGenerator failed: %v", err)
+	}
+	if err := g.GenerateAt(make([]byte, 2048), 0); err == nil {
+		t.Error("expected error generating past the end of the map")
+	}
+}
@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType is the kind of value a record field holds.
+type FieldType string
+
+const (
+	// FieldID is unique and increasing: the byte offset of the record.
+	FieldID FieldType = "id"
+	// FieldTimestamp is an RFC 3339 time that increases with the offset.
+	FieldTimestamp FieldType = "timestamp"
+	FieldString    FieldType = "string"
+	FieldInt       FieldType = "int"
+	FieldFloat     FieldType = "float"
+	FieldBool      FieldType = "bool"
+)
+
+// fieldTypes lists the supported field types in the order shown to users.
+var fieldTypes = []FieldType{FieldID, FieldTimestamp, FieldString, FieldInt, FieldFloat, FieldBool}
+
+// DefaultSchema is the record schema used when none is given.
+const DefaultSchema = "id,timestamp,message:string"
+
+// recordEpoch is the time of the record at offset 0. Record times advance one
+// microsecond per byte from it, so they increase monotonically through the
+// file and are reproducible.
+var recordEpoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Field is a named, typed field of a generated record.
+type Field struct {
+	Name string
+	Type FieldType
+}
+
+// ParseSchema parses a comma-separated list of fields written as name:type.
+// A bare type name (e.g. "id") declares a field of that type with the same
+// name. Field names may contain letters, digits and underscores.
+func ParseSchema(spec string) ([]Field, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, fmt.Errorf("empty schema")
+	}
+
+	var fields []Field
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(spec, ",") {
+		name, typ, found := strings.Cut(strings.TrimSpace(item), ":")
+		name = strings.TrimSpace(name)
+		if !found {
+			typ = name
+		}
+		typ = strings.ToLower(strings.TrimSpace(typ))
+
+		if !validFieldName(name) {
+			return nil, fmt.Errorf("invalid field name %q: use letters, digits and underscores", name)
+		}
+		if !isFieldType(FieldType(typ)) {
+			return nil, fmt.Errorf("unknown type %q for field %s (available: %s)", typ, name, fieldTypeList())
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("field %s given more than once", name)
+		}
+		seen[name] = true
+		fields = append(fields, Field{Name: name, Type: FieldType(typ)})
+	}
+
+	return fields, nil
+}
+
+// appendValue appends the unquoted text of a field value for the record at
+// offset.
+func appendValue(dst []byte, typ FieldType, offset int64, rng *rand.Rand) []byte {
+	switch typ {
+	case FieldID:
+		return strconv.AppendInt(dst, offset, 10)
+	case FieldTimestamp:
+		return recordTime(offset).AppendFormat(dst, time.RFC3339Nano)
+	case FieldString:
+		return appendRandomString(dst, 8+rng.IntN(25), rng)
+	case FieldInt:
+		return strconv.AppendInt(dst, rng.Int64N(1000000), 10)
+	case FieldFloat:
+		return strconv.AppendFloat(dst, rng.Float64()*1000, 'f', 3, 64)
+	case FieldBool:
+		return strconv.AppendBool(dst, rng.IntN(2) == 1)
+	}
+	return dst
+}
+
+// recordTime returns the time of the record at offset.
+func recordTime(offset int64) time.Time {
+	return recordEpoch.Add(time.Duration(offset) * time.Microsecond)
+}
+
+// appendRandomString appends n random lowercase letters and digits.
+func appendRandomString(dst []byte, n int, rng *rand.Rand) []byte {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	for range n {
+		dst = append(dst, alphabet[rng.IntN(len(alphabet))])
+	}
+	return dst
+}
+
+// validFieldName reports whether name is a non-empty identifier.
+func validFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && (r < '0' || r > '9') && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// isFieldType reports whether typ is a supported field type.
+func isFieldType(typ FieldType) bool {
+	for _, t := range fieldTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldTypeList returns the supported field types for error messages.
+func fieldTypeList() string {
+	names := make([]string, len(fieldTypes))
+	for i, t := range fieldTypes {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
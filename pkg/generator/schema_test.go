@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSchema(t *testing.T) {
+	fields, err := ParseSchema("id, ts:timestamp,user:STRING,score:float,count:int,ok:bool")
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+
+	expected := []Field{
+		{"id", FieldID},
+		{"ts", FieldTimestamp},
+		{"user", FieldString},
+		{"score", FieldFloat},
+		{"count", FieldInt},
+		{"ok", FieldBool},
+	}
+	if len(fields) != len(expected) {
+		t.Fatalf("expected %d fields, got %v", len(expected), fields)
+	}
+	for i := range expected {
+		if fields[i] != expected[i] {
+			t.Errorf("field %d: expected %v, got %v", i, expected[i], fields[i])
+		}
+	}
+
+	if _, err := ParseSchema(DefaultSchema); err != nil {
+		t.Errorf("default schema does not parse: %v", err)
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	tests := []struct {
+		spec        string
+		expectedMsg string
+	}{
+		{"", "empty schema"},
+		{"id,,name:string", "invalid field name"},
+		{"user name:string", "invalid field name"},
+		{"name:text", "unknown type"},
+		{"message", "unknown type"},
+		{"id,id", "more than once"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			_, err := ParseSchema(test.spec)
+			if err == nil || !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestRecordTimeMonotonic(t *testing.T) {
+	if !recordTime(1000).After(recordTime(999)) {
+		t.Error("expected record times to increase with the offset")
+	}
+	if !recordTime(0).Equal(recordEpoch) {
+		t.Errorf("expected offset 0 at the epoch, got %v", recordTime(0))
+	}
+}
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+)
+
+// seedBlockSize is the granularity at which seeded output is keyed to the
+// file offset. Each block is an independent ChaCha8 stream, so any block can
+// be produced without generating the ones before it.
+const seedBlockSize = 64 * 1024
+
+// SeededRandomGenerator generates reproducible pseudo-random data from a seed.
+// Output depends only on the seed and the file offset, so files can be
+// regenerated, repaired and verified without storing their contents.
+type SeededRandomGenerator struct {
+	seed   int64
+	offset int64
+	mu     sync.Mutex
+}
+
+// NewSeededRandomGenerator creates a SeededRandomGenerator for the given seed.
+func NewSeededRandomGenerator(seed int64) *SeededRandomGenerator {
+	return &SeededRandomGenerator{seed: seed}
+}
+
+// Name returns the name of the generator.
+func (g *SeededRandomGenerator) Name() string {
+	return "random"
+}
+
+// Seed returns the seed the generator was created with.
+func (g *SeededRandomGenerator) Seed() int64 {
+	return g.seed
+}
+
+// Generate fills the buffer with the next bytes of the seeded stream.
+func (g *SeededRandomGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+// GenerateAt fills the buffer with the seeded stream as it appears at offset.
+func (g *SeededRandomGenerator) GenerateAt(buffer []byte, offset int64) error {
+	var scratch []byte
+
+	for len(buffer) > 0 {
+		block := offset / seedBlockSize
+		within := int(offset % seedBlockSize)
+		n := min(len(buffer), seedBlockSize-within)
+
+		rng := rand.NewChaCha8(g.blockSeed(block))
+		if within == 0 {
+			rng.Read(buffer[:n])
+		} else {
+			// Chunk starts mid-block: regenerate the block prefix and discard it
+			if scratch == nil {
+				scratch = make([]byte, seedBlockSize)
+			}
+			rng.Read(scratch[:within+n])
+			copy(buffer[:n], scratch[within:within+n])
+		}
+
+		buffer = buffer[n:]
+		offset += int64(n)
+	}
+
+	return nil
+}
+
+// blockSeed derives the ChaCha8 key for a block from the generator seed.
+func (g *SeededRandomGenerator) blockSeed(block int64) [32]byte {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[0:8], uint64(g.seed))
+	binary.LittleEndian.PutUint64(key[8:16], uint64(block))
+	copy(key[16:], "trasher-seeded")
+	return key
+}
+
+// randomSeed returns an unpredictable seed for seeded generators created
+// without an explicit seed.
+func randomSeed() int64 {
+	var b [8]byte
+	cryptorand.Read(b[:])
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
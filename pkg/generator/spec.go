@@ -0,0 +1,260 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maxkimambo/trasher/pkg/sizeparser"
+)
+
+// patternOptions lists the options each pattern accepts in a specification.
+var patternOptions = map[string][]string{
+	"random":     {"seed"},
+	"sequential": nil,
+	"zero":       nil,
+	"mixed":      {"chunk", "phase", "ratio", "seed"},
+	// ratio is the target compression ratio, e.g. compressible:ratio=2.5
+	"compressible": {"ratio", "seed"},
+	// ratio is the fraction of duplicate blocks, e.g. dedup:ratio=0.3,block=8KB
+	"dedup": {"ratio", "block", "seed"},
+	// target is the normalized Shannon entropy, e.g. entropy:target=0.6
+	"entropy": {"target", "seed"},
+	"text":    {"seed"},
+	"jsonl":   {"seed"},
+	"csv":     {"seed"},
+	"logs":    {"format", "seed"},
+	// hex is the repeated byte sequence, e.g. bytes:hex=0xDEADBEEF
+	"bytes": {"hex"},
+	// seed is the run ID stamped into every block
+	"verify": {"seed"},
+	"crc":    {"seed"},
+	// the bit-stress patterns repeat fixed sequences
+	"aa55":         nil,
+	"walking-ones": nil,
+	// the command is given with --generator-cmd
+	"exec": nil,
+	// the corpus is given with --corpus
+	"markov": {"seed"},
+}
+
+// PatternOptions returns the option names accepted by a pattern.
+func PatternOptions(name string) []string {
+	return patternOptions[name]
+}
+
+// ParsePattern parses a pattern specification of the form
+//
+//	name[:key=value[,key=value...]]
+//
+// for example "random:seed=42" or "mixed:ratio=70:30,chunk=4KB", and returns
+// the pattern name with its options applied on top of base.
+func ParsePattern(spec string, base Options) (string, Options, error) {
+	opts := base
+
+	name, rawOptions, hasOptions := strings.Cut(strings.TrimSpace(spec), ":")
+	allowed, known := patternOptions[name]
+	if !known {
+		return "", opts, fmt.Errorf("unknown pattern: %s", name)
+	}
+	if !hasOptions {
+		return name, opts, nil
+	}
+	if rawOptions == "" {
+		return "", opts, fmt.Errorf("pattern %s: empty option list", name)
+	}
+
+	seen := make(map[string]bool)
+	for _, option := range strings.Split(rawOptions, ",") {
+		key, value, ok := strings.Cut(option, "=")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return "", opts, fmt.Errorf("pattern %s: option %q must be key=value", name, option)
+		}
+		if !contains(allowed, key) {
+			return "", opts, fmt.Errorf("pattern %s: unknown option %q (available: %s)", name, key, describeOptions(allowed))
+		}
+		if seen[key] {
+			return "", opts, fmt.Errorf("pattern %s: option %q given more than once", name, key)
+		}
+		seen[key] = true
+
+		if err := applyOption(&opts, name, key, value); err != nil {
+			return "", opts, fmt.Errorf("pattern %s: %v", name, err)
+		}
+	}
+
+	return name, opts, nil
+}
+
+// applyOption parses a single option value for the named pattern into opts.
+func applyOption(opts *Options, name, key, value string) error {
+	switch key {
+	case "seed":
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid seed %q: must be an integer", value)
+		}
+		opts.Seed = seed
+		opts.Seeded = true
+
+	case "chunk":
+		size, err := sizeparser.Parse(value)
+		if err != nil {
+			return fmt.Errorf("invalid chunk %q: %v", value, err)
+		}
+		if size > MaxMixedChunkSize {
+			return fmt.Errorf("invalid chunk %q: must be at most %d bytes", value, MaxMixedChunkSize)
+		}
+		opts.MixedChunkSize = int(size)
+
+	case "phase":
+		switch value {
+		case "random":
+			opts.MixedStartZero = false
+		case "zero":
+			opts.MixedStartZero = true
+		default:
+			return fmt.Errorf("invalid phase %q (available: random, zero)", value)
+		}
+
+	case "format":
+		if !contains(LogFormats, value) {
+			return fmt.Errorf("invalid format %q (available: %s)", value, strings.Join(LogFormats, ", "))
+		}
+		opts.LogFormat = value
+
+	case "ratio":
+		switch name {
+		case "compressible":
+			ratio, err := strconv.ParseFloat(value, 64)
+			if err != nil || ratio < 1 || ratio > MaxCompressRatio {
+				return fmt.Errorf("invalid ratio %q: must be a number between 1 and %g", value, MaxCompressRatio)
+			}
+			opts.CompressRatio = ratio
+		case "dedup":
+			ratio, err := parseFraction(value)
+			if err == nil && ratio == 0 {
+				err = fmt.Errorf("must be greater than 0")
+			}
+			if err != nil {
+				return fmt.Errorf("invalid ratio %q: %v", value, err)
+			}
+			opts.DedupRatio = ratio
+		default:
+			ratio, err := parseRatio(value)
+			if err != nil {
+				return err
+			}
+			opts.MixedRandomRatio = ratio
+		}
+
+	case "hex":
+		b, err := ParseHexBytes(value)
+		if err != nil {
+			return err
+		}
+		opts.PatternBytes = b
+
+	case "target":
+		entropy, err := strconv.ParseFloat(value, 64)
+		if err != nil || entropy <= 0 || entropy > 1 {
+			return fmt.Errorf("invalid target %q: entropy must be greater than 0 and at most 1", value)
+		}
+		opts.Entropy = entropy
+
+	case "block":
+		size, err := sizeparser.Parse(value)
+		if err != nil {
+			return fmt.Errorf("invalid block %q: %v", value, err)
+		}
+		if size < MinDedupBlockSize || size > MaxDedupBlockSize {
+			return fmt.Errorf("invalid block %q: must be between %d and %d bytes", value, MinDedupBlockSize, MaxDedupBlockSize)
+		}
+		opts.DedupBlockSize = int(size)
+
+	default:
+		return fmt.Errorf("unsupported option %q", key)
+	}
+
+	return nil
+}
+
+// parseRatio parses a "random:zero" weight pair such as "70:30" into the
+// fraction of random data.
+func parseRatio(value string) (float64, error) {
+	left, right, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid ratio %q: must be random:zero, e.g. 70:30", value)
+	}
+
+	random, err := strconv.ParseFloat(left, 64)
+	if err != nil || random < 0 {
+		return 0, fmt.Errorf("invalid ratio %q: weights must be non-negative numbers", value)
+	}
+	zero, err := strconv.ParseFloat(right, 64)
+	if err != nil || zero < 0 {
+		return 0, fmt.Errorf("invalid ratio %q: weights must be non-negative numbers", value)
+	}
+	if random == 0 || zero == 0 {
+		return 0, fmt.Errorf("invalid ratio %q: both weights must be positive (use the random or zero pattern instead)", value)
+	}
+
+	return random / (random + zero), nil
+}
+
+// parseFraction parses a fraction given as a percentage ("30%") or a
+// number between 0 and 1 ("0.3").
+func parseFraction(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	percent := strings.HasSuffix(value, "%")
+
+	f, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("must be a fraction like 0.3 or a percentage like 30%%")
+	}
+	if percent {
+		f /= 100
+	}
+	if f < 0 || f > 1 {
+		return 0, fmt.Errorf("must be between 0 and 1 (0%% and 100%%)")
+	}
+	return f, nil
+}
+
+// Reproducible reports whether a pattern specification generates the same
+// data on every run: the pattern draws no random data, or it is given a
+// seed. Weighted patterns lay out their regions at random, and the exec
+// pattern's data is up to its command, so neither is reproducible.
+func Reproducible(spec string) bool {
+	if _, ok, _ := ParseComposite(spec); ok {
+		return false
+	}
+	name, opts, err := ParsePattern(spec, Options{})
+	if err != nil || name == "exec" {
+		return false
+	}
+	return opts.Seeded || !contains(patternOptions[name], "seed")
+}
+
+// contains reports whether list contains s.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// describeOptions formats an option list for error messages.
+func describeOptions(options []string) string {
+	if len(options) == 0 {
+		return "none"
+	}
+	sorted := append([]string(nil), options...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ", ")
+}
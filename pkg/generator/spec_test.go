@@ -0,0 +1,239 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected string
+		check    func(t *testing.T, opts Options)
+	}{
+		{"plain pattern", "random", "random", func(t *testing.T, opts Options) {
+			if opts.Seeded {
+				t.Error("expected unseeded options")
+			}
+		}},
+		{"seeded random", "random:seed=42", "random", func(t *testing.T, opts Options) {
+			if !opts.Seeded || opts.Seed != 42 {
+				t.Errorf("expected seed 42, got %d (seeded=%v)", opts.Seed, opts.Seeded)
+			}
+		}},
+		{"negative seed", "random:seed=-7", "random", func(t *testing.T, opts Options) {
+			if opts.Seed != -7 {
+				t.Errorf("expected seed -7, got %d", opts.Seed)
+			}
+		}},
+		{"mixed options", "mixed:ratio=70:30,chunk=4KB,phase=zero", "mixed", func(t *testing.T, opts Options) {
+			if opts.MixedChunkSize != 4096 {
+				t.Errorf("expected chunk 4096, got %d", opts.MixedChunkSize)
+			}
+			if opts.MixedRandomRatio != 0.7 {
+				t.Errorf("expected ratio 0.7, got %f", opts.MixedRandomRatio)
+			}
+			if !opts.MixedStartZero {
+				t.Error("expected zero starting phase")
+			}
+		}},
+		{"compressible ratio", "compressible:ratio=2.5,seed=3", "compressible", func(t *testing.T, opts Options) {
+			if opts.CompressRatio != 2.5 {
+				t.Errorf("expected compression ratio 2.5, got %f", opts.CompressRatio)
+			}
+			if opts.MixedRandomRatio != 0 {
+				t.Errorf("compressible ratio leaked into mixed ratio: %f", opts.MixedRandomRatio)
+			}
+			if !opts.Seeded || opts.Seed != 3 {
+				t.Errorf("expected seed 3, got %d", opts.Seed)
+			}
+		}},
+		{"dedup options", "dedup:ratio=30%,block=8KB", "dedup", func(t *testing.T, opts Options) {
+			if opts.DedupRatio != 0.3 {
+				t.Errorf("expected dedup ratio 0.3, got %f", opts.DedupRatio)
+			}
+			if opts.DedupBlockSize != 8192 {
+				t.Errorf("expected dedup block 8192, got %d", opts.DedupBlockSize)
+			}
+			if opts.CompressRatio != 0 || opts.MixedRandomRatio != 0 {
+				t.Error("dedup ratio leaked into another pattern's ratio")
+			}
+		}},
+		{"dedup fraction", "dedup:ratio=0.25", "dedup", func(t *testing.T, opts Options) {
+			if opts.DedupRatio != 0.25 {
+				t.Errorf("expected dedup ratio 0.25, got %f", opts.DedupRatio)
+			}
+		}},
+		{"entropy target", "entropy:target=0.6,seed=9", "entropy", func(t *testing.T, opts Options) {
+			if opts.Entropy != 0.6 {
+				t.Errorf("expected entropy 0.6, got %f", opts.Entropy)
+			}
+			if !opts.Seeded || opts.Seed != 9 {
+				t.Errorf("expected seed 9, got %d", opts.Seed)
+			}
+		}},
+		{"log format", "logs:format=apache", "logs", func(t *testing.T, opts Options) {
+			if opts.LogFormat != LogFormatApache {
+				t.Errorf("expected apache log format, got %q", opts.LogFormat)
+			}
+		}},
+		{"byte sequence", "bytes:hex=0xDEADBEEF", "bytes", func(t *testing.T, opts Options) {
+			if string(opts.PatternBytes) != "\xde\xad\xbe\xef" {
+				t.Errorf("expected DEADBEEF, got %x", opts.PatternBytes)
+			}
+		}},
+		{"whitespace", " mixed:chunk = 2KB ", "mixed", func(t *testing.T, opts Options) {
+			if opts.MixedChunkSize != 2048 {
+				t.Errorf("expected chunk 2048, got %d", opts.MixedChunkSize)
+			}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name, opts, err := ParsePattern(test.spec, Options{})
+			if err != nil {
+				t.Fatalf("ParsePattern(%q) failed: %v", test.spec, err)
+			}
+			if name != test.expected {
+				t.Errorf("expected name %s, got %s", test.expected, name)
+			}
+			test.check(t, opts)
+		})
+	}
+}
+
+func TestParsePatternOverridesBase(t *testing.T) {
+	base := Options{MixedChunkSize: 1024, MixedRandomRatio: 0.5}
+
+	_, opts, err := ParsePattern("mixed:chunk=8KB", base)
+	if err != nil {
+		t.Fatalf("ParsePattern failed: %v", err)
+	}
+	if opts.MixedChunkSize != 8192 {
+		t.Errorf("expected spec to override chunk size, got %d", opts.MixedChunkSize)
+	}
+	if opts.MixedRandomRatio != 0.5 {
+		t.Errorf("expected base ratio to be kept, got %f", opts.MixedRandomRatio)
+	}
+}
+
+func TestParsePatternErrors(t *testing.T) {
+	tests := []struct {
+		spec        string
+		expectedMsg string
+	}{
+		{"bogus", "unknown pattern"},
+		{"random:", "empty option list"},
+		{"random:seed", "must be key=value"},
+		{"random:seed=", "must be key=value"},
+		{"random:seed=abc", "invalid seed"},
+		{"zero:seed=1", "unknown option"},
+		{"random:seed=1,seed=2", "more than once"},
+		{"mixed:chunk=huge", "invalid chunk"},
+		{"mixed:chunk=100GB", "must be at most 1073741824 bytes"},
+		{"mixed:phase=sideways", "invalid phase"},
+		{"mixed:ratio=70", "must be random:zero"},
+		{"mixed:ratio=0:100", "both weights must be positive"},
+		{"mixed:ratio=a:b", "non-negative numbers"},
+		{"compressible:ratio=0.5", "between 1 and"},
+		{"compressible:ratio=70:30", "between 1 and"},
+		{"dedup:ratio=150%", "between 0 and 1"},
+		{"dedup:ratio=0", "greater than 0"},
+		{"dedup:ratio=lots", "must be a fraction"},
+		{"dedup:block=100B", "must be between"},
+		{"dedup:block=big", "invalid block"},
+		{"mixed:block=4KB", "unknown option"},
+		{"entropy:target=0", "greater than 0"},
+		{"entropy:target=1.5", "at most 1"},
+		{"random:target=0.5", "unknown option"},
+		{"logs:format=json", "invalid format"},
+		{"bytes:hex=0xABC", "odd number"},
+		{"aa55:seed=1", "unknown option"},
+		{"markov:corpus=book.txt", "unknown option"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			_, _, err := ParsePattern(test.spec, Options{})
+			if err == nil {
+				t.Fatalf("expected error for %q", test.spec)
+			}
+			if !strings.Contains(err.Error(), test.expectedMsg) {
+				t.Errorf("expected error containing %q, got %v", test.expectedMsg, err)
+			}
+		})
+	}
+}
+
+func TestPatternOptions(t *testing.T) {
+	if opts := PatternOptions("random"); len(opts) != 1 || opts[0] != "seed" {
+		t.Errorf("expected random to accept seed, got %v", opts)
+	}
+	if opts := PatternOptions("zero"); len(opts) != 0 {
+		t.Errorf("expected zero to accept no options, got %v", opts)
+	}
+
+	// Every available pattern must have a schema
+	for _, pattern := range AvailablePatterns() {
+		if _, ok := patternOptions[pattern]; !ok {
+			t.Errorf("pattern %s has no option schema", pattern)
+		}
+	}
+}
+
+func TestNewGeneratorFromSpec(t *testing.T) {
+	gen, err := NewGenerator("random:seed=42")
+	if err != nil {
+		t.Fatalf("NewGenerator failed: %v", err)
+	}
+	if _, ok := gen.(*SeededRandomGenerator); !ok {
+		t.Errorf("expected *SeededRandomGenerator, got %T", gen)
+	}
+
+	gen, err = NewGenerator("mixed:ratio=3:1,chunk=100B")
+	if err != nil {
+		t.Fatalf("NewGenerator failed: %v", err)
+	}
+
+	// With a 3:1 ratio each 200-byte pair is 150 random then 50 zero bytes
+	buffer := make([]byte, 400)
+	gen.Generate(buffer)
+	for _, start := range []int{150, 350} {
+		for i := start; i < start+50; i++ {
+			if buffer[i] != 0 {
+				t.Fatalf("expected zero run at %d-%d, found non-zero at %d", start, start+50, i)
+			}
+		}
+	}
+
+	if _, err := NewGenerator("mixed:ratio=bad"); err == nil {
+		t.Error("expected error for invalid spec")
+	}
+}
+
+func TestReproducible(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected bool
+	}{
+		{"random", false},
+		{"random:seed=42", true},
+		{"sequential", true},
+		{"zero", true},
+		{"bytes:hex=0xDEADBEEF", true},
+		{"mixed:chunk=4KB", false},
+		{"mixed:chunk=4KB,seed=1", true},
+		{"crc:seed=7", true},
+		{"aa55", true},
+		{"exec", false},
+		{"random:0.5,zero:0.5", false},
+		{"nonexistent", false},
+	}
+	for _, tt := range tests {
+		if got := Reproducible(tt.spec); got != tt.expected {
+			t.Errorf("Reproducible(%q) = %v, expected %v", tt.spec, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+)
+
+const (
+	// StampBlockSize is the size of the blocks the verify pattern stamps.
+	StampBlockSize = 4096
+
+	// stampHeaderSize is the size of the header at the start of each block:
+	// the magic, the block's own file offset and the run ID.
+	stampHeaderSize = 24
+)
+
+// stampMagic marks the start of every stamped block.
+var stampMagic = []byte("TRSHSTMP")
+
+// StampProblem describes how a stamped block failed verification.
+type StampProblem int
+
+const (
+	// StampMissing means the block has no stamp: it was overwritten or never
+	// written.
+	StampMissing StampProblem = iota + 1
+	// StampMisdirected means the block carries the stamp of another offset,
+	// so a write landed at the wrong address.
+	StampMisdirected
+	// StampStale means the block was stamped by another run, so a write was
+	// lost and older data remains.
+	StampStale
+	// StampCorrupt means the stamp is right but the payload differs.
+	StampCorrupt
+)
+
+// String returns a short description of the problem.
+func (p StampProblem) String() string {
+	switch p {
+	case StampMissing:
+		return "missing stamp"
+	case StampMisdirected:
+		return "misdirected write"
+	case StampStale:
+		return "stale data"
+	case StampCorrupt:
+		return "corrupted payload"
+	default:
+		return "unknown"
+	}
+}
+
+// StampMismatch describes a block that failed verification.
+type StampMismatch struct {
+	// Offset is where the block is in the file.
+	Offset  int64
+	Problem StampProblem
+	// FoundOffset and FoundRunID are read from the block's stamp; they are
+	// only meaningful for misdirected and stale blocks.
+	FoundOffset int64
+	FoundRunID  uint64
+}
+
+// String describes the mismatch for reports.
+func (m StampMismatch) String() string {
+	switch m.Problem {
+	case StampMisdirected:
+		return fmt.Sprintf("offset %d: %s (holds the block for offset %d)", m.Offset, m.Problem, m.FoundOffset)
+	case StampStale:
+		return fmt.Sprintf("offset %d: %s (from run %016x)", m.Offset, m.Problem, m.FoundRunID)
+	default:
+		return fmt.Sprintf("offset %d: %s", m.Offset, m.Problem)
+	}
+}
+
+// BlockChecker checks blocks read back from a file for the stamps a pattern
+// wrote into them.
+type BlockChecker interface {
+	// Check verifies data read from the file at offset, which must be a
+	// multiple of StampBlockSize, and returns the blocks that don't match.
+	Check(data []byte, offset int64) []StampMismatch
+	// RunID returns the run ID blocks must carry.
+	RunID() uint64
+}
+
+// StampGenerator generates the verify pattern: every 4KB block starts with a
+// stamp holding its own file offset and a per-run ID, followed by a payload
+// derived from both. Reading a block back and checking its stamp catches
+// misdirected and lost writes that a checksum over the whole file misses.
+type StampGenerator struct {
+	runID  uint64
+	offset int64
+	mu     sync.Mutex
+}
+
+// NewStampGenerator creates a StampGenerator stamping blocks with runID.
+func NewStampGenerator(runID uint64) *StampGenerator {
+	return &StampGenerator{runID: runID}
+}
+
+// Name returns the name of the generator.
+func (g *StampGenerator) Name() string {
+	return "verify"
+}
+
+// RunID returns the run ID stamped into every block.
+func (g *StampGenerator) RunID() uint64 {
+	return g.runID
+}
+
+// Generate fills the buffer with the next stamped blocks.
+func (g *StampGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+// GenerateAt fills the buffer with the stamped blocks as they appear at
+// offset.
+func (g *StampGenerator) GenerateAt(buffer []byte, offset int64) error {
+	generateBlocks(buffer, offset, g.fillBlock)
+	return nil
+}
+
+// generateBlocks fills buffer with the StampBlockSize blocks fill builds, as
+// they appear at offset.
+func generateBlocks(buffer []byte, offset int64, fill func(dst []byte, offset int64)) {
+	var scratch []byte
+
+	for len(buffer) > 0 {
+		within := int(offset % StampBlockSize)
+		n := min(len(buffer), StampBlockSize-within)
+
+		if within == 0 && n == StampBlockSize {
+			fill(buffer[:n], offset)
+		} else {
+			// Chunk starts or ends mid-block: build the block and copy the part needed
+			if scratch == nil {
+				scratch = make([]byte, StampBlockSize)
+			}
+			fill(scratch, offset-int64(within))
+			copy(buffer[:n], scratch[within:within+n])
+		}
+
+		buffer = buffer[n:]
+		offset += int64(n)
+	}
+}
+
+// fillBlock writes the stamped block for the block-aligned offset into dst,
+// which must be StampBlockSize bytes long.
+func (g *StampGenerator) fillBlock(dst []byte, offset int64) {
+	copy(dst, stampMagic)
+	binary.LittleEndian.PutUint64(dst[8:16], uint64(offset))
+	binary.LittleEndian.PutUint64(dst[16:24], g.runID)
+
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[0:8], g.runID)
+	binary.LittleEndian.PutUint64(key[8:16], uint64(offset))
+	copy(key[16:], "trasher-stamp")
+	rand.NewChaCha8(key).Read(dst[stampHeaderSize:])
+}
+
+// Check verifies data read from the file at offset, which must be a multiple
+// of StampBlockSize, and returns the blocks that don't match. A short final
+// block, as at the end of a file, is compared as far as it goes.
+func (g *StampGenerator) Check(data []byte, offset int64) []StampMismatch {
+	var mismatches []StampMismatch
+	expected := make([]byte, StampBlockSize)
+
+	for len(data) > 0 {
+		block := data[:min(len(data), StampBlockSize)]
+		g.fillBlock(expected, offset)
+		want := expected[:len(block)]
+
+		if !bytes.Equal(block, want) {
+			mismatches = append(mismatches, g.diagnose(block, want, offset))
+		}
+
+		data = data[len(block):]
+		offset += int64(len(block))
+	}
+
+	return mismatches
+}
+
+// diagnose classifies a block that differs from the expected one.
+func (g *StampGenerator) diagnose(block, want []byte, offset int64) StampMismatch {
+	m := StampMismatch{Offset: offset, Problem: StampCorrupt}
+
+	// A block cut short before the end of its header can only be compared bytewise
+	if len(block) < stampHeaderSize {
+		return m
+	}
+
+	foundOffset, foundRunID, ok := ReadStamp(block)
+	switch {
+	case !ok:
+		m.Problem = StampMissing
+	case foundOffset != offset:
+		m.Problem = StampMisdirected
+	case foundRunID != g.runID:
+		m.Problem = StampStale
+	default:
+		return m
+	}
+	m.FoundOffset, m.FoundRunID = foundOffset, foundRunID
+	return m
+}
+
+// ReadStamp reads the stamp at the start of a block, returning the offset
+// and run ID it records. ok is false if the block isn't stamped.
+func ReadStamp(block []byte) (offset int64, runID uint64, ok bool) {
+	if len(block) < stampHeaderSize || !bytes.Equal(block[:8], stampMagic) {
+		return 0, 0, false
+	}
+	offset = int64(binary.LittleEndian.Uint64(block[8:16]))
+	runID = binary.LittleEndian.Uint64(block[16:24])
+	return offset, runID, true
+}
@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStampGeneratorBlocks(t *testing.T) {
+	g := NewStampGenerator(0xfeed)
+
+	data := make([]byte, 3*StampBlockSize+100)
+	if err := g.Generate(data); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		offset, runID, ok := ReadStamp(data[i*StampBlockSize:])
+		if !ok || offset != int64(i*StampBlockSize) || runID != 0xfeed {
+			t.Errorf("block %d: expected stamp for offset %d, got %d/%x (ok=%v)", i, i*StampBlockSize, offset, runID, ok)
+		}
+	}
+	if bytes.Equal(data[stampHeaderSize:StampBlockSize], data[StampBlockSize+stampHeaderSize:2*StampBlockSize]) {
+		t.Error("expected block payloads to differ")
+	}
+
+	// Chunks starting mid-block line up with the whole
+	for _, split := range []int{1, 23, 24, 4095, StampBlockSize, 2*StampBlockSize + 7} {
+		part := make([]byte, len(data)-split)
+		g.GenerateAt(part, int64(split))
+		if !bytes.Equal(part, data[split:]) {
+			t.Errorf("chunk at offset %d does not match the whole stream", split)
+		}
+	}
+}
+
+func TestStampGeneratorCheck(t *testing.T) {
+	g := NewStampGenerator(7)
+	data := make([]byte, 6*StampBlockSize+1000)
+	g.GenerateAt(data, 0)
+
+	if mismatches := g.Check(data, 0); len(mismatches) != 0 {
+		t.Fatalf("expected clean data to verify, got %v", mismatches)
+	}
+
+	// Block 1: overwritten with zeros
+	clear(data[StampBlockSize : 2*StampBlockSize])
+	// Block 2: holds the block written for offset 4
+	copy(data[2*StampBlockSize:3*StampBlockSize], data[4*StampBlockSize:5*StampBlockSize])
+	// Block 3: left over from another run
+	NewStampGenerator(8).GenerateAt(data[3*StampBlockSize:4*StampBlockSize], 3*StampBlockSize)
+	// Block 5: payload bit flip
+	data[5*StampBlockSize+100] ^= 1
+	// Short final block: corrupted past the header
+	data[len(data)-1] ^= 1
+
+	mismatches := g.Check(data, 0)
+	expected := []StampMismatch{
+		{Offset: 1 * StampBlockSize, Problem: StampMissing},
+		{Offset: 2 * StampBlockSize, Problem: StampMisdirected, FoundOffset: 4 * StampBlockSize, FoundRunID: 7},
+		{Offset: 3 * StampBlockSize, Problem: StampStale, FoundOffset: 3 * StampBlockSize, FoundRunID: 8},
+		{Offset: 5 * StampBlockSize, Problem: StampCorrupt},
+		{Offset: 6 * StampBlockSize, Problem: StampCorrupt},
+	}
+	if len(mismatches) != len(expected) {
+		t.Fatalf("expected %d mismatches, got %v", len(expected), mismatches)
+	}
+	for i := range expected {
+		if mismatches[i] != expected[i] {
+			t.Errorf("mismatch %d: expected %+v, got %+v", i, expected[i], mismatches[i])
+		}
+	}
+
+	if s := mismatches[1].String(); !strings.Contains(s, "holds the block for offset 16384") {
+		t.Errorf("unexpected description %q", s)
+	}
+	if s := mismatches[2].String(); !strings.Contains(s, "from run 0000000000000008") {
+		t.Errorf("unexpected description %q", s)
+	}
+}
+
+func TestReadStamp(t *testing.T) {
+	if _, _, ok := ReadStamp(make([]byte, StampBlockSize)); ok {
+		t.Error("expected zeros to have no stamp")
+	}
+	if _, _, ok := ReadStamp(stampMagic); ok {
+		t.Error("expected a truncated header to have no stamp")
+	}
+}
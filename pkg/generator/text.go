@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// textLineWidth is the column at which text paragraphs are wrapped.
+const textLineWidth = 72
+
+// loremWords is the vocabulary text paragraphs are built from.
+var loremWords = []string{
+	"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit",
+	"sed", "do", "eiusmod", "tempor", "incididunt", "ut", "labore", "et", "dolore",
+	"magna", "aliqua", "enim", "ad", "minim", "veniam", "quis", "nostrud",
+	"exercitation", "ullamco", "laboris", "nisi", "aliquip", "ex", "ea", "commodo",
+	"consequat", "duis", "aute", "irure", "in", "reprehenderit", "voluptate",
+	"velit", "esse", "cillum", "eu", "fugiat", "nulla", "pariatur", "excepteur",
+	"sint", "occaecat", "cupidatat", "non", "proident", "sunt", "culpa", "qui",
+	"officia", "deserunt", "mollit", "anim", "id", "est", "laborum",
+}
+
+// TextGenerator generates human-readable ASCII text: lorem ipsum paragraphs
+// wrapped at 72 columns and separated by blank lines. Paragraphs never cross
+// chunk boundaries, and each chunk's text is derived from the seed and the
+// chunk offset so it can be regenerated.
+type TextGenerator struct {
+	seed   int64
+	offset int64
+	mu     sync.Mutex
+}
+
+// NewTextGenerator creates a TextGenerator for the given seed.
+func NewTextGenerator(seed int64) *TextGenerator {
+	return &TextGenerator{seed: seed}
+}
+
+// Name returns the name of the generator.
+func (g *TextGenerator) Name() string {
+	return "text"
+}
+
+// Generate fills the buffer with the next chunk of text.
+func (g *TextGenerator) Generate(buffer []byte) error {
+	g.mu.Lock()
+	offset := g.offset
+	g.offset += int64(len(buffer))
+	g.mu.Unlock()
+
+	return g.GenerateAt(buffer, offset)
+}
+
+func (g *TextGenerator) recordChunks() {}
+
+// GenerateAt fills the buffer with the chunk of text starting at offset.
+func (g *TextGenerator) GenerateAt(buffer []byte, offset int64) error {
+	rng := recordRand(g.seed, offset, "trasher-text")
+	fillRecords(buffer, offset, padSpaces, func(dst []byte, _ int64) []byte {
+		return appendParagraph(dst, rng)
+	})
+	return nil
+}
+
+// appendParagraph appends a wrapped paragraph of 3 to 7 sentences followed
+// by a blank line.
+func appendParagraph(dst []byte, rng *rand.Rand) []byte {
+	column := 0
+	sentences := 3 + rng.IntN(5)
+	for s := 0; s < sentences; s++ {
+		words := 4 + rng.IntN(12)
+		for w := 0; w < words; w++ {
+			word := loremWords[rng.IntN(len(loremWords))]
+			length := len(word)
+			if w == words-1 {
+				length++ // trailing period
+			}
+
+			// Wrap before a word that would overflow the line
+			if column > 0 && column+1+length > textLineWidth {
+				dst = append(dst, '\n')
+				column = 0
+			} else if column > 0 {
+				dst = append(dst, ' ')
+				column++
+			}
+
+			start := len(dst)
+			dst = append(dst, word...)
+			if w == 0 {
+				dst[start] -= 'a' - 'A'
+			}
+			if w == words-1 {
+				dst = append(dst, '.')
+			}
+			column += length
+		}
+	}
+	return append(dst, '\n', '\n')
+}
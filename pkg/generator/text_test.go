@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTextGeneratorReadable(t *testing.T) {
+	g := NewTextGenerator(1)
+	data := make([]byte, 256*1024)
+	if err := g.Generate(data); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	for _, b := range data {
+		if b != '\n' && (b < ' ' || b > '~') {
+			t.Fatalf("unexpected non-printable byte %#x", b)
+		}
+	}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimRight(line, " ")) > textLineWidth {
+			t.Fatalf("line %d exceeds %d columns: %q", i, textLineWidth, line)
+		}
+	}
+	if !bytes.Contains(data, []byte(".\n\n")) {
+		t.Error("expected paragraphs separated by blank lines")
+	}
+	if data[len(data)-1] != '\n' {
+		t.Error("expected the chunk to end with a newline")
+	}
+}
+
+func TestTextGeneratorChunks(t *testing.T) {
+	g := NewTextGenerator(7)
+
+	// Regenerating a chunk at its offset reproduces it exactly
+	first := make([]byte, 10000)
+	second := make([]byte, 10000)
+	g.Generate(first)
+	g.Generate(second)
+
+	again := make([]byte, 10000)
+	g.GenerateAt(again, 10000)
+	if !bytes.Equal(again, second) {
+		t.Error("expected GenerateAt to reproduce the second chunk")
+	}
+	if bytes.Equal(first, second) {
+		t.Error("expected chunks at different offsets to differ")
+	}
+
+	// Each chunk ends a paragraph, so chunks start with a capitalized word
+	if second[0] < 'A' || second[0] > 'Z' {
+		t.Errorf("expected the chunk to start a paragraph, got %q", second[:20])
+	}
+}
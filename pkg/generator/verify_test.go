@@ -0,0 +1,128 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// generateStream produces size bytes of the given generator from offset 0.
+func generateStream(t *testing.T, gen OffsetGenerator, size int) []byte {
+	t.Helper()
+
+	data := make([]byte, size)
+	if err := gen.GenerateAt(data, 0); err != nil {
+		t.Fatalf("GenerateAt failed: %v", err)
+	}
+	return data
+}
+
+// mixedWithSeed returns a mixed generator whose random runs use seed.
+func mixedWithSeed(t *testing.T, seed int64, chunkSize int) *MixedGenerator {
+	t.Helper()
+
+	gen, err := NewGeneratorWithOptions("mixed", Options{Seed: seed, Seeded: true, MixedChunkSize: chunkSize})
+	if err != nil {
+		t.Fatalf("NewGeneratorWithOptions failed: %v", err)
+	}
+	return gen.(*MixedGenerator)
+}
+
+func TestVerifyReaderMatches(t *testing.T) {
+	size := 3*verifyBlockSize + 12345
+
+	tests := []struct {
+		name    string
+		pattern string
+		seed    int64
+		data    []byte
+	}{
+		{"seeded random", "random", 42, generateStream(t, NewSeededRandomGenerator(42), size)},
+		{"seed in spec", "random:seed=7", 42, generateStream(t, NewSeededRandomGenerator(7), size)},
+		{"sequential", "sequential", 0, generateStream(t, &SequentialGenerator{}, size)},
+		{"zero", "zero", 0, make([]byte, size)},
+		{"mixed", "mixed:seed=9,chunk=4KB", 0, generateStream(t, mixedWithSeed(t, 9, 4096), size)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report, err := VerifyReader(bytes.NewReader(test.data), test.pattern, test.seed)
+			if err != nil {
+				t.Fatalf("VerifyReader failed: %v", err)
+			}
+			if !report.OK() || report.FirstMismatch != -1 {
+				t.Errorf("expected stream to match, got %+v", report)
+			}
+			if report.BytesVerified != int64(size) {
+				t.Errorf("expected %d bytes verified, got %d", size, report.BytesVerified)
+			}
+		})
+	}
+}
+
+func TestVerifyReaderMismatch(t *testing.T) {
+	data := generateStream(t, NewSeededRandomGenerator(1), 2*verifyBlockSize)
+	data[verifyBlockSize+10] ^= 0xFF
+	data[verifyBlockSize+20] ^= 0xFF
+
+	report, err := VerifyReader(bytes.NewReader(data), "random", 1)
+	if err != nil {
+		t.Fatalf("VerifyReader failed: %v", err)
+	}
+	if report.OK() {
+		t.Fatal("expected mismatch to be reported")
+	}
+	if report.MismatchedBytes != 2 {
+		t.Errorf("expected 2 mismatched bytes, got %d", report.MismatchedBytes)
+	}
+	if report.FirstMismatch != verifyBlockSize+10 {
+		t.Errorf("expected first mismatch at %d, got %d", verifyBlockSize+10, report.FirstMismatch)
+	}
+
+	// A different seed shouldn't match at all
+	report, err = VerifyReader(bytes.NewReader(data), "random", 2)
+	if err != nil {
+		t.Fatalf("VerifyReader failed: %v", err)
+	}
+	if report.OK() || report.FirstMismatch != 0 {
+		t.Errorf("expected wrong seed to mismatch from offset 0, got %+v", report)
+	}
+}
+
+// failingReader returns data and then a non-EOF error.
+type failingReader struct {
+	data []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestVerifyReaderErrors(t *testing.T) {
+	if _, err := VerifyReader(strings.NewReader(""), "bogus", 0); err == nil {
+		t.Error("expected error for unknown pattern")
+	}
+	if _, err := VerifyReader(strings.NewReader(""), "text", 0); err == nil || !strings.Contains(err.Error(), "chunk boundaries") {
+		t.Errorf("expected error for chunk-dependent pattern, got %v", err)
+	}
+
+	report, err := VerifyReader(&failingReader{data: make([]byte, 100)}, "zero", 0)
+	if err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("expected read error, got %v", err)
+	}
+	if report.BytesVerified != 100 {
+		t.Errorf("expected 100 bytes verified before the error, got %d", report.BytesVerified)
+	}
+
+	report, err = VerifyReader(io.LimitReader(strings.NewReader(""), 0), "zero", 0)
+	if err != nil || report.BytesVerified != 0 || !report.OK() {
+		t.Errorf("expected empty stream to verify cleanly, got %+v, %v", report, err)
+	}
+}
@@ -65,4 +65,23 @@ func Parse(sizeStr string) (int64, error) {
 	}
 
 	return bytes, nil
-}
\ No newline at end of file
+}
+
+// IsPercent reports whether sizeStr is a percentage, such as "50%", rather
+// than a size.
+func IsPercent(sizeStr string) bool {
+	return strings.HasSuffix(strings.TrimSpace(sizeStr), "%")
+}
+
+// ParsePercent parses a percentage such as "50%" or "12.5%" and returns it
+// as a fraction of 1. Percentages must be above 0 and at most 100.
+func ParsePercent(percentStr string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(percentStr), "%"), 64)
+	if err != nil || !IsPercent(percentStr) {
+		return 0, fmt.Errorf("invalid percentage: %s", percentStr)
+	}
+	if !(value > 0 && value <= 100) {
+		return 0, fmt.Errorf("percentage must be above 0%% and at most 100%%, got %s", percentStr)
+	}
+	return value / 100, nil
+}
@@ -111,4 +111,43 @@ func BenchmarkParse(b *testing.B) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		hasError bool
+	}{
+		{"50%", 0.5, false},
+		{"12.5%", 0.125, false},
+		{" 100% ", 1, false},
+		{"0%", 0, true},
+		{"101%", 0, true},
+		{"-5%", 0, true},
+		{"NaN%", 0, true},
+		{"50", 0, true},
+		{"%", 0, true},
+		{"half%", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if !IsPercent(tt.input) != (tt.input == "50") {
+				t.Errorf("IsPercent(%q) = %v", tt.input, IsPercent(tt.input))
+			}
+			result, err := ParsePercent(tt.input)
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("expected error for input %q, got nil", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error for input %q: %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("for input %q, expected %v, got %v", tt.input, tt.expected, result)
+			}
+		})
+	}
+}